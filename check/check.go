@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package check compiles spx projects without a language server, so that
+// tools such as CI jobs and backends can validate projects headlessly. It
+// reports the same diagnostics as the language server does.
+package check
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/goplus/xgolsw/internal/server"
+	"github.com/goplus/xgolsw/protocol"
+	"github.com/goplus/xgolsw/xgo"
+	"github.com/goplus/xgolsw/xgo/xgoutil"
+)
+
+// Result is the result of compiling a project.
+type Result struct {
+	// Diagnostics are the diagnostics of each source file, including those
+	// of local packages, and resource metadata file (index.json), keyed by
+	// its path. Every checked file has
+	// an entry, which is empty if the file has no diagnostics.
+	Diagnostics map[string][]protocol.Diagnostic
+
+	// ResourceRefs are the references to spx resources in spx source files,
+	// sorted by file and position.
+	ResourceRefs []ResourceRef
+
+	// Resources are the URIs of all spx resources of the project, including
+	// sprite costumes and animations, sorted, for example
+	// "spx://resources/sprites/MySprite".
+	Resources []string
+
+	// TypeInfo is the type information of the project. It is nil only if
+	// type checking failed entirely, in which case the errors are reported
+	// in Diagnostics.
+	TypeInfo *xgo.TypeInfo
+}
+
+// HasErrors reports whether any of the diagnostics is an error.
+func (r *Result) HasErrors() bool {
+	for _, diags := range r.Diagnostics {
+		for _, diag := range diags {
+			if diag.Severity == protocol.SeverityError {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReferencesTo returns the references to the spx resource with the given URI,
+// sorted by file and position.
+func (r *Result) ReferencesTo(uri string) []ResourceRef {
+	var refs []ResourceRef
+	for _, ref := range r.ResourceRefs {
+		if ref.URI == uri {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// ResourceRef is a reference to an spx resource in an spx source file.
+type ResourceRef struct {
+	// URI is the URI of the referenced resource, for example
+	// "spx://resources/sprites/MySprite".
+	URI string
+
+	// Kind is the kind of the reference, for example "stringLiteral" or
+	// "autoBinding".
+	Kind string
+
+	// File is the path of the spx source file of the reference.
+	File string
+
+	// Range is the range of the reference in File.
+	Range protocol.Range
+}
+
+// Compile compiles the spx source files of proj and returns the diagnostics,
+// resources, resource references and type information. The PkgPath, Mod and Importer of
+// proj are set up for spx, so proj only has to contain the files. To resolve
+// imports of third-party modules required by its go.mod file, set the
+// ModCache of proj as well.
+//
+// An error is returned only if the project cannot be compiled at all, for
+// example if it has no main.spx file. Errors in the code are reported as
+// diagnostics instead.
+func Compile(proj *xgo.Project) (*Result, error) {
+	compiled, err := server.Compile(context.Background(), proj)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Diagnostics:  compiled.Diagnostics,
+		ResourceRefs: make([]ResourceRef, 0, len(compiled.SpxResourceRefs)),
+	}
+	for _, ref := range compiled.SpxResourceRefs {
+		result.ResourceRefs = append(result.ResourceRefs, ResourceRef{
+			URI:   string(ref.ID.URI()),
+			Kind:  string(ref.Kind),
+			File:  xgoutil.PosFilename(compiled.Proj, ref.Node.Pos()),
+			Range: server.RangeForNode(compiled.Proj, ref.Node),
+		})
+	}
+	slices.SortFunc(result.ResourceRefs, func(a, b ResourceRef) int {
+		return cmp.Or(
+			cmp.Compare(a.File, b.File),
+			cmp.Compare(a.Range.Start.Line, b.Range.Start.Line),
+			cmp.Compare(a.Range.Start.Character, b.Range.Start.Character),
+			cmp.Compare(a.URI, b.URI),
+		)
+	})
+	for _, id := range compiled.SpxResourceSet.IDs() {
+		result.Resources = append(result.Resources, string(id.URI()))
+	}
+	result.TypeInfo, _ = compiled.Proj.TypeInfo()
+	return result, nil
+}
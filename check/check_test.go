@@ -0,0 +1,116 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package check
+
+import (
+	"testing"
+
+	"github.com/goplus/xgolsw/protocol"
+	"github.com/goplus/xgolsw/xgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newProject(files map[string]string) *xgo.Project {
+	m := make(map[string]*xgo.File, len(files))
+	for name, content := range files {
+		m[name] = &xgo.File{Content: []byte(content)}
+	}
+	return xgo.NewProject(nil, m, xgo.FeatAll)
+}
+
+func TestCompile(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		proj := newProject(map[string]string{
+			"main.spx": `
+var (
+	MySprite MySprite
+)
+run "assets", {Title: "My Game"}
+`,
+			"MySprite.spx": `
+onStart => {
+	play "biu"
+}
+`,
+			"assets/index.json":                  `{"zorder":["MySprite"]}`,
+			"assets/sprites/MySprite/index.json": `{"costumes":[{"name":"c1","path":"c1.png"}]}`,
+			"assets/sprites/MySprite/c1.png":     ``,
+			"assets/sounds/biu/index.json":       `{"path":"biu.wav"}`,
+			"assets/sounds/biu/biu.wav":          ``,
+		})
+
+		result, err := Compile(proj)
+		require.NoError(t, err)
+		assert.False(t, result.HasErrors())
+		assert.Contains(t, result.Diagnostics, "main.spx")
+		assert.Contains(t, result.Diagnostics, "MySprite.spx")
+		assert.Empty(t, result.Diagnostics["MySprite.spx"])
+		require.NotNil(t, result.TypeInfo)
+		assert.NotNil(t, result.TypeInfo.Pkg().Scope().Lookup("MySprite"))
+
+		assert.Contains(t, result.ResourceRefs, ResourceRef{
+			URI:  "spx://resources/sounds/biu",
+			Kind: "stringLiteral",
+			File: "MySprite.spx",
+			Range: protocol.Range{
+				Start: protocol.Position{Line: 2, Character: 6},
+				End:   protocol.Position{Line: 2, Character: 11},
+			},
+		})
+		assert.Equal(t, []string{
+			"spx://resources/sounds/biu",
+			"spx://resources/sprites/MySprite",
+			"spx://resources/sprites/MySprite/costumes/c1",
+		}, result.Resources)
+
+		refs := result.ReferencesTo("spx://resources/sprites/MySprite")
+		require.Len(t, refs, 1)
+		assert.Equal(t, "autoBinding", refs[0].Kind)
+		assert.Equal(t, "main.spx", refs[0].File)
+		assert.Empty(t, result.ReferencesTo("spx://resources/sprites/Unknown"))
+	})
+
+	t.Run("WithErrors", func(t *testing.T) {
+		proj := newProject(map[string]string{
+			"main.spx": `
+run "assets", {Title: "My Game"}
+play "boom"
+echo undefinedVar
+`,
+			"assets/index.json": `{}`,
+		})
+
+		result, err := Compile(proj)
+		require.NoError(t, err)
+		assert.True(t, result.HasErrors())
+
+		var messages []string
+		for _, diag := range result.Diagnostics["main.spx"] {
+			messages = append(messages, diag.Message)
+		}
+		assert.Contains(t, messages, "undefined: undefinedVar")
+		assert.Contains(t, messages, `sound resource "boom" not found`)
+	})
+
+	t.Run("NoMainSpxFile", func(t *testing.T) {
+		_, err := Compile(newProject(map[string]string{
+			"assets/index.json": `{}`,
+		}))
+		assert.Error(t, err)
+	})
+}
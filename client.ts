@@ -27,7 +27,8 @@ export class Spxlc {
    * @param message Message from the server.
    * @throws Error if the message type is unknown.
    */
-  private handleMessage(message: ResponseMessage | NotificationMessage): void {
+  private handleMessage(message: ResponseMessage | NotificationMessage | ResponseMessage[]): void {
+    if (Array.isArray(message)) return message.forEach(m => this.handleResponseMessage(m))
     if ('id' in message) return this.handleResponseMessage(message)
     if ('method' in message) return this.handleNotificationMessage(message)
     throw new Error('unknown message type')
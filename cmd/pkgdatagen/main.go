@@ -20,6 +20,7 @@ import (
 	"archive/zip"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"go/ast"
@@ -27,11 +28,14 @@ import (
 	"go/parser"
 	"go/token"
 	"go/types"
+	"io/fs"
+	"maps"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"slices"
+	"strings"
 
 	"github.com/goplus/xgolsw/pkgdoc"
 	"golang.org/x/mod/module"
@@ -152,9 +156,13 @@ var stdPkgPaths = []string{
 	"github.com/goplus/spx/v2/pkg/gdspx/pkg/engine",
 }
 
+// spxModulePath is the module path of spx.
+const spxModulePath = "github.com/goplus/spx/v2"
+
 // generate generates the package data file containing the exported symbols of
-// the given packages.
-func generate(pkgPaths []string, outputFile string) error {
+// the given packages, along with the package docs of the packages of the spx
+// module as of each of the given spx versions.
+func generate(pkgPaths, spxVersions []string, outputFile string) error {
 	var zipBuf bytes.Buffer
 	zw := zip.NewWriter(&zipBuf)
 	for _, pkgPath := range pkgPaths {
@@ -276,16 +284,21 @@ func generate(pkgPaths []string, outputFile string) error {
 				return fmt.Errorf("failed to write optimized package export data: %w", err)
 			}
 
-			astPkgs, err := parser.ParseDir(token.NewFileSet(), buildPkg.Dir, nil, parser.ParseComments)
+			fset = token.NewFileSet()
+			astPkgs, err := parser.ParseDir(fset, buildPkg.Dir, nil, parser.ParseComments)
 			if err != nil {
 				return fmt.Errorf("failed to parse package: %w", err)
 			}
-			astPkg, ok := astPkgs[pkgName]
-			if !ok {
+			var ok bool
+			pkgDoc, ok, err = newPkgDoc(fset, pkgPath, pkgName, astPkgs)
+			if err != nil {
+				return err
+			} else if !ok {
 				continue
 			}
-
-			pkgDoc = pkgdoc.NewGo(pkgPath, astPkg)
+		}
+		if pkgPath == spxModulePath {
+			setSpxAPICategories(pkgDoc)
 		}
 		if zf, err := zw.Create(pkgPath + ".pkgdoc"); err != nil {
 			return err
@@ -293,12 +306,107 @@ func generate(pkgPaths []string, outputFile string) error {
 			return fmt.Errorf("failed to encode package doc: %w", err)
 		}
 	}
+	if slices.ContainsFunc(pkgPaths, isSpxPkgPath) {
+		for _, version := range spxVersions {
+			if err := generateSpxBundle(zw, pkgPaths, version); err != nil {
+				return fmt.Errorf("failed to generate package docs of spx %s: %w", version, err)
+			}
+		}
+
+		version, err := execGo("list", "-m", "-f", "{{.Version}}", spxModulePath)
+		if err != nil {
+			return err
+		}
+		if zf, err := zw.Create("spx.version"); err != nil {
+			return err
+		} else if _, err := zf.Write(bytes.TrimSpace(version)); err != nil {
+			return err
+		}
+	}
 	if err := zw.Close(); err != nil {
 		return err
 	}
 	return os.WriteFile(outputFile, zipBuf.Bytes(), 0o644)
 }
 
+// generateSpxBundle writes the package docs of the packages of the spx module
+// in pkgPaths as of the given spx version to zw, under the "spx@<version>/"
+// directory. Packages that do not exist in that version are skipped.
+func generateSpxBundle(zw *zip.Writer, pkgPaths []string, version string) error {
+	output, err := execGo("mod", "download", "-json", spxModulePath+"@"+version)
+	if err != nil {
+		return err
+	}
+	var mod struct{ Dir string }
+	if err := json.Unmarshal(output, &mod); err != nil {
+		return fmt.Errorf("failed to decode module download info: %w", err)
+	}
+
+	for _, pkgPath := range pkgPaths {
+		if !isSpxPkgPath(pkgPath) {
+			continue
+		}
+		subdir := strings.TrimPrefix(strings.TrimPrefix(pkgPath, spxModulePath), "/")
+		fset := token.NewFileSet()
+		astPkgs, err := parser.ParseDir(fset, filepath.Join(mod.Dir, filepath.FromSlash(subdir)), nil, parser.ParseComments)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to parse package: %w", err)
+		}
+
+		pkgName := path.Base(pkgPath)
+		if prefix, _, ok := module.SplitPathVersion(pkgPath); ok {
+			pkgName = path.Base(prefix)
+		}
+		pkgDoc, ok, err := newPkgDoc(fset, pkgPath, pkgName, astPkgs)
+		if err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		if pkgPath == spxModulePath {
+			setSpxAPICategories(pkgDoc)
+		}
+		if zf, err := zw.Create("spx@" + version + "/" + pkgPath + ".pkgdoc"); err != nil {
+			return err
+		} else if err := json.NewEncoder(zf).Encode(pkgDoc); err != nil {
+			return fmt.Errorf("failed to encode package doc: %w", err)
+		}
+	}
+	return nil
+}
+
+// newPkgDoc creates the documentation of the package named pkgName from the
+// packages parsed from its directory, including the examples in its test
+// files. It reports false if there is no package named pkgName.
+func newPkgDoc(fset *token.FileSet, pkgPath, pkgName string, astPkgs map[string]*ast.Package) (*pkgdoc.PkgDoc, bool, error) {
+	astPkg, ok := astPkgs[pkgName]
+	if !ok {
+		return nil, false, nil
+	}
+	var files []*ast.File
+	for _, name := range slices.Sorted(maps.Keys(astPkg.Files)) {
+		files = append(files, astPkg.Files[name])
+	}
+	if testPkg, ok := astPkgs[pkgName+"_test"]; ok {
+		for _, name := range slices.Sorted(maps.Keys(testPkg.Files)) {
+			files = append(files, testPkg.Files[name])
+		}
+	}
+	pkgDoc, err := pkgdoc.NewGoFromFiles(fset, pkgPath, files)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create package doc: %w", err)
+	}
+	return pkgDoc, true, nil
+}
+
+// isSpxPkgPath reports whether pkgPath is the path of a package of the spx
+// module.
+func isSpxPkgPath(pkgPath string) bool {
+	return pkgPath == spxModulePath || strings.HasPrefix(pkgPath, spxModulePath+"/")
+}
+
 // execGo executes the given go command.
 func execGo(args ...string) ([]byte, error) {
 	cmd := exec.Command("go", args...)
@@ -316,6 +424,7 @@ func execGo(args ...string) ([]byte, error) {
 func main() {
 	outputFile := flag.String("o", "pkgdata.zip", "output file")
 	noStd := flag.Bool("no-std", false, "do not generate standard packages")
+	spxVersions := flag.String("spx-versions", "", "comma-separated list of other spx versions to bundle package docs for, e.g. v2.0.0,v2.1.0")
 	flag.Parse()
 
 	var pkgPaths []string
@@ -328,7 +437,11 @@ func main() {
 		}
 	}
 
-	if err := generate(pkgPaths, *outputFile); err != nil {
+	var versions []string
+	if *spxVersions != "" {
+		versions = strings.Split(*spxVersions, ",")
+	}
+	if err := generate(pkgPaths, versions, *outputFile); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to generate package data: %v\n", err)
 		os.Exit(1)
 	}
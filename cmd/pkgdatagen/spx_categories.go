@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"regexp"
+
+	"github.com/goplus/xgolsw/pkgdoc"
+	"github.com/goplus/xgolsw/xgo/xgoutil"
+)
+
+// spxClassTypeNames are the names of the spx types whose methods are the APIs
+// available to spx classes.
+var spxClassTypeNames = []string{"Game", "SpriteImpl"}
+
+// spxAPICategoryByName maps the XGo names of spx APIs, such as "turn" for
+// SpriteImpl.Turn, to their categories, which are the ones of a block palette.
+// Event handlers, such as "onStart", are categorized by their names instead.
+var spxAPICategoryByName = map[string]string{
+	// Motion.
+	"move":             "motion",
+	"step":             "motion",
+	"goto":             "motion",
+	"glide":            "motion",
+	"turn":             "motion",
+	"turnTo":           "motion",
+	"heading":          "motion",
+	"setHeading":       "motion",
+	"changeHeading":    "motion",
+	"xpos":             "motion",
+	"ypos":             "motion",
+	"setXYpos":         "motion",
+	"changeXYpos":      "motion",
+	"setXpos":          "motion",
+	"changeXpos":       "motion",
+	"setYpos":          "motion",
+	"changeYpos":       "motion",
+	"setRotationStyle": "motion",
+	"bounceOffEdge":    "motion",
+
+	// Looks.
+	"say":                 "looks",
+	"think":               "looks",
+	"quote":               "looks",
+	"show":                "looks",
+	"hide":                "looks",
+	"visible":             "looks",
+	"animate":             "looks",
+	"costumeName":         "looks",
+	"costumeIndex":        "looks",
+	"costumeWidth":        "looks",
+	"costumeHeight":       "looks",
+	"setCostume":          "looks",
+	"nextCostume":         "looks",
+	"prevCostume":         "looks",
+	"backdropName":        "looks",
+	"backdropIndex":       "looks",
+	"startBackdrop":       "looks",
+	"nextBackdrop":        "looks",
+	"prevBackdrop":        "looks",
+	"size":                "looks",
+	"setSize":             "looks",
+	"changeSize":          "looks",
+	"setEffect":           "looks",
+	"changeEffect":        "looks",
+	"clearGraphicEffects": "looks",
+	"gotoFront":           "looks",
+	"gotoBack":            "looks",
+	"goBackLayers":        "looks",
+	"showVar":             "looks",
+	"hideVar":             "looks",
+
+	// Sound.
+	"play":              "sound",
+	"stopAllSounds":     "sound",
+	"volume":            "sound",
+	"setVolume":         "sound",
+	"changeVolume":      "sound",
+	"getSoundEffect":    "sound",
+	"setSoundEffect":    "sound",
+	"changeSoundEffect": "sound",
+	"clearSoundEffects": "sound",
+
+	// Events.
+	"broadcast": "events",
+
+	// Control.
+	"wait":            "control",
+	"waitNextFrame":   "control",
+	"stop":            "control",
+	"clone":           "control",
+	"isCloned":        "control",
+	"die":             "control",
+	"destroy":         "control",
+	"deleteThisClone": "control",
+
+	// Sensing.
+	"touching":      "sensing",
+	"touchingColor": "sensing",
+	"distanceTo":    "sensing",
+	"ask":           "sensing",
+	"answer":        "sensing",
+	"keyPressed":    "sensing",
+	"mouseX":        "sensing",
+	"mouseY":        "sensing",
+	"mousePressed":  "sensing",
+	"loudness":      "sensing",
+	"timer":         "sensing",
+	"resetTimer":    "sensing",
+	"username":      "sensing",
+}
+
+// spxEventHandlerNameRE matches the XGo names of spx event handlers.
+var spxEventHandlerNameRE = regexp.MustCompile(`^on[A-Z]\w*$`)
+
+// setSpxAPICategories sets the categories of the APIs of the spx classes
+// documented by pkgDoc. Only the APIs of the documented spx version are
+// categorized, so each bundled version gets its own table.
+func setSpxAPICategories(pkgDoc *pkgdoc.PkgDoc) {
+	for _, typeName := range spxClassTypeNames {
+		typeDoc, ok := pkgDoc.Types[typeName]
+		if !ok {
+			continue
+		}
+		for methodName := range typeDoc.Methods {
+			name, _ := xgoutil.ParseXGoFuncName(methodName)
+			category, ok := spxAPICategoryByName[name]
+			if !ok && spxEventHandlerNameRE.MatchString(name) {
+				category, ok = "events", true
+			}
+			if !ok {
+				continue
+			}
+			if pkgDoc.Categories == nil {
+				pkgDoc.Categories = make(map[string]string)
+			}
+			pkgDoc.Categories[name] = category
+		}
+	}
+}
@@ -0,0 +1,301 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/goplus/xgolsw/internal"
+	"github.com/goplus/xgolsw/internal/server"
+	"github.com/goplus/xgolsw/internal/vfs"
+	"github.com/goplus/xgolsw/jsonrpc2"
+	"github.com/goplus/xgolsw/protocol"
+	"github.com/goplus/xgolsw/xgo"
+)
+
+// conn serves a [server.Server] over a [jsonrpc2.Stream].
+//
+// Unlike the browser, where the client provides all workspace files, desktop
+// editors only send the content of opened documents. So if diskAccess is
+// enabled, conn backs the project with the workspace root directory on
+// initialize, and reloads files reported by workspace/didChangeWatchedFiles
+// before passing them to the server. Opened documents are kept as an overlay
+// until they are closed, so their unsaved changes are not overwritten.
+type conn struct {
+	stream     jsonrpc2.Stream
+	diskAccess bool
+	proj       *xgo.Project
+	server     *server.Server
+	disk       *vfs.DiskFS
+	shutdown   bool
+}
+
+// newConn creates a new [conn] for the given stream. The diskAccess should
+// only be enabled for local clients, as it allows the client to read any file
+// accessible to the server process.
+func newConn(stream jsonrpc2.Stream, diskAccess bool) *conn {
+	c := &conn{
+		stream:     stream,
+		diskAccess: diskAccess,
+		proj:       xgo.NewProject(nil, nil, projectFeatures),
+	}
+	if diskAccess {
+		c.proj.ModCache = moduleCache()
+	}
+	c.server = server.New(c.proj, c, nil, &scheduler{})
+	c.server.SetLogger(slog.Default())
+	return c
+}
+
+// moduleCache returns the module cache to load third-party packages from,
+// which serves the directory given by -modcache, or GOMODCACHE, which defaults
+// to pkg/mod in the first GOPATH entry, and downloads the modules missing from
+// it with [goProxy]. It returns nil if there is neither such a directory nor
+// a proxy to download from.
+func moduleCache() fs.FS {
+	var dirFS fs.FS
+	if dir := moduleCacheDir(); dir != "" {
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			dirFS = os.DirFS(dir)
+		}
+	}
+	var fetch internal.ModuleFetcher
+	if proxy := goProxy(); proxy != "off" {
+		fetch = internal.NewProxyFetcher(proxy, nil)
+	}
+	if dirFS == nil && fetch == nil {
+		return nil
+	}
+	return internal.NewModCache(dirFS, fetch)
+}
+
+// moduleCacheDir returns the directory given by -modcache, or GOMODCACHE,
+// which defaults to pkg/mod in the first GOPATH entry.
+func moduleCacheDir() string {
+	if *flagModCache != "" {
+		return *flagModCache
+	}
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		gopath = filepath.Join(home, "go")
+	}
+	gopath, _, _ = strings.Cut(gopath, string(os.PathListSeparator))
+	return filepath.Join(gopath, "pkg", "mod")
+}
+
+// goProxy returns the Go module proxies given by -goproxy, or GOPROXY, which
+// defaults to the one of the go command.
+func goProxy() string {
+	if *flagGoProxy != "" {
+		return *flagGoProxy
+	}
+	if proxy := os.Getenv("GOPROXY"); proxy != "" {
+		return proxy
+	}
+	return "https://proxy.golang.org,direct"
+}
+
+// ReplyMessage implements [server.MessageReplier].
+func (c *conn) ReplyMessage(m jsonrpc2.Message) error {
+	_, err := c.stream.Write(context.Background(), m)
+	return err
+}
+
+// serve reads messages from the stream and dispatches them to the server
+// until the stream is closed, ctx is done, or the exit notification is
+// received. It reports an error if the client exits without a prior shutdown
+// request, as required by the LSP specification.
+//
+// On return, the stream is closed and all in-flight requests are cancelled.
+func (c *conn) serve(ctx context.Context) error {
+	addActiveConn(c)
+	defer removeActiveConn(c)
+	defer c.server.Close()
+	defer c.stream.Close()
+
+	// Unblock the pending read once ctx is done.
+	stop := context.AfterFunc(ctx, func() { c.stream.Close() })
+	defer stop()
+
+	for {
+		msg, _, err := c.stream.Read(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		exit := false
+		if batch, ok := msg.(jsonrpc2.Batch); ok {
+			for _, m := range batch {
+				exit = c.preHandle(m) || exit
+			}
+		} else {
+			exit = c.preHandle(msg)
+		}
+		if exit {
+			if !c.shutdown {
+				return errors.New("exit notification received without prior shutdown request")
+			}
+			return nil
+		}
+		if err := c.server.HandleMessage(msg); err != nil {
+			slog.Warn("failed to handle message", "error", err)
+		}
+	}
+}
+
+// preHandle performs the connection level work for msg, such as loading files
+// from disk, before it is handled by the server. It reports whether msg is the
+// exit notification.
+func (c *conn) preHandle(msg jsonrpc2.Message) (exit bool) {
+	req, ok := msg.(jsonrpc2.Request)
+	if !ok {
+		return
+	}
+	switch req.Method() {
+	case "exit":
+		return true
+	case "shutdown":
+		c.shutdown = true
+		return
+	}
+	if !c.diskAccess {
+		return
+	}
+	switch req.Method() {
+	case "initialize":
+		var params protocol.InitializeParams
+		if err := json.Unmarshal(req.Params(), &params); err != nil {
+			return // Let the server report the error.
+		}
+		rootDir, err := uriToPath(string(params.RootURI))
+		if err != nil || rootDir == "" {
+			return
+		}
+		c.disk = vfs.NewDiskFS(c.proj, rootDir)
+		if err := c.disk.Load(); err != nil {
+			slog.Warn("failed to load workspace", "dir", rootDir, "error", err)
+		}
+	case "workspace/didCreateFiles":
+		var params protocol.CreateFilesParams
+		if err := json.Unmarshal(req.Params(), &params); err != nil {
+			return
+		}
+		for _, file := range params.Files {
+			name, ok := c.workspaceFile(file.URI)
+			if !ok {
+				continue
+			}
+			if err := c.disk.Reload(name); err != nil {
+				slog.Warn("failed to load file", "uri", file.URI, "error", err)
+			}
+		}
+	case "workspace/didChangeWatchedFiles":
+		var params protocol.DidChangeWatchedFilesParams
+		if err := json.Unmarshal(req.Params(), &params); err != nil {
+			return
+		}
+		for _, event := range params.Changes {
+			name, ok := c.workspaceFile(string(event.URI))
+			if !ok {
+				continue
+			}
+			if err := c.disk.Reload(name); err != nil {
+				slog.Warn("failed to reload file", "uri", event.URI, "error", err)
+			}
+		}
+	case "textDocument/didOpen":
+		var params protocol.DidOpenTextDocumentParams
+		if err := json.Unmarshal(req.Params(), &params); err != nil {
+			return
+		}
+		if name, ok := c.workspaceFile(string(params.TextDocument.URI)); ok {
+			c.disk.Open(name)
+		}
+	case "textDocument/didClose":
+		var params protocol.DidCloseTextDocumentParams
+		if err := json.Unmarshal(req.Params(), &params); err != nil {
+			return
+		}
+		if name, ok := c.workspaceFile(string(params.TextDocument.URI)); ok {
+			if err := c.disk.Close(name); err != nil {
+				slog.Warn("failed to reload file", "uri", params.TextDocument.URI, "error", err)
+			}
+		}
+	}
+	return
+}
+
+// workspaceFile returns the path of the file at the given URI relative to the
+// workspace root directory. It reports false if the workspace is not backed
+// by a directory on disk or the file is not in it.
+func (c *conn) workspaceFile(uri string) (name string, ok bool) {
+	if c.disk == nil {
+		return "", false
+	}
+	p, err := uriToPath(uri)
+	if err != nil || p == "" {
+		return "", false
+	}
+	return c.disk.Rel(p)
+}
+
+// uriToPath converts a file URI to a local file path.
+func uriToPath(uri string) (string, error) {
+	if uri == "" {
+		return "", nil
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URI scheme %q", u.Scheme)
+	}
+	p := u.Path
+	if runtime.GOOS == "windows" {
+		p = strings.TrimPrefix(p, "/") // "/C:/foo" -> "C:/foo"
+	}
+	return filepath.FromSlash(p), nil
+}
+
+// scheduler implements [server.Scheduler] for native platforms.
+type scheduler struct{}
+
+// Sched yields the processor, allowing other goroutines to run.
+func (s *scheduler) Sched() {
+	runtime.Gosched()
+}
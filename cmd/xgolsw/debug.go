@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+
+	"github.com/goplus/xgolsw/internal/server"
+)
+
+// activeConns is the set of connections being served, for reporting their
+// metrics on the debug endpoint.
+var activeConns struct {
+	mu    sync.Mutex
+	conns map[*conn]struct{}
+}
+
+// addActiveConn adds c to the set of active connections.
+func addActiveConn(c *conn) {
+	activeConns.mu.Lock()
+	defer activeConns.mu.Unlock()
+	if activeConns.conns == nil {
+		activeConns.conns = make(map[*conn]struct{})
+	}
+	activeConns.conns[c] = struct{}{}
+}
+
+// removeActiveConn removes c from the set of active connections.
+func removeActiveConn(c *conn) {
+	activeConns.mu.Lock()
+	defer activeConns.mu.Unlock()
+	delete(activeConns.conns, c)
+}
+
+// connMetrics returns the metrics of all active connections.
+func connMetrics() []server.Metrics {
+	activeConns.mu.Lock()
+	conns := make([]*conn, 0, len(activeConns.conns))
+	for c := range activeConns.conns {
+		conns = append(conns, c)
+	}
+	activeConns.mu.Unlock()
+
+	metrics := make([]server.Metrics, 0, len(conns))
+	for _, c := range conns {
+		metrics = append(metrics, c.server.Metrics())
+	}
+	return metrics
+}
+
+// newDebugHandler returns an [http.Handler] that serves pprof profiles under
+// /debug/pprof/ and the metrics of all active connections as JSON at
+// /metrics.
+func newDebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"connections": connMetrics()})
+	})
+	return mux
+}
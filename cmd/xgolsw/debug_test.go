@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goplus/xgolsw/internal/server"
+	"github.com/goplus/xgolsw/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugHandler(t *testing.T) {
+	t.Run("Metrics", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- serveTCP(ctx, ln) }()
+
+		nc, err := net.Dial("tcp", ln.Addr().String())
+		require.NoError(t, err)
+		stream := jsonrpc2.NewHeaderStream(nc)
+		defer stream.Close()
+		roundTripInitialize(t, stream)
+
+		srv := httptest.NewServer(newDebugHandler())
+		defer srv.Close()
+		getMetrics := func() []server.Metrics {
+			resp, err := http.Get(srv.URL + "/metrics")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			var body struct {
+				Connections []server.Metrics `json:"connections"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			return body.Connections
+		}
+		require.Eventually(t, func() bool {
+			conns := getMetrics()
+			return len(conns) == 1 && conns[0].Requests["initialize"].Count == 1
+		}, 5*time.Second, 10*time.Millisecond)
+
+		cancel()
+		waitServeDone(t, done)
+		assert.Empty(t, getMetrics())
+	})
+
+	t.Run("Pprof", func(t *testing.T) {
+		srv := httptest.NewServer(newDebugHandler())
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/debug/pprof/heap?debug=1")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command xgolsw runs the XGo language server for spx as a standalone
+// process, so it can be used from desktop editors such as VS Code, or as a
+// shared backend service for web clients.
+//
+// By default, it serves a single client over stdin/stdout. With -tcp or -ws,
+// it listens on the given address instead and serves each connection with its
+// own server instance.
+//
+// With -debug, it also serves pprof profiles under /debug/pprof/ and internal
+// metrics of all connections as JSON at /metrics on the given address.
+//
+// When serving over stdin/stdout, packages of the third-party modules required
+// by the go.mod file of the workspace are loaded from the module cache, which
+// is GOMODCACHE unless -modcache is given. Modules missing from it are
+// downloaded from the Go module proxies given by -goproxy, which defaults to
+// GOPROXY.
+//
+// With -features, only part of the project features are enabled, such as
+// "ast" for deployments that only need syntax highlighting and formatting.
+// Features that rely on type checking, such as hover and completion, are then
+// neither advertised nor served, which saves the time and memory spent on
+// type checking.
+//
+// With -cachemem, the caches kept across compilations, such as spx definitions
+// of imported packages, are bounded by the given amount of memory instead of
+// the default of 64 MiB. They are shared by all connections.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/goplus/xgolsw/internal/server"
+	"github.com/goplus/xgolsw/jsonrpc2"
+	"github.com/goplus/xgolsw/xgo"
+)
+
+var (
+	flagTCP      = flag.String("tcp", "", "listen for LSP connections over TCP on the given `address`, e.g. :4389")
+	flagWS       = flag.String("ws", "", "listen for LSP connections over WebSocket on the given `address`, e.g. :4389")
+	flagOrigin   = flag.String("origin", "", "comma-separated `origins` allowed to connect over WebSocket, or * for any")
+	flagLogLevel = flag.String("loglevel", "info", "minimum `level` of logs written to stderr: debug, info, warn, or error")
+	flagDebug    = flag.String("debug", "", "serve pprof profiles and metrics over HTTP on the given `address`, e.g. localhost:6060")
+	flagModCache = flag.String("modcache", "", "module cache `directory` to load third-party packages from when serving over stdio; defaults to GOMODCACHE")
+	flagGoProxy  = flag.String("goproxy", "", "Go module `proxies` to download modules missing from the module cache from, with the syntax of GOPROXY, or off; defaults to GOPROXY")
+	flagFeatures = flag.String("features", "all", "comma-separated project `features` to enable: ast, types, pkgdoc, or all")
+	flagCacheMem = flag.Int("cachemem", server.DefaultCacheMemoryBudget>>20, "approximate memory in `MiB` that caches kept across compilations and shared by all connections may use")
+)
+
+// projectFeatures are the project features parsed from -features.
+var projectFeatures uint = xgo.FeatAll
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: xgolsw [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "Runs the XGo language server for spx. By default, it serves over stdin/stdout.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *flagTCP != "" && *flagWS != "" {
+		fmt.Fprintln(os.Stderr, "xgolsw: -tcp and -ws are mutually exclusive")
+		os.Exit(2)
+	}
+
+	feats, err := xgo.ParseFeatures(*flagFeatures)
+	if err == nil && feats&xgo.FeatASTCache == 0 {
+		err = errors.New("no features enabled")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "xgolsw: invalid -features: %v\n", err)
+		os.Exit(2)
+	}
+	projectFeatures = feats
+
+	if *flagCacheMem <= 0 {
+		fmt.Fprintf(os.Stderr, "xgolsw: invalid -cachemem: must be positive: %d\n", *flagCacheMem)
+		os.Exit(2)
+	}
+	server.SetCacheMemoryBudget(int64(*flagCacheMem) << 20)
+
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(*flagLogLevel)); err != nil {
+		fmt.Fprintf(os.Stderr, "xgolsw: invalid -loglevel: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Stdout is reserved for LSP messages.
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *flagDebug != "" {
+		ln, err := net.Listen("tcp", *flagDebug)
+		if err != nil {
+			slog.Error("failed to serve debug endpoint", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("serving debug endpoint", "addr", ln.Addr())
+		go func() {
+			if err := http.Serve(ln, newDebugHandler()); err != nil {
+				slog.Warn("debug endpoint stopped", "error", err)
+			}
+		}()
+	}
+	if err := run(ctx); err != nil {
+		slog.Error("failed to serve", "error", err)
+		os.Exit(1)
+	}
+}
+
+// run serves LSP with the transport selected by the flags until ctx is done.
+func run(ctx context.Context) error {
+	switch {
+	case *flagTCP != "":
+		ln, err := net.Listen("tcp", *flagTCP)
+		if err != nil {
+			return err
+		}
+		slog.Info("listening for TCP connections", "addr", ln.Addr())
+		return serveTCP(ctx, ln)
+	case *flagWS != "":
+		ln, err := net.Listen("tcp", *flagWS)
+		if err != nil {
+			return err
+		}
+		var allowedOrigins []string
+		if *flagOrigin != "" {
+			allowedOrigins = strings.Split(*flagOrigin, ",")
+		}
+		slog.Info("listening for WebSocket connections", "addr", ln.Addr())
+		return serveWebSocket(ctx, ln, allowedOrigins)
+	default:
+		stream := jsonrpc2.NewHeaderStream(stdio{})
+		return newConn(stream, true).serve(ctx)
+	}
+}
+
+// stdio is an [io.ReadWriteCloser] on top of stdin and stdout.
+type stdio struct{}
+
+func (stdio) Read(p []byte) (int, error)  { return os.Stdin.Read(p) }
+func (stdio) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+func (stdio) Close() error {
+	if err := os.Stdin.Close(); err != nil {
+		os.Stdout.Close()
+		return err
+	}
+	return os.Stdout.Close()
+}
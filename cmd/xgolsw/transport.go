@@ -0,0 +1,166 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/goplus/xgolsw/jsonrpc2"
+	"github.com/gorilla/websocket"
+)
+
+// serveTCP accepts TCP connections on ln and serves each of them with its own
+// [conn], using the same Content-Length framing as stdio. It returns once ctx
+// is done, after all connections are torn down.
+func serveTCP(ctx context.Context, ln net.Listener) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	stop := context.AfterFunc(ctx, func() { ln.Close() })
+	defer stop()
+	for {
+		nc, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serveStream(ctx, jsonrpc2.NewHeaderStream(nc), nc.RemoteAddr())
+		}()
+	}
+}
+
+// newWebSocketHandler returns an [http.Handler] that upgrades requests to
+// WebSocket connections and serves each of them with its own [conn]. Each LSP
+// message is sent as a single WebSocket text message without headers.
+//
+// Cross-origin requests are rejected unless their origin is listed in
+// allowedOrigins, or allowedOrigins contains "*".
+func newWebSocketHandler(ctx context.Context, allowedOrigins []string) http.Handler {
+	upgrader := websocket.Upgrader{}
+	if slices.Contains(allowedOrigins, "*") {
+		upgrader.CheckOrigin = func(r *http.Request) bool { return true }
+	} else if len(allowedOrigins) > 0 {
+		upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || slices.Contains(allowedOrigins, origin)
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wc, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return // The upgrader has already replied with an error.
+		}
+		serveStream(ctx, &webSocketStream{conn: wc}, wc.RemoteAddr())
+	})
+}
+
+// serveWebSocket serves LSP over WebSocket on ln. See [newWebSocketHandler].
+// It returns once ctx is done, after all connections are torn down.
+func serveWebSocket(ctx context.Context, ln net.Listener, allowedOrigins []string) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	handler := newWebSocketHandler(ctx, allowedOrigins)
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wg.Add(1)
+			defer wg.Done()
+			handler.ServeHTTP(w, r)
+		}),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	stop := context.AfterFunc(ctx, func() { srv.Close() })
+	defer stop()
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// serveStream serves a single remote connection and logs its lifecycle. Disk
+// access is disabled for remote connections, so their workspace files must be
+// provided via document synchronization and file operation notifications.
+func serveStream(ctx context.Context, stream jsonrpc2.Stream, addr net.Addr) {
+	slog.Info("connection opened", "addr", addr)
+	if err := newConn(stream, false).serve(ctx); err != nil {
+		slog.Warn("connection closed", "addr", addr, "error", err)
+		return
+	}
+	slog.Info("connection closed", "addr", addr)
+}
+
+// webSocketStream is a [jsonrpc2.Stream] on top of a WebSocket connection.
+type webSocketStream struct {
+	conn *websocket.Conn
+	wmu  sync.Mutex
+}
+
+// Read implements [jsonrpc2.Stream].
+func (s *webSocketStream) Read(ctx context.Context) (jsonrpc2.Message, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	typ, data, err := s.conn.ReadMessage()
+	if err != nil {
+		if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+			return nil, 0, io.EOF
+		}
+		return nil, 0, err
+	}
+	if typ != websocket.TextMessage {
+		return nil, int64(len(data)), fmt.Errorf("unexpected websocket message type: %d", typ)
+	}
+	msg, err := jsonrpc2.DecodeMessage(data)
+	return msg, int64(len(data)), err
+}
+
+// Write implements [jsonrpc2.Stream].
+func (s *webSocketStream) Write(ctx context.Context, msg jsonrpc2.Message) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return 0, fmt.Errorf("marshaling message: %w", err)
+	}
+
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	if err := s.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		return 0, err
+	}
+	return int64(len(data)), nil
+}
+
+// Close implements [jsonrpc2.Stream].
+func (s *webSocketStream) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/goplus/xgolsw/jsonrpc2"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTripInitialize sends an initialize request over stream and waits for
+// its response.
+func roundTripInitialize(t *testing.T, stream jsonrpc2.Stream) {
+	t.Helper()
+
+	call, err := jsonrpc2.NewCall(jsonrpc2.NewIntID(1), "initialize", map[string]any{"capabilities": map[string]any{}})
+	require.NoError(t, err)
+	_, err = stream.Write(context.Background(), call)
+	require.NoError(t, err)
+
+	for {
+		msg, _, err := stream.Read(context.Background())
+		require.NoError(t, err)
+		if resp, ok := msg.(*jsonrpc2.Response); ok {
+			assert.Equal(t, call.ID(), resp.ID())
+			assert.NoError(t, resp.Err())
+			assert.Contains(t, string(resp.Result()), `"name":"xgolsw"`)
+			return
+		}
+	}
+}
+
+// waitServeDone waits for a serve function to return after its ctx is done.
+func waitServeDone(t *testing.T, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after ctx is done")
+	}
+}
+
+func TestServeTCP(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- serveTCP(ctx, ln) }()
+
+		// Each connection is served by its own server instance.
+		for range 2 {
+			nc, err := net.Dial("tcp", ln.Addr().String())
+			require.NoError(t, err)
+			stream := jsonrpc2.NewHeaderStream(nc)
+			roundTripInitialize(t, stream)
+			defer stream.Close()
+		}
+
+		cancel()
+		waitServeDone(t, done)
+	})
+}
+
+func TestServeWebSocket(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- serveWebSocket(ctx, ln, nil) }()
+
+		wc, _, err := websocket.DefaultDialer.Dial("ws://"+ln.Addr().String(), nil)
+		require.NoError(t, err)
+		stream := &webSocketStream{conn: wc}
+		defer stream.Close()
+		roundTripInitialize(t, stream)
+
+		cancel()
+		waitServeDone(t, done)
+	})
+
+	t.Run("Origin", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- serveWebSocket(ctx, ln, []string{"https://builder.example.com"}) }()
+
+		header := http.Header{"Origin": {"https://evil.example.com"}}
+		_, resp, err := websocket.DefaultDialer.Dial("ws://"+ln.Addr().String(), header)
+		require.Error(t, err)
+		require.NotNil(t, resp)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		header = http.Header{"Origin": {"https://builder.example.com"}}
+		wc, _, err := websocket.DefaultDialer.Dial("ws://"+ln.Addr().String(), header)
+		require.NoError(t, err)
+		wc.Close()
+
+		cancel()
+		waitServeDone(t, done)
+	})
+}
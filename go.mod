@@ -9,6 +9,7 @@ require (
 	github.com/goplus/mod v0.17.1
 	github.com/goplus/spx/v2 v2.0.0-beta2.0.20250606025628-6ca49773741e
 	github.com/goplus/xgo v1.5.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/qiniu/x v1.15.1
 	github.com/stretchr/testify v1.10.0
 	golang.org/x/mod v0.25.0
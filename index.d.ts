@@ -6,8 +6,22 @@ export interface Spxls {
    * Handles incoming LSP messages from the client.
    *
    * @param message - The message to process. Any required response will be sent via the messageReplier callback.
+   *                  Responses to requests initiated by the server are also accepted. A batch of messages is
+   *                  handled in order, and the responses to its requests are sent together as a batch.
    */
-  handleMessage(message: RequestMessage | NotificationMessage): Error | null
+  handleMessage(message: AnyMessage | AnyMessage[]): Error | null
+}
+
+/**
+  * Options for creating a language server with NewSpxls.
+  */
+export interface SpxlsOptions {
+  /**
+   * Comma-separated project features to enable: "ast", "types", "pkgdoc", or "all", which is the default. Without
+   * "types", type checking is skipped, and only features that do not rely on it, such as semantic tokens, formatting,
+   * and syntax error diagnostics, are served.
+   */
+  features?: string
 }
 
 declare global {
@@ -19,9 +33,12 @@ declare global {
    *                       access the file system.
    *
    * @param messageReplier - Function called when the language server needs to reply to the client. The client should
-   *                        handle these messages according to the LSP specification.
+   *                        handle these messages according to the LSP specification. Requests initiated by the
+   *                        server should be responded to via Spxls.handleMessage.
+   *
+   * @param options - Optional settings of the language server.
    */
-  function NewSpxls(filesProvider: () => Files, messageReplier: (message: ResponseMessage | NotificationMessage) => void): Spxls | Error
+  function NewSpxls(filesProvider: () => Files, messageReplier: (message: AnyMessage | ResponseMessage[]) => void, options?: SpxlsOptions): Spxls | Error
 
   /**
    * Sets custom package data that will be used with higher priority than the embedded package data.
@@ -37,8 +54,32 @@ declare global {
    * @param packages - A map where keys are package names and values are the full import paths.
    */
   function SetClassfileAutoImportedPackages(id: string, packages: Record<string, string>): Error | null
+
+  /**
+   * Sets the approximate amount of memory that caches kept across compilations, such as spx definitions of imported
+   * packages, may use. Least recently used entries are evicted once it is exceeded. The caches are shared by all
+   * language servers.
+   *
+   * @param mib - The amount of memory in MiB, which defaults to 64.
+   */
+  function SetCacheMemoryBudget(mib: number): Error | null
+
+  /**
+   * Sets the function that fetches the third-party modules required by the go.mod file of projects, so their packages
+   * can be imported. It only affects language servers created afterwards.
+   *
+   * @param fetcher - Function that fetches the zip archive of the module version, in the format served by a Go module
+   *                  proxy at `$GOPROXY/<module>/@v/<version>.zip`, e.g., by fetching it from a Go module proxy. It
+   *                  returns null if there is no such module version. Fetched modules are kept in memory.
+   */
+  function SetModuleFetcher(fetcher: (modulePath: string, version: string) => Uint8Array | null | Promise<Uint8Array | null>): Error | null
 }
 
+/**
+ * Any of the request, response, and notification messages.
+ */
+export type AnyMessage = RequestMessage | ResponseMessage | NotificationMessage
+
 /**
  * A general message as defined by JSON-RPC. The language server protocol always uses “2.0” as the `jsonrpc` version.
  *
@@ -139,9 +180,31 @@ export type Files = {
 }
 
 /**
- * A file in the workspace.
+ * A file in the workspace, either with its content or loaded lazily.
+ */
+export type File = LoadedFile | LazyFile
+
+/**
+ * A file whose content is held in memory.
  */
-export type File = {
+export type LoadedFile = {
   content: Uint8Array
   modTime: number // unix timestamp in milliseconds
 }
+
+/**
+ * A file whose content is loaded only when it is needed, which suits large asset files, such as sounds and images.
+ * Files with the same size are compared by the hash of their content, so load is also called to detect whether the
+ * content of a file has changed.
+ */
+export type LazyFile = {
+  /**
+   * The size of the content in bytes.
+   */
+  size: number
+
+  /**
+   * Function that loads the content. It is called every time the content is needed, as the content is not kept.
+   */
+  load: () => Uint8Array | Promise<Uint8Array>
+}
@@ -2,6 +2,10 @@ package analysis
 
 import (
 	"github.com/goplus/xgolsw/internal/analysis/passes/appends"
+	"github.com/goplus/xgolsw/internal/analysis/passes/deprecated"
+	"github.com/goplus/xgolsw/internal/analysis/passes/eventhandlers"
+	"github.com/goplus/xgolsw/internal/analysis/passes/unreachable"
+	"github.com/goplus/xgolsw/internal/analysis/passes/unusedvars"
 	"github.com/goplus/xgolsw/internal/analysis/protocol"
 )
 
@@ -19,6 +23,14 @@ type Analyzer struct {
 // Analyzer returns the [protocol.Analyzer] that this Analyzer wraps.
 func (a *Analyzer) Analyzer() *protocol.Analyzer { return a.analyzer }
 
+// Name returns the name of the analyzer, which is also the key used to
+// configure it in user settings.
+func (a *Analyzer) Name() string { return a.analyzer.Name }
+
+// Doc returns the documentation of the analyzer, suitable for display in a
+// settings UI.
+func (a *Analyzer) Doc() string { return a.analyzer.Doc }
+
 // EnabledByDefault reports whether the analyzer is enabled by default for all sessions.
 // This value can be configured per-analysis in user settings.
 func (a *Analyzer) EnabledByDefault() bool { return !a.nonDefault }
@@ -77,6 +89,19 @@ func init() {
 	analyzers := []*Analyzer{
 		// The traditional vet suite:
 		{analyzer: appends.Analyzer},
+		{analyzer: unreachable.Analyzer, tags: []protocol.DiagnosticTag{protocol.Unnecessary}},
+
+		// Non-vet analyzers:
+		{analyzer: deprecated.Analyzer, tags: []protocol.DiagnosticTag{protocol.Deprecated}},
+
+		// spx-specific analyzers:
+		{analyzer: eventhandlers.Analyzer},
+		{analyzer: unusedvars.Analyzer},
+		{
+			analyzer: unusedvars.AutoBindingAnalyzer,
+			severity: protocol.SeverityHint,
+			tags:     []protocol.DiagnosticTag{protocol.Unnecessary},
+		},
 	}
 	for _, analyzer := range analyzers {
 		DefaultAnalyzers[analyzer.analyzer.Name] = analyzer
@@ -0,0 +1,225 @@
+package deprecated
+
+import (
+	_ "embed"
+	"fmt"
+	"go/types"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/goplus/xgo/ast"
+	"github.com/goplus/xgo/token"
+	"github.com/goplus/xgolsw/internal/analysis/ast/astutil"
+	"github.com/goplus/xgolsw/internal/analysis/ast/inspector"
+	"github.com/goplus/xgolsw/internal/analysis/passes/inspect"
+	"github.com/goplus/xgolsw/internal/analysis/passes/internal/analysisutil"
+	"github.com/goplus/xgolsw/internal/analysis/passes/internal/typeutil"
+	"github.com/goplus/xgolsw/internal/analysis/protocol"
+	"github.com/goplus/xgolsw/internal/pkgdata"
+)
+
+//go:embed doc.go
+var doc string
+
+var Analyzer = &protocol.Analyzer{
+	Name:     "deprecated",
+	Doc:      analysisutil.MustExtractDoc(doc, "deprecated"),
+	Requires: []*protocol.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *protocol.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	nodeFilter := []ast.Node{
+		(*ast.CallExpr)(nil),
+	}
+	inspect.Preorder(nodeFilter, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+		if !ok || fn.Pkg() == nil {
+			return
+		}
+		var ident *ast.Ident
+		switch fun := astutil.Unparen(call.Fun).(type) {
+		case *ast.Ident:
+			ident = fun
+		case *ast.SelectorExpr:
+			ident = fun.Sel
+		default:
+			return
+		}
+
+		recvType := ""
+		if recv := fn.Signature().Recv(); recv != nil {
+			typ := recv.Type()
+			if ptr, ok := typ.(*types.Pointer); ok {
+				typ = ptr.Elem()
+			}
+			named, ok := typ.(*types.Named)
+			if !ok {
+				return
+			}
+			recvType = named.Obj().Name()
+		}
+		name, _, _ := strings.Cut(fn.Name(), "__")
+		member, ok := pkgdata.LookupDeprecatedFor(fn.Pkg().Path(), recvType, name, pass.SpxVersion)
+		if !ok {
+			return
+		}
+
+		fix, replacement := suggestedFix(pass, call, ident, recvType, member.Replacement)
+		if fix == nil {
+			pass.ReportRangef(ident, "%s is deprecated", ident.Name)
+			return
+		}
+		pass.Report(protocol.Diagnostic{
+			Pos:            ident.Pos(),
+			End:            ident.End(),
+			Message:        fmt.Sprintf("%s is deprecated: use %s instead", ident.Name, replacement),
+			SuggestedFixes: []protocol.SuggestedFix{*fix},
+		})
+	})
+
+	return nil, nil
+}
+
+// suggestedFix returns the fix that rewrites the call to use the given
+// replacement, along with the replacement as it is written in the fixed code.
+// It returns nil if there is no replacement or the call cannot be rewritten.
+func suggestedFix(pass *protocol.Pass, call *ast.CallExpr, ident *ast.Ident, recvType, replacement string) (*protocol.SuggestedFix, string) {
+	if replacement == "" {
+		return nil, ""
+	}
+
+	// Methods are replaced by methods of the same receiver type.
+	if recvType != "" {
+		newName := matchCase(ident.Name, replacement)
+		return &protocol.SuggestedFix{
+			Message: fmt.Sprintf("Replace with %s", newName),
+			TextEdits: []protocol.TextEdit{{
+				Pos:     ident.Pos(),
+				End:     ident.End(),
+				NewText: []byte(newName),
+			}},
+		}, newName
+	}
+
+	// Functions are replaced by functions of possibly another package.
+	sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return nil, ""
+	}
+	i := strings.LastIndex(replacement, ".")
+	if i < 0 {
+		return nil, ""
+	}
+	pkgPath, funcName := replacement[:i], matchCase(ident.Name, replacement[i+1:])
+	file := fileOf(pass, call)
+	if file == nil {
+		return nil, ""
+	}
+	pkgName, importEdit, ok := importPkg(pass, file, pkgPath)
+	if !ok {
+		return nil, ""
+	}
+	newText := pkgName + "." + funcName
+	fix := &protocol.SuggestedFix{
+		Message: fmt.Sprintf("Replace with %s", newText),
+		TextEdits: []protocol.TextEdit{{
+			Pos:     sel.Pos(),
+			End:     sel.End(),
+			NewText: []byte(newText),
+		}},
+	}
+	if importEdit != nil {
+		fix.TextEdits = append(fix.TextEdits, *importEdit)
+	}
+	return fix, newText
+}
+
+// importPkg returns the name by which the package with the given path is
+// referred to in file, along with the edit that imports it if it is not
+// imported yet. It reports false if the package cannot be imported under its
+// default name because the name is taken by another import.
+func importPkg(pass *protocol.Pass, file *ast.File, pkgPath string) (string, *protocol.TextEdit, bool) {
+	name := path.Base(pkgPath)
+	for _, imp := range file.Imports {
+		impPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		impName := path.Base(impPath)
+		if imp.Name != nil {
+			impName = imp.Name.Name
+		}
+		if impPath == pkgPath && impName != "_" && impName != "." {
+			return impName, nil, true
+		}
+		if impName == name {
+			return "", nil, false
+		}
+	}
+
+	importLine := "import " + strconv.Quote(pkgPath)
+	for _, decl := range file.Decls {
+		decl, ok := decl.(*ast.GenDecl)
+		if !ok || decl.Tok != token.IMPORT {
+			continue
+		}
+		if decl.Lparen.IsValid() {
+			return name, &protocol.TextEdit{
+				Pos:     decl.Rparen,
+				End:     decl.Rparen,
+				NewText: []byte("\t" + strconv.Quote(pkgPath) + "\n"),
+			}, true
+		}
+		return name, &protocol.TextEdit{
+			Pos:     decl.End(),
+			End:     decl.End(),
+			NewText: []byte("\n" + importLine),
+		}, true
+	}
+	if file.Name != nil && file.Package.IsValid() {
+		return name, &protocol.TextEdit{
+			Pos:     file.Name.End(),
+			End:     file.Name.End(),
+			NewText: []byte("\n\n" + importLine),
+		}, true
+	}
+	tokFile := pass.Fset.File(file.Pos())
+	if tokFile == nil {
+		return "", nil, false
+	}
+	start := tokFile.Pos(0)
+	return name, &protocol.TextEdit{
+		Pos:     start,
+		End:     start,
+		NewText: []byte(importLine + "\n\n"),
+	}, true
+}
+
+// fileOf returns the file in pass that contains n.
+func fileOf(pass *protocol.Pass, n ast.Node) *ast.File {
+	for _, f := range pass.Files {
+		tokFile := pass.Fset.File(f.Pos())
+		if tokFile != nil && tokFile == pass.Fset.File(n.Pos()) {
+			return f
+		}
+	}
+	return nil
+}
+
+// matchCase returns name with its first letter lowercased if used starts
+// with a lowercase letter, which is how XGo code refers to exported members.
+func matchCase(used, name string) string {
+	r, _ := utf8.DecodeRuneInString(used)
+	if !unicode.IsLower(r) {
+		return name
+	}
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(first)) + name[size:]
+}
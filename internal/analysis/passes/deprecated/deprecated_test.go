@@ -0,0 +1,127 @@
+package deprecated
+
+import (
+	"testing"
+
+	"github.com/goplus/xgo/ast"
+	"github.com/goplus/xgolsw/internal"
+	"github.com/goplus/xgolsw/internal/analysis/ast/inspector"
+	"github.com/goplus/xgolsw/internal/analysis/passes/inspect"
+	"github.com/goplus/xgolsw/internal/analysis/protocol"
+	"github.com/goplus/xgolsw/xgo"
+)
+
+func TestDeprecated(t *testing.T) {
+	type edit struct {
+		old, new string
+	}
+	tests := []struct {
+		name      string
+		src       string
+		wantMsg   string
+		wantEdits []edit
+	}{
+		{
+			name: "replacement in another package",
+			src: `import "io/ioutil"
+
+data, _ := ioutil.ReadFile("data.txt")
+echo data
+`,
+			wantMsg:   "ReadFile is deprecated: use os.ReadFile instead",
+			wantEdits: []edit{{"ioutil.ReadFile", "os.ReadFile"}, {"", "\nimport \"os\""}},
+		},
+		{
+			name: "replacement package already imported",
+			src: `import (
+	"io/ioutil"
+	"os"
+)
+
+data, _ := ioutil.readFile("data.txt")
+echo data, os.Args
+`,
+			wantMsg:   "readFile is deprecated: use os.readFile instead",
+			wantEdits: []edit{{"ioutil.readFile", "os.readFile"}},
+		},
+		{
+			name: "deprecated by documentation",
+			src: `import "strings"
+
+echo strings.Title("hi")
+`,
+			wantMsg: "Title is deprecated",
+		},
+		{
+			name: "not deprecated",
+			src: `import "strings"
+
+echo strings.ToUpper("hi")
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proj := xgo.NewProject(nil, map[string]*xgo.File{
+				"main.xgo": {Content: []byte(tt.src)},
+			}, xgo.FeatAll)
+			proj.PkgPath = "main"
+			proj.Importer = internal.Importer
+			typeInfo, _ := proj.TypeInfo()
+			if typeInfo == nil {
+				t.Fatal("missing type info")
+			}
+			f, err := proj.ASTFile("main.xgo")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var diagnostics []protocol.Diagnostic
+			pass := &protocol.Pass{
+				Fset:      proj.Fset,
+				Files:     []*ast.File{f},
+				TypesInfo: typeInfo,
+				Report: func(d protocol.Diagnostic) {
+					diagnostics = append(diagnostics, d)
+				},
+				ResultOf: map[*protocol.Analyzer]any{
+					inspect.Analyzer: inspector.New([]*ast.File{f}),
+				},
+			}
+			if _, err := Analyzer.Run(pass); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantMsg == "" {
+				if len(diagnostics) > 0 {
+					t.Fatalf("got diagnostics %v, want none", diagnostics)
+				}
+				return
+			}
+			if len(diagnostics) != 1 {
+				t.Fatalf("got %d diagnostics, want 1", len(diagnostics))
+			}
+			d := diagnostics[0]
+			if d.Message != tt.wantMsg {
+				t.Errorf("got message %q, want %q", d.Message, tt.wantMsg)
+			}
+			var gotEdits []edit
+			for _, fix := range d.SuggestedFixes {
+				for _, e := range fix.TextEdits {
+					start := proj.Fset.Position(e.Pos).Offset
+					end := proj.Fset.Position(e.End).Offset
+					gotEdits = append(gotEdits, edit{tt.src[start:end], string(e.NewText)})
+				}
+			}
+			if len(gotEdits) != len(tt.wantEdits) {
+				t.Fatalf("got edits %q, want %q", gotEdits, tt.wantEdits)
+			}
+			for i := range gotEdits {
+				if gotEdits[i] != tt.wantEdits[i] {
+					t.Errorf("got edit %q, want %q", gotEdits[i], tt.wantEdits[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+// Package deprecated defines an Analyzer that checks for uses of deprecated
+// functions and methods.
+//
+// # Analyzer deprecated
+//
+// deprecated: check for calls to deprecated functions and methods
+//
+// This checker reports calls to functions and methods that are deprecated,
+// either because they are listed in the table of deprecated members, such as
+// renamed spx APIs, or because their documentation has a paragraph starting
+// with "Deprecated: ".
+//
+//	data, _ := ioutil.ReadFile("data.txt")
+//
+// If the deprecated member has a drop-in replacement, a quick fix rewrites
+// the call to use it, importing its package if needed.
+package deprecated
@@ -0,0 +1,21 @@
+// Package eventhandlers defines an Analyzer that checks for suspicious spx
+// event handler setups.
+//
+// # Analyzer eventhandlers
+//
+// eventhandlers: check for suspicious event handler setups
+//
+// This checker reports sprites that register no event handlers at all, and
+// so never run any code once the game has started; onStart handlers that are
+// registered more than once in the same file; and event handlers that are
+// registered inside a loop of another event handler, which registers a new
+// handler on every iteration.
+//
+//	onStart => {
+//		forever => {
+//			onClick => { // registered again and again
+//				say "Hi"
+//			}
+//		}
+//	}
+package eventhandlers
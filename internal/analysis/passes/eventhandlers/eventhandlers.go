@@ -0,0 +1,214 @@
+package eventhandlers
+
+import (
+	_ "embed"
+	"fmt"
+	"go/types"
+	"regexp"
+
+	"github.com/goplus/xgo/ast"
+	"github.com/goplus/xgolsw/internal/analysis/ast/inspector"
+	"github.com/goplus/xgolsw/internal/analysis/passes/inspect"
+	"github.com/goplus/xgolsw/internal/analysis/passes/internal/analysisutil"
+	"github.com/goplus/xgolsw/internal/analysis/passes/internal/typeutil"
+	"github.com/goplus/xgolsw/internal/analysis/protocol"
+	"github.com/goplus/xgolsw/xgo"
+)
+
+//go:embed doc.go
+var doc string
+
+var Analyzer = &protocol.Analyzer{
+	Name:     "eventhandlers",
+	Doc:      analysisutil.MustExtractDoc(doc, "eventhandlers"),
+	Requires: []*protocol.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// spxPkgPath is the path to the spx package.
+const spxPkgPath = "github.com/goplus/spx/v2"
+
+// eventHandlerFuncNameRE is the regular expression of the names of spx
+// functions that register event handlers.
+var eventHandlerFuncNameRE = regexp.MustCompile(`^On[A-Z]\w*$`)
+
+func run(pass *protocol.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	var (
+		hasHandler = make(map[*ast.File]bool)
+		onStarts   = make(map[*ast.File]*ast.CallExpr)
+		file       *ast.File
+	)
+	nodeFilter := []ast.Node{
+		(*ast.File)(nil),
+		(*ast.CallExpr)(nil),
+	}
+	inspect.WithStack(nodeFilter, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		if f, ok := n.(*ast.File); ok {
+			file = f
+			return true
+		}
+		call := n.(*ast.CallExpr)
+		name, ok := eventHandlerFuncName(pass.TypesInfo, call)
+		if !ok {
+			return true
+		}
+		hasHandler[file] = true
+
+		if loop := enclosingHandlerLoop(pass.TypesInfo, stack[:len(stack)-1]); loop != nil {
+			pass.Report(protocol.Diagnostic{
+				Pos:      call.Fun.Pos(),
+				End:      call.Fun.End(),
+				Category: "loopregistration",
+				Message:  fmt.Sprintf("%s is registered inside a loop of another event handler, which registers a new handler on every iteration", callName(call)),
+			})
+			return true
+		}
+		if name == "OnStart" && !isInEventHandler(pass.TypesInfo, stack[:len(stack)-1]) {
+			if first, ok := onStarts[file]; ok {
+				line := pass.Fset.Position(first.Pos()).Line
+				pass.Report(protocol.Diagnostic{
+					Pos:      call.Fun.Pos(),
+					End:      call.Fun.End(),
+					Category: "duplicateonstart",
+					Message:  fmt.Sprintf("%s is already registered in this file at line %d", callName(call), line),
+					Related: []protocol.RelatedInformation{{
+						Pos:     first.Fun.Pos(),
+						End:     first.Fun.End(),
+						Message: fmt.Sprintf("%s is first registered here", callName(first)),
+					}},
+				})
+			} else {
+				onStarts[file] = call
+			}
+		}
+		return true
+	})
+
+	for _, f := range pass.Files {
+		if !f.IsClass || f.IsProj || hasHandler[f] {
+			continue
+		}
+		decl := firstDecl(f)
+		if decl == nil {
+			continue
+		}
+		pos := decl.Pos()
+		// A sprite without event handlers may still be driven by other
+		// sprites or the stage, so this is merely informational.
+		pass.Report(protocol.Diagnostic{
+			Pos:      pos,
+			End:      pos,
+			Category: "nohandlers",
+			Message:  "sprite registers no event handlers, so its code never runs after the game starts",
+			Severity: protocol.SeverityInformation,
+		})
+	}
+	return nil, nil
+}
+
+// firstDecl returns the first declaration of f that is written in the source,
+// or nil if there is none. An empty shadow entry is not considered written.
+func firstDecl(f *ast.File) ast.Decl {
+	for _, decl := range f.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Shadow && (fd.Body == nil || len(fd.Body.List) == 0) {
+			continue
+		}
+		return decl
+	}
+	return nil
+}
+
+// eventHandlerFuncName returns the name of the spx function called by call if
+// it registers an event handler.
+func eventHandlerFuncName(info *xgo.TypeInfo, call *ast.CallExpr) (string, bool) {
+	if len(call.Args) == 0 {
+		return "", false
+	}
+	fn, ok := typeutil.Callee(info, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != spxPkgPath {
+		return "", false
+	}
+	name := trimOverloadSuffix(fn.Name())
+	if !eventHandlerFuncNameRE.MatchString(name) {
+		return "", false
+	}
+	return name, true
+}
+
+// isInEventHandler reports whether the innermost node of stack is inside an
+// event handler, given the stack of its ancestors.
+func isInEventHandler(info *xgo.TypeInfo, stack []ast.Node) bool {
+	for _, n := range stack {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if _, ok := eventHandlerFuncName(info, call); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// enclosingHandlerLoop returns the innermost loop enclosing the innermost
+// node of stack that is itself inside an event handler, given the stack of
+// its ancestors. Loops include for statements and calls to the spx forever,
+// repeat and repeatUntil functions. It returns nil if there is no such loop.
+func enclosingHandlerLoop(info *xgo.TypeInfo, stack []ast.Node) ast.Node {
+	var loop ast.Node
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch n := stack[i].(type) {
+		case *ast.FuncDecl:
+			return nil
+		case *ast.ForStmt, *ast.RangeStmt, *ast.ForPhraseStmt:
+			if loop == nil {
+				loop = n
+			}
+		case *ast.CallExpr:
+			if _, ok := eventHandlerFuncName(info, n); ok {
+				return loop
+			}
+			if loop == nil && isLoopCall(info, n) {
+				loop = n
+			}
+		}
+	}
+	return nil
+}
+
+// isLoopCall reports whether call is a call to the spx forever, repeat or
+// repeatUntil function.
+func isLoopCall(info *xgo.TypeInfo, call *ast.CallExpr) bool {
+	fn, ok := typeutil.Callee(info, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != spxPkgPath {
+		return false
+	}
+	switch fn.Name() {
+	case "Forever", "Repeat", "RepeatUntil":
+		return true
+	}
+	return false
+}
+
+// callName returns the name of the function called by call as it is written.
+func callName(call *ast.CallExpr) string {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return fun.Name
+	case *ast.SelectorExpr:
+		return fun.Sel.Name
+	}
+	return "event handler"
+}
+
+// trimOverloadSuffix trims the suffix of XGo overloaded function names, such
+// as "__0" in "OnClick__0".
+func trimOverloadSuffix(name string) string {
+	if i := len(name) - 3; i > 0 && name[i:i+2] == "__" {
+		return name[:i]
+	}
+	return name
+}
@@ -0,0 +1,140 @@
+package eventhandlers
+
+import (
+	"testing"
+
+	"github.com/goplus/mod/modload"
+	"github.com/goplus/mod/xgomod"
+	"github.com/goplus/xgo/ast"
+	"github.com/goplus/xgolsw/internal"
+	"github.com/goplus/xgolsw/internal/analysis/ast/inspector"
+	"github.com/goplus/xgolsw/internal/analysis/passes/inspect"
+	"github.com/goplus/xgolsw/internal/analysis/protocol"
+	"github.com/goplus/xgolsw/xgo"
+)
+
+func TestEventHandlers(t *testing.T) {
+	mod := xgomod.New(modload.Default)
+	if err := mod.ImportClasses(); err != nil {
+		t.Fatal(err)
+	}
+	newProject := func(spriteSrc string) *xgo.Project {
+		proj := xgo.NewProject(nil, map[string]*xgo.File{
+			"main.spx":     {Content: []byte(`var MySprite MySprite`)},
+			"MySprite.spx": {Content: []byte(spriteSrc)},
+		}, xgo.FeatAll)
+		proj.PkgPath = "main"
+		proj.Mod = mod
+		proj.Importer = internal.Importer
+		return proj
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "no event handlers",
+			src: `
+func greet() {
+	say "Hi"
+}
+`,
+			want: []string{"sprite registers no event handlers, so its code never runs after the game starts"},
+		},
+		{
+			name: "duplicate onStart",
+			src: `
+onStart => {
+	say "Hi"
+}
+
+onStart => {
+	say "Bye"
+}
+`,
+			want: []string{"onStart is already registered in this file at line 2"},
+		},
+		{
+			name: "handler registered in forever loop",
+			src: `
+onStart => {
+	forever => {
+		onClick => {
+			say "Hi"
+		}
+	}
+}
+`,
+			want: []string{"onClick is registered inside a loop of another event handler, which registers a new handler on every iteration"},
+		},
+		{
+			name: "handler registered in for loop",
+			src: `
+onStart => {
+	for i := 0; i < 3; i++ {
+		onMsg "ping", => {
+			say "pong"
+		}
+	}
+}
+`,
+			want: []string{"onMsg is registered inside a loop of another event handler, which registers a new handler on every iteration"},
+		},
+		{
+			name: "nested handler outside loop",
+			src: `
+onStart => {
+	onClick => {
+		say "Hi"
+	}
+}
+`,
+			want: nil,
+		},
+		{
+			name: "empty sprite",
+			src:  ``,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proj := newProject(tt.src)
+			typeInfo, _ := proj.TypeInfo()
+			if typeInfo == nil {
+				t.Fatal("missing type info")
+			}
+			f, err := proj.ASTFile("MySprite.spx")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			pass := &protocol.Pass{
+				Fset:      proj.Fset,
+				Files:     []*ast.File{f},
+				TypesInfo: typeInfo,
+				ResultOf: map[*protocol.Analyzer]any{
+					inspect.Analyzer: inspector.New([]*ast.File{f}),
+				},
+				Report: func(d protocol.Diagnostic) {
+					got = append(got, d.Message)
+				},
+			}
+			if _, err := Analyzer.Run(pass); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got diagnostics %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got diagnostic %q, want %q", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
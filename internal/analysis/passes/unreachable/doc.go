@@ -0,0 +1,21 @@
+// Package unreachable defines an Analyzer that checks for unreachable code.
+//
+// # Analyzer unreachable
+//
+// unreachable: check for unreachable code
+//
+// This checker reports statements that can never be executed because they
+// follow a statement that never completes normally, such as a return
+// statement, a call to panic, an infinite for loop without a break, or a
+// call to forever in spx.
+//
+//	onStart => {
+//		forever => {
+//			turn 1
+//		}
+//		say "Hi" // never runs
+//	}
+//
+// Such code usually indicates a misunderstanding of the control flow, for
+// example expecting code after forever to run concurrently.
+package unreachable
@@ -0,0 +1,155 @@
+package unreachable
+
+import (
+	_ "embed"
+	"go/types"
+
+	"github.com/goplus/xgo/ast"
+	"github.com/goplus/xgo/token"
+	"github.com/goplus/xgolsw/internal/analysis/ast/astutil"
+	"github.com/goplus/xgolsw/internal/analysis/ast/inspector"
+	"github.com/goplus/xgolsw/internal/analysis/passes/inspect"
+	"github.com/goplus/xgolsw/internal/analysis/passes/internal/analysisutil"
+	"github.com/goplus/xgolsw/internal/analysis/passes/internal/typeutil"
+	"github.com/goplus/xgolsw/internal/analysis/protocol"
+	"github.com/goplus/xgolsw/xgo"
+)
+
+//go:embed doc.go
+var doc string
+
+var Analyzer = &protocol.Analyzer{
+	Name:     "unreachable",
+	Doc:      analysisutil.MustExtractDoc(doc, "unreachable"),
+	URL:      "https://pkg.go.dev/golang.org/x/tools/go/analysis/passes/unreachable",
+	Requires: []*protocol.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// spxPkgPath is the path to the spx package.
+const spxPkgPath = "github.com/goplus/spx/v2"
+
+func run(pass *protocol.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	nodeFilter := []ast.Node{
+		(*ast.BlockStmt)(nil),
+		(*ast.CaseClause)(nil),
+		(*ast.CommClause)(nil),
+	}
+	inspect.Preorder(nodeFilter, func(n ast.Node) {
+		var list []ast.Stmt
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			list = n.List
+		case *ast.CaseClause:
+			list = n.Body
+		case *ast.CommClause:
+			list = n.Body
+		}
+		checkStmts(pass, list)
+	})
+
+	return nil, nil
+}
+
+// checkStmts reports the statements in list that follow a statement that
+// never completes normally. Statements after a labeled statement may be
+// reached by a goto, so they are not reported.
+func checkStmts(pass *protocol.Pass, list []ast.Stmt) {
+	for i, stmt := range list {
+		if !isTerminating(pass.TypesInfo, stmt, "") {
+			continue
+		}
+		rest := list[i+1:]
+		for len(rest) > 0 {
+			if _, ok := rest[0].(*ast.EmptyStmt); !ok {
+				break
+			}
+			rest = rest[1:]
+		}
+		if len(rest) == 0 {
+			return
+		}
+		if _, ok := rest[0].(*ast.LabeledStmt); ok {
+			continue
+		}
+		pass.Report(protocol.Diagnostic{
+			Pos:     rest[0].Pos(),
+			End:     rest[len(rest)-1].End(),
+			Message: "unreachable code",
+		})
+		return
+	}
+}
+
+// isTerminating reports whether stmt never completes normally. The label is
+// the label of stmt, if any.
+func isTerminating(info *xgo.TypeInfo, stmt ast.Stmt, label string) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStmt:
+		return true
+	case *ast.BranchStmt:
+		return stmt.Tok != token.FALLTHROUGH
+	case *ast.LabeledStmt:
+		return isTerminating(info, stmt.Stmt, stmt.Label.Name)
+	case *ast.BlockStmt:
+		return len(stmt.List) > 0 && isTerminating(info, stmt.List[len(stmt.List)-1], "")
+	case *ast.IfStmt:
+		return stmt.Else != nil &&
+			isTerminating(info, stmt.Body, "") &&
+			isTerminating(info, stmt.Else, "")
+	case *ast.ForStmt:
+		return stmt.Cond == nil && !hasBreak(stmt.Body, label)
+	case *ast.SelectStmt:
+		return len(stmt.Body.List) == 0
+	case *ast.ExprStmt:
+		call, ok := astutil.Unparen(stmt.X).(*ast.CallExpr)
+		if !ok {
+			return false
+		}
+		switch callee := typeutil.Callee(info, call).(type) {
+		case *types.Builtin:
+			return callee.Name() == "panic"
+		case *types.Func:
+			return callee.Pkg() != nil && callee.Pkg().Path() == spxPkgPath && callee.Name() == "Forever"
+		}
+	}
+	return false
+}
+
+// hasBreak reports whether body contains a break statement that exits the
+// loop with the given body and label.
+func hasBreak(body *ast.BlockStmt, label string) bool {
+	found := false
+	var depth int
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.FuncLit, *ast.LambdaExpr, *ast.LambdaExpr2:
+			return false
+		case *ast.BranchStmt:
+			if n.Tok == token.BREAK && ((n.Label == nil && depth == 0) || (n.Label != nil && n.Label.Name == label)) {
+				found = true
+			}
+			return false
+		case *ast.ForStmt, *ast.RangeStmt, *ast.ForPhraseStmt,
+			*ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+			depth++
+			ast.Inspect(n, func(child ast.Node) bool {
+				if child == n {
+					return true
+				}
+				return visit(child)
+			})
+			depth--
+			return false
+		}
+		return true
+	}
+	ast.Inspect(body, visit)
+	return found
+}
@@ -0,0 +1,164 @@
+package unreachable
+
+import (
+	"testing"
+
+	"github.com/goplus/mod/modload"
+	"github.com/goplus/mod/xgomod"
+	"github.com/goplus/xgo/ast"
+	"github.com/goplus/xgolsw/internal"
+	"github.com/goplus/xgolsw/internal/analysis/ast/inspector"
+	"github.com/goplus/xgolsw/internal/analysis/passes/inspect"
+	"github.com/goplus/xgolsw/internal/analysis/protocol"
+	"github.com/goplus/xgolsw/xgo"
+)
+
+func TestUnreachable(t *testing.T) {
+	mod := xgomod.New(modload.Default)
+	if err := mod.ImportClasses(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "after forever",
+			src: `
+onStart => {
+	forever => {
+		turn 1
+	}
+	say "Hi"
+	turn 2
+}
+`,
+			want: []string{`say "Hi"
+	turn 2`},
+		},
+		{
+			name: "after infinite for",
+			src: `
+onStart => {
+	for {
+		wait 1
+	}
+	say "Hi"
+}
+`,
+			want: []string{`say "Hi"`},
+		},
+		{
+			name: "after return",
+			src: `
+func f() int {
+	return 1
+	echo "unreachable"
+}
+`,
+			want: []string{`echo "unreachable"`},
+		},
+		{
+			name: "infinite for with break",
+			src: `
+onStart => {
+	for {
+		if touching(Edge) {
+			break
+		}
+	}
+	say "Hi"
+}
+`,
+			want: nil,
+		},
+		{
+			name: "infinite for with labeled break",
+			src: `
+onStart => {
+outer:
+	for {
+		for {
+			break outer
+		}
+	}
+	say "Hi"
+}
+`,
+			want: nil,
+		},
+		{
+			name: "infinite for with nested break",
+			src: `
+onStart => {
+	for {
+		for {
+			break
+		}
+	}
+	say "Hi"
+}
+`,
+			want: []string{`say "Hi"`},
+		},
+		{
+			name: "conditional for",
+			src: `
+onStart => {
+	for i := 0; i < 3; i++ {
+	}
+	say "Hi"
+}
+`,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proj := xgo.NewProject(nil, map[string]*xgo.File{
+				"main.spx":     {Content: []byte(`run "assets", {Title: "My Game"}`)},
+				"MySprite.spx": {Content: []byte(tt.src)},
+			}, xgo.FeatAll)
+			proj.PkgPath = "main"
+			proj.Mod = mod
+			proj.Importer = internal.Importer
+			typeInfo, _ := proj.TypeInfo()
+			if typeInfo == nil {
+				t.Fatal("missing type info")
+			}
+			f, err := proj.ASTFile("MySprite.spx")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			pass := &protocol.Pass{
+				Fset:      proj.Fset,
+				Files:     []*ast.File{f},
+				TypesInfo: typeInfo,
+				Report: func(d protocol.Diagnostic) {
+					start := proj.Fset.Position(d.Pos).Offset
+					end := proj.Fset.Position(d.End).Offset
+					got = append(got, tt.src[start:end])
+				},
+				ResultOf: map[*protocol.Analyzer]any{
+					inspect.Analyzer: inspector.New([]*ast.File{f}),
+				},
+			}
+			if _, err := Analyzer.Run(pass); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got unreachable code %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got unreachable code %q, want %q", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
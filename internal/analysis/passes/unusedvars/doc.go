@@ -0,0 +1,33 @@
+// Package unusedvars defines Analyzers that detect class fields declared in
+// the first var block of spx files that are never used.
+//
+// # Analyzer unusedvars
+//
+// unusedvars: check for unused class fields
+//
+// This checker reports fields declared in the first var block of an spx
+// file that are never used in the package.
+//
+//	var (
+//		score int
+//	)
+//
+// Such fields are often left over after refactoring or indicate a missing
+// piece of code.
+//
+// # Analyzer unusedautobindings
+//
+// unusedautobindings: check for unused auto-bound sprites and sounds
+//
+// This checker reports sprites and sounds that are auto-bound to fields
+// declared in the first var block of an spx file but are never used in the
+// package.
+//
+//	var (
+//		MySprite MySprite
+//		MySound  Sound
+//	)
+//
+// Auto-bound fields are legitimate even if unused, so such fields are only
+// reported as unnecessary code.
+package unusedvars
@@ -0,0 +1,98 @@
+package unusedvars
+
+import (
+	_ "embed"
+	"go/types"
+
+	"github.com/goplus/xgo/ast"
+	"github.com/goplus/xgo/token"
+	"github.com/goplus/xgolsw/internal/analysis/passes/internal/analysisutil"
+	"github.com/goplus/xgolsw/internal/analysis/protocol"
+)
+
+//go:embed doc.go
+var doc string
+
+var Analyzer = &protocol.Analyzer{
+	Name: "unusedvars",
+	Doc:  analysisutil.MustExtractDoc(doc, "unusedvars"),
+	Run: func(pass *protocol.Pass) (any, error) {
+		return run(pass, false)
+	},
+}
+
+var AutoBindingAnalyzer = &protocol.Analyzer{
+	Name: "unusedautobindings",
+	Doc:  analysisutil.MustExtractDoc(doc, "unusedautobindings"),
+	Run: func(pass *protocol.Pass) (any, error) {
+		return run(pass, true)
+	},
+}
+
+// spxPkgPath is the path to the spx package.
+const spxPkgPath = "github.com/goplus/spx/v2"
+
+// run reports unused class fields in the first var block of each file. If
+// autoBinding is true, only auto-bound fields are reported, otherwise only
+// the other fields are reported.
+func run(pass *protocol.Pass, autoBinding bool) (any, error) {
+	var used map[types.Object]bool
+	for _, f := range pass.Files {
+		decl := f.ClassFieldsDecl()
+		if decl == nil || decl.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range decl.Specs {
+			spec, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+			for _, name := range spec.Names {
+				if name.Name == "_" {
+					continue
+				}
+				obj, ok := pass.TypesInfo.Defs[name].(*types.Var)
+				if !ok || isAutoBinding(obj) != autoBinding {
+					continue
+				}
+				if used == nil {
+					used = make(map[types.Object]bool, len(pass.TypesInfo.Uses))
+					for _, obj := range pass.TypesInfo.Uses {
+						used[obj] = true
+					}
+				}
+				if used[obj] {
+					continue
+				}
+				if autoBinding {
+					pass.ReportRangef(name, "auto-bound %s is never used", name.Name)
+				} else {
+					pass.ReportRangef(name, "%s is declared but never used", name.Name)
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// isAutoBinding reports whether v is a field that sprites or sounds may be
+// auto-bound to, which is a field of type spx.Sound or spx.Sprite, or a field
+// named after its sprite type.
+func isAutoBinding(v *types.Var) bool {
+	typ := v.Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	if obj.Pkg() == nil {
+		return false
+	}
+	if obj.Pkg().Path() == spxPkgPath {
+		return obj.Name() == "Sound" || obj.Name() == "Sprite"
+	}
+	return obj.Pkg() == v.Pkg() && obj.Name() == v.Name()
+}
@@ -0,0 +1,108 @@
+package unusedvars
+
+import (
+	"testing"
+
+	"github.com/goplus/mod/modload"
+	"github.com/goplus/mod/xgomod"
+	"github.com/goplus/xgo/ast"
+	"github.com/goplus/xgolsw/internal"
+	"github.com/goplus/xgolsw/internal/analysis/protocol"
+	"github.com/goplus/xgolsw/xgo"
+)
+
+func TestUnusedVars(t *testing.T) {
+	mod := xgomod.New(modload.Default)
+	if err := mod.ImportClasses(); err != nil {
+		t.Fatal(err)
+	}
+	newProject := func(mainSrc string) *xgo.Project {
+		proj := xgo.NewProject(nil, map[string]*xgo.File{
+			"main.spx":     {Content: []byte(mainSrc)},
+			"MySprite.spx": {Content: []byte(`onStart => {}`)},
+		}, xgo.FeatAll)
+		proj.PkgPath = "main"
+		proj.Mod = mod
+		proj.Importer = internal.Importer
+		return proj
+	}
+
+	tests := []struct {
+		name     string
+		analyzer *protocol.Analyzer
+		src      string
+		want     []string
+	}{
+		{
+			name:     "unused field",
+			analyzer: Analyzer,
+			src: `
+var (
+	MySprite MySprite
+	score    int
+	lives    int
+)
+echo lives
+`,
+			want: []string{"score is declared but never used"},
+		},
+		{
+			name:     "unused auto-binding",
+			analyzer: AutoBindingAnalyzer,
+			src: `
+var (
+	MySprite MySprite
+	MySound  Sound
+	score    int
+)
+echo MySound
+`,
+			want: []string{"auto-bound MySprite is never used"},
+		},
+		{
+			name:     "blank field",
+			analyzer: Analyzer,
+			src: `
+var (
+	_ int
+)
+`,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proj := newProject(tt.src)
+			typeInfo, _ := proj.TypeInfo()
+			if typeInfo == nil {
+				t.Fatal("missing type info")
+			}
+			f, err := proj.ASTFile("main.spx")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			pass := &protocol.Pass{
+				Fset:      proj.Fset,
+				Files:     []*ast.File{f},
+				TypesInfo: typeInfo,
+				Report: func(d protocol.Diagnostic) {
+					got = append(got, d.Message)
+				},
+			}
+			if _, err := tt.analyzer.Run(pass); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got diagnostics %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got diagnostic %q, want %q", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -72,6 +72,10 @@ type Analyzer struct {
 	//
 	// FactTypes establishes a "vertical" dependency between
 	// analysis passes (same analyzer, different packages).
+	//
+	// An analyzer that declares FactTypes is run once on all files
+	// of the package, rather than once on each file, so that it can
+	// reason across files.
 	FactTypes []Fact
 }
 
@@ -98,6 +102,7 @@ type Pass struct {
 	TypesInfo    *xgo.TypeInfo  // type information about the syntax trees
 	TypesSizes   types.Sizes    // function for computing sizes of types
 	TypeErrors   []types.Error  // type errors (only if Analyzer.RunDespiteErrors)
+	SpxVersion   string         // spx version targeted by the package (empty if unknown)
 
 	// Report reports a Diagnostic, a finding about a specific location
 	// in the analyzed source code such as a potential mistake.
@@ -17,6 +17,11 @@ type Diagnostic struct {
 	Category string    // optional
 	Message  string
 
+	// Severity is the optional severity of the diagnostic. If it is
+	// zero, the driver uses the severity of the Analyzer that reported
+	// it.
+	Severity DiagnosticSeverity
+
 	// URL is the optional location of a web page that provides
 	// additional documentation for this diagnostic.
 	//
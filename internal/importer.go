@@ -3,6 +3,7 @@ package internal
 import (
 	"fmt"
 	"go/types"
+	"io/fs"
 	"sync"
 
 	xgotoken "github.com/goplus/xgo/token"
@@ -11,10 +12,15 @@ import (
 )
 
 // importer implements [types.Importer].
+//
+// It imports the packages bundled in pkgdata. Packages of third-party modules
+// are imported from the module cache it is configured with, see
+// [importer.SetModCache].
 type importer struct {
-	mu     sync.Mutex
-	fset   *xgotoken.FileSet
-	loaded map[string]*types.Package
+	mu       sync.Mutex
+	fset     *xgotoken.FileSet
+	loaded   map[string]*types.Package
+	modCache fs.FS
 }
 
 // newImporter creates a new instance of [importer].
@@ -49,5 +55,35 @@ func (imp *importer) Import(path string) (*types.Package, error) {
 	return pkg, nil
 }
 
+// Imported reports whether pkg was imported by the importer, either directly
+// or as a dependency of another package, which means it lives as long as the
+// importer.
+func (imp *importer) Imported(pkg *types.Package) bool {
+	if pkg == nil {
+		return false
+	}
+	imp.mu.Lock()
+	defer imp.mu.Unlock()
+	return imp.loaded[pkg.Path()] == pkg
+}
+
+// SetModCache sets the module cache, laid out like GOMODCACHE, from which the
+// packages of the third-party modules required by projects are loaded. It is
+// typically created by [NewModCache] with a module cache directory, a
+// [ModuleFetcher], or both.
+func (imp *importer) SetModCache(modCache fs.FS) {
+	imp.mu.Lock()
+	defer imp.mu.Unlock()
+	imp.modCache = modCache
+}
+
+// ModCache returns the module cache set by [importer.SetModCache], or nil if
+// there is none.
+func (imp *importer) ModCache() fs.FS {
+	imp.mu.Lock()
+	defer imp.mu.Unlock()
+	return imp.modCache
+}
+
 // Importer is the global instance of [importer].
 var Importer = newImporter()
@@ -0,0 +1,25 @@
+package internal
+
+import (
+	"go/types"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImporterImported(t *testing.T) {
+	imp := newImporter()
+	fmtPkg, err := imp.Import("fmt")
+	require.NoError(t, err)
+	assert.True(t, imp.Imported(fmtPkg))
+
+	// Dependencies of imported packages are imported along with them.
+	idx := slices.IndexFunc(fmtPkg.Imports(), func(pkg *types.Package) bool { return pkg.Path() == "io" })
+	require.GreaterOrEqual(t, idx, 0)
+	assert.True(t, imp.Imported(fmtPkg.Imports()[idx]))
+
+	assert.False(t, imp.Imported(types.NewPackage("fmt", "fmt")))
+	assert.False(t, imp.Imported(nil))
+}
@@ -0,0 +1,235 @@
+package internal
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/module"
+)
+
+// maxModuleZipSize is the maximum size of a module zip archive, which is the
+// same as the one enforced by the go command.
+const maxModuleZipSize = 500 << 20
+
+// defaultProxyClient is the HTTP client used by [NewProxyFetcher] if none is
+// given. Its timeout keeps a slow or unresponsive proxy from blocking imports
+// of the module forever.
+var defaultProxyClient = &http.Client{Timeout: 2 * time.Minute}
+
+// ModuleFetcher fetches the zip archive of a module version, in the format
+// served by a Go module proxy at $GOPROXY/<module>/@v/<version>.zip. It
+// returns an error wrapping [fs.ErrNotExist] if there is no such module
+// version.
+type ModuleFetcher func(modulePath, version string) ([]byte, error)
+
+// NewProxyFetcher returns a [ModuleFetcher] that downloads modules from the Go
+// module proxies listed in goproxy, which has the same syntax as GOPROXY. The
+// proxies are tried in order: a proxy followed by a comma is skipped only if
+// it does not have the module, while a proxy followed by a pipe is skipped on
+// any error. The "direct" entry is skipped, as fetching from version control
+// systems is not supported, and "off" disallows downloading.
+//
+// If client is nil, a client that gives up on a download after two minutes
+// is used.
+func NewProxyFetcher(goproxy string, client *http.Client) ModuleFetcher {
+	if client == nil {
+		client = defaultProxyClient
+	}
+	return func(modulePath, version string) ([]byte, error) {
+		escapedPath, err := module.EscapePath(modulePath)
+		if err != nil {
+			return nil, err
+		}
+		escapedVersion, err := module.EscapeVersion(version)
+		if err != nil {
+			return nil, err
+		}
+
+		err = fmt.Errorf("module %s@%s: %w", modulePath, version, fs.ErrNotExist)
+		for rest := goproxy; rest != ""; {
+			var (
+				proxy       string
+				fallBackAny bool
+			)
+			if i := strings.IndexAny(rest, ",|"); i >= 0 {
+				proxy, fallBackAny, rest = rest[:i], rest[i] == '|', rest[i+1:]
+			} else {
+				proxy, rest = rest, ""
+			}
+			switch proxy = strings.TrimSpace(proxy); proxy {
+			case "":
+				continue
+			case "off":
+				return nil, fmt.Errorf("module %s@%s: module lookup disabled by GOPROXY=off", modulePath, version)
+			case "direct":
+				continue
+			}
+
+			var content []byte
+			content, err = fetchModuleZip(client, strings.TrimSuffix(proxy, "/")+"/"+escapedPath+"/@v/"+escapedVersion+".zip")
+			if err == nil {
+				return content, nil
+			}
+			if !fallBackAny && !errors.Is(err, fs.ErrNotExist) {
+				return nil, fmt.Errorf("module %s@%s: %w", modulePath, version, err)
+			}
+		}
+		return nil, err
+	}
+}
+
+// fetchModuleZip fetches the module zip archive at url. It returns an error
+// wrapping [fs.ErrNotExist] if the proxy responds with 404 or 410, which means
+// the proxy does not have the module.
+func fetchModuleZip(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return nil, fmt.Errorf("reading %s: %s: %w", url, resp.Status, fs.ErrNotExist)
+	default:
+		return nil, fmt.Errorf("reading %s: %s", url, resp.Status)
+	}
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxModuleZipSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", url, err)
+	}
+	if len(content) > maxModuleZipSize {
+		return nil, fmt.Errorf("reading %s: module zip exceeds %d bytes", url, maxModuleZipSize)
+	}
+	return content, nil
+}
+
+// modCache is an [fs.FS] laid out like GOMODCACHE, which serves the modules
+// in an existing module cache, and downloads the missing ones with a
+// [ModuleFetcher] on first access.
+//
+// Downloaded modules are kept in memory rather than written to the module
+// cache. NOTE: They are not verified against go.sum or the checksum
+// database, as they are only used for code intelligence.
+type modCache struct {
+	dir   fs.FS
+	fetch ModuleFetcher
+
+	mu        sync.Mutex
+	downloads map[module.Version]*moduleDownload
+}
+
+// moduleDownload is a download of a module version, which is shared by all
+// accesses to the module while it is in progress and after it is done.
+type moduleDownload struct {
+	done chan struct{} // Closed when zr and err are set.
+	zr   *zip.Reader
+	err  error
+}
+
+// NewModCache creates a module cache that serves the modules in dir, and
+// downloads the missing ones with fetch. Either of dir and fetch can be nil.
+func NewModCache(dir fs.FS, fetch ModuleFetcher) fs.FS {
+	return &modCache{
+		dir:       dir,
+		fetch:     fetch,
+		downloads: make(map[module.Version]*moduleDownload),
+	}
+}
+
+// Open implements [fs.FS].
+func (c *modCache) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if c.dir != nil {
+		f, err := c.dir.Open(name)
+		if err == nil || !errors.Is(err, fs.ErrNotExist) || c.fetch == nil {
+			return f, err
+		}
+	}
+	mod, rest, ok := splitModCachePath(name)
+	if !ok || c.fetch == nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	zr, err := c.download(mod)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return zr.Open(path.Join(mod.Path+"@"+mod.Version, rest))
+}
+
+// download returns the zip archive of mod, fetching it if it has not been
+// fetched yet. Concurrent accesses to the same module share a single fetch,
+// which runs without holding the lock, so accesses to other modules are not
+// blocked by it. Modules that do not exist are remembered, so they are not
+// fetched again, while other errors are retried on the next access.
+func (c *modCache) download(mod module.Version) (*zip.Reader, error) {
+	c.mu.Lock()
+	dl, ok := c.downloads[mod]
+	if !ok {
+		dl = &moduleDownload{done: make(chan struct{})}
+		c.downloads[mod] = dl
+	}
+	c.mu.Unlock()
+	if ok {
+		<-dl.done
+		return dl.zr, dl.err
+	}
+
+	dl.zr, dl.err = c.fetchZip(mod)
+	if dl.err != nil && !errors.Is(dl.err, fs.ErrNotExist) {
+		c.mu.Lock()
+		delete(c.downloads, mod)
+		c.mu.Unlock()
+	}
+	close(dl.done)
+	return dl.zr, dl.err
+}
+
+// fetchZip fetches the zip archive of mod.
+func (c *modCache) fetchZip(mod module.Version) (*zip.Reader, error) {
+	content, err := c.fetch(mod.Path, mod.Version)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive of module %s: %w", mod, err)
+	}
+	return zr, nil
+}
+
+// splitModCachePath splits name in a module cache into the module version it
+// belongs to and the path relative to the module root, such as
+// "github.com/!foo/bar@v1.0.0/baz/qux.go" into github.com/Foo/bar@v1.0.0 and
+// "baz/qux.go".
+func splitModCachePath(name string) (mod module.Version, rest string, ok bool) {
+	i := strings.IndexByte(name, '@')
+	if i < 0 {
+		return module.Version{}, "", false
+	}
+	escapedPath, escapedVersion := name[:i], name[i+1:]
+	escapedVersion, rest, _ = strings.Cut(escapedVersion, "/")
+	if rest == "" {
+		rest = "."
+	}
+
+	var err error
+	if mod.Path, err = module.UnescapePath(escapedPath); err != nil {
+		return module.Version{}, "", false
+	}
+	if mod.Version, err = module.UnescapeVersion(escapedVersion); err != nil {
+		return module.Version{}, "", false
+	}
+	return mod, rest, true
+}
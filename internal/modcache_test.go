@@ -0,0 +1,229 @@
+package internal
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newModuleZip creates a module zip archive of modulePath at version with the
+// given files, keyed by their paths relative to the module root.
+func newModuleZip(t *testing.T, modulePath, version string, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(modulePath + "@" + version + "/" + name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestNewProxyFetcher(t *testing.T) {
+	greetZip := newModuleZip(t, "example.com/Greet", "v1.0.0", map[string]string{"greet.go": "package greet\n"})
+	newProxy := func(t *testing.T, handler http.HandlerFunc) string {
+		srv := httptest.NewServer(handler)
+		t.Cleanup(srv.Close)
+		return srv.URL
+	}
+
+	t.Run("Found", func(t *testing.T) {
+		proxy := newProxy(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/example.com/!greet/@v/v1.0.0.zip", r.URL.Path)
+			w.Write(greetZip)
+		})
+
+		content, err := NewProxyFetcher(proxy+"/", nil)("example.com/Greet", "v1.0.0")
+		require.NoError(t, err)
+		assert.Equal(t, greetZip, content)
+	})
+
+	t.Run("FallBackOnNotFound", func(t *testing.T) {
+		notFound := newProxy(t, http.NotFound)
+		found := newProxy(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write(greetZip)
+		})
+
+		content, err := NewProxyFetcher("direct,"+notFound+","+found, nil)("example.com/Greet", "v1.0.0")
+		require.NoError(t, err)
+		assert.Equal(t, greetZip, content)
+
+		_, err = NewProxyFetcher(notFound+",direct", nil)("example.com/Greet", "v1.0.0")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("FallBackOnError", func(t *testing.T) {
+		broken := newProxy(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		found := newProxy(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write(greetZip)
+		})
+
+		_, err := NewProxyFetcher(broken+","+found, nil)("example.com/Greet", "v1.0.0")
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, fs.ErrNotExist)
+		assert.Contains(t, err.Error(), "500 Internal Server Error")
+
+		content, err := NewProxyFetcher(broken+"|"+found, nil)("example.com/Greet", "v1.0.0")
+		require.NoError(t, err)
+		assert.Equal(t, greetZip, content)
+	})
+
+	t.Run("Off", func(t *testing.T) {
+		_, err := NewProxyFetcher("off", nil)("example.com/Greet", "v1.0.0")
+		assert.EqualError(t, err, "module example.com/Greet@v1.0.0: module lookup disabled by GOPROXY=off")
+	})
+}
+
+func TestNewModCache(t *testing.T) {
+	greetZip := newModuleZip(t, "example.com/Greet", "v1.0.0", map[string]string{
+		"greet.go":      "package greet\n",
+		"hello/hi.go":   "package hello\n",
+		"greet_test.go": "package greet_test\n",
+	})
+
+	t.Run("Fetch", func(t *testing.T) {
+		var fetched []string
+		modCache := NewModCache(nil, func(modulePath, version string) ([]byte, error) {
+			fetched = append(fetched, modulePath+"@"+version)
+			if modulePath != "example.com/Greet" {
+				return nil, fs.ErrNotExist
+			}
+			return greetZip, nil
+		})
+
+		content, err := fs.ReadFile(modCache, "example.com/!greet@v1.0.0/greet.go")
+		require.NoError(t, err)
+		assert.Equal(t, "package greet\n", string(content))
+		entries, err := fs.ReadDir(modCache, "example.com/!greet@v1.0.0/hello")
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "hi.go", entries[0].Name())
+
+		_, err = fs.ReadFile(modCache, "example.com/missing@v1.0.0/missing.go")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+		_, err = fs.ReadFile(modCache, "example.com/missing@v1.0.0/other.go")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+		_, err = fs.ReadFile(modCache, "example.com/greet.go")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+
+		assert.Equal(t, []string{"example.com/Greet@v1.0.0", "example.com/missing@v1.0.0"}, fetched)
+	})
+
+	t.Run("DirFirst", func(t *testing.T) {
+		dir := fstest.MapFS{
+			"example.com/!greet@v1.0.0/greet.go": {Data: []byte("package greet // From dir.\n")},
+		}
+		var fetched int
+		modCache := NewModCache(dir, func(modulePath, version string) ([]byte, error) {
+			fetched++
+			return newModuleZip(t, modulePath, version, map[string]string{"other.go": "package other\n"}), nil
+		})
+
+		content, err := fs.ReadFile(modCache, "example.com/!greet@v1.0.0/greet.go")
+		require.NoError(t, err)
+		assert.Equal(t, "package greet // From dir.\n", string(content))
+		assert.Zero(t, fetched)
+
+		content, err = fs.ReadFile(modCache, "example.com/other@v1.0.0/other.go")
+		require.NoError(t, err)
+		assert.Equal(t, "package other\n", string(content))
+		assert.Equal(t, 1, fetched)
+	})
+
+	t.Run("FetchErrorIsRetried", func(t *testing.T) {
+		errFetch := errors.New("network is down")
+		var fetched int
+		modCache := NewModCache(nil, func(modulePath, version string) ([]byte, error) {
+			fetched++
+			if fetched == 1 {
+				return nil, errFetch
+			}
+			return greetZip, nil
+		})
+
+		_, err := fs.ReadFile(modCache, "example.com/!greet@v1.0.0/greet.go")
+		assert.ErrorIs(t, err, errFetch)
+		_, err = fs.ReadFile(modCache, "example.com/!greet@v1.0.0/greet.go")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, fetched)
+	})
+
+	t.Run("ConcurrentFetchesAreShared", func(t *testing.T) {
+		var fetched atomic.Int32
+		release := make(chan struct{})
+		modCache := NewModCache(nil, func(modulePath, version string) ([]byte, error) {
+			fetched.Add(1)
+			<-release
+			return greetZip, nil
+		})
+
+		var wg sync.WaitGroup
+		contents := make([]string, 8)
+		for i := range contents {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				content, err := fs.ReadFile(modCache, "example.com/!greet@v1.0.0/greet.go")
+				assert.NoError(t, err)
+				contents[i] = string(content)
+			}()
+		}
+		require.Eventually(t, func() bool { return fetched.Load() == 1 }, time.Second, time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), fetched.Load())
+		for _, content := range contents {
+			assert.Equal(t, "package greet\n", content)
+		}
+	})
+
+	t.Run("SlowFetchDoesNotBlockOtherModules", func(t *testing.T) {
+		fastZip := newModuleZip(t, "example.com/fast", "v1.0.0", map[string]string{"a.go": "package a\n"})
+		release := make(chan struct{})
+		defer close(release)
+		modCache := NewModCache(nil, func(modulePath, version string) ([]byte, error) {
+			if modulePath == "example.com/slow" {
+				<-release
+				return nil, fs.ErrNotExist
+			}
+			return fastZip, nil
+		})
+
+		go fs.ReadFile(modCache, "example.com/slow@v1.0.0/a.go")
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := fs.ReadFile(modCache, "example.com/fast@v1.0.0/a.go")
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("fetch of example.com/fast was blocked by example.com/slow")
+		}
+	})
+
+	t.Run("NoFetcher", func(t *testing.T) {
+		modCache := NewModCache(fstest.MapFS{}, nil)
+
+		_, err := fs.ReadFile(modCache, "example.com/!greet@v1.0.0/greet.go")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
@@ -0,0 +1,146 @@
+package pkgdata
+
+import (
+	"go/doc"
+	"go/types"
+	"sync"
+
+	"github.com/goplus/xgolsw/pkgdoc"
+	"github.com/goplus/xgolsw/xgo/xgoutil"
+)
+
+// CompletionIndexEntryKind is the kind of a [CompletionIndexEntry].
+type CompletionIndexEntryKind int
+
+const (
+	CompletionIndexEntryVar CompletionIndexEntryKind = iota
+	CompletionIndexEntryConst
+	CompletionIndexEntryType
+	CompletionIndexEntryFunc
+	CompletionIndexEntryPkgName
+)
+
+// CompletionIndexEntry is an exported package member in a [CompletionIndex].
+type CompletionIndexEntry struct {
+	// Name is the name of the member.
+	Name string
+
+	// Kind is the kind of the member.
+	Kind CompletionIndexEntryKind
+
+	// Signature is the declaration of the member qualified relative to its
+	// package, e.g. "func Println(a ...any) (n int, err error)".
+	Signature string
+
+	// Synopsis is the first sentence of the documentation of the member.
+	Synopsis string
+
+	// Object is the object of the member. For XGo overloadable functions,
+	// there is one entry for each overload.
+	Object types.Object
+}
+
+// CompletionIndex is an index of the exported members of a package, built
+// once and reused by completion requests, so completing package members does
+// not walk the package scope every time.
+type CompletionIndex struct {
+	// Entries holds the entries of the package members, sorted by name.
+	Entries []CompletionIndexEntry
+
+	// constsByType maps named types to the indexes of the constants of that
+	// type in Entries.
+	constsByType map[*types.TypeName][]int
+}
+
+// ConstsOf returns the entries of the constants of the given named type.
+func (idx *CompletionIndex) ConstsOf(named *types.Named) []CompletionIndexEntry {
+	indexes := idx.constsByType[named.Origin().Obj()]
+	entries := make([]CompletionIndexEntry, 0, len(indexes))
+	for _, i := range indexes {
+		entries = append(entries, idx.Entries[i])
+	}
+	return entries
+}
+
+// completionIndexCache is a cache for completion indexes of the packages
+// imported from the package data. It is never evicted, as those packages live
+// as long as the process.
+var completionIndexCache sync.Map // map[*types.Package]*CompletionIndex
+
+// GetCompletionIndex gets the completion index for a package. The pkg must be
+// imported from the package data, as the index is built only once for each
+// package and kept for the lifetime of the process. Use [NewCompletionIndex]
+// for other packages.
+func GetCompletionIndex(pkg *types.Package) *CompletionIndex {
+	if idxIface, ok := completionIndexCache.Load(pkg); ok {
+		return idxIface.(*CompletionIndex)
+	}
+	pkgDoc, _ := GetPkgDoc(xgoutil.PkgPath(pkg))
+	idxIface, _ := completionIndexCache.LoadOrStore(pkg, newCompletionIndex(pkg, pkgDoc))
+	return idxIface.(*CompletionIndex)
+}
+
+// NewCompletionIndex builds a new [CompletionIndex] for pkg without caching
+// it, which is meant for packages that are not imported from the package data,
+// such as local packages that are type checked again on every change. The
+// pkgDoc may be nil, in which case the entries have no synopsis.
+func NewCompletionIndex(pkg *types.Package, pkgDoc *pkgdoc.PkgDoc) *CompletionIndex {
+	return newCompletionIndex(pkg, pkgDoc)
+}
+
+// newCompletionIndex builds a new [CompletionIndex] for pkg. The pkgDoc may be
+// nil, in which case the entries have no synopsis.
+func newCompletionIndex(pkg *types.Package, pkgDoc *pkgdoc.PkgDoc) *CompletionIndex {
+	if pkgDoc == nil {
+		pkgDoc = &pkgdoc.PkgDoc{}
+	}
+
+	idx := &CompletionIndex{constsByType: make(map[*types.TypeName][]int)}
+	qualifier := types.RelativeTo(pkg)
+	add := func(obj types.Object, kind CompletionIndexEntryKind, docText string) {
+		if kind == CompletionIndexEntryConst {
+			if named, ok := obj.Type().(*types.Named); ok {
+				typeName := named.Origin().Obj()
+				idx.constsByType[typeName] = append(idx.constsByType[typeName], len(idx.Entries))
+			}
+		}
+		idx.Entries = append(idx.Entries, CompletionIndexEntry{
+			Name:      obj.Name(),
+			Kind:      kind,
+			Signature: types.ObjectString(obj, qualifier),
+			Synopsis:  new(doc.Package).Synopsis(docText),
+			Object:    obj,
+		})
+	}
+
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if obj == nil || !obj.Exported() {
+			continue
+		}
+		switch obj := obj.(type) {
+		case *types.Var:
+			add(obj, CompletionIndexEntryVar, pkgDoc.Vars[name])
+		case *types.Const:
+			add(obj, CompletionIndexEntryConst, pkgDoc.Consts[name])
+		case *types.TypeName:
+			var docText string
+			if typeDoc, ok := pkgDoc.Types[name]; ok {
+				docText = typeDoc.Doc
+			}
+			add(obj, CompletionIndexEntryType, docText)
+		case *types.Func:
+			if funcOverloads := xgoutil.ExpandXGoOverloadableFunc(obj); funcOverloads != nil {
+				for _, funcOverload := range funcOverloads {
+					add(funcOverload, CompletionIndexEntryFunc, pkgDoc.Funcs[name])
+				}
+			} else {
+				add(obj, CompletionIndexEntryFunc, pkgDoc.Funcs[name])
+			}
+		case *types.PkgName:
+			add(obj, CompletionIndexEntryPkgName, "")
+		}
+	}
+	return idx
+}
@@ -0,0 +1,68 @@
+package pkgdata_test
+
+import (
+	"go/constant"
+	"go/types"
+	"testing"
+
+	"github.com/goplus/xgolsw/internal"
+	"github.com/goplus/xgolsw/internal/pkgdata"
+	"github.com/goplus/xgolsw/pkgdoc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCompletionIndex(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		pkg, err := internal.Importer.Import("fmt")
+		require.NoError(t, err)
+
+		idx := pkgdata.GetCompletionIndex(pkg)
+		assert.Same(t, idx, pkgdata.GetCompletionIndex(pkg))
+
+		var println *pkgdata.CompletionIndexEntry
+		for i, entry := range idx.Entries {
+			assert.True(t, entry.Object.Exported(), entry.Name)
+			if i > 0 {
+				assert.LessOrEqual(t, idx.Entries[i-1].Name, entry.Name)
+			}
+			if entry.Name == "Println" {
+				println = &idx.Entries[i]
+			}
+		}
+		require.NotNil(t, println)
+		assert.Equal(t, pkgdata.CompletionIndexEntryFunc, println.Kind)
+		assert.Equal(t, "func Println(a ...any) (n int, err error)", println.Signature)
+		assert.Equal(t, "Println formats using the default formats for its operands and writes to standard output.", println.Synopsis)
+		assert.Same(t, pkg.Scope().Lookup("Println"), println.Object)
+	})
+
+	t.Run("ConstsOf", func(t *testing.T) {
+		pkg, err := internal.Importer.Import("time")
+		require.NoError(t, err)
+		month := pkg.Scope().Lookup("Month").Type().(*types.Named)
+
+		consts := pkgdata.GetCompletionIndex(pkg).ConstsOf(month)
+		require.Len(t, consts, 12)
+		for _, entry := range consts {
+			assert.Equal(t, pkgdata.CompletionIndexEntryConst, entry.Kind)
+			assert.Same(t, month, entry.Object.Type())
+		}
+	})
+}
+
+func TestNewCompletionIndex(t *testing.T) {
+	pkg := types.NewPackage("example.com/game/utils", "utils")
+	answer := types.NewConst(0, pkg, "Answer", types.Typ[types.Int], constant.MakeInt64(42))
+	pkg.Scope().Insert(answer)
+	pkg.Scope().Insert(types.NewConst(0, pkg, "hidden", types.Typ[types.Int], constant.MakeInt64(0)))
+
+	idx := pkgdata.NewCompletionIndex(pkg, &pkgdoc.PkgDoc{
+		Consts: map[string]string{"Answer": "Answer is the answer."},
+	})
+	require.Len(t, idx.Entries, 1)
+	assert.Equal(t, "Answer", idx.Entries[0].Name)
+	assert.Equal(t, "Answer is the answer.", idx.Entries[0].Synopsis)
+	assert.Same(t, answer, idx.Entries[0].Object)
+	assert.NotSame(t, idx, pkgdata.NewCompletionIndex(pkg, nil))
+}
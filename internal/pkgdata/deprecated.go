@@ -0,0 +1,63 @@
+package pkgdata
+
+import "github.com/goplus/xgolsw/pkgdoc"
+
+// DeprecatedMember describes a deprecated package member.
+type DeprecatedMember struct {
+	// Replacement is the member that replaces the deprecated one as a drop-in
+	// replacement. For methods, it is the name of a method of the same
+	// receiver type, e.g. "Destroy". For functions, it is the name of a
+	// function qualified by its package path, e.g. "os.ReadFile". Empty means
+	// there is no drop-in replacement.
+	Replacement string
+}
+
+// deprecatedMemberKey identifies a package member in [deprecatedMembers].
+type deprecatedMemberKey struct {
+	pkgPath  string
+	recvType string // Empty for functions.
+	name     string
+}
+
+// deprecatedMembers holds the deprecated package members that have drop-in
+// replacements. Renamed spx APIs belong here as well, so that projects
+// written against older spx versions can be migrated by quick fixes.
+var deprecatedMembers = map[deprecatedMemberKey]DeprecatedMember{
+	{pkgPath: "io/ioutil", name: "NopCloser"}: {Replacement: "io.NopCloser"},
+	{pkgPath: "io/ioutil", name: "ReadAll"}:   {Replacement: "io.ReadAll"},
+	{pkgPath: "io/ioutil", name: "ReadFile"}:  {Replacement: "os.ReadFile"},
+	{pkgPath: "io/ioutil", name: "TempDir"}:   {Replacement: "os.MkdirTemp"},
+	{pkgPath: "io/ioutil", name: "TempFile"}:  {Replacement: "os.CreateTemp"},
+	{pkgPath: "io/ioutil", name: "WriteFile"}: {Replacement: "os.WriteFile"},
+}
+
+// LookupDeprecated reports whether the package member with the given name is
+// deprecated, and returns how to replace it. The recvType is the name of the
+// receiver type for methods, and empty for functions.
+//
+// A member is deprecated if it is listed in the table of deprecated members,
+// or if its documentation has a paragraph starting with "Deprecated: ".
+func LookupDeprecated(pkgPath, recvType, name string) (DeprecatedMember, bool) {
+	return LookupDeprecatedFor(pkgPath, recvType, name, "")
+}
+
+// LookupDeprecatedFor is like [LookupDeprecated], but looks up the
+// documentation of the member as of the given spx version. See
+// [GetPkgDocFor].
+func LookupDeprecatedFor(pkgPath, recvType, name, spxVersion string) (DeprecatedMember, bool) {
+	if member, ok := deprecatedMembers[deprecatedMemberKey{pkgPath, recvType, name}]; ok {
+		return member, true
+	}
+
+	pkgDoc, err := GetPkgDocFor(pkgPath, spxVersion)
+	if err != nil {
+		return DeprecatedMember{}, false
+	}
+	var doc string
+	if recvType == "" {
+		doc = pkgDoc.Funcs[name]
+	} else if typeDoc := pkgDoc.Types[recvType]; typeDoc != nil {
+		doc = typeDoc.Methods[name]
+	}
+	return DeprecatedMember{}, pkgdoc.ParseNotes(doc).Deprecated != ""
+}
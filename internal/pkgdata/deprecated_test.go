@@ -0,0 +1,62 @@
+package pkgdata_test
+
+import (
+	"testing"
+
+	"github.com/goplus/xgolsw/internal/pkgdata"
+	"github.com/goplus/xgolsw/pkgdoc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupDeprecated(t *testing.T) {
+	t.Run("Table", func(t *testing.T) {
+		member, ok := pkgdata.LookupDeprecated("io/ioutil", "", "ReadFile")
+		assert.True(t, ok)
+		assert.Equal(t, "os.ReadFile", member.Replacement)
+	})
+
+	t.Run("Documentation", func(t *testing.T) {
+		member, ok := pkgdata.LookupDeprecated("strings", "", "Title")
+		assert.True(t, ok)
+		assert.Empty(t, member.Replacement)
+
+		_, ok = pkgdata.LookupDeprecated("regexp", "Regexp", "Copy")
+		assert.True(t, ok)
+	})
+
+	t.Run("NotDeprecated", func(t *testing.T) {
+		_, ok := pkgdata.LookupDeprecated("strings", "", "ToUpper")
+		assert.False(t, ok)
+
+		_, ok = pkgdata.LookupDeprecated("github.com/goplus/spx/v2", "SpriteImpl", "Destroy")
+		assert.False(t, ok)
+
+		_, ok = pkgdata.LookupDeprecated("unknown/pkg", "", "Func")
+		assert.False(t, ok)
+	})
+
+	t.Run("SpxVersion", func(t *testing.T) {
+		setCustomPkgdataZip(t, map[string]string{"spx.version": "v2.2.0"}, map[string]*pkgdoc.PkgDoc{
+			"spx@v2.0.0/github.com/goplus/spx/v2.pkgdoc": {
+				Path: pkgdata.SpxModulePath,
+				Name: "spx",
+				Types: map[string]*pkgdoc.TypeDoc{
+					"SpriteImpl": {
+						Methods: map[string]string{
+							"Hide": "Hide hides the sprite.\n\nDeprecated: Set the visible field instead.\n",
+						},
+					},
+				},
+			},
+		})
+
+		_, ok := pkgdata.LookupDeprecatedFor(pkgdata.SpxModulePath, "SpriteImpl", "Hide", "v2.0.0")
+		assert.True(t, ok)
+
+		_, ok = pkgdata.LookupDeprecatedFor(pkgdata.SpxModulePath, "SpriteImpl", "Hide", "v2.2.0")
+		assert.False(t, ok)
+
+		_, ok = pkgdata.LookupDeprecated(pkgdata.SpxModulePath, "SpriteImpl", "Hide")
+		assert.False(t, ok)
+	})
+}
@@ -12,8 +12,10 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/goplus/xgolsw/pkgdoc"
+	"golang.org/x/mod/semver"
 )
 
 //go:generate go tool pkgdatagen
@@ -30,13 +32,26 @@ var (
 // SetCustomPkgdataZip sets the customPkgdataZip.
 func SetCustomPkgdataZip(data []byte) {
 	customPkgdataZip = data
+	pkgDocCache.Clear()
+	spxBundleIndexCache.Store(nil)
 }
 
 const (
 	pkgExportSuffix = ".pkgexport"
 	pkgDocSuffix    = ".pkgdoc"
+
+	// spxBundlePrefix is the prefix of the directories holding the package
+	// docs of other spx versions, e.g. "spx@v2.0.0/".
+	spxBundlePrefix = "spx@"
+
+	// spxVersionFile is the file holding the spx version that the package
+	// data is generated for.
+	spxVersionFile = "spx.version"
 )
 
+// SpxModulePath is the module path of spx.
+const SpxModulePath = "github.com/goplus/spx/v2"
+
 // ListPkgs lists all packages in the pkgdata.zip file.
 func ListPkgs() ([]string, error) {
 	pkgs, err := listPkgs(pkgdataZip)
@@ -147,3 +162,136 @@ func getPkgDoc(zipData []byte, pkgPath string) (pkgDoc *pkgdoc.PkgDoc, err error
 	}
 	return nil, fmt.Errorf("failed to find doc file for package %q: %w", pkgPath, fs.ErrNotExist)
 }
+
+// GetPkgDocFor gets the documentation for a package as of the given spx
+// version, which is usually the one required by a project. Packages of the spx
+// module are documented by the bundle resolved by [ResolveSpxVersion], falling
+// back to [GetPkgDoc] if the bundle does not contain the package. Other
+// packages are always documented by [GetPkgDoc].
+func GetPkgDocFor(pkgPath, spxVersion string) (*pkgdoc.PkgDoc, error) {
+	if !isSpxPkgPath(pkgPath) {
+		return GetPkgDoc(pkgPath)
+	}
+	bundle := ResolveSpxVersion(spxVersion)
+	if bundle == "" {
+		return GetPkgDoc(pkgPath)
+	}
+	pkgDoc, err := GetPkgDoc(spxBundlePrefix + bundle + "/" + pkgPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return GetPkgDoc(pkgPath)
+	}
+	return pkgDoc, err
+}
+
+// isSpxPkgPath reports whether pkgPath is the path of a package of the spx
+// module.
+func isSpxPkgPath(pkgPath string) bool {
+	return pkgPath == SpxModulePath || strings.HasPrefix(pkgPath, SpxModulePath+"/")
+}
+
+// DefaultSpxVersion returns the spx version that the package data is
+// generated for, or an empty string if it is unknown.
+func DefaultSpxVersion() string {
+	return getSpxBundleIndex().defaultVersion
+}
+
+// SpxVersions returns the spx versions whose package docs are bundled besides
+// those of [DefaultSpxVersion], in ascending order.
+func SpxVersions() []string {
+	return slices.Clone(getSpxBundleIndex().versions)
+}
+
+// ResolveSpxVersion returns the bundled spx version whose package docs best
+// match the given version, which is the latest bundled version not newer than
+// it, or the oldest bundled version if all of them are newer. It returns an
+// empty string if the package docs of [DefaultSpxVersion] match best, which is
+// also the case if the given version is not a valid semantic version.
+func ResolveSpxVersion(version string) string {
+	if !semver.IsValid(version) {
+		return ""
+	}
+	idx := getSpxBundleIndex()
+	if len(idx.versions) == 0 {
+		return ""
+	}
+	if idx.defaultVersion != "" && semver.Compare(version, idx.defaultVersion) >= 0 {
+		return ""
+	}
+	resolved := idx.versions[0]
+	for _, v := range idx.versions[1:] {
+		if semver.Compare(v, version) > 0 {
+			break
+		}
+		resolved = v
+	}
+	return resolved
+}
+
+// spxBundleIndex is an index of the spx versions in the package data.
+type spxBundleIndex struct {
+	defaultVersion string
+	versions       []string // Sorted in ascending order.
+}
+
+// spxBundleIndexCache is a cache for [spxBundleIndex].
+var spxBundleIndexCache atomic.Pointer[spxBundleIndex]
+
+// getSpxBundleIndex gets the index of the spx versions in the package data.
+// Versions in the custom package data take precedence.
+func getSpxBundleIndex() *spxBundleIndex {
+	if idx := spxBundleIndexCache.Load(); idx != nil {
+		return idx
+	}
+
+	idx := &spxBundleIndex{}
+	for _, zipData := range [][]byte{customPkgdataZip, pkgdataZip} {
+		if len(zipData) == 0 {
+			continue
+		}
+		defaultVersion, versions, err := listSpxBundles(zipData)
+		if err != nil {
+			continue
+		}
+		if idx.defaultVersion == "" {
+			idx.defaultVersion = defaultVersion
+		}
+		idx.versions = append(idx.versions, versions...)
+	}
+	semver.Sort(idx.versions)
+	idx.versions = slices.Compact(idx.versions)
+	spxBundleIndexCache.Store(idx)
+	return idx
+}
+
+// listSpxBundles lists the default spx version and the bundled spx versions
+// in the provided zip data.
+func listSpxBundles(zipData []byte) (defaultVersion string, versions []string, err error) {
+	zr, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create zip reader: %w", err)
+	}
+	for _, f := range zr.File {
+		if f.Name == spxVersionFile {
+			rc, err := f.Open()
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to open spx version file: %w", err)
+			}
+			b, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to read spx version file: %w", err)
+			}
+			defaultVersion = strings.TrimSpace(string(b))
+			continue
+		}
+		dir, _, ok := strings.Cut(f.Name, "/")
+		if !ok {
+			continue
+		}
+		version, ok := strings.CutPrefix(dir, spxBundlePrefix)
+		if ok && semver.IsValid(version) && !slices.Contains(versions, version) {
+			versions = append(versions, version)
+		}
+	}
+	return defaultVersion, versions, nil
+}
@@ -0,0 +1,123 @@
+package pkgdata_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/goplus/xgolsw/internal/pkgdata"
+	"github.com/goplus/xgolsw/pkgdoc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/semver"
+)
+
+// setCustomPkgdataZip sets the custom package data to a zip file holding the
+// given files and package docs for the duration of the test.
+func setCustomPkgdataZip(t *testing.T, files map[string]string, pkgDocs map[string]*pkgdoc.PkgDoc) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	for name, pkgDoc := range pkgDocs {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		require.NoError(t, json.NewEncoder(w).Encode(pkgDoc))
+	}
+	require.NoError(t, zw.Close())
+
+	pkgdata.SetCustomPkgdataZip(buf.Bytes())
+	t.Cleanup(func() { pkgdata.SetCustomPkgdataZip(nil) })
+}
+
+func TestSpxVersions(t *testing.T) {
+	t.Run("Embedded", func(t *testing.T) {
+		assert.True(t, semver.IsValid(pkgdata.DefaultSpxVersion()))
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		setCustomPkgdataZip(t, map[string]string{"spx.version": "v2.2.0\n"}, map[string]*pkgdoc.PkgDoc{
+			"spx@v2.1.0/github.com/goplus/spx/v2.pkgdoc":                      {Path: pkgdata.SpxModulePath, Name: "spx"},
+			"spx@v2.0.0/github.com/goplus/spx/v2.pkgdoc":                      {Path: pkgdata.SpxModulePath, Name: "spx"},
+			"spx@v2.0.0/github.com/goplus/spx/v2/pkg/gdspx/pkg/engine.pkgdoc": {Path: pkgdata.SpxModulePath + "/pkg/gdspx/pkg/engine", Name: "engine"},
+		})
+
+		assert.Equal(t, "v2.2.0", pkgdata.DefaultSpxVersion())
+		assert.Equal(t, []string{"v2.0.0", "v2.1.0"}, pkgdata.SpxVersions())
+	})
+}
+
+func TestResolveSpxVersion(t *testing.T) {
+	setCustomPkgdataZip(t, map[string]string{"spx.version": "v2.2.0"}, map[string]*pkgdoc.PkgDoc{
+		"spx@v2.0.0/github.com/goplus/spx/v2.pkgdoc": {Path: pkgdata.SpxModulePath, Name: "spx"},
+		"spx@v2.1.0/github.com/goplus/spx/v2.pkgdoc": {Path: pkgdata.SpxModulePath, Name: "spx"},
+	})
+
+	for _, tt := range []struct {
+		version string
+		want    string
+	}{
+		{"v2.0.0", "v2.0.0"},
+		{"v2.0.5", "v2.0.0"},
+		{"v2.1.0", "v2.1.0"},
+		{"v2.1.0-rc.1", "v2.0.0"},
+		{"v1.0.0", "v2.0.0"},
+		{"v2.2.0", ""},
+		{"v3.0.0", ""},
+		{"2.0.0", ""},
+		{"", ""},
+	} {
+		assert.Equal(t, tt.want, pkgdata.ResolveSpxVersion(tt.version), tt.version)
+	}
+}
+
+func TestGetPkgDocFor(t *testing.T) {
+	const enginePkgPath = pkgdata.SpxModulePath + "/pkg/gdspx/pkg/engine"
+	setCustomPkgdataZip(t, map[string]string{"spx.version": "v2.2.0"}, map[string]*pkgdoc.PkgDoc{
+		"spx@v2.0.0/github.com/goplus/spx/v2.pkgdoc": {
+			Path: pkgdata.SpxModulePath,
+			Name: "spx",
+			Doc:  "Package spx as of v2.0.0.\n",
+		},
+	})
+
+	t.Run("Bundled", func(t *testing.T) {
+		pkgDoc, err := pkgdata.GetPkgDocFor(pkgdata.SpxModulePath, "v2.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, "Package spx as of v2.0.0.\n", pkgDoc.Doc)
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		want, err := pkgdata.GetPkgDoc(pkgdata.SpxModulePath)
+		require.NoError(t, err)
+
+		for _, version := range []string{"", "v2.2.0"} {
+			pkgDoc, err := pkgdata.GetPkgDocFor(pkgdata.SpxModulePath, version)
+			require.NoError(t, err)
+			assert.Same(t, want, pkgDoc, version)
+		}
+	})
+
+	t.Run("NotInBundle", func(t *testing.T) {
+		want, err := pkgdata.GetPkgDoc(enginePkgPath)
+		require.NoError(t, err)
+
+		pkgDoc, err := pkgdata.GetPkgDocFor(enginePkgPath, "v2.0.1")
+		require.NoError(t, err)
+		assert.Same(t, want, pkgDoc)
+	})
+
+	t.Run("NonSpxPkg", func(t *testing.T) {
+		want, err := pkgdata.GetPkgDoc("fmt")
+		require.NoError(t, err)
+
+		pkgDoc, err := pkgdata.GetPkgDocFor("fmt", "v2.0.1")
+		require.NoError(t, err)
+		assert.Same(t, want, pkgDoc)
+	})
+}
@@ -0,0 +1,429 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"maps"
+	"reflect"
+	"runtime"
+	"slices"
+	"sync"
+
+	xgoast "github.com/goplus/xgo/ast"
+	"github.com/goplus/xgolsw/internal/analysis/protocol"
+	"github.com/goplus/xgolsw/xgo"
+	"github.com/goplus/xgolsw/xgo/xgoutil"
+)
+
+// analyzerJob is a single analyzer to run on a single analysis unit.
+type analyzerJob struct {
+	unit     *analysisUnit
+	analyzer *protocol.Analyzer
+
+	diagnostics map[string][]Diagnostic
+}
+
+// analysisUnit holds the state for running analyzers on a unit of files,
+// which is either a single file or, for analyzers that use facts, all files of
+// the package. Each analyzer runs at most once per unit, so results of required
+// analyzers such as the inspect analyzer are shared by all analyzers that
+// depend on them.
+type analysisUnit struct {
+	proj       *xgo.Project
+	typeInfo   *xgo.TypeInfo
+	spxVersion string
+	paths      []string
+	astFiles   []*xgoast.File
+
+	// configs holds the configuration of diagnostics reported by each
+	// analyzer. Analyzers not listed here report errors.
+	configs map[*protocol.Analyzer]analyzerConfig
+
+	// toDocumentURI converts a file path to its document URI.
+	toDocumentURI func(path string) DocumentURI
+
+	// facts holds the facts exported by analyzers in the unit. It is nil for
+	// single file units.
+	facts *factStore
+
+	mu      sync.Mutex
+	actions map[*protocol.Analyzer]*analyzerAction
+}
+
+// newAnalysisUnit creates a new analysis unit for the given files, keyed by
+// their paths.
+func newAnalysisUnit(proj *xgo.Project, typeInfo *xgo.TypeInfo, spxVersion string, files map[string]*xgoast.File, paths []string, configs map[*protocol.Analyzer]analyzerConfig, toDocumentURI func(string) DocumentURI) *analysisUnit {
+	astFiles := make([]*xgoast.File, 0, len(paths))
+	for _, path := range paths {
+		astFiles = append(astFiles, files[path])
+	}
+	return &analysisUnit{
+		proj:          proj,
+		typeInfo:      typeInfo,
+		spxVersion:    spxVersion,
+		paths:         paths,
+		astFiles:      astFiles,
+		configs:       configs,
+		toDocumentURI: toDocumentURI,
+		actions:       make(map[*protocol.Analyzer]*analyzerAction),
+	}
+}
+
+// analyzerConfig holds the configuration of diagnostics reported by an
+// analyzer.
+type analyzerConfig struct {
+	// severity is the severity of diagnostics that do not specify one.
+	severity DiagnosticSeverity
+
+	// overrideSeverity indicates that severity also applies to diagnostics
+	// that specify one, as it was explicitly configured by the user.
+	overrideSeverity bool
+
+	tags []DiagnosticTag
+}
+
+// analyzerAction is the memoized outcome of running an analyzer on a unit.
+type analyzerAction struct {
+	once        sync.Once
+	result      any
+	err         error
+	diagnostics map[string][]Diagnostic
+}
+
+// action returns the action for an, creating it if necessary.
+func (u *analysisUnit) action(an *protocol.Analyzer) *analyzerAction {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	act, ok := u.actions[an]
+	if !ok {
+		act = &analyzerAction{}
+		u.actions[an] = act
+	}
+	return act
+}
+
+// run runs an and the analyzers it requires on the unit, unless they have
+// already been run. It is safe for concurrent use. A caller waiting for an
+// analyzer that is being run by another goroutine blocks until it is done.
+func (u *analysisUnit) run(an *protocol.Analyzer) *analyzerAction {
+	act := u.action(an)
+	act.once.Do(func() {
+		resultOf := make(map[*protocol.Analyzer]any, len(an.Requires))
+		for _, req := range an.Requires {
+			reqAct := u.run(req)
+			if reqAct.err != nil {
+				act.err = fmt.Errorf("required analyzer %q failed: %w", req.Name, reqAct.err)
+				return
+			}
+			resultOf[req] = reqAct.result
+		}
+
+		act.diagnostics = make(map[string][]Diagnostic)
+		pass := &protocol.Pass{
+			Analyzer:   an,
+			Fset:       u.proj.Fset,
+			Files:      u.astFiles,
+			Pkg:        u.typeInfo.Pkg(),
+			TypesInfo:  u.typeInfo,
+			SpxVersion: u.spxVersion,
+			Report: func(d protocol.Diagnostic) {
+				config, ok := u.configs[an]
+				if !ok {
+					config.severity = SeverityError
+				}
+				severity := config.severity
+				if d.Severity != 0 && !config.overrideSeverity {
+					severity = DiagnosticSeverity(d.Severity)
+				}
+				var code any
+				if d.Category != "" {
+					code = d.Category
+				}
+				// Diagnostics of package units are reported on the file
+				// containing them.
+				path := u.paths[0]
+				if len(u.paths) > 1 {
+					path = xgoutil.PosFilename(u.proj, d.Pos)
+				}
+				act.diagnostics[path] = append(act.diagnostics[path], Diagnostic{
+					Range:              RangeForPosEnd(u.proj, d.Pos, d.End),
+					Severity:           severity,
+					Code:               code,
+					Source:             an.Name,
+					Message:            d.Message,
+					Tags:               config.tags,
+					RelatedInformation: u.relatedInformation(d),
+					Data:               u.diagnosticData(d),
+				})
+			},
+			ResultOf: resultOf,
+		}
+		if u.facts != nil {
+			u.facts.bind(pass)
+		}
+		act.result, act.err = an.Run(pass)
+	})
+	return act
+}
+
+// factStore holds the facts exported by analyzers on a package. Facts are
+// private to the analyzer that exported them.
+type factStore struct {
+	mu           sync.Mutex
+	objectFacts  map[objectFactKey]protocol.Fact
+	packageFacts map[packageFactKey]protocol.Fact
+}
+
+// objectFactKey is the key of an object fact in a [factStore].
+type objectFactKey struct {
+	analyzer *protocol.Analyzer
+	obj      types.Object
+	typ      reflect.Type
+}
+
+// packageFactKey is the key of a package fact in a [factStore].
+type packageFactKey struct {
+	analyzer *protocol.Analyzer
+	pkg      *types.Package
+	typ      reflect.Type
+}
+
+// newFactStore creates a new empty fact store.
+func newFactStore() *factStore {
+	return &factStore{
+		objectFacts:  make(map[objectFactKey]protocol.Fact),
+		packageFacts: make(map[packageFactKey]protocol.Fact),
+	}
+}
+
+// bind sets the fact functions of pass to import and export facts of its
+// analyzer from and to the store.
+func (fs *factStore) bind(pass *protocol.Pass) {
+	an := pass.Analyzer
+	pass.ImportObjectFact = func(obj types.Object, ptr protocol.Fact) bool {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		fact, ok := fs.objectFacts[objectFactKey{an, obj, factType(an, ptr)}]
+		if ok {
+			reflect.ValueOf(ptr).Elem().Set(reflect.ValueOf(fact).Elem())
+		}
+		return ok
+	}
+	pass.ExportObjectFact = func(obj types.Object, fact protocol.Fact) {
+		if obj.Pkg() != pass.Pkg {
+			panic(fmt.Sprintf("%s: ExportObjectFact(%s, %T): can't set fact on object belonging to another package", an.Name, obj, fact))
+		}
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		fs.objectFacts[objectFactKey{an, obj, factType(an, fact)}] = fact
+	}
+	pass.ImportPackageFact = func(pkg *types.Package, ptr protocol.Fact) bool {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		fact, ok := fs.packageFacts[packageFactKey{an, pkg, factType(an, ptr)}]
+		if ok {
+			reflect.ValueOf(ptr).Elem().Set(reflect.ValueOf(fact).Elem())
+		}
+		return ok
+	}
+	pass.ExportPackageFact = func(fact protocol.Fact) {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		fs.packageFacts[packageFactKey{an, pass.Pkg, factType(an, fact)}] = fact
+	}
+	pass.AllObjectFacts = func() []protocol.ObjectFact {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		var facts []protocol.ObjectFact
+		for key, fact := range fs.objectFacts {
+			if key.analyzer == an {
+				facts = append(facts, protocol.ObjectFact{Object: key.obj, Fact: fact})
+			}
+		}
+		return facts
+	}
+	pass.AllPackageFacts = func() []protocol.PackageFact {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		var facts []protocol.PackageFact
+		for key, fact := range fs.packageFacts {
+			if key.analyzer == an {
+				facts = append(facts, protocol.PackageFact{Package: key.pkg, Fact: fact})
+			}
+		}
+		return facts
+	}
+}
+
+// factType returns the type of fact, which must be a pointer to one of the
+// fact types declared by an. It panics otherwise.
+func factType(an *protocol.Analyzer, fact protocol.Fact) reflect.Type {
+	t := reflect.TypeOf(fact)
+	if t == nil || t.Kind() != reflect.Pointer {
+		panic(fmt.Sprintf("%s: invalid fact type %T, must be a pointer", an.Name, fact))
+	}
+	for _, ft := range an.FactTypes {
+		if reflect.TypeOf(ft) == t {
+			return t
+		}
+	}
+	panic(fmt.Sprintf("%s: fact type %T is not declared in FactTypes", an.Name, fact))
+}
+
+// isPackageAnalyzer reports whether an runs once on all files of the package
+// instead of once on each file, which is the case for analyzers that use
+// facts.
+func isPackageAnalyzer(an *protocol.Analyzer) bool {
+	return len(an.FactTypes) > 0
+}
+
+// relatedInformation returns the related information of the analyzer
+// diagnostic d.
+func (u *analysisUnit) relatedInformation(d protocol.Diagnostic) []DiagnosticRelatedInformation {
+	if len(d.Related) == 0 {
+		return nil
+	}
+	relatedInfo := make([]DiagnosticRelatedInformation, 0, len(d.Related))
+	for _, related := range d.Related {
+		end := related.End
+		if !end.IsValid() {
+			end = related.Pos
+		}
+		relatedInfo = append(relatedInfo, DiagnosticRelatedInformation{
+			Location: Location{
+				URI:   u.toDocumentURI(xgoutil.PosFilename(u.proj, related.Pos)),
+				Range: RangeForPosEnd(u.proj, related.Pos, end),
+			},
+			Message: related.Message,
+		})
+	}
+	return relatedInfo
+}
+
+// analyzerDiagnosticData is the data of diagnostics reported by analyzers,
+// which is preserved by clients between publishing diagnostics and requesting
+// code actions for them.
+type analyzerDiagnosticData struct {
+	// Fixes holds the suggested fixes of the diagnostic.
+	Fixes []analyzerFix `json:"fixes"`
+}
+
+// analyzerFix is a suggested fix of an analyzer diagnostic.
+type analyzerFix struct {
+	Title string     `json:"title"`
+	Edits []TextEdit `json:"edits"`
+}
+
+// diagnosticData returns the data of the analyzer diagnostic d, which holds
+// its suggested fixes. It returns nil if d has no suggested fixes.
+func (u *analysisUnit) diagnosticData(d protocol.Diagnostic) *json.RawMessage {
+	if len(d.SuggestedFixes) == 0 {
+		return nil
+	}
+	var data analyzerDiagnosticData
+	for _, fix := range d.SuggestedFixes {
+		edits := make([]TextEdit, 0, len(fix.TextEdits))
+		for _, edit := range fix.TextEdits {
+			end := edit.End
+			if !end.IsValid() {
+				end = edit.Pos
+			}
+			edits = append(edits, TextEdit{
+				Range:   RangeForPosEnd(u.proj, edit.Pos, end),
+				NewText: string(edit.NewText),
+			})
+		}
+		data.Fixes = append(data.Fixes, analyzerFix{Title: fix.Message, Edits: edits})
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	raw := json.RawMessage(b)
+	return &raw
+}
+
+// runAnalyzers runs analyzers on each of files concurrently using a bounded
+// pool of workers, and returns the diagnostics for each file keyed by its
+// path. Diagnostics of a file are ordered by analyzer, in the order given by
+// analyzers, regardless of the order in which the analyzers finished.
+//
+// Analyzers that use facts run once on all files, so that they can reason
+// across files, and share a fact store. Other analyzers run once on each file.
+//
+// Only diagnostics reported by analyzers in analyzers are returned. Analyzers
+// that are merely required by them run as needed but their diagnostics are
+// discarded. A failed analyzer is reported as an error diagnostic on each file
+// it ran on.
+//
+// Diagnostics reported by an analyzer have the analyzer name as their source,
+// their category as their code, and the tags given for it in configs. Their
+// severity is the one they specify, unless configs overrides it, falling back
+// to the one given in configs. Analyzers not listed in configs report errors
+// by default. Locations of related information are converted to document URIs
+// using toDocumentURI. Analyzers are told that the files target spxVersion.
+func runAnalyzers(proj *xgo.Project, typeInfo *xgo.TypeInfo, spxVersion string, files map[string]*xgoast.File, analyzers []*protocol.Analyzer, configs map[*protocol.Analyzer]analyzerConfig, toDocumentURI func(string) DocumentURI) map[string][]Diagnostic {
+	paths := slices.Sorted(maps.Keys(files))
+	fileUnits := make([]*analysisUnit, 0, len(paths))
+	for _, path := range paths {
+		fileUnits = append(fileUnits, newAnalysisUnit(proj, typeInfo, spxVersion, files, []string{path}, configs, toDocumentURI))
+	}
+	var pkgUnit *analysisUnit
+	jobs := make([]*analyzerJob, 0, len(paths)*len(analyzers))
+	for _, an := range analyzers {
+		if isPackageAnalyzer(an) {
+			if len(paths) == 0 {
+				continue
+			}
+			if pkgUnit == nil {
+				pkgUnit = newAnalysisUnit(proj, typeInfo, spxVersion, files, paths, configs, toDocumentURI)
+				pkgUnit.facts = newFactStore()
+			}
+			jobs = append(jobs, &analyzerJob{unit: pkgUnit, analyzer: an})
+			continue
+		}
+		for _, unit := range fileUnits {
+			jobs = append(jobs, &analyzerJob{unit: unit, analyzer: an})
+		}
+	}
+
+	jobCh := make(chan *analyzerJob)
+	var wg sync.WaitGroup
+	for range min(len(jobs), runtime.GOMAXPROCS(0)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobCh {
+				act := job.unit.run(job.analyzer)
+				job.diagnostics = make(map[string][]Diagnostic, len(job.unit.paths))
+				for _, path := range job.unit.paths {
+					diags := act.diagnostics[path]
+					if act.err != nil {
+						diags = append(slices.Clip(diags), Diagnostic{
+							Severity: SeverityError,
+							Message:  fmt.Sprintf("analyzer %q failed: %v", job.analyzer.Name, act.err),
+						})
+					}
+					job.diagnostics[path] = diags
+				}
+			}
+		}()
+	}
+	for _, job := range jobs {
+		jobCh <- job
+	}
+	close(jobCh)
+	wg.Wait()
+
+	diagnostics := make(map[string][]Diagnostic, len(paths))
+	for _, path := range paths {
+		diagnostics[path] = nil
+	}
+	for _, job := range jobs {
+		for _, path := range job.unit.paths {
+			diagnostics[path] = append(diagnostics[path], job.diagnostics[path]...)
+		}
+	}
+	return diagnostics
+}
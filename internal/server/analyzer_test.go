@@ -0,0 +1,259 @@
+package server
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	xgoast "github.com/goplus/xgo/ast"
+	"github.com/goplus/xgolsw/internal/analysis/protocol"
+	"github.com/goplus/xgolsw/xgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAnalyzerTestProject(t *testing.T) (*xgo.Project, *xgo.TypeInfo, map[string]*xgoast.File) {
+	s := New(newMapFSWithoutModTime(map[string][]byte{
+		"main.spx":    []byte("echo 1\n"),
+		"Sprite1.spx": []byte("onStart => {}\n"),
+		"Sprite2.spx": []byte("onStart => {}\n"),
+	}), &mockReplier{}, nil, &MockScheduler{})
+	proj := s.getProj()
+	typeInfo, _ := proj.TypeInfo()
+	require.NotNil(t, typeInfo)
+	astPkg, _ := proj.ASTPackage()
+	require.NotNil(t, astPkg)
+	return proj, typeInfo, astPkg.Files
+}
+
+// testDocumentURI converts a file path to its document URI in tests.
+func testDocumentURI(path string) DocumentURI {
+	return DocumentURI("file:///" + path)
+}
+
+func TestRunAnalyzers(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		proj, typeInfo, files := newAnalyzerTestProject(t)
+
+		var requiredRuns atomic.Int32
+		required := &protocol.Analyzer{
+			Name: "required",
+			Run: func(pass *protocol.Pass) (any, error) {
+				requiredRuns.Add(1)
+				pass.Report(protocol.Diagnostic{Pos: pass.Files[0].Pos(), End: pass.Files[0].End(), Message: "discarded"})
+				return pass.Files[0], nil
+			},
+		}
+		newAnalyzer := func(name string) *protocol.Analyzer {
+			return &protocol.Analyzer{
+				Name:     name,
+				Requires: []*protocol.Analyzer{required},
+				Run: func(pass *protocol.Pass) (any, error) {
+					assert.Same(t, pass.Files[0], pass.ResultOf[required])
+					pass.Report(protocol.Diagnostic{Pos: pass.Files[0].Pos(), End: pass.Files[0].End(), Message: pass.Analyzer.Name})
+					return nil, nil
+				},
+			}
+		}
+		analyzers := []*protocol.Analyzer{newAnalyzer("a"), newAnalyzer("b"), newAnalyzer("c")}
+
+		diagnostics := runAnalyzers(proj, typeInfo, "", files, analyzers, nil, testDocumentURI)
+		assert.Equal(t, int32(len(files)), requiredRuns.Load())
+		require.Len(t, diagnostics, len(files))
+		for file := range files {
+			var messages []string
+			for _, diag := range diagnostics[file] {
+				messages = append(messages, diag.Message)
+			}
+			assert.Equal(t, []string{"a", "b", "c"}, messages, file)
+		}
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		proj, typeInfo, files := newAnalyzerTestProject(t)
+
+		required := &protocol.Analyzer{
+			Name: "required",
+			Run: func(pass *protocol.Pass) (any, error) {
+				return nil, errors.New("boom")
+			},
+		}
+		dependent := &protocol.Analyzer{
+			Name:     "dependent",
+			Requires: []*protocol.Analyzer{required},
+			Run: func(pass *protocol.Pass) (any, error) {
+				t.Error("dependent should not run")
+				return nil, nil
+			},
+		}
+		failing := &protocol.Analyzer{
+			Name: "failing",
+			Run: func(pass *protocol.Pass) (any, error) {
+				pass.Report(protocol.Diagnostic{Pos: pass.Files[0].Pos(), End: pass.Files[0].End(), Message: "reported"})
+				return nil, errors.New("oops")
+			},
+		}
+
+		diagnostics := runAnalyzers(proj, typeInfo, "", files, []*protocol.Analyzer{dependent, failing}, nil, testDocumentURI)
+		require.Len(t, diagnostics, len(files))
+		for file := range files {
+			diags := diagnostics[file]
+			require.Len(t, diags, 3, file)
+			assert.Equal(t, `analyzer "dependent" failed: required analyzer "required" failed: boom`, diags[0].Message)
+			assert.Equal(t, "reported", diags[1].Message)
+			assert.Equal(t, `analyzer "failing" failed: oops`, diags[2].Message)
+			for _, diag := range diags {
+				assert.Equal(t, SeverityError, diag.Severity)
+			}
+		}
+	})
+
+	t.Run("Configs", func(t *testing.T) {
+		proj, typeInfo, files := newAnalyzerTestProject(t)
+
+		newAnalyzer := func(name string) *protocol.Analyzer {
+			return &protocol.Analyzer{
+				Name: name,
+				Run: func(pass *protocol.Pass) (any, error) {
+					pass.Report(protocol.Diagnostic{Pos: pass.Files[0].Pos(), End: pass.Files[0].End(), Message: pass.Analyzer.Name})
+					return nil, nil
+				},
+			}
+		}
+		hint, unlisted := newAnalyzer("hint"), newAnalyzer("unlisted")
+
+		diagnostics := runAnalyzers(proj, typeInfo, "", files, []*protocol.Analyzer{hint, unlisted}, map[*protocol.Analyzer]analyzerConfig{
+			hint: {severity: SeverityHint, tags: []DiagnosticTag{Unnecessary}},
+		}, testDocumentURI)
+		require.Len(t, diagnostics, len(files))
+		for file := range files {
+			diags := diagnostics[file]
+			require.Len(t, diags, 2, file)
+			assert.Equal(t, SeverityHint, diags[0].Severity)
+			assert.Equal(t, []DiagnosticTag{Unnecessary}, diags[0].Tags)
+			assert.Equal(t, SeverityError, diags[1].Severity)
+			assert.Empty(t, diags[1].Tags)
+		}
+	})
+
+	t.Run("DiagnosticSeverity", func(t *testing.T) {
+		proj, typeInfo, files := newAnalyzerTestProject(t)
+
+		newAnalyzer := func(name string) *protocol.Analyzer {
+			return &protocol.Analyzer{
+				Name: name,
+				Run: func(pass *protocol.Pass) (any, error) {
+					pass.Report(protocol.Diagnostic{Pos: pass.Files[0].Pos(), End: pass.Files[0].End(), Message: "plain"})
+					pass.Report(protocol.Diagnostic{
+						Pos:      pass.Files[0].Pos(),
+						End:      pass.Files[0].End(),
+						Category: "style",
+						Message:  "styled",
+						Severity: protocol.SeverityInformation,
+					})
+					return nil, nil
+				},
+			}
+		}
+		defaulted, overridden, unlisted := newAnalyzer("defaulted"), newAnalyzer("overridden"), newAnalyzer("unlisted")
+
+		diagnostics := runAnalyzers(proj, typeInfo, "", files, []*protocol.Analyzer{defaulted, overridden, unlisted}, map[*protocol.Analyzer]analyzerConfig{
+			defaulted:  {severity: SeverityWarning},
+			overridden: {severity: SeverityHint, overrideSeverity: true},
+		}, testDocumentURI)
+		require.Len(t, diagnostics, len(files))
+		for file := range files {
+			diags := diagnostics[file]
+			require.Len(t, diags, 6, file)
+			assert.Equal(t, SeverityWarning, diags[0].Severity)
+			assert.Nil(t, diags[0].Code)
+			assert.Equal(t, SeverityInformation, diags[1].Severity)
+			assert.Equal(t, "style", diags[1].Code)
+			assert.Equal(t, SeverityHint, diags[2].Severity)
+			assert.Equal(t, SeverityHint, diags[3].Severity)
+			assert.Equal(t, "style", diags[3].Code)
+			assert.Equal(t, SeverityError, diags[4].Severity)
+			assert.Equal(t, SeverityInformation, diags[5].Severity)
+		}
+	})
+
+	t.Run("Facts", func(t *testing.T) {
+		proj, typeInfo, files := newAnalyzerTestProject(t)
+
+		var runs atomic.Int32
+		an := &protocol.Analyzer{
+			Name:      "facts",
+			FactTypes: []protocol.Fact{new(testFact)},
+			Run: func(pass *protocol.Pass) (any, error) {
+				runs.Add(1)
+				require.Len(t, pass.Files, len(files))
+				require.NotNil(t, pass.Pkg)
+
+				sprite1 := pass.Pkg.Scope().Lookup("Sprite1")
+				require.NotNil(t, sprite1)
+				var fact testFact
+				assert.False(t, pass.ImportObjectFact(sprite1, &fact))
+				pass.ExportObjectFact(sprite1, &testFact{Value: "Sprite1"})
+				require.True(t, pass.ImportObjectFact(sprite1, &fact))
+				assert.Equal(t, "Sprite1", fact.Value)
+
+				pass.ExportPackageFact(&testFact{Value: "main"})
+				require.True(t, pass.ImportPackageFact(pass.Pkg, &fact))
+				assert.Equal(t, "main", fact.Value)
+				assert.Len(t, pass.AllObjectFacts(), 1)
+				assert.Len(t, pass.AllPackageFacts(), 1)
+
+				assert.Panics(t, func() {
+					pass.ExportPackageFact(&otherTestFact{})
+				})
+
+				for _, f := range pass.Files {
+					pass.Report(protocol.Diagnostic{Pos: f.Pos(), End: f.Pos(), Message: "facts"})
+				}
+				return nil, nil
+			},
+		}
+		perFile := &protocol.Analyzer{
+			Name: "perFile",
+			Run: func(pass *protocol.Pass) (any, error) {
+				assert.Len(t, pass.Files, 1)
+				assert.Nil(t, pass.ImportObjectFact)
+				pass.Report(protocol.Diagnostic{Pos: pass.Files[0].Pos(), End: pass.Files[0].Pos(), Message: "perFile"})
+				return nil, nil
+			},
+		}
+
+		diagnostics := runAnalyzers(proj, typeInfo, "", files, []*protocol.Analyzer{an, perFile}, nil, testDocumentURI)
+		assert.Equal(t, int32(1), runs.Load())
+		require.Len(t, diagnostics, len(files))
+		for file := range files {
+			var messages []string
+			for _, diag := range diagnostics[file] {
+				messages = append(messages, diag.Message)
+			}
+			assert.Equal(t, []string{"facts", "perFile"}, messages, file)
+		}
+	})
+
+	t.Run("NoAnalyzers", func(t *testing.T) {
+		proj, typeInfo, files := newAnalyzerTestProject(t)
+
+		diagnostics := runAnalyzers(proj, typeInfo, "", files, nil, nil, testDocumentURI)
+		require.Len(t, diagnostics, len(files))
+		for file := range files {
+			assert.Empty(t, diagnostics[file])
+		}
+	})
+}
+
+// testFact is a fact used in tests.
+type testFact struct {
+	Value string
+}
+
+func (*testFact) AFact() {}
+
+// otherTestFact is a fact used in tests that is not declared by any analyzer.
+type otherTestFact struct{}
+
+func (*otherTestFact) AFact() {}
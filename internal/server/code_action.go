@@ -0,0 +1,377 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	xgoast "github.com/goplus/xgo/ast"
+	xgotoken "github.com/goplus/xgo/token"
+	"github.com/goplus/xgolsw/xgo"
+)
+
+// spxSpriteResourceStub is the metadata of a newly scaffolded spx sprite
+// resource.
+const spxSpriteResourceStub = `{
+  "heading": 90,
+  "x": 0,
+  "y": 0,
+  "size": 1,
+  "rotationStyle": "normal",
+  "costumeIndex": 0,
+  "visible": true,
+  "isDraggable": false,
+  "pivot": {"x": 0, "y": 0},
+  "costumes": [],
+  "fAnimations": {},
+  "animBindings": {}
+}
+`
+
+// spxSoundResourceStub is the metadata of a newly scaffolded spx sound
+// resource.
+const spxSoundResourceStub = `{
+  "rate": 0,
+  "sampleCount": 0,
+  "path": ""
+}
+`
+
+// See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#textDocument_codeAction
+func (s *Server) textDocumentCodeAction(ctx context.Context, params *CodeActionParams) ([]CodeAction, error) {
+	only := params.Context.Only
+	includeQuickFixes := isCodeActionKindRequested(only, QuickFix)
+	includeExtractVariable := isCodeActionKindRequested(only, codeActionKindRefactorExtractVariable)
+	includeExtractFunction := isCodeActionKindRequested(only, codeActionKindRefactorExtractFunction)
+	includeExtractConstant := isCodeActionKindRequested(only, codeActionKindRefactorExtractConstant)
+	includeRewriteCallSyntax := isCodeActionKindRequested(only, RefactorRewrite)
+	includeOrganizeImports := isCodeActionKindRequested(only, SourceOrganizeImports)
+	includeAddFileHeader := isCodeActionKindRequested(only, codeActionKindSourceAddFileHeader)
+	if !includeQuickFixes && !includeExtractVariable && !includeExtractFunction && !includeExtractConstant && !includeRewriteCallSyntax && !includeOrganizeImports && !includeAddFileHeader {
+		return nil, nil
+	}
+
+	result, spxFile, astFile, err := s.compileAndGetASTFileForDocumentURI(ctx, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	if astFile == nil {
+		return nil, nil
+	}
+	var codeActions []CodeAction
+	if includeQuickFixes {
+		codeActions = append(codeActions, s.spxCreateResourceCodeActions(result, params.TextDocument.URI, params.Range)...)
+		codeActions = append(codeActions, s.spxMoveResourceBindingCodeActions(result, astFile, params.TextDocument.URI, params.Range)...)
+		codeActions = append(codeActions, analyzerFixCodeActions(result, params.TextDocument.URI, params.Range)...)
+	}
+	if includeExtractVariable {
+		codeActions = append(codeActions, s.spxExtractVariableCodeActions(result, astFile, params.TextDocument.URI, params.Range)...)
+	}
+	if includeExtractFunction {
+		codeActions = append(codeActions, s.spxExtractFunctionCodeActions(result, astFile, params.TextDocument.URI, params.Range)...)
+	}
+	if includeExtractConstant {
+		codeActions = append(codeActions, s.spxExtractResourceNameCodeActions(result, astFile, params.Range)...)
+	}
+	if includeRewriteCallSyntax {
+		codeActions = append(codeActions, s.spxRewriteCallSyntaxCodeActions(result, astFile, params.TextDocument.URI, params.Range)...)
+	}
+	if includeOrganizeImports {
+		if textEdits := s.spxOrganizeImportsEdits(result, astFile); textEdits != nil {
+			codeActions = append(codeActions, CodeAction{
+				Title: "Organize imports",
+				Kind:  SourceOrganizeImports,
+				Edit: &WorkspaceEdit{
+					Changes: map[DocumentURI][]TextEdit{params.TextDocument.URI: textEdits},
+				},
+			})
+		}
+	}
+	if includeAddFileHeader {
+		codeActions = append(codeActions, s.spxAddFileHeaderCodeActions(result, spxFile, astFile, params.TextDocument.URI)...)
+	}
+	return codeActions, nil
+}
+
+// analyzerFixCodeActions returns quick fixes that apply the fixes suggested
+// by analyzers for the diagnostics in the given range of the document.
+func analyzerFixCodeActions(result *compileResult, documentURI DocumentURI, rng Range) []CodeAction {
+	var codeActions []CodeAction
+	for _, diagnostic := range result.diagnostics[documentURI] {
+		if diagnostic.Source == "" || diagnostic.Data == nil || !IsRangesOverlap(diagnostic.Range, rng) {
+			continue
+		}
+		var data analyzerDiagnosticData
+		if err := json.Unmarshal(*diagnostic.Data, &data); err != nil {
+			continue
+		}
+		for _, fix := range data.Fixes {
+			codeActions = append(codeActions, CodeAction{
+				Title:       fix.Title,
+				Kind:        QuickFix,
+				Diagnostics: []Diagnostic{diagnostic},
+				IsPreferred: len(data.Fixes) == 1,
+				Edit: &WorkspaceEdit{
+					Changes: map[DocumentURI][]TextEdit{documentURI: fix.Edits},
+				},
+			})
+		}
+	}
+	return codeActions
+}
+
+// spxCreateResourceCodeActions returns quick fixes that scaffold the missing
+// spx sprite and sound resources referenced in the given range of the document.
+func (s *Server) spxCreateResourceCodeActions(result *compileResult, documentURI DocumentURI, rng Range) []CodeAction {
+	if !s.clientSupportsResourceOperation(CreateResourceOperation) {
+		return nil
+	}
+
+	var (
+		codeActions []CodeAction
+		seenIDs     = make(map[SpxResourceID]struct{})
+	)
+	for _, ref := range result.spxResourceRefs {
+		var (
+			resourceKind  string
+			resourceDir   string
+			resourceStub  string
+			diagnosticMsg string
+		)
+		switch id := ref.ID.(type) {
+		case SpxSpriteResourceID:
+			if result.spxResourceSet.Sprite(id.SpriteName) != nil {
+				continue
+			}
+			resourceKind = "sprite"
+			resourceDir = path.Join("sprites", id.SpriteName)
+			resourceStub = spxSpriteResourceStub
+			diagnosticMsg = fmt.Sprintf("%s resource %q not found", resourceKind, id.SpriteName)
+		case SpxSoundResourceID:
+			if result.spxResourceSet.Sound(id.SoundName) != nil {
+				continue
+			}
+			resourceKind = "sound"
+			resourceDir = path.Join("sounds", id.SoundName)
+			resourceStub = spxSoundResourceStub
+			diagnosticMsg = fmt.Sprintf("%s resource %q not found", resourceKind, id.SoundName)
+		default:
+			continue
+		}
+		if _, ok := seenIDs[ref.ID]; ok {
+			continue
+		}
+		if s.nodeDocumentURI(result.proj, ref.Node) != documentURI {
+			continue
+		}
+		refRange := RangeForNode(result.proj, ref.Node)
+		if !IsRangesOverlap(refRange, rng) {
+			continue
+		}
+		seenIDs[ref.ID] = struct{}{}
+
+		var diagnostics []Diagnostic
+		for _, diagnostic := range result.diagnostics[documentURI] {
+			if diagnostic.Range == refRange && diagnostic.Code == diagnosticCodeSpxResourceNotFound && diagnostic.Message == diagnosticMsg {
+				diagnostics = append(diagnostics, diagnostic)
+			}
+		}
+
+		metadataFile := path.Join(result.spxResourceRootDir, resourceDir, "index.json")
+		if _, ok := result.proj.File(metadataFile); ok {
+			continue
+		}
+		metadataURI := s.toDocumentURI(metadataFile)
+		codeActions = append(codeActions, CodeAction{
+			Title:       fmt.Sprintf("Create %s resource %q", resourceKind, ref.ID.Name()),
+			Kind:        QuickFix,
+			Diagnostics: diagnostics,
+			IsPreferred: true,
+			Edit: &WorkspaceEdit{
+				DocumentChanges: []DocumentChange{
+					{CreateFile: &CreateFile{
+						Kind: "create",
+						URI:  metadataURI,
+					}},
+					{TextDocumentEdit: &TextDocumentEdit{
+						TextDocument: OptionalVersionedTextDocumentIdentifier{
+							TextDocumentIdentifier: TextDocumentIdentifier{URI: metadataURI},
+						},
+						Edits: []Or_TextDocumentEdit_edits_Elem{{Value: TextEdit{NewText: resourceStub}}},
+					}},
+				},
+			},
+		})
+	}
+	return codeActions
+}
+
+// spxMoveResourceBindingCodeActions returns quick fixes that move the spx
+// resource variables reported as not defined in the first var block of the
+// AST file into it, creating the block if needed, so they are auto-bound.
+//
+// Only variables declared alone in a top-level var spec without an initial
+// value are moved, as moving others would change the meaning of the code. Var
+// declarations among the statements of the shadow entry count as top-level.
+func (s *Server) spxMoveResourceBindingCodeActions(result *compileResult, astFile *xgoast.File, documentURI DocumentURI, rng Range) []CodeAction {
+	var codeActions []CodeAction
+	for _, diagnostic := range result.diagnostics[documentURI] {
+		if diagnostic.Code != diagnosticCodeSpxAutoBindOrder || !IsRangesOverlap(diagnostic.Range, rng) {
+			continue
+		}
+
+		pos := PosAt(result.proj, astFile, diagnostic.Range.Start)
+		var (
+			genDecl   *xgoast.GenDecl
+			valueSpec *xgoast.ValueSpec
+		)
+		for _, d := range topLevelVarDecls(astFile) {
+			if d.Pos() <= pos && pos < d.End() {
+				genDecl = d
+				break
+			}
+		}
+		if genDecl == nil {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			if spec, ok := spec.(*xgoast.ValueSpec); ok && spec.Pos() <= pos && pos < spec.End() {
+				valueSpec = spec
+				break
+			}
+		}
+		if valueSpec == nil || len(valueSpec.Names) != 1 || len(valueSpec.Values) > 0 {
+			continue
+		}
+
+		textEdits := spxMoveValueSpecToClassFieldsDeclEdits(result.proj, astFile, genDecl, valueSpec)
+		if textEdits == nil {
+			continue
+		}
+		codeActions = append(codeActions, CodeAction{
+			Title:       fmt.Sprintf("Move %q into the first var block", valueSpec.Names[0].Name),
+			Kind:        QuickFix,
+			Diagnostics: []Diagnostic{diagnostic},
+			IsPreferred: true,
+			Edit: &WorkspaceEdit{
+				Changes: map[DocumentURI][]TextEdit{documentURI: textEdits},
+			},
+		})
+	}
+	return codeActions
+}
+
+// topLevelVarDecls returns the top-level var declarations of astFile, including
+// those among the statements of its shadow entry.
+func topLevelVarDecls(astFile *xgoast.File) []*xgoast.GenDecl {
+	var decls []*xgoast.GenDecl
+	for _, decl := range astFile.Decls {
+		if d, ok := decl.(*xgoast.GenDecl); ok && d.Tok == xgotoken.VAR {
+			decls = append(decls, d)
+		}
+	}
+	if shadowEntry := astFile.ShadowEntry; shadowEntry != nil && shadowEntry.Body != nil {
+		for _, stmt := range shadowEntry.Body.List {
+			if declStmt, ok := stmt.(*xgoast.DeclStmt); ok {
+				if d, ok := declStmt.Decl.(*xgoast.GenDecl); ok && d.Tok == xgotoken.VAR {
+					decls = append(decls, d)
+				}
+			}
+		}
+	}
+	return decls
+}
+
+// spxMoveValueSpecToClassFieldsDeclEdits returns the text edits that move
+// valueSpec of genDecl into the first var block of astFile, which is created
+// before the first declaration other than imports if it does not exist. It
+// returns nil if the move is not possible.
+func spxMoveValueSpecToClassFieldsDeclEdits(proj *xgo.Project, astFile *xgoast.File, genDecl *xgoast.GenDecl, valueSpec *xgoast.ValueSpec) []TextEdit {
+	code := astFile.Code
+	offsetOf := func(pos xgotoken.Pos) int {
+		return proj.Fset.Position(pos).Offset
+	}
+	positionOf := func(offset int) Position {
+		return OffsetPosition(code, offset)
+	}
+	specText := string(code[offsetOf(valueSpec.Pos()):offsetOf(valueSpec.End())])
+
+	// Remove the spec, or the whole declaration if it is the only spec.
+	removed := xgoast.Node(valueSpec)
+	if len(genDecl.Specs) == 1 {
+		removed = genDecl
+	}
+	removeStart, removeEnd := lineExtent(code, offsetOf(removed.Pos()), offsetOf(removed.End()))
+	textEdits := []TextEdit{{
+		Range: Range{Start: positionOf(removeStart), End: positionOf(removeEnd)},
+	}}
+
+	if classFieldsDecl := astFile.ClassFieldsDecl(); classFieldsDecl != nil {
+		if classFieldsDecl.Rparen.IsValid() {
+			// Append the spec to the parenthesized block.
+			rparen := offsetOf(classFieldsDecl.Rparen)
+			lineStart := bytes.LastIndexByte(code[:rparen], '\n') + 1
+			insert := Position{}
+			newText := "\t" + specText + "\n"
+			if len(bytes.TrimSpace(code[lineStart:rparen])) == 0 {
+				insert = positionOf(lineStart)
+			} else {
+				insert = positionOf(rparen)
+				newText = "\n" + newText
+			}
+			textEdits = append(textEdits, TextEdit{
+				Range:   Range{Start: insert, End: insert},
+				NewText: newText,
+			})
+		} else {
+			// Turn the single-spec declaration into a parenthesized block.
+			existingSpec := classFieldsDecl.Specs[0]
+			existingText := string(code[offsetOf(existingSpec.Pos()):offsetOf(existingSpec.End())])
+			textEdits = append(textEdits, TextEdit{
+				Range: Range{
+					Start: positionOf(offsetOf(classFieldsDecl.Pos())),
+					End:   positionOf(offsetOf(classFieldsDecl.End())),
+				},
+				NewText: "var (\n\t" + existingText + "\n\t" + specText + "\n)",
+			})
+		}
+	} else {
+		// Create the block before the first declaration other than imports.
+		var anchor xgoast.Decl
+		for _, decl := range astFile.Decls {
+			if d, ok := decl.(*xgoast.GenDecl); ok && d.Tok == xgotoken.IMPORT {
+				continue
+			}
+			anchor = decl
+			break
+		}
+		if anchor == nil || !anchor.Pos().IsValid() {
+			return nil
+		}
+		anchorStart, _ := lineExtent(code, offsetOf(anchor.Pos()), offsetOf(anchor.Pos()))
+		insert := positionOf(anchorStart)
+		textEdits = append(textEdits, TextEdit{
+			Range:   Range{Start: insert, End: insert},
+			NewText: "var (\n\t" + specText + "\n)\n\n",
+		})
+	}
+
+	return textEdits
+}
+
+// lineExtent extends the range [start, end) of code to whole lines, including
+// the trailing newline, if the range is the only content on its lines apart
+// from whitespace. Otherwise it returns the range unchanged.
+func lineExtent(code []byte, start, end int) (int, int) {
+	lineStart := bytes.LastIndexByte(code[:start], '\n') + 1
+	lineEnd := len(code)
+	if i := bytes.IndexByte(code[end:], '\n'); i >= 0 {
+		lineEnd = end + i + 1
+	}
+	if len(bytes.TrimSpace(code[lineStart:start])) > 0 || len(bytes.TrimSpace(code[end:lineEnd])) > 0 {
+		return start, end
+	}
+	return lineStart, lineEnd
+}
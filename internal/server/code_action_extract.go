@@ -0,0 +1,876 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"go/constant"
+	"go/types"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	xgoast "github.com/goplus/xgo/ast"
+	xgotoken "github.com/goplus/xgo/token"
+	"github.com/goplus/xgolsw/xgo"
+	"github.com/goplus/xgolsw/xgo/xgoutil"
+)
+
+const (
+	// codeActionKindRefactorExtractVariable is the kind of code actions that
+	// extract an expression into a new local variable.
+	codeActionKindRefactorExtractVariable = RefactorExtract + ".variable"
+
+	// codeActionKindRefactorExtractFunction is the kind of code actions that
+	// extract statements into a new function.
+	codeActionKindRefactorExtractFunction = RefactorExtract + ".function"
+
+	// codeActionKindRefactorExtractConstant is the kind of code actions that
+	// extract spx resource names into a new constant.
+	codeActionKindRefactorExtractConstant = RefactorExtract + ".constant"
+)
+
+// isCodeActionKindRequested reports whether code actions of the given kind are
+// requested by only. Kinds are matched hierarchically, so "refactor" requests
+// "refactor.extract.variable". An empty only requests all kinds.
+func isCodeActionKindRequested(only []CodeActionKind, kind CodeActionKind) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, k := range only {
+		if k == kind || strings.HasPrefix(string(kind), string(k)+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// selectionInterval returns the positions of the given range in astFile with
+// surrounding whitespace trimmed. It returns false if the range is empty.
+func selectionInterval(proj *xgo.Project, astFile *xgoast.File, rng Range) (start, end xgotoken.Pos, ok bool) {
+	start = PosAt(proj, astFile, rng.Start)
+	end = PosAt(proj, astFile, rng.End)
+	tokenFile := xgoutil.NodeTokenFile(proj, astFile)
+	code := astFile.Code
+	startOffset, endOffset := tokenFile.Offset(start), tokenFile.Offset(end)
+	for startOffset < endOffset && unicode.IsSpace(rune(code[startOffset])) {
+		startOffset++
+	}
+	for endOffset > startOffset && unicode.IsSpace(rune(code[endOffset-1])) {
+		endOffset--
+	}
+	if startOffset >= endOffset {
+		return 0, 0, false
+	}
+	return tokenFile.Pos(startOffset), tokenFile.Pos(endOffset), true
+}
+
+// enclosingListStmt returns the innermost statement in path that belongs to a
+// statement list, along with its index in path. It returns -1 if there is none.
+func enclosingListStmt(path []xgoast.Node) (xgoast.Stmt, int) {
+	for i := 0; i+1 < len(path); i++ {
+		stmt, ok := path[i].(xgoast.Stmt)
+		if !ok {
+			continue
+		}
+		switch path[i+1].(type) {
+		case *xgoast.BlockStmt, *xgoast.CaseClause, *xgoast.CommClause:
+			return stmt, i
+		}
+	}
+	return nil, -1
+}
+
+// stmtIndent returns the indentation of the line where stmt starts. It returns
+// false if stmt is not the first thing on its line.
+func stmtIndent(proj *xgo.Project, astFile *xgoast.File, stmt xgoast.Stmt) (int, string, bool) {
+	code := astFile.Code
+	offset := proj.Fset.Position(stmt.Pos()).Offset
+	lineStart := bytes.LastIndexByte(code[:offset], '\n') + 1
+	indent := code[lineStart:offset]
+	if len(bytes.TrimSpace(indent)) > 0 {
+		return 0, "", false
+	}
+	return lineStart, string(indent), true
+}
+
+// spxExtractVariableCodeActions returns refactorings that extract the
+// expression selected by rng into a new local variable declared right before
+// the enclosing statement.
+func (s *Server) spxExtractVariableCodeActions(result *compileResult, astFile *xgoast.File, documentURI DocumentURI, rng Range) []CodeAction {
+	typeInfo, _ := result.proj.TypeInfo()
+	if typeInfo == nil {
+		return nil
+	}
+	start, end, ok := selectionInterval(result.proj, astFile, rng)
+	if !ok {
+		return nil
+	}
+	path, _ := xgoutil.PathEnclosingInterval(astFile, start, end)
+	if len(path) < 2 {
+		return nil
+	}
+	expr, ok := path[0].(xgoast.Expr)
+	if !ok || expr.Pos() != start || expr.End() != end {
+		return nil
+	}
+	tv, ok := typeInfo.Types[expr]
+	if !ok || !tv.IsValue() || tv.Type == nil {
+		return nil
+	}
+	if _, ok := tv.Type.(*types.Tuple); ok {
+		return nil
+	}
+	if !isExtractableExpr(path) {
+		return nil
+	}
+	stmt, stmtIdx := enclosingListStmt(path)
+	if stmt == nil || !isHoistableFrom(path[:stmtIdx+1]) {
+		return nil
+	}
+
+	// The new variable is declared before stmt, so expr must not use anything
+	// declared by stmt itself.
+	usesStmtDecl := false
+	xgoast.Inspect(expr, func(node xgoast.Node) bool {
+		if ident, ok := node.(*xgoast.Ident); ok {
+			if obj := typeInfo.ObjectOf(ident); obj != nil && stmt.Pos() <= obj.Pos() && obj.Pos() < stmt.End() {
+				usesStmtDecl = true
+			}
+		}
+		return !usesStmtDecl
+	})
+	if usesStmtDecl {
+		return nil
+	}
+
+	lineStart, indent, ok := stmtIndent(result.proj, astFile, stmt)
+	if !ok {
+		return nil
+	}
+
+	name := uniqueLocalName(result.proj, path[stmtIdx+1], stmt.Pos(), extractedVarBaseName(typeInfo, expr, tv))
+	exprText := string(astFile.Code[result.proj.Fset.Position(expr.Pos()).Offset:result.proj.Fset.Position(expr.End()).Offset])
+	declText := name + " := " + exprText
+	if typ := extractedVarDeclType(typeInfo, path, tv); typ != nil {
+		declText = fmt.Sprintf("var %s %s = %s", name, extractedTypeString(typ), exprText)
+	}
+
+	insertPos := OffsetPosition(astFile.Code, lineStart)
+	return []CodeAction{{
+		Title: fmt.Sprintf("Extract variable %q", name),
+		Kind:  codeActionKindRefactorExtractVariable,
+		Edit: &WorkspaceEdit{
+			Changes: map[DocumentURI][]TextEdit{
+				documentURI: {
+					{
+						Range:   Range{Start: insertPos, End: insertPos},
+						NewText: indent + declText + "\n",
+					},
+					{
+						Range:   RangeForASTFileNode(result.proj, astFile, expr),
+						NewText: name,
+					},
+				},
+			},
+		},
+	}}
+}
+
+// isExtractableExpr reports whether the expression at path[0] can be replaced
+// with a variable without changing what its parent means.
+func isExtractableExpr(path []xgoast.Node) bool {
+	expr := path[0]
+	switch parent := path[1].(type) {
+	case *xgoast.ExprStmt:
+		return false // The value would be left unused.
+	case *xgoast.AssignStmt:
+		for _, lhs := range parent.Lhs {
+			if lhs == expr {
+				return false
+			}
+		}
+	case *xgoast.IncDecStmt:
+		return parent.X != expr
+	case *xgoast.RangeStmt:
+		return parent.Key != expr && parent.Value != expr
+	case *xgoast.UnaryExpr:
+		return parent.Op != xgotoken.AND
+	case *xgoast.SelectorExpr:
+		return parent.Sel != expr
+	case *xgoast.CallExpr:
+		return parent.Fun != expr
+	case *xgoast.KeyValueExpr:
+		if parent.Key == expr {
+			// Struct field names in composite literals are not values.
+			if _, ok := path[2].(*xgoast.CompositeLit); ok {
+				return false
+			}
+		}
+	case *xgoast.ValueSpec, *xgoast.Field, *xgoast.LabeledStmt, *xgoast.BranchStmt:
+		return false
+	}
+	return true
+}
+
+// isHoistableFrom reports whether the expression at path[0] is evaluated
+// exactly once each time the statement at path[len(path)-1] runs, so that it
+// can be evaluated right before the statement instead.
+func isHoistableFrom(path []xgoast.Node) bool {
+	for i := 0; i+1 < len(path); i++ {
+		child := path[i]
+		switch parent := path[i+1].(type) {
+		case *xgoast.ForStmt:
+			if child == parent.Cond || child == parent.Post || child == parent.Body {
+				return false
+			}
+		case *xgoast.IfStmt:
+			if child == parent.Else || child == parent.Body {
+				return false
+			}
+		case *xgoast.RangeStmt:
+			if child == parent.Body {
+				return false
+			}
+		case *xgoast.CaseClause, *xgoast.CommClause, *xgoast.FuncLit, *xgoast.LambdaExpr, *xgoast.LambdaExpr2, *xgoast.BlockStmt:
+			return false
+		}
+	}
+	return true
+}
+
+// extractedVarBaseName infers a name for a variable holding the value of expr.
+func extractedVarBaseName(typeInfo *xgo.TypeInfo, expr xgoast.Expr, tv types.TypeAndValue) string {
+	var name string
+	switch expr := expr.(type) {
+	case *xgoast.CallExpr:
+		var funIdent *xgoast.Ident
+		switch fun := expr.Fun.(type) {
+		case *xgoast.Ident:
+			funIdent = fun
+		case *xgoast.SelectorExpr:
+			funIdent = fun.Sel
+		}
+		if funIdent != nil && typeInfo.ObjectOf(funIdent) != nil {
+			if _, ok := typeInfo.ObjectOf(funIdent).(*types.TypeName); !ok {
+				name, _ = xgoutil.ParseXGoFuncName(funIdent.Name)
+				if rest, ok := strings.CutPrefix(name, "get"); ok && rest != "" && unicode.IsUpper(rune(rest[0])) {
+					name = rest
+				}
+			}
+		}
+	case *xgoast.SelectorExpr:
+		name = expr.Sel.Name
+	}
+	if name == "" {
+		if named, ok := xgoutil.DerefType(tv.Type).(*types.Named); ok {
+			name = named.Obj().Name()
+		}
+	}
+	name = xgoutil.ToLowerCamelCase(name)
+	if !xgotoken.IsIdentifier(name) {
+		return "v"
+	}
+	return name
+}
+
+// uniqueLocalName returns base, or base followed by a number, so that it does
+// not clash with anything visible at pos or any identifier used in block.
+func uniqueLocalName(proj *xgo.Project, block xgoast.Node, pos xgotoken.Pos, base string) string {
+	used := make(map[string]struct{})
+	xgoast.Inspect(block, func(node xgoast.Node) bool {
+		if ident, ok := node.(*xgoast.Ident); ok {
+			used[ident.Name] = struct{}{}
+		}
+		return true
+	})
+	scope := xgoutil.InnermostScopeAt(proj, pos)
+	isUsed := func(name string) bool {
+		if _, ok := used[name]; ok {
+			return true
+		}
+		if scope != nil {
+			if _, obj := scope.LookupParent(name, pos); obj != nil {
+				return true
+			}
+		}
+		return false
+	}
+	name := base
+	for i := 1; isUsed(name); i++ {
+		name = base + strconv.Itoa(i)
+	}
+	return name
+}
+
+// extractedVarDeclType returns the type that must be spelled out when
+// declaring a variable for the constant expression at path[0], which is the
+// type the constant is converted to in its context. It returns nil if the type
+// inferred with ":=" is already the same.
+func extractedVarDeclType(typeInfo *xgo.TypeInfo, path []xgoast.Node, tv types.TypeAndValue) types.Type {
+	if tv.Value == nil {
+		return nil
+	}
+	expr := path[0].(xgoast.Expr)
+	typ := tv.Type
+	if basic, ok := typ.(*types.Basic); ok && basic.Info()&types.IsUntyped != 0 {
+		// Untyped constants passed to XGo command-style calls may be recorded
+		// as untyped, so look up the parameter type instead.
+		typ = nil
+		if callExpr, ok := path[1].(*xgoast.CallExpr); ok {
+			xgoutil.WalkCallExprArgs(typeInfo, callExpr, func(fun *types.Func, params *types.Tuple, paramIndex int, arg xgoast.Expr, argIndex int) bool {
+				if arg != expr {
+					return true
+				}
+				typ = params.At(paramIndex).Type()
+				if fun.Signature().Variadic() && paramIndex == params.Len()-1 && !callExpr.Ellipsis.IsValid() {
+					if slice, ok := typ.(*types.Slice); ok {
+						typ = slice.Elem()
+					}
+				}
+				return false
+			})
+		}
+		if typ == nil {
+			return nil
+		}
+	}
+	if _, ok := typ.Underlying().(*types.Interface); ok || types.Identical(typ, untypedConstDefaultType(expr, tv.Value)) {
+		return nil
+	}
+	return typ
+}
+
+// untypedConstDefaultType returns the type that an untyped constant expression
+// with the given value gets when declared with ":=".
+func untypedConstDefaultType(expr xgoast.Expr, value constant.Value) types.Type {
+	if lit, ok := expr.(*xgoast.BasicLit); ok && lit.Kind == xgotoken.CHAR {
+		return types.Universe.Lookup("rune").Type()
+	}
+	switch value.Kind() {
+	case constant.Bool:
+		return types.Typ[types.Bool]
+	case constant.String:
+		return types.Typ[types.String]
+	case constant.Int:
+		return types.Typ[types.Int]
+	case constant.Float:
+		return types.Typ[types.Float64]
+	case constant.Complex:
+		return types.Typ[types.Complex128]
+	}
+	return types.Typ[types.Invalid]
+}
+
+// extractedTypeString returns the string representation of typ to be used in
+// extracted code, with the spx and main package names omitted.
+func extractedTypeString(typ types.Type) string {
+	return types.TypeString(typ, func(p *types.Package) string {
+		if p == GetSpxPkg() || xgoutil.IsMainPkg(p) {
+			return ""
+		}
+		return p.Name()
+	})
+}
+
+// spxExtractFunctionCodeActions returns refactorings that extract the
+// statements selected by rng into a new function declared in the same file.
+// Local variables used by the statements become parameters of the function,
+// and variables they declare that are used afterwards become its results.
+//
+// When extracting from inside an spx event handler, the function is named
+// after the event, and the parameters of the handler are captured like any
+// other local variables.
+func (s *Server) spxExtractFunctionCodeActions(result *compileResult, astFile *xgoast.File, documentURI DocumentURI, rng Range) []CodeAction {
+	typeInfo, _ := result.proj.TypeInfo()
+	if typeInfo == nil {
+		return nil
+	}
+	start, end, ok := selectionInterval(result.proj, astFile, rng)
+	if !ok {
+		return nil
+	}
+	path, _ := xgoutil.PathEnclosingInterval(astFile, start, end)
+	stmts, listIdx := selectedStmts(path, start, end)
+	if len(stmts) == 0 {
+		return nil
+	}
+
+	var funcDecl *xgoast.FuncDecl
+	for _, node := range path[listIdx:] {
+		if d, ok := node.(*xgoast.FuncDecl); ok {
+			funcDecl = d
+			break
+		}
+	}
+	if funcDecl == nil || funcDecl.Body == nil || !canMoveStmts(stmts) {
+		return nil
+	}
+
+	// Find the local variables used but not declared by the statements, and
+	// those declared by the statements and used after them.
+	isLocalVar := func(obj types.Object) (*types.Var, bool) {
+		v, ok := obj.(*types.Var)
+		if !ok || v.IsField() || v.Parent() == nil || v.Parent() == types.Universe || v.Parent() == v.Pkg().Scope() {
+			return nil, false
+		}
+		return v, funcDecl.Pos() <= v.Pos() && v.Pos() < funcDecl.End()
+	}
+	var (
+		params  []*types.Var
+		results []*types.Var
+		seen    = make(map[*types.Var]struct{})
+	)
+	for _, stmt := range stmts {
+		xgoast.Inspect(stmt, func(node xgoast.Node) bool {
+			ident, ok := node.(*xgoast.Ident)
+			if !ok {
+				return true
+			}
+			v, ok := isLocalVar(typeInfo.ObjectOf(ident))
+			if !ok {
+				return true
+			}
+			if _, ok := seen[v]; ok {
+				return true
+			}
+			seen[v] = struct{}{}
+			if v.Pos() < start || v.Pos() >= end {
+				params = append(params, v)
+				return true
+			}
+			for _, refIdent := range typeInfo.RefIdentsFor(v) {
+				if refIdent.Pos() >= end {
+					results = append(results, v)
+					break
+				}
+			}
+			return true
+		})
+	}
+	for _, v := range params {
+		if isVarMutatedIn(typeInfo, stmts, v) {
+			// Changes would not be visible to the caller.
+			return nil
+		}
+	}
+
+	lineStart, indent, ok := stmtIndent(result.proj, astFile, stmts[0])
+	if !ok {
+		return nil
+	}
+
+	baseName := "extracted"
+	if handlerName := enclosingSpxEventHandlerName(typeInfo, path[listIdx:]); handlerName != "" {
+		baseName = "handle" + strings.TrimPrefix(handlerName, "on")
+	}
+	name := uniqueLocalName(result.proj, astFile, start, baseName)
+
+	paramNames := make([]string, 0, len(params))
+	paramDecls := make([]string, 0, len(params))
+	for _, v := range params {
+		paramNames = append(paramNames, v.Name())
+		paramDecls = append(paramDecls, v.Name()+" "+extractedTypeString(v.Type()))
+	}
+	resultNames := make([]string, 0, len(results))
+	resultTypes := make([]string, 0, len(results))
+	for _, v := range results {
+		resultNames = append(resultNames, v.Name())
+		resultTypes = append(resultTypes, extractedTypeString(v.Type()))
+	}
+
+	endOffset := result.proj.Fset.Position(end).Offset
+	funcText := extractedFuncText(name, paramDecls, resultTypes, resultNames, string(astFile.Code[lineStart:endOffset]), indent)
+	callText := name + "(" + strings.Join(paramNames, ", ") + ")"
+	if len(resultNames) > 0 {
+		callText = strings.Join(resultNames, ", ") + " := " + callText
+	}
+
+	// Functions must be declared before the statements of the shadow entry,
+	// so the new function goes right after the enclosing function, or before
+	// the first statement if the selection is in the shadow entry.
+	var insertOffset int
+	if funcDecl == astFile.ShadowEntry {
+		firstStmtOffset := result.proj.Fset.Position(funcDecl.Body.List[0].Pos()).Offset
+		insertOffset = bytes.LastIndexByte(astFile.Code[:firstStmtOffset], '\n') + 1
+		funcText += "\n"
+	} else {
+		insertOffset = result.proj.Fset.Position(funcDecl.End()).Offset
+		funcText = "\n\n" + strings.TrimSuffix(funcText, "\n")
+	}
+	insertPos := OffsetPosition(astFile.Code, insertOffset)
+	return []CodeAction{{
+		Title: fmt.Sprintf("Extract function %q", name),
+		Kind:  codeActionKindRefactorExtractFunction,
+		Edit: &WorkspaceEdit{
+			Changes: map[DocumentURI][]TextEdit{
+				documentURI: {
+					{
+						Range:   Range{Start: insertPos, End: insertPos},
+						NewText: funcText,
+					},
+					{
+						Range: Range{
+							Start: FromPosition(result.proj, astFile, result.proj.Fset.Position(start)),
+							End:   FromPosition(result.proj, astFile, result.proj.Fset.Position(end)),
+						},
+						NewText: callText,
+					},
+				},
+			},
+		},
+	}}
+}
+
+// extractedFuncText returns the declaration of a function with the given
+// signature whose body is the given source lines, reindented by one level
+// after removing indent from each of them.
+func extractedFuncText(name string, paramDecls, resultTypes, resultNames []string, body, indent string) string {
+	var sb strings.Builder
+	sb.WriteString("func " + name + "(" + strings.Join(paramDecls, ", ") + ")")
+	switch len(resultTypes) {
+	case 0:
+	case 1:
+		sb.WriteString(" " + resultTypes[0])
+	default:
+		sb.WriteString(" (" + strings.Join(resultTypes, ", ") + ")")
+	}
+	sb.WriteString(" {\n")
+	for line := range strings.SplitSeq(body, "\n") {
+		line = strings.TrimPrefix(line, indent)
+		if strings.TrimSpace(line) == "" {
+			sb.WriteString("\n")
+			continue
+		}
+		sb.WriteString("\t" + line + "\n")
+	}
+	if len(resultNames) > 0 {
+		sb.WriteString("\treturn " + strings.Join(resultNames, ", ") + "\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// selectedStmts returns the statements of a statement list that are exactly
+// covered by [start, end), along with the index in path of the node holding
+// the list. It returns nil if there are none.
+func selectedStmts(path []xgoast.Node, start, end xgotoken.Pos) ([]xgoast.Stmt, int) {
+	if len(path) == 0 {
+		return nil, -1
+	}
+	listIdx := 0
+	for i := 0; i+1 < len(path) && path[i].Pos() == start && path[i].End() == end; i++ {
+		if _, ok := path[i].(xgoast.Stmt); ok {
+			listIdx = i + 1
+		}
+	}
+	var list []xgoast.Stmt
+	switch node := path[listIdx].(type) {
+	case *xgoast.BlockStmt:
+		list = node.List
+	case *xgoast.CaseClause:
+		list = node.Body
+	case *xgoast.CommClause:
+		list = node.Body
+	default:
+		return nil, -1
+	}
+	var stmts []xgoast.Stmt
+	for _, stmt := range list {
+		if start <= stmt.Pos() && stmt.End() <= end {
+			stmts = append(stmts, stmt)
+		}
+	}
+	if len(stmts) == 0 || stmts[0].Pos() != start || stmts[len(stmts)-1].End() != end {
+		return nil, -1
+	}
+	return stmts, listIdx
+}
+
+// canMoveStmts reports whether stmts can be moved into a function of their own
+// without changing their control flow. It returns false if they contain
+// return, defer, goto, fallthrough or labeled statements, or break and continue
+// statements that leave them.
+func canMoveStmts(stmts []xgoast.Stmt) bool {
+	ok := true
+	var inspect func(node xgoast.Node, inLoop, inSwitch bool) bool
+	inspect = func(node xgoast.Node, inLoop, inSwitch bool) bool {
+		switch node := node.(type) {
+		case *xgoast.FuncLit, *xgoast.LambdaExpr, *xgoast.LambdaExpr2:
+			return false // Control flow statements in nested functions are fine.
+		case *xgoast.ReturnStmt, *xgoast.DeferStmt, *xgoast.LabeledStmt:
+			ok = false
+		case *xgoast.BranchStmt:
+			switch {
+			case node.Label != nil:
+				ok = false
+			case node.Tok == xgotoken.BREAK:
+				ok = ok && (inLoop || inSwitch)
+			case node.Tok == xgotoken.CONTINUE:
+				ok = ok && inLoop
+			default:
+				ok = false
+			}
+		case *xgoast.ForStmt, *xgoast.RangeStmt, *xgoast.ForPhraseStmt:
+			xgoast.Inspect(node, func(child xgoast.Node) bool {
+				if child == node {
+					return true
+				}
+				return ok && inspect(child, true, inSwitch)
+			})
+			return false
+		case *xgoast.SwitchStmt, *xgoast.TypeSwitchStmt, *xgoast.SelectStmt:
+			xgoast.Inspect(node, func(child xgoast.Node) bool {
+				if child == node {
+					return true
+				}
+				return ok && inspect(child, inLoop, true)
+			})
+			return false
+		}
+		return ok
+	}
+	for _, stmt := range stmts {
+		xgoast.Inspect(stmt, func(node xgoast.Node) bool {
+			return ok && inspect(node, false, false)
+		})
+	}
+	return ok
+}
+
+// isVarMutatedIn reports whether v is assigned to, incremented, decremented or
+// has its address taken in stmts.
+func isVarMutatedIn(typeInfo *xgo.TypeInfo, stmts []xgoast.Stmt, v *types.Var) bool {
+	isV := func(expr xgoast.Expr) bool {
+		ident, ok := expr.(*xgoast.Ident)
+		return ok && typeInfo.Uses[ident] == v
+	}
+	mutated := false
+	for _, stmt := range stmts {
+		xgoast.Inspect(stmt, func(node xgoast.Node) bool {
+			switch node := node.(type) {
+			case *xgoast.AssignStmt:
+				mutated = mutated || slices.ContainsFunc(node.Lhs, isV)
+			case *xgoast.IncDecStmt:
+				mutated = mutated || isV(node.X)
+			case *xgoast.UnaryExpr:
+				mutated = mutated || node.Op == xgotoken.AND && isV(node.X)
+			case *xgoast.RangeStmt:
+				mutated = mutated || node.Tok == xgotoken.ASSIGN && (isV(node.Key) || isV(node.Value))
+			}
+			return !mutated
+		})
+	}
+	return mutated
+}
+
+// enclosingSpxEventHandlerName returns the name of the spx event handler
+// registration function, such as "onClick", whose callback encloses path[0].
+// It returns an empty string if there is none.
+func enclosingSpxEventHandlerName(typeInfo *xgo.TypeInfo, path []xgoast.Node) string {
+	for _, node := range path {
+		callExpr, ok := node.(*xgoast.CallExpr)
+		if !ok {
+			continue
+		}
+		var funIdent *xgoast.Ident
+		switch fun := callExpr.Fun.(type) {
+		case *xgoast.Ident:
+			funIdent = fun
+		case *xgoast.SelectorExpr:
+			funIdent = fun.Sel
+		}
+		if funIdent != nil && IsSpxEventHandlerFuncName(funIdent.Name) && IsInSpxPkg(typeInfo.ObjectOf(funIdent)) {
+			return funIdent.Name
+		}
+	}
+	return ""
+}
+
+// spxExtractResourceNameCodeActions returns the code actions that extract the
+// spx resource name string literal in the given range into a new constant
+// declared in main.spx, replacing all string literals referring to the same
+// resource. They are only offered if the resource is referred to by more than
+// one string literal.
+//
+// The constant is declared with the spx resource name type, such as
+// [spx.SoundName], so its value keeps being inspected as an spx resource
+// reference.
+func (s *Server) spxExtractResourceNameCodeActions(result *compileResult, astFile *xgoast.File, rng Range) []CodeAction {
+	typeInfo, _ := result.proj.TypeInfo()
+	if typeInfo == nil {
+		return nil
+	}
+	mainASTFile, _ := result.proj.ASTFile(result.mainSpxFile)
+	if mainASTFile == nil {
+		return nil
+	}
+	fset := result.proj.Fset
+	ref := result.spxResourceRefAtASTFilePosition(astFile, fset.Position(PosAt(result.proj, astFile, rng.Start)))
+	if ref == nil || ref.Kind != SpxResourceRefKindStringLiteral {
+		return nil
+	}
+	if _, ok := ref.Node.(*xgoast.BasicLit); !ok {
+		return nil
+	}
+	typeName, suffix := spxResourceNameTypeName(ref.ID)
+	if typeName == "" {
+		return nil
+	}
+
+	var lits []*xgoast.BasicLit
+	for _, r := range result.spxResourceRefs {
+		if r.ID != ref.ID || r.Kind != SpxResourceRefKindStringLiteral {
+			continue
+		}
+		if lit, ok := r.Node.(*xgoast.BasicLit); ok && !slices.Contains(lits, lit) {
+			lits = append(lits, lit)
+		}
+	}
+	if len(lits) < 2 {
+		return nil
+	}
+	slices.SortFunc(lits, func(a, b *xgoast.BasicLit) int {
+		aPos, bPos := fset.Position(a.Pos()), fset.Position(b.Pos())
+		return cmp.Or(strings.Compare(aPos.Filename, bPos.Filename), cmp.Compare(aPos.Offset, bPos.Offset))
+	})
+
+	name := uniquePkgLevelName(typeInfo, spxResourceConstBaseName(ref.ID.Name(), suffix))
+	declText := "const " + name + " " + typeName + " = " + strconv.Quote(ref.ID.Name())
+
+	// Constants go before the variable declarations, which is where they
+	// end up when declarations are reordered by formatting.
+	code := mainASTFile.Code
+	insertOffset := len(code)
+	for _, decl := range mainASTFile.Decls {
+		if d, ok := decl.(*xgoast.GenDecl); ok && (d.Tok == xgotoken.IMPORT || d.Tok == xgotoken.TYPE || d.Tok == xgotoken.CONST) {
+			continue
+		}
+		anchor := decl.Pos()
+		if decl == mainASTFile.ShadowEntry {
+			if len(mainASTFile.ShadowEntry.Body.List) == 0 {
+				continue
+			}
+			anchor = mainASTFile.ShadowEntry.Body.List[0].Pos()
+		} else if doc := getDeclDoc(decl); doc != nil {
+			anchor = doc.Pos()
+		}
+		offset := fset.Position(anchor).Offset
+		insertOffset = bytes.LastIndexByte(code[:offset], '\n') + 1
+		declText += "\n\n"
+		break
+	}
+	if insertOffset == len(code) {
+		declText = "\n" + declText + "\n"
+		if len(code) > 0 && code[len(code)-1] != '\n' {
+			declText = "\n" + declText
+		}
+	}
+	insertPos := OffsetPosition(code, insertOffset)
+
+	mainDocumentURI := s.toDocumentURI(result.mainSpxFile)
+	changes := map[DocumentURI][]TextEdit{
+		mainDocumentURI: {{
+			Range:   Range{Start: insertPos, End: insertPos},
+			NewText: declText,
+		}},
+	}
+	for _, lit := range lits {
+		documentURI := s.nodeDocumentURI(result.proj, lit)
+		changes[documentURI] = append(changes[documentURI], TextEdit{
+			Range:   RangeForNode(result.proj, lit),
+			NewText: name,
+		})
+	}
+	return []CodeAction{{
+		Title: fmt.Sprintf("Extract %q into constant %q", ref.ID.Name(), name),
+		Kind:  codeActionKindRefactorExtractConstant,
+		Edit:  &WorkspaceEdit{Changes: changes},
+	}}
+}
+
+// spxResourceNameTypeName returns the name of the spx type for names of the
+// spx resource identified by id, along with a suffix describing the resource
+// kind to be used in constant names. It returns empty strings if the resource
+// kind has no such type.
+func spxResourceNameTypeName(id SpxResourceID) (typeName, suffix string) {
+	switch id.(type) {
+	case SpxBackdropResourceID:
+		return "BackdropName", "Backdrop"
+	case SpxSoundResourceID:
+		return "SoundName", "Sound"
+	case SpxSpriteResourceID:
+		return "SpriteName", "Sprite"
+	case SpxSpriteCostumeResourceID:
+		return "SpriteCostumeName", "Costume"
+	case SpxSpriteAnimationResourceID:
+		return "SpriteAnimationName", "Animation"
+	case SpxWidgetResourceID:
+		return "WidgetName", "Widget"
+	}
+	return "", ""
+}
+
+// spxResourceConstBaseName returns the base name of a constant for the given
+// spx resource name, such as "jumpSound" for sound "jump". Characters that
+// cannot appear in identifiers are dropped, with the following letter
+// uppercased.
+func spxResourceConstBaseName(resourceName, suffix string) string {
+	var (
+		sb        strings.Builder
+		upperNext bool
+	)
+	for _, r := range resourceName {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_':
+			upperNext = sb.Len() > 0
+		case sb.Len() == 0:
+			if !unicode.IsLetter(r) {
+				continue
+			}
+			sb.WriteRune(unicode.ToLower(r))
+		case upperNext:
+			sb.WriteRune(unicode.ToUpper(r))
+			upperNext = false
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	if sb.Len() == 0 {
+		return strings.ToLower(suffix)
+	}
+	return sb.String() + suffix
+}
+
+// uniquePkgLevelName returns base, or base followed by the smallest positive
+// number, such that it neither conflicts with nor is shadowed by any existing
+// name in the package described by typeInfo.
+func uniquePkgLevelName(typeInfo *xgo.TypeInfo, base string) string {
+	used := make(map[string]struct{})
+	for ident := range typeInfo.Defs {
+		used[ident.Name] = struct{}{}
+	}
+	for ident := range typeInfo.Uses {
+		used[ident.Name] = struct{}{}
+	}
+	isUsed := func(name string) bool {
+		if _, ok := used[name]; ok {
+			return true
+		}
+		if typeInfo.Pkg().Scope().Lookup(name) != nil || types.Universe.Lookup(name) != nil || GetSpxPkg().Scope().Lookup(name) != nil {
+			return true
+		}
+		for _, typ := range []types.Type{GetSpxGameType(), GetSpxSpriteImplType()} {
+			if obj, _, _ := types.LookupFieldOrMethod(typ, true, nil, name); obj != nil {
+				return true
+			}
+		}
+		return false
+	}
+	name := base
+	for i := 1; isUsed(name); i++ {
+		name = base + strconv.Itoa(i)
+	}
+	return name
+}
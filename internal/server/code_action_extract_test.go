@@ -0,0 +1,586 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/goplus/xgolsw/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerSpxExtractVariableCodeActions(t *testing.T) {
+	newServer := func(mainSpx string) *Server {
+		m := map[string][]byte{
+			"main.spx":          []byte(mainSpx),
+			"assets/index.json": []byte(`{}`),
+		}
+		return New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+	}
+	extractVariable := func(t *testing.T, s *Server, rng Range) []CodeAction {
+		codeActions, err := s.textDocumentCodeAction(context.Background(), &CodeActionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Range:        rng,
+			Context:      protocol.CodeActionContext{Only: []CodeActionKind{codeActionKindRefactorExtractVariable}},
+		})
+		require.NoError(t, err)
+		return codeActions
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		s := newServer(`
+onStart => {
+	a := 1
+	println a+2
+}
+run "assets", {Title: "My Game"}
+`)
+
+		codeActions := extractVariable(t, s, Range{
+			Start: Position{Line: 3, Character: 9},
+			End:   Position{Line: 3, Character: 12},
+		})
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, CodeAction{
+			Title: `Extract variable "v"`,
+			Kind:  codeActionKindRefactorExtractVariable,
+			Edit: &WorkspaceEdit{
+				Changes: map[DocumentURI][]TextEdit{
+					"file:///main.spx": {
+						{
+							Range: Range{
+								Start: Position{Line: 3, Character: 0},
+								End:   Position{Line: 3, Character: 0},
+							},
+							NewText: "\tv := a+2\n",
+						},
+						{
+							Range: Range{
+								Start: Position{Line: 3, Character: 9},
+								End:   Position{Line: 3, Character: 12},
+							},
+							NewText: "v",
+						},
+					},
+				},
+			},
+		}, codeActions[0])
+	})
+
+	t.Run("CommandStyleCallArg", func(t *testing.T) {
+		s := newServer(`
+onStart => {
+	setXYpos 45, 2
+}
+run "assets", {Title: "My Game"}
+`)
+
+		codeActions := extractVariable(t, s, Range{
+			Start: Position{Line: 2, Character: 10},
+			End:   Position{Line: 2, Character: 12},
+		})
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 2, Character: 0},
+					End:   Position{Line: 2, Character: 0},
+				},
+				NewText: "\tvar v float64 = 45\n",
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 2, Character: 10},
+					End:   Position{Line: 2, Character: 12},
+				},
+				NewText: "v",
+			},
+		}, codeActions[0].Edit.Changes["file:///main.spx"])
+	})
+
+	t.Run("CallResult", func(t *testing.T) {
+		s := newServer(`
+onStart => {
+	println getWidget(Monitor, "w")
+}
+run "assets", {Title: "My Game"}
+`)
+
+		codeActions := extractVariable(t, s, Range{
+			Start: Position{Line: 2, Character: 9},
+			End:   Position{Line: 2, Character: 32},
+		})
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, `Extract variable "widget"`, codeActions[0].Title)
+		assert.Equal(t, "\twidget := getWidget(Monitor, \"w\")\n", codeActions[0].Edit.Changes["file:///main.spx"][0].NewText)
+	})
+
+	t.Run("UniqueName", func(t *testing.T) {
+		s := newServer(`
+onStart => {
+	v := 1
+	println v*2
+}
+run "assets", {Title: "My Game"}
+`)
+
+		codeActions := extractVariable(t, s, Range{
+			Start: Position{Line: 3, Character: 9},
+			End:   Position{Line: 3, Character: 12},
+		})
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, "\tv1 := v*2\n", codeActions[0].Edit.Changes["file:///main.spx"][0].NewText)
+	})
+
+	t.Run("VoidCall", func(t *testing.T) {
+		s := newServer(`
+onStart => {
+	setXYpos 45, 2
+}
+run "assets", {Title: "My Game"}
+`)
+
+		codeActions := extractVariable(t, s, Range{
+			Start: Position{Line: 2, Character: 1},
+			End:   Position{Line: 2, Character: 15},
+		})
+		assert.Empty(t, codeActions)
+	})
+
+	t.Run("LoopCondition", func(t *testing.T) {
+		s := newServer(`
+onStart => {
+	for i := 0; i < len("abc"); i++ {
+	}
+}
+run "assets", {Title: "My Game"}
+`)
+
+		codeActions := extractVariable(t, s, Range{
+			Start: Position{Line: 2, Character: 17},
+			End:   Position{Line: 2, Character: 27},
+		})
+		assert.Empty(t, codeActions)
+	})
+
+	t.Run("PartialSelection", func(t *testing.T) {
+		s := newServer(`
+onStart => {
+	a := 1
+	println a+2
+}
+run "assets", {Title: "My Game"}
+`)
+
+		codeActions := extractVariable(t, s, Range{
+			Start: Position{Line: 3, Character: 9},
+			End:   Position{Line: 3, Character: 11},
+		})
+		assert.Empty(t, codeActions)
+	})
+
+	t.Run("QuickFixOnly", func(t *testing.T) {
+		s := newServer(`
+onStart => {
+	a := 1
+	println a+2
+}
+run "assets", {Title: "My Game"}
+`)
+
+		codeActions, err := s.textDocumentCodeAction(context.Background(), &CodeActionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Range: Range{
+				Start: Position{Line: 3, Character: 9},
+				End:   Position{Line: 3, Character: 12},
+			},
+			Context: protocol.CodeActionContext{Only: []CodeActionKind{protocol.QuickFix}},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, codeActions)
+	})
+}
+
+func TestServerSpxExtractFunctionCodeActions(t *testing.T) {
+	newServer := func(files map[string]string) *Server {
+		m := map[string][]byte{
+			"assets/index.json": []byte(`{}`),
+		}
+		for name, content := range files {
+			m[name] = []byte(content)
+		}
+		return New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+	}
+	extractFunction := func(t *testing.T, s *Server, documentURI DocumentURI, rng Range) []CodeAction {
+		codeActions, err := s.textDocumentCodeAction(context.Background(), &CodeActionParams{
+			TextDocument: TextDocumentIdentifier{URI: documentURI},
+			Range:        rng,
+			Context:      protocol.CodeActionContext{Only: []CodeActionKind{codeActionKindRefactorExtractFunction}},
+		})
+		require.NoError(t, err)
+		return codeActions
+	}
+
+	t.Run("EventHandler", func(t *testing.T) {
+		s := newServer(map[string]string{
+			"main.spx": `
+onStart => {
+	a := 1
+	b := a + 1
+	println b
+	println a, b
+}
+run "assets", {Title: "My Game"}
+`,
+		})
+
+		codeActions := extractFunction(t, s, "file:///main.spx", Range{
+			Start: Position{Line: 3, Character: 0},
+			End:   Position{Line: 4, Character: 10},
+		})
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, CodeAction{
+			Title: `Extract function "handleStart"`,
+			Kind:  codeActionKindRefactorExtractFunction,
+			Edit: &WorkspaceEdit{
+				Changes: map[DocumentURI][]TextEdit{
+					"file:///main.spx": {
+						{
+							Range: Range{
+								Start: Position{Line: 1, Character: 0},
+								End:   Position{Line: 1, Character: 0},
+							},
+							NewText: "func handleStart(a int) int {\n\tb := a + 1\n\tprintln b\n\treturn b\n}\n\n",
+						},
+						{
+							Range: Range{
+								Start: Position{Line: 3, Character: 1},
+								End:   Position{Line: 4, Character: 10},
+							},
+							NewText: "b := handleStart(a)",
+						},
+					},
+				},
+			},
+		}, codeActions[0])
+	})
+
+	t.Run("EventHandlerParams", func(t *testing.T) {
+		s := newServer(map[string]string{
+			"main.spx": `
+run "assets", {Title: "My Game"}
+`,
+			"MySprite.spx": `
+onMsg (msg, data) => {
+	println msg
+	println data
+}
+`,
+			"assets/sprites/MySprite/index.json": `{}`,
+		})
+
+		codeActions := extractFunction(t, s, "file:///MySprite.spx", Range{
+			Start: Position{Line: 2, Character: 1},
+			End:   Position{Line: 3, Character: 13},
+		})
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, "func handleMsg(msg string, data any) {\n\tprintln msg\n\tprintln data\n}\n\n", codeActions[0].Edit.Changes["file:///MySprite.spx"][0].NewText)
+		assert.Equal(t, "handleMsg(msg, data)", codeActions[0].Edit.Changes["file:///MySprite.spx"][1].NewText)
+	})
+
+	t.Run("FuncDecl", func(t *testing.T) {
+		s := newServer(map[string]string{
+			"main.spx": `
+func greet(name string) {
+	msg := "Hello, " + name
+	println msg
+}
+
+onStart => {
+	greet "XGo"
+}
+run "assets", {Title: "My Game"}
+`,
+		})
+
+		codeActions := extractFunction(t, s, "file:///main.spx", Range{
+			Start: Position{Line: 3, Character: 1},
+			End:   Position{Line: 3, Character: 12},
+		})
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 4, Character: 1},
+					End:   Position{Line: 4, Character: 1},
+				},
+				NewText: "\n\nfunc extracted(msg string) {\n\tprintln msg\n}",
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 3, Character: 1},
+					End:   Position{Line: 3, Character: 12},
+				},
+				NewText: "extracted(msg)",
+			},
+		}, codeActions[0].Edit.Changes["file:///main.spx"])
+	})
+
+	t.Run("Return", func(t *testing.T) {
+		s := newServer(map[string]string{
+			"main.spx": `
+func check(n int) {
+	if n < 0 {
+		return
+	}
+	println n
+}
+run "assets", {Title: "My Game"}
+`,
+		})
+
+		codeActions := extractFunction(t, s, "file:///main.spx", Range{
+			Start: Position{Line: 2, Character: 1},
+			End:   Position{Line: 4, Character: 2},
+		})
+		assert.Empty(t, codeActions)
+	})
+
+	t.Run("MutatedCapturedVar", func(t *testing.T) {
+		s := newServer(map[string]string{
+			"main.spx": `
+onStart => {
+	n := 1
+	n++
+	println n
+}
+run "assets", {Title: "My Game"}
+`,
+		})
+
+		codeActions := extractFunction(t, s, "file:///main.spx", Range{
+			Start: Position{Line: 3, Character: 1},
+			End:   Position{Line: 3, Character: 4},
+		})
+		assert.Empty(t, codeActions)
+	})
+
+	t.Run("PartialStatement", func(t *testing.T) {
+		s := newServer(map[string]string{
+			"main.spx": `
+onStart => {
+	println 1
+	println 2
+}
+run "assets", {Title: "My Game"}
+`,
+		})
+
+		codeActions := extractFunction(t, s, "file:///main.spx", Range{
+			Start: Position{Line: 2, Character: 3},
+			End:   Position{Line: 3, Character: 10},
+		})
+		assert.Empty(t, codeActions)
+	})
+}
+
+func TestServerSpxExtractResourceNameCodeActions(t *testing.T) {
+	newServer := func(files map[string]string) *Server {
+		m := map[string][]byte{
+			"assets/index.json":             []byte(`{}`),
+			"assets/sounds/bang/index.json": []byte(`{}`),
+		}
+		for name, content := range files {
+			m[name] = []byte(content)
+		}
+		return New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+	}
+	extractConstant := func(t *testing.T, s *Server, documentURI DocumentURI, rng Range) []CodeAction {
+		codeActions, err := s.textDocumentCodeAction(context.Background(), &CodeActionParams{
+			TextDocument: TextDocumentIdentifier{URI: documentURI},
+			Range:        rng,
+			Context:      protocol.CodeActionContext{Only: []CodeActionKind{codeActionKindRefactorExtractConstant}},
+		})
+		require.NoError(t, err)
+		return codeActions
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		s := newServer(map[string]string{
+			"main.spx": `var (
+	score int
+)
+
+onStart => {
+	play "bang"
+	play "bang", true
+}
+run "assets", {Title: "My Game"}
+`,
+			"MySprite.spx": `
+onClick => {
+	play "bang"
+}
+`,
+			"assets/sprites/MySprite/index.json": `{}`,
+		})
+
+		codeActions := extractConstant(t, s, "file:///MySprite.spx", Range{
+			Start: Position{Line: 2, Character: 8},
+			End:   Position{Line: 2, Character: 8},
+		})
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, CodeAction{
+			Title: `Extract "bang" into constant "bangSound"`,
+			Kind:  codeActionKindRefactorExtractConstant,
+			Edit: &WorkspaceEdit{
+				Changes: map[DocumentURI][]TextEdit{
+					"file:///main.spx": {
+						{
+							Range: Range{
+								Start: Position{Line: 0, Character: 0},
+								End:   Position{Line: 0, Character: 0},
+							},
+							NewText: "const bangSound SoundName = \"bang\"\n\n",
+						},
+						{
+							Range: Range{
+								Start: Position{Line: 5, Character: 6},
+								End:   Position{Line: 5, Character: 12},
+							},
+							NewText: "bangSound",
+						},
+						{
+							Range: Range{
+								Start: Position{Line: 6, Character: 6},
+								End:   Position{Line: 6, Character: 12},
+							},
+							NewText: "bangSound",
+						},
+					},
+					"file:///MySprite.spx": {
+						{
+							Range: Range{
+								Start: Position{Line: 2, Character: 6},
+								End:   Position{Line: 2, Character: 12},
+							},
+							NewText: "bangSound",
+						},
+					},
+				},
+			},
+		}, codeActions[0])
+	})
+
+	t.Run("ShadowEntryOnly", func(t *testing.T) {
+		s := newServer(map[string]string{
+			"main.spx": `import "fmt"
+
+play "bang"
+play "bang"
+fmt.Println "done"
+`,
+		})
+
+		codeActions := extractConstant(t, s, "file:///main.spx", Range{
+			Start: Position{Line: 2, Character: 6},
+			End:   Position{Line: 2, Character: 11},
+		})
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, TextEdit{
+			Range: Range{
+				Start: Position{Line: 2, Character: 0},
+				End:   Position{Line: 2, Character: 0},
+			},
+			NewText: "const bangSound SoundName = \"bang\"\n\n",
+		}, codeActions[0].Edit.Changes["file:///main.spx"][0])
+	})
+
+	t.Run("UniqueName", func(t *testing.T) {
+		s := newServer(map[string]string{
+			"main.spx": `onStart => {
+	bangSound := 1
+	println bangSound
+	play "bang"
+	play "bang"
+}
+run "assets", {Title: "My Game"}
+`,
+		})
+
+		codeActions := extractConstant(t, s, "file:///main.spx", Range{
+			Start: Position{Line: 3, Character: 7},
+			End:   Position{Line: 3, Character: 7},
+		})
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, `Extract "bang" into constant "bangSound1"`, codeActions[0].Title)
+	})
+
+	t.Run("SingleOccurrence", func(t *testing.T) {
+		s := newServer(map[string]string{
+			"main.spx": `onStart => {
+	play "bang"
+}
+run "assets", {Title: "My Game"}
+`,
+		})
+
+		codeActions := extractConstant(t, s, "file:///main.spx", Range{
+			Start: Position{Line: 1, Character: 7},
+			End:   Position{Line: 1, Character: 7},
+		})
+		assert.Empty(t, codeActions)
+	})
+
+	t.Run("NotResourceName", func(t *testing.T) {
+		s := newServer(map[string]string{
+			"main.spx": `onStart => {
+	println "bang"
+	println "bang"
+}
+run "assets", {Title: "My Game"}
+`,
+		})
+
+		codeActions := extractConstant(t, s, "file:///main.spx", Range{
+			Start: Position{Line: 1, Character: 10},
+			End:   Position{Line: 1, Character: 10},
+		})
+		assert.Empty(t, codeActions)
+	})
+
+	t.Run("RenameAfterExtraction", func(t *testing.T) {
+		s := newServer(map[string]string{
+			"main.spx": `const bangSound SoundName = "bang"
+
+onStart => {
+	play bangSound
+	play bangSound, true
+}
+run "assets", {Title: "My Game"}
+`,
+		})
+
+		codeActions := extractConstant(t, s, "file:///main.spx", Range{
+			Start: Position{Line: 0, Character: 30},
+			End:   Position{Line: 0, Character: 30},
+		})
+		assert.Empty(t, codeActions)
+
+		workspaceEdit, err := s.spxRenameResources(context.Background(), []SpxRenameResourceParams{{
+			Resource: SpxResourceIdentifier{URI: "spx://resources/sounds/bang"},
+			NewName:  "boom",
+		}})
+		require.NoError(t, err)
+		require.NotNil(t, workspaceEdit)
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 0, Character: 29},
+					End:   Position{Line: 0, Character: 33},
+				},
+				NewText: "boom",
+			},
+		}, workspaceEdit.Changes["file:///main.spx"])
+	})
+}
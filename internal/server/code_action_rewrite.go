@@ -0,0 +1,168 @@
+package server
+
+import (
+	"cmp"
+	"slices"
+
+	xgoast "github.com/goplus/xgo/ast"
+	"github.com/goplus/xgolsw/xgo"
+	"github.com/goplus/xgolsw/xgo/xgoutil"
+)
+
+// spxRewriteCallSyntaxCodeActions returns the code actions that convert call
+// statements between the XGo command-style syntax, such as `play "bang"`, and
+// the conventional parenthesized syntax, such as `play("bang")`, either for
+// the call statement in the given range or for all call statements in the
+// document. They are only offered if the range is in a call statement.
+func (s *Server) spxRewriteCallSyntaxCodeActions(result *compileResult, astFile *xgoast.File, documentURI DocumentURI, rng Range) []CodeAction {
+	start := PosAt(result.proj, astFile, rng.Start)
+	end := PosAt(result.proj, astFile, rng.End)
+
+	// Only the innermost statement enclosing the range is considered, so
+	// nothing is selected within the event handler passed to a call.
+	var selected *xgoast.CallExpr
+	path, _ := xgoutil.PathEnclosingInterval(astFile, start, end)
+	for _, node := range path {
+		if stmt, ok := node.(xgoast.Stmt); ok {
+			if exprStmt, ok := stmt.(*xgoast.ExprStmt); ok {
+				selected, _ = exprStmt.X.(*xgoast.CallExpr)
+			}
+			break
+		}
+	}
+
+	if selected == nil {
+		return nil
+	}
+
+	var (
+		toParenEdits, toCmdEdits []TextEdit
+		toParenCount, toCmdCount int
+	)
+	for _, call := range spxCallStmtCalls(astFile) {
+		if edits := parenthesizedCallEdits(result.proj, call); edits != nil {
+			if call != selected {
+				toParenCount++
+			}
+			toParenEdits = append(toParenEdits, edits...)
+		} else if edits := commandStyleCallEdits(result.proj, astFile, call); edits != nil {
+			if call != selected {
+				toCmdCount++
+			}
+			toCmdEdits = append(toCmdEdits, edits...)
+		}
+	}
+
+	var codeActions []CodeAction
+	newCodeAction := func(title string, edits []TextEdit) CodeAction {
+		slices.SortStableFunc(edits, func(a, b TextEdit) int {
+			return cmp.Or(cmp.Compare(a.Range.Start.Line, b.Range.Start.Line), cmp.Compare(a.Range.Start.Character, b.Range.Start.Character))
+		})
+		return CodeAction{
+			Title: title,
+			Kind:  RefactorRewrite,
+			Edit: &WorkspaceEdit{
+				Changes: map[DocumentURI][]TextEdit{documentURI: edits},
+			},
+		}
+	}
+	if edits := parenthesizedCallEdits(result.proj, selected); edits != nil {
+		codeActions = append(codeActions, newCodeAction("Convert to parenthesized call", edits))
+	} else if edits := commandStyleCallEdits(result.proj, astFile, selected); edits != nil {
+		codeActions = append(codeActions, newCodeAction("Convert to command-style call", edits))
+	}
+	if toParenCount > 0 {
+		codeActions = append(codeActions, newCodeAction("Convert all calls in file to parenthesized syntax", toParenEdits))
+	}
+	if toCmdCount > 0 {
+		codeActions = append(codeActions, newCodeAction("Convert all calls in file to command-style syntax", toCmdEdits))
+	}
+	return codeActions
+}
+
+// spxCallStmtCalls returns the calls in astFile that are statements on their
+// own, which are the only ones that may use the command-style syntax.
+func spxCallStmtCalls(astFile *xgoast.File) []*xgoast.CallExpr {
+	var calls []*xgoast.CallExpr
+	collect := func(stmts []xgoast.Stmt) {
+		for _, stmt := range stmts {
+			if exprStmt, ok := stmt.(*xgoast.ExprStmt); ok {
+				if call, ok := exprStmt.X.(*xgoast.CallExpr); ok {
+					calls = append(calls, call)
+				}
+			}
+		}
+	}
+	xgoast.Inspect(astFile, func(node xgoast.Node) bool {
+		switch node := node.(type) {
+		case *xgoast.BlockStmt:
+			collect(node.List)
+		case *xgoast.CaseClause:
+			collect(node.Body)
+		case *xgoast.CommClause:
+			collect(node.Body)
+		}
+		return true
+	})
+	return calls
+}
+
+// parenthesizedCallEdits returns the text edits that convert the
+// command-style call into the parenthesized syntax. It returns nil if the call
+// does not use the command-style syntax.
+func parenthesizedCallEdits(proj *xgo.Project, call *xgoast.CallExpr) []TextEdit {
+	if !call.IsCommand() || len(call.Args) == 0 {
+		return nil
+	}
+	return []TextEdit{
+		{
+			Range:   RangeForPosEnd(proj, call.Fun.End(), call.Args[0].Pos()),
+			NewText: "(",
+		},
+		{
+			Range:   RangeForPosEnd(proj, call.End(), call.End()),
+			NewText: ")",
+		},
+	}
+}
+
+// commandStyleCallEdits returns the text edits that convert the parenthesized
+// call into the command-style syntax. It returns nil if the call cannot be
+// written in the command-style syntax, such as when it has no arguments,
+// which would turn it into a bare function value.
+func commandStyleCallEdits(proj *xgo.Project, astFile *xgoast.File, call *xgoast.CallExpr) []TextEdit {
+	if call.IsCommand() || !call.Lparen.IsValid() || !call.Rparen.IsValid() || len(call.Args) == 0 || call.Ellipsis.IsValid() || !isIdentChain(call.Fun) {
+		return nil
+	}
+	lastArgEnd := call.Args[len(call.Args)-1].End()
+	for _, cg := range astFile.Comments {
+		if (call.Lparen <= cg.Pos() && cg.Pos() < call.Args[0].Pos()) || (lastArgEnd <= cg.Pos() && cg.Pos() < call.Rparen) {
+			return nil // Comments would be lost.
+		}
+	}
+	return []TextEdit{
+		{
+			Range:   RangeForPosEnd(proj, call.Lparen, call.Args[0].Pos()),
+			NewText: " ",
+		},
+		{
+			Range:   RangeForPosEnd(proj, lastArgEnd, call.Rparen+1),
+			NewText: "",
+		},
+	}
+}
+
+// isIdentChain reports whether expr is an identifier or a chain of selectors
+// on an identifier, such as `a.b.c`.
+func isIdentChain(expr xgoast.Expr) bool {
+	for {
+		switch e := expr.(type) {
+		case *xgoast.Ident:
+			return true
+		case *xgoast.SelectorExpr:
+			expr = e.X
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/goplus/xgolsw/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerSpxRewriteCallSyntaxCodeActions(t *testing.T) {
+	newServer := func(mainSpx string) *Server {
+		m := map[string][]byte{
+			"main.spx":          []byte(mainSpx),
+			"assets/index.json": []byte(`{}`),
+		}
+		return New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+	}
+	rewrite := func(t *testing.T, s *Server, rng Range) []CodeAction {
+		codeActions, err := s.textDocumentCodeAction(context.Background(), &CodeActionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Range:        rng,
+			Context:      protocol.CodeActionContext{Only: []CodeActionKind{RefactorRewrite}},
+		})
+		require.NoError(t, err)
+		return codeActions
+	}
+
+	t.Run("ToParenthesized", func(t *testing.T) {
+		s := newServer(`onStart => {
+	println "a", 1
+}
+`)
+
+		codeActions := rewrite(t, s, Range{
+			Start: Position{Line: 1, Character: 3},
+			End:   Position{Line: 1, Character: 3},
+		})
+		require.Len(t, codeActions, 2)
+		assert.Equal(t, CodeAction{
+			Title: "Convert to parenthesized call",
+			Kind:  RefactorRewrite,
+			Edit: &WorkspaceEdit{
+				Changes: map[DocumentURI][]TextEdit{
+					"file:///main.spx": {
+						{
+							Range: Range{
+								Start: Position{Line: 1, Character: 8},
+								End:   Position{Line: 1, Character: 9},
+							},
+							NewText: "(",
+						},
+						{
+							Range: Range{
+								Start: Position{Line: 1, Character: 15},
+								End:   Position{Line: 1, Character: 15},
+							},
+							NewText: ")",
+						},
+					},
+				},
+			},
+		}, codeActions[0])
+		assert.Equal(t, "Convert all calls in file to parenthesized syntax", codeActions[1].Title)
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 0, Character: 7},
+					End:   Position{Line: 0, Character: 8},
+				},
+				NewText: "(",
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 1, Character: 8},
+					End:   Position{Line: 1, Character: 9},
+				},
+				NewText: "(",
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 1, Character: 15},
+					End:   Position{Line: 1, Character: 15},
+				},
+				NewText: ")",
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 2, Character: 1},
+					End:   Position{Line: 2, Character: 1},
+				},
+				NewText: ")",
+			},
+		}, codeActions[1].Edit.Changes["file:///main.spx"])
+	})
+
+	t.Run("ToCommandStyle", func(t *testing.T) {
+		s := newServer(`onStart(=> {
+	println("a", 1)
+})
+`)
+
+		codeActions := rewrite(t, s, Range{
+			Start: Position{Line: 1, Character: 3},
+			End:   Position{Line: 1, Character: 3},
+		})
+		require.Len(t, codeActions, 2)
+		assert.Equal(t, CodeAction{
+			Title: "Convert to command-style call",
+			Kind:  RefactorRewrite,
+			Edit: &WorkspaceEdit{
+				Changes: map[DocumentURI][]TextEdit{
+					"file:///main.spx": {
+						{
+							Range: Range{
+								Start: Position{Line: 1, Character: 8},
+								End:   Position{Line: 1, Character: 9},
+							},
+							NewText: " ",
+						},
+						{
+							Range: Range{
+								Start: Position{Line: 1, Character: 15},
+								End:   Position{Line: 1, Character: 16},
+							},
+							NewText: "",
+						},
+					},
+				},
+			},
+		}, codeActions[0])
+		assert.Equal(t, "Convert all calls in file to command-style syntax", codeActions[1].Title)
+		assert.Len(t, codeActions[1].Edit.Changes["file:///main.spx"], 4)
+	})
+
+	t.Run("MultiLine", func(t *testing.T) {
+		s := newServer(`println(
+	"a",
+	1,
+)
+`)
+
+		codeActions := rewrite(t, s, Range{
+			Start: Position{Line: 0, Character: 0},
+			End:   Position{Line: 0, Character: 0},
+		})
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 0, Character: 7},
+					End:   Position{Line: 1, Character: 1},
+				},
+				NewText: " ",
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 2, Character: 2},
+					End:   Position{Line: 3, Character: 1},
+				},
+				NewText: "",
+			},
+		}, codeActions[0].Edit.Changes["file:///main.spx"])
+	})
+
+	t.Run("NoArgs", func(t *testing.T) {
+		s := newServer(`onStart => {
+	println()
+	println 1
+}
+`)
+
+		codeActions := rewrite(t, s, Range{
+			Start: Position{Line: 1, Character: 3},
+			End:   Position{Line: 1, Character: 3},
+		})
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, "Convert all calls in file to parenthesized syntax", codeActions[0].Title)
+	})
+
+	t.Run("Comments", func(t *testing.T) {
+		s := newServer(`println("a" /* first */)
+`)
+
+		codeActions := rewrite(t, s, Range{
+			Start: Position{Line: 0, Character: 0},
+			End:   Position{Line: 0, Character: 0},
+		})
+		assert.Empty(t, codeActions)
+	})
+
+	t.Run("NotCallStmt", func(t *testing.T) {
+		s := newServer(`onStart => {
+	n := len("a")
+	println n
+}
+`)
+
+		codeActions := rewrite(t, s, Range{
+			Start: Position{Line: 1, Character: 8},
+			End:   Position{Line: 1, Character: 8},
+		})
+		assert.Empty(t, codeActions)
+	})
+}
@@ -0,0 +1,371 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/goplus/xgolsw/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerTextDocumentCodeAction(t *testing.T) {
+	newServer := func() *Server {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	Sprite1 Sprite1
+)
+onStart => {
+	play "Sound1"
+	getWidget Monitor, "widget1"
+}
+run "assets", {Title: "My Game"}
+`),
+			"Sprite1.spx":       []byte(``),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		s.clientCapabilities.Workspace.WorkspaceEdit = &protocol.WorkspaceEditClientCapabilities{
+			DocumentChanges:    true,
+			ResourceOperations: []protocol.ResourceOperationKind{protocol.Create},
+		}
+		return s
+	}
+	lineRange := func(line uint32) Range {
+		return Range{
+			Start: Position{Line: line, Character: 0},
+			End:   Position{Line: line, Character: 30},
+		}
+	}
+
+	t.Run("MissingSound", func(t *testing.T) {
+		s := newServer()
+
+		codeActions, err := s.textDocumentCodeAction(context.Background(), &CodeActionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Range:        lineRange(5),
+			Context:      protocol.CodeActionContext{Only: []CodeActionKind{QuickFix}},
+		})
+		require.NoError(t, err)
+		require.Len(t, codeActions, 1)
+
+		codeAction := codeActions[0]
+		assert.Equal(t, `Create sound resource "Sound1"`, codeAction.Title)
+		assert.Equal(t, QuickFix, codeAction.Kind)
+		assert.True(t, codeAction.IsPreferred)
+		require.Len(t, codeAction.Diagnostics, 1)
+		assert.Equal(t, `sound resource "Sound1" not found`, codeAction.Diagnostics[0].Message)
+		assert.Equal(t, Range{
+			Start: Position{Line: 5, Character: 6},
+			End:   Position{Line: 5, Character: 14},
+		}, codeAction.Diagnostics[0].Range)
+
+		require.NotNil(t, codeAction.Edit)
+		require.Len(t, codeAction.Edit.DocumentChanges, 2)
+		assert.Equal(t, &CreateFile{
+			Kind: "create",
+			URI:  "file:///assets/sounds/Sound1/index.json",
+		}, codeAction.Edit.DocumentChanges[0].CreateFile)
+		textDocumentEdit := codeAction.Edit.DocumentChanges[1].TextDocumentEdit
+		require.NotNil(t, textDocumentEdit)
+		assert.Equal(t, DocumentURI("file:///assets/sounds/Sound1/index.json"), textDocumentEdit.TextDocument.URI)
+		assert.Equal(t, []Or_TextDocumentEdit_edits_Elem{{Value: TextEdit{NewText: spxSoundResourceStub}}}, textDocumentEdit.Edits)
+	})
+
+	t.Run("MissingSprite", func(t *testing.T) {
+		s := newServer()
+
+		codeActions, err := s.textDocumentCodeAction(context.Background(), &CodeActionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Range:        lineRange(2),
+		})
+		require.NoError(t, err)
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, `Create sprite resource "Sprite1"`, codeActions[0].Title)
+		require.NotNil(t, codeActions[0].Edit)
+		require.Len(t, codeActions[0].Edit.DocumentChanges, 2)
+		assert.Equal(t, DocumentURI("file:///assets/sprites/Sprite1/index.json"), codeActions[0].Edit.DocumentChanges[0].CreateFile.URI)
+	})
+
+	t.Run("OtherResource", func(t *testing.T) {
+		s := newServer()
+
+		codeActions, err := s.textDocumentCodeAction(context.Background(), &CodeActionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Range:        lineRange(6),
+			Context:      protocol.CodeActionContext{Only: []CodeActionKind{QuickFix}},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, codeActions)
+	})
+
+	t.Run("OnlyOtherKinds", func(t *testing.T) {
+		s := newServer()
+
+		codeActions, err := s.textDocumentCodeAction(context.Background(), &CodeActionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Range:        lineRange(5),
+			Context:      protocol.CodeActionContext{Only: []CodeActionKind{protocol.Source}},
+		})
+		require.NoError(t, err)
+		assert.Nil(t, codeActions)
+	})
+
+	t.Run("ResourceOperationsUnsupported", func(t *testing.T) {
+		s := newServer()
+		s.clientCapabilities.Workspace.WorkspaceEdit = nil
+
+		codeActions, err := s.textDocumentCodeAction(context.Background(), &CodeActionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Range:        lineRange(5),
+			Context:      protocol.CodeActionContext{Only: []CodeActionKind{QuickFix}},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, codeActions)
+	})
+}
+
+func TestServerSpxMoveResourceBindingCodeActions(t *testing.T) {
+	codeActions := func(t *testing.T, mainSpx string) []CodeAction {
+		m := map[string][]byte{
+			"main.spx":                        []byte(mainSpx),
+			"assets/index.json":               []byte(`{}`),
+			"assets/sounds/Sound1/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		codeActions, err := s.textDocumentCodeAction(context.Background(), &CodeActionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Range: Range{
+				Start: Position{Line: 0, Character: 0},
+				End:   Position{Line: 100, Character: 0},
+			},
+		})
+		require.NoError(t, err)
+		return codeActions
+	}
+
+	t.Run("IntoExistingBlock", func(t *testing.T) {
+		codeActions := codeActions(t, `var (
+	x int
+)
+play "Sound1"
+var (
+	Sound1 Sound
+)
+`)
+		require.Len(t, codeActions, 1)
+		codeAction := codeActions[0]
+		assert.Equal(t, `Move "Sound1" into the first var block`, codeAction.Title)
+		assert.Equal(t, QuickFix, codeAction.Kind)
+		assert.True(t, codeAction.IsPreferred)
+		require.Len(t, codeAction.Diagnostics, 1)
+		assert.Equal(t, spxResourceAutoBindingNotInFirstVarBlockMessage, codeAction.Diagnostics[0].Message)
+		assert.Equal(t, map[DocumentURI][]TextEdit{
+			"file:///main.spx": {
+				{
+					Range: Range{
+						Start: Position{Line: 4, Character: 0},
+						End:   Position{Line: 7, Character: 0},
+					},
+				},
+				{
+					Range: Range{
+						Start: Position{Line: 2, Character: 0},
+						End:   Position{Line: 2, Character: 0},
+					},
+					NewText: "\tSound1 Sound\n",
+				},
+			},
+		}, codeAction.Edit.Changes)
+	})
+
+	t.Run("FromBlockWithOtherSpecs", func(t *testing.T) {
+		codeActions := codeActions(t, `var (
+	x int
+)
+play "Sound1"
+var (
+	y      int
+	Sound1 Sound
+)
+`)
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, map[DocumentURI][]TextEdit{
+			"file:///main.spx": {
+				{
+					Range: Range{
+						Start: Position{Line: 6, Character: 0},
+						End:   Position{Line: 7, Character: 0},
+					},
+				},
+				{
+					Range: Range{
+						Start: Position{Line: 2, Character: 0},
+						End:   Position{Line: 2, Character: 0},
+					},
+					NewText: "\tSound1 Sound\n",
+				},
+			},
+		}, codeActions[0].Edit.Changes)
+	})
+
+	t.Run("IntoSingleSpecDecl", func(t *testing.T) {
+		codeActions := codeActions(t, `var x int
+play "Sound1"
+var Sound1 Sound
+`)
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, map[DocumentURI][]TextEdit{
+			"file:///main.spx": {
+				{
+					Range: Range{
+						Start: Position{Line: 2, Character: 0},
+						End:   Position{Line: 3, Character: 0},
+					},
+				},
+				{
+					Range: Range{
+						Start: Position{Line: 0, Character: 0},
+						End:   Position{Line: 0, Character: 9},
+					},
+					NewText: "var (\n\tx int\n\tSound1 Sound\n)",
+				},
+			},
+		}, codeActions[0].Edit.Changes)
+	})
+
+	t.Run("CreateBlock", func(t *testing.T) {
+		codeActions := codeActions(t, `play "Sound1"
+var Sound1 Sound
+`)
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, map[DocumentURI][]TextEdit{
+			"file:///main.spx": {
+				{
+					Range: Range{
+						Start: Position{Line: 1, Character: 0},
+						End:   Position{Line: 2, Character: 0},
+					},
+				},
+				{
+					Range: Range{
+						Start: Position{Line: 0, Character: 0},
+						End:   Position{Line: 0, Character: 0},
+					},
+					NewText: "var (\n\tSound1 Sound\n)\n\n",
+				},
+			},
+		}, codeActions[0].Edit.Changes)
+	})
+
+	t.Run("WithInitialValue", func(t *testing.T) {
+		codeActions := codeActions(t, `var (
+	x int
+)
+play "Sound1"
+var Sound1 Sound = nil
+`)
+		assert.Empty(t, codeActions)
+	})
+
+	t.Run("NotInRange", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`var (
+	x int
+)
+play "Sound1"
+var Sound1 Sound
+`),
+			"assets/index.json":               []byte(`{}`),
+			"assets/sounds/Sound1/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		codeActions, err := s.textDocumentCodeAction(context.Background(), &CodeActionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Range: Range{
+				Start: Position{Line: 0, Character: 0},
+				End:   Position{Line: 1, Character: 0},
+			},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, codeActions)
+	})
+}
+
+func TestServerAnalyzerFixCodeActions(t *testing.T) {
+	t.Run("DeprecatedFunc", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+import "io/ioutil"
+
+data, _ := ioutil.ReadFile("data.txt")
+echo data
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		codeActions, err := s.textDocumentCodeAction(context.Background(), &CodeActionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Range: Range{
+				Start: Position{Line: 3, Character: 0},
+				End:   Position{Line: 3, Character: 30},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, codeActions, 1)
+
+		codeAction := codeActions[0]
+		assert.Equal(t, "Replace with os.ReadFile", codeAction.Title)
+		assert.Equal(t, QuickFix, codeAction.Kind)
+		assert.True(t, codeAction.IsPreferred)
+		require.Len(t, codeAction.Diagnostics, 1)
+		assert.Equal(t, "ReadFile is deprecated: use os.ReadFile instead", codeAction.Diagnostics[0].Message)
+		assert.Equal(t, "deprecated", codeAction.Diagnostics[0].Source)
+		assert.Equal(t, []DiagnosticTag{Deprecated}, codeAction.Diagnostics[0].Tags)
+		require.NotNil(t, codeAction.Edit)
+		assert.Equal(t, map[DocumentURI][]TextEdit{
+			"file:///main.spx": {
+				{
+					Range: Range{
+						Start: Position{Line: 3, Character: 11},
+						End:   Position{Line: 3, Character: 26},
+					},
+					NewText: "os.ReadFile",
+				},
+				{
+					Range: Range{
+						Start: Position{Line: 1, Character: 18},
+						End:   Position{Line: 1, Character: 18},
+					},
+					NewText: "\nimport \"os\"",
+				},
+			},
+		}, codeAction.Edit.Changes)
+	})
+
+	t.Run("NoFixes", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+a := []int{}
+a = append(a)
+echo a
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		codeActions, err := s.textDocumentCodeAction(context.Background(), &CodeActionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Range: Range{
+				Start: Position{Line: 2, Character: 0},
+				End:   Position{Line: 2, Character: 13},
+			},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, codeActions)
+	})
+}
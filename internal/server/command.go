@@ -2,21 +2,44 @@ package server
 
 import (
 	"cmp"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"go/types"
+	"maps"
+	"path"
 	"slices"
 	"strconv"
 	"strings"
 
 	xgoast "github.com/goplus/xgo/ast"
 	xgotoken "github.com/goplus/xgo/token"
+	"github.com/goplus/xgolsw/xgo"
 	"github.com/goplus/xgolsw/xgo/xgoutil"
 )
 
+// commandFeatures are the project features required by the commands. See
+// [methodFeatures].
+var commandFeatures = map[string]uint{
+	"spx.renameResources":       xgo.FeatTypeInfoCache,
+	"spx.renameResource":        xgo.FeatTypeInfoCache,
+	"spx.getInputSlots":         xgo.FeatTypeInfoCache,
+	"spx.getDefinitions":        xgo.FeatTypeInfoCache,
+	"spx.listResources":         xgo.FeatTypeInfoCache,
+	"spx.getResourceReferences": xgo.FeatTypeInfoCache,
+	"spx.generateGo":            xgo.FeatTypeInfoCache,
+	"spx.createSprite":          xgo.FeatTypeInfoCache,
+	"spx.organizeImports":       xgo.FeatTypeInfoCache,
+	"spx.getSpriteAPIs":         xgo.FeatTypeInfoCache,
+	"spx.runAnalyzers":          xgo.FeatTypeInfoCache,
+}
+
 // See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#workspace_executeCommand
-func (s *Server) workspaceExecuteCommand(params *ExecuteCommandParams) (any, error) {
+func (s *Server) workspaceExecuteCommand(ctx context.Context, params *ExecuteCommandParams) (any, error) {
+	if feats, ok := commandFeatures[params.Command]; ok && !s.hasFeatures(feats) {
+		return nil, fmt.Errorf("command %s is not supported without type checking", params.Command)
+	}
 	switch params.Command {
 	case "spx.renameResources":
 		var cmdParams []SpxRenameResourceParams
@@ -27,7 +50,16 @@ func (s *Server) workspaceExecuteCommand(params *ExecuteCommandParams) (any, err
 			}
 			cmdParams = append(cmdParams, cmdParam)
 		}
-		return s.spxRenameResources(cmdParams)
+		return s.spxRenameResources(ctx, cmdParams)
+	case "spx.renameResource":
+		if len(params.Arguments) != 1 {
+			return nil, fmt.Errorf("command %s expects exactly 1 argument, got %d", params.Command, len(params.Arguments))
+		}
+		var cmdParam SpxRenameResourceParams
+		if err := json.Unmarshal(params.Arguments[0], &cmdParam); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal command argument as SpxRenameResourceParams: %w", err)
+		}
+		return s.spxRenameResource(ctx, cmdParam)
 	case "spx.getInputSlots":
 		var cmdParams []SpxGetInputSlotsParams
 		for _, arg := range params.Arguments {
@@ -37,31 +69,380 @@ func (s *Server) workspaceExecuteCommand(params *ExecuteCommandParams) (any, err
 			}
 			cmdParams = append(cmdParams, cmdParam)
 		}
-		return s.spxGetInputSlots(cmdParams)
+		return s.spxGetInputSlots(ctx, cmdParams)
+	case "spx.getDefinitions":
+		var cmdParams []SpxGetDefinitionsParams
+		for _, arg := range params.Arguments {
+			var cmdParam SpxGetDefinitionsParams
+			if err := json.Unmarshal(arg, &cmdParam); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal command argument as SpxGetDefinitionsParams: %w", err)
+			}
+			cmdParams = append(cmdParams, cmdParam)
+		}
+		return s.spxGetDefinitions(ctx, cmdParams)
+	case "spx.getSpriteAPIs":
+		var cmdParams []SpxGetSpriteAPIsParams
+		for _, arg := range params.Arguments {
+			var cmdParam SpxGetSpriteAPIsParams
+			if err := json.Unmarshal(arg, &cmdParam); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal command argument as SpxGetSpriteAPIsParams: %w", err)
+			}
+			cmdParams = append(cmdParams, cmdParam)
+		}
+		return s.spxGetSpriteAPIs(ctx, cmdParams)
+	case "spx.diagnoseWorkspace":
+		return s.spxDiagnoseWorkspace(ctx)
+	case "spx.runAnalyzers":
+		if len(params.Arguments) != 1 {
+			return nil, fmt.Errorf("command %s expects exactly 1 argument, got %d", params.Command, len(params.Arguments))
+		}
+		var cmdParam SpxRunAnalyzersParams
+		if err := json.Unmarshal(params.Arguments[0], &cmdParam); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal command argument as SpxRunAnalyzersParams: %w", err)
+		}
+		return s.spxRunAnalyzers(ctx, cmdParam)
+	case "spx.formatProject":
+		return s.spxFormatProject()
+	case "spx.organizeImports":
+		return s.spxOrganizeImports(ctx)
+	case "spx.clearCaches":
+		s.spxClearCaches()
+		return nil, nil
+	case "spx.getMetrics":
+		return s.Metrics(), nil
+	case "spx.listResources":
+		return s.spxListResources(ctx)
+	case "spx.getResourceReferences":
+		var cmdParams []SpxGetResourceReferencesParams
+		for _, arg := range params.Arguments {
+			var cmdParam SpxGetResourceReferencesParams
+			if err := json.Unmarshal(arg, &cmdParam); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal command argument as SpxGetResourceReferencesParams: %w", err)
+			}
+			cmdParams = append(cmdParams, cmdParam)
+		}
+		return s.spxGetResourceReferences(ctx, cmdParams)
+	case "spx.listAnalyzers":
+		return s.spxListAnalyzers(), nil
+	case "spx.generateGo":
+		return s.spxGenerateGo()
+	case "spx.createSprite":
+		if len(params.Arguments) != 1 {
+			return nil, fmt.Errorf("command %s expects exactly 1 argument, got %d", params.Command, len(params.Arguments))
+		}
+		var cmdParam SpxCreateSpriteParams
+		if err := json.Unmarshal(params.Arguments[0], &cmdParam); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal command argument as SpxCreateSpriteParams: %w", err)
+		}
+		return s.spxCreateSprite(ctx, cmdParam)
 	}
 	return nil, fmt.Errorf("unknown command: %s", params.Command)
 }
 
+// spxListAnalyzers returns all analyzers of the server sorted by name, along
+// with their defaults and their configuration in the current settings.
+func (s *Server) spxListAnalyzers() []SpxAnalyzerInfo {
+	settings := s.getSettings()
+	infos := make([]SpxAnalyzerInfo, 0, len(s.analyzers))
+	for _, analyzer := range s.analyzers {
+		infos = append(infos, SpxAnalyzerInfo{
+			Name:             analyzer.Name(),
+			Doc:              analyzer.Doc(),
+			EnabledByDefault: analyzer.EnabledByDefault(),
+			DefaultSeverity:  analyzerSeverityName(DiagnosticSeverity(analyzer.Severity())),
+			Enabled:          settings.analyzerEnabled(analyzer),
+			Severity:         analyzerSeverityName(settings.analyzerSeverity(analyzer)),
+		})
+	}
+	return infos
+}
+
+// spxListResources returns all spx resources of the project, sorted by name
+// within each kind. Sprite costumes keep their order in the sprite metadata.
+func (s *Server) spxListResources(ctx context.Context) (*SpxResourceList, error) {
+	result, err := s.compileWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	set := result.spxResourceSet
+	rootDir := result.spxResourceRootDir
+	fileURI := func(elem ...string) DocumentURI {
+		if elem[len(elem)-1] == "" {
+			return ""
+		}
+		return s.toDocumentURI(path.Join(append([]string{rootDir}, elem...)...))
+	}
+
+	list := &SpxResourceList{
+		Backdrops: []SpxBackdropResourceInfo{},
+		Sounds:    []SpxSoundResourceInfo{},
+		Sprites:   []SpxSpriteResourceInfo{},
+		Widgets:   []SpxWidgetResourceInfo{},
+	}
+	for _, name := range slices.Sorted(maps.Keys(set.backdrops)) {
+		backdrop := set.backdrops[name]
+		list.Backdrops = append(list.Backdrops, SpxBackdropResourceInfo{
+			URI:              backdrop.ID.URI(),
+			Name:             backdrop.Name,
+			File:             fileURI(backdrop.Path),
+			BitmapResolution: backdrop.BitmapResolution,
+		})
+	}
+	for _, name := range slices.Sorted(maps.Keys(set.sounds)) {
+		sound := set.sounds[name]
+		list.Sounds = append(list.Sounds, SpxSoundResourceInfo{
+			URI:      sound.ID.URI(),
+			Name:     sound.Name,
+			File:     fileURI("sounds", sound.Name, sound.Path),
+			Format:   sound.Format(),
+			Duration: sound.Duration().Seconds(),
+			Rate:     sound.Rate,
+		})
+	}
+	for _, name := range slices.Sorted(maps.Keys(set.sprites)) {
+		sprite := set.sprites[name]
+		info := SpxSpriteResourceInfo{
+			URI:              sprite.ID.URI(),
+			Name:             sprite.Name,
+			Costumes:         make([]SpxSpriteCostumeResourceInfo, 0, len(sprite.Costumes)),
+			CostumeIndex:     sprite.CostumeIndex,
+			Animations:       make([]SpxSpriteAnimationResourceInfo, 0, len(sprite.Animations)),
+			DefaultAnimation: sprite.DefaultAnimation,
+		}
+		for _, costume := range sprite.Costumes {
+			info.Costumes = append(info.Costumes, SpxSpriteCostumeResourceInfo{
+				URI:              costume.ID.URI(),
+				Name:             costume.Name,
+				File:             fileURI("sprites", sprite.Name, costume.Path),
+				BitmapResolution: costume.BitmapResolution,
+			})
+		}
+		animations := slices.SortedFunc(slices.Values(sprite.Animations), func(a, b SpxSpriteAnimationResource) int {
+			return cmp.Compare(a.Name, b.Name)
+		})
+		for _, animation := range animations {
+			frames := []string{}
+			for i, costume := range sprite.Costumes {
+				if animation.includeCostume(i) {
+					frames = append(frames, costume.Name)
+				}
+			}
+			info.Animations = append(info.Animations, SpxSpriteAnimationResourceInfo{
+				URI:    animation.ID.URI(),
+				Name:   animation.Name,
+				Frames: frames,
+			})
+		}
+		list.Sprites = append(list.Sprites, info)
+	}
+	for _, name := range slices.Sorted(maps.Keys(set.widgets)) {
+		widget := set.widgets[name]
+		list.Widgets = append(list.Widgets, SpxWidgetResourceInfo{
+			URI:   widget.ID.URI(),
+			Name:  widget.Name,
+			Type:  widget.Type,
+			Label: widget.Label,
+		})
+	}
+	return list, nil
+}
+
+// spxGetResourceReferences returns the references to the spx resources given
+// by params, or to all spx resources if params is empty, sorted by document
+// and position.
+func (s *Server) spxGetResourceReferences(ctx context.Context, params []SpxGetResourceReferencesParams) ([]SpxResourceReference, error) {
+	ids := make(map[SpxResourceID]struct{}, len(params))
+	for _, param := range params {
+		id, err := ParseSpxResourceURI(param.Resource.URI)
+		if err != nil {
+			return nil, err
+		}
+		ids[id] = struct{}{}
+	}
+
+	result, err := s.compileWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.spxResourceReferences(result, ids), nil
+}
+
+// spxResourceReferences returns the references to the spx resources in ids, or
+// to all spx resources if ids is empty, sorted by document and position.
+func (s *Server) spxResourceReferences(result *compileResult, ids map[SpxResourceID]struct{}) []SpxResourceReference {
+	refs := []SpxResourceReference{}
+	for _, ref := range result.spxResourceRefs {
+		if _, ok := ids[ref.ID]; !ok && len(ids) > 0 {
+			continue
+		}
+		refs = append(refs, SpxResourceReference{
+			Resource: ref.ID.URI(),
+			Kind:     ref.Kind,
+			Location: s.locationForNode(result.proj, ref.Node),
+		})
+	}
+	slices.SortFunc(refs, func(a, b SpxResourceReference) int {
+		return cmp.Or(
+			cmp.Compare(a.Location.URI, b.Location.URI),
+			cmp.Compare(a.Location.Range.Start.Line, b.Location.Range.Start.Line),
+			cmp.Compare(a.Location.Range.Start.Character, b.Location.Range.Start.Character),
+			cmp.Compare(a.Resource, b.Resource),
+		)
+	})
+	return refs
+}
+
+// spxGenerateGo compiles the project to Go and returns the generated Go source
+// files. It fails if the project has any parse or type errors.
+func (s *Server) spxGenerateGo() (*SpxGeneratedGo, error) {
+	files, err := s.getProj().Snapshot().GenGo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate Go code: %w", err)
+	}
+
+	generated := &SpxGeneratedGo{
+		Files: make([]SpxGeneratedGoFile, 0, len(files)),
+	}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		generated.Files = append(generated.Files, SpxGeneratedGoFile{
+			Name:    name,
+			Content: string(files[name]),
+		})
+	}
+	return generated, nil
+}
+
+// spxSpriteClassfileStub is the content of a newly scaffolded spx sprite
+// classfile.
+const spxSpriteClassfileStub = `onStart => {
+}
+`
+
+// spxCreateSprite scaffolds a new spx sprite, which consists of its classfile
+// with a starter handler and its resource metadata. The files are created by
+// the returned [WorkspaceEdit] rather than by the server.
+func (s *Server) spxCreateSprite(ctx context.Context, param SpxCreateSpriteParams) (*WorkspaceEdit, error) {
+	name := param.Name
+	if !xgotoken.IsIdentifier(name) {
+		return nil, fmt.Errorf("invalid sprite name %q: it must be a valid identifier", name)
+	}
+
+	result, err := s.compileWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if result.spxResourceSet.Sprite(name) != nil {
+		return nil, fmt.Errorf("sprite resource %q already exists", name)
+	}
+	if typeInfo, _ := result.proj.TypeInfo(); typeInfo != nil && typeInfo.Pkg().Scope().Lookup(name) != nil {
+		return nil, fmt.Errorf("%q is already declared in the project", name)
+	}
+	classfile := name + ".spx"
+	spriteDir := path.Join(result.spxResourceRootDir, "sprites", name)
+	for filePath := range result.proj.Files() {
+		if filePath == classfile || strings.HasPrefix(filePath, spriteDir+"/") {
+			return nil, fmt.Errorf("file %q already exists", filePath)
+		}
+	}
+
+	var documentChanges []DocumentChange
+	for _, file := range []struct {
+		path    string
+		content string
+	}{
+		{classfile, spxSpriteClassfileStub},
+		{path.Join(spriteDir, "index.json"), spxSpriteResourceStub},
+	} {
+		documentURI := s.toDocumentURI(file.path)
+		documentChanges = append(documentChanges,
+			DocumentChange{CreateFile: &CreateFile{
+				Kind: "create",
+				URI:  documentURI,
+			}},
+			DocumentChange{TextDocumentEdit: &TextDocumentEdit{
+				TextDocument: OptionalVersionedTextDocumentIdentifier{
+					TextDocumentIdentifier: TextDocumentIdentifier{URI: documentURI},
+				},
+				Edits: []Or_TextDocumentEdit_edits_Elem{{Value: TextEdit{NewText: file.content}}},
+			}},
+		)
+	}
+	return &WorkspaceEdit{DocumentChanges: documentChanges}, nil
+}
+
+// spxClearCaches clears all caches of the server, including the caches shared
+// with other servers. They are rebuilt as needed.
+func (s *Server) spxClearCaches() {
+	s.compileMu.Lock()
+	s.lastCompile = nil
+	s.lastSpxResourceSet.Store(nil)
+	s.compileMu.Unlock()
+	s.semanticTokens.Clear()
+
+	s.getProj().ClearCaches()
+	nonMainPkgSpxDefCache.clear()
+}
+
 // spxRenameResources renames spx resources in the workspace.
-func (s *Server) spxRenameResources(params []SpxRenameResourceParams) (*WorkspaceEdit, error) {
-	result, err := s.compile()
+func (s *Server) spxRenameResources(ctx context.Context, params []SpxRenameResourceParams) (*WorkspaceEdit, error) {
+	result, err := s.compileWithContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 	return s.spxRenameResourcesWithCompileResult(result, params)
 }
 
+// spxRenameResource renames an spx resource in the workspace. Unlike
+// [Server.spxRenameResources], it also returns the references to the resource,
+// so the rename can be previewed before it is applied.
+func (s *Server) spxRenameResource(ctx context.Context, param SpxRenameResourceParams) (*SpxRenameResourceResult, error) {
+	id, err := ParseSpxResourceURI(param.Resource.URI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse spx resource URI: %w", err)
+	}
+
+	result, err := s.compileWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	edit, err := s.spxRenameResourcesWithCompileResult(result, []SpxRenameResourceParams{param})
+	if err != nil {
+		return nil, err
+	}
+	return &SpxRenameResourceResult{
+		OldURI:     id.URI(),
+		NewURI:     SpxResourceURI(fmt.Sprintf("%s/%s", id.ContextURI(), param.NewName)),
+		Edit:       edit,
+		References: s.spxResourceReferences(result, map[SpxResourceID]struct{}{id: {}}),
+	}, nil
+}
+
 // spxRenameResourcesWithCompileResult renames spx resources in the workspace with the given compile result.
 func (s *Server) spxRenameResourcesWithCompileResult(result *compileResult, params []SpxRenameResourceParams) (*WorkspaceEdit, error) {
 	workspaceEdit := WorkspaceEdit{
 		Changes: make(map[DocumentURI][]TextEdit),
 	}
+	var (
+		renameFiles          []RenameFile
+		includeResourceFiles bool
+	)
 	seenTextEdits := make(map[DocumentURI]map[TextEdit]struct{})
+	renamedIDs := make(map[SpxResourceID]struct{}, len(params))
+	newURIs := make(map[SpxResourceURI]SpxResourceURI, len(params))
 	for _, param := range params {
 		id, err := ParseSpxResourceURI(param.Resource.URI)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse spx resource URI: %w", err)
 		}
+		if _, ok := renamedIDs[id]; ok {
+			return nil, fmt.Errorf("spx resource %q cannot be renamed more than once", id.URI())
+		}
+		renamedIDs[id] = struct{}{}
+		newURI := SpxResourceURI(fmt.Sprintf("%s/%s", id.ContextURI(), param.NewName))
+		if otherURI, ok := newURIs[newURI]; ok {
+			return nil, fmt.Errorf("spx resources %q and %q cannot both be renamed to %q", otherURI, id.URI(), param.NewName)
+		}
+		newURIs[newURI] = id.URI()
 		var changes map[DocumentURI][]TextEdit
 		switch id := id.(type) {
 		case SpxBackdropResourceID:
@@ -79,6 +460,17 @@ func (s *Server) spxRenameResourcesWithCompileResult(result *compileResult, para
 		default:
 			return nil, fmt.Errorf("unsupported spx resource type: %T", id)
 		}
+		if err == nil && param.IncludeResourceFiles {
+			includeResourceFiles = true
+
+			var (
+				fileChanges map[DocumentURI][]TextEdit
+				renames     []RenameFile
+			)
+			fileChanges, renames, err = s.spxResourceFileChanges(result, id, param.NewName)
+			changes = mergeTextEdits(changes, fileChanges)
+			renameFiles = append(renameFiles, renames...)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to rename spx resource %q: %w", param.Resource.URI, err)
 		}
@@ -96,11 +488,40 @@ func (s *Server) spxRenameResourcesWithCompileResult(result *compileResult, para
 			}
 		}
 	}
+	if includeResourceFiles {
+		return toDocumentChangesWorkspaceEdit(workspaceEdit.Changes, renameFiles), nil
+	}
 	return &workspaceEdit, nil
 }
 
+// toDocumentChangesWorkspaceEdit returns a [WorkspaceEdit] that applies the
+// given text edits, followed by the given file renames, using documentChanges.
+// Text edits are applied before renames so they refer to the original files.
+func toDocumentChangesWorkspaceEdit(changes map[DocumentURI][]TextEdit, renameFiles []RenameFile) *WorkspaceEdit {
+	documentURIs := slices.Sorted(maps.Keys(changes))
+	documentChanges := make([]DocumentChange, 0, len(documentURIs)+len(renameFiles))
+	for _, documentURI := range documentURIs {
+		edits := make([]Or_TextDocumentEdit_edits_Elem, 0, len(changes[documentURI]))
+		for _, textEdit := range changes[documentURI] {
+			edits = append(edits, Or_TextDocumentEdit_edits_Elem{Value: textEdit})
+		}
+		documentChanges = append(documentChanges, DocumentChange{
+			TextDocumentEdit: &TextDocumentEdit{
+				TextDocument: OptionalVersionedTextDocumentIdentifier{
+					TextDocumentIdentifier: TextDocumentIdentifier{URI: documentURI},
+				},
+				Edits: edits,
+			},
+		})
+	}
+	for _, renameFile := range renameFiles {
+		documentChanges = append(documentChanges, DocumentChange{RenameFile: &renameFile})
+	}
+	return &WorkspaceEdit{DocumentChanges: documentChanges}
+}
+
 // spxGetInputSlots gets input slots in a document.
-func (s *Server) spxGetInputSlots(params []SpxGetInputSlotsParams) ([]SpxInputSlot, error) {
+func (s *Server) spxGetInputSlots(ctx context.Context, params []SpxGetInputSlotsParams) ([]SpxInputSlot, error) {
 	if l := len(params); l == 0 {
 		return nil, nil
 	} else if l > 1 {
@@ -108,7 +529,7 @@ func (s *Server) spxGetInputSlots(params []SpxGetInputSlotsParams) ([]SpxInputSl
 	}
 	param := params[0]
 
-	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(param.TextDocument.URI)
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(ctx, param.TextDocument.URI)
 	if err != nil {
 		return nil, err
 	}
@@ -119,6 +540,45 @@ func (s *Server) spxGetInputSlots(params []SpxGetInputSlotsParams) ([]SpxInputSl
 	return findInputSlots(result, astFile), nil
 }
 
+// spxGetDefinitions returns the spx definitions of the symbol at the given
+// position, one for each overload of an overloaded function.
+func (s *Server) spxGetDefinitions(ctx context.Context, params []SpxGetDefinitionsParams) ([]SpxDefinitionInfo, error) {
+	if l := len(params); l == 0 {
+		return nil, nil
+	} else if l > 1 {
+		return nil, errors.New("spx.getDefinitions only supports one position at a time")
+	}
+	param := params[0]
+
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(ctx, param.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	if astFile == nil || !astFile.Pos().IsValid() {
+		return nil, nil
+	}
+
+	ident, spxDefs := spxDefinitionsAtPosition(result, astFile, param.Position)
+	if ident == nil {
+		return nil, nil
+	}
+	infos := make([]SpxDefinitionInfo, 0, len(spxDefs))
+	for _, spxDef := range spxDefs {
+		info := SpxDefinitionInfo{
+			ID:       spxDef.ID,
+			Overview: spxDef.Overview,
+			Detail:   spxDef.Detail,
+			Kind:     spxDef.CompletionItemKind,
+			Range:    RangeForNode(result.proj, ident),
+		}
+		if spxDef.TypeHint != nil {
+			info.Type = GetSimplifiedTypeString(spxDef.TypeHint)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
 // findInputSlots finds all input slots in the AST file.
 func findInputSlots(result *compileResult, astFile *xgoast.File) []SpxInputSlot {
 	typeInfo, _ := result.proj.TypeInfo()
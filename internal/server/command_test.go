@@ -1,13 +1,17 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
 	"go/types"
 	"reflect"
 	"slices"
+	"strings"
 	"testing"
 
 	xgoast "github.com/goplus/xgo/ast"
 	xgotoken "github.com/goplus/xgo/token"
+	"github.com/goplus/xgolsw/xgo"
 	"github.com/goplus/xgolsw/xgo/xgoutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -51,7 +55,7 @@ onStart => {
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
 		params := []SpxGetInputSlotsParams{{TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"}}}
-		inputSlots, err := s.spxGetInputSlots(params)
+		inputSlots, err := s.spxGetInputSlots(context.Background(), params)
 		require.NoError(t, err)
 		require.NotNil(t, inputSlots)
 		assert.Greater(t, len(inputSlots), 10)
@@ -222,7 +226,7 @@ var (
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
 		params := []SpxGetInputSlotsParams{{TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"}}}
-		inputSlots, err := s.spxGetInputSlots(params)
+		inputSlots, err := s.spxGetInputSlots(context.Background(), params)
 		require.NoError(t, err)
 		assert.Nil(t, inputSlots)
 	})
@@ -234,7 +238,7 @@ var (
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
 		params := []SpxGetInputSlotsParams{{TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"}}}
-		inputSlots, err := s.spxGetInputSlots(params)
+		inputSlots, err := s.spxGetInputSlots(context.Background(), params)
 		require.NoError(t, err)
 		assert.Empty(t, inputSlots)
 	})
@@ -244,7 +248,7 @@ var (
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
 		params := []SpxGetInputSlotsParams{{TextDocument: TextDocumentIdentifier{URI: "file:///nonexistent.spx"}}}
-		inputSlots, err := s.spxGetInputSlots(params)
+		inputSlots, err := s.spxGetInputSlots(context.Background(), params)
 		require.Error(t, err)
 		assert.Nil(t, inputSlots)
 	})
@@ -259,7 +263,7 @@ var (
 			{TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"}},
 			{TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"}},
 		}
-		inputSlots, err := s.spxGetInputSlots(params)
+		inputSlots, err := s.spxGetInputSlots(context.Background(), params)
 		require.Error(t, err)
 		assert.Nil(t, inputSlots)
 		assert.ErrorContains(t, err, "only supports one document")
@@ -272,12 +276,737 @@ var (
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
 		params := []SpxGetInputSlotsParams{}
-		inputSlots, err := s.spxGetInputSlots(params)
+		inputSlots, err := s.spxGetInputSlots(context.Background(), params)
 		require.NoError(t, err)
 		assert.Nil(t, inputSlots)
 	})
 }
 
+func TestServerSpxGetDefinitions(t *testing.T) {
+	m := map[string][]byte{
+		"main.spx": []byte(`
+var (
+	MySprite Sprite
+)
+// count is a counter.
+var count int
+count++
+run "assets", {Title: "My Game"}
+`),
+		"MySprite.spx": []byte(`
+onStart => {
+	turn Left
+}
+`),
+		"assets/index.json":                  []byte(`{}`),
+		"assets/sprites/MySprite/index.json": []byte(`{}`),
+	}
+	newParams := func(documentURI DocumentURI, position Position) json.RawMessage {
+		var params SpxGetDefinitionsParams
+		params.TextDocument.URI = documentURI
+		params.Position = position
+		arg, err := json.Marshal(params)
+		require.NoError(t, err)
+		return arg
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		result, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{
+			Command:   "spx.getDefinitions",
+			Arguments: []json.RawMessage{newParams("file:///main.spx", Position{Line: 6, Character: 1})},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []SpxDefinitionInfo{{
+			ID: SpxDefinitionIdentifier{
+				Package: ToPtr("main"),
+				Name:    ToPtr("count"),
+			},
+			Overview: "var count int",
+			Detail:   "count is a counter.\n",
+			Kind:     VariableCompletion,
+			Type:     "int",
+			Range: Range{
+				Start: Position{Line: 6, Character: 0},
+				End:   Position{Line: 6, Character: 5},
+			},
+		}}, result)
+	})
+
+	t.Run("OverloadedFunc", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		result, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{
+			Command:   "spx.getDefinitions",
+			Arguments: []json.RawMessage{newParams("file:///MySprite.spx", Position{Line: 2, Character: 2})},
+		})
+		require.NoError(t, err)
+		require.IsType(t, []SpxDefinitionInfo{}, result)
+		infos := result.([]SpxDefinitionInfo)
+		require.Len(t, infos, 1)
+		assert.Equal(t, "xgo:github.com/goplus/spx/v2?Sprite.turn#0", infos[0].ID.String())
+		assert.Equal(t, "func turn(dir Direction)", infos[0].Overview)
+		assert.Equal(t, FunctionCompletion, infos[0].Kind)
+	})
+
+	t.Run("NoSymbol", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		result, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{
+			Command:   "spx.getDefinitions",
+			Arguments: []json.RawMessage{newParams("file:///main.spx", Position{Line: 0, Character: 0})},
+		})
+		require.NoError(t, err)
+		assert.Nil(t, result)
+	})
+
+	t.Run("MultipleParams", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{
+			Command: "spx.getDefinitions",
+			Arguments: []json.RawMessage{
+				newParams("file:///main.spx", Position{Line: 6, Character: 1}),
+				newParams("file:///MySprite.spx", Position{Line: 2, Character: 2}),
+			},
+		})
+		require.EqualError(t, err, "spx.getDefinitions only supports one position at a time")
+	})
+}
+
+func TestServerSpxRenameResources(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		result, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{
+			Command:   "spx.renameResources",
+			Arguments: []json.RawMessage{json.RawMessage(`{"resource":{"uri":"spx://resources/sprites/MyAircraft"},"newName":"Hero"}`)},
+		})
+		require.NoError(t, err)
+		require.IsType(t, &WorkspaceEdit{}, result)
+		workspaceEdit := result.(*WorkspaceEdit)
+		assert.NotEmpty(t, workspaceEdit.Changes)
+		assert.Nil(t, workspaceEdit.DocumentChanges)
+		assert.NotContains(t, workspaceEdit.Changes, DocumentURI("file:///assets/index.json"))
+	})
+
+	t.Run("IncludeResourceFiles", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		result, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{
+			Command:   "spx.renameResources",
+			Arguments: []json.RawMessage{json.RawMessage(`{"resource":{"uri":"spx://resources/sprites/MyAircraft"},"newName":"Hero","includeResourceFiles":true}`)},
+		})
+		require.NoError(t, err)
+		require.IsType(t, &WorkspaceEdit{}, result)
+		workspaceEdit := result.(*WorkspaceEdit)
+		assert.Nil(t, workspaceEdit.Changes)
+
+		var (
+			editedURIs  []DocumentURI
+			renameFiles []RenameFile
+		)
+		for _, documentChange := range workspaceEdit.DocumentChanges {
+			if documentChange.TextDocumentEdit != nil {
+				editedURIs = append(editedURIs, documentChange.TextDocumentEdit.TextDocument.URI)
+			}
+			if documentChange.RenameFile != nil {
+				renameFiles = append(renameFiles, *documentChange.RenameFile)
+			}
+		}
+		assert.Contains(t, editedURIs, DocumentURI("file:///assets/index.json"))
+		assert.Contains(t, editedURIs, DocumentURI("file:///main.spx"))
+		assert.True(t, slices.IsSorted(editedURIs))
+		assert.Equal(t, []RenameFile{
+			{
+				Kind:   "rename",
+				OldURI: "file:///assets/sprites/MyAircraft",
+				NewURI: "file:///assets/sprites/Hero",
+			},
+			{
+				Kind:   "rename",
+				OldURI: "file:///MyAircraft.spx",
+				NewURI: "file:///Hero.spx",
+			},
+		}, renameFiles)
+
+		// Text edits must come before file renames.
+		lastDocumentChange := workspaceEdit.DocumentChanges[len(workspaceEdit.DocumentChanges)-1]
+		assert.NotNil(t, lastDocumentChange.RenameFile)
+	})
+
+	t.Run("ResourceDirAlreadyExists", func(t *testing.T) {
+		m := newTestFileMap()
+		m["assets/sprites/Hero/index.json"] = []byte(`{}`)
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, nil, &MockScheduler{})
+
+		_, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{
+			Command:   "spx.renameResources",
+			Arguments: []json.RawMessage{json.RawMessage(`{"resource":{"uri":"spx://resources/sprites/MyAircraft"},"newName":"Hero","includeResourceFiles":true}`)},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("ConflictingRenames", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		_, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{
+			Command: "spx.renameResources",
+			Arguments: []json.RawMessage{
+				json.RawMessage(`{"resource":{"uri":"spx://resources/sprites/MyAircraft"},"newName":"Hero"}`),
+				json.RawMessage(`{"resource":{"uri":"spx://resources/sprites/Bullet"},"newName":"Hero"}`),
+			},
+		})
+		require.EqualError(t, err, `spx resources "spx://resources/sprites/MyAircraft" and "spx://resources/sprites/Bullet" cannot both be renamed to "Hero"`)
+
+		_, err = s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{
+			Command: "spx.renameResources",
+			Arguments: []json.RawMessage{
+				json.RawMessage(`{"resource":{"uri":"spx://resources/sprites/MyAircraft"},"newName":"Hero"}`),
+				json.RawMessage(`{"resource":{"uri":"spx://resources/sprites/MyAircraft"},"newName":"Villain"}`),
+			},
+		})
+		require.EqualError(t, err, `spx resource "spx://resources/sprites/MyAircraft" cannot be renamed more than once`)
+	})
+}
+
+func TestServerSpxRenameResource(t *testing.T) {
+	m := map[string][]byte{
+		"main.spx": []byte(`
+const MySoundName = "MySound"
+play MySoundName
+run "assets", {Title: "My Game"}
+`),
+		"MySprite.spx": []byte(`
+onStart => {
+	play "MySound"
+}
+`),
+		"assets/index.json":                  []byte(`{}`),
+		"assets/sounds/MySound/index.json":   []byte(`{}`),
+		"assets/sprites/MySprite/index.json": []byte(`{}`),
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, fileMapGetter(m), &MockScheduler{})
+
+		result, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{
+			Command:   "spx.renameResource",
+			Arguments: []json.RawMessage{json.RawMessage(`{"resource":{"uri":"spx://resources/sounds/MySound"},"newName":"NewSound"}`)},
+		})
+		require.NoError(t, err)
+		require.IsType(t, &SpxRenameResourceResult{}, result)
+		renameResult := result.(*SpxRenameResourceResult)
+		assert.Equal(t, SpxResourceURI("spx://resources/sounds/MySound"), renameResult.OldURI)
+		assert.Equal(t, SpxResourceURI("spx://resources/sounds/NewSound"), renameResult.NewURI)
+		require.NotNil(t, renameResult.Edit)
+		assert.Equal(t, map[DocumentURI][]TextEdit{
+			"file:///main.spx": {{
+				Range: Range{
+					Start: Position{Line: 1, Character: 21},
+					End:   Position{Line: 1, Character: 28},
+				},
+				NewText: "NewSound",
+			}},
+			"file:///MySprite.spx": {{
+				Range: Range{
+					Start: Position{Line: 2, Character: 7},
+					End:   Position{Line: 2, Character: 14},
+				},
+				NewText: "NewSound",
+			}},
+		}, renameResult.Edit.Changes)
+
+		require.Len(t, renameResult.References, 2)
+		assert.Equal(t, DocumentURI("file:///MySprite.spx"), renameResult.References[0].Location.URI)
+		assert.Equal(t, SpxResourceRefKindStringLiteral, renameResult.References[0].Kind)
+		assert.Equal(t, DocumentURI("file:///main.spx"), renameResult.References[1].Location.URI)
+		assert.Equal(t, SpxResourceRefKindConstantReference, renameResult.References[1].Kind)
+	})
+
+	t.Run("IncludeResourceFiles", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, fileMapGetter(m), &MockScheduler{})
+
+		renameResult, err := s.spxRenameResource(context.Background(), SpxRenameResourceParams{
+			Resource:             SpxResourceIdentifier{URI: "spx://resources/sounds/MySound"},
+			NewName:              "NewSound",
+			IncludeResourceFiles: true,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, renameResult.Edit)
+		assert.Nil(t, renameResult.Edit.Changes)
+		assert.NotEmpty(t, renameResult.Edit.DocumentChanges)
+	})
+
+	t.Run("WrongArgumentCount", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, fileMapGetter(m), &MockScheduler{})
+
+		_, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{Command: "spx.renameResource"})
+		require.EqualError(t, err, "command spx.renameResource expects exactly 1 argument, got 0")
+	})
+
+	t.Run("InvalidResourceURI", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, fileMapGetter(m), &MockScheduler{})
+
+		_, err := s.spxRenameResource(context.Background(), SpxRenameResourceParams{
+			Resource: SpxResourceIdentifier{URI: "spx://resources/unknown/MyResource"},
+			NewName:  "NewName",
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestServerSpxClearCaches(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		result1, err := s.compile()
+		require.NoError(t, err)
+		GetSpxDefinitionsForPkg(GetSpxPkg(), nil)
+		count, _ := nonMainPkgSpxDefCache.stats()
+		require.NotZero(t, count)
+
+		_, err = s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{Command: "spx.clearCaches"})
+		require.NoError(t, err)
+		count, _ = nonMainPkgSpxDefCache.stats()
+		assert.Zero(t, count)
+
+		result2, err := s.compile()
+		require.NoError(t, err)
+		assert.NotSame(t, result1, result2)
+		astFile1, err := result1.proj.ASTFile("main.spx")
+		require.NoError(t, err)
+		astFile2, err := result2.proj.ASTFile("main.spx")
+		require.NoError(t, err)
+		assert.NotSame(t, astFile1, astFile2)
+	})
+}
+
+func TestServerSpxGetMetrics(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		_, err := s.compile()
+		require.NoError(t, err)
+
+		result, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{Command: "spx.getMetrics"})
+		require.NoError(t, err)
+		require.IsType(t, Metrics{}, result)
+		metrics := result.(Metrics)
+		assert.Equal(t, uint64(1), metrics.Compile.Count)
+		assert.Equal(t, 3, metrics.Compile.SpxFiles)
+	})
+}
+
+func TestServerSpxListResources(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx":                         []byte(`run "assets", {Title: "My Game"}`),
+			"MySprite.spx":                     []byte(``),
+			"assets/index.json":                []byte(`{"backdrops":[{"name":"b2","path":"b2.png"},{"name":"b1","path":"b1.png","bitmapResolution":2}],"zorder":["MySprite",{"name":"w1","type":"monitor","label":"Score"}]}`),
+			"assets/sounds/MySound/index.json": []byte(`{"path":"MySound.wav","rate":8000,"sampleCount":4000}`),
+			"assets/sprites/MySprite/index.json": []byte(`{
+  "costumeIndex": 1,
+  "costumes": [{"name": "c1", "path": "c1.png"}, {"name": "walk1", "path": "walk1.png"}, {"name": "walk2", "path": "walk2.png"}],
+  "fAnimations": {"walk": {"frameFrom": "walk1", "frameTo": "walk2"}, "idle": {}},
+  "defaultAnimation": "walk"
+}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		result, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{Command: "spx.listResources"})
+		require.NoError(t, err)
+		assert.Equal(t, &SpxResourceList{
+			Backdrops: []SpxBackdropResourceInfo{
+				{URI: "spx://resources/backdrops/b1", Name: "b1", File: "file:///assets/b1.png", BitmapResolution: 2},
+				{URI: "spx://resources/backdrops/b2", Name: "b2", File: "file:///assets/b2.png"},
+			},
+			Sounds: []SpxSoundResourceInfo{
+				{URI: "spx://resources/sounds/MySound", Name: "MySound", File: "file:///assets/sounds/MySound/MySound.wav", Format: "wav", Duration: 0.5, Rate: 8000},
+			},
+			Sprites: []SpxSpriteResourceInfo{{
+				URI:  "spx://resources/sprites/MySprite",
+				Name: "MySprite",
+				Costumes: []SpxSpriteCostumeResourceInfo{
+					{URI: "spx://resources/sprites/MySprite/costumes/c1", Name: "c1", File: "file:///assets/sprites/MySprite/c1.png"},
+					{URI: "spx://resources/sprites/MySprite/costumes/walk1", Name: "walk1", File: "file:///assets/sprites/MySprite/walk1.png"},
+					{URI: "spx://resources/sprites/MySprite/costumes/walk2", Name: "walk2", File: "file:///assets/sprites/MySprite/walk2.png"},
+				},
+				CostumeIndex: 1,
+				Animations: []SpxSpriteAnimationResourceInfo{
+					{URI: "spx://resources/sprites/MySprite/animations/idle", Name: "idle", Frames: []string{}},
+					{URI: "spx://resources/sprites/MySprite/animations/walk", Name: "walk", Frames: []string{"walk1", "walk2"}},
+				},
+				DefaultAnimation: "walk",
+			}},
+			Widgets: []SpxWidgetResourceInfo{
+				{URI: "spx://resources/widgets/w1", Name: "w1", Type: "monitor", Label: "Score"},
+			},
+		}, result)
+	})
+
+	t.Run("EmptyProject", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`run "assets", {Title: "My Game"}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		list, err := s.spxListResources(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, list.Backdrops)
+		assert.NotNil(t, list.Backdrops)
+		assert.Empty(t, list.Sounds)
+		assert.Empty(t, list.Sprites)
+		assert.Empty(t, list.Widgets)
+	})
+}
+
+func TestServerSpxGetResourceReferences(t *testing.T) {
+	m := map[string][]byte{
+		"main.spx": []byte(`
+var (
+	MySprite Sprite
+)
+play "MySound"
+run "assets", {Title: "My Game"}
+`),
+		"MySprite.spx": []byte(`
+onStart => {
+	play "MySound"
+}
+`),
+		"assets/index.json":                  []byte(`{}`),
+		"assets/sounds/MySound/index.json":   []byte(`{}`),
+		"assets/sprites/MySprite/index.json": []byte(`{}`),
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		arg, err := json.Marshal(SpxGetResourceReferencesParams{
+			Resource: SpxResourceIdentifier{URI: "spx://resources/sounds/MySound"},
+		})
+		require.NoError(t, err)
+		result, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{
+			Command:   "spx.getResourceReferences",
+			Arguments: []json.RawMessage{arg},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []SpxResourceReference{
+			{
+				Resource: "spx://resources/sounds/MySound",
+				Kind:     SpxResourceRefKindStringLiteral,
+				Location: Location{
+					URI: "file:///MySprite.spx",
+					Range: Range{
+						Start: Position{Line: 2, Character: 6},
+						End:   Position{Line: 2, Character: 15},
+					},
+				},
+			},
+			{
+				Resource: "spx://resources/sounds/MySound",
+				Kind:     SpxResourceRefKindStringLiteral,
+				Location: Location{
+					URI: "file:///main.spx",
+					Range: Range{
+						Start: Position{Line: 4, Character: 5},
+						End:   Position{Line: 4, Character: 14},
+					},
+				},
+			},
+		}, result)
+	})
+
+	t.Run("AllResources", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		refs, err := s.spxGetResourceReferences(context.Background(), nil)
+		require.NoError(t, err)
+		require.Len(t, refs, 3)
+		assert.Equal(t, SpxResourceReference{
+			Resource: "spx://resources/sprites/MySprite",
+			Kind:     SpxResourceRefKindAutoBinding,
+			Location: Location{
+				URI: "file:///main.spx",
+				Range: Range{
+					Start: Position{Line: 2, Character: 1},
+					End:   Position{Line: 2, Character: 9},
+				},
+			},
+		}, refs[1])
+	})
+
+	t.Run("SpriteReferences", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	Hero Sprite
+)
+Hero.turn Left
+run "assets", {Title: "My Game"}
+`),
+			"Enemy.spx": []byte(`
+onStart => {
+	touching "Hero"
+}
+`),
+			"assets/index.json":               []byte(`{}`),
+			"assets/sprites/Hero/index.json":  []byte(`{}`),
+			"assets/sprites/Enemy/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		refs, err := s.spxGetResourceReferences(context.Background(), []SpxGetResourceReferencesParams{
+			{Resource: SpxResourceIdentifier{URI: "spx://resources/sprites/Hero"}},
+		})
+		require.NoError(t, err)
+		var kinds []SpxResourceRefKind
+		for _, ref := range refs {
+			assert.Equal(t, SpxResourceURI("spx://resources/sprites/Hero"), ref.Resource)
+			kinds = append(kinds, ref.Kind)
+		}
+		assert.Equal(t, []SpxResourceRefKind{
+			SpxResourceRefKindStringLiteral,
+			SpxResourceRefKindAutoBinding,
+			SpxResourceRefKindAutoBindingReference,
+		}, kinds)
+		assert.Equal(t, DocumentURI("file:///Enemy.spx"), refs[0].Location.URI)
+		assert.Equal(t, Range{
+			Start: Position{Line: 4, Character: 0},
+			End:   Position{Line: 4, Character: 4},
+		}, refs[2].Location.Range)
+	})
+
+	t.Run("MultipleResources", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		refs, err := s.spxGetResourceReferences(context.Background(), []SpxGetResourceReferencesParams{
+			{Resource: SpxResourceIdentifier{URI: "spx://resources/sounds/MySound"}},
+			{Resource: SpxResourceIdentifier{URI: "spx://resources/sprites/MySprite"}},
+		})
+		require.NoError(t, err)
+		assert.Len(t, refs, 3)
+	})
+
+	t.Run("UnreferencedResource", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		refs, err := s.spxGetResourceReferences(context.Background(), []SpxGetResourceReferencesParams{
+			{Resource: SpxResourceIdentifier{URI: "spx://resources/backdrops/MyBackdrop"}},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, refs)
+		assert.NotNil(t, refs)
+	})
+
+	t.Run("InvalidResourceURI", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := s.spxGetResourceReferences(context.Background(), []SpxGetResourceReferencesParams{
+			{Resource: SpxResourceIdentifier{URI: "spx://resources/unknown/MyResource"}},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestServerSpxListAnalyzers(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), &mockReplier{}, nil, &MockScheduler{})
+
+		result, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{Command: "spx.listAnalyzers"})
+		require.NoError(t, err)
+		infos, ok := result.([]SpxAnalyzerInfo)
+		require.True(t, ok)
+		idx := slices.IndexFunc(infos, func(info SpxAnalyzerInfo) bool { return info.Name == "appends" })
+		require.GreaterOrEqual(t, idx, 0)
+		appends := infos[idx]
+		assert.NotEmpty(t, appends.Doc)
+		assert.True(t, appends.EnabledByDefault)
+		assert.Equal(t, "warning", appends.DefaultSeverity)
+		assert.True(t, appends.Enabled)
+		assert.Equal(t, "warning", appends.Severity)
+		assert.True(t, slices.IsSortedFunc(infos, func(a, b SpxAnalyzerInfo) int {
+			return strings.Compare(a.Name, b.Name)
+		}))
+	})
+
+	t.Run("Configured", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), &mockReplier{}, nil, &MockScheduler{})
+		require.NoError(t, s.didChangeConfiguration(&DidChangeConfigurationParams{
+			Settings: map[string]any{
+				"analyzers":        map[string]any{"appends": false},
+				"analyzerSeverity": map[string]any{"appends": "hint"},
+			},
+		}))
+
+		infos := s.spxListAnalyzers()
+		idx := slices.IndexFunc(infos, func(info SpxAnalyzerInfo) bool { return info.Name == "appends" })
+		require.GreaterOrEqual(t, idx, 0)
+		assert.False(t, infos[idx].Enabled)
+		assert.Equal(t, "hint", infos[idx].Severity)
+		assert.Equal(t, "warning", infos[idx].DefaultSeverity)
+	})
+}
+
+func TestServerSpxGenerateGo(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	MySprite Sprite
+)
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx": []byte(`
+onStart => {
+	say "Hello"
+}
+`),
+			"assets/index.json":                  []byte(`{}`),
+			"assets/sprites/MySprite/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		result, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{Command: "spx.generateGo"})
+		require.NoError(t, err)
+		generated, ok := result.(*SpxGeneratedGo)
+		require.True(t, ok)
+		require.Len(t, generated.Files, 1)
+		assert.Equal(t, "xgo_autogen.go", generated.Files[0].Name)
+		content := generated.Files[0].Content
+		assert.Contains(t, content, "package main")
+		assert.Contains(t, content, "type MySprite struct {")
+		assert.Contains(t, content, `this.Say__0("Hello")`)
+	})
+
+	t.Run("TypeError", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+echo undefinedVar
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{Command: "spx.generateGo"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to generate Go code")
+	})
+
+	t.Run("WithoutTypes", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`run "assets", {Title: "My Game"}`),
+		}
+		s := New(newMapFSWithFeatures(m, xgo.FeatASTCache), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{Command: "spx.generateGo"})
+		assert.EqualError(t, err, "command spx.generateGo is not supported without type checking")
+	})
+}
+
+func TestServerSpxCreateSprite(t *testing.T) {
+	m := map[string][]byte{
+		"main.spx": []byte(`
+var (
+	MySprite Sprite
+)
+run "assets", {Title: "My Game"}
+`),
+		"MySprite.spx":                       []byte(`onStart => {}`),
+		"assets/index.json":                  []byte(`{}`),
+		"assets/sprites/MySprite/index.json": []byte(`{}`),
+	}
+	createSprite := func(s *Server, name string) (any, error) {
+		arg, err := json.Marshal(SpxCreateSpriteParams{Name: name})
+		require.NoError(t, err)
+		return s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{
+			Command:   "spx.createSprite",
+			Arguments: []json.RawMessage{arg},
+		})
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		result, err := createSprite(s, "NewSprite")
+		require.NoError(t, err)
+		assert.Equal(t, &WorkspaceEdit{
+			DocumentChanges: []DocumentChange{
+				{CreateFile: &CreateFile{Kind: "create", URI: "file:///NewSprite.spx"}},
+				{TextDocumentEdit: &TextDocumentEdit{
+					TextDocument: OptionalVersionedTextDocumentIdentifier{
+						TextDocumentIdentifier: TextDocumentIdentifier{URI: "file:///NewSprite.spx"},
+					},
+					Edits: []Or_TextDocumentEdit_edits_Elem{{Value: TextEdit{NewText: spxSpriteClassfileStub}}},
+				}},
+				{CreateFile: &CreateFile{Kind: "create", URI: "file:///assets/sprites/NewSprite/index.json"}},
+				{TextDocumentEdit: &TextDocumentEdit{
+					TextDocument: OptionalVersionedTextDocumentIdentifier{
+						TextDocumentIdentifier: TextDocumentIdentifier{URI: "file:///assets/sprites/NewSprite/index.json"},
+					},
+					Edits: []Or_TextDocumentEdit_edits_Elem{{Value: TextEdit{NewText: spxSpriteResourceStub}}},
+				}},
+			},
+		}, result)
+	})
+
+	t.Run("CustomResourceRootDir", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx":       []byte(`run "res", {Title: "My Game"}`),
+			"res/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		result, err := createSprite(s, "NewSprite")
+		require.NoError(t, err)
+		edit, ok := result.(*WorkspaceEdit)
+		require.True(t, ok)
+		require.Len(t, edit.DocumentChanges, 4)
+		assert.Equal(t, DocumentURI("file:///res/sprites/NewSprite/index.json"), edit.DocumentChanges[2].CreateFile.URI)
+	})
+
+	t.Run("InvalidName", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := createSprite(s, "New Sprite")
+		assert.EqualError(t, err, `invalid sprite name "New Sprite": it must be a valid identifier`)
+	})
+
+	t.Run("ExistingSprite", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := createSprite(s, "MySprite")
+		assert.EqualError(t, err, `sprite resource "MySprite" already exists`)
+	})
+
+	t.Run("ExistingDeclaration", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := createSprite(s, "Game")
+		assert.EqualError(t, err, `"Game" is already declared in the project`)
+	})
+
+	t.Run("ExistingFile", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx":                          []byte(`run "assets", {Title: "My Game"}`),
+			"assets/index.json":                 []byte(`{}`),
+			"assets/sprites/Orphan/costume.png": []byte(``),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := createSprite(s, "Orphan")
+		assert.EqualError(t, err, `file "assets/sprites/Orphan/costume.png" already exists`)
+	})
+
+	t.Run("WrongArgumentCount", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{Command: "spx.createSprite"})
+		assert.EqualError(t, err, "command spx.createSprite expects exactly 1 argument, got 0")
+	})
+}
+
 func TestFindInputSlots(t *testing.T) {
 	m := map[string][]byte{
 		"main.spx": []byte(`
@@ -363,7 +1092,7 @@ onStart => {
 	}
 	s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///main.spx")
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
 	require.NoError(t, err)
 	require.False(t, result.hasErrorSeverityDiagnostic)
 	require.NotNil(t, astFile)
@@ -503,7 +1232,7 @@ onStart => {
 	})
 
 	t.Run("spx.Sprite.Goto", func(t *testing.T) {
-		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///MySprite.spx")
+		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///MySprite.spx")
 		require.NoError(t, err)
 		require.False(t, result.hasErrorSeverityDiagnostic)
 		require.NotNil(t, astFile)
@@ -526,7 +1255,7 @@ onStart => {
 	})
 
 	t.Run("spx.Sprite.Clone", func(t *testing.T) {
-		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///MySprite.spx")
+		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///MySprite.spx")
 		require.NoError(t, err)
 		require.False(t, result.hasErrorSeverityDiagnostic)
 		require.NotNil(t, astFile)
@@ -573,7 +1302,7 @@ onStart => {
 	}
 	s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///main.spx")
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
 	require.NoError(t, err)
 	require.False(t, result.hasErrorSeverityDiagnostic)
 	require.NotNil(t, astFile)
@@ -708,7 +1437,7 @@ onStart => {
 	}
 	s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///main.spx")
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
 	require.NoError(t, err)
 	require.False(t, result.hasErrorSeverityDiagnostic)
 	require.NotNil(t, astFile)
@@ -798,7 +1527,7 @@ onStart => {
 	}
 	s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///main.spx")
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
 	require.NoError(t, err)
 	require.False(t, result.hasErrorSeverityDiagnostic)
 	require.NotNil(t, astFile)
@@ -966,7 +1695,7 @@ onStart => {
 	}
 	s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///main.spx")
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
 	require.NoError(t, err)
 	require.False(t, result.hasErrorSeverityDiagnostic)
 	require.NotNil(t, astFile)
@@ -1088,7 +1817,7 @@ onStart => {
 	}
 	s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///main.spx")
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
 	require.NoError(t, err)
 	require.False(t, result.hasErrorSeverityDiagnostic)
 	require.NotNil(t, astFile)
@@ -1190,7 +1919,7 @@ onStart => {
 	}
 	s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///main.spx")
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
 	require.NoError(t, err)
 	require.False(t, result.hasErrorSeverityDiagnostic)
 	require.NotNil(t, astFile)
@@ -1415,7 +2144,7 @@ onStart => {
 	s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
 	t.Run("MainFile", func(t *testing.T) {
-		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///main.spx")
+		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
 		require.NoError(t, err)
 		require.False(t, result.hasErrorSeverityDiagnostic)
 		require.NotNil(t, astFile)
@@ -1440,7 +2169,7 @@ onStart => {
 	})
 
 	t.Run("SpriteFile", func(t *testing.T) {
-		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///MySprite.spx")
+		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///MySprite.spx")
 		require.NoError(t, err)
 		require.False(t, result.hasErrorSeverityDiagnostic)
 		require.NotNil(t, astFile)
@@ -1465,7 +2194,7 @@ onStart => {
 	})
 
 	t.Run("NonSpriteNode", func(t *testing.T) {
-		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///main.spx")
+		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
 		require.NoError(t, err)
 		require.False(t, result.hasErrorSeverityDiagnostic)
 		require.NotNil(t, astFile)
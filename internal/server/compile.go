@@ -1,19 +1,27 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"go/constant"
 	"go/types"
+	"io/fs"
+	"maps"
 	"path"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/goplus/gogen"
 	xgoast "github.com/goplus/xgo/ast"
 	xgoscanner "github.com/goplus/xgo/scanner"
 	xgotoken "github.com/goplus/xgo/token"
-	"github.com/goplus/xgolsw/internal/analysis/ast/inspector"
-	"github.com/goplus/xgolsw/internal/analysis/passes/inspect"
+	"github.com/goplus/xgolsw/internal/analysis"
 	"github.com/goplus/xgolsw/internal/analysis/protocol"
 	"github.com/goplus/xgolsw/internal/pkgdata"
 	"github.com/goplus/xgolsw/internal/vfs"
@@ -38,6 +46,13 @@ type compileResult struct {
 	// spxSpriteTypes stores the spx sprite types.
 	spxSpriteTypes map[types.Type]struct{}
 
+	// spxResourceRootDir is the root directory of spx resources.
+	spxResourceRootDir string
+
+	// spxVersion is the spx version that the project targets. See
+	// [Server.spxVersion].
+	spxVersion string
+
 	// spxResourceSet is the set of spx resources.
 	spxResourceSet SpxResourceSet
 
@@ -60,6 +75,10 @@ type compileResult struct {
 	// seenDiagnostics stores already reported diagnostics to avoid duplicates.
 	seenDiagnostics map[DocumentURI]map[string]struct{}
 
+	// ignoreDirectives stores the ignore directives of each document, keyed
+	// by the zero-based line they apply to.
+	ignoreDirectives map[DocumentURI]map[uint32]*ignoreDirective
+
 	// hasErrorSeverityDiagnostic is true if the compile result has any
 	// diagnostics with error severity.
 	hasErrorSeverityDiagnostic bool
@@ -76,6 +95,43 @@ func newCompileResult(proj *xgo.Project) *compileResult {
 	}
 }
 
+// pkgDoc returns the documentation for the package at pkgPath as of the spx
+// version that the project targets. Packages that pkgdata has no docs for,
+// such as those of third-party modules, are documented from their source files
+// available to the project instead.
+func (r *compileResult) pkgDoc(pkgPath string) (*pkgdoc.PkgDoc, error) {
+	pkgDoc, err := pkgdata.GetPkgDocFor(pkgPath, r.spxVersion)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r.proj.PkgDocOf(pkgPath)
+	}
+	return pkgDoc, err
+}
+
+// spxPkgDefinitions returns the spx definitions for the spx package as of the
+// spx version that the project targets.
+func (r *compileResult) spxPkgDefinitions() []SpxDefinition {
+	spxPkgDoc, err := r.pkgDoc(SpxPkgPath)
+	if err != nil {
+		return GetSpxPkgDefinitions()
+	}
+	return GetSpxDefinitionsForPkg(GetSpxPkg(), spxPkgDoc)
+}
+
+// spxVersion returns the spx version that proj targets, which is the one set
+// by [Settings.SpxVersion], or else the required version of the spx module in
+// go.mod. It returns an empty string if neither is set.
+func (s *Server) spxVersion(proj *xgo.Project) string {
+	if v := s.getSettings().SpxVersion; v != "" {
+		return v
+	}
+	for _, mod := range proj.Requires() {
+		if mod.Path == SpxPkgPath {
+			return mod.Version
+		}
+	}
+	return ""
+}
+
 // spxDefinitionsFor returns all spx definitions for the given object. It
 // returns multiple definitions only if the object is an XGo overloadable
 // function.
@@ -92,7 +148,7 @@ func (r *compileResult) spxDefinitionsFor(obj types.Object, selectorTypeName str
 		pkgDoc, _ = r.proj.PkgDoc()
 	} else {
 		pkgPath := xgoutil.PkgPath(obj.Pkg())
-		pkgDoc, _ = pkgdata.GetPkgDoc(pkgPath)
+		pkgDoc, _ = r.pkgDoc(pkgPath)
 	}
 
 	switch obj := obj.(type) {
@@ -128,6 +184,11 @@ func (r *compileResult) spxDefinitionsFor(obj types.Object, selectorTypeName str
 // spxDefinitionsForIdent returns all spx definitions for the given identifier.
 // It returns multiple definitions only if the identifier is an XGo
 // overloadable function.
+//
+// If the identifier is the member of a selector expression resolved through
+// more than one type, such as a field promoted from an embedded struct, the
+// definitions are attributed to the type declaring the member, and their
+// details start with the chain of types the member is resolved through.
 func (r *compileResult) spxDefinitionsForIdent(ident *xgoast.Ident) []SpxDefinition {
 	if ident.Name == "_" {
 		return nil
@@ -136,7 +197,107 @@ func (r *compileResult) spxDefinitionsForIdent(ident *xgoast.Ident) []SpxDefinit
 	if typeInfo == nil {
 		return nil
 	}
-	return r.spxDefinitionsFor(typeInfo.ObjectOf(ident), SelectorTypeNameForIdent(r.proj, ident))
+
+	selectorTypeName := SelectorTypeNameForIdent(r.proj, ident)
+	var chain []string
+	if sel := selectorExprForSel(r.proj, ident); sel != nil {
+		chain = r.selectorTypeNameChain(typeInfo, sel)
+		if len(chain) > 0 {
+			selectorTypeName = chain[len(chain)-1]
+		}
+	}
+
+	defs := r.spxDefinitionsFor(typeInfo.ObjectOf(ident), selectorTypeName)
+	if len(chain) > 1 {
+		resolution := fmt.Sprintf("Resolved through `%s → %s`.\n", strings.Join(chain, " → "), ident.Name)
+		for i := range defs {
+			if defs[i].Detail == "" {
+				defs[i].Detail = resolution
+			} else {
+				defs[i].Detail = resolution + "\n" + defs[i].Detail
+			}
+		}
+	}
+	return defs
+}
+
+// selectorExprForSel returns the selector expression whose selected member is
+// the given identifier, or nil if there is none.
+func selectorExprForSel(proj *xgo.Project, ident *xgoast.Ident) *xgoast.SelectorExpr {
+	astFile := xgoutil.NodeASTFile(proj, ident)
+	if astFile == nil {
+		return nil
+	}
+	path, _ := xgoutil.PathEnclosingInterval(astFile, ident.Pos(), ident.End())
+	if len(path) < 2 {
+		return nil
+	}
+	sel, ok := path[1].(*xgoast.SelectorExpr)
+	if !ok || sel.Sel != ident {
+		return nil
+	}
+	return sel
+}
+
+// selectorTypeNameChain returns the names of the types the member selected by
+// the given selector expression is resolved through, from the outermost owner
+// of the selector expression to the type declaring the member. It returns nil
+// if the chain cannot be determined.
+func (r *compileResult) selectorTypeNameChain(typeInfo *xgo.TypeInfo, sel *xgoast.SelectorExpr) []string {
+	// Selections are not recorded for XGo, so look the member up by the
+	// name of its object, which may differ from the selector name.
+	obj := typeInfo.ObjectOf(sel.Sel)
+	recvType := typeInfo.TypeOf(sel.X)
+	if obj == nil || recvType == nil {
+		return nil
+	}
+	if _, ok := obj.(*types.PkgName); ok {
+		return nil
+	}
+	member, index, _ := types.LookupFieldOrMethod(recvType, true, obj.Pkg(), obj.Name())
+	if member == nil || len(index) == 0 {
+		return nil
+	}
+
+	var chain []string
+	switch x := sel.X.(type) {
+	case *xgoast.Ident:
+		// Class fields are selected implicitly through the class, except
+		// for the embedded base class such as Game itself.
+		if v, ok := typeInfo.ObjectOf(x).(*types.Var); ok && v.IsField() && !v.Embedded() {
+			if typeName := getTypeFromObject(typeInfo, v); typeName != "" {
+				chain = append(chain, typeName)
+			}
+		}
+	case *xgoast.SelectorExpr:
+		chain = r.selectorTypeNameChain(typeInfo, x)
+	}
+
+	typ := xgoutil.DerefType(recvType)
+	typeName := extractTypeName(typ)
+	if typeName == "" {
+		return nil
+	}
+	chain = append(chain, typeName)
+
+	// Follow the embedded fields the member is promoted through, up to
+	// unexported types of other packages, which are implementation details.
+	for _, i := range index[:len(index)-1] {
+		st, ok := typ.Underlying().(*types.Struct)
+		if !ok {
+			return nil
+		}
+		typ = xgoutil.DerefType(st.Field(i).Type())
+		named, ok := typ.(*types.Named)
+		if !ok {
+			return nil
+		}
+		if !named.Obj().Exported() && !xgoutil.IsInMainPkg(named.Obj()) {
+			break
+		}
+		chain = append(chain, extractTypeName(named))
+	}
+	return chain
 }
 
 // spxDefinitionsForNamedStruct returns all spx definitions for the given named
@@ -153,45 +314,42 @@ func (r *compileResult) spxDefinitionsForNamedStruct(named *types.Named) []SpxDe
 // spxDefinitionForField returns the spx definition for the given field and
 // optional selector type name.
 func (r *compileResult) spxDefinitionForField(field *types.Var, selectorTypeName string) SpxDefinition {
-	var (
-		forceVar bool
-		pkgDoc   *pkgdoc.PkgDoc
-	)
 	if typeInfo, _ := r.proj.TypeInfo(); typeInfo != nil {
 		if defIdent := typeInfo.DefIdentFor(field); defIdent != nil {
 			if selectorTypeName == "" {
 				selectorTypeName = SelectorTypeNameForIdent(r.proj, defIdent)
 			}
-			forceVar = xgoutil.IsDefinedInClassFieldsDecl(r.proj, field)
-			pkgDoc, _ = r.proj.PkgDoc()
+			forceVar := xgoutil.IsDefinedInClassFieldsDecl(r.proj, field)
+			pkgDoc, _ := r.proj.PkgDoc()
+			return GetSpxDefinitionForVar(field, selectorTypeName, forceVar, pkgDoc)
 		}
-	} else {
-		pkg := field.Pkg()
-		pkgPath := xgoutil.PkgPath(pkg)
-		pkgDoc, _ = pkgdata.GetPkgDoc(pkgPath)
 	}
-	return GetSpxDefinitionForVar(field, selectorTypeName, forceVar, pkgDoc)
+
+	pkg := field.Pkg()
+	pkgPath := xgoutil.PkgPath(pkg)
+	pkgDoc, _ := r.pkgDoc(pkgPath)
+	return GetSpxDefinitionForVar(field, selectorTypeName, false, pkgDoc)
 }
 
 // spxDefinitionForMethod returns the spx definition for the given method and
 // optional selector type name.
 func (r *compileResult) spxDefinitionForMethod(method *types.Func, selectorTypeName string) SpxDefinition {
-	var pkgDoc *pkgdoc.PkgDoc
 	if typeInfo, _ := r.proj.TypeInfo(); typeInfo != nil {
 		if defIdent := typeInfo.DefIdentFor(method); defIdent != nil {
 			if selectorTypeName == "" {
 				selectorTypeName = SelectorTypeNameForIdent(r.proj, defIdent)
 			}
-			pkgDoc, _ = r.proj.PkgDoc()
+			pkgDoc, _ := r.proj.PkgDoc()
+			return GetSpxDefinitionForFunc(method, selectorTypeName, pkgDoc)
 		}
-	} else {
-		if idx := strings.LastIndex(selectorTypeName, "."); idx >= 0 {
-			selectorTypeName = selectorTypeName[idx+1:]
-		}
-		pkg := method.Pkg()
-		pkgPath := xgoutil.PkgPath(pkg)
-		pkgDoc, _ = pkgdata.GetPkgDoc(pkgPath)
 	}
+
+	if idx := strings.LastIndex(selectorTypeName, "."); idx >= 0 {
+		selectorTypeName = selectorTypeName[idx+1:]
+	}
+	pkg := method.Pkg()
+	pkgPath := xgoutil.PkgPath(pkg)
+	pkgDoc, _ := r.pkgDoc(pkgPath)
 	return GetSpxDefinitionForFunc(method, selectorTypeName, pkgDoc)
 }
 
@@ -272,7 +430,7 @@ func (r *compileResult) spxImportsAtASTFilePosition(astFile *xgoast.File, positi
 		if err != nil {
 			continue
 		}
-		pkgDoc, err := pkgdata.GetPkgDoc(pkg)
+		pkgDoc, err := r.pkgDoc(pkg)
 		if err != nil {
 			continue
 		}
@@ -291,6 +449,28 @@ func (r *compileResult) hasSpxSpriteType(typ types.Type) bool {
 	return ok
 }
 
+// isSpxSpriteTypeName reports whether the given object is the type name of an
+// spx sprite type.
+func (r *compileResult) isSpxSpriteTypeName(obj types.Object) bool {
+	typeName, ok := obj.(*types.TypeName)
+	return ok && r.hasSpxSpriteType(typeName.Type())
+}
+
+// spxResourceIDForAutoBinding returns the ID of the spx resource that the
+// given object is auto-bound to, or nil if it is not an auto-binding.
+func (r *compileResult) spxResourceIDForAutoBinding(obj types.Object) SpxResourceID {
+	if obj == nil {
+		return nil
+	}
+	if _, ok := r.spxSpriteResourceAutoBindings[obj]; ok {
+		return SpxSpriteResourceID{SpriteName: obj.Name()}
+	}
+	if _, ok := r.spxSoundResourceAutoBindings[obj]; ok {
+		return SpxSoundResourceID{SoundName: obj.Name()}
+	}
+	return nil
+}
+
 // addSpxResourceRef adds an spx resource reference to the compile result.
 func (r *compileResult) addSpxResourceRef(ref SpxResourceRef) {
 	if r.seenSpxResourceRefs == nil {
@@ -305,6 +485,38 @@ func (r *compileResult) addSpxResourceRef(ref SpxResourceRef) {
 	r.spxResourceRefs = append(r.spxResourceRefs, ref)
 }
 
+// addParseErrorDiagnostics adds diagnostics for err, which is returned from
+// parsing the source file of astFile, to the compile result.
+func (r *compileResult) addParseErrorDiagnostics(documentURI DocumentURI, astFile *xgoast.File, err error) {
+	var (
+		errorList xgoscanner.ErrorList
+		codeError *gogen.CodeError
+	)
+	if errors.As(err, &errorList) && astFile.Pos().IsValid() {
+		// Handle parse errors.
+		for _, e := range errorList {
+			r.addDiagnostics(documentURI, Diagnostic{
+				Severity: SeverityError,
+				Range:    RangeForASTFilePosition(r.proj, astFile, e.Pos),
+				Message:  e.Msg,
+			})
+		}
+	} else if errors.As(err, &codeError) {
+		// Handle code generation errors.
+		r.addDiagnostics(documentURI, Diagnostic{
+			Severity: SeverityError,
+			Range:    RangeForPos(r.proj, codeError.Pos),
+			Message:  codeError.Error(),
+		})
+	} else {
+		// Handle unknown errors (including recovered panics).
+		r.addDiagnostics(documentURI, Diagnostic{
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("failed to parse spx file: %v", err),
+		})
+	}
+}
+
 // addDiagnostics adds diagnostics to the compile result.
 func (r *compileResult) addDiagnostics(documentURI DocumentURI, diags ...Diagnostic) {
 	if r.seenDiagnostics == nil {
@@ -318,6 +530,13 @@ func (r *compileResult) addDiagnostics(documentURI DocumentURI, diags ...Diagnos
 
 	r.diagnostics[documentURI] = slices.Grow(r.diagnostics[documentURI], len(diags))
 	for _, diag := range diags {
+		if r.isDiagnosticIgnored(documentURI, diag) {
+			continue
+		}
+		if code, ok := diag.Code.(string); ok && diag.CodeDescription == nil {
+			diag.CodeDescription = diagnosticCodeDescription(code)
+		}
+
 		fingerprint := fmt.Sprintf("%d\n%v\n%s", diag.Severity, diag.Range, diag.Message)
 		if _, ok := seenDiagnostics[fingerprint]; ok {
 			continue
@@ -331,20 +550,199 @@ func (r *compileResult) addDiagnostics(documentURI DocumentURI, diags ...Diagnos
 	}
 }
 
+// ignoreDirectivePrefix is the prefix of comment directives that suppress
+// diagnostics on a line, for example "//xgolsw:ignore appends".
+const ignoreDirectivePrefix = "//xgolsw:ignore"
+
+// ignoreDirective is a parsed ignore directive.
+//
+// A directive lists the names of the analyzers whose diagnostics it
+// suppresses, separated by commas or spaces. A directive without names
+// suppresses diagnostics of all analyzers as well as all other diagnostics
+// that are not errors. A trailing directive applies to its own line, while a
+// directive on a line of its own applies to the next line.
+type ignoreDirective struct {
+	all   bool
+	names []string
+}
+
+// addIgnoreDirectives parses the ignore directives in astFile, which is the
+// file of the given document, and records them in the compile result.
+func (r *compileResult) addIgnoreDirectives(documentURI DocumentURI, spxFile string, astFile *xgoast.File) {
+	var content []byte
+	for _, cg := range astFile.Comments {
+		for _, c := range cg.List {
+			rest, ok := strings.CutPrefix(c.Text, ignoreDirectivePrefix)
+			if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+				continue
+			}
+			names := strings.FieldsFunc(rest, func(r rune) bool {
+				return r == ',' || unicode.IsSpace(r)
+			})
+
+			if content == nil {
+				content, _ = vfs.ReadFile(r.proj, spxFile)
+			}
+			pos := r.proj.Fset.Position(c.Slash)
+			line := uint32(pos.Line - 1)
+			if pos.Offset <= len(content) {
+				lineStart := bytes.LastIndexByte(content[:pos.Offset], '\n') + 1
+				if len(bytes.TrimSpace(content[lineStart:pos.Offset])) == 0 {
+					line++
+				}
+			}
+
+			if r.ignoreDirectives == nil {
+				r.ignoreDirectives = make(map[DocumentURI]map[uint32]*ignoreDirective)
+			}
+			directives := r.ignoreDirectives[documentURI]
+			if directives == nil {
+				directives = make(map[uint32]*ignoreDirective)
+				r.ignoreDirectives[documentURI] = directives
+			}
+			directive := directives[line]
+			if directive == nil {
+				directive = &ignoreDirective{}
+				directives[line] = directive
+			}
+			directive.all = directive.all || len(names) == 0
+			directive.names = append(directive.names, names...)
+		}
+	}
+}
+
+// isDiagnosticIgnored reports whether diag in the given document is suppressed
+// by an ignore directive. Analyzer diagnostics are identified by their source,
+// which is the analyzer name.
+func (r *compileResult) isDiagnosticIgnored(documentURI DocumentURI, diag Diagnostic) bool {
+	directive := r.ignoreDirectives[documentURI][diag.Range.Start.Line]
+	if directive == nil {
+		return false
+	}
+	if diag.Source != "" {
+		return directive.all || slices.Contains(directive.names, diag.Source)
+	}
+	return directive.all && diag.Severity != SeverityError
+}
+
 // compile compiles spx source files and returns compile result. It uses cached
 // result if available.
 func (s *Server) compile() (*compileResult, error) {
-	// NOTE(xsw): don't create a snapshot
-	snapshot := s.workspaceRootFS // .Snapshot()
+	return s.compileWithContext(context.Background())
+}
+
+// CompileResult is the result of [Compile].
+type CompileResult struct {
+	// Proj is the snapshot of the project that was compiled.
+	Proj *xgo.Project
+
+	// Diagnostics are the diagnostics of each spx source file and spx
+	// resource metadata file, keyed by its path. Every checked file has an
+	// entry, which is empty if the file has no diagnostics.
+	Diagnostics map[string][]Diagnostic
+
+	// SpxResourceRefs are the references to spx resources.
+	SpxResourceRefs []SpxResourceRef
+
+	// SpxResourceSet is the set of spx resources of the project.
+	SpxResourceSet *SpxResourceSet
+}
+
+// Compile compiles the spx source files of proj with the default settings,
+// without a client, so projects can be validated headlessly. The PkgPath, Mod
+// and Importer of proj are set up as by [New].
+func Compile(ctx context.Context, proj *xgo.Project) (*CompileResult, error) {
+	s := New(proj, nil, nil, nil)
+	defer s.Close()
+
+	result, err := s.compileWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	diagnostics := make(map[string][]Diagnostic, len(result.diagnostics))
+	for documentURI, diags := range result.diagnostics {
+		spxFile, err := s.fromDocumentURI(documentURI)
+		if err != nil {
+			return nil, err
+		}
+		diagnostics[spxFile] = diags
+	}
+	return &CompileResult{
+		Proj:            result.proj,
+		Diagnostics:     diagnostics,
+		SpxResourceRefs: result.spxResourceRefs,
+		SpxResourceSet:  &result.spxResourceSet,
+	}, nil
+}
+
+// compileWithContext is like [Server.compile], but stops early and returns the
+// cause of ctx if ctx is done before the compilation completes.
+//
+// The compilation runs against a snapshot of the project, so it sees a
+// consistent state even if the project is changed while it is running. The
+// last compile result is reused until the project or the settings change.
+func (s *Server) compileWithContext(ctx context.Context) (*compileResult, error) {
+	proj := s.workspaceRootFS
 
 	// TODO(wyvern): remove this once we have a better way to update files.
-	snapshot.UpdateFiles(s.fileMapGetter())
-	return s.compileAt(snapshot)
+	if s.fileMapGetter != nil {
+		proj.UpdateFiles(s.fileMapGetter())
+	}
+
+	s.compileMu.Lock()
+	defer s.compileMu.Unlock()
+
+	snapshot := proj.Snapshot()
+	generation := snapshot.Generation()
+	settings := s.getSettings()
+	if c := s.lastCompile; c != nil && c.generation == generation && c.settings == settings {
+		s.metrics.recordCompileReused()
+		return c.result, c.err
+	}
+
+	startTime := time.Now()
+	result, err := s.compileAt(ctx, snapshot)
+	duration := time.Since(startTime)
+	cacheStats := snapshot.CacheStats()
+	if result != nil {
+		var spxFiles int
+		if astPkg, _ := snapshot.ASTPackage(); astPkg != nil {
+			spxFiles = len(astPkg.Files)
+		}
+		s.metrics.recordCompile(duration, spxFiles, result.spxResourceSet.count(), cacheStats)
+	}
+	s.getLogger().Debug("compiled project",
+		"duration", duration,
+		"error", err,
+		"cacheHits", cacheStats.Hits,
+		"cacheMisses", cacheStats.Misses,
+		"cacheHitRate", cacheStats.HitRate(),
+	)
+	if err == nil || errors.Is(err, errNoMainSpxFile) {
+		s.lastCompile = &compileCache{
+			generation: generation,
+			settings:   settings,
+			result:     result,
+			err:        err,
+		}
+	}
+	return result, err
+}
+
+// compileCache is the last compile result of a [Server].
+type compileCache struct {
+	generation uint64    // Generation of the project that was compiled.
+	settings   *Settings // Settings that were used for the compilation.
+	result     *compileResult
+	err        error
 }
 
 // compileAt compiles spx source files at the given snapshot and returns the
 // compile result.
-func (s *Server) compileAt(snapshot *vfs.MapFS) (*compileResult, error) {
+//
+// The ctx is checked between compile phases, so a cancelled request does not
+// keep running the remaining phases.
+func (s *Server) compileAt(ctx context.Context, snapshot *vfs.MapFS) (*compileResult, error) {
 	spxFiles, err := vfs.ListSpxFiles(snapshot)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get spx files: %w", err)
@@ -353,40 +751,22 @@ func (s *Server) compileAt(snapshot *vfs.MapFS) (*compileResult, error) {
 		return nil, errNoMainSpxFile
 	}
 
+	progress := workDoneProgressFromContext(ctx)
+
 	result := newCompileResult(snapshot)
-	for _, spxFile := range spxFiles {
+	result.spxVersion = s.spxVersion(snapshot)
+	for i, spxFile := range spxFiles {
+		if err := s.checkContext(ctx); err != nil {
+			return nil, err
+		}
+		progress.report("Parsing "+spxFile, uint32(i*40/len(spxFiles)))
+
 		documentURI := s.toDocumentURI(spxFile)
 		result.diagnostics[documentURI] = []Diagnostic{}
 
 		astFile, err := snapshot.ASTFile(spxFile)
 		if err != nil {
-			var (
-				errorList xgoscanner.ErrorList
-				codeError *gogen.CodeError
-			)
-			if errors.As(err, &errorList) && astFile.Pos().IsValid() {
-				// Handle parse errors.
-				for _, e := range errorList {
-					result.addDiagnostics(documentURI, Diagnostic{
-						Severity: SeverityError,
-						Range:    RangeForASTFilePosition(result.proj, astFile, e.Pos),
-						Message:  e.Msg,
-					})
-				}
-			} else if errors.As(err, &codeError) {
-				// Handle code generation errors.
-				result.addDiagnostics(documentURI, Diagnostic{
-					Severity: SeverityError,
-					Range:    RangeForPos(result.proj, codeError.Pos),
-					Message:  codeError.Error(),
-				})
-			} else {
-				// Handle unknown errors (including recovered panics).
-				result.addDiagnostics(documentURI, Diagnostic{
-					Severity: SeverityError,
-					Message:  fmt.Sprintf("failed to parse spx file: %v", err),
-				})
-			}
+			result.addParseErrorDiagnostics(documentURI, astFile, err)
 		}
 		if astFile == nil {
 			continue
@@ -394,12 +774,15 @@ func (s *Server) compileAt(snapshot *vfs.MapFS) (*compileResult, error) {
 		if astFile.Name.Name != "main" && astFile.Pos().IsValid() {
 			result.addDiagnostics(documentURI, Diagnostic{
 				Severity: SeverityError,
+				Code:     diagnosticCodeSpxPackageName,
 				Range:    RangeForASTFileNode(result.proj, astFile, astFile.Name),
 				Message:  "package name must be main",
 			})
 			continue
 		}
 
+		result.addIgnoreDirectives(documentURI, spxFile, astFile)
+
 		if spxFileBaseName := path.Base(spxFile); spxFileBaseName == "main.spx" {
 			result.mainSpxFile = spxFile
 		}
@@ -410,6 +793,10 @@ func (s *Server) compileAt(snapshot *vfs.MapFS) (*compileResult, error) {
 		}
 		return result, nil
 	}
+	if snapshot.Features()&xgo.FeatTypeInfoCache == 0 {
+		// Only syntax errors are reported without type checking.
+		return result, nil
+	}
 
 	handleErr := func(err error) {
 		if typeErr, ok := err.(types.Error); ok {
@@ -418,14 +805,20 @@ func (s *Server) compileAt(snapshot *vfs.MapFS) (*compileResult, error) {
 			}
 			position := typeErr.Fset.Position(typeErr.Pos)
 			documentURI := s.toDocumentURI(position.Filename)
+			msg, relatedInfo := s.splitTypeErrorMsg(result.proj, typeErr.Msg)
 			result.addDiagnostics(documentURI, Diagnostic{
-				Severity: SeverityError,
-				Range:    RangeForPos(result.proj, typeErr.Pos),
-				Message:  typeErr.Msg,
+				Severity:           SeverityError,
+				Range:              RangeForPos(result.proj, typeErr.Pos),
+				Message:            msg,
+				RelatedInformation: relatedInfo,
 			})
 		}
 	}
 
+	if err := s.checkContext(ctx); err != nil {
+		return nil, err
+	}
+	progress.report("Type checking", 40)
 	typeInfo, err := snapshot.TypeInfo()
 	if err != nil {
 		switch err := err.(type) {
@@ -439,6 +832,26 @@ func (s *Server) compileAt(snapshot *vfs.MapFS) (*compileResult, error) {
 	}
 	pkg := typeInfo.Pkg()
 
+	localPkgs, _ := snapshot.LocalPackages()
+	for _, localPkg := range localPkgs {
+		for file := range localPkg.Files {
+			documentURI := s.toDocumentURI(file)
+			result.diagnostics[documentURI] = []Diagnostic{}
+			if astFile, err := snapshot.ASTFile(file); err != nil {
+				result.addParseErrorDiagnostics(documentURI, astFile, err)
+			}
+		}
+		switch err := localPkg.Err.(type) {
+		case nil:
+		case errors.List:
+			for _, e := range err {
+				handleErr(e)
+			}
+		default:
+			handleErr(err)
+		}
+	}
+
 	vfs.RangeSpriteNames(snapshot, func(name string) bool {
 		obj := pkg.Scope().Lookup(name)
 		if obj != nil {
@@ -450,17 +863,116 @@ func (s *Server) compileAt(snapshot *vfs.MapFS) (*compileResult, error) {
 		return true
 	})
 
+	if err := s.checkContext(ctx); err != nil {
+		return nil, err
+	}
+	progress.report("Scanning resources", 60)
 	s.inspectForSpxResourceSet(snapshot, result)
 	s.inspectForSpxResourceRefs(result)
+
+	if err := s.checkContext(ctx); err != nil {
+		return nil, err
+	}
+	progress.report("Running analyzers", 80)
 	s.inspectDiagnosticsAnalyzers(result)
 
 	return result, nil
 }
 
+// splitTypeErrorMsg splits the continuation lines of the given type error
+// message, such as "\tmain.spx:2:2 other declaration of a" in the message of a
+// duplicate declaration error, into related information. It returns the
+// message without them and the related information. Continuation lines that
+// do not refer to a position in proj are kept in the message.
+func (s *Server) splitTypeErrorMsg(proj *xgo.Project, msg string) (string, []DiagnosticRelatedInformation) {
+	lines := strings.Split(msg, "\n\t")
+	kept := lines[:1]
+	var relatedInfo []DiagnosticRelatedInformation
+	for _, line := range lines[1:] {
+		loc, text, ok := strings.Cut(line, " ")
+		if ok {
+			var location Location
+			location, ok = s.locationForTypeErrorPos(proj, loc)
+			if ok {
+				relatedInfo = append(relatedInfo, DiagnosticRelatedInformation{
+					Location: location,
+					Message:  text,
+				})
+			}
+		}
+		if !ok {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n\t"), relatedInfo
+}
+
+// locationForTypeErrorPos returns the [Location] of the given position in a
+// type error message, which is in the form "file:line:column".
+func (s *Server) locationForTypeErrorPos(proj *xgo.Project, pos string) (Location, bool) {
+	rest, colStr, ok := cutLast(pos, ":")
+	if !ok {
+		return Location{}, false
+	}
+	filename, lineStr, ok := cutLast(rest, ":")
+	if !ok {
+		return Location{}, false
+	}
+	line, err := strconv.Atoi(lineStr)
+	if err != nil {
+		return Location{}, false
+	}
+	column, err := strconv.Atoi(colStr)
+	if err != nil {
+		return Location{}, false
+	}
+	astFile, _ := proj.ASTFile(filename)
+	if astFile == nil {
+		return Location{}, false
+	}
+	tokenFile := xgoutil.NodeTokenFile(proj, astFile)
+	if tokenFile == nil || line < 1 || line > tokenFile.LineCount() || column < 1 {
+		return Location{}, false
+	}
+	if offset := int(tokenFile.LineStart(line)) - tokenFile.Base() + column - 1; offset > len(astFile.Code) {
+		return Location{}, false
+	}
+	return Location{
+		URI:   s.toDocumentURI(filename),
+		Range: RangeForASTFilePosition(proj, astFile, xgotoken.Position{Filename: filename, Line: line, Column: column}),
+	}, true
+}
+
+// cutLast slices s around the last instance of sep, returning the text before
+// and after sep. If sep does not appear in s, cutLast returns s, "", false.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// checkContext reports the cause of ctx if it is done. For cancellable
+// contexts, it also yields the processor first so that pending cancellation
+// notifications have a chance to be handled, which matters in environments
+// like WebAssembly where goroutines are not preempted.
+func (s *Server) checkContext(ctx context.Context) error {
+	if ctx.Done() == nil {
+		return nil
+	}
+	if s.scheduler != nil {
+		s.scheduler.Sched()
+	}
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	return nil
+}
+
 // compileAndGetASTFileForDocumentURI handles common compilation and file
 // retrieval logic for a given document URI. The returned astFile is probably
 // nil even if the compilation succeeded.
-func (s *Server) compileAndGetASTFileForDocumentURI(uri DocumentURI) (result *compileResult, spxFile string, astFile *xgoast.File, err error) {
+func (s *Server) compileAndGetASTFileForDocumentURI(ctx context.Context, uri DocumentURI) (result *compileResult, spxFile string, astFile *xgoast.File, err error) {
 	spxFile, err = s.fromDocumentURI(uri)
 	if err != nil {
 		return nil, "", nil, fmt.Errorf("failed to get file path from document URI %q: %w", uri, err)
@@ -468,7 +980,7 @@ func (s *Server) compileAndGetASTFileForDocumentURI(uri DocumentURI) (result *co
 	if path.Ext(spxFile) != ".spx" {
 		return nil, "", nil, fmt.Errorf("file %q does not have .spx extension", spxFile)
 	}
-	result, err = s.compile()
+	result, err = s.compileWithContext(ctx)
 	if err != nil {
 		return nil, "", nil, fmt.Errorf("failed to compile: %w", err)
 	}
@@ -480,63 +992,307 @@ func (s *Server) compileAndGetASTFileForDocumentURI(uri DocumentURI) (result *co
 
 // inspectForSpxResourceSet inspects for spx resource set in main.spx.
 func (s *Server) inspectForSpxResourceSet(snapshot *vfs.MapFS, result *compileResult) {
-	mainASTFile, _ := result.proj.ASTFile(result.mainSpxFile)
-	typeInfo, _ := snapshot.TypeInfo()
-	if typeInfo == nil {
+	spxResourceRootDir := s.inspectForSpxResourceRootDir(snapshot, result)
+	if override := s.getSettings().ResourceRootDir; override != "" {
+		spxResourceRootDir = override
+	}
+	if spxResourceRootDir == "" {
+		spxResourceRootDir = defaultSpxResourceRootDir
+	}
+	result.spxResourceRootDir = spxResourceRootDir
+	s.inspectForSpxResourceMetadata(snapshot, result)
+
+	spxResourceSet, err := s.spxResourceSet(snapshot, spxResourceRootDir)
+	if err != nil {
+		documentURI := s.toDocumentURI(result.mainSpxFile)
+		result.addDiagnostics(documentURI, Diagnostic{
+			Severity: SeverityError,
+			Code:     diagnosticCodeSpxResourceSetInvalid,
+			Message:  fmt.Sprintf("failed to create spx resource set: %v", err),
+		})
 		return
 	}
+	result.spxResourceSet = *spxResourceSet
+	s.inspectForSpxStageConfig(snapshot, result)
+}
 
-	var spxResourceRootDir string
-	for expr, tv := range typeInfo.Types {
-		if expr == nil || !expr.Pos().IsValid() || expr.Pos() < mainASTFile.Pos() || expr.End() > mainASTFile.End() {
-			continue
+// spxMapModes is the set of map modes supported by spx.
+var spxMapModes = map[string]bool{
+	"":          true,
+	"fill":      true,
+	"repeat":    true,
+	"fillCut":   true,
+	"fillRatio": true,
+}
+
+// inspectForSpxStageConfig cross-checks the stage configuration in the
+// index.json file of the resource root directory, including the default
+// backdrop, the map and the zorder, against the resources that actually exist.
+// Problems are reported on main.spx at the resource root directory argument
+// of the first run call, as the configured scene is what the game starts with.
+// Malformed configuration is left to the metadata validation.
+func (s *Server) inspectForSpxStageConfig(snapshot *vfs.MapFS, result *compileResult) {
+	rootDir := result.spxResourceRootDir
+	content, err := vfs.ReadFile(snapshot, path.Join(rootDir, "index.json"))
+	if err != nil {
+		return
+	}
+	var stage struct {
+		Backdrops []struct {
+			Name string `json:"name"`
+			Path string `json:"path"`
+		} `json:"backdrops"`
+		BackdropIndex *int `json:"backdropIndex"`
+		Map           struct {
+			Width  int    `json:"width"`
+			Height int    `json:"height"`
+			Mode   string `json:"mode"`
+		} `json:"map"`
+		Zorder []json.RawMessage `json:"zorder"`
+	}
+	if err := json.Unmarshal(content, &stage); err != nil {
+		return
+	}
+
+	documentURI := s.toDocumentURI(result.mainSpxFile)
+	var rng Range
+	if mainASTFile, _ := result.proj.ASTFile(result.mainSpxFile); mainASTFile != nil {
+		if typeInfo, _ := snapshot.TypeInfo(); typeInfo != nil {
+			if runCallExprs := spxRunCallExprs(mainASTFile, typeInfo); len(runCallExprs) > 0 {
+				rng = RangeForNode(result.proj, runCallExprs[0].Args[0])
+			}
+		}
+	}
+	report := func(format string, args ...any) {
+		result.addDiagnostics(documentURI, Diagnostic{
+			Severity: SeverityWarning,
+			Code:     diagnosticCodeSpxStageConfig,
+			Range:    rng,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	if backdropIndex := stage.BackdropIndex; backdropIndex != nil && len(stage.Backdrops) > 0 {
+		if *backdropIndex < 0 || *backdropIndex >= len(stage.Backdrops) {
+			report("default backdrop index %d is out of range [0, %d)", *backdropIndex, len(stage.Backdrops))
+		} else if backdrop := stage.Backdrops[*backdropIndex]; backdrop.Path != "" {
+			backdropPath := path.Join(rootDir, backdrop.Path)
+			if _, ok := snapshot.File(backdropPath); !ok {
+				report("image file %q of default backdrop %q not found", backdropPath, backdrop.Name)
+			}
 		}
+	}
+
+	if stage.Map.Width < 0 || stage.Map.Height < 0 {
+		report("map size %dx%d must not be negative", stage.Map.Width, stage.Map.Height)
+	}
+	if !spxMapModes[stage.Map.Mode] {
+		report("unknown map mode %q", stage.Map.Mode)
+	}
 
-		callExpr, ok := expr.(*xgoast.CallExpr)
-		if !ok || len(callExpr.Args) == 0 || tv.Type != GetSpxGoptGameRunFunc().Type() {
+	for _, item := range stage.Zorder {
+		var spriteName string
+		if err := json.Unmarshal(item, &spriteName); err != nil {
 			continue
 		}
+		if result.spxResourceSet.Sprite(spriteName) == nil {
+			report("sprite %q in the stage zorder not found", spriteName)
+		}
+	}
+}
+
+// inspectForSpxResourceRootDir inspects the run calls in main.spx for the spx
+// resource root directory, which is the first argument of the first run call.
+// It returns an empty string if no run call specifies the directory.
+//
+// The first argument may be a string literal or a string constant, including
+// one defined in another file. Run calls specifying a different directory than
+// the first one are reported, as only one resource root directory is used.
+func (s *Server) inspectForSpxResourceRootDir(snapshot *vfs.MapFS, result *compileResult) string {
+	mainASTFile, _ := result.proj.ASTFile(result.mainSpxFile)
+	if mainASTFile == nil {
+		return ""
+	}
+	typeInfo, _ := snapshot.TypeInfo()
+	if typeInfo == nil {
+		return ""
+	}
+
+	documentURI := s.toDocumentURI(result.mainSpxFile)
+	var spxResourceRootDir string
+	for _, callExpr := range spxRunCallExprs(mainASTFile, typeInfo) {
 		firstArg := callExpr.Args[0]
 		firstArgTV, ok := typeInfo.Types[firstArg]
 		if !ok {
 			continue
 		}
-
-		if types.AssignableTo(firstArgTV.Type, types.Typ[types.String]) {
-			spxResourceRootDir, _ = xgoutil.StringLitOrConstValue(firstArg, firstArgTV)
-		} else {
-			documentURI := s.toDocumentURI(result.mainSpxFile)
+		if !types.AssignableTo(firstArgTV.Type, types.Typ[types.String]) {
 			result.addDiagnostics(documentURI, Diagnostic{
 				Severity: SeverityError,
+				Code:     diagnosticCodeSpxResourceRootInvalid,
 				Range:    RangeForNode(result.proj, firstArg),
 				Message:  "first argument of run must be a string literal or constant",
 			})
+			continue
+		}
+		if firstArgTV.Value == nil || firstArgTV.Value.Kind() != constant.String {
+			continue
+		}
+
+		dir := path.Clean(constant.StringVal(firstArgTV.Value))
+		if spxResourceRootDir == "" {
+			spxResourceRootDir = dir
+		} else if dir != spxResourceRootDir {
+			result.addDiagnostics(documentURI, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     diagnosticCodeSpxResourceRootMismatch,
+				Range:    RangeForNode(result.proj, firstArg),
+				Message:  fmt.Sprintf("resource root directory %q differs from %q of the first run call, which is used instead", dir, spxResourceRootDir),
+			})
 		}
-		break
 	}
-	if spxResourceRootDir == "" {
-		spxResourceRootDir = "assets"
+	return spxResourceRootDir
+}
+
+// spxRunCallExprs returns the run calls with arguments in the given main.spx
+// AST file.
+func spxRunCallExprs(mainASTFile *xgoast.File, typeInfo *xgo.TypeInfo) []*xgoast.CallExpr {
+	var runCallExprs []*xgoast.CallExpr
+	xgoast.Inspect(mainASTFile, func(node xgoast.Node) bool {
+		callExpr, ok := node.(*xgoast.CallExpr)
+		if ok && len(callExpr.Args) > 0 && xgoutil.FuncFromCallExpr(typeInfo, callExpr) == GetSpxGoptGameRunFunc() {
+			runCallExprs = append(runCallExprs, callExpr)
+		}
+		return true
+	})
+	return runCallExprs
+}
+
+// spxResourceSet returns the spx resource set in the given resource root
+// directory of the snapshot. The last resource set is reused as long as the
+// resource root directory does not change, so changes to code alone do not
+// cause the resource set to be rebuilt.
+//
+// It must be called with s.compileMu held.
+func (s *Server) spxResourceSet(snapshot *vfs.MapFS, rootDir string) (*SpxResourceSet, error) {
+	key := spxResourceSetKey(snapshot, rootDir)
+	if c := s.lastSpxResourceSet.Load(); c != nil && c.key == key {
+		return c.set, c.err
+	}
+	set, err := NewSpxResourceSet(vfs.Sub(snapshot, rootDir))
+	s.lastSpxResourceSet.Store(&spxResourceSetCache{
+		key:     key,
+		rootDir: rootDir,
+		set:     set,
+		err:     err,
+	})
+	return set, err
+}
+
+// spxResourceAutoBindingNotInFirstVarBlockMessage is the message of the
+// diagnostic reported for an auto-binding spx resource variable that is not
+// defined in the first var block.
+const spxResourceAutoBindingNotInFirstVarBlockMessage = "resources must be defined in the first var block for auto-binding"
+
+// reportSpxResourceAutoBindingTypeMismatch reports a warning for the auto-binding
+// variable declared at ident, whose type does not match the kind of the
+// resource with the same name.
+func (s *Server) reportSpxResourceAutoBindingTypeMismatch(result *compileResult, ident *xgoast.Ident, typeName, resourceKind string) {
+	result.addDiagnostics(s.nodeDocumentURI(result.proj, ident), Diagnostic{
+		Severity: SeverityWarning,
+		Code:     diagnosticCodeSpxAutoBindTypeMismatch,
+		Range:    RangeForNode(result.proj, ident),
+		Message:  fmt.Sprintf("%q is declared as %s, but the resource with that name is a %s", ident.Name, typeName, resourceKind),
+	})
+}
+
+// defaultSpxResourceRootDir is the resource root directory used when the main
+// spx file does not specify one.
+const defaultSpxResourceRootDir = "assets"
+
+// spxResourceSetCache is the last spx resource set built by a [Server].
+type spxResourceSetCache struct {
+	key     [sha256.Size]byte // See [spxResourceSetKey].
+	rootDir string
+	set     *SpxResourceSet
+	err     error
+}
+
+// affectsSpxResources reports whether changing the files at paths in proj
+// may affect the spx resource set, and hence the resource diagnostics of all
+// spx files. This is the case if any of paths is an ignore file, or is under
+// the resource root directory and is not an existing file, such as a file
+// being added or a directory, or is an index.json file or an asset file
+// referenced by the resource set. It must be called before the changes are
+// applied.
+func (s *Server) affectsSpxResources(proj *xgo.Project, paths []string) bool {
+	rootDir := defaultSpxResourceRootDir
+	var set *SpxResourceSet
+	if c := s.lastSpxResourceSet.Load(); c != nil {
+		rootDir = c.rootDir
+		set = c.set
+	}
+	for _, p := range paths {
+		if xgo.IsIgnoreFile(p) {
+			return true
+		}
+		rel, ok := strings.CutPrefix(p, rootDir+"/")
+		if !ok {
+			continue
+		}
+		if path.Base(rel) == "index.json" {
+			return true
+		}
+		if set != nil && set.HasAsset(rel) {
+			return true
+		}
+		if _, ok := proj.File(p); !ok {
+			return true
+		}
 	}
-	spxResourceRootFS := vfs.Sub(snapshot, spxResourceRootDir)
+	return false
+}
 
-	spxResourceSet, err := NewSpxResourceSet(spxResourceRootFS)
-	if err != nil {
-		documentURI := s.toDocumentURI(result.mainSpxFile)
-		result.addDiagnostics(documentURI, Diagnostic{
-			Severity: SeverityError,
-			Message:  fmt.Sprintf("failed to create spx resource set: %v", err),
-		})
-		return
+// spxResourceSetKey returns a hash of everything [NewSpxResourceSet] reads
+// from the given resource root directory of the snapshot: the paths of all
+// files in it that are not ignored, which make up its directory structure, and
+// the content of its index.json files. Other files, such as images and sounds, are not read.
+//
+// The content of index.json files is taken into account by their hashes,
+// which are kept with the files, so they are not hashed again on every
+// compilation.
+func spxResourceSetKey(snapshot *vfs.MapFS, rootDir string) [sha256.Size]byte {
+	prefix := rootDir + "/"
+	files := make(map[string]*vfs.MapFile)
+	for p, file := range snapshot.Files() {
+		if strings.HasPrefix(p, prefix) && !snapshot.IsIgnored(p) {
+			files[p] = file
+		}
 	}
-	result.spxResourceSet = *spxResourceSet
+
+	h := sha256.New()
+	for _, p := range slices.Sorted(maps.Keys(files)) {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+		if path.Base(p) == "index.json" {
+			hash := files[p].Hash()
+			h.Write(hash[:])
+		}
+	}
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key
 }
 
 // inspectDiagnosticsAnalyzers runs registered analyzers on each spx source file
 // and collects diagnostics.
 //
-// For each spx file in the main package, it:
-//  1. Creates an analysis pass with file-specific information
-//  2. Runs all registered analyzers on the file
+// Analyzers run concurrently on a bounded pool of workers. For each spx file
+// in the main package, it:
+//  1. Runs all enabled analyzers on the file, after the analyzers they require.
+//     Analyzers that use facts run once on all files of the package instead
+//  2. Shares results of required analyzers (such as the inspect analyzer)
+//     between all analyzers on the same file
 //  3. Collects diagnostics from analyzers
 //  4. Reports any analyzer errors as diagnostics
 //
@@ -544,12 +1300,14 @@ func (s *Server) inspectForSpxResourceSet(snapshot *vfs.MapFS, result *compileRe
 //   - result: The compilation result containing AST and type information
 //
 // The function updates result.diagnostics with any issues found by analyzers.
-// Diagnostic severity levels include:
-//   - Error: For analyzer failures or serious code issues
-//   - Warning: For potential problems that don't prevent compilation
+// Diagnostics reported by an analyzer have the severity configured for it in
+// the settings, falling back to the severity given by the diagnostic itself and
+// then to the analyzer's default severity, and its tags. The category of a
+// diagnostic is reported as its code. Analyzer failures are always reported as
+// errors.
 func (s *Server) inspectDiagnosticsAnalyzers(result *compileResult) {
+	settings := s.getSettings()
 	proj := result.proj
-	fset := proj.Fset
 	typeInfo, _ := proj.TypeInfo()
 	if typeInfo == nil {
 		return
@@ -558,37 +1316,27 @@ func (s *Server) inspectDiagnosticsAnalyzers(result *compileResult) {
 	if astPkg == nil {
 		return
 	}
-	for spxFile, astFile := range astPkg.Files {
-		var diagnostics []Diagnostic
-		pass := &protocol.Pass{
-			Fset:      fset,
-			Files:     []*xgoast.File{astFile},
-			TypesInfo: typeInfo,
-			Report: func(d protocol.Diagnostic) {
-				diagnostics = append(diagnostics, Diagnostic{
-					Range:    RangeForPosEnd(proj, d.Pos, d.End),
-					Severity: SeverityError,
-					Message:  d.Message,
-				})
-			},
-			ResultOf: map[*protocol.Analyzer]any{
-				inspect.Analyzer: inspector.New([]*xgoast.File{astFile}),
-			},
-		}
-
-		for _, analyzer := range s.analyzers {
-			an := analyzer.Analyzer()
-			if _, err := an.Run(pass); err != nil {
-				diagnostics = append(diagnostics, Diagnostic{
-					Severity: SeverityError,
-					Message:  fmt.Sprintf("analyzer %q failed: %v", an.Name, err),
-				})
-			}
+
+	var analyzers []*protocol.Analyzer
+	configs := make(map[*protocol.Analyzer]analyzerConfig, len(s.analyzers))
+	for _, analyzer := range s.analyzers {
+		if settings.analyzerEnabled(analyzer) {
+			analyzers = append(analyzers, analyzer.Analyzer())
+			configs[analyzer.Analyzer()] = settings.analyzerConfig(analyzer)
 		}
+	}
+	for spxFile, diagnostics := range runAnalyzers(proj, typeInfo, result.spxVersion, astPkg.Files, analyzers, configs, s.toDocumentURI) {
+		result.addDiagnostics(s.toDocumentURI(spxFile), diagnostics...)
+	}
+}
 
-		documentURI := s.toDocumentURI(spxFile)
-		result.addDiagnostics(documentURI, diagnostics...)
+// analyzerTags returns the tags of diagnostics reported by the given analyzer.
+func analyzerTags(analyzer *analysis.Analyzer) []DiagnosticTag {
+	var tags []DiagnosticTag
+	for _, tag := range analyzer.Tags() {
+		tags = append(tags, DiagnosticTag(tag))
 	}
+	return tags
 }
 
 // isInspectableSpxResourceType reports whether the given type is an
@@ -661,8 +1409,14 @@ func (s *Server) inspectForSpxResourceRefs(result *compileResult) {
 			switch varType {
 			case GetSpxSoundType():
 				isSpxSoundResourceAutoBinding = result.spxResourceSet.Sound(v.Name()) != nil
+				if !isSpxSoundResourceAutoBinding && result.spxResourceSet.Sprite(v.Name()) != nil {
+					s.reportSpxResourceAutoBindingTypeMismatch(result, ident, "Sound", "sprite")
+				}
 			case GetSpxSpriteType():
 				isSpxSpriteResourceAutoBinding = result.spxResourceSet.Sprite(v.Name()) != nil
+				if !isSpxSpriteResourceAutoBinding && result.spxResourceSet.Sound(v.Name()) != nil {
+					s.reportSpxResourceAutoBindingTypeMismatch(result, ident, "Sprite", "sound")
+				}
 			default:
 				isSpxSpriteResourceAutoBinding = v.Name() == varType.Obj().Name() && result.hasSpxSpriteType(varType)
 			}
@@ -675,8 +1429,9 @@ func (s *Server) inspectForSpxResourceRefs(result *compileResult) {
 				documentURI := s.toDocumentURI(spxFile)
 				result.addDiagnostics(documentURI, Diagnostic{
 					Severity: SeverityWarning,
+					Code:     diagnosticCodeSpxAutoBindOrder,
 					Range:    RangeForNode(result.proj, ident),
-					Message:  "resources must be defined in the first var block for auto-binding",
+					Message:  spxResourceAutoBindingNotInFirstVarBlockMessage,
 				})
 				continue
 			}
@@ -743,6 +1498,15 @@ func (s *Server) inspectForSpxResourceRefs(result *compileResult) {
 					s.inspectSpxResourceRefForTypeAtExpr(result, arg, paramType, spxSpriteResource)
 				}
 			}
+
+			if fun := xgoutil.FuncFromCallExpr(typeInfo, expr); fun != nil {
+				switch {
+				case fun.Origin() == GetSpxGoptGameGopxGetWidgetFunc():
+					s.inspectSpxWidgetTypeAtCallExpr(result, expr, tv.Type)
+				case spxSpriteResource != nil && len(expr.Args) == 1 && slices.Contains(GetSpxSpriteImplSetCostumeByIndexFuncs(), fun):
+					s.inspectSpxSpriteCostumeIndexRefAtExpr(result, spxSpriteResource, expr.Args[0])
+				}
+			}
 		default:
 			typ := xgoutil.DerefType(tv.Type)
 			if isInspectableSpxResourceType(typ) || result.hasSpxSpriteType(typ) {
@@ -835,6 +1599,7 @@ func (s *Server) inspectSpxBackdropResourceRefAtExpr(result *compileResult, expr
 	if spxBackdropName == "" {
 		result.addDiagnostics(exprDocumentURI, Diagnostic{
 			Severity: SeverityError,
+			Code:     diagnosticCodeSpxResourceNameEmpty,
 			Range:    exprRange,
 			Message:  "backdrop resource name cannot be empty",
 		})
@@ -853,9 +1618,11 @@ func (s *Server) inspectSpxBackdropResourceRefAtExpr(result *compileResult, expr
 	spxBackdropResource := result.spxResourceSet.Backdrop(spxBackdropName)
 	if spxBackdropResource == nil {
 		result.addDiagnostics(exprDocumentURI, Diagnostic{
-			Severity: SeverityError,
-			Range:    exprRange,
-			Message:  fmt.Sprintf("backdrop resource %q not found", spxBackdropName),
+			Severity:           SeverityError,
+			Code:               diagnosticCodeSpxResourceNotFound,
+			Range:              exprRange,
+			Message:            fmt.Sprintf("backdrop resource %q not found", spxBackdropName),
+			RelatedInformation: s.spxResourceRelatedInformation(result, "index.json", "backdrop resources"),
 		})
 		return nil
 	}
@@ -929,6 +1696,7 @@ func (s *Server) inspectSpxSpriteResourceRefAtExpr(result *compileResult, expr x
 		if spxSpriteName == "" {
 			result.addDiagnostics(exprDocumentURI, Diagnostic{
 				Severity: SeverityError,
+				Code:     diagnosticCodeSpxResourceNameEmpty,
 				Range:    exprRange,
 				Message:  "sprite resource name cannot be empty",
 			})
@@ -944,9 +1712,11 @@ func (s *Server) inspectSpxSpriteResourceRefAtExpr(result *compileResult, expr x
 	spxSpriteResource := result.spxResourceSet.Sprite(spxSpriteName)
 	if spxSpriteResource == nil {
 		result.addDiagnostics(exprDocumentURI, Diagnostic{
-			Severity: SeverityError,
-			Range:    exprRange,
-			Message:  fmt.Sprintf("sprite resource %q not found", spxSpriteName),
+			Severity:           SeverityError,
+			Code:               diagnosticCodeSpxResourceNotFound,
+			Range:              exprRange,
+			Message:            fmt.Sprintf("sprite resource %q not found", spxSpriteName),
+			RelatedInformation: s.spxResourceRelatedInformation(result, "sprites", "sprite resources"),
 		})
 		return nil
 	}
@@ -980,6 +1750,7 @@ func (s *Server) inspectSpxSpriteCostumeResourceRefAtExpr(result *compileResult,
 	if spxSpriteCostumeName == "" {
 		result.addDiagnostics(exprDocumentURI, Diagnostic{
 			Severity: SeverityError,
+			Code:     diagnosticCodeSpxResourceNameEmpty,
 			Range:    exprRange,
 			Message:  "sprite costume resource name cannot be empty",
 		})
@@ -998,12 +1769,54 @@ func (s *Server) inspectSpxSpriteCostumeResourceRefAtExpr(result *compileResult,
 	spxSpriteCostumeResource := spxSpriteResource.Costume(spxSpriteCostumeName)
 	if spxSpriteCostumeResource == nil {
 		result.addDiagnostics(exprDocumentURI, Diagnostic{
+			Severity:           SeverityError,
+			Code:               diagnosticCodeSpxResourceNotFound,
+			Range:              exprRange,
+			Message:            fmt.Sprintf("costume resource %q not found in sprite %q", spxSpriteCostumeName, spxSpriteResource.Name),
+			RelatedInformation: s.spxResourceRelatedInformation(result, path.Join("sprites", spxSpriteResource.Name, "index.json"), fmt.Sprintf("costume resources of sprite %q", spxSpriteResource.Name)),
+		})
+		return nil
+	}
+	return spxSpriteCostumeResource
+}
+
+// inspectSpxSpriteCostumeIndexRefAtExpr inspects an spx sprite costume
+// resource reference by index at an expression, which is only tracked for
+// integer literals. It returns the spx sprite costume resource if it was
+// successfully retrieved.
+func (s *Server) inspectSpxSpriteCostumeIndexRefAtExpr(result *compileResult, spxSpriteResource *SpxSpriteResource, expr xgoast.Expr) *SpxSpriteCostumeResource {
+	typeInfo, _ := result.proj.TypeInfo()
+	if typeInfo == nil {
+		return nil
+	}
+	lit, ok := expr.(*xgoast.BasicLit)
+	if !ok || lit.Kind != xgotoken.INT {
+		return nil
+	}
+	exprTV := typeInfo.Types[expr]
+	if exprTV.Value == nil {
+		return nil
+	}
+	index, ok := constant.Int64Val(exprTV.Value)
+	if !ok {
+		return nil
+	}
+
+	if index < 0 || index >= int64(len(spxSpriteResource.Costumes)) {
+		result.addDiagnostics(s.nodeDocumentURI(result.proj, expr), Diagnostic{
 			Severity: SeverityError,
-			Range:    exprRange,
-			Message:  fmt.Sprintf("costume resource %q not found in sprite %q", spxSpriteCostumeName, spxSpriteResource.Name),
+			Code:     diagnosticCodeSpxCostumeIndexOutOfRange,
+			Range:    RangeForNode(result.proj, expr),
+			Message:  fmt.Sprintf("costume index %d is out of range [0, %d) in sprite %q", index, len(spxSpriteResource.Costumes), spxSpriteResource.Name),
 		})
 		return nil
 	}
+	spxSpriteCostumeResource := &spxSpriteResource.Costumes[index]
+	result.addSpxResourceRef(SpxResourceRef{
+		ID:   spxSpriteCostumeResource.ID,
+		Kind: SpxResourceRefKindIndexLiteral,
+		Node: expr,
+	})
 	return spxSpriteCostumeResource
 }
 
@@ -1038,6 +1851,7 @@ func (s *Server) inspectSpxSpriteAnimationResourceRefAtExpr(result *compileResul
 	if spxSpriteAnimationName == "" {
 		result.addDiagnostics(exprDocumentURI, Diagnostic{
 			Severity: SeverityError,
+			Code:     diagnosticCodeSpxResourceNameEmpty,
 			Range:    exprRange,
 			Message:  "sprite animation resource name cannot be empty",
 		})
@@ -1052,9 +1866,11 @@ func (s *Server) inspectSpxSpriteAnimationResourceRefAtExpr(result *compileResul
 	spxSpriteAnimationResource := spxSpriteResource.Animation(spxSpriteAnimationName)
 	if spxSpriteAnimationResource == nil {
 		result.addDiagnostics(exprDocumentURI, Diagnostic{
-			Severity: SeverityError,
-			Range:    exprRange,
-			Message:  fmt.Sprintf("animation resource %q not found in sprite %q", spxSpriteAnimationName, spxSpriteResource.Name),
+			Severity:           SeverityError,
+			Code:               diagnosticCodeSpxResourceNotFound,
+			Range:              exprRange,
+			Message:            fmt.Sprintf("animation resource %q not found in sprite %q", spxSpriteAnimationName, spxSpriteResource.Name),
+			RelatedInformation: s.spxResourceRelatedInformation(result, path.Join("sprites", spxSpriteResource.Name, "index.json"), fmt.Sprintf("animation resources of sprite %q", spxSpriteResource.Name)),
 		})
 		return nil
 	}
@@ -1118,6 +1934,7 @@ func (s *Server) inspectSpxSoundResourceRefAtExpr(result *compileResult, expr xg
 	if spxSoundName == "" {
 		result.addDiagnostics(exprDocumentURI, Diagnostic{
 			Severity: SeverityError,
+			Code:     diagnosticCodeSpxResourceNameEmpty,
 			Range:    exprRange,
 			Message:  "sound resource name cannot be empty",
 		})
@@ -1132,15 +1949,98 @@ func (s *Server) inspectSpxSoundResourceRefAtExpr(result *compileResult, expr xg
 	spxSoundResource := result.spxResourceSet.Sound(spxSoundName)
 	if spxSoundResource == nil {
 		result.addDiagnostics(exprDocumentURI, Diagnostic{
-			Severity: SeverityError,
-			Range:    exprRange,
-			Message:  fmt.Sprintf("sound resource %q not found", spxSoundName),
+			Severity:           SeverityError,
+			Code:               diagnosticCodeSpxResourceNotFound,
+			Range:              exprRange,
+			Message:            fmt.Sprintf("sound resource %q not found", spxSoundName),
+			RelatedInformation: s.spxResourceRelatedInformation(result, "sounds", "sound resources"),
 		})
 		return nil
 	}
+	if spxResourceRefKind != SpxResourceRefKindAutoBindingReference && spxSoundResource.Path != "" {
+		soundPath := path.Join(result.spxResourceRootDir, "sounds", spxSoundName, spxSoundResource.Path)
+		if _, ok := result.proj.File(soundPath); !ok {
+			result.addDiagnostics(exprDocumentURI, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     diagnosticCodeSpxSoundFileNotFound,
+				Range:    exprRange,
+				Message:  fmt.Sprintf("sound file %q of sound resource %q not found", soundPath, spxSoundName),
+			})
+		} else if format := spxSoundResource.Format(); !spxSupportedSoundFormats[format] {
+			result.addDiagnostics(exprDocumentURI, Diagnostic{
+				Severity: SeverityWarning,
+				Code:     diagnosticCodeSpxSoundFormatUnsupported,
+				Range:    exprRange,
+				Message:  fmt.Sprintf("sound resource %q has unsupported format %q", spxSoundName, format),
+			})
+		}
+	}
 	return spxSoundResource
 }
 
+// spxResourceRelatedInformation returns the related information of a
+// diagnostic about a missing spx resource, which points at the given path,
+// relative to the spx resource root directory, where resources of the given
+// kind were looked up.
+func (s *Server) spxResourceRelatedInformation(result *compileResult, relPath, kind string) []DiagnosticRelatedInformation {
+	p := path.Join(result.spxResourceRootDir, relPath)
+	return []DiagnosticRelatedInformation{{
+		Location: Location{URI: s.toDocumentURI(p)},
+		Message:  fmt.Sprintf("%s are looked up in %s", kind, p),
+	}}
+}
+
+// spxWidgetResourceType returns the type of the widgets of the given type in
+// spx resource metadata. It returns nil if such widgets are not [spx.Widget]s.
+func spxWidgetResourceType(widgetType string) types.Type {
+	switch widgetType {
+	case "monitor", "stageMonitor":
+		return GetSpxMonitorType()
+	}
+	return nil
+}
+
+// inspectSpxWidgetTypeAtCallExpr inspects a call to [spx.Gopt_Game_Gopx_GetWidget]
+// that results in resultType, and reports the widget resource it gets if its
+// type does not match the requested one, as the call panics at runtime then.
+func (s *Server) inspectSpxWidgetTypeAtCallExpr(result *compileResult, callExpr *xgoast.CallExpr, resultType types.Type) {
+	typeInfo, _ := result.proj.TypeInfo()
+	if typeInfo == nil || len(callExpr.Args) == 0 {
+		return
+	}
+	nameExpr := callExpr.Args[len(callExpr.Args)-1]
+	spxWidgetName, ok := xgoutil.StringLitOrConstValue(nameExpr, typeInfo.Types[nameExpr])
+	if !ok {
+		return
+	}
+	spxWidgetResource := result.spxResourceSet.Widget(spxWidgetName)
+	if spxWidgetResource == nil || spxWidgetResource.Type == "" {
+		return
+	}
+
+	var msg string
+	requestedType := xgoutil.DerefType(resultType)
+	if widgetType := spxWidgetResourceType(spxWidgetResource.Type); widgetType == nil {
+		msg = fmt.Sprintf("widget resource %q has unsupported type %q", spxWidgetName, spxWidgetResource.Type)
+	} else if !types.Identical(requestedType, widgetType) {
+		qualifier := func(pkg *types.Package) string {
+			if pkg == GetSpxPkg() || xgoutil.IsMainPkg(pkg) {
+				return ""
+			}
+			return pkg.Name()
+		}
+		msg = fmt.Sprintf("widget resource %q is a %s, not %s", spxWidgetName, types.TypeString(widgetType, qualifier), types.TypeString(requestedType, qualifier))
+	} else {
+		return
+	}
+	result.addDiagnostics(s.nodeDocumentURI(result.proj, nameExpr), Diagnostic{
+		Severity: SeverityError,
+		Code:     diagnosticCodeSpxWidgetTypeMismatch,
+		Range:    RangeForNode(result.proj, nameExpr),
+		Message:  msg,
+	})
+}
+
 // inspectSpxWidgetResourceRefAtExpr inspects an spx widget resource reference
 // at an expression. It returns the spx widget resource if it was successfully
 // retrieved.
@@ -1172,6 +2072,7 @@ func (s *Server) inspectSpxWidgetResourceRefAtExpr(result *compileResult, expr x
 	if spxWidgetName == "" {
 		result.addDiagnostics(exprDocumentURI, Diagnostic{
 			Severity: SeverityError,
+			Code:     diagnosticCodeSpxResourceNameEmpty,
 			Range:    exprRange,
 			Message:  "widget resource name cannot be empty",
 		})
@@ -1186,9 +2087,11 @@ func (s *Server) inspectSpxWidgetResourceRefAtExpr(result *compileResult, expr x
 	spxWidgetResource := result.spxResourceSet.Widget(spxWidgetName)
 	if spxWidgetResource == nil {
 		result.addDiagnostics(exprDocumentURI, Diagnostic{
-			Severity: SeverityError,
-			Range:    exprRange,
-			Message:  fmt.Sprintf("widget resource %q not found", spxWidgetName),
+			Severity:           SeverityError,
+			Code:               diagnosticCodeSpxResourceNotFound,
+			Range:              exprRange,
+			Message:            fmt.Sprintf("widget resource %q not found", spxWidgetName),
+			RelatedInformation: s.spxResourceRelatedInformation(result, "index.json", "widget resources"),
 		})
 		return nil
 	}
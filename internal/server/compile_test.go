@@ -0,0 +1,568 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+
+	"github.com/goplus/xgolsw/internal/pkgdata"
+	"github.com/goplus/xgolsw/internal/vfs"
+	"github.com/goplus/xgolsw/pkgdoc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerCompileCache(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		m := newTestFileMap()
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, fileMapGetter(m), &MockScheduler{})
+
+		result1, err := s.compile()
+		require.NoError(t, err)
+		result2, err := s.compile()
+		require.NoError(t, err)
+		assert.Same(t, result1, result2)
+	})
+
+	t.Run("FileChanged", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		result1, err := s.compile()
+		require.NoError(t, err)
+
+		// Putting the same content keeps the result.
+		content, err := vfs.ReadFile(s.getProj(), "main.spx")
+		require.NoError(t, err)
+		s.getProj().PutFile("main.spx", &vfs.MapFile{Content: content, Version: 2})
+		result2, err := s.compile()
+		require.NoError(t, err)
+		assert.Same(t, result1, result2)
+
+		s.getProj().PutFile("main.spx", &vfs.MapFile{Content: append(content, "\necho 1\n"...), Version: 3})
+		result3, err := s.compile()
+		require.NoError(t, err)
+		assert.NotSame(t, result1, result3)
+
+		// Asset changes make the result stale too.
+		s.getProj().PutFile("assets/index.json", &vfs.MapFile{Content: []byte(`{"zorder":[]}`)})
+		result4, err := s.compile()
+		require.NoError(t, err)
+		assert.NotSame(t, result3, result4)
+	})
+
+	t.Run("Snapshot", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		result, err := s.compile()
+		require.NoError(t, err)
+		require.NotSame(t, s.getProj(), result.proj)
+
+		// Caches built by the compilation are shared with the project.
+		astFile, err := result.proj.ASTFile("main.spx")
+		require.NoError(t, err)
+		projASTFile, err := s.getProj().ASTFile("main.spx")
+		require.NoError(t, err)
+		assert.Same(t, astFile, projASTFile)
+
+		// Later changes to the project are not visible in the result.
+		content, err := vfs.ReadFile(s.getProj(), "main.spx")
+		require.NoError(t, err)
+		s.getProj().PutFile("main.spx", &vfs.MapFile{Content: append(content, "\necho 1\n"...)})
+		resultContent, err := vfs.ReadFile(result.proj, "main.spx")
+		require.NoError(t, err)
+		assert.Equal(t, content, resultContent)
+	})
+
+	t.Run("SettingsChanged", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		result1, err := s.compile()
+		require.NoError(t, err)
+
+		require.NoError(t, s.didChangeConfiguration(&DidChangeConfigurationParams{
+			Settings: map[string]any{"analyzers": map[string]any{"appends": false}},
+		}))
+		result2, err := s.compile()
+		require.NoError(t, err)
+		assert.NotSame(t, result1, result2)
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := s.compileWithContext(ctx)
+		require.ErrorIs(t, err, context.Canceled)
+
+		// A cancelled compilation is not reused.
+		result, err := s.compile()
+		require.NoError(t, err)
+		assert.NotNil(t, result)
+	})
+
+	t.Run("NoMainSpxFile", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), &mockReplier{}, nil, &MockScheduler{})
+
+		_, err := s.compile()
+		assert.ErrorIs(t, err, errNoMainSpxFile)
+
+		s.getProj().PutFile("main.spx", &vfs.MapFile{Content: []byte("echo 1")})
+		result, err := s.compile()
+		require.NoError(t, err)
+		assert.NotNil(t, result)
+	})
+}
+
+func TestCompile(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		result, err := Compile(context.Background(), newMapFSWithoutModTime(newTestFileMap()))
+		require.NoError(t, err)
+		assert.Equal(t, "main", result.Proj.PkgPath)
+		assert.Contains(t, result.Diagnostics, "main.spx")
+		assert.Contains(t, result.Diagnostics, "assets/index.json")
+		assert.Empty(t, result.Diagnostics["MyAircraft.spx"])
+		assert.NotEmpty(t, result.SpxResourceRefs)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := Compile(ctx, newMapFSWithoutModTime(newTestFileMap()))
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestServerCompileIgnoredFiles(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		m := newTestFileMap()
+		m["backup/MySprite.spx"] = []byte(`onStart => {`)
+		m["MySprite.spx~"] = []byte(`onStart => {`)
+		m[".gitignore"] = []byte("# Editor backup files.\n*~\n")
+		m[".spxignore"] = []byte("/backup/\n")
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, nil, &MockScheduler{})
+
+		result, err := s.compile()
+		require.NoError(t, err)
+		assert.NotContains(t, result.diagnostics, s.toDocumentURI("backup/MySprite.spx"))
+		astPkg, err := result.proj.ASTPackage()
+		require.NoError(t, err)
+		assert.NotContains(t, astPkg.Files, "backup/MySprite.spx")
+		assert.Contains(t, astPkg.Files, "main.spx")
+
+		// Removing the ignore file makes the files part of the project again.
+		require.NoError(t, s.getProj().DeleteFile(".spxignore"))
+		result, err = s.compile()
+		require.NoError(t, err)
+		assert.Contains(t, result.diagnostics, s.toDocumentURI("backup/MySprite.spx"))
+	})
+}
+
+func TestServerCompileLocalPackages(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		m := map[string][]byte{
+			"go.mod": []byte("module example.com/game\n"),
+			"main.spx": []byte(`import "example.com/game/utils"
+
+echo utils.Double(21)
+run "assets", {Title: "My Game"}
+`),
+			"utils/utils.xgo": []byte(`package utils
+
+func Double(x int) int {
+	return x * 2
+}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, nil, &MockScheduler{})
+
+		result, err := s.compile()
+		require.NoError(t, err)
+		assert.Empty(t, result.diagnostics["file:///main.spx"])
+		assert.Contains(t, result.diagnostics, DocumentURI("file:///utils/utils.xgo"))
+		assert.Empty(t, result.diagnostics["file:///utils/utils.xgo"])
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`import "utils"
+
+echo utils.Double(21)
+run "assets", {Title: "My Game"}
+`),
+			"utils/utils.xgo": []byte(`package utils
+
+func Double(x int) int {
+	return x * "2"
+}
+`),
+			"utils/syntax.xgo":  []byte("package utils\n\nfunc broken( {\n"),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, nil, &MockScheduler{})
+
+		result, err := s.compile()
+		require.NoError(t, err)
+		assert.Empty(t, result.diagnostics["file:///main.spx"])
+		require.Len(t, result.diagnostics["file:///utils/utils.xgo"], 1)
+		assert.Equal(t, Range{
+			Start: Position{Line: 3, Character: 8},
+			End:   Position{Line: 3, Character: 8},
+		}, result.diagnostics["file:///utils/utils.xgo"][0].Range)
+		assert.NotEmpty(t, result.diagnostics["file:///utils/syntax.xgo"])
+	})
+}
+
+func TestServerCompileThirdPartyImports(t *testing.T) {
+	modCache := fstest.MapFS{
+		"example.com/greet@v1.0.0/greet.go": {Data: []byte(`package greet
+
+func Hello(name string) string {
+	return "Hello, " + name
+}
+`)},
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		m := map[string][]byte{
+			"go.mod": []byte("module example.com/game\n\nrequire example.com/greet v1.0.0\n"),
+			"main.spx": []byte(`import "example.com/greet"
+
+echo greet.Hello("spx")
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		proj := newMapFSWithoutModTime(m)
+		proj.ModCache = modCache
+		s := New(proj, &mockReplier{}, nil, &MockScheduler{})
+
+		result, err := s.compile()
+		require.NoError(t, err)
+		assert.Empty(t, result.diagnostics["file:///main.spx"])
+	})
+
+	t.Run("NotRequired", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`import "example.com/greet"
+
+echo greet.Hello("spx")
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		proj := newMapFSWithoutModTime(m)
+		proj.ModCache = modCache
+		s := New(proj, &mockReplier{}, nil, &MockScheduler{})
+
+		result, err := s.compile()
+		require.NoError(t, err)
+		require.NotEmpty(t, result.diagnostics["file:///main.spx"])
+		diag := result.diagnostics["file:///main.spx"][0]
+		assert.Equal(t, SeverityError, diag.Severity)
+		assert.Contains(t, diag.Message, `go get example.com/greet`)
+		assert.Equal(t, Range{
+			Start: Position{Line: 0, Character: 7},
+			End:   Position{Line: 0, Character: 7},
+		}, diag.Range)
+	})
+}
+
+func TestServerSpxVersion(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("spx@v2.0.0-alpha.1/" + SpxPkgPath + ".pkgdoc")
+	require.NoError(t, err)
+	require.NoError(t, json.NewEncoder(w).Encode(&pkgdoc.PkgDoc{
+		Path: SpxPkgPath,
+		Name: "spx",
+		Types: map[string]*pkgdoc.TypeDoc{
+			"SpriteImpl": {
+				Methods: map[string]string{
+					"Hide": "Hide hides the sprite.\n\nDeprecated: Set the visible field instead.\n",
+				},
+			},
+		},
+	}))
+	require.NoError(t, zw.Close())
+	pkgdata.SetCustomPkgdataZip(buf.Bytes())
+	t.Cleanup(func() { pkgdata.SetCustomPkgdataZip(nil) })
+
+	newServer := func(goMod string) *Server {
+		m := map[string][]byte{
+			"main.spx":          []byte(`run "assets", {Title: "My Game"}`),
+			"MySprite.spx":      []byte("onStart => {\n\thide()\n}\n"),
+			"assets/index.json": []byte(`{}`),
+		}
+		if goMod != "" {
+			m["go.mod"] = []byte(goMod)
+		}
+		return New(newMapFSWithoutModTime(m), &mockReplier{}, fileMapGetter(m), &MockScheduler{})
+	}
+	hideDeprecated := func(result *compileResult) bool {
+		for _, diag := range result.diagnostics["file:///MySprite.spx"] {
+			if diag.Message == "hide is deprecated" {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("GoMod", func(t *testing.T) {
+		s := newServer("module example.com/game\n\nrequire github.com/goplus/spx/v2 v2.0.0-alpha.1\n")
+
+		result, err := s.compile()
+		require.NoError(t, err)
+		assert.Equal(t, "v2.0.0-alpha.1", result.spxVersion)
+		assert.True(t, hideDeprecated(result))
+
+		spxPkgDoc, err := result.pkgDoc(SpxPkgPath)
+		require.NoError(t, err)
+		assert.Contains(t, spxPkgDoc.Types["SpriteImpl"].Methods["Hide"], "Hide hides the sprite.")
+	})
+
+	t.Run("Settings", func(t *testing.T) {
+		s := newServer("")
+		settings, err := parseSettings(map[string]any{"spxVersion": "v2.0.0-alpha.1"})
+		require.NoError(t, err)
+		s.setSettings(settings)
+
+		result, err := s.compile()
+		require.NoError(t, err)
+		assert.Equal(t, "v2.0.0-alpha.1", result.spxVersion)
+		assert.True(t, hideDeprecated(result))
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		s := newServer("")
+
+		result, err := s.compile()
+		require.NoError(t, err)
+		assert.Empty(t, result.spxVersion)
+		assert.False(t, hideDeprecated(result))
+	})
+}
+
+func TestServerSpxResourceSetCache(t *testing.T) {
+	compileSprite := func(t *testing.T, s *Server) *SpxSpriteResource {
+		result, err := s.compile()
+		require.NoError(t, err)
+		sprite := result.spxResourceSet.Sprite("MyAircraft")
+		require.NotNil(t, sprite)
+		return sprite
+	}
+
+	t.Run("CodeChanged", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		sprite1 := compileSprite(t, s)
+		content, err := vfs.ReadFile(s.getProj(), "main.spx")
+		require.NoError(t, err)
+		s.getProj().PutFile("main.spx", &vfs.MapFile{Content: append(content, "\necho 1\n"...)})
+		sprite2 := compileSprite(t, s)
+		assert.Same(t, sprite1, sprite2)
+	})
+
+	t.Run("NonMetadataAssetChanged", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		sprite1 := compileSprite(t, s)
+		s.getProj().PutFile("assets/sprites/MyAircraft/hero.png", &vfs.MapFile{Content: []byte("png")})
+		sprite2 := compileSprite(t, s)
+		assert.Same(t, sprite1, sprite2)
+	})
+
+	t.Run("MetadataChanged", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		sprite1 := compileSprite(t, s)
+		s.getProj().PutFile("assets/sprites/MyAircraft/index.json", &vfs.MapFile{
+			Content: []byte(`{"costumes":[{"name":"hero","path":"hero.png"},{"name":"hero2","path":"hero2.png"}]}`),
+		})
+		sprite2 := compileSprite(t, s)
+		assert.NotSame(t, sprite1, sprite2)
+		assert.Len(t, sprite2.Costumes, 2)
+	})
+
+	t.Run("DirectoryChanged", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		compileSprite(t, s)
+		s.getProj().PutFile("assets/sounds/boom/index.json", &vfs.MapFile{Content: []byte(`{"path":"boom.wav"}`)})
+		result, err := s.compile()
+		require.NoError(t, err)
+		assert.NotNil(t, result.spxResourceSet.Sound("boom"))
+	})
+
+	t.Run("IgnoredDirectory", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		compileSprite(t, s)
+		s.getProj().PutFile("assets/sounds/boom/index.json", &vfs.MapFile{Content: []byte(`{"path":"boom.wav"}`)})
+		s.getProj().PutFile(".spxignore", &vfs.MapFile{Content: []byte("assets/sounds/boom/\n")})
+		result, err := s.compile()
+		require.NoError(t, err)
+		assert.Nil(t, result.spxResourceSet.Sound("boom"))
+		assert.NotNil(t, result.spxResourceSet.Sound("biu"))
+	})
+}
+
+func TestSpxResourceSetHasAsset(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		result, err := s.compile()
+		require.NoError(t, err)
+		set := result.spxResourceSet
+		assert.True(t, set.HasAsset("backdrop1.png"))
+		assert.True(t, set.HasAsset("sounds/biu/biu.wav"))
+		assert.True(t, set.HasAsset("sprites/MyAircraft/hero.png"))
+		assert.True(t, set.HasAsset("./sprites/Bullet/bullet.png"))
+		assert.False(t, set.HasAsset("sprites/MyAircraft/index.json"))
+		assert.False(t, set.HasAsset("sprites/MyAircraft/other.png"))
+	})
+}
+
+func TestServerAffectsSpxResources(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+		_, err := s.compile()
+		require.NoError(t, err)
+		s.getProj().PutFile("assets/sprites/MyAircraft/unused.png", &vfs.MapFile{Content: []byte("png")})
+
+		for _, tt := range []struct {
+			path string
+			want bool
+		}{
+			{"main.spx", false},
+			{"assets/index.json", true},
+			{"assets/sprites/MyAircraft/index.json", true},
+			{"assets/sprites/MyAircraft/hero.png", true},
+			{"assets/sprites/MyAircraft/unused.png", false},
+			{"assets/sprites/MyAircraft/new.png", true},
+			{"other/new.png", false},
+			{".spxignore", true},
+			{".gitignore", true},
+		} {
+			got := s.affectsSpxResources(s.getProj(), []string{tt.path})
+			assert.Equal(t, tt.want, got, tt.path)
+		}
+	})
+
+	t.Run("CustomRootDir", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		// The default root directory is assumed before the first compilation.
+		assert.True(t, s.affectsSpxResources(s.getProj(), []string{"assets/index.json"}))
+
+		s.lastSpxResourceSet.Store(&spxResourceSetCache{rootDir: "res"})
+		assert.False(t, s.affectsSpxResources(s.getProj(), []string{"assets/index.json"}))
+		assert.True(t, s.affectsSpxResources(s.getProj(), []string{"res/index.json"}))
+		assert.True(t, s.affectsSpxResources(s.getProj(), []string{"res/sounds/boom/boom.wav"}))
+	})
+}
+
+func TestServerInspectForSpxResourceRootDir(t *testing.T) {
+	compile := func(t *testing.T, m map[string][]byte) (*Server, *compileResult) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		result, err := s.compile()
+		require.NoError(t, err)
+		return s, result
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		_, result := compile(t, map[string][]byte{
+			"main.spx": []byte(`
+run "res", {Title: "My Game"}
+`),
+			"res/index.json": []byte(`{}`),
+		})
+		assert.Equal(t, "res", result.spxResourceRootDir)
+		assert.Empty(t, result.diagnostics["file:///main.spx"])
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		_, result := compile(t, map[string][]byte{
+			"main.spx":          []byte(`echo "Hello"`),
+			"assets/index.json": []byte(`{}`),
+		})
+		assert.Equal(t, "assets", result.spxResourceRootDir)
+	})
+
+	t.Run("ConstantInOtherFile", func(t *testing.T) {
+		_, result := compile(t, map[string][]byte{
+			"main.spx": []byte(`
+run resourceRoot, {Title: "My Game"}
+`),
+			"MySprite.spx":      []byte(`const resourceRoot = "./res/"`),
+			"res/index.json":    []byte(`{}`),
+			"assets/index.json": []byte(`{}`),
+		})
+		assert.Equal(t, "res", result.spxResourceRootDir)
+	})
+
+	t.Run("MultipleRunCalls", func(t *testing.T) {
+		_, result := compile(t, map[string][]byte{
+			"main.spx": []byte(`
+if true {
+	run "res", {Title: "My Game"}
+} else {
+	run "res2", {Title: "My Game"}
+}
+`),
+			"res/index.json": []byte(`{}`),
+		})
+		assert.Equal(t, "res", result.spxResourceRootDir)
+		assert.Contains(t, result.diagnostics["file:///main.spx"], Diagnostic{
+			Severity:        SeverityWarning,
+			Code:            "spx-resource-root-mismatch",
+			CodeDescription: diagnosticCodeDescription("spx-resource-root-mismatch"),
+			Range: Range{
+				Start: Position{Line: 4, Character: 5},
+				End:   Position{Line: 4, Character: 11},
+			},
+			Message: `resource root directory "res2" differs from "res" of the first run call, which is used instead`,
+		})
+	})
+
+	t.Run("NonStringArg", func(t *testing.T) {
+		_, result := compile(t, map[string][]byte{
+			"main.spx": []byte(`
+run 1, {Title: "My Game"}
+`),
+			"assets/index.json": []byte(`{}`),
+		})
+		assert.Equal(t, "assets", result.spxResourceRootDir)
+		assert.Contains(t, result.diagnostics["file:///main.spx"], Diagnostic{
+			Severity:        SeverityError,
+			Code:            "spx-resource-root-invalid",
+			CodeDescription: diagnosticCodeDescription("spx-resource-root-invalid"),
+			Range: Range{
+				Start: Position{Line: 1, Character: 4},
+				End:   Position{Line: 1, Character: 5},
+			},
+			Message: "first argument of run must be a string literal or constant",
+		})
+	})
+
+	t.Run("SettingsOverride", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json": []byte(`{}`),
+			"res/index.json":    []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, fileMapGetter(m), &MockScheduler{})
+		require.NoError(t, s.didChangeConfiguration(&DidChangeConfigurationParams{
+			Settings: map[string]any{"resourceRootDir": "res"},
+		}))
+		result, err := s.compile()
+		require.NoError(t, err)
+		assert.Equal(t, "res", result.spxResourceRootDir)
+	})
+}
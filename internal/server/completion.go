@@ -2,6 +2,7 @@ package server
 
 import (
 	"cmp"
+	"context"
 	"fmt"
 	"go/types"
 	"path"
@@ -9,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	xgoast "github.com/goplus/xgo/ast"
 	xgoscanner "github.com/goplus/xgo/scanner"
@@ -20,8 +22,8 @@ import (
 )
 
 // See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#textDocument_completion
-func (s *Server) textDocumentCompletion(params *CompletionParams) ([]CompletionItem, error) {
-	result, spxFile, astFile, err := s.compileAndGetASTFileForDocumentURI(params.TextDocument.URI)
+func (s *Server) textDocumentCompletion(ctx context.Context, params *CompletionParams) (*CompletionList, error) {
+	result, spxFile, astFile, err := s.compileAndGetASTFileForDocumentURI(ctx, params.TextDocument.URI)
 	if err != nil {
 		return nil, err
 	}
@@ -45,7 +47,7 @@ func (s *Server) textDocumentCompletion(params *CompletionParams) ([]CompletionI
 	if typeInfo == nil {
 		return nil, nil
 	}
-	ctx := &completionContext{
+	cctx := &completionContext{
 		itemSet:        newCompletionItemSet(),
 		proj:           result.proj,
 		typeInfo:       typeInfo,
@@ -56,14 +58,25 @@ func (s *Server) textDocumentCompletion(params *CompletionParams) ([]CompletionI
 		tokenFile:      xgoutil.NodeTokenFile(result.proj, astFile),
 		pos:            pos,
 		innermostScope: innermostScope,
+		snippetSupport: s.clientCapabilities.TextDocument.Completion.CompletionItem.SnippetSupport,
+		settings:       s.getSettings().Completion,
 	}
-	ctx.analyze()
-	if err := ctx.collect(); err != nil {
+	cctx.analyze()
+	if err := cctx.collect(); err != nil {
 		return nil, fmt.Errorf("failed to collect completion items: %w", err)
 	}
-	return ctx.sortedItems(), nil
+	items, isIncomplete := cctx.cappedItems(cctx.sortedItems())
+	cctx.decorateItems(items)
+	return &CompletionList{
+		IsIncomplete: isIncomplete,
+		Items:        items,
+	}, nil
 }
 
+// maxCompletionItems is the maximum number of completion items returned in a
+// single completion response.
+const maxCompletionItems = 200
+
 // completionKind represents different kinds of completion contexts.
 type completionKind int
 
@@ -96,6 +109,8 @@ type completionContext struct {
 	tokenFile      *xgotoken.File
 	pos            xgotoken.Pos
 	innermostScope *types.Scope
+	snippetSupport bool
+	settings       CompletionSettings
 
 	kind completionKind
 
@@ -110,6 +125,7 @@ type completionContext struct {
 	returnIndex        int
 
 	inStringLit       bool
+	stringLit         *xgoast.BasicLit
 	inSpxEventHandler bool
 }
 
@@ -141,26 +157,45 @@ func (ctx *completionContext) analyze() {
 				// Try to get type from the CompositeLit.Type field.
 				if node.Type != nil {
 					tv, ok = ctx.typeInfo.Types[node.Type]
+				} else if i+1 < len(path) {
+					// Try to infer type from the context, as literals with
+					// errors, like a missing value, may not be type-checked.
+					tv.Type = ctx.compositeLitTypeFromContext(node, path[i+1])
+					ok = tv.Type != nil
 				}
 				if !ok {
 					continue
 				}
 			}
 			typ := xgoutil.DerefType(tv.Type)
-			named, ok := typ.(*types.Named)
-			if !ok {
-				continue
-			}
-			st, ok := named.Underlying().(*types.Struct)
+			st, ok := typ.Underlying().(*types.Struct)
 			if !ok {
 				continue
 			}
+			named, _ := typ.(*types.Named)
 
 			// CompositeLit is more specific than other contexts, so override.
 			ctx.kind = completionKindStructLit
 			ctx.expectedStructType = st
 			ctx.compositeLitType = named
 			ctx.enclosingNode = node
+		case *xgoast.KeyValueExpr:
+			if ctx.kind != completionKindStructLit || ctx.enclosingNode != path[i+1] || ctx.pos <= node.Colon {
+				continue
+			}
+
+			// In the value of a keyed element, complete values of the
+			// field type instead of field names.
+			ctx.kind = completionKindGeneral
+			ctx.expectedTypes = nil
+			if ident, ok := node.Key.(*xgoast.Ident); ok {
+				for field := range ctx.expectedStructType.Fields() {
+					if field.Name() == ident.Name {
+						ctx.expectedTypes = []types.Type{field.Type()}
+						break
+					}
+				}
+			}
 		case *xgoast.AssignStmt:
 			if node.Tok != xgotoken.ASSIGN && node.Tok != xgotoken.DEFINE {
 				continue
@@ -230,6 +265,7 @@ func (ctx *completionContext) analyze() {
 					ctx.kind = completionKindStringLit
 				}
 				ctx.inStringLit = true
+				ctx.stringLit = node
 			}
 		case *xgoast.BlockStmt:
 			ctx.kind = completionKindUnknown
@@ -250,6 +286,57 @@ func (ctx *completionContext) analyze() {
 	ctx.inSpxEventHandler = ctx.result.isInSpxEventHandler(ctx.pos)
 }
 
+// compositeLitTypeFromContext returns the type of the given composite literal
+// without an explicit type inferred from its parent node, which is either a
+// call it is an argument of or a composite literal it is an element of. It
+// returns nil if the type cannot be inferred.
+func (ctx *completionContext) compositeLitTypeFromContext(lit *xgoast.CompositeLit, parent xgoast.Node) types.Type {
+	switch parent := parent.(type) {
+	case *xgoast.CallExpr:
+		argIndex := slices.Index(parent.Args, xgoast.Expr(lit))
+		if argIndex < 0 {
+			return nil
+		}
+		var sigs []*types.Signature
+		if fun := xgoutil.FuncFromCallExpr(ctx.typeInfo, parent); fun != nil {
+			for _, funcOverload := range xgoutil.ExpandXGoOverloadableFunc(fun) {
+				sigs = append(sigs, funcOverload.Type().(*types.Signature))
+			}
+		}
+		if tv, ok := ctx.typeInfo.Types[parent.Fun]; ok {
+			if sig, ok := tv.Type.(*types.Signature); ok {
+				sigs = append(sigs, sig)
+			}
+		}
+		for _, sig := range sigs {
+			var paramType types.Type
+			if argIndex < sig.Params().Len() && !(sig.Variadic() && argIndex == sig.Params().Len()-1) {
+				paramType = sig.Params().At(argIndex).Type()
+			} else if sig.Variadic() && argIndex >= sig.Params().Len()-1 {
+				paramType = sig.Params().At(sig.Params().Len() - 1).Type().(*types.Slice).Elem()
+			}
+			if paramType == nil {
+				continue
+			}
+			if _, ok := xgoutil.DerefType(paramType).Underlying().(*types.Struct); ok {
+				return paramType
+			}
+		}
+	case *xgoast.CompositeLit:
+		tv, ok := ctx.typeInfo.Types[parent]
+		if !ok {
+			return nil
+		}
+		switch typ := xgoutil.DerefType(tv.Type).Underlying().(type) {
+		case *types.Slice:
+			return typ.Elem()
+		case *types.Array:
+			return typ.Elem()
+		}
+	}
+	return nil
+}
+
 // isInComment reports whether the position of the current completion context
 // is inside a comment.
 func (ctx *completionContext) isInComment() bool {
@@ -460,6 +547,12 @@ func (ctx *completionContext) collectGeneral() error {
 	}
 	ctx.itemSet.setExpectedTypes(ctx.expectedTypes)
 
+	// Add parameters of enclosing spx event handler callbacks first so they
+	// are ranked above other local definitions.
+	if ctx.inSpxEventHandler {
+		ctx.collectSpxEventHandlerParams()
+	}
+
 	// Add local definitions from innermost scope and its parents.
 	pkg := ctx.typeInfo.Pkg()
 	for scope := ctx.innermostScope; scope != nil; scope = scope.Parent() {
@@ -507,7 +600,7 @@ func (ctx *completionContext) collectGeneral() error {
 		if err != nil {
 			continue
 		}
-		pkgDoc, err := pkgdata.GetPkgDoc(pkgPath)
+		pkgDoc, err := ctx.result.pkgDoc(pkgPath)
 		if err != nil {
 			continue
 		}
@@ -533,7 +626,7 @@ func (ctx *completionContext) collectGeneral() error {
 	}
 
 	// Add other definitions.
-	ctx.itemSet.addSpxDefs(GetSpxPkgDefinitions()...)
+	ctx.itemSet.addSpxDefs(ctx.result.spxPkgDefinitions()...)
 	ctx.itemSet.addSpxDefs(GetMathPkgSpxDefinitions()...)
 	ctx.itemSet.addSpxDefs(GetBuiltinSpxDefinitions()...)
 	ctx.itemSet.addSpxDefs(GeneralSpxDefinitions...)
@@ -541,9 +634,120 @@ func (ctx *completionContext) collectGeneral() error {
 		ctx.itemSet.addSpxDefs(FileScopeSpxDefinitions...)
 	}
 
+	// Add event handler snippets where a new statement may start.
+	if ctx.snippetSupport && ctx.kind == completionKindGeneral && !ctx.inSpxEventHandler && ctx.isLineStart() {
+		ctx.itemSet.add(spxEventHandlerSnippets...)
+	}
+
 	return nil
 }
 
+// collectSpxEventHandlerParams collects the parameters of the spx event handler
+// callbacks enclosing the position, with their types as details.
+func (ctx *completionContext) collectSpxEventHandlerParams() {
+	path, _ := xgoutil.PathEnclosingInterval(ctx.astFile, ctx.pos-1, ctx.pos)
+	for i, node := range path {
+		lambda, ok := node.(*xgoast.LambdaExpr2)
+		if !ok || len(lambda.Lhs) == 0 || i+1 >= len(path) {
+			continue
+		}
+		callExpr, ok := path[i+1].(*xgoast.CallExpr)
+		if !ok {
+			continue
+		}
+		funcIdent, ok := callExpr.Fun.(*xgoast.Ident)
+		if !ok || !IsSpxEventHandlerFuncName(funcIdent.Name) || !IsInSpxPkg(ctx.typeInfo.ObjectOf(funcIdent)) {
+			continue
+		}
+
+		sig := ctx.spxEventHandlerCallbackSignature(callExpr, lambda)
+		for j, param := range lambda.Lhs {
+			obj := ctx.typeInfo.ObjectOf(param)
+			var paramType types.Type
+			if obj != nil {
+				paramType = obj.Type()
+			} else if sig != nil && j < sig.Params().Len() {
+				paramType = sig.Params().At(j).Type()
+			}
+			if paramType == nil || paramType == types.Typ[types.Invalid] {
+				continue
+			}
+			if ctx.itemSet.isCompatibleWithExpectedTypes != nil && !ctx.itemSet.isCompatibleWithExpectedTypes(paramType) {
+				continue
+			}
+
+			item := CompletionItem{
+				Label:            param.Name,
+				Kind:             VariableCompletion,
+				InsertText:       param.Name,
+				InsertTextFormat: ToPtr(PlainTextTextFormat),
+			}
+			if defs := ctx.result.spxDefinitionsFor(obj, ""); len(defs) == 1 {
+				// Keep the definition from being added again with the
+				// other local definitions.
+				ctx.itemSet.seenSpxDefs[defs[0].ID.String()] = struct{}{}
+				item = defs[0].CompletionItem()
+			}
+			item.Detail = GetSimplifiedTypeString(paramType)
+			item.SortText = "0" + param.Name
+			ctx.itemSet.add(item)
+		}
+	}
+}
+
+// spxEventHandlerCallbackSignature returns the signature of the given callback
+// of the spx event handler call, or nil if it cannot be determined.
+func (ctx *completionContext) spxEventHandlerCallbackSignature(callExpr *xgoast.CallExpr, lambda *xgoast.LambdaExpr2) *types.Signature {
+	if sig, ok := ctx.typeInfo.TypeOf(lambda).(*types.Signature); ok {
+		return sig
+	}
+
+	fun := xgoutil.FuncFromCallExpr(ctx.typeInfo, callExpr)
+	if fun == nil {
+		return nil
+	}
+	argIndex := slices.Index(callExpr.Args, xgoast.Expr(lambda))
+	params := fun.Type().(*types.Signature).Params()
+	if argIndex < 0 || argIndex >= params.Len() {
+		return nil
+	}
+	sig, _ := params.At(argIndex).Type().Underlying().(*types.Signature)
+	return sig
+}
+
+// spxEventHandlerSnippets are the snippet completion items for common spx
+// event handlers, which expand to the handler with a lambda body.
+var spxEventHandlerSnippets = []CompletionItem{
+	{
+		Label:            "onStart",
+		Kind:             SnippetCompletion,
+		Detail:           "onStart => { … }",
+		InsertText:       "onStart => {\n\t$0\n}",
+		InsertTextFormat: ToPtr(SnippetTextFormat),
+	},
+	{
+		Label:            "onClick",
+		Kind:             SnippetCompletion,
+		Detail:           "onClick => { … }",
+		InsertText:       "onClick => {\n\t$0\n}",
+		InsertTextFormat: ToPtr(SnippetTextFormat),
+	},
+	{
+		Label:            "onKey",
+		Kind:             SnippetCompletion,
+		Detail:           "onKey key, => { … }",
+		InsertText:       "onKey ${1:KeySpace}, => {\n\t$0\n}",
+		InsertTextFormat: ToPtr(SnippetTextFormat),
+	},
+	{
+		Label:            "onMsg",
+		Kind:             SnippetCompletion,
+		Detail:           "onMsg msg, => { … }",
+		InsertText:       "onMsg \"${1:message}\", => {\n\t$0\n}",
+		InsertTextFormat: ToPtr(SnippetTextFormat),
+	},
+}
+
 // collectImport collects import completions.
 func (ctx *completionContext) collectImport() error {
 	pkgs, err := pkgdata.ListPkgs()
@@ -551,7 +755,7 @@ func (ctx *completionContext) collectImport() error {
 		return fmt.Errorf("failed to list packages: %w", err)
 	}
 	for _, pkgPath := range pkgs {
-		pkgDoc, err := pkgdata.GetPkgDoc(pkgPath)
+		pkgDoc, err := ctx.result.pkgDoc(pkgPath)
 		if err != nil {
 			continue
 		}
@@ -627,7 +831,7 @@ func (ctx *completionContext) collectPackageMembers(pkg *types.Package) error {
 	} else {
 		pkgPath := xgoutil.PkgPath(pkg)
 		var err error
-		pkgDoc, err = pkgdata.GetPkgDoc(pkgPath)
+		pkgDoc, err = ctx.result.pkgDoc(pkgPath)
 		if err != nil {
 			return nil
 		}
@@ -774,22 +978,64 @@ func (ctx *completionContext) collectTypeSpecific(typ types.Type) error {
 			spxResourceIDs = append(spxResourceIDs, SpxWidgetResourceID{spxWidgetName})
 		}
 	}
+	stringLitContentRange, hasStringLitContentRange := ctx.stringLitContentRange()
+	seenNames := make(map[string]struct{}, len(spxResourceIDs))
 	for _, spxResourceID := range spxResourceIDs {
 		name := spxResourceID.Name()
+		if _, ok := seenNames[name]; ok {
+			continue
+		}
+		seenNames[name] = struct{}{}
+
 		if !ctx.inStringLit {
 			name = strconv.Quote(name)
 		}
-		ctx.itemSet.add(CompletionItem{
+		item := CompletionItem{
 			Label:            name,
 			Kind:             TextCompletion,
 			Documentation:    &Or_CompletionItem_documentation{Value: MarkupContent{Kind: Markdown, Value: spxResourceID.URI().HTML()}},
 			InsertText:       name,
 			InsertTextFormat: ToPtr(PlainTextTextFormat),
-		})
+		}
+		if hasStringLitContentRange {
+			// Replace the whole string content, so names containing
+			// non-identifier characters like "roll-in" are completed as a
+			// whole.
+			item.FilterText = name
+			item.TextEdit = &Or_CompletionItem_textEdit{Value: TextEdit{
+				Range:   stringLitContentRange,
+				NewText: name,
+			}}
+		}
+		ctx.itemSet.add(item)
 	}
 	return nil
 }
 
+// stringLitContentRange returns the range of the content of the string literal
+// enclosing the position of the current completion context, excluding the
+// quotes. It reports false if the position is not inside a single-line string
+// literal.
+func (ctx *completionContext) stringLitContentRange() (Range, bool) {
+	if !ctx.inStringLit || ctx.stringLit == nil {
+		return Range{}, false
+	}
+	value := ctx.stringLit.Value
+	if len(value) == 0 {
+		return Range{}, false
+	}
+
+	rng := RangeForNode(ctx.proj, ctx.stringLit)
+	if rng.Start.Line != rng.End.Line {
+		return Range{}, false
+	}
+	rng.Start.Character++
+	if quote := value[0]; len(value) >= 2 && value[len(value)-1] == quote {
+		rng.End.Character--
+	}
+	return rng, true
+}
+
 // getSpxSpriteResource returns a [SpxSpriteResource] for the current context.
 // It returns nil if no [SpxSpriteResource] can be inferred.
 func (ctx *completionContext) getSpxSpriteResource() *SpxSpriteResource {
@@ -817,7 +1063,7 @@ func (ctx *completionContext) getSpxSpriteResource() *SpxSpriteResource {
 	if obj == nil {
 		return nil
 	}
-	named, ok := obj.Type().(*types.Named)
+	named, ok := xgoutil.DerefType(obj.Type()).(*types.Named)
 	if !ok {
 		return nil
 	}
@@ -826,29 +1072,34 @@ func (ctx *completionContext) getSpxSpriteResource() *SpxSpriteResource {
 		return ctx.result.spxResourceSet.sprites[ident.Name]
 	}
 	if ctx.result.hasSpxSpriteType(named) {
-		return ctx.result.spxResourceSet.sprites[obj.Name()]
+		// Sprite types are named after their sprite resources, which also
+		// covers receivers like "this" in sprite files.
+		return ctx.result.spxResourceSet.sprites[named.Obj().Name()]
 	}
 	return nil
 }
 
 // collectStructLit collects struct literal completions.
 func (ctx *completionContext) collectStructLit() error {
-	if ctx.expectedStructType == nil || ctx.compositeLitType == nil {
+	if ctx.expectedStructType == nil {
 		return nil
 	}
 
-	selectorTypeName := ctx.compositeLitType.Obj().Name()
-	if IsInSpxPkg(ctx.compositeLitType.Obj()) && selectorTypeName == "SpriteImpl" {
-		selectorTypeName = "Sprite"
+	var selectorTypeName string
+	if ctx.compositeLitType != nil {
+		selectorTypeName = ctx.compositeLitType.Obj().Name()
+		if IsInSpxPkg(ctx.compositeLitType.Obj()) && selectorTypeName == "SpriteImpl" {
+			selectorTypeName = "Sprite"
+		}
 	}
 
 	seenFields := make(map[string]struct{})
 
-	// Collect already used fields.
+	// Collect already used fields, except the one being edited.
 	if composite, ok := ctx.enclosingNode.(*xgoast.CompositeLit); ok {
 		for _, elem := range composite.Elts {
 			if kv, ok := elem.(*xgoast.KeyValueExpr); ok {
-				if ident, ok := kv.Key.(*xgoast.Ident); ok {
+				if ident, ok := kv.Key.(*xgoast.Ident); ok && (ctx.pos < ident.Pos() || ctx.pos > ident.End()) {
 					seenFields[ident.Name] = struct{}{}
 				}
 			}
@@ -865,7 +1116,7 @@ func (ctx *completionContext) collectStructLit() error {
 		}
 
 		spxDef := ctx.result.spxDefinitionForField(field, selectorTypeName)
-		spxDef.CompletionItemInsertText = field.Name() + ": ${1:}"
+		spxDef.CompletionItemInsertText = field.Name() + ": " + structLitFieldValueSnippet(field.Type())
 		spxDef.CompletionItemInsertTextFormat = SnippetTextFormat
 		ctx.itemSet.addSpxDefs(spxDef)
 	}
@@ -873,6 +1124,15 @@ func (ctx *completionContext) collectStructLit() error {
 	return nil
 }
 
+// structLitFieldValueSnippet returns the snippet of the value of a struct
+// literal field of the given type. Values of string types are quoted.
+func structLitFieldValueSnippet(typ types.Type) string {
+	if basic, ok := typ.Underlying().(*types.Basic); ok && basic.Info()&types.IsString != 0 {
+		return `"${1:}"`
+	}
+	return "${1:}"
+}
+
 // collectSwitchCase collects switch/case completions.
 func (ctx *completionContext) collectSwitchCase() error {
 	if ctx.switchTag == nil {
@@ -897,14 +1157,18 @@ func (ctx *completionContext) collectSwitchCase() error {
 		return nil
 	}
 
-	var pkgDoc *pkgdoc.PkgDoc
-	if xgoutil.IsMainPkg(pkg) {
-		pkgDoc, _ = ctx.proj.PkgDoc()
-	} else {
-		pkgPath := xgoutil.PkgPath(pkg)
-		pkgDoc, _ = pkgdata.GetPkgDoc(pkgPath)
+	if !xgoutil.IsMainPkg(pkg) {
+		pkgDoc, _ := ctx.result.pkgDoc(xgoutil.PkgPath(pkg))
+		for _, entry := range completionIndexFor(pkg, pkgDoc).ConstsOf(named) {
+			c := entry.Object.(*types.Const)
+			if types.Identical(c.Type(), tv.Type) {
+				ctx.itemSet.addSpxDefs(GetSpxDefinitionForConst(c, pkgDoc))
+			}
+		}
+		return nil
 	}
 
+	pkgDoc, _ := ctx.proj.PkgDoc()
 	scope := pkg.Scope()
 	for _, name := range scope.Names() {
 		obj := scope.Lookup(name)
@@ -951,23 +1215,129 @@ var completionItemKindPriority = map[CompletionItemKind]int{
 	InterfaceCompletion: 7,
 	ModuleCompletion:    8,
 	KeywordCompletion:   9,
+	SnippetCompletion:   10,
 }
 
-// sortedItems returns the sorted items.
+// sortedItems returns the sorted items. Items with a preset sort text, such as
+// parameters of enclosing spx event handler callbacks, are ranked first.
 func (ctx *completionContext) sortedItems() []CompletionItem {
 	slices.SortStableFunc(ctx.itemSet.items, func(a, b CompletionItem) int {
+		if a.SortText != b.SortText {
+			switch {
+			case a.SortText == "":
+				return 1
+			case b.SortText == "":
+				return -1
+			}
+			return cmp.Compare(a.SortText, b.SortText)
+		}
 		if p1, p2 := completionItemKindPriority[a.Kind], completionItemKindPriority[b.Kind]; p1 != p2 {
 			return p1 - p2
 		}
+		if !ctx.settings.CaseSensitiveSort {
+			if c := cmp.Compare(strings.ToLower(a.Label), strings.ToLower(b.Label)); c != 0 {
+				return c
+			}
+		}
 		return cmp.Compare(a.Label, b.Label)
 	})
 	return ctx.itemSet.items
 }
 
+// decorateItems populates the fields of the given sorted items that depend on
+// their order and on the completion settings. The sort text of each item is
+// set to its rank so that clients keep the order of the items.
+func (ctx *completionContext) decorateItems(items []CompletionItem) {
+	width := len(strconv.Itoa(len(items)))
+	for i := range items {
+		items[i].SortText = fmt.Sprintf("%0*d", width, i)
+		items[i].CommitCharacters = ctx.settings.commitCharacters(items[i].Kind)
+	}
+
+	if ctx.settings.Preselect && len(items) > 0 {
+		best := 0
+		if prefix := strings.ToLower(ctx.identPrefix()); prefix != "" {
+			best = slices.IndexFunc(items, func(item CompletionItem) bool {
+				return strings.HasPrefix(strings.ToLower(item.Label), prefix)
+			})
+		}
+		if best >= 0 {
+			items[best].Preselect = true
+		}
+	}
+}
+
+// cappedItems caps the given sorted items at [maxCompletionItems], not counting
+// keywords and snippets. Items not matching the identifier prefix before the
+// position are dropped first. If any item is dropped, it reports the result as
+// incomplete so that the client re-queries as the user keeps typing instead of
+// filtering a stale list.
+func (ctx *completionContext) cappedItems(items []CompletionItem) ([]CompletionItem, bool) {
+	if len(items) <= maxCompletionItems {
+		return items, false
+	}
+
+	if prefix := ctx.identPrefix(); prefix != "" {
+		items = slices.DeleteFunc(items, func(item CompletionItem) bool {
+			filterText := item.FilterText
+			if filterText == "" {
+				filterText = item.Label
+			}
+			return !isFuzzyMatch(filterText, prefix)
+		})
+	}
+	if len(items) > maxCompletionItems {
+		// Keep keywords and snippets, which are few and hand-picked but
+		// sorted last.
+		capped := items[:maxCompletionItems:maxCompletionItems]
+		for _, item := range items[maxCompletionItems:] {
+			if item.Kind == KeywordCompletion || item.Kind == SnippetCompletion {
+				capped = append(capped, item)
+			}
+		}
+		items = capped
+	}
+	return items, true
+}
+
+// identPrefix returns the part of the identifier before the position.
+func (ctx *completionContext) identPrefix() string {
+	fileBase := xgotoken.Pos(ctx.tokenFile.Base())
+	relPos := int(ctx.pos - fileBase)
+	if relPos < 0 || relPos > len(ctx.astFile.Code) {
+		return ""
+	}
+
+	start := relPos
+	for start > 0 {
+		r, size := utf8.DecodeLastRune(ctx.astFile.Code[:start])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		start -= size
+	}
+	return string(ctx.astFile.Code[start:relPos])
+}
+
+// isFuzzyMatch reports whether all characters of pattern appear in s in order,
+// ignoring case.
+func isFuzzyMatch(s, pattern string) bool {
+	s = strings.ToLower(s)
+	for _, r := range strings.ToLower(pattern) {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+utf8.RuneLen(r):]
+	}
+	return true
+}
+
 // completionItemSet is a set of completion items.
 type completionItemSet struct {
 	items                         []CompletionItem
 	seenSpxDefs                   map[string]struct{}
+	funcOverloads                 map[string]*completionFuncOverloads
 	supportedKinds                map[CompletionItemKind]struct{}
 	isCompatibleWithExpectedTypes func(typ types.Type) bool
 }
@@ -975,11 +1345,20 @@ type completionItemSet struct {
 // newCompletionItemSet creates a new [completionItemSet].
 func newCompletionItemSet() *completionItemSet {
 	return &completionItemSet{
-		items:       []CompletionItem{},
-		seenSpxDefs: make(map[string]struct{}),
+		items:         []CompletionItem{},
+		seenSpxDefs:   make(map[string]struct{}),
+		funcOverloads: make(map[string]*completionFuncOverloads),
 	}
 }
 
+// completionFuncOverloads records the overloads of an XGo overloadable function
+// that have been collapsed into a single completion item.
+type completionFuncOverloads struct {
+	itemIndex int
+	overview  string
+	count     int
+}
+
 // setSupportedKinds sets the supported kinds for the completion items.
 func (s *completionItemSet) setSupportedKinds(kinds ...CompletionItemKind) {
 	if len(kinds) == 0 {
@@ -1033,6 +1412,36 @@ func (s *completionItemSet) addSpxDefs(spxDefs ...SpxDefinition) {
 		}
 		s.seenSpxDefs[spxDefIDKey] = struct{}{}
 
+		if spxDef.ID.OverloadID == nil {
+			s.add(spxDef.CompletionItem())
+			continue
+		}
+
+		// Collapse overloads of the same function into a single item, and
+		// let signature help present the variants once it is accepted.
+		funcKey := SpxDefinitionIdentifier{Package: spxDef.ID.Package, Name: spxDef.ID.Name}.String()
+		if overloads, ok := s.funcOverloads[funcKey]; ok {
+			overloads.count++
+			item := &s.items[overloads.itemIndex]
+			noun := "overloads"
+			if overloads.count == 2 {
+				noun = "overload"
+			}
+			item.Detail = fmt.Sprintf("%s (+%d %s)", overloads.overview, overloads.count-1, noun)
+			item.Command = &Command{
+				Title:   "Trigger Parameter Hints",
+				Command: "editor.action.triggerParameterHints",
+			}
+			continue
+		}
+		itemCount := len(s.items)
 		s.add(spxDef.CompletionItem())
+		if len(s.items) > itemCount {
+			s.funcOverloads[funcKey] = &completionFuncOverloads{
+				itemIndex: itemCount,
+				overview:  spxDef.Overview,
+				count:     1,
+			}
+		}
 	}
 }
@@ -1,7 +1,11 @@
 package server
 
 import (
+	"cmp"
+	"context"
+	"regexp"
 	"slices"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -29,13 +33,15 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		emptyLineItems, err := s.textDocumentCompletion(&CompletionParams{
+		emptyLineList, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 4, Character: 0},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, emptyLineList)
+		emptyLineItems := emptyLineList.Items
 		require.NotNil(t, emptyLineItems)
 		assert.NotEmpty(t, emptyLineItems)
 		assert.True(t, containsCompletionItemLabel(emptyLineItems, "println"))
@@ -44,7 +50,7 @@ onStart => {
 			Name:    ToPtr("MySprite"),
 		}))
 
-		assert.Contains(t, emptyLineItems, SpxDefinition{
+		assert.Contains(t, withoutCompletionItemSortText(emptyLineItems), SpxDefinition{
 			ID: SpxDefinitionIdentifier{
 				Package: ToPtr(SpxPkgPath),
 				Name:    ToPtr("Game.getWidget"),
@@ -58,13 +64,15 @@ onStart => {
 			CompletionItemInsertTextFormat: PlainTextTextFormat,
 		}.CompletionItem())
 
-		mySpriteDotItems, err := s.textDocumentCompletion(&CompletionParams{
+		mySpriteDotList, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 5, Character: 9},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, mySpriteDotList)
+		mySpriteDotItems := mySpriteDotList.Items
 		require.NotNil(t, mySpriteDotItems)
 		assert.NotEmpty(t, mySpriteDotItems)
 		assert.False(t, containsCompletionItemLabel(mySpriteDotItems, "println"))
@@ -78,7 +86,7 @@ onStart => {
 			Name:       ToPtr("Sprite.turn"),
 			OverloadID: ToPtr("0"),
 		}))
-		assert.True(t, containsCompletionSpxDefinitionID(mySpriteDotItems, SpxDefinitionIdentifier{
+		assert.False(t, containsCompletionSpxDefinitionID(mySpriteDotItems, SpxDefinitionIdentifier{
 			Package:    ToPtr(SpxPkgPath),
 			Name:       ToPtr("Sprite.turn"),
 			OverloadID: ToPtr("1"),
@@ -88,7 +96,7 @@ onStart => {
 			Name:       ToPtr("Sprite.clone"),
 			OverloadID: ToPtr("0"),
 		}))
-		assert.True(t, containsCompletionSpxDefinitionID(mySpriteDotItems, SpxDefinitionIdentifier{
+		assert.False(t, containsCompletionSpxDefinitionID(mySpriteDotItems, SpxDefinitionIdentifier{
 			Package:    ToPtr(SpxPkgPath),
 			Name:       ToPtr("Sprite.clone"),
 			OverloadID: ToPtr("1"),
@@ -106,13 +114,15 @@ run "assets", {Title: "My Game"}
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 1},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.False(t, containsCompletionSpxDefinitionID(items, SpxDefinitionIdentifier{
@@ -125,6 +135,252 @@ run "assets", {Title: "My Game"}
 		}))
 	})
 
+	t.Run("SpxEventHandlerParams", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+onMsg (msg, data) => {
+	
+}
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 2, Character: 1},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
+
+		var msgItems []CompletionItem
+		for _, item := range items {
+			if item.Label == "msg" {
+				msgItems = append(msgItems, item)
+			}
+		}
+		require.Len(t, msgItems, 1)
+		assert.Equal(t, VariableCompletion, msgItems[0].Kind)
+		assert.Equal(t, "string", msgItems[0].Detail)
+		assert.True(t, slices.ContainsFunc(items, func(item CompletionItem) bool {
+			return item.Label == "data" && item.Detail == "any"
+		}))
+		require.GreaterOrEqual(t, len(items), 2)
+		assert.Equal(t, "data", items[0].Label)
+		assert.Equal(t, "msg", items[1].Label)
+	})
+
+	t.Run("SpxEventHandlerSnippets", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	MySprite MySprite
+)
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx": []byte(`
+
+onStart => {
+
+}
+`),
+			"assets/index.json":                  []byte(`{}`),
+			"assets/sprites/MySprite/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		s.clientCapabilities.TextDocument.Completion.CompletionItem.SnippetSupport = true
+
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
+				Position:     Position{Line: 1, Character: 0},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
+		for _, snippet := range spxEventHandlerSnippets {
+			assert.Contains(t, withoutCompletionItemSortText(items), snippet)
+		}
+
+		inHandlerList, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
+				Position:     Position{Line: 3, Character: 1},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, inHandlerList)
+		inHandlerItems := inHandlerList.Items
+		for _, item := range inHandlerItems {
+			assert.NotEqual(t, SnippetCompletion, item.Kind, item.Label)
+		}
+
+		s.clientCapabilities.TextDocument.Completion.CompletionItem.SnippetSupport = false
+		noSnippetList, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
+				Position:     Position{Line: 1, Character: 0},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, noSnippetList)
+		noSnippetItems := noSnippetList.Items
+		for _, item := range noSnippetItems {
+			assert.NotEqual(t, SnippetCompletion, item.Kind, item.Label)
+		}
+	})
+
+	t.Run("SpxEventHandlerSnippetsCompile", func(t *testing.T) {
+		placeholderRE := regexp.MustCompile(`\$\{\d+:([^}]*)\}|\$\d+`)
+		var code strings.Builder
+		for _, snippet := range spxEventHandlerSnippets {
+			code.WriteString(placeholderRE.ReplaceAllString(snippet.InsertText, "$1"))
+			code.WriteString("\n")
+		}
+		m := map[string][]byte{
+			"main.spx":          []byte(code.String() + `run "assets", {Title: "My Game"}` + "\n"),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		report, err := s.textDocumentDiagnostic(context.Background(), &DocumentDiagnosticParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		})
+		require.NoError(t, err)
+		for _, diag := range report.Value.(RelatedFullDocumentDiagnosticReport).Items {
+			assert.NotEqual(t, SeverityError, diag.Severity, diag.Message)
+		}
+	})
+
+	t.Run("IncompleteList", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+onStart => {
+	
+	pla
+}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 2, Character: 1},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, list)
+		assert.True(t, list.IsIncomplete)
+		assert.LessOrEqual(t, len(slices.DeleteFunc(slices.Clone(list.Items), func(item CompletionItem) bool {
+			return item.Kind == KeywordCompletion || item.Kind == SnippetCompletion
+		})), maxCompletionItems)
+		assert.True(t, containsCompletionItemLabel(list.Items, "for"))
+
+		prefixList, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 3, Character: 4},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, prefixList)
+		assert.True(t, prefixList.IsIncomplete)
+		assert.True(t, containsCompletionItemLabel(prefixList.Items, "play"))
+		for _, item := range prefixList.Items {
+			assert.True(t, isFuzzyMatch(item.Label, "pla"), item.Label)
+		}
+	})
+
+	t.Run("Settings", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	apple  int
+	Banana int
+	cherry int
+)
+
+onStart => {
+	
+	ch
+}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		params := &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 8, Character: 1},
+			},
+		}
+		prefixParams := &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 9, Character: 3},
+			},
+		}
+		varLabels := func(items []CompletionItem) []string {
+			var labels []string
+			for _, item := range items {
+				if slices.Contains([]string{"apple", "Banana", "cherry"}, item.Label) {
+					labels = append(labels, item.Label)
+				}
+			}
+			return labels
+		}
+
+		list, err := s.textDocumentCompletion(context.Background(), params)
+		require.NoError(t, err)
+		require.NotNil(t, list)
+		assert.Equal(t, []string{"apple", "Banana", "cherry"}, varLabels(list.Items))
+		assert.True(t, slices.IsSortedFunc(list.Items, func(a, b CompletionItem) int {
+			return cmp.Compare(a.SortText, b.SortText)
+		}))
+		for _, item := range list.Items {
+			assert.Nil(t, item.CommitCharacters)
+			assert.False(t, item.Preselect)
+		}
+
+		settings, err := parseSettings(map[string]any{
+			"completion": map[string]any{
+				"commitCharacters":  map[string]any{"variable": []string{"."}},
+				"caseSensitiveSort": true,
+				"preselect":         true,
+			},
+		})
+		require.NoError(t, err)
+		s.setSettings(settings)
+
+		list, err = s.textDocumentCompletion(context.Background(), params)
+		require.NoError(t, err)
+		require.NotNil(t, list)
+		assert.Equal(t, []string{"Banana", "apple", "cherry"}, varLabels(list.Items))
+
+		list, err = s.textDocumentCompletion(context.Background(), prefixParams)
+		require.NoError(t, err)
+		require.NotNil(t, list)
+		var preselected []string
+		for _, item := range list.Items {
+			if item.Kind == VariableCompletion {
+				assert.Equal(t, []string{"."}, item.CommitCharacters)
+			} else {
+				assert.Nil(t, item.CommitCharacters)
+			}
+			if item.Preselect {
+				preselected = append(preselected, item.Label)
+			}
+		}
+		assert.Equal(t, []string{"cherry"}, preselected)
+	})
+
 	t.Run("InStringLit", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.spx": []byte(`
@@ -133,13 +389,15 @@ run "a
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 1, Character: 6},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.Empty(t, items)
 	})
@@ -153,13 +411,15 @@ run "assets", {Title: "My Game"}
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 1, Character: 11},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.Empty(t, items)
 	})
@@ -172,13 +432,15 @@ run "a
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 1, Character: 6},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.Empty(t, items)
 	})
@@ -191,13 +453,15 @@ import "f
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 1, Character: 9},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "fmt"))
@@ -212,13 +476,15 @@ import (
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 3},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "fmt"))
@@ -233,13 +499,15 @@ fmt.
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 4},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "println"))
@@ -257,34 +525,40 @@ run "assets", {Title: "My Game"}
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items1, err := s.textDocumentCompletion(&CompletionParams{
+		list1, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 1, Character: 1},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list1)
+		items1 := list1.Items
 		require.NotNil(t, items1)
 		assert.NotEmpty(t, items1)
 		assert.True(t, containsCompletionItemLabel(items1, "len"))
 
-		items2, err := s.textDocumentCompletion(&CompletionParams{
+		list2, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 12},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list2)
+		items2 := list2.Items
 		require.NotNil(t, items2)
 		assert.Empty(t, items2)
 
-		items3, err := s.textDocumentCompletion(&CompletionParams{
+		list3, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 3, Character: 1},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list3)
+		items3 := list3.Items
 		require.NotNil(t, items3)
 		assert.NotEmpty(t, items3)
 		assert.True(t, containsCompletionItemLabel(items3, "len"))
@@ -306,13 +580,15 @@ run "assets", {Title: "My Game"}
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 3, Character: 8},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "int"))
@@ -338,13 +614,15 @@ run "assets", {Title: "My Game"}
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 22},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "MySprite"))
@@ -361,13 +639,15 @@ run "assets", {Title: "My Game"}
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 1, Character: 7},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "recording"))
@@ -388,19 +668,56 @@ run "assets", {Title: "My Game"}
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 5, Character: 6},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "recording"))
 		assert.True(t, containsCompletionItemLabel(items, `"recording"`))
 	})
 
+	t.Run("CollapsedFuncOverloads", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+onStart => {
+	pl
+}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 2, Character: 3},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
+		require.NotNil(t, items)
+
+		var playItems []CompletionItem
+		for _, item := range items {
+			if item.Label == "play" {
+				playItems = append(playItems, item)
+			}
+		}
+		require.Len(t, playItems, 1)
+		assert.Equal(t, FunctionCompletion, playItems[0].Kind)
+		assert.Regexp(t, `^func play\(.*\) \(\+\d+ overloads\)$`, playItems[0].Detail)
+		require.NotNil(t, playItems[0].Command)
+		assert.Equal(t, "editor.action.triggerParameterHints", playItems[0].Command.Command)
+	})
+
 	t.Run("WithImplicitSpxSpriteResource", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.spx": []byte(`
@@ -416,13 +733,15 @@ onClick => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
 				Position:     Position{Line: 2, Character: 14},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "costume"))
@@ -445,13 +764,15 @@ run "assets", {Title: "My Game"}
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 5, Character: 22},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "costume"))
@@ -479,18 +800,122 @@ onClick => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///Sprite1.spx"},
 				Position:     Position{Line: 2, Character: 22},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "Sprite2Costume"))
 	})
 
+	t.Run("SpxResourceStringLitTextEdit", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+play "roll"
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json":                 []byte(`{}`),
+			"assets/sounds/roll-in/index.json":  []byte(`{}`),
+			"assets/sounds/roll-out/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 1, Character: 8},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
+		idx := slices.IndexFunc(items, func(item CompletionItem) bool {
+			return item.Label == "roll-in"
+		})
+		require.GreaterOrEqual(t, idx, 0)
+		assert.Equal(t, "roll-in", items[idx].FilterText)
+		assert.Equal(t, &Or_CompletionItem_textEdit{Value: TextEdit{
+			Range: Range{
+				Start: Position{Line: 1, Character: 6},
+				End:   Position{Line: 1, Character: 10},
+			},
+			NewText: "roll-in",
+		}}, items[idx].TextEdit)
+		assert.True(t, containsCompletionItemLabel(items, "roll-out"))
+	})
+
+	t.Run("WithThisSpxSpriteResource", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	Sprite1 Sprite1
+	Sprite2 Sprite2
+)
+run "assets", {Title: "My Game"}
+`),
+			"Sprite1.spx": []byte(`
+onClick => {
+	this.setCostume "c"
+}
+`),
+			"Sprite2.spx": []byte(`
+`),
+			"assets/index.json":                 []byte(`{}`),
+			"assets/sprites/Sprite1/index.json": []byte(`{"costumes":[{"name":"Sprite1Costume"}]}`),
+			"assets/sprites/Sprite2/index.json": []byte(`{"costumes":[{"name":"Sprite2Costume"}]}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///Sprite1.spx"},
+				Position:     Position{Line: 2, Character: 19},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
+		assert.True(t, containsCompletionItemLabel(items, "Sprite1Costume"))
+		assert.False(t, containsCompletionItemLabel(items, "Sprite2Costume"))
+	})
+
+	t.Run("SpxSpriteAnimationResourceStringLit", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	Sprite1 Sprite1
+	Sprite2 Sprite2
+)
+Sprite2.animate "a"
+run "assets", {Title: "My Game"}
+`),
+			"Sprite1.spx":                       []byte(``),
+			"Sprite2.spx":                       []byte(``),
+			"assets/index.json":                 []byte(`{}`),
+			"assets/sprites/Sprite1/index.json": []byte(`{"fAnimations":{"anim1":{}}}`),
+			"assets/sprites/Sprite2/index.json": []byte(`{"fAnimations":{"anim2":{}}}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 5, Character: 18},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
+		assert.True(t, containsCompletionItemLabel(items, "anim2"))
+		assert.False(t, containsCompletionItemLabel(items, "anim1"))
+	})
+
 	t.Run("AtLineStartWithAnIdentifier", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.spx": []byte(`
@@ -501,13 +926,15 @@ onClick => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 3},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "println"))
@@ -530,24 +957,28 @@ onClick => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items1, err := s.textDocumentCompletion(&CompletionParams{
+		list1, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 4, Character: 14},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list1)
+		items1 := list1.Items
 		require.NotNil(t, items1)
 		assert.NotEmpty(t, items1)
 		assert.True(t, containsCompletionItemLabel(items1, "setCostume"))
 
-		items2, err := s.textDocumentCompletion(&CompletionParams{
+		list2, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
 				Position:     Position{Line: 2, Character: 15},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list2)
+		items2 := list2.Items
 		require.NotNil(t, items2)
 		assert.NotEmpty(t, items2)
 		assert.True(t, containsCompletionItemLabel(items2, "setCostume"))
@@ -570,24 +1001,28 @@ onClick => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items1, err := s.textDocumentCompletion(&CompletionParams{
+		list1, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 9},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list1)
+		items1 := list1.Items
 		require.NotNil(t, items1)
 		assert.NotEmpty(t, items1)
 		assert.True(t, containsCompletionItemLabel(items1, "int128"))
 
-		items2, err := s.textDocumentCompletion(&CompletionParams{
+		list2, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
 				Position:     Position{Line: 2, Character: 3},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list2)
+		items2 := list2.Items
 		require.NotNil(t, items2)
 		assert.NotEmpty(t, items2)
 		assert.True(t, containsCompletionItemLabel(items2, "echo"))
@@ -611,13 +1046,15 @@ onStart => {}
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 10, Character: 3},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionSpxDefinitionID(items, SpxDefinitionIdentifier{
@@ -646,13 +1083,15 @@ onStart => {}
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 8, Character: 3},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionSpxDefinitionID(items, SpxDefinitionIdentifier{
@@ -680,13 +1119,15 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 7, Character: 12},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, slices.ContainsFunc(items, func(item CompletionItem) bool {
@@ -721,13 +1162,15 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 4, Character: 17},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, slices.ContainsFunc(items, func(item CompletionItem) bool {
@@ -750,13 +1193,15 @@ onStar => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 1, Character: 6},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "onStart"))
@@ -774,13 +1219,15 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 3, Character: 6},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "abc"))
@@ -803,13 +1250,15 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 9, Character: 10},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "error"))
@@ -825,15 +1274,14 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 9, Character: 10},
 			},
 		})
 		require.NoError(t, err)
-		require.Nil(t, items)
-		assert.Empty(t, items)
+		require.Nil(t, list)
 	})
 
 	t.Run("MathPackage", func(t *testing.T) {
@@ -846,13 +1294,15 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 8},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "abs"))
@@ -872,13 +1322,15 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 6, Character: 9},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "MyStruct"))
@@ -900,18 +1352,165 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 7, Character: 4},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "Foobar"))
 	})
 
+	t.Run("StructLitFieldNameBeingEdited", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+type MyStruct struct {
+	Foobar int
+	Baz    int
+}
+
+onStart => {
+	ms := MyStruct{Fo: 1, Baz: 2}
+}
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 7, Character: 18},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
+		require.NotNil(t, items)
+		assert.True(t, containsCompletionItemLabel(items, "Foobar"))
+		assert.False(t, containsCompletionItemLabel(items, "Baz"))
+	})
+
+	t.Run("StructLitFieldValue", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+type MyStruct struct {
+	Foobar int
+}
+
+onStart => {
+	n := 1
+	ms := MyStruct{Foobar: n}
+}
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 7, Character: 25},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
+		require.NotNil(t, items)
+		assert.True(t, containsCompletionItemLabel(items, "n"))
+		assert.False(t, containsCompletionItemLabel(items, "Foobar"))
+	})
+
+	t.Run("AnonymousStructLitField", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+onStart => {
+	p := struct {
+		X int
+		Y int
+	}{X: 1, }
+}
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 5, Character: 9},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
+		require.NotNil(t, items)
+		assert.False(t, containsCompletionItemLabel(items, "X"))
+		assert.True(t, slices.ContainsFunc(items, func(item CompletionItem) bool {
+			if item.Label == "Y" {
+				assert.Equal(t, "Y: ${1:}", item.InsertText)
+				assert.Equal(t, ToPtr(SnippetTextFormat), item.InsertTextFormat)
+				return true
+			}
+			return false
+		}))
+	})
+
+	t.Run("SpxConfigStructLitField", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+run "assets", {Title: "My Game", }
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 1, Character: 33},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
+		require.NotNil(t, items)
+		assert.False(t, containsCompletionItemLabel(items, "Title"))
+		assert.True(t, containsCompletionItemLabel(items, "Width"))
+		assert.True(t, slices.ContainsFunc(items, func(item CompletionItem) bool {
+			if item.Label == "ScreenshotKey" {
+				assert.Equal(t, `ScreenshotKey: "${1:}"`, item.InsertText)
+				return true
+			}
+			return false
+		}))
+	})
+
+	t.Run("SpxConfigStructLitFieldWithMissingValue", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+run "assets", {Title: }
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 1, Character: 22},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
+		require.NotNil(t, items)
+		assert.NotEmpty(t, items)
+		assert.False(t, containsCompletionItemLabel(items, "Width"))
+	})
+
 	t.Run("TypeAssertion", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.spx": []byte(`
@@ -927,19 +1526,29 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		items, err := s.textDocumentCompletion(&CompletionParams{
+		list, err := s.textDocumentCompletion(context.Background(), &CompletionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 7, Character: 15},
 			},
 		})
 		require.NoError(t, err)
+		require.NotNil(t, list)
+		items := list.Items
 		require.NotNil(t, items)
 		assert.NotEmpty(t, items)
 		assert.True(t, containsCompletionItemLabel(items, "MyStruct"))
 	})
 }
 
+func withoutCompletionItemSortText(items []CompletionItem) []CompletionItem {
+	items = slices.Clone(items)
+	for i := range items {
+		items[i].SortText = ""
+	}
+	return items
+}
+
 func containsCompletionItemLabel(items []CompletionItem, label string) bool {
 	return slices.ContainsFunc(items, func(item CompletionItem) bool {
 		return item.Label == label
@@ -955,3 +1564,20 @@ func containsCompletionSpxDefinitionID(items []CompletionItem, id SpxDefinitionI
 		return itemData.Definition.String() == id.String()
 	})
 }
+
+func TestIsFuzzyMatch(t *testing.T) {
+	for _, tt := range []struct {
+		s       string
+		pattern string
+		want    bool
+	}{
+		{"play", "", true},
+		{"play", "pla", true},
+		{"playAndWait", "paw", true},
+		{"Play", "pl", true},
+		{"play", "lp", false},
+		{"play", "plays", false},
+	} {
+		assert.Equal(t, tt.want, isFuzzyMatch(tt.s, tt.pattern), "%q %q", tt.s, tt.pattern)
+	}
+}
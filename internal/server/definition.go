@@ -1,15 +1,17 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"go/types"
 
+	"github.com/goplus/xgolsw/xgo"
 	"github.com/goplus/xgolsw/xgo/xgoutil"
 )
 
 // See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#textDocument_declaration
-func (s *Server) textDocumentDeclaration(params *DeclarationParams) (any, error) {
-	return s.textDocumentDefinition(&DefinitionParams{
+func (s *Server) textDocumentDeclaration(ctx context.Context, params *DeclarationParams) (any, error) {
+	return s.textDocumentDefinition(ctx, &DefinitionParams{
 		TextDocumentPositionParams: params.TextDocumentPositionParams,
 		WorkDoneProgressParams:     params.WorkDoneProgressParams,
 		PartialResultParams:        params.PartialResultParams,
@@ -17,7 +19,11 @@ func (s *Server) textDocumentDeclaration(params *DeclarationParams) (any, error)
 }
 
 // See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#textDocument_definition
-func (s *Server) textDocumentDefinition(params *DefinitionParams) (any, error) {
+func (s *Server) textDocumentDefinition(ctx context.Context, params *DefinitionParams) (any, error) {
+	if location := s.spxResourceDefinitionAtPosition(ctx, params.TextDocument.URI, params.Position); location != nil {
+		return *location, nil
+	}
+
 	proj := s.getProjWithFile()
 	if proj == nil {
 		return nil, nil
@@ -35,17 +41,20 @@ func (s *Server) textDocumentDefinition(params *DefinitionParams) (any, error) {
 	position := ToPosition(proj, astFile, params.Position)
 	ident := xgoutil.IdentAtPosition(proj, astFile, position)
 
-	typeInfo, _ := proj.TypeInfo()
+	typeInfo, _ := proj.TypeInfoFor(spxFile)
 	if typeInfo == nil {
 		return nil, nil
 	}
 
 	obj := typeInfo.ObjectOf(ident)
-	if !xgoutil.IsInMainPkg(obj) || !obj.Pos().IsValid() {
+	if !isProjectObject(proj, obj) || !obj.Pos().IsValid() {
 		return nil, nil
 	}
 
 	defIdent := typeInfo.DefIdentFor(obj)
+	if localPkg := proj.LocalPackageFor(obj.Pkg()); localPkg != nil {
+		defIdent = localPkg.TypeInfo.DefIdentFor(obj)
+	}
 	if defIdent == nil {
 		// Fall back to the start position of the object identifier in declaration.
 		return s.locationForPos(proj, obj.Pos()), nil
@@ -53,6 +62,27 @@ func (s *Server) textDocumentDefinition(params *DefinitionParams) (any, error) {
 	return s.locationForNode(proj, defIdent), nil
 }
 
+// spxResourceDefinitionAtPosition returns the location of the metadata of the
+// spx resource referenced at the given position, if the reference is a string
+// literal or the declaration of an auto-binding variable. Other references,
+// such as usages of auto-binding variables and constants, go to their
+// declarations instead, from which the resource can be reached in turn.
+func (s *Server) spxResourceDefinitionAtPosition(ctx context.Context, documentURI DocumentURI, position Position) *Location {
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(ctx, documentURI)
+	if err != nil || astFile == nil || !astFile.Pos().IsValid() {
+		return nil
+	}
+	ref := result.spxResourceRefAtASTFilePosition(astFile, ToPosition(result.proj, astFile, position))
+	if ref == nil {
+		return nil
+	}
+	switch ref.Kind {
+	case SpxResourceRefKindStringLiteral, SpxResourceRefKindAutoBinding, SpxResourceRefKindIndexLiteral:
+		return s.spxResourceDefinitionLocation(result, ref.ID)
+	}
+	return nil
+}
+
 // See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#textDocument_typeDefinition
 func (s *Server) textDocumentTypeDefinition(params *TypeDefinitionParams) (any, error) {
 	proj := s.getProjWithFile()
@@ -71,13 +101,13 @@ func (s *Server) textDocumentTypeDefinition(params *TypeDefinitionParams) (any,
 	position := ToPosition(proj, astFile, params.Position)
 	ident := xgoutil.IdentAtPosition(proj, astFile, position)
 
-	typeInfo, _ := proj.TypeInfo()
+	typeInfo, _ := proj.TypeInfoFor(spxFile)
 	if typeInfo == nil {
 		return nil, nil
 	}
 
 	obj := typeInfo.ObjectOf(ident)
-	if !xgoutil.IsInMainPkg(obj) {
+	if !isProjectObject(proj, obj) {
 		return nil, nil
 	}
 
@@ -93,3 +123,9 @@ func (s *Server) textDocumentTypeDefinition(params *TypeDefinitionParams) (any,
 	}
 	return s.locationForPos(proj, objPos), nil
 }
+
+// isProjectObject reports whether obj is defined in the project, either in
+// the main package or in one of its local packages.
+func isProjectObject(proj *xgo.Project, obj types.Object) bool {
+	return obj != nil && (xgoutil.IsInMainPkg(obj) || proj.LocalPackageFor(obj.Pkg()) != nil)
+}
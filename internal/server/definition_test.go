@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -27,7 +28,7 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		mainSpxMySpriteDef, err := s.textDocumentDefinition(&DefinitionParams{
+		mainSpxMySpriteDef, err := s.textDocumentDefinition(context.Background(), &DefinitionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 4, Character: 0},
@@ -44,7 +45,7 @@ onStart => {
 			},
 		}, mainSpxMySpriteDef.(Location))
 
-		mainSpxMySpriteTurnDef, err := s.textDocumentDefinition(&DefinitionParams{
+		mainSpxMySpriteTurnDef, err := s.textDocumentDefinition(context.Background(), &DefinitionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 4, Character: 9},
@@ -53,7 +54,7 @@ onStart => {
 		require.NoError(t, err)
 		require.Nil(t, mainSpxMySpriteTurnDef)
 
-		mySpriteSpxMySpriteDef, err := s.textDocumentDefinition(&DefinitionParams{
+		mySpriteSpxMySpriteDef, err := s.textDocumentDefinition(context.Background(), &DefinitionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
 				Position:     Position{Line: 2, Character: 1},
@@ -71,6 +72,62 @@ onStart => {
 		}, mainSpxMySpriteDef.(Location))
 	})
 
+	t.Run("SpxResource", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	Sound1 Sound
+)
+play "Sound1"
+play Sound1
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json":               []byte(`{}`),
+			"assets/sounds/Sound1/index.json": []byte(`{"name":"Sound1","path":"sound1.wav"}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		soundLocation := Location{
+			URI: "file:///assets/sounds/Sound1/index.json",
+			Range: Range{
+				Start: Position{Line: 0, Character: 8},
+				End:   Position{Line: 0, Character: 16},
+			},
+		}
+
+		stringLitDef, err := s.textDocumentDefinition(context.Background(), &DefinitionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 4, Character: 7},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, soundLocation, stringLitDef)
+
+		autoBindingDef, err := s.textDocumentDefinition(context.Background(), &DefinitionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 2, Character: 2},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, soundLocation, autoBindingDef)
+
+		autoBindingRefDef, err := s.textDocumentDefinition(context.Background(), &DefinitionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 5, Character: 6},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, Location{
+			URI: "file:///main.spx",
+			Range: Range{
+				Start: Position{Line: 2, Character: 1},
+				End:   Position{Line: 2, Character: 7},
+			},
+		}, autoBindingRefDef)
+	})
+
 	t.Run("BuiltinType", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.spx": []byte(`
@@ -79,7 +136,7 @@ var x int
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		def, err := s.textDocumentDefinition(&DefinitionParams{
+		def, err := s.textDocumentDefinition(context.Background(), &DefinitionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 1, Character: 6},
@@ -97,7 +154,7 @@ this.run "assets", {Title: "My Game"}
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		def, err := s.textDocumentDefinition(&DefinitionParams{
+		def, err := s.textDocumentDefinition(context.Background(), &DefinitionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 1, Character: 0},
@@ -115,7 +172,7 @@ var x int
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		def, err := s.textDocumentDefinition(&DefinitionParams{
+		def, err := s.textDocumentDefinition(context.Background(), &DefinitionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 99, Character: 99},
@@ -134,7 +191,7 @@ fmt.println "Hello, spx!"
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		def, err := s.textDocumentDefinition(&DefinitionParams{
+		def, err := s.textDocumentDefinition(context.Background(), &DefinitionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 0},
@@ -161,7 +218,7 @@ fmt2.println "Hello, spx!"
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		def, err := s.textDocumentDefinition(&DefinitionParams{
+		def, err := s.textDocumentDefinition(context.Background(), &DefinitionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 0},
@@ -187,7 +244,7 @@ var x int
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		def, err := s.textDocumentDefinition(&DefinitionParams{
+		def, err := s.textDocumentDefinition(context.Background(), &DefinitionParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "bucket:///main.spx"},
 				Position:     Position{Line: 99, Character: 99},
@@ -196,6 +253,56 @@ var x int
 		require.Contains(t, err.Error(), "failed to get file path from document URI")
 		require.Nil(t, def)
 	})
+
+	t.Run("LocalPackage", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`import "utils"
+
+echo utils.Double(21)
+`),
+			"utils/utils.xgo": []byte(`package utils
+
+func Double(x int) int {
+	return triple(x) - x
+}
+
+func triple(x int) int {
+	return x * 3
+}
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		def, err := s.textDocumentDefinition(context.Background(), &DefinitionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 2, Character: 11},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, Location{
+			URI: "file:///utils/utils.xgo",
+			Range: Range{
+				Start: Position{Line: 2, Character: 5},
+				End:   Position{Line: 2, Character: 11},
+			},
+		}, def)
+
+		def, err = s.textDocumentDefinition(context.Background(), &DefinitionParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///utils/utils.xgo"},
+				Position:     Position{Line: 3, Character: 8},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, Location{
+			URI: "file:///utils/utils.xgo",
+			Range: Range{
+				Start: Position{Line: 6, Character: 5},
+				End:   Position{Line: 6, Character: 11},
+			},
+		}, def)
+	})
 }
 
 func TestServerTextDocumentTypeDefinition(t *testing.T) {
@@ -1,8 +1,72 @@
 package server
 
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/goplus/xgolsw/internal/analysis"
+	"github.com/goplus/xgolsw/internal/analysis/protocol"
+)
+
+// diagnosticDocsURL is the URL of the documentation of diagnostic codes. Each
+// code has a section in it, whose anchor is the code itself.
+const diagnosticDocsURL = "https://github.com/goplus/xgolsw/blob/main/docs/diagnostics.md"
+
+// Codes of diagnostics reported by the server. They are stable, so clients can
+// rely on them to tell kinds of diagnostics apart.
+const (
+	diagnosticCodeSpxPackageName             = "spx-package-name"
+	diagnosticCodeSpxResourceSetInvalid      = "spx-resource-set-invalid"
+	diagnosticCodeSpxStageConfig             = "spx-stage-config"
+	diagnosticCodeSpxResourceRootInvalid     = "spx-resource-root-invalid"
+	diagnosticCodeSpxResourceRootMismatch    = "spx-resource-root-mismatch"
+	diagnosticCodeSpxAutoBindTypeMismatch    = "spx-autobind-type-mismatch"
+	diagnosticCodeSpxAutoBindOrder           = "spx-autobind-order"
+	diagnosticCodeSpxResourceNameEmpty       = "spx-resource-name-empty"
+	diagnosticCodeSpxResourceNotFound        = "spx-resource-not-found"
+	diagnosticCodeSpxCostumeIndexOutOfRange  = "spx-costume-index-out-of-range"
+	diagnosticCodeSpxSoundFileNotFound       = "spx-sound-file-not-found"
+	diagnosticCodeSpxSoundFormatUnsupported  = "spx-sound-format-unsupported"
+	diagnosticCodeSpxWidgetTypeMismatch      = "spx-widget-type-mismatch"
+	diagnosticCodeSpxResourceMetadataInvalid = "spx-resource-metadata-invalid"
+)
+
+// documentedDiagnosticCodes is the set of diagnostic codes that have a section
+// in the documentation at [diagnosticDocsURL].
+var documentedDiagnosticCodes = map[string]bool{
+	diagnosticCodeSpxPackageName:             true,
+	diagnosticCodeSpxResourceSetInvalid:      true,
+	diagnosticCodeSpxStageConfig:             true,
+	diagnosticCodeSpxResourceRootInvalid:     true,
+	diagnosticCodeSpxResourceRootMismatch:    true,
+	diagnosticCodeSpxAutoBindTypeMismatch:    true,
+	diagnosticCodeSpxAutoBindOrder:           true,
+	diagnosticCodeSpxResourceNameEmpty:       true,
+	diagnosticCodeSpxResourceNotFound:        true,
+	diagnosticCodeSpxCostumeIndexOutOfRange:  true,
+	diagnosticCodeSpxSoundFileNotFound:       true,
+	diagnosticCodeSpxSoundFormatUnsupported:  true,
+	diagnosticCodeSpxWidgetTypeMismatch:      true,
+	diagnosticCodeSpxResourceMetadataInvalid: true,
+}
+
+// diagnosticCodeDescription returns the description of the given diagnostic
+// code, which links to its documentation. It returns nil if the code is not
+// documented.
+func diagnosticCodeDescription(code string) *CodeDescription {
+	if !documentedDiagnosticCodes[code] {
+		return nil
+	}
+	return &CodeDescription{Href: URI(diagnosticDocsURL + "#" + code)}
+}
+
 // See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification#textDocument_diagnostic
-func (s *Server) textDocumentDiagnostic(params *DocumentDiagnosticParams) (*DocumentDiagnosticReport, error) {
-	result, err := s.compile()
+func (s *Server) textDocumentDiagnostic(ctx context.Context, params *DocumentDiagnosticParams) (*DocumentDiagnosticReport, error) {
+	result, err := s.compileWithContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -16,8 +80,12 @@ func (s *Server) textDocumentDiagnostic(params *DocumentDiagnosticParams) (*Docu
 }
 
 // See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification#workspace_diagnostic
-func (s *Server) workspaceDiagnostic(params *WorkspaceDiagnosticParams) (*WorkspaceDiagnosticReport, error) {
-	result, err := s.compile()
+func (s *Server) workspaceDiagnostic(ctx context.Context, params *WorkspaceDiagnosticParams) (*WorkspaceDiagnosticReport, error) {
+	progress := s.newWorkDoneProgress(params.WorkDoneToken)
+	progress.begin("Diagnosing workspace", "")
+	defer progress.end("")
+
+	result, err := s.compileWithContext(withWorkDoneProgress(ctx, progress))
 	if err != nil {
 		return nil, err
 	}
@@ -36,3 +104,110 @@ func (s *Server) workspaceDiagnostic(params *WorkspaceDiagnosticParams) (*Worksp
 	}
 	return &WorkspaceDiagnosticReport{Items: items}, nil
 }
+
+// spxDiagnoseWorkspace compiles the workspace and returns the diagnostics of
+// all spx source files and spx resource metadata files, including those
+// reported by the enabled analyzers. Unlike [Server.workspaceDiagnostic], the
+// result is sorted and summarized, so it can be consumed by tools such as CI
+// gates.
+func (s *Server) spxDiagnoseWorkspace(ctx context.Context) (*SpxWorkspaceDiagnosis, error) {
+	result, err := s.compileWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSpxWorkspaceDiagnosis(result.diagnostics), nil
+}
+
+// spxRunAnalyzers compiles the workspace and runs the analyzers with the given
+// names on all spx source files, whether they are enabled or not, and returns
+// only the diagnostics reported by them. Diagnostics have the severities
+// configured for the analyzers in the settings.
+func (s *Server) spxRunAnalyzers(ctx context.Context, param SpxRunAnalyzersParams) (*SpxWorkspaceDiagnosis, error) {
+	if len(param.Analyzers) == 0 {
+		return nil, errors.New("no analyzers to run")
+	}
+	settings := s.getSettings()
+	analyzers := make([]*protocol.Analyzer, 0, len(param.Analyzers))
+	configs := make(map[*protocol.Analyzer]analyzerConfig, len(param.Analyzers))
+	for _, name := range param.Analyzers {
+		idx := slices.IndexFunc(s.analyzers, func(analyzer *analysis.Analyzer) bool {
+			return analyzer.Name() == name
+		})
+		if idx < 0 {
+			return nil, fmt.Errorf("unknown analyzer: %s", name)
+		}
+		analyzer := s.analyzers[idx]
+		if _, ok := configs[analyzer.Analyzer()]; ok {
+			continue
+		}
+		analyzers = append(analyzers, analyzer.Analyzer())
+		configs[analyzer.Analyzer()] = settings.analyzerConfig(analyzer)
+	}
+
+	result, err := s.compileWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	proj := result.proj
+	typeInfo, _ := proj.TypeInfo()
+	astPkg, _ := proj.ASTPackage()
+	if typeInfo == nil || astPkg == nil {
+		return newSpxWorkspaceDiagnosis(nil), nil
+	}
+
+	diagnostics := make(map[DocumentURI][]Diagnostic, len(astPkg.Files))
+	for spxFile := range astPkg.Files {
+		diagnostics[s.toDocumentURI(spxFile)] = []Diagnostic{}
+	}
+	for spxFile, diags := range runAnalyzers(proj, typeInfo, result.spxVersion, astPkg.Files, analyzers, configs, s.toDocumentURI) {
+		documentURI := s.toDocumentURI(spxFile)
+		diagnostics[documentURI] = append(diagnostics[documentURI], diags...)
+	}
+	return newSpxWorkspaceDiagnosis(diagnostics), nil
+}
+
+// newSpxWorkspaceDiagnosis returns the [SpxWorkspaceDiagnosis] of the given
+// diagnostics keyed by document URI, with files sorted by URI.
+func newSpxWorkspaceDiagnosis(diagnostics map[DocumentURI][]Diagnostic) *SpxWorkspaceDiagnosis {
+	diagnosis := &SpxWorkspaceDiagnosis{
+		Files: make([]SpxFileDiagnosis, 0, len(diagnostics)),
+	}
+	for documentURI, diags := range diagnostics {
+		for _, diag := range diags {
+			switch diag.Severity {
+			case SeverityError:
+				diagnosis.ErrorCount++
+			case SeverityWarning:
+				diagnosis.WarningCount++
+			}
+		}
+		diagnosis.Files = append(diagnosis.Files, SpxFileDiagnosis{
+			URI:         documentURI,
+			Resource:    path.Ext(string(documentURI)) == ".json",
+			Diagnostics: diags,
+		})
+	}
+	slices.SortFunc(diagnosis.Files, func(a, b SpxFileDiagnosis) int {
+		return cmp.Compare(a.URI, b.URI)
+	})
+	return diagnosis
+}
+
+// refreshDiagnostics asks the client to pull diagnostics again. It is used
+// for changes that make pulled diagnostics stale without changing the
+// documents they belong to, such as changes to spx resources. It does nothing
+// if the client does not support refreshing diagnostics.
+//
+// The request is sent in the background, so it does not block handling of
+// other messages.
+func (s *Server) refreshDiagnostics() {
+	if caps := s.clientCapabilities.Workspace.Diagnostics; caps == nil || !caps.RefreshSupport {
+		return
+	}
+	go func() {
+		if _, err := s.call(context.Background(), "workspace/diagnostic/refresh", nil); err != nil {
+			s.getLogger().Debug("failed to refresh diagnostics", "error", err)
+		}
+	}()
+}
@@ -1,8 +1,12 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
+	"os"
 	"testing"
 
+	"github.com/goplus/xgolsw/xgo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -57,7 +61,7 @@ func TestServerTextDocumentDiagnostic(t *testing.T) {
 			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 		}
 
-		report, err := s.textDocumentDiagnostic(params)
+		report, err := s.textDocumentDiagnostic(context.Background(), params)
 		require.NoError(t, err)
 		require.NotNil(t, report)
 
@@ -79,7 +83,7 @@ var (
 			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 		}
 
-		report, err := s.textDocumentDiagnostic(params)
+		report, err := s.textDocumentDiagnostic(context.Background(), params)
 		require.NoError(t, err)
 		require.NotNil(t, report)
 
@@ -105,6 +109,26 @@ var (
 		})
 	})
 
+	t.Run("WithoutTypes", func(t *testing.T) {
+		fileMap := newTestFileMap()
+		fileMap["main.spx"] = []byte(`
+echo undefinedVar
+`)
+		s := New(newMapFSWithFeatures(fileMap, xgo.FeatASTCache), nil, fileMapGetter(fileMap), &MockScheduler{})
+		params := &DocumentDiagnosticParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		}
+
+		report, err := s.textDocumentDiagnostic(context.Background(), params)
+		require.NoError(t, err)
+		require.NotNil(t, report)
+
+		fullReport, ok := report.Value.(RelatedFullDocumentDiagnosticReport)
+		assert.True(t, ok, "expected RelatedFullDocumentDiagnosticReport")
+		assert.Equal(t, string(DiagnosticFull), fullReport.Kind)
+		assert.Empty(t, fullReport.Items, "type errors are not reported without type checking")
+	})
+
 	t.Run("NonSpxFile", func(t *testing.T) {
 		fileMap := newTestFileMap()
 		fileMap["main.xgo"] = []byte(`echo "Hello, XGo!"`)
@@ -113,7 +137,7 @@ var (
 			TextDocument: TextDocumentIdentifier{URI: "file:///main.xgo"},
 		}
 
-		report, err := s.textDocumentDiagnostic(params)
+		report, err := s.textDocumentDiagnostic(context.Background(), params)
 		require.NoError(t, err)
 		require.NotNil(t, report)
 
@@ -131,7 +155,7 @@ var (
 			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 		}
 
-		report, err := s.textDocumentDiagnostic(params)
+		report, err := s.textDocumentDiagnostic(context.Background(), params)
 		require.NoError(t, err)
 		require.NotNil(t, report)
 
@@ -140,8 +164,10 @@ var (
 		assert.Equal(t, string(DiagnosticFull), fullReport.Kind)
 		require.Len(t, fullReport.Items, 1)
 		assert.Contains(t, fullReport.Items, Diagnostic{
-			Severity: SeverityError,
-			Message:  "package name must be main",
+			Severity:        SeverityError,
+			Code:            "spx-package-name",
+			CodeDescription: diagnosticCodeDescription("spx-package-name"),
+			Message:         "package name must be main",
 			Range: Range{
 				Start: Position{Line: 0, Character: 8},
 				End:   Position{Line: 0, Character: 15},
@@ -155,7 +181,7 @@ var (
 			TextDocument: TextDocumentIdentifier{URI: "file:///notexist.spx"},
 		}
 
-		report, err := s.textDocumentDiagnostic(params)
+		report, err := s.textDocumentDiagnostic(context.Background(), params)
 		require.NoError(t, err)
 		require.NotNil(t, report)
 
@@ -170,10 +196,10 @@ func TestServerWorkspaceDiagnostic(t *testing.T) {
 	t.Run("Normal", func(t *testing.T) {
 		s := New(newMapFSWithoutModTime(newTestFileMap()), nil, fileMapGetter(newTestFileMap()), &MockScheduler{})
 
-		report, err := s.workspaceDiagnostic(&WorkspaceDiagnosticParams{})
+		report, err := s.workspaceDiagnostic(context.Background(), &WorkspaceDiagnosticParams{})
 		require.NoError(t, err)
 		require.NotNil(t, report)
-		assert.Len(t, report.Items, 3)
+		assert.Len(t, report.Items, 7)
 		foundFiles := make(map[string]struct{})
 		for _, item := range report.Items {
 			fullReport := item.Value.(WorkspaceFullDocumentDiagnosticReport)
@@ -195,20 +221,20 @@ func TestServerWorkspaceDiagnostic(t *testing.T) {
 var (
 	MyAircraft MyAircraft
 `),
-			"MyAircraft.spx":                       []byte(`var x int`),
+			"MyAircraft.spx":                       []byte("var x int\nonStart => {\n\techo x\n}"),
 			"assets/index.json":                    []byte(`{}`),
 			"assets/sprites/MyAircraft/index.json": []byte(`{}`),
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		report, err := s.workspaceDiagnostic(&WorkspaceDiagnosticParams{})
+		report, err := s.workspaceDiagnostic(context.Background(), &WorkspaceDiagnosticParams{})
 		require.NoError(t, err)
 		require.NotNil(t, report)
-		assert.Len(t, report.Items, 2)
+		assert.Len(t, report.Items, 4)
 		for _, item := range report.Items {
 			fullReport := item.Value.(WorkspaceFullDocumentDiagnosticReport)
 			if fullReport.URI == "file:///main.spx" {
-				require.Len(t, fullReport.Items, 2)
+				require.Len(t, fullReport.Items, 3)
 				assert.Contains(t, fullReport.Items, Diagnostic{
 					Severity: SeverityError,
 					Message:  "expected ')', found 'EOF'",
@@ -225,6 +251,16 @@ var (
 						End:   Position{Line: 3, Character: 23},
 					},
 				})
+				assert.Contains(t, fullReport.Items, Diagnostic{
+					Severity: SeverityHint,
+					Source:   "unusedautobindings",
+					Message:  "auto-bound MyAircraft is never used",
+					Range: Range{
+						Start: Position{Line: 3, Character: 1},
+						End:   Position{Line: 3, Character: 11},
+					},
+					Tags: []DiagnosticTag{Unnecessary},
+				})
 			} else {
 				assert.Empty(t, fullReport.Items)
 			}
@@ -234,7 +270,7 @@ var (
 	t.Run("EmptyWorkspace", func(t *testing.T) {
 		s := New(newMapFSWithoutModTime(map[string][]byte{}), nil, fileMapGetter(map[string][]byte{}), &MockScheduler{})
 
-		report, err := s.workspaceDiagnostic(&WorkspaceDiagnosticParams{})
+		report, err := s.workspaceDiagnostic(context.Background(), &WorkspaceDiagnosticParams{})
 		require.EqualError(t, err, "no valid main.spx file found in main package")
 		require.Nil(t, report)
 	})
@@ -269,10 +305,10 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		report, err := s.workspaceDiagnostic(&WorkspaceDiagnosticParams{})
+		report, err := s.workspaceDiagnostic(context.Background(), &WorkspaceDiagnosticParams{})
 		require.NoError(t, err)
 		require.NotNil(t, report)
-		assert.Len(t, report.Items, 2)
+		assert.Len(t, report.Items, 4)
 		for _, item := range report.Items {
 			fullReport := item.Value.(WorkspaceFullDocumentDiagnosticReport)
 			assert.Equal(t, string(DiagnosticFull), fullReport.Kind)
@@ -280,28 +316,42 @@ onStart => {
 			case "file:///MySprite.spx":
 				require.Len(t, fullReport.Items, 3)
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  "sound resource name cannot be empty",
+					Severity:        SeverityError,
+					Code:            "spx-resource-name-empty",
+					CodeDescription: diagnosticCodeDescription("spx-resource-name-empty"),
+					Message:         "sound resource name cannot be empty",
 					Range: Range{
 						Start: Position{Line: 8, Character: 6},
 						End:   Position{Line: 8, Character: 8},
 					},
 				})
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  `sound resource "ConstSoundName" not found`,
+					Severity:        SeverityError,
+					Code:            "spx-resource-not-found",
+					CodeDescription: diagnosticCodeDescription("spx-resource-not-found"),
+					Message:         `sound resource "ConstSoundName" not found`,
 					Range: Range{
 						Start: Position{Line: 9, Character: 6},
 						End:   Position{Line: 9, Character: 20},
 					},
+					RelatedInformation: []DiagnosticRelatedInformation{{
+						Location: Location{URI: "file:///assets/sounds"},
+						Message:  "sound resources are looked up in assets/sounds",
+					}},
 				})
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  `sound resource "LiteralSoundName" not found`,
+					Severity:        SeverityError,
+					Code:            "spx-resource-not-found",
+					CodeDescription: diagnosticCodeDescription("spx-resource-not-found"),
+					Message:         `sound resource "LiteralSoundName" not found`,
 					Range: Range{
 						Start: Position{Line: 10, Character: 6},
 						End:   Position{Line: 10, Character: 24},
 					},
+					RelatedInformation: []DiagnosticRelatedInformation{{
+						Location: Location{URI: "file:///assets/sounds"},
+						Message:  "sound resources are looked up in assets/sounds",
+					}},
 				})
 			default:
 				assert.Empty(t, fullReport.Items)
@@ -309,6 +359,432 @@ onStart => {
 		}
 	})
 
+	t.Run("SoundFileMissingOrUnsupported", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	MissingSound Sound
+)
+onStart => {
+	play MissingSound
+	play "FlacSound"
+	play "WavSound"
+}
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json":                     []byte(`{}`),
+			"assets/sounds/MissingSound/index.json": []byte(`{"path":"missing.wav"}`),
+			"assets/sounds/FlacSound/index.json":    []byte(`{"path":"sound.flac"}`),
+			"assets/sounds/FlacSound/sound.flac":    []byte(``),
+			"assets/sounds/WavSound/index.json":     []byte(`{"path":"sound.wav"}`),
+			"assets/sounds/WavSound/sound.wav":      []byte(``),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		report, err := s.textDocumentDiagnostic(context.Background(), &DocumentDiagnosticParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, report)
+		fullReport := report.Value.(RelatedFullDocumentDiagnosticReport)
+		assert.Equal(t, []Diagnostic{
+			{
+				Severity:        SeverityWarning,
+				Code:            "spx-sound-file-not-found",
+				CodeDescription: diagnosticCodeDescription("spx-sound-file-not-found"),
+				Message:         `sound file "assets/sounds/MissingSound/missing.wav" of sound resource "MissingSound" not found`,
+				Range: Range{
+					Start: Position{Line: 2, Character: 1},
+					End:   Position{Line: 2, Character: 13},
+				},
+			},
+			{
+				Severity:        SeverityWarning,
+				Code:            "spx-sound-format-unsupported",
+				CodeDescription: diagnosticCodeDescription("spx-sound-format-unsupported"),
+				Message:         `sound resource "FlacSound" has unsupported format "flac"`,
+				Range: Range{
+					Start: Position{Line: 6, Character: 6},
+					End:   Position{Line: 6, Character: 17},
+				},
+			},
+		}, fullReport.Items)
+	})
+
+	t.Run("AutoBindingTypeMismatch", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	MySprite Sound
+	MySound  Sprite
+)
+echo MySprite, MySound
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx":                       []byte(``),
+			"assets/index.json":                  []byte(`{}`),
+			"assets/sprites/MySprite/index.json": []byte(`{}`),
+			"assets/sounds/MySound/index.json":   []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		report, err := s.textDocumentDiagnostic(context.Background(), &DocumentDiagnosticParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, report)
+		fullReport := report.Value.(RelatedFullDocumentDiagnosticReport)
+		assert.ElementsMatch(t, []Diagnostic{
+			{
+				Severity:        SeverityWarning,
+				Code:            "spx-autobind-type-mismatch",
+				CodeDescription: diagnosticCodeDescription("spx-autobind-type-mismatch"),
+				Message:         `"MySprite" is declared as Sound, but the resource with that name is a sprite`,
+				Range: Range{
+					Start: Position{Line: 2, Character: 1},
+					End:   Position{Line: 2, Character: 9},
+				},
+			},
+			{
+				Severity:        SeverityWarning,
+				Code:            "spx-autobind-type-mismatch",
+				CodeDescription: diagnosticCodeDescription("spx-autobind-type-mismatch"),
+				Message:         `"MySound" is declared as Sprite, but the resource with that name is a sound`,
+				Range: Range{
+					Start: Position{Line: 3, Character: 1},
+					End:   Position{Line: 3, Character: 8},
+				},
+			},
+		}, fullReport.Items)
+	})
+
+	t.Run("UnusedVars", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	MySprite MySprite
+	MySound  Sound
+	score    int
+	lives    int
+)
+echo lives
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx":                       []byte(``),
+			"assets/index.json":                  []byte(`{"zorder":["MySprite"]}`),
+			"assets/sprites/MySprite/index.json": []byte(`{}`),
+			"assets/sounds/MySound/index.json":   []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		report, err := s.textDocumentDiagnostic(context.Background(), &DocumentDiagnosticParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, report)
+		fullReport := report.Value.(RelatedFullDocumentDiagnosticReport)
+		assert.ElementsMatch(t, []Diagnostic{
+			{
+				Severity: SeverityWarning,
+				Source:   "unusedvars",
+				Message:  "score is declared but never used",
+				Range: Range{
+					Start: Position{Line: 4, Character: 1},
+					End:   Position{Line: 4, Character: 6},
+				},
+			},
+			{
+				Severity: SeverityHint,
+				Source:   "unusedautobindings",
+				Message:  "auto-bound MySprite is never used",
+				Range: Range{
+					Start: Position{Line: 2, Character: 1},
+					End:   Position{Line: 2, Character: 9},
+				},
+				Tags: []DiagnosticTag{Unnecessary},
+			},
+			{
+				Severity: SeverityHint,
+				Source:   "unusedautobindings",
+				Message:  "auto-bound MySound is never used",
+				Range: Range{
+					Start: Position{Line: 3, Character: 1},
+					End:   Position{Line: 3, Character: 8},
+				},
+				Tags: []DiagnosticTag{Unnecessary},
+			},
+		}, fullReport.Items)
+	})
+
+	t.Run("UnreachableCode", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	MySprite MySprite
+)
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx": []byte(`
+onStart => {
+	forever => {
+		turn 1
+	}
+	say "Hi"
+}
+`),
+			"assets/index.json":                  []byte(`{"zorder":["MySprite"]}`),
+			"assets/sprites/MySprite/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		report, err := s.textDocumentDiagnostic(context.Background(), &DocumentDiagnosticParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, report)
+		fullReport := report.Value.(RelatedFullDocumentDiagnosticReport)
+		assert.Equal(t, []Diagnostic{
+			{
+				Severity: SeverityWarning,
+				Source:   "unreachable",
+				Message:  "unreachable code",
+				Range: Range{
+					Start: Position{Line: 5, Character: 1},
+					End:   Position{Line: 5, Character: 9},
+				},
+				Tags: []DiagnosticTag{Unnecessary},
+			},
+		}, fullReport.Items)
+	})
+
+	t.Run("SuspiciousEventHandlers", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	MySprite MySprite
+)
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx": []byte(`
+onStart => {
+	forever => {
+		onClick => {
+			say "Hi"
+		}
+	}
+}
+
+onStart => {
+	say "Bye"
+}
+`),
+			"assets/index.json":                  []byte(`{"zorder":["MySprite"]}`),
+			"assets/sprites/MySprite/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		report, err := s.textDocumentDiagnostic(context.Background(), &DocumentDiagnosticParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, report)
+		fullReport := report.Value.(RelatedFullDocumentDiagnosticReport)
+		assert.Equal(t, []Diagnostic{
+			{
+				Severity: SeverityWarning,
+				Code:     "loopregistration",
+				Source:   "eventhandlers",
+				Message:  "onClick is registered inside a loop of another event handler, which registers a new handler on every iteration",
+				Range: Range{
+					Start: Position{Line: 3, Character: 2},
+					End:   Position{Line: 3, Character: 9},
+				},
+			},
+			{
+				Severity: SeverityWarning,
+				Code:     "duplicateonstart",
+				Source:   "eventhandlers",
+				Message:  "onStart is already registered in this file at line 2",
+				Range: Range{
+					Start: Position{Line: 9, Character: 0},
+					End:   Position{Line: 9, Character: 7},
+				},
+				RelatedInformation: []DiagnosticRelatedInformation{{
+					Location: Location{
+						URI: "file:///MySprite.spx",
+						Range: Range{
+							Start: Position{Line: 1, Character: 0},
+							End:   Position{Line: 1, Character: 7},
+						},
+					},
+					Message: "onStart is first registered here",
+				}},
+			},
+		}, fullReport.Items)
+	})
+
+	t.Run("DuplicateDeclaration", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	a int
+	a int
+)
+echo a
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		report, err := s.textDocumentDiagnostic(context.Background(), &DocumentDiagnosticParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, report)
+		fullReport := report.Value.(RelatedFullDocumentDiagnosticReport)
+		assert.Contains(t, fullReport.Items, Diagnostic{
+			Severity: SeverityError,
+			Message:  "a redeclared",
+			Range: Range{
+				Start: Position{Line: 3, Character: 1},
+				End:   Position{Line: 3, Character: 1},
+			},
+			RelatedInformation: []DiagnosticRelatedInformation{{
+				Location: Location{
+					URI: "file:///main.spx",
+					Range: Range{
+						Start: Position{Line: 2, Character: 1},
+						End:   Position{Line: 2, Character: 1},
+					},
+				},
+				Message: "other declaration of a",
+			}},
+		})
+	})
+
+	t.Run("IgnoreDirectives", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+a := []int{}
+a = append(a) //xgolsw:ignore appends
+//xgolsw:ignore
+a = append(a)
+a = append(a) //xgolsw:ignore other
+echo a, b //xgolsw:ignore
+//xgolsw:ignore
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json": []byte(`{"map":{"mode":"bogus"}}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		report, err := s.textDocumentDiagnostic(context.Background(), &DocumentDiagnosticParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		})
+		require.NoError(t, err)
+		fullReport, ok := report.Value.(RelatedFullDocumentDiagnosticReport)
+		require.True(t, ok)
+		require.Len(t, fullReport.Items, 2)
+		assert.Equal(t, Diagnostic{
+			Severity: SeverityWarning,
+			Source:   "appends",
+			Message:  "append with no values",
+			Range: Range{
+				Start: Position{Line: 5, Character: 4},
+				End:   Position{Line: 5, Character: 13},
+			},
+		}, fullReport.Items[1])
+		assert.Equal(t, SeverityError, fullReport.Items[0].Severity)
+		assert.Equal(t, "undefined: b", fullReport.Items[0].Message)
+	})
+
+	t.Run("StageConfig", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx": []byte(``),
+			"assets/index.json": []byte(`{
+  "backdrops": [{"name": "b1", "path": "b1.png"}, {"name": "b2", "path": "b2.png"}],
+  "backdropIndex": 1,
+  "map": {"width": -1, "height": 360, "mode": "stretch"},
+  "zorder": ["MySprite", "Ghost", {"name": "w1", "type": "monitor"}]
+}`),
+			"assets/b1.png":                      []byte(``),
+			"assets/sprites/MySprite/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		report, err := s.textDocumentDiagnostic(context.Background(), &DocumentDiagnosticParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, report)
+		fullReport := report.Value.(RelatedFullDocumentDiagnosticReport)
+		rng := Range{
+			Start: Position{Line: 1, Character: 4},
+			End:   Position{Line: 1, Character: 12},
+		}
+		assert.Equal(t, []Diagnostic{
+			{
+				Severity:        SeverityWarning,
+				Code:            "spx-stage-config",
+				CodeDescription: diagnosticCodeDescription("spx-stage-config"),
+				Range:           rng,
+				Message:         `image file "assets/b2.png" of default backdrop "b2" not found`,
+			},
+			{
+				Severity:        SeverityWarning,
+				Code:            "spx-stage-config",
+				CodeDescription: diagnosticCodeDescription("spx-stage-config"),
+				Range:           rng,
+				Message:         `map size -1x360 must not be negative`,
+			},
+			{
+				Severity:        SeverityWarning,
+				Code:            "spx-stage-config",
+				CodeDescription: diagnosticCodeDescription("spx-stage-config"),
+				Range:           rng,
+				Message:         `unknown map mode "stretch"`,
+			},
+			{
+				Severity:        SeverityWarning,
+				Code:            "spx-stage-config",
+				CodeDescription: diagnosticCodeDescription("spx-stage-config"),
+				Range:           rng,
+				Message:         `sprite "Ghost" in the stage zorder not found`,
+			},
+		}, fullReport.Items)
+	})
+
+	t.Run("StageConfigBackdropIndexOutOfRange", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json": []byte(`{"backdrops":[{"name":"b1"}],"backdropIndex":1}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		report, err := s.textDocumentDiagnostic(context.Background(), &DocumentDiagnosticParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, report)
+		fullReport := report.Value.(RelatedFullDocumentDiagnosticReport)
+		assert.Equal(t, []Diagnostic{{
+			Severity:        SeverityWarning,
+			Code:            "spx-stage-config",
+			CodeDescription: diagnosticCodeDescription("spx-stage-config"),
+			Range: Range{
+				Start: Position{Line: 1, Character: 4},
+				End:   Position{Line: 1, Character: 12},
+			},
+			Message: "default backdrop index 1 is out of range [0, 1)",
+		}}, fullReport.Items)
+	})
+
 	t.Run("BackdropResourceNotFound", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.spx": []byte(`
@@ -330,10 +806,10 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		report, err := s.workspaceDiagnostic(&WorkspaceDiagnosticParams{})
+		report, err := s.workspaceDiagnostic(context.Background(), &WorkspaceDiagnosticParams{})
 		require.NoError(t, err)
 		require.NotNil(t, report)
-		assert.Len(t, report.Items, 2)
+		assert.Len(t, report.Items, 3)
 		for _, item := range report.Items {
 			fullReport := item.Value.(WorkspaceFullDocumentDiagnosticReport)
 			assert.Equal(t, string(DiagnosticFull), fullReport.Kind)
@@ -341,38 +817,58 @@ onStart => {
 			case "file:///main.spx":
 				require.Len(t, fullReport.Items, 2)
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  "backdrop resource name cannot be empty",
+					Severity:        SeverityError,
+					Code:            "spx-resource-name-empty",
+					CodeDescription: diagnosticCodeDescription("spx-resource-name-empty"),
+					Message:         "backdrop resource name cannot be empty",
 					Range: Range{
 						Start: Position{Line: 1, Character: 11},
 						End:   Position{Line: 1, Character: 13},
 					},
 				})
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  `backdrop resource "NonExistentBackdrop" not found`,
+					Severity:        SeverityError,
+					Code:            "spx-resource-not-found",
+					CodeDescription: diagnosticCodeDescription("spx-resource-not-found"),
+					Message:         `backdrop resource "NonExistentBackdrop" not found`,
 					Range: Range{
 						Start: Position{Line: 2, Character: 11},
 						End:   Position{Line: 2, Character: 32},
 					},
+					RelatedInformation: []DiagnosticRelatedInformation{{
+						Location: Location{URI: "file:///assets/index.json"},
+						Message:  "backdrop resources are looked up in assets/index.json",
+					}},
 				})
 			case "file:///MySprite.spx":
 				require.Len(t, fullReport.Items, 2)
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  `backdrop resource "ConstBackdropName" not found`,
+					Severity:        SeverityError,
+					Code:            "spx-resource-not-found",
+					CodeDescription: diagnosticCodeDescription("spx-resource-not-found"),
+					Message:         `backdrop resource "ConstBackdropName" not found`,
 					Range: Range{
 						Start: Position{Line: 5, Character: 12},
 						End:   Position{Line: 5, Character: 29},
 					},
+					RelatedInformation: []DiagnosticRelatedInformation{{
+						Location: Location{URI: "file:///assets/index.json"},
+						Message:  "backdrop resources are looked up in assets/index.json",
+					}},
 				})
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  `backdrop resource "LiteralBackdropName" not found`,
+					Severity:        SeverityError,
+					Code:            "spx-resource-not-found",
+					CodeDescription: diagnosticCodeDescription("spx-resource-not-found"),
+					Message:         `backdrop resource "LiteralBackdropName" not found`,
 					Range: Range{
 						Start: Position{Line: 6, Character: 12},
 						End:   Position{Line: 6, Character: 33},
 					},
+					RelatedInformation: []DiagnosticRelatedInformation{{
+						Location: Location{URI: "file:///assets/index.json"},
+						Message:  "backdrop resources are looked up in assets/index.json",
+					}},
 				})
 			default:
 				assert.Empty(t, fullReport.Items)
@@ -407,56 +903,86 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		report, err := s.workspaceDiagnostic(&WorkspaceDiagnosticParams{})
+		report, err := s.workspaceDiagnostic(context.Background(), &WorkspaceDiagnosticParams{})
 		require.NoError(t, err)
 		require.NotNil(t, report)
-		assert.Len(t, report.Items, 3)
+		assert.Len(t, report.Items, 4)
 		for _, item := range report.Items {
 			fullReport := item.Value.(WorkspaceFullDocumentDiagnosticReport)
 			assert.Equal(t, string(DiagnosticFull), fullReport.Kind)
 			switch fullReport.URI {
 			case "file:///main.spx":
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  `sprite resource "MySprite2" not found`,
+					Severity:        SeverityError,
+					Code:            "spx-resource-not-found",
+					CodeDescription: diagnosticCodeDescription("spx-resource-not-found"),
+					Message:         `sprite resource "MySprite2" not found`,
 					Range: Range{
 						Start: Position{Line: 3, Character: 1},
 						End:   Position{Line: 3, Character: 10},
 					},
+					RelatedInformation: []DiagnosticRelatedInformation{{
+						Location: Location{URI: "file:///assets/sprites"},
+						Message:  "sprite resources are looked up in assets/sprites",
+					}},
 				})
 			case "file:///MySprite1.spx":
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  `sprite resource "MySprite1" not found`,
+					Severity:        SeverityError,
+					Code:            "spx-resource-not-found",
+					CodeDescription: diagnosticCodeDescription("spx-resource-not-found"),
+					Message:         `sprite resource "MySprite1" not found`,
 					Range: Range{
 						Start: Position{Line: 3, Character: 1},
 						End:   Position{Line: 3, Character: 18},
 					},
+					RelatedInformation: []DiagnosticRelatedInformation{{
+						Location: Location{URI: "file:///assets/sprites"},
+						Message:  "sprite resources are looked up in assets/sprites",
+					}},
 				})
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  `sprite resource "MySprite2" not found`,
+					Severity:        SeverityError,
+					Code:            "spx-resource-not-found",
+					CodeDescription: diagnosticCodeDescription("spx-resource-not-found"),
+					Message:         `sprite resource "MySprite2" not found`,
 					Range: Range{
 						Start: Position{Line: 4, Character: 1},
 						End:   Position{Line: 4, Character: 10},
 					},
+					RelatedInformation: []DiagnosticRelatedInformation{{
+						Location: Location{URI: "file:///assets/sprites"},
+						Message:  "sprite resources are looked up in assets/sprites",
+					}},
 				})
 			case "file:///MySprite2.spx":
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  `sprite resource "MySprite2" not found`,
+					Severity:        SeverityError,
+					Code:            "spx-resource-not-found",
+					CodeDescription: diagnosticCodeDescription("spx-resource-not-found"),
+					Message:         `sprite resource "MySprite2" not found`,
 					Range: Range{
 						Start: Position{Line: 3, Character: 1},
 						End:   Position{Line: 3, Character: 18},
 					},
+					RelatedInformation: []DiagnosticRelatedInformation{{
+						Location: Location{URI: "file:///assets/sprites"},
+						Message:  "sprite resources are looked up in assets/sprites",
+					}},
 				})
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  `sprite resource "MySprite2" not found`,
+					Severity:        SeverityError,
+					Code:            "spx-resource-not-found",
+					CodeDescription: diagnosticCodeDescription("spx-resource-not-found"),
+					Message:         `sprite resource "MySprite2" not found`,
 					Range: Range{
 						Start: Position{Line: 4, Character: 1},
 						End:   Position{Line: 4, Character: 10},
 					},
+					RelatedInformation: []DiagnosticRelatedInformation{{
+						Location: Location{URI: "file:///assets/sprites"},
+						Message:  "sprite resources are looked up in assets/sprites",
+					}},
 				})
 			default:
 				assert.Empty(t, fullReport.Items)
@@ -480,10 +1006,10 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		report, err := s.workspaceDiagnostic(&WorkspaceDiagnosticParams{})
+		report, err := s.workspaceDiagnostic(context.Background(), &WorkspaceDiagnosticParams{})
 		require.NoError(t, err)
 		require.NotNil(t, report)
-		assert.Len(t, report.Items, 2)
+		assert.Len(t, report.Items, 4)
 		for _, item := range report.Items {
 			fullReport := item.Value.(WorkspaceFullDocumentDiagnosticReport)
 			assert.Equal(t, string(DiagnosticFull), fullReport.Kind)
@@ -491,20 +1017,28 @@ onStart => {
 			case "file:///MySprite.spx":
 				require.Len(t, fullReport.Items, 2)
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  "sprite costume resource name cannot be empty",
+					Severity:        SeverityError,
+					Code:            "spx-resource-name-empty",
+					CodeDescription: diagnosticCodeDescription("spx-resource-name-empty"),
+					Message:         "sprite costume resource name cannot be empty",
 					Range: Range{
 						Start: Position{Line: 2, Character: 12},
 						End:   Position{Line: 2, Character: 14},
 					},
 				})
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  `costume resource "NonExistentCostume" not found in sprite "MySprite"`,
+					Severity:        SeverityError,
+					Code:            "spx-resource-not-found",
+					CodeDescription: diagnosticCodeDescription("spx-resource-not-found"),
+					Message:         `costume resource "NonExistentCostume" not found in sprite "MySprite"`,
 					Range: Range{
 						Start: Position{Line: 3, Character: 12},
 						End:   Position{Line: 3, Character: 32},
 					},
+					RelatedInformation: []DiagnosticRelatedInformation{{
+						Location: Location{URI: "file:///assets/sprites/MySprite/index.json"},
+						Message:  `costume resources of sprite "MySprite" are looked up in assets/sprites/MySprite/index.json`,
+					}},
 				})
 			default:
 				assert.Empty(t, fullReport.Items)
@@ -512,6 +1046,41 @@ onStart => {
 		}
 	})
 
+	t.Run("SpriteCostumeResourceIndexOutOfRange", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx": []byte(`
+onStart => {
+	setCostume 0
+	setCostume 1
+	setCostume 2
+}
+`),
+			"assets/index.json":                  []byte(`{}`),
+			"assets/sprites/MySprite/index.json": []byte(`{"costumes":[{"name":"c1"},{"name":"c2"}]}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		report, err := s.textDocumentDiagnostic(context.Background(), &DocumentDiagnosticParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, report)
+		fullReport := report.Value.(RelatedFullDocumentDiagnosticReport)
+		assert.Equal(t, []Diagnostic{{
+			Severity:        SeverityError,
+			Code:            "spx-costume-index-out-of-range",
+			CodeDescription: diagnosticCodeDescription("spx-costume-index-out-of-range"),
+			Message:         `costume index 2 is out of range [0, 2) in sprite "MySprite"`,
+			Range: Range{
+				Start: Position{Line: 4, Character: 12},
+				End:   Position{Line: 4, Character: 13},
+			},
+		}}, fullReport.Items)
+	})
+
 	t.Run("SpriteAnimationResourceNotFound", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.spx": []byte(`
@@ -528,10 +1097,10 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		report, err := s.workspaceDiagnostic(&WorkspaceDiagnosticParams{})
+		report, err := s.workspaceDiagnostic(context.Background(), &WorkspaceDiagnosticParams{})
 		require.NoError(t, err)
 		require.NotNil(t, report)
-		assert.Len(t, report.Items, 2)
+		assert.Len(t, report.Items, 4)
 		for _, item := range report.Items {
 			fullReport := item.Value.(WorkspaceFullDocumentDiagnosticReport)
 			assert.Equal(t, string(DiagnosticFull), fullReport.Kind)
@@ -539,20 +1108,28 @@ onStart => {
 			case "file:///MySprite.spx":
 				require.Len(t, fullReport.Items, 2)
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  "sprite animation resource name cannot be empty",
+					Severity:        SeverityError,
+					Code:            "spx-resource-name-empty",
+					CodeDescription: diagnosticCodeDescription("spx-resource-name-empty"),
+					Message:         "sprite animation resource name cannot be empty",
 					Range: Range{
 						Start: Position{Line: 2, Character: 9},
 						End:   Position{Line: 2, Character: 11},
 					},
 				})
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  `animation resource "roll-in" not found in sprite "MySprite"`,
+					Severity:        SeverityError,
+					Code:            "spx-resource-not-found",
+					CodeDescription: diagnosticCodeDescription("spx-resource-not-found"),
+					Message:         `animation resource "roll-in" not found in sprite "MySprite"`,
 					Range: Range{
 						Start: Position{Line: 3, Character: 9},
 						End:   Position{Line: 3, Character: 18},
 					},
+					RelatedInformation: []DiagnosticRelatedInformation{{
+						Location: Location{URI: "file:///assets/sprites/MySprite/index.json"},
+						Message:  `animation resources of sprite "MySprite" are looked up in assets/sprites/MySprite/index.json`,
+					}},
 				})
 			default:
 				assert.Empty(t, fullReport.Items)
@@ -580,10 +1157,10 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		report, err := s.workspaceDiagnostic(&WorkspaceDiagnosticParams{})
+		report, err := s.workspaceDiagnostic(context.Background(), &WorkspaceDiagnosticParams{})
 		require.NoError(t, err)
 		require.NotNil(t, report)
-		assert.Len(t, report.Items, 2)
+		assert.Len(t, report.Items, 3)
 		for _, item := range report.Items {
 			fullReport := item.Value.(WorkspaceFullDocumentDiagnosticReport)
 			assert.Equal(t, string(DiagnosticFull), fullReport.Kind)
@@ -591,28 +1168,42 @@ onStart => {
 			case "file:///MySprite.spx":
 				require.Len(t, fullReport.Items, 3)
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  "widget resource name cannot be empty",
+					Severity:        SeverityError,
+					Code:            "spx-resource-name-empty",
+					CodeDescription: diagnosticCodeDescription("spx-resource-name-empty"),
+					Message:         "widget resource name cannot be empty",
 					Range: Range{
 						Start: Position{Line: 5, Character: 20},
 						End:   Position{Line: 5, Character: 22},
 					},
 				})
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  `widget resource "ConstWidgetName" not found`,
+					Severity:        SeverityError,
+					Code:            "spx-resource-not-found",
+					CodeDescription: diagnosticCodeDescription("spx-resource-not-found"),
+					Message:         `widget resource "ConstWidgetName" not found`,
 					Range: Range{
 						Start: Position{Line: 6, Character: 20},
 						End:   Position{Line: 6, Character: 35},
 					},
+					RelatedInformation: []DiagnosticRelatedInformation{{
+						Location: Location{URI: "file:///assets/index.json"},
+						Message:  "widget resources are looked up in assets/index.json",
+					}},
 				})
 				assert.Contains(t, fullReport.Items, Diagnostic{
-					Severity: SeverityError,
-					Message:  `widget resource "LiteralWidgetName" not found`,
+					Severity:        SeverityError,
+					Code:            "spx-resource-not-found",
+					CodeDescription: diagnosticCodeDescription("spx-resource-not-found"),
+					Message:         `widget resource "LiteralWidgetName" not found`,
 					Range: Range{
 						Start: Position{Line: 7, Character: 20},
 						End:   Position{Line: 7, Character: 39},
 					},
+					RelatedInformation: []DiagnosticRelatedInformation{{
+						Location: Location{URI: "file:///assets/index.json"},
+						Message:  "widget resources are looked up in assets/index.json",
+					}},
 				})
 			default:
 				assert.Empty(t, fullReport.Items)
@@ -620,6 +1211,40 @@ onStart => {
 		}
 	})
 
+	t.Run("WidgetResourceTypeMismatch", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx": []byte(`
+onStart => {
+	getWidget Monitor, "widget1"
+	getWidget Monitor, "widget2"
+}
+`),
+			"assets/index.json":                  []byte(`{"zorder":["MySprite",{"name":"widget1","type":"monitor"},{"name":"widget2","type":"measure"}]}`),
+			"assets/sprites/MySprite/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		report, err := s.textDocumentDiagnostic(context.Background(), &DocumentDiagnosticParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, report)
+		fullReport := report.Value.(RelatedFullDocumentDiagnosticReport)
+		assert.Equal(t, []Diagnostic{{
+			Severity:        SeverityError,
+			Code:            "spx-widget-type-mismatch",
+			CodeDescription: diagnosticCodeDescription("spx-widget-type-mismatch"),
+			Message:         `widget resource "widget2" has unsupported type "measure"`,
+			Range: Range{
+				Start: Position{Line: 3, Character: 20},
+				End:   Position{Line: 3, Character: 29},
+			},
+		}}, fullReport.Items)
+	})
+
 	t.Run("WithNonBasicTypeAliases", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.spx": []byte(`
@@ -637,10 +1262,10 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		report, err := s.workspaceDiagnostic(&WorkspaceDiagnosticParams{})
+		report, err := s.workspaceDiagnostic(context.Background(), &WorkspaceDiagnosticParams{})
 		require.NoError(t, err)
 		require.NotNil(t, report)
-		assert.Len(t, report.Items, 2)
+		assert.Len(t, report.Items, 4)
 		for _, item := range report.Items {
 			fullReport := item.Value.(WorkspaceFullDocumentDiagnosticReport)
 			assert.Equal(t, string(DiagnosticFull), fullReport.Kind)
@@ -661,10 +1286,10 @@ run "assets", {Title: "My Game"}
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		report, err := s.workspaceDiagnostic(&WorkspaceDiagnosticParams{})
+		report, err := s.workspaceDiagnostic(context.Background(), &WorkspaceDiagnosticParams{})
 		require.NoError(t, err)
 		require.NotNil(t, report)
-		assert.Len(t, report.Items, 1)
+		assert.Len(t, report.Items, 2)
 		for _, item := range report.Items {
 			fullReport := item.Value.(WorkspaceFullDocumentDiagnosticReport)
 			assert.Equal(t, string(DiagnosticFull), fullReport.Kind)
@@ -688,10 +1313,10 @@ run "assets", {Title: "My Game"}
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		report, err := s.workspaceDiagnostic(&WorkspaceDiagnosticParams{})
+		report, err := s.workspaceDiagnostic(context.Background(), &WorkspaceDiagnosticParams{})
 		require.NoError(t, err)
 		require.NotNil(t, report)
-		assert.Len(t, report.Items, 2)
+		assert.Len(t, report.Items, 4)
 		for _, item := range report.Items {
 			fullReport := item.Value.(WorkspaceFullDocumentDiagnosticReport)
 			assert.Equal(t, string(DiagnosticFull), fullReport.Kind)
@@ -699,3 +1324,186 @@ run "assets", {Title: "My Game"}
 		}
 	})
 }
+
+func TestServerSpxDiagnoseWorkspace(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var x int
+play "Missing"
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx":                       []byte(`onStart => { echo undefinedVar }`),
+			"assets/index.json":                  []byte(`{}`),
+			"assets/sprites/MySprite/index.json": []byte(`{"costumes": 1}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		result, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{Command: "spx.diagnoseWorkspace"})
+		require.NoError(t, err)
+		require.IsType(t, &SpxWorkspaceDiagnosis{}, result)
+		diagnosis := result.(*SpxWorkspaceDiagnosis)
+		assert.Equal(t, 4, diagnosis.ErrorCount)
+		assert.Equal(t, 1, diagnosis.WarningCount)
+
+		var (
+			uris      []DocumentURI
+			resources []DocumentURI
+		)
+		codes := make(map[DocumentURI][]any)
+		for _, file := range diagnosis.Files {
+			uris = append(uris, file.URI)
+			if file.Resource {
+				resources = append(resources, file.URI)
+			}
+			for _, diag := range file.Diagnostics {
+				if diag.Code != nil {
+					codes[file.URI] = append(codes[file.URI], diag.Code)
+				}
+			}
+		}
+		assert.Equal(t, []DocumentURI{
+			"file:///MySprite.spx",
+			"file:///assets/index.json",
+			"file:///assets/sprites/MySprite/index.json",
+			"file:///main.spx",
+		}, uris)
+		assert.Equal(t, []DocumentURI{
+			"file:///assets/index.json",
+			"file:///assets/sprites/MySprite/index.json",
+		}, resources)
+		assert.Equal(t, map[DocumentURI][]any{
+			"file:///assets/sprites/MySprite/index.json": {diagnosticCodeSpxResourceMetadataInvalid},
+			"file:///main.spx":                           {diagnosticCodeSpxResourceSetInvalid, diagnosticCodeSpxResourceNotFound},
+		}, codes)
+		assert.NotNil(t, diagnosis.Files[1].Diagnostics)
+	})
+
+	t.Run("NoMainSpxFile", func(t *testing.T) {
+		m := map[string][]byte{}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := s.spxDiagnoseWorkspace(context.Background())
+		require.ErrorIs(t, err, errNoMainSpxFile)
+	})
+}
+
+func TestDiagnosticCodeDescription(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		desc := diagnosticCodeDescription(diagnosticCodeSpxResourceNotFound)
+		require.NotNil(t, desc)
+		assert.Equal(t, URI(diagnosticDocsURL+"#spx-resource-not-found"), desc.Href)
+	})
+
+	t.Run("Undocumented", func(t *testing.T) {
+		assert.Nil(t, diagnosticCodeDescription("loopregistration"))
+	})
+
+	t.Run("Documented", func(t *testing.T) {
+		doc, err := os.ReadFile("../../docs/diagnostics.md")
+		require.NoError(t, err)
+		for code := range documentedDiagnosticCodes {
+			assert.Contains(t, string(doc), "\n## "+code+"\n", code)
+		}
+	})
+}
+
+func TestServerSpxRunAnalyzers(t *testing.T) {
+	m := map[string][]byte{
+		"main.spx": []byte(`
+var (
+	MySprite MySprite
+)
+play "Missing"
+run "assets", {Title: "My Game"}
+`),
+		"MySprite.spx": []byte(`
+onStart => {
+	forever => {
+		turn 1
+	}
+	say "Hi"
+}
+`),
+		"assets/index.json":                  []byte(`{"zorder":["MySprite"]}`),
+		"assets/sprites/MySprite/index.json": []byte(`{}`),
+	}
+	runAnalyzers := func(s *Server, analyzers ...string) (any, error) {
+		arg, err := json.Marshal(SpxRunAnalyzersParams{Analyzers: analyzers})
+		require.NoError(t, err)
+		return s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{
+			Command:   "spx.runAnalyzers",
+			Arguments: []json.RawMessage{arg},
+		})
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		result, err := runAnalyzers(s, "unreachable")
+		require.NoError(t, err)
+		assert.Equal(t, &SpxWorkspaceDiagnosis{
+			Files: []SpxFileDiagnosis{
+				{
+					URI: "file:///MySprite.spx",
+					Diagnostics: []Diagnostic{
+						{
+							Severity: SeverityWarning,
+							Source:   "unreachable",
+							Message:  "unreachable code",
+							Range: Range{
+								Start: Position{Line: 5, Character: 1},
+								End:   Position{Line: 5, Character: 9},
+							},
+							Tags: []DiagnosticTag{Unnecessary},
+						},
+					},
+				},
+				{
+					URI:         "file:///main.spx",
+					Diagnostics: []Diagnostic{},
+				},
+			},
+			WarningCount: 1,
+		}, result)
+	})
+
+	t.Run("DisabledAnalyzer", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, fileMapGetter(m), &MockScheduler{})
+		require.NoError(t, s.didChangeConfiguration(&DidChangeConfigurationParams{
+			Settings: map[string]any{
+				"analyzers":        map[string]any{"unreachable": false},
+				"analyzerSeverity": map[string]any{"unreachable": "error"},
+			},
+		}))
+
+		result, err := runAnalyzers(s, "unreachable", "unreachable")
+		require.NoError(t, err)
+		diagnosis := result.(*SpxWorkspaceDiagnosis)
+		assert.Equal(t, 1, diagnosis.ErrorCount)
+		assert.Zero(t, diagnosis.WarningCount)
+		require.Len(t, diagnosis.Files[0].Diagnostics, 1)
+		assert.Equal(t, SeverityError, diagnosis.Files[0].Diagnostics[0].Severity)
+	})
+
+	t.Run("UnknownAnalyzer", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := runAnalyzers(s, "unreachable", "nonexistent")
+		assert.EqualError(t, err, "unknown analyzer: nonexistent")
+	})
+
+	t.Run("NoAnalyzers", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := runAnalyzers(s)
+		assert.EqualError(t, err, "no analyzers to run")
+	})
+
+	t.Run("WrongArgumentCount", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{Command: "spx.runAnalyzers"})
+		assert.EqualError(t, err, "command spx.runAnalyzers expects exactly 1 argument, got 0")
+	})
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// lineDiffTextEdits returns the text edits that turn original into modified,
+// one for each run of changed lines. Unchanged lines are left out, so clients
+// keep cursors, selections and folded regions outside the changes intact.
+func lineDiffTextEdits(original, modified string) []TextEdit {
+	a := splitLines(original)
+	b := splitLines(modified)
+
+	// lineStart returns the position of the start of the i-th line in
+	// original, which is right after its last character if there are no
+	// more lines.
+	lineStart := func(i int) Position {
+		if i == len(a) && i > 0 && !strings.HasSuffix(a[i-1], "\n") {
+			return Position{Line: uint32(i - 1), Character: uint32(UTF16Len(a[i-1]))}
+		}
+		return Position{Line: uint32(i)}
+	}
+
+	var edits []TextEdit
+	for _, h := range diffLines(a, b) {
+		edits = append(edits, TextEdit{
+			Range: Range{
+				Start: lineStart(h.aStart),
+				End:   lineStart(h.aEnd),
+			},
+			NewText: strings.Join(b[h.bStart:h.bEnd], ""),
+		})
+	}
+	return edits
+}
+
+// applyTextEdits returns content with the non-overlapping text edits applied.
+func applyTextEdits(content string, edits []TextEdit) string {
+	type offsetEdit struct {
+		start, end int
+		newText    string
+	}
+	b := []byte(content)
+	offsetEdits := make([]offsetEdit, 0, len(edits))
+	for _, edit := range edits {
+		start := PositionOffset(b, edit.Range.Start)
+		end := max(start, PositionOffset(b, edit.Range.End))
+		offsetEdits = append(offsetEdits, offsetEdit{start, end, edit.NewText})
+	}
+	slices.SortStableFunc(offsetEdits, func(a, b offsetEdit) int {
+		return cmp.Compare(a.start, b.start)
+	})
+
+	var sb strings.Builder
+	last := 0
+	for _, edit := range offsetEdits {
+		start := max(edit.start, last)
+		sb.WriteString(content[last:start])
+		sb.WriteString(edit.newText)
+		last = max(edit.end, start)
+	}
+	sb.WriteString(content[last:])
+	return sb.String()
+}
+
+// splitLines splits s into lines, each with its trailing newline if any.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineDiffHunk is a run of changed lines, where the lines a[aStart:aEnd] are
+// replaced with the lines b[bStart:bEnd].
+type lineDiffHunk struct {
+	aStart, aEnd int
+	bStart, bEnd int
+}
+
+// maxDiffEditDistance is the maximum edit distance for which [diffLines]
+// finds a shortest edit script. Memory used by the search grows with the
+// square of the edit distance, so beyond it the changed lines are replaced as
+// a whole, which is the case when a file is reindented entirely.
+const maxDiffEditDistance = 1000
+
+// diffLines returns the hunks of a shortest edit script that turns the lines
+// a into the lines b, using the Myers diff algorithm. If the edit distance
+// exceeds [maxDiffEditDistance], it returns a single hunk covering all lines
+// between the common prefix and suffix instead.
+func diffLines(a, b []string) []lineDiffHunk {
+	// Trim the common prefix and suffix, which are usually most of the lines.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	// Find the furthest reaching paths, keeping the diagonals -d..d of the
+	// state before each step d to backtrack the shortest edit script.
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	var trace [][]int
+	found := false
+	for d := 0; d <= n+m && !found; d++ {
+		if d > maxDiffEditDistance {
+			return []lineDiffHunk{{
+				aStart: prefix,
+				aEnd:   prefix + n,
+				bStart: prefix,
+				bEnd:   prefix + m,
+			}}
+		}
+		trace = append(trace, slices.Clone(v[offset-d:offset+d+1]))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	// Backtrack to mark the lines that are kept in both a and b.
+	keptA := make([]bool, n)
+	keptB := make([]bool, m)
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		var prevX, prevY int
+		if d > 0 {
+			prev := trace[d] // Diagonal k is at prev[k+d].
+			k := x - y
+			var prevK int
+			if k == -d || (k != d && prev[k-1+d] < prev[k+1+d]) {
+				prevK = k + 1
+			} else {
+				prevK = k - 1
+			}
+			prevX = prev[prevK+d]
+			prevY = prevX - prevK
+		}
+		for x > prevX && y > prevY {
+			x--
+			y--
+			keptA[x], keptB[y] = true, true
+		}
+		x, y = prevX, prevY
+	}
+
+	// Group the lines that are not kept into hunks.
+	var hunks []lineDiffHunk
+	i, j := 0, 0
+	for i < n || j < m {
+		if i < n && j < m && keptA[i] && keptB[j] {
+			i++
+			j++
+			continue
+		}
+		h := lineDiffHunk{aStart: i, bStart: j}
+		for i < n && !keptA[i] {
+			i++
+		}
+		for j < m && !keptB[j] {
+			j++
+		}
+		h.aEnd, h.bEnd = i, j
+		h.aStart, h.aEnd = h.aStart+prefix, h.aEnd+prefix
+		h.bStart, h.bEnd = h.bStart+prefix, h.bEnd+prefix
+		hunks = append(hunks, h)
+	}
+	return hunks
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineDiffTextEdits(t *testing.T) {
+	t.Run("NoChanges", func(t *testing.T) {
+		assert.Nil(t, lineDiffTextEdits("a\nb\n", "a\nb\n"))
+	})
+
+	t.Run("ReplaceLine", func(t *testing.T) {
+		edits := lineDiffTextEdits("a\nb\nc\n", "a\nB\nc\n")
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 1, Character: 0},
+					End:   Position{Line: 2, Character: 0},
+				},
+				NewText: "B\n",
+			},
+		}, edits)
+	})
+
+	t.Run("InsertAndDeleteLines", func(t *testing.T) {
+		edits := lineDiffTextEdits("a\nb\nc\nd\n", "a\nx\nb\nd\n")
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 1, Character: 0},
+					End:   Position{Line: 1, Character: 0},
+				},
+				NewText: "x\n",
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 2, Character: 0},
+					End:   Position{Line: 3, Character: 0},
+				},
+				NewText: "",
+			},
+		}, edits)
+	})
+
+	t.Run("MissingTrailingNewline", func(t *testing.T) {
+		edits := lineDiffTextEdits("a\nb", "a\nb\n")
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 1, Character: 0},
+					End:   Position{Line: 1, Character: 1},
+				},
+				NewText: "b\n",
+			},
+		}, edits)
+	})
+
+	t.Run("EmptyOriginal", func(t *testing.T) {
+		edits := lineDiffTextEdits("", "a\n")
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 0, Character: 0},
+					End:   Position{Line: 0, Character: 0},
+				},
+				NewText: "a\n",
+			},
+		}, edits)
+	})
+
+	t.Run("EmptyModified", func(t *testing.T) {
+		edits := lineDiffTextEdits(" ", "")
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 0, Character: 0},
+					End:   Position{Line: 0, Character: 1},
+				},
+				NewText: "",
+			},
+		}, edits)
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		for _, tt := range []struct {
+			original, modified string
+		}{
+			{"a\nb\nc\n", "c\nb\na\n"},
+			{"x\ny\nz", "y\nz\nw\n"},
+			{"1\n2\n3\n4\n5\n", "1\n3\n5\n6\n"},
+			{"", ""},
+			{"a\n", ""},
+		} {
+			edits := lineDiffTextEdits(tt.original, tt.modified)
+			assert.Equal(t, tt.modified, applyTextEdits(tt.original, edits), "original: %q", tt.original)
+		}
+	})
+
+	t.Run("LargeReindentedFile", func(t *testing.T) {
+		var original, modified strings.Builder
+		original.WriteString("onStart => {\n")
+		modified.WriteString("onStart => {\n")
+		for i := range 5000 {
+			fmt.Fprintf(&original, "\tsay \"%d\"\n", i)
+			fmt.Fprintf(&modified, "    say \"%d\"\n", i)
+		}
+		original.WriteString("}\n")
+		modified.WriteString("}\n")
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		edits := lineDiffTextEdits(original.String(), modified.String())
+		runtime.ReadMemStats(&after)
+		assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(64<<20))
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 1, Character: 0},
+					End:   Position{Line: 5001, Character: 0},
+				},
+				NewText: strings.Join(splitLines(modified.String())[1:5001], ""),
+			},
+		}, edits)
+		assert.Equal(t, modified.String(), applyTextEdits(original.String(), edits))
+	})
+
+	t.Run("EditDistanceBelowCap", func(t *testing.T) {
+		var original, modified strings.Builder
+		for i := range 5000 {
+			fmt.Fprintf(&original, "line %d\n", i)
+			if i%20 == 0 {
+				fmt.Fprintf(&modified, "changed %d\n", i)
+			} else {
+				fmt.Fprintf(&modified, "line %d\n", i)
+			}
+		}
+
+		edits := lineDiffTextEdits(original.String(), modified.String())
+		assert.Len(t, edits, 250)
+		assert.Equal(t, modified.String(), applyTextEdits(original.String(), edits))
+	})
+}
+
+func TestApplyTextEdits(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		got := applyTextEdits("a\nb\nc\n", []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 2, Character: 0},
+					End:   Position{Line: 2, Character: 1},
+				},
+				NewText: "C",
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 0, Character: 1},
+					End:   Position{Line: 1, Character: 0},
+				},
+				NewText: "",
+			},
+		})
+		assert.Equal(t, "ab\nC\n", got)
+	})
+
+	t.Run("UTF16", func(t *testing.T) {
+		got := applyTextEdits("s := \"😀a\"\n", []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 0, Character: 8},
+					End:   Position{Line: 0, Character: 9},
+				},
+				NewText: "b",
+			},
+		})
+		assert.Equal(t, "s := \"😀b\"\n", got)
+	})
+
+	t.Run("Insert", func(t *testing.T) {
+		got := applyTextEdits("", []TextEdit{{NewText: "echo 1\n"}})
+		assert.Equal(t, "echo 1\n", got)
+	})
+}
@@ -2,15 +2,23 @@ package server
 
 import (
 	"cmp"
+	"context"
+	"path"
 	"slices"
+	"strings"
 
 	xgoast "github.com/goplus/xgo/ast"
+	"github.com/goplus/xgolsw/internal/vfs"
 	"github.com/goplus/xgolsw/xgo/xgoutil"
 )
 
 // See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification#textDocument_documentLink
-func (s *Server) textDocumentDocumentLink(params *DocumentLinkParams) ([]DocumentLink, error) {
-	result, spxFile, astFile, err := s.compileAndGetASTFileForDocumentURI(params.TextDocument.URI)
+func (s *Server) textDocumentDocumentLink(ctx context.Context, params *DocumentLinkParams) ([]DocumentLink, error) {
+	if filePath, err := s.fromDocumentURI(params.TextDocument.URI); err == nil && path.Base(filePath) == "index.json" {
+		return s.spxResourceMetadataDocumentLinks(ctx, filePath)
+	}
+
+	result, spxFile, astFile, err := s.compileAndGetASTFileForDocumentURI(ctx, params.TextDocument.URI)
 	if err != nil {
 		return nil, err
 	}
@@ -24,14 +32,22 @@ func (s *Server) textDocumentDocumentLink(params *DocumentLinkParams) ([]Documen
 		if xgoutil.NodeFilename(result.proj, spxResourceRef.Node) != spxFile {
 			continue
 		}
+		refRange := RangeForNode(result.proj, spxResourceRef.Node)
 		target := URI(spxResourceRef.ID.URI())
 		links = append(links, DocumentLink{
-			Range:  RangeForNode(result.proj, spxResourceRef.Node),
+			Range:  refRange,
 			Target: &target,
 			Data: SpxResourceRefDocumentLinkData{
 				Kind: spxResourceRef.Kind,
 			},
 		})
+		if assetPath := result.spxResourceAssetPath(spxResourceRef.ID); assetPath != "" {
+			assetTarget := URI(s.toDocumentURI(assetPath))
+			links = append(links, DocumentLink{
+				Range:  refRange,
+				Target: &assetTarget,
+			})
+		}
 	}
 
 	typeInfo, _ := result.proj.TypeInfo()
@@ -66,6 +82,107 @@ func (s *Server) textDocumentDocumentLink(params *DocumentLinkParams) ([]Documen
 	return links, nil
 }
 
+// spxResourceAssetPath returns the path of the asset of the spx resource
+// identified by id: the directory of a sprite, the audio file of a sound, the
+// image file of a backdrop or costume, or the image file of the first frame of
+// an animation. It returns an empty string if the resource has no existing
+// asset.
+func (r *compileResult) spxResourceAssetPath(id SpxResourceID) string {
+	rootDir := r.spxResourceRootDir
+	var assetPath string
+	switch id := id.(type) {
+	case SpxBackdropResourceID:
+		if backdrop := r.spxResourceSet.Backdrop(id.BackdropName); backdrop != nil && backdrop.Path != "" {
+			assetPath = path.Join(rootDir, backdrop.Path)
+		}
+	case SpxSoundResourceID:
+		if sound := r.spxResourceSet.Sound(id.SoundName); sound != nil && sound.Path != "" {
+			assetPath = path.Join(rootDir, "sounds", id.SoundName, sound.Path)
+		}
+	case SpxSpriteResourceID:
+		if r.spxResourceSet.Sprite(id.SpriteName) != nil {
+			return path.Join(rootDir, "sprites", id.SpriteName)
+		}
+	case SpxSpriteCostumeResourceID:
+		if sprite := r.spxResourceSet.Sprite(id.SpriteName); sprite != nil {
+			if costume := sprite.Costume(id.CostumeName); costume != nil && costume.Path != "" {
+				assetPath = path.Join(rootDir, "sprites", id.SpriteName, costume.Path)
+			}
+		}
+	case SpxSpriteAnimationResourceID:
+		if sprite := r.spxResourceSet.Sprite(id.SpriteName); sprite != nil {
+			if animation := sprite.Animation(id.AnimationName); animation != nil &&
+				animation.FromIndex != nil && *animation.FromIndex >= 0 && *animation.FromIndex < len(sprite.Costumes) {
+				if costume := sprite.Costumes[*animation.FromIndex]; costume.Path != "" {
+					assetPath = path.Join(rootDir, "sprites", id.SpriteName, costume.Path)
+				}
+			}
+		}
+	}
+	if assetPath == "" {
+		return ""
+	}
+	if _, ok := r.proj.File(assetPath); !ok {
+		return ""
+	}
+	return assetPath
+}
+
+// spxResourceMetadataDocumentLinks returns links for the asset paths in the
+// spx resource metadata file at filePath, which point to the asset files. It
+// returns nil if the file is not a metadata file under the resource root
+// directory.
+func (s *Server) spxResourceMetadataDocumentLinks(ctx context.Context, filePath string) ([]DocumentLink, error) {
+	result, err := s.compileWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rel, ok := strings.CutPrefix(filePath, result.spxResourceRootDir+"/")
+	if !ok {
+		return nil, nil
+	}
+	var pathPattern []string
+	switch parts := strings.Split(rel, "/"); {
+	case len(parts) == 1:
+		pathPattern = []string{"backdrops", "[]", "path"}
+	case len(parts) == 3 && parts[0] == "sprites":
+		pathPattern = []string{"costumes", "[]", "path"}
+	case len(parts) == 3 && parts[0] == "sounds":
+		pathPattern = []string{"path"}
+	default:
+		return nil, nil
+	}
+
+	content, err := vfs.ReadFile(result.proj, filePath)
+	if err != nil {
+		return nil, nil
+	}
+	tokens, err := jsonStringTokens(content)
+	if err != nil {
+		return nil, nil
+	}
+	var links []DocumentLink
+	for _, tok := range tokens {
+		if tok.isKey || tok.value == "" || !tok.pathMatches(pathPattern...) {
+			continue
+		}
+		assetPath := path.Join(path.Dir(filePath), tok.value)
+		if _, ok := result.proj.File(assetPath); !ok {
+			continue
+		}
+		target := URI(s.toDocumentURI(assetPath))
+		links = append(links, DocumentLink{
+			Range: Range{
+				Start: OffsetPosition(content, tok.start+1),
+				End:   OffsetPosition(content, tok.end-1),
+			},
+			Target: &target,
+		})
+	}
+	sortDocumentLinks(links)
+	return links, nil
+}
+
 // sortDocumentLinks sorts the given document links in a stable manner.
 func sortDocumentLinks(links []DocumentLink) {
 	slices.SortFunc(links, func(a, b DocumentLink) int {
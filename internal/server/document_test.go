@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,11 +37,11 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		linksForMainSpx, err := s.textDocumentDocumentLink(&DocumentLinkParams{
+		linksForMainSpx, err := s.textDocumentDocumentLink(context.Background(), &DocumentLinkParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 		})
 		require.NoError(t, err)
-		require.Len(t, linksForMainSpx, 14)
+		require.Len(t, linksForMainSpx, 15)
 		assert.Contains(t, linksForMainSpx, DocumentLink{
 			Range: Range{
 				Start: Position{Line: 1, Character: 6},
@@ -152,11 +153,11 @@ onStart => {
 			Target: toURI("xgo:github.com/goplus/spx/v2?Game.Title"),
 		})
 
-		linksForMySpriteSpx, err := s.textDocumentDocumentLink(&DocumentLinkParams{
+		linksForMySpriteSpx, err := s.textDocumentDocumentLink(context.Background(), &DocumentLinkParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
 		})
 		require.NoError(t, err)
-		require.Len(t, linksForMySpriteSpx, 21)
+		require.Len(t, linksForMySpriteSpx, 25)
 		assert.Contains(t, linksForMySpriteSpx, DocumentLink{
 			Range: Range{
 				Start: Position{Line: 3, Character: 12},
@@ -249,13 +250,122 @@ onStart => {
 		})
 	})
 
+	t.Run("AssetLinks", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	MySound  Sound
+	MySprite Sprite
+)
+onBackdrop "backdrop1", func() {}
+MySprite.setCostume "costume1"
+MySprite.animate "anim1"
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx":                         []byte(``),
+			"assets/index.json":                    []byte(`{"backdrops":[{"name":"backdrop1","path":"backdrop1.png"},{"name":"backdrop2","path":"missing.png"}]}`),
+			"assets/backdrop1.png":                 []byte(``),
+			"assets/sprites/MySprite/index.json":   []byte(`{"costumes":[{"name":"costume1","path":"costume1.png"}],"fAnimations":{"anim1":{"frameFrom":"costume1","frameTo":"costume1"}}}`),
+			"assets/sprites/MySprite/costume1.png": []byte(``),
+			"assets/sounds/MySound/index.json":     []byte(`{"path":"sound.wav"}`),
+			"assets/sounds/MySound/sound.wav":      []byte(``),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		links, err := s.textDocumentDocumentLink(context.Background(), &DocumentLinkParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		})
+		require.NoError(t, err)
+		for _, want := range []DocumentLink{
+			{
+				Range: Range{
+					Start: Position{Line: 2, Character: 1},
+					End:   Position{Line: 2, Character: 8},
+				},
+				Target: toURI("file:///assets/sounds/MySound/sound.wav"),
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 3, Character: 1},
+					End:   Position{Line: 3, Character: 9},
+				},
+				Target: toURI("file:///assets/sprites/MySprite"),
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 5, Character: 11},
+					End:   Position{Line: 5, Character: 22},
+				},
+				Target: toURI("file:///assets/backdrop1.png"),
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 6, Character: 20},
+					End:   Position{Line: 6, Character: 30},
+				},
+				Target: toURI("file:///assets/sprites/MySprite/costume1.png"),
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 7, Character: 17},
+					End:   Position{Line: 7, Character: 24},
+				},
+				Target: toURI("file:///assets/sprites/MySprite/costume1.png"),
+			},
+		} {
+			assert.Contains(t, links, want)
+		}
+
+		stageLinks, err := s.textDocumentDocumentLink(context.Background(), &DocumentLinkParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///assets/index.json"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []DocumentLink{{
+			Range: Range{
+				Start: Position{Line: 0, Character: 42},
+				End:   Position{Line: 0, Character: 55},
+			},
+			Target: toURI("file:///assets/backdrop1.png"),
+		}}, stageLinks)
+
+		spriteLinks, err := s.textDocumentDocumentLink(context.Background(), &DocumentLinkParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///assets/sprites/MySprite/index.json"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []DocumentLink{{
+			Range: Range{
+				Start: Position{Line: 0, Character: 40},
+				End:   Position{Line: 0, Character: 52},
+			},
+			Target: toURI("file:///assets/sprites/MySprite/costume1.png"),
+		}}, spriteLinks)
+
+		soundLinks, err := s.textDocumentDocumentLink(context.Background(), &DocumentLinkParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///assets/sounds/MySound/index.json"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []DocumentLink{{
+			Range: Range{
+				Start: Position{Line: 0, Character: 9},
+				End:   Position{Line: 0, Character: 18},
+			},
+			Target: toURI("file:///assets/sounds/MySound/sound.wav"),
+		}}, soundLinks)
+
+		otherLinks, err := s.textDocumentDocumentLink(context.Background(), &DocumentLinkParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///other/index.json"},
+		})
+		require.NoError(t, err)
+		assert.Nil(t, otherLinks)
+	})
+
 	t.Run("NonSpxFile", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.xgo": []byte(`echo "Hello, XGo!"`),
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		links, err := s.textDocumentDocumentLink(&DocumentLinkParams{
+		links, err := s.textDocumentDocumentLink(context.Background(), &DocumentLinkParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///main.xgo"},
 		})
 		assert.EqualError(t, err, `file "main.xgo" does not have .spx extension`)
@@ -265,7 +375,7 @@ onStart => {
 	t.Run("FileNotFound", func(t *testing.T) {
 		s := New(newMapFSWithoutModTime(map[string][]byte{}), nil, fileMapGetter(map[string][]byte{}), &MockScheduler{})
 
-		links, err := s.textDocumentDocumentLink(&DocumentLinkParams{
+		links, err := s.textDocumentDocumentLink(context.Background(), &DocumentLinkParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///notexist.spx"},
 		})
 		assert.ErrorIs(t, err, errNoMainSpxFile)
@@ -284,7 +394,7 @@ var (
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		links, err := s.textDocumentDocumentLink(&DocumentLinkParams{
+		links, err := s.textDocumentDocumentLink(context.Background(), &DocumentLinkParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 		})
 		require.NoError(t, err)
@@ -328,11 +438,11 @@ var (
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		links, err := s.textDocumentDocumentLink(&DocumentLinkParams{
+		links, err := s.textDocumentDocumentLink(context.Background(), &DocumentLinkParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 		})
 		require.NoError(t, err)
-		require.Len(t, links, 3)
+		require.Len(t, links, 4)
 		assert.Contains(t, links, DocumentLink{
 			Range: Range{
 				Start: Position{Line: 2, Character: 1},
@@ -375,11 +485,11 @@ var (
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		links, err := s.textDocumentDocumentLink(&DocumentLinkParams{
+		links, err := s.textDocumentDocumentLink(context.Background(), &DocumentLinkParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 		})
 		require.NoError(t, err)
-		require.Len(t, links, 3)
+		require.Len(t, links, 4)
 		assert.Contains(t, links, DocumentLink{
 			Range: Range{
 				Start: Position{Line: 2, Character: 1},
@@ -421,7 +531,7 @@ var (
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		links, err := s.textDocumentDocumentLink(&DocumentLinkParams{
+		links, err := s.textDocumentDocumentLink(context.Background(), &DocumentLinkParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 		})
 		require.NoError(t, err)
@@ -462,7 +572,7 @@ var (
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		links, err := s.textDocumentDocumentLink(&DocumentLinkParams{
+		links, err := s.textDocumentDocumentLink(context.Background(), &DocumentLinkParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 		})
 		require.NoError(t, err)
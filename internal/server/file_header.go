@@ -0,0 +1,95 @@
+package server
+
+import (
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	xgoast "github.com/goplus/xgo/ast"
+	"github.com/goplus/xgolsw/xgo"
+	"github.com/goplus/xgolsw/xgo/xgoutil"
+)
+
+// codeActionKindSourceAddFileHeader is the kind of code actions that insert
+// the configured file header.
+const codeActionKindSourceAddFileHeader = Source + ".addFileHeader"
+
+// isDirectiveComment reports whether the comment is a directive, such as
+// `//go:build ignore`.
+func isDirectiveComment(c *xgoast.Comment) bool {
+	for _, prefix := range []string{"//go:", "//xgo:", "//gop:", "//line ", "// +build"} {
+		if strings.HasPrefix(c.Text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// spxFileHeader returns the comment group at the start of astFile if it is a
+// file header, which consists of directives or a license header.
+func spxFileHeader(proj *xgo.Project, astFile *xgoast.File) *xgoast.CommentGroup {
+	if len(astFile.Comments) == 0 {
+		return nil
+	}
+	cg := astFile.Comments[0]
+	tokenFile := xgoutil.NodeTokenFile(proj, cg)
+	if tokenFile == nil || strings.TrimSpace(string(astFile.Code[:tokenFile.Offset(cg.Pos())])) != "" {
+		return nil
+	}
+	for _, c := range cg.List {
+		if isDirectiveComment(c) || strings.Contains(c.Text, "Copyright") || strings.Contains(c.Text, "SPDX-License-Identifier") {
+			return cg
+		}
+	}
+	return nil
+}
+
+// renderFileHeader renders the file header template for the spx source file,
+// with `${year}` replaced by the current year and `${file}` by the file name.
+// Each line of the template is turned into a line comment.
+func renderFileHeader(template, spxFile string) string {
+	template = strings.NewReplacer(
+		"${year}", strconv.Itoa(time.Now().Year()),
+		"${file}", path.Base(spxFile),
+	).Replace(strings.TrimRight(template, "\n"))
+
+	var sb strings.Builder
+	for line := range strings.Lines(template) {
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			sb.WriteString("//\n")
+		} else {
+			sb.WriteString("// " + line + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// spxAddFileHeaderCodeActions returns the source action that inserts the file
+// header configured in settings at the start of the spx source file. It is
+// only offered if the file has no file header yet.
+func (s *Server) spxAddFileHeaderCodeActions(result *compileResult, spxFile string, astFile *xgoast.File, documentURI DocumentURI) []CodeAction {
+	template := s.getSettings().Formatting.FileHeader
+	if template == "" {
+		return nil
+	}
+	header := renderFileHeader(template, spxFile)
+	if spxFileHeader(result.proj, astFile) != nil || strings.HasPrefix(string(astFile.Code), header) {
+		return nil
+	}
+
+	newText := header
+	if strings.TrimSpace(string(astFile.Code)) != "" {
+		newText += "\n"
+	}
+	return []CodeAction{{
+		Title: "Add file header",
+		Kind:  codeActionKindSourceAddFileHeader,
+		Edit: &WorkspaceEdit{
+			Changes: map[DocumentURI][]TextEdit{
+				documentURI: {{NewText: newText}},
+			},
+		},
+	}}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/goplus/xgolsw/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderFileHeader(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		header := renderFileHeader("Copyright ${year} The Authors.\n\n${file} is part of the game.\n", "sprites/Hero.spx")
+		assert.Equal(t, "// Copyright "+strconv.Itoa(time.Now().Year())+" The Authors.\n//\n// Hero.spx is part of the game.\n", header)
+	})
+
+	t.Run("SingleLine", func(t *testing.T) {
+		assert.Equal(t, "// SPDX-License-Identifier: MIT\n", renderFileHeader("SPDX-License-Identifier: MIT", "main.spx"))
+	})
+}
+
+func TestServerSpxAddFileHeaderCodeActions(t *testing.T) {
+	newServer := func(t *testing.T, mainSpx, fileHeader string) *Server {
+		m := map[string][]byte{
+			"main.spx":          []byte(mainSpx),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, fileMapGetter(m), &MockScheduler{})
+		require.NoError(t, s.didChangeConfiguration(&DidChangeConfigurationParams{
+			Settings: map[string]any{"formatting": map[string]any{"fileHeader": fileHeader}},
+		}))
+		return s
+	}
+	addFileHeader := func(t *testing.T, s *Server) []CodeAction {
+		codeActions, err := s.textDocumentCodeAction(context.Background(), &CodeActionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Context:      protocol.CodeActionContext{Only: []CodeActionKind{Source}},
+		})
+		require.NoError(t, err)
+		return codeActions
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		s := newServer(t, `onStart => {
+	println "a"
+}
+`, "SPDX-License-Identifier: MIT")
+
+		codeActions := addFileHeader(t, s)
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, CodeAction{
+			Title: "Add file header",
+			Kind:  codeActionKindSourceAddFileHeader,
+			Edit: &WorkspaceEdit{
+				Changes: map[DocumentURI][]TextEdit{
+					"file:///main.spx": {
+						{NewText: "// SPDX-License-Identifier: MIT\n\n"},
+					},
+				},
+			},
+		}, codeActions[0])
+	})
+
+	t.Run("EmptyFile", func(t *testing.T) {
+		s := newServer(t, ``, "SPDX-License-Identifier: MIT")
+
+		codeActions := addFileHeader(t, s)
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, []TextEdit{
+			{NewText: "// SPDX-License-Identifier: MIT\n"},
+		}, codeActions[0].Edit.Changes["file:///main.spx"])
+	})
+
+	t.Run("ExistingFileHeader", func(t *testing.T) {
+		s := newServer(t, `// Copyright 2025 The XGo Authors.
+
+onStart => {
+	println "a"
+}
+`, "SPDX-License-Identifier: MIT")
+
+		assert.Empty(t, addFileHeader(t, s))
+	})
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		s := newServer(t, `onStart => {
+	println "a"
+}
+`, "")
+
+		assert.Empty(t, addFileHeader(t, s))
+	})
+}
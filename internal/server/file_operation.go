@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/goplus/xgolsw/internal/vfs"
+	"github.com/goplus/xgolsw/protocol"
+	"github.com/goplus/xgolsw/xgo"
+)
+
+// fileOperationRegistrationOptions is the registration options for file
+// operation notifications the server is interested in. It matches all files
+// and folders in the workspace so that both spx source files and asset files
+// are tracked.
+var fileOperationRegistrationOptions = protocol.FileOperationRegistrationOptions{
+	Filters: []protocol.FileOperationFilter{{
+		Scheme:  "file",
+		Pattern: protocol.FileOperationPattern{Glob: "**/*"},
+	}},
+}
+
+// See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#workspace_didCreateFiles
+func (s *Server) didCreateFiles(params *CreateFilesParams) error {
+	proj := s.getProj()
+
+	// The server has no direct access to the file system, so the content of
+	// created files can only be read via the file map getter. Files whose
+	// content is unknown are left out until the client provides it, rather
+	// than being added as empty files.
+	var files map[string]*vfs.MapFile
+	if s.fileMapGetter != nil {
+		files = s.fileMapGetter()
+	}
+
+	createdFiles := make(map[string]*vfs.MapFile, len(params.Files))
+	for _, file := range params.Files {
+		filePath, err := s.fromDocumentURI(DocumentURI(file.URI))
+		if err != nil {
+			return err
+		}
+		if _, ok := proj.File(filePath); ok {
+			// The file content was already provided by the client, for
+			// example via textDocument/didOpen.
+			continue
+		}
+		if mapFile, ok := files[filePath]; ok {
+			createdFiles[filePath] = mapFile
+		}
+	}
+	if len(createdFiles) == 0 {
+		return nil
+	}
+	affectsSpxResources := s.affectsSpxResources(proj, slices.Collect(maps.Keys(createdFiles)))
+	for filePath, mapFile := range createdFiles {
+		proj.PutFile(filePath, mapFile)
+	}
+
+	s.publishDiagnosticsForSpxFiles()
+	if affectsSpxResources {
+		s.refreshDiagnostics()
+	}
+	return nil
+}
+
+// See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#workspace_didDeleteFiles
+func (s *Server) didDeleteFiles(params *DeleteFilesParams) error {
+	proj := s.getProj()
+	filePaths := make([]string, 0, len(params.Files))
+	for _, file := range params.Files {
+		filePath, err := s.fromDocumentURI(DocumentURI(file.URI))
+		if err != nil {
+			return err
+		}
+		filePaths = append(filePaths, filePath)
+	}
+	affectsSpxResources := s.affectsSpxResources(proj, filePaths)
+
+	var deletedSpxFiles []string
+	for _, filePath := range filePaths {
+		deletedSpxFiles = append(deletedSpxFiles, deleteFileOrDir(proj, filePath)...)
+	}
+	if affectsSpxResources {
+		s.refreshDiagnostics()
+	}
+	return s.refreshDiagnosticsAfterDeletion(deletedSpxFiles)
+}
+
+// See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#workspace_didChangeWatchedFiles
+func (s *Server) didChangeWatchedFiles(params *DidChangeWatchedFilesParams) error {
+	proj := s.getProj()
+
+	// The server has no direct access to the file system, so the content of
+	// created or changed files can only be refreshed via the file map getter.
+	var files map[string]*vfs.MapFile
+	if s.fileMapGetter != nil {
+		files = s.fileMapGetter()
+	}
+
+	filePaths := make([]string, 0, len(params.Changes))
+	for _, event := range params.Changes {
+		filePath, err := s.fromDocumentURI(event.URI)
+		if err != nil {
+			return err
+		}
+		filePaths = append(filePaths, filePath)
+	}
+	affectsSpxResources := s.affectsSpxResources(proj, filePaths)
+
+	var deletedSpxFiles []string
+	for i, event := range params.Changes {
+		filePath := filePaths[i]
+		switch event.Type {
+		case protocol.Created, protocol.Changed:
+			file, ok := files[filePath]
+			if !ok {
+				file, ok = proj.File(filePath)
+			}
+			if !ok {
+				continue // Content is unknown, or nothing to invalidate.
+			}
+
+			// Putting the file back invalidates all caches depending on it,
+			// including the ones of the spx resource set.
+			proj.PutFile(filePath, file)
+		case protocol.Deleted:
+			deletedSpxFiles = append(deletedSpxFiles, deleteFileOrDir(proj, filePath)...)
+		}
+	}
+	if affectsSpxResources {
+		s.refreshDiagnostics()
+	}
+	return s.refreshDiagnosticsAfterDeletion(deletedSpxFiles)
+}
+
+// deleteFileOrDir deletes the file at filePath from proj. If filePath refers
+// to a directory, all files under it are deleted instead. It returns the paths
+// of the deleted spx files.
+func deleteFileOrDir(proj *xgo.Project, filePath string) (deletedSpxFiles []string) {
+	dirPrefix := strings.TrimSuffix(filePath, "/") + "/"
+	for p := range proj.Files() {
+		if p != filePath && !strings.HasPrefix(p, dirPrefix) {
+			continue
+		}
+		if err := proj.DeleteFile(p); err != nil {
+			continue
+		}
+		if path.Ext(p) == ".spx" {
+			deletedSpxFiles = append(deletedSpxFiles, p)
+		}
+	}
+	return
+}
+
+// refreshDiagnosticsAfterDeletion clears diagnostics of the deleted spx files
+// and republishes diagnostics for the remaining ones.
+func (s *Server) refreshDiagnosticsAfterDeletion(deletedSpxFiles []string) error {
+	for _, spxFile := range deletedSpxFiles {
+		if err := s.publishDiagnostics(s.toDocumentURI(spxFile), nil); err != nil {
+			return err
+		}
+	}
+
+	s.publishDiagnosticsForSpxFiles()
+	return nil
+}
+
+// registerFileWatchers dynamically registers file system watchers for all
+// files in the workspace, so the client sends workspace/didChangeWatchedFiles
+// notifications for changes made outside the editor. It does nothing if the
+// client does not support dynamic registration of file watchers.
+func (s *Server) registerFileWatchers(ctx context.Context) error {
+	if !s.clientCapabilities.Workspace.DidChangeWatchedFiles.DynamicRegistration {
+		return nil
+	}
+	_, err := s.call(ctx, "client/registerCapability", &protocol.RegistrationParams{
+		Registrations: []protocol.Registration{{
+			ID:     "workspace/didChangeWatchedFiles",
+			Method: "workspace/didChangeWatchedFiles",
+			RegisterOptions: protocol.DidChangeWatchedFilesRegistrationOptions{
+				Watchers: []protocol.FileSystemWatcher{{
+					GlobPattern: protocol.GlobPattern{Value: protocol.Pattern("**/*")},
+				}},
+			},
+		}},
+	})
+	return err
+}
@@ -0,0 +1,315 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/goplus/xgolsw/jsonrpc2"
+	"github.com/goplus/xgolsw/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerDidCreateFiles(t *testing.T) {
+	t.Run("NewSpriteFile", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`var x = 100`),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, fileMapGetter(m), &MockScheduler{})
+
+		m["MySprite.spx"] = []byte(`echo "Hello"`)
+		err := s.didCreateFiles(&CreateFilesParams{
+			Files: []protocol.FileCreate{{URI: "file:///MySprite.spx"}},
+		})
+		require.NoError(t, err)
+
+		file, ok := s.getProj().File("MySprite.spx")
+		require.True(t, ok)
+		assert.Equal(t, `echo "Hello"`, string(file.Content))
+	})
+
+	t.Run("UnknownContent", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`var x = 100`),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, nil, &MockScheduler{})
+
+		err := s.didCreateFiles(&CreateFilesParams{
+			Files: []protocol.FileCreate{{URI: "file:///MySprite.spx"}},
+		})
+		require.NoError(t, err)
+
+		_, ok := s.getProj().File("MySprite.spx")
+		assert.False(t, ok)
+	})
+
+	t.Run("ExistingFile", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`var x = 100`),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, nil, &MockScheduler{})
+
+		err := s.didCreateFiles(&CreateFilesParams{
+			Files: []protocol.FileCreate{{URI: "file:///main.spx"}},
+		})
+		require.NoError(t, err)
+
+		file, ok := s.getProj().File("main.spx")
+		require.True(t, ok)
+		assert.Equal(t, "var x = 100", string(file.Content))
+	})
+
+	t.Run("InvalidURI", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), &mockReplier{}, nil, &MockScheduler{})
+
+		err := s.didCreateFiles(&CreateFilesParams{
+			Files: []protocol.FileCreate{{URI: "https://example.com/MySprite.spx"}},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestServerDidDeleteFiles(t *testing.T) {
+	t.Run("SpriteFile", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx":     []byte(`var x = 100`),
+			"MySprite.spx": []byte(`echo "Hello"`),
+		}
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(m), replier, nil, &MockScheduler{})
+
+		err := s.didDeleteFiles(&DeleteFilesParams{
+			Files: []protocol.FileDelete{{URI: "file:///MySprite.spx"}},
+		})
+		require.NoError(t, err)
+
+		_, ok := s.getProj().File("MySprite.spx")
+		assert.False(t, ok)
+		_, ok = s.getProj().File("main.spx")
+		assert.True(t, ok)
+
+		msgs := replier.getMessages()
+		require.NotEmpty(t, msgs)
+		n, ok := msgs[0].(*jsonrpc2.Notification)
+		require.True(t, ok)
+		assert.Equal(t, "textDocument/publishDiagnostics", n.Method())
+	})
+
+	t.Run("AssetDirectory", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx":                            []byte(`var x = 100`),
+			"assets/index.json":                   []byte(`{}`),
+			"assets/sprites/MySprite/index.json":  []byte(`{}`),
+			"assets/sprites/MySprite/1.png":       []byte(`png`),
+			"assets/sprites/MySprite2/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, nil, &MockScheduler{})
+
+		err := s.didDeleteFiles(&DeleteFilesParams{
+			Files: []protocol.FileDelete{{URI: "file:///assets/sprites/MySprite"}},
+		})
+		require.NoError(t, err)
+
+		proj := s.getProj()
+		_, ok := proj.File("assets/sprites/MySprite/index.json")
+		assert.False(t, ok)
+		_, ok = proj.File("assets/sprites/MySprite/1.png")
+		assert.False(t, ok)
+		_, ok = proj.File("assets/sprites/MySprite2/index.json")
+		assert.True(t, ok)
+		_, ok = proj.File("assets/index.json")
+		assert.True(t, ok)
+	})
+}
+
+func TestServerDidChangeWatchedFiles(t *testing.T) {
+	t.Run("ChangedWithFileMapGetter", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx":          []byte(`var x = 100`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, nil, &MockScheduler{})
+		m["assets/index.json"] = []byte(`{"backdrops":[]}`)
+		s.fileMapGetter = fileMapGetter(m)
+
+		err := s.didChangeWatchedFiles(&DidChangeWatchedFilesParams{
+			Changes: []protocol.FileEvent{{URI: "file:///assets/index.json", Type: protocol.Changed}},
+		})
+		require.NoError(t, err)
+
+		file, ok := s.getProj().File("assets/index.json")
+		require.True(t, ok)
+		assert.Equal(t, `{"backdrops":[]}`, string(file.Content))
+	})
+
+	t.Run("ChangedWithoutFileMapGetter", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx":          []byte(`var x = 100`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, nil, &MockScheduler{})
+
+		err := s.didChangeWatchedFiles(&DidChangeWatchedFilesParams{
+			Changes: []protocol.FileEvent{
+				{URI: "file:///assets/index.json", Type: protocol.Changed},
+				{URI: "file:///assets/unknown.json", Type: protocol.Changed},
+			},
+		})
+		require.NoError(t, err)
+
+		file, ok := s.getProj().File("assets/index.json")
+		require.True(t, ok)
+		assert.Equal(t, `{}`, string(file.Content))
+		_, ok = s.getProj().File("assets/unknown.json")
+		assert.False(t, ok)
+	})
+
+	t.Run("CreatedWithFileMapGetter", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`var x = 100`),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, nil, &MockScheduler{})
+		m["assets/sounds/biu/biu.wav"] = []byte(`RIFF`)
+		s.fileMapGetter = fileMapGetter(m)
+
+		err := s.didChangeWatchedFiles(&DidChangeWatchedFilesParams{
+			Changes: []protocol.FileEvent{{URI: "file:///assets/sounds/biu/biu.wav", Type: protocol.Created}},
+		})
+		require.NoError(t, err)
+
+		file, ok := s.getProj().File("assets/sounds/biu/biu.wav")
+		require.True(t, ok)
+		assert.Equal(t, `RIFF`, string(file.Content))
+	})
+
+	t.Run("CreatedWithUnknownContent", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx":          []byte(`var x = 100`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, nil, &MockScheduler{})
+
+		err := s.didChangeWatchedFiles(&DidChangeWatchedFilesParams{
+			Changes: []protocol.FileEvent{{URI: "file:///assets/sprites/MySprite/index.json", Type: protocol.Created}},
+		})
+		require.NoError(t, err)
+
+		_, ok := s.getProj().File("assets/sprites/MySprite/index.json")
+		assert.False(t, ok)
+
+		result, err := s.compile()
+		require.NoError(t, err)
+		assert.NotNil(t, result.spxResourceSet)
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx":                           []byte(`var x = 100`),
+			"MySprite.spx":                       []byte(`echo "Hello"`),
+			"assets/sprites/MySprite/index.json": []byte(`{}`),
+		}
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(m), replier, nil, &MockScheduler{})
+
+		err := s.didChangeWatchedFiles(&DidChangeWatchedFilesParams{
+			Changes: []protocol.FileEvent{
+				{URI: "file:///MySprite.spx", Type: protocol.Deleted},
+				{URI: "file:///assets/sprites/MySprite", Type: protocol.Deleted},
+			},
+		})
+		require.NoError(t, err)
+
+		proj := s.getProj()
+		_, ok := proj.File("MySprite.spx")
+		assert.False(t, ok)
+		_, ok = proj.File("assets/sprites/MySprite/index.json")
+		assert.False(t, ok)
+
+		msgs := replier.getMessages()
+		require.NotEmpty(t, msgs)
+		n, ok := msgs[0].(*jsonrpc2.Notification)
+		require.True(t, ok)
+		assert.Equal(t, "textDocument/publishDiagnostics", n.Method())
+	})
+
+	t.Run("RefreshDiagnostics", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx":                           []byte(`var x = 100`),
+			"assets/sprites/MySprite/index.json": []byte(`{}`),
+			"assets/sprites/MySprite/.DS_Store":  []byte(`x`),
+		}
+		replier := &callRespondingReplier{}
+		s := New(newMapFSWithoutModTime(m), replier, nil, &MockScheduler{})
+		replier.s = s
+		s.clientCapabilities.Workspace.Diagnostics = &protocol.DiagnosticWorkspaceClientCapabilities{RefreshSupport: true}
+		defer s.Close()
+
+		// Changes to files not used by spx resources do not refresh.
+		err := s.didChangeWatchedFiles(&DidChangeWatchedFilesParams{
+			Changes: []protocol.FileEvent{
+				{URI: "file:///main.spx", Type: protocol.Changed},
+				{URI: "file:///assets/sprites/MySprite/.DS_Store", Type: protocol.Deleted},
+			},
+		})
+		require.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+		assert.Zero(t, refreshDiagnosticsCalls(replier.getMessages()))
+
+		err = s.didChangeWatchedFiles(&DidChangeWatchedFilesParams{
+			Changes: []protocol.FileEvent{{URI: "file:///assets/sprites/MySprite", Type: protocol.Deleted}},
+		})
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			return refreshDiagnosticsCalls(replier.getMessages()) == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("InvalidURI", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), &mockReplier{}, nil, &MockScheduler{})
+
+		err := s.didChangeWatchedFiles(&DidChangeWatchedFilesParams{
+			Changes: []protocol.FileEvent{{URI: "https://example.com/main.spx", Type: protocol.Changed}},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestServerRegisterFileWatchers(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		replier := &callRespondingReplier{}
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), replier, nil, &MockScheduler{})
+		replier.s = s
+		s.clientCapabilities.Workspace.DidChangeWatchedFiles.DynamicRegistration = true
+
+		err := s.registerFileWatchers(context.Background())
+		require.NoError(t, err)
+
+		msgs := replier.getMessages()
+		require.Len(t, msgs, 1)
+		c, ok := msgs[0].(*jsonrpc2.Call)
+		require.True(t, ok)
+		assert.Equal(t, "client/registerCapability", c.Method())
+		assert.Contains(t, string(c.Params()), `"method":"workspace/didChangeWatchedFiles"`)
+		assert.Contains(t, string(c.Params()), `"globPattern":"**/*"`)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), replier, nil, &MockScheduler{})
+
+		err := s.registerFileWatchers(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, replier.getMessages())
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		replier := &callRespondingReplier{err: jsonrpc2.NewError(-32603, "rejected")}
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), replier, nil, &MockScheduler{})
+		replier.s = s
+		s.clientCapabilities.Workspace.DidChangeWatchedFiles.DynamicRegistration = true
+
+		err := s.registerFileWatchers(context.Background())
+		require.Error(t, err)
+	})
+}
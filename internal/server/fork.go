@@ -0,0 +1,101 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/goplus/xgolsw/xgo"
+)
+
+// forkWithWorkspaceEdit returns a fork of proj with edit applied, without
+// changing proj, so the result of a proposed change, such as a refactoring
+// or a speculative completion edit, can be type checked before the client
+// applies it.
+//
+// Both the changes and the document changes of edit are applied, including
+// the create, rename and delete file operations.
+func (s *Server) forkWithWorkspaceEdit(proj *xgo.Project, edit *WorkspaceEdit) (*xgo.Project, error) {
+	overlay := make(map[string]*xgo.File)
+
+	// file returns the file at path with the changes applied so far.
+	file := func(path string) (*xgo.File, bool) {
+		if f, ok := overlay[path]; ok {
+			return f, f != nil
+		}
+		return proj.File(path)
+	}
+
+	applyEdits := func(documentURI DocumentURI, edits []TextEdit) error {
+		path, err := s.fromDocumentURI(documentURI)
+		if err != nil {
+			return err
+		}
+		f, ok := file(path)
+		if !ok {
+			return fmt.Errorf("file %q not found", path)
+		}
+		content, err := f.ReadContent()
+		if err != nil {
+			return err
+		}
+		overlay[path] = &xgo.File{
+			Content: []byte(applyTextEdits(string(content), edits)),
+			Version: f.Version,
+		}
+		return nil
+	}
+
+	for documentURI, edits := range edit.Changes {
+		if err := applyEdits(documentURI, edits); err != nil {
+			return nil, err
+		}
+	}
+	for _, change := range edit.DocumentChanges {
+		switch {
+		case change.TextDocumentEdit != nil:
+			edits := make([]TextEdit, 0, len(change.TextDocumentEdit.Edits))
+			for _, e := range change.TextDocumentEdit.Edits {
+				switch e := e.Value.(type) {
+				case TextEdit:
+					edits = append(edits, e)
+				case AnnotatedTextEdit:
+					edits = append(edits, e.TextEdit)
+				default:
+					return nil, fmt.Errorf("unsupported text document edit %T", e)
+				}
+			}
+			if err := applyEdits(change.TextDocumentEdit.TextDocument.URI, edits); err != nil {
+				return nil, err
+			}
+		case change.CreateFile != nil:
+			path, err := s.fromDocumentURI(change.CreateFile.URI)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := file(path); !ok || (change.CreateFile.Options != nil && change.CreateFile.Options.Overwrite) {
+				overlay[path] = &xgo.File{}
+			}
+		case change.RenameFile != nil:
+			oldPath, err := s.fromDocumentURI(change.RenameFile.OldURI)
+			if err != nil {
+				return nil, err
+			}
+			newPath, err := s.fromDocumentURI(change.RenameFile.NewURI)
+			if err != nil {
+				return nil, err
+			}
+			f, ok := file(oldPath)
+			if !ok {
+				return nil, fmt.Errorf("file %q not found", oldPath)
+			}
+			overlay[oldPath] = nil
+			overlay[newPath] = f
+		case change.DeleteFile != nil:
+			path, err := s.fromDocumentURI(change.DeleteFile.URI)
+			if err != nil {
+				return nil, err
+			}
+			overlay[path] = nil
+		}
+	}
+	return proj.Fork(overlay), nil
+}
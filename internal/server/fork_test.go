@@ -0,0 +1,112 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/goplus/xgolsw/internal/vfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerForkWithWorkspaceEdit(t *testing.T) {
+	t.Run("Changes", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`var count int
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		proj := s.getProj()
+		_, err := proj.TypeInfo()
+		require.NoError(t, err)
+
+		fork, err := s.forkWithWorkspaceEdit(proj, &WorkspaceEdit{
+			Changes: map[DocumentURI][]TextEdit{
+				"file:///main.spx": {
+					{
+						Range: Range{
+							Start: Position{Line: 0, Character: 10},
+							End:   Position{Line: 0, Character: 13},
+						},
+						NewText: "string",
+					},
+					{
+						Range: Range{
+							Start: Position{Line: 2, Character: 0},
+							End:   Position{Line: 2, Character: 0},
+						},
+						NewText: "count = 1\n",
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+		content, err := vfs.ReadFile(fork, "main.spx")
+		require.NoError(t, err)
+		assert.Equal(t, "var count string\nrun \"assets\", {Title: \"My Game\"}\ncount = 1\n", string(content))
+		_, err = fork.TypeInfo()
+		assert.Error(t, err)
+
+		// The workspace is not changed.
+		content, err = vfs.ReadFile(proj, "main.spx")
+		require.NoError(t, err)
+		assert.Equal(t, string(m["main.spx"]), string(content))
+		_, err = proj.TypeInfo()
+		assert.NoError(t, err)
+	})
+
+	t.Run("DocumentChanges", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx":          []byte(`run "assets", {Title: "My Game"}`),
+			"MySprite.spx":      []byte(`onStart => {}`),
+			"Old.spx":           []byte(`onClick => {}`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		fork, err := s.forkWithWorkspaceEdit(s.getProj(), &WorkspaceEdit{
+			DocumentChanges: []DocumentChange{
+				{RenameFile: &RenameFile{Kind: "rename", OldURI: "file:///MySprite.spx", NewURI: "file:///Hero.spx"}},
+				{TextDocumentEdit: &TextDocumentEdit{
+					TextDocument: OptionalVersionedTextDocumentIdentifier{
+						TextDocumentIdentifier: TextDocumentIdentifier{URI: "file:///Hero.spx"},
+					},
+					Edits: []Or_TextDocumentEdit_edits_Elem{
+						{Value: AnnotatedTextEdit{TextEdit: TextEdit{NewText: "// Hero\n"}}},
+					},
+				}},
+				{CreateFile: &CreateFile{Kind: "create", URI: "file:///New.spx"}},
+				{DeleteFile: &DeleteFile{Kind: "delete", URI: "file:///Old.spx"}},
+			},
+		})
+		require.NoError(t, err)
+
+		content, err := vfs.ReadFile(fork, "Hero.spx")
+		require.NoError(t, err)
+		assert.Equal(t, "// Hero\nonStart => {}", string(content))
+		_, ok := fork.File("MySprite.spx")
+		assert.False(t, ok)
+		_, ok = fork.File("New.spx")
+		assert.True(t, ok)
+		_, ok = fork.File("Old.spx")
+		assert.False(t, ok)
+
+		_, ok = s.getProj().File("MySprite.spx")
+		assert.True(t, ok)
+		_, ok = s.getProj().File("Old.spx")
+		assert.True(t, ok)
+	})
+
+	t.Run("FileNotFound", func(t *testing.T) {
+		m := map[string][]byte{"main.spx": []byte(`echo 1`)}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := s.forkWithWorkspaceEdit(s.getProj(), &WorkspaceEdit{
+			Changes: map[DocumentURI][]TextEdit{
+				"file:///missing.spx": {{NewText: "echo 2"}},
+			},
+		})
+		assert.EqualError(t, err, `file "missing.spx" not found`)
+	})
+}
@@ -6,10 +6,14 @@ import (
 	"go/types"
 	"path"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	xgoast "github.com/goplus/xgo/ast"
 	xgofmt "github.com/goplus/xgo/format"
+	xgoscanner "github.com/goplus/xgo/scanner"
 	xgotoken "github.com/goplus/xgo/token"
 	"github.com/goplus/xgolsw/internal/vfs"
 	"github.com/goplus/xgolsw/xgo"
@@ -26,7 +30,12 @@ func (s *Server) textDocumentFormatting(params *DocumentFormattingParams) ([]Tex
 		return nil, nil // Not an spx source file.
 	}
 
-	snapshot := s.getProj().Snapshot()
+	return s.formatSpxTextEdits(s.getProj().Snapshot(), spxFile)
+}
+
+// formatSpxTextEdits returns the text edits that format the spx source file
+// in the given snapshot. It returns nil if the file is already formatted.
+func (s *Server) formatSpxTextEdits(snapshot *xgo.Project, spxFile string) ([]TextEdit, error) {
 	original, err := vfs.ReadFile(snapshot, spxFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read spx source file: %w", err)
@@ -42,25 +51,180 @@ func (s *Server) textDocumentFormatting(params *DocumentFormattingParams) ([]Tex
 		return nil, nil // No changes.
 	}
 
-	// Simply replace the entire document.
-	lines := bytes.Count(original, []byte("\n"))
-	lastNewLine := bytes.LastIndex(original, []byte("\n"))
-	lastLineContent := original
-	if lastNewLine >= 0 {
-		lastLineContent = lastLineContent[lastNewLine+1:]
+	return lineDiffTextEdits(string(original), string(formatted)), nil
+}
+
+// spxFormatProject formats all spx source files in the project and returns
+// the edits as a single workspace edit. Files that cannot be formatted, such
+// as those with syntax errors, are left unchanged.
+func (s *Server) spxFormatProject() (*WorkspaceEdit, error) {
+	snapshot := s.getProj().Snapshot()
+	spxFiles, err := vfs.ListSpxFiles(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spx files: %w", err)
+	}
+
+	workspaceEdit := &WorkspaceEdit{
+		Changes: make(map[DocumentURI][]TextEdit),
+	}
+	for _, spxFile := range spxFiles {
+		edits, err := s.formatSpxTextEdits(snapshot, spxFile)
+		if err != nil {
+			continue
+		}
+		if len(edits) > 0 {
+			workspaceEdit.Changes[s.toDocumentURI(spxFile)] = edits
+		}
+	}
+	return workspaceEdit, nil
+}
+
+// See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification#textDocument_onTypeFormatting
+func (s *Server) textDocumentOnTypeFormatting(params *DocumentOnTypeFormattingParams) ([]TextEdit, error) {
+	if params.Ch != "}" {
+		return nil, nil
+	}
+	spxFile, err := s.fromDocumentURI(params.TextDocument.URI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file path from document uri %q: %w", params.TextDocument.URI, err)
+	}
+	if path.Ext(spxFile) != ".spx" {
+		return nil, nil // Not an spx source file.
+	}
+
+	proj := s.getProjWithFile()
+	astFile, _ := proj.ASTFile(spxFile)
+	if astFile == nil {
+		return nil, nil
+	}
+	tokenFile := xgoutil.NodeTokenFile(proj, astFile)
+	if tokenFile == nil {
+		return nil, nil
+	}
+	indentUnit := s.getSettings().Formatting.indentUnit()
+	return reindentClosedBlock(tokenFile, astFile.Code, PosAt(proj, astFile, params.Position), indentUnit), nil
+}
+
+// reindentClosedBlock returns the text edits that re-indent the lines of the
+// block closed by the `}` right before pos on the same line. The indentation
+// of each line is derived from the brackets left open before its first token,
+// relative to the line of the opening `{` and in multiples of indentUnit, so
+// the lines are located with the token file instead of formatting the entire
+// document.
+//
+// Lines continuing an expression from the previous line, and lines inside
+// multi-line raw strings or comments, are kept as they are.
+func reindentClosedBlock(tokenFile *xgotoken.File, code []byte, pos xgotoken.Pos, indentUnit string) []TextEdit {
+	// bracket is an open bracket, along with the indentation level of the
+	// line it is opened on.
+	type bracket struct {
+		tok   xgotoken.Token
+		line  int
+		level int
+	}
+	// lineLevel is the indentation level of a line that is not a
+	// continuation line.
+	type lineLevel struct {
+		line  int
+		level int
+	}
+	var (
+		s         xgoscanner.Scanner
+		stack     []bracket
+		lines     []lineLevel
+		prevTok   = xgotoken.SEMICOLON
+		lastLine  int
+		level     int
+		closed    *bracket
+		closeLine int
+	)
+	s.Init(tokenFile, code, nil, xgoscanner.ScanComments)
+	for {
+		tokPos, tok, lit := s.Scan()
+		if tok == xgotoken.EOF || tokPos >= pos {
+			break
+		}
+		if tok == xgotoken.SEMICOLON && lit == "\n" {
+			prevTok = tok
+			continue // Automatically inserted semicolon.
+		}
+
+		line := tokenFile.Line(tokPos)
+		if line != lastLine {
+			level = 0
+			if len(stack) > 0 {
+				level = stack[len(stack)-1].level + 1
+			}
+			switch tok {
+			case xgotoken.RBRACE, xgotoken.RPAREN, xgotoken.RBRACK, xgotoken.CASE, xgotoken.DEFAULT:
+				if len(stack) > 0 {
+					level = stack[len(stack)-1].level
+				}
+			}
+			switch prevTok {
+			case xgotoken.SEMICOLON, xgotoken.LBRACE, xgotoken.LPAREN, xgotoken.LBRACK, xgotoken.COMMA, xgotoken.COLON, xgotoken.COMMENT:
+				lines = append(lines, lineLevel{line: line, level: level})
+			default:
+				level++ // Continuation line.
+			}
+		}
+		lastLine = line
+		if tok == xgotoken.COMMENT || tok == xgotoken.STRING {
+			// Skip the lines inside multi-line comments and raw strings.
+			lastLine = tokenFile.Line(tokPos + xgotoken.Pos(len(lit)) - 1)
+		}
+		if tok != xgotoken.COMMENT || prevTok == xgotoken.SEMICOLON {
+			prevTok = tok
+		}
+
+		switch tok {
+		case xgotoken.LBRACE, xgotoken.LPAREN, xgotoken.LBRACK:
+			stack = append(stack, bracket{tok: tok, line: line, level: level})
+		case xgotoken.RBRACE, xgotoken.RPAREN, xgotoken.RBRACK:
+			if len(stack) == 0 {
+				return nil // Unbalanced brackets.
+			}
+			open := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			closed = nil
+			if tok == xgotoken.RBRACE && open.tok == xgotoken.LBRACE {
+				closed, closeLine = &open, line
+			}
+		}
+	}
+	if closed == nil || closeLine != tokenFile.Line(pos) || closed.line == closeLine {
+		return nil
+	}
+
+	lineIndent := func(line int) []byte {
+		start := tokenFile.Offset(tokenFile.LineStart(line))
+		end := start
+		for end < len(code) && (code[end] == ' ' || code[end] == '\t') {
+			end++
+		}
+		return code[start:end]
 	}
-	return []TextEdit{
-		{
+	baseIndent := lineIndent(closed.line)
+
+	var edits []TextEdit
+	for _, ll := range lines {
+		if ll.line <= closed.line || ll.line > closeLine {
+			continue
+		}
+		indent := lineIndent(ll.line)
+		newIndent := slices.Concat(baseIndent, bytes.Repeat([]byte(indentUnit), max(ll.level-closed.level, 0)))
+		if bytes.Equal(indent, newIndent) {
+			continue
+		}
+		edits = append(edits, TextEdit{
 			Range: Range{
-				Start: Position{Line: 0, Character: 0},
-				End: Position{
-					Line:      uint32(lines),
-					Character: uint32(UTF16Len(string(lastLineContent))),
-				},
+				Start: Position{Line: uint32(ll.line - 1), Character: 0},
+				End:   Position{Line: uint32(ll.line - 1), Character: uint32(len(indent))},
 			},
-			NewText: string(formatted),
-		},
-	}, nil
+			NewText: string(newIndent),
+		})
+	}
+	return edits
 }
 
 // spxFormatter defines a function that formats an spx source file in the given
@@ -71,15 +235,29 @@ type spxFormatter func(snapshot *vfs.MapFS, spxFile string) (formatted []byte, e
 //
 // The formatters are applied in the following order:
 //  1. XGo formatter
-//  2. Lambda parameter elimination
-//  3. Declaration reordering
+//  2. Lambda parameter elimination (if enabled in settings)
+//  3. File header normalization
+//  4. Declaration reordering (if enabled in settings or in strict mode)
+//  5. Strict simplification (in strict mode)
+//  6. Classfile idiom normalization
+//  7. Layout options in settings, such as indenting with spaces
 func (s *Server) formatSpx(snapshot *xgo.Project, spxFile string, original []byte) ([]byte, error) {
+	settings := s.getSettings()
+	formatters := []spxFormatter{s.formatSpxXGo}
+	if settings.Formatting.EliminateUnusedLambdaParams {
+		formatters = append(formatters, s.formatSpxLambda)
+	}
+	formatters = append(formatters, s.formatSpxHeader)
+	if settings.Formatting.ReorderDecls || settings.Formatting.Strict {
+		formatters = append(formatters, s.formatSpxDecls)
+	}
+	if settings.Formatting.Strict {
+		formatters = append(formatters, s.formatSpxStrict)
+	}
+	formatters = append(formatters, s.formatSpxClassfile, s.formatSpxLayout)
+
 	formatted := original
-	for _, formatter := range []spxFormatter{
-		s.formatSpxXGo,
-		s.formatSpxLambda,
-		s.formatSpxDecls,
-	} {
+	for _, formatter := range formatters {
 		subFormatted, err := formatter(snapshot, spxFile)
 		if err != nil {
 			return nil, err
@@ -113,9 +291,57 @@ func (s *Server) formatSpxXGo(snapshot *vfs.MapFS, spxFile string) ([]byte, erro
 	return formatted, err
 }
 
+// formatSpxHeader formats an spx source file by setting its file header, if
+// any, apart from the code that follows with a blank line, as well as the
+// directives at the start of the file header from the rest of it. This keeps
+// the file header at the start of the file, instead of being taken as the doc
+// comment of the first declaration, which may be moved when reordering
+// declarations.
+func (s *Server) formatSpxHeader(snapshot *vfs.MapFS, spxFile string) ([]byte, error) {
+	astFile, _ := snapshot.ASTFile(spxFile)
+	if astFile == nil {
+		return nil, nil
+	}
+	header := spxFileHeader(snapshot, astFile)
+	if header == nil {
+		return nil, nil
+	}
+	tokFile := snapshot.Fset.File(header.Pos())
+	code := astFile.Code
+
+	// Find the offsets after which a blank line is needed.
+	var splitOffsets []int
+	directives := 0
+	for directives < len(header.List) && isDirectiveComment(header.List[directives]) {
+		directives++
+	}
+	if directives > 0 && directives < len(header.List) {
+		splitOffsets = append(splitOffsets, tokFile.Offset(header.List[directives-1].End()))
+	}
+	headerEnd := tokFile.Offset(header.End())
+	if rest := code[headerEnd:]; len(bytes.TrimSpace(rest)) > 0 && bytes.Count(rest[:len(rest)-len(bytes.TrimLeft(rest, " \t\n"))], []byte("\n")) < 2 {
+		splitOffsets = append(splitOffsets, headerEnd)
+	}
+	if len(splitOffsets) == 0 {
+		return nil, nil
+	}
+
+	var formattedBuf bytes.Buffer
+	last := 0
+	for _, offset := range splitOffsets {
+		formattedBuf.Write(code[last:offset])
+		formattedBuf.WriteByte('\n')
+		last = offset
+	}
+	formattedBuf.Write(code[last:])
+	return xgofmt.Source(formattedBuf.Bytes(), true, spxFile)
+}
+
 // formatSpxLambda formats an spx source file by eliminating unused lambda parameters.
 func (s *Server) formatSpxLambda(snapshot *vfs.MapFS, spxFile string) ([]byte, error) {
-	snapshot.UpdateFiles(s.fileMapGetter())
+	if s.fileMapGetter != nil {
+		snapshot.UpdateFiles(s.fileMapGetter())
+	}
 	astFile, _ := snapshot.ASTFile(spxFile)
 	if astFile == nil {
 		return nil, nil
@@ -490,6 +716,366 @@ func (s *Server) formatSpxDecls(snapshot *vfs.MapFS, spxFile string) ([]byte, er
 	return xgofmt.Source(formatted, true, spxFile)
 }
 
+// formatSpxStrict formats an spx source file by applying the simplifications
+// of the strict formatting profile:
+//   - Redundant parentheses are removed, such as in `x := (a + b)` and
+//     `(p.x)`.
+//   - Raw string literals that need no escaping are rewritten as interpreted
+//     string literals, such as a raw string literal of bang as `"bang"`.
+func (s *Server) formatSpxStrict(snapshot *vfs.MapFS, spxFile string) ([]byte, error) {
+	astFile, _ := snapshot.ASTFile(spxFile)
+	if astFile == nil {
+		return nil, nil
+	}
+	tokFile := snapshot.Fset.File(astFile.Pos())
+	if tokFile == nil {
+		return nil, nil
+	}
+	code := astFile.Code
+
+	type edit struct {
+		start, end int
+		newText    string
+	}
+	var edits []edit
+	hasCommentIn := func(start, end xgotoken.Pos) bool {
+		for _, cg := range astFile.Comments {
+			if cg.Pos() < end && start < cg.End() {
+				return true
+			}
+		}
+		return false
+	}
+	// removeParen removes the parenthesis at offset, keeping a space if the
+	// tokens around it would otherwise be joined.
+	removeParen := func(offset int) {
+		var newText string
+		if offset > 0 && offset+1 < len(code) && isIdentByte(code[offset-1]) && isIdentByte(code[offset+1]) {
+			newText = " "
+		}
+		edits = append(edits, edit{start: offset, end: offset + 1, newText: newText})
+	}
+
+	var stack []xgoast.Node
+	xgoast.Inspect(astFile, func(node xgoast.Node) bool {
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		var parent xgoast.Node
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+		}
+		stack = append(stack, node)
+
+		switch node := node.(type) {
+		case *xgoast.ParenExpr:
+			if isRedundantParenExpr(parent, node) && !hasCommentIn(node.Lparen, node.Rparen) {
+				removeParen(tokFile.Offset(node.Lparen))
+				removeParen(tokFile.Offset(node.Rparen))
+			}
+		case *xgoast.BasicLit:
+			if node.Kind != xgotoken.STRING || node.Extra != nil || !strings.HasPrefix(node.Value, "`") {
+				break
+			}
+			content := node.Value[1 : len(node.Value)-1]
+			if strings.Contains(content, "$") {
+				break // Would turn into string interpolation.
+			}
+			if quoted := strconv.Quote(content); quoted == `"`+content+`"` {
+				edits = append(edits, edit{
+					start:   tokFile.Offset(node.Pos()),
+					end:     tokFile.Offset(node.End()),
+					newText: quoted,
+				})
+			}
+		}
+		return true
+	})
+	if len(edits) == 0 {
+		return nil, nil
+	}
+
+	slices.SortFunc(edits, func(a, b edit) int {
+		return b.start - a.start
+	})
+	formatted := slices.Clone(code)
+	for _, e := range edits {
+		formatted = slices.Concat(formatted[:e.start], []byte(e.newText), formatted[e.end:])
+	}
+	return xgofmt.Source(formatted, true, spxFile)
+}
+
+// isRedundantParenExpr reports whether the parentheses of paren, whose parent
+// node is parent, can be removed without changing the meaning of the code.
+// That is the case if paren encloses an operand or a primary expression, or
+// if it is the entire value of an assignment, a return or an argument.
+func isRedundantParenExpr(parent xgoast.Node, paren *xgoast.ParenExpr) bool {
+	// Composite literals may need parentheses to be parsed, for example in
+	// if statements.
+	hasCompositeLit := false
+	xgoast.Inspect(paren.X, func(node xgoast.Node) bool {
+		if _, ok := node.(*xgoast.CompositeLit); ok {
+			hasCompositeLit = true
+		}
+		return !hasCompositeLit
+	})
+	if hasCompositeLit {
+		return false
+	}
+
+	switch x := paren.X.(type) {
+	case *xgoast.Ident, *xgoast.BasicLit, *xgoast.SelectorExpr, *xgoast.IndexExpr, *xgoast.ParenExpr:
+		return true
+	case *xgoast.CallExpr:
+		if !x.IsCommand() {
+			return true
+		}
+	}
+
+	isParen := func(expr xgoast.Expr) bool { return expr == paren }
+	switch parent := parent.(type) {
+	case *xgoast.AssignStmt:
+		return slices.ContainsFunc(parent.Rhs, isParen)
+	case *xgoast.ReturnStmt:
+		return slices.ContainsFunc(parent.Results, isParen)
+	case *xgoast.ValueSpec:
+		return slices.ContainsFunc(parent.Values, isParen)
+	case *xgoast.CallExpr:
+		return slices.ContainsFunc(parent.Args, isParen)
+	case *xgoast.KeyValueExpr:
+		return parent.Value == paren
+	}
+	return false
+}
+
+// isIdentByte reports whether b may be part of an identifier or a number.
+func isIdentByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || b >= utf8.RuneSelf
+}
+
+// formatSpxClassfile formats an spx source file by normalizing the idioms of
+// XGo classfiles that are not covered by the XGo formatter:
+//   - Event handler lambdas drop the parentheses around zero or one parameter,
+//     as in `onStart => {}` and `onKey KeyA, key => {}`.
+//   - Event handler bodies have no leading or trailing blank lines.
+//   - Class fields in the leading var block are not separated by blank lines,
+//     unless either of them is documented or a comment stands between them.
+//
+// Command-style calls are kept as they are, and no package clause is added.
+func (s *Server) formatSpxClassfile(snapshot *vfs.MapFS, spxFile string) ([]byte, error) {
+	astFile, _ := snapshot.ASTFile(spxFile)
+	if astFile == nil {
+		return nil, nil
+	}
+	fset := snapshot.Fset
+	tokFile := fset.File(astFile.Pos())
+	if tokFile == nil {
+		return nil, nil
+	}
+
+	type edit struct {
+		start, end int
+		newText    string
+	}
+	var edits []edit
+	hasCommentIn := func(start, end xgotoken.Pos) bool {
+		for _, cg := range astFile.Comments {
+			if cg.Pos() < end && start < cg.End() {
+				return true
+			}
+		}
+		return false
+	}
+	// removeBlankLines removes the blank lines strictly between line
+	// fromLine and line toLine.
+	removeBlankLines := func(fromLine, toLine int) {
+		if toLine-fromLine < 2 {
+			return
+		}
+		start := tokFile.Offset(tokFile.LineStart(fromLine + 1))
+		end := tokFile.Offset(tokFile.LineStart(toLine))
+		if len(bytes.TrimSpace(astFile.Code[start:end])) == 0 {
+			edits = append(edits, edit{start: start, end: end})
+		}
+	}
+
+	// Normalize event handler lambdas.
+	xgoast.Inspect(astFile, func(node xgoast.Node) bool {
+		callExpr, ok := node.(*xgoast.CallExpr)
+		if !ok {
+			return true
+		}
+		funIdent, ok := callExpr.Fun.(*xgoast.Ident)
+		if !ok || !IsSpxEventHandlerFuncName(funIdent.Name) {
+			return true
+		}
+		for _, arg := range callExpr.Args {
+			lambdaExpr, ok := arg.(*xgoast.LambdaExpr2)
+			if !ok || lambdaExpr.Body == nil {
+				continue
+			}
+
+			if lambdaExpr.LhsHasParen && len(lambdaExpr.Lhs) <= 1 && !hasCommentIn(lambdaExpr.First, lambdaExpr.Rarrow) {
+				var newText string
+				if len(lambdaExpr.Lhs) == 1 {
+					newText = lambdaExpr.Lhs[0].Name + " "
+				}
+				edits = append(edits, edit{
+					start:   tokFile.Offset(lambdaExpr.First),
+					end:     tokFile.Offset(lambdaExpr.Rarrow),
+					newText: newText,
+				})
+			}
+
+			body := lambdaExpr.Body
+			firstPos, lastEnd := body.Rbrace, body.Lbrace+1
+			if len(body.List) > 0 {
+				firstPos, lastEnd = body.List[0].Pos(), body.List[len(body.List)-1].End()
+			}
+			for _, cg := range astFile.Comments {
+				if body.Lbrace < cg.Pos() && cg.End() <= body.Rbrace {
+					firstPos = min(firstPos, cg.Pos())
+					lastEnd = max(lastEnd, cg.End())
+				}
+			}
+			lbraceLine := tokFile.Line(body.Lbrace)
+			rbraceLine := tokFile.Line(body.Rbrace)
+			if firstPos < body.Rbrace {
+				removeBlankLines(lbraceLine, tokFile.Line(firstPos))
+				removeBlankLines(tokFile.Line(lastEnd), rbraceLine)
+			} else {
+				removeBlankLines(lbraceLine, rbraceLine)
+			}
+		}
+		return true
+	})
+
+	// Compact the leading var block of class fields.
+	if classFieldsDecl := astFile.ClassFieldsDecl(); classFieldsDecl != nil && classFieldsDecl.Lparen.IsValid() {
+		for i := 1; i < len(classFieldsDecl.Specs); i++ {
+			prev, ok := classFieldsDecl.Specs[i-1].(*xgoast.ValueSpec)
+			if !ok {
+				continue
+			}
+			next, ok := classFieldsDecl.Specs[i].(*xgoast.ValueSpec)
+			if !ok {
+				continue
+			}
+			prevEnd := prev.End()
+			if prev.Comment != nil {
+				prevEnd = prev.Comment.End()
+			}
+			if prev.Doc != nil || next.Doc != nil || hasCommentIn(prevEnd, next.Pos()) {
+				continue // Keep documented fields set apart.
+			}
+			removeBlankLines(tokFile.Line(prevEnd), tokFile.Line(next.Pos()))
+		}
+	}
+
+	if len(edits) == 0 {
+		return nil, nil
+	}
+	slices.SortFunc(edits, func(a, b edit) int {
+		return b.start - a.start
+	})
+	formatted := slices.Clone(astFile.Code)
+	for _, e := range edits {
+		formatted = slices.Concat(formatted[:e.start], []byte(e.newText), formatted[e.end:])
+	}
+	return xgofmt.Source(formatted, true, spxFile)
+}
+
+// formatSpxLayout formats an spx source file by applying the layout options
+// in settings that the XGo formatter does not support. It must be the last
+// formatter, as the XGo formatter reverts the layout.
+func (s *Server) formatSpxLayout(snapshot *vfs.MapFS, spxFile string) ([]byte, error) {
+	settings := s.getSettings().Formatting
+	if !settings.UseSpaces && settings.MaxBlankLines > 0 && settings.AlignStructLiteralFields {
+		return nil, nil
+	}
+	astFile, _ := snapshot.ASTFile(spxFile)
+	if astFile == nil {
+		return nil, nil
+	}
+	tokFile := snapshot.Fset.File(astFile.Pos())
+	if tokFile == nil {
+		return nil, nil
+	}
+
+	// Find the lines inside multi-line raw strings, which are kept as they
+	// are, and inside multi-line comments, which are kept even if blank.
+	var (
+		rawStringLines = make(map[int]bool)
+		commentLines   = make(map[int]bool)
+		alignedValues  []*xgoast.KeyValueExpr
+	)
+	markLines := func(lines map[int]bool, pos, end xgotoken.Pos) {
+		for line := tokFile.Line(pos) + 1; line <= tokFile.Line(end); line++ {
+			lines[line] = true
+		}
+	}
+	for _, cg := range astFile.Comments {
+		markLines(commentLines, cg.Pos(), cg.End())
+	}
+	xgoast.Inspect(astFile, func(node xgoast.Node) bool {
+		switch node := node.(type) {
+		case *xgoast.BasicLit:
+			if node.Kind == xgotoken.STRING {
+				markLines(rawStringLines, node.Pos(), node.End())
+			}
+		case *xgoast.CompositeLit:
+			if !settings.AlignStructLiteralFields {
+				for _, elt := range node.Elts {
+					if kv, ok := elt.(*xgoast.KeyValueExpr); ok && tokFile.Line(kv.Colon) == tokFile.Line(kv.Value.Pos()) {
+						alignedValues = append(alignedValues, kv)
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	// Remove the padding between keys and values, which keeps line numbers.
+	code := slices.Clone(astFile.Code)
+	for _, kv := range slices.Backward(alignedValues) {
+		start := tokFile.Offset(kv.Colon) + 1
+		end := tokFile.Offset(kv.Value.Pos())
+		if end-start > 1 && len(bytes.Trim(code[start:end], " ")) == 0 {
+			code = slices.Concat(code[:start], []byte(" "), code[end:])
+		}
+	}
+
+	var (
+		formattedBuf bytes.Buffer
+		blankLines   int
+	)
+	for i, line := range bytes.SplitAfter(code, []byte("\n")) {
+		lineNum := i + 1
+		if rawStringLines[lineNum] {
+			formattedBuf.Write(line)
+			blankLines = 0
+			continue
+		}
+		if len(bytes.TrimSpace(line)) == 0 && len(line) > 0 && !commentLines[lineNum] {
+			blankLines++
+			if blankLines > settings.MaxBlankLines {
+				continue
+			}
+		} else {
+			blankLines = 0
+		}
+		if settings.UseSpaces {
+			content := bytes.TrimLeft(line, "\t")
+			tabs := len(line) - len(content)
+			formattedBuf.WriteString(strings.Repeat(" ", tabs*settings.TabWidth))
+			line = content
+		}
+		formattedBuf.Write(line)
+	}
+	return formattedBuf.Bytes(), nil
+}
+
 // getDeclDoc returns the doc comment of a declaration if any.
 func getDeclDoc(decl xgoast.Decl) *xgoast.CommentGroup {
 	switch decl := decl.(type) {
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"io/fs"
 	"testing"
 
@@ -29,13 +30,7 @@ run "assets",    { Title:    "Bullet (by XGo)" }
 
 		edits, err := s.textDocumentFormatting(params)
 		require.NoError(t, err)
-		require.Len(t, edits, 1)
-		assert.Contains(t, edits, TextEdit{
-			Range: Range{
-				Start: Position{Line: 0, Character: 0},
-				End:   Position{Line: 9, Character: 0},
-			},
-			NewText: `// An spx game.
+		assert.Equal(t, `// An spx game.
 
 type Score int
 
@@ -45,8 +40,129 @@ var (
 )
 
 run "assets", {Title: "Bullet (by XGo)"}
-`,
-		})
+`, applyTextEdits(string(m["main.spx"]), edits))
+	})
+
+	t.Run("MinimalEdits", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`onStart => {
+	println "a"
+	println   "b"
+}
+
+run "assets",    { Title:    "My Game" }
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		params := &DocumentFormattingParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		}
+
+		edits, err := s.textDocumentFormatting(params)
+		require.NoError(t, err)
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 2, Character: 0},
+					End:   Position{Line: 3, Character: 0},
+				},
+				NewText: "\tprintln \"b\"\n",
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 5, Character: 0},
+					End:   Position{Line: 6, Character: 0},
+				},
+				NewText: "run \"assets\", {Title: \"My Game\"}\n",
+			},
+		}, edits)
+	})
+
+	t.Run("WithLayoutSettings", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte("type Point struct {\n\tX, Long int\n}\n\nfunc f() {\n\tp := Point{\n\t\tX: 1,\n\t\tLong: 2,\n\t}\n\tprintln p, `a\n\n\tb`\n}\n"),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, fileMapGetter(m), &MockScheduler{})
+		require.NoError(t, s.didChangeConfiguration(&DidChangeConfigurationParams{
+			Settings: map[string]any{"formatting": map[string]any{
+				"useSpaces":                true,
+				"tabWidth":                 2,
+				"maxBlankLines":            0,
+				"alignStructLiteralFields": false,
+				"reorderDecls":             false,
+			}},
+		}))
+		params := &DocumentFormattingParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		}
+
+		edits, err := s.textDocumentFormatting(params)
+		require.NoError(t, err)
+		assert.Equal(t, "type Point struct {\n  X, Long int\n}\nfunc f() {\n  p := Point{\n    X: 1,\n    Long: 2,\n  }\n  println p, `a\n\n\tb`\n}\n", applyTextEdits(string(m["main.spx"]), edits))
+	})
+
+	t.Run("WithStrictProfile", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte("func double(n int) int {\n\treturn (n * 2)\n}\n\nvar (\n\tscore int\n)\n\nonStart => {\n\tscore = (double(score))\n\tif (score > 10) && score < (20) {\n\t\tplay `bang`\n\t}\n\tprintln (score + 1) * 2, `${score}`, `a\\b`\n}\n"),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, fileMapGetter(m), &MockScheduler{})
+		require.NoError(t, s.didChangeConfiguration(&DidChangeConfigurationParams{
+			Settings: map[string]any{"formatting": map[string]any{"strict": true, "reorderDecls": false}},
+		}))
+		params := &DocumentFormattingParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		}
+
+		edits, err := s.textDocumentFormatting(params)
+		require.NoError(t, err)
+		assert.Equal(t, "var (\n\tscore int\n)\n\nfunc double(n int) int {\n\treturn n * 2\n}\n\nonStart => {\n\tscore = double(score)\n\tif (score > 10) && score < 20 {\n\t\tplay \"bang\"\n\t}\n\tprintln (score+1)*2, `${score}`, `a\\b`\n}\n", applyTextEdits(string(m["main.spx"]), edits))
+	})
+
+	t.Run("WithFileHeader", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`//go:build ignore
+// Copyright 2025 The XGo Authors.
+// SPDX-License-Identifier: Apache-2.0
+var x int
+
+type T int
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		params := &DocumentFormattingParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		}
+
+		edits, err := s.textDocumentFormatting(params)
+		require.NoError(t, err)
+		assert.Equal(t, `//go:build ignore
+
+// Copyright 2025 The XGo Authors.
+// SPDX-License-Identifier: Apache-2.0
+
+type T int
+
+var (
+	x int
+)
+`, applyTextEdits(string(m["main.spx"]), edits))
+	})
+
+	t.Run("WithDocCommentNotFileHeader", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`// The game.
+onStart => {
+}
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		params := &DocumentFormattingParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		}
+
+		edits, err := s.textDocumentFormatting(params)
+		require.NoError(t, err)
+		assert.Nil(t, edits)
 	})
 
 	t.Run("NonSpxFile", func(t *testing.T) {
@@ -103,21 +219,14 @@ var MyAircraft MyAircraft
 
 		edits, err := s.textDocumentFormatting(params)
 		require.NoError(t, err)
-		require.Len(t, edits, 1)
-		assert.Contains(t, edits, TextEdit{
-			Range: Range{
-				Start: Position{Line: 0, Character: 0},
-				End:   Position{Line: 4, Character: 0},
-			},
-			NewText: `// An spx game.
+		assert.Equal(t, `// An spx game.
 
 var (
 	MyAircraft MyAircraft
 )
 
 !InvalidSyntax
-`,
-		})
+`, applyTextEdits(string(m["main.spx"]), edits))
 	})
 
 	t.Run("WithFormatSpx", func(t *testing.T) {
@@ -161,13 +270,7 @@ var (
 
 		edits, err := s.textDocumentFormatting(params)
 		require.NoError(t, err)
-		require.Len(t, edits, 1)
-		assert.Contains(t, edits, TextEdit{
-			Range: Range{
-				Start: Position{Line: 0, Character: 0},
-				End:   Position{Line: 30, Character: 0},
-			},
-			NewText: `// An spx game.
+		assert.Equal(t, `// An spx game.
 
 var (
 	// The first var block.
@@ -202,8 +305,7 @@ var (
 
 	// Trailing comment for the last var block.
 )
-`,
-		})
+`, applyTextEdits(string(m["main.spx"]), edits))
 	})
 
 	t.Run("VarBlockWithoutDoc", func(t *testing.T) {
@@ -225,13 +327,7 @@ var Bullet Bullet
 
 		edits, err := s.textDocumentFormatting(params)
 		require.NoError(t, err)
-		require.Len(t, edits, 1)
-		assert.Contains(t, edits, TextEdit{
-			Range: Range{
-				Start: Position{Line: 0, Character: 0},
-				End:   Position{Line: 8, Character: 0},
-			},
-			NewText: `// An spx game.
+		assert.Equal(t, `// An spx game.
 
 var (
 	// The aircraft.
@@ -239,8 +335,7 @@ var (
 
 	Bullet Bullet
 )
-`,
-		})
+`, applyTextEdits(string(m["main.spx"]), edits))
 	})
 
 	t.Run("NoTypeSpriteVarDeclaration", func(t *testing.T) {
@@ -305,22 +400,15 @@ onKey [KeyLeft, KeyRight], (key) => {
 
 		edits, err := s.textDocumentFormatting(params)
 		require.NoError(t, err)
-		require.Len(t, edits, 1)
-		assert.Contains(t, edits, TextEdit{
-			Range: Range{
-				Start: Position{Line: 0, Character: 0},
-				End:   Position{Line: 8, Character: 0},
-			},
-			NewText: `// An spx game.
-onKey [KeyLeft, KeyRight], () => {
+		assert.Equal(t, `// An spx game.
+onKey [KeyLeft, KeyRight], => {
 	println "key"
 }
 
-onKey [KeyLeft, KeyRight], (key) => {
+onKey [KeyLeft, KeyRight], key => {
 	println key
 }
-`,
-		})
+`, applyTextEdits(string(m["main.spx"]), edits))
 	})
 
 	t.Run("WithUnusedLambdaParamsForSprite", func(t *testing.T) {
@@ -348,14 +436,8 @@ onTouchStart 123, (s) => { // type mismatch
 
 		edits, err := s.textDocumentFormatting(params)
 		require.NoError(t, err)
-		require.Len(t, edits, 1)
-		assert.Contains(t, edits, TextEdit{
-			Range: Range{
-				Start: Position{Line: 0, Character: 0},
-				End:   Position{Line: 13, Character: 0},
-			},
-			NewText: `// An spx game.
-onKey [KeyLeft, KeyRight], () => {
+		assert.Equal(t, `// An spx game.
+onKey [KeyLeft, KeyRight], => {
 	println "key"
 }
 onTouchStart => {
@@ -367,10 +449,9 @@ onTouchStart => {
 }
 onTouchStart (s, t) => { // type mismatch
 }
-onTouchStart 123, (s) => { // type mismatch
+onTouchStart 123, s => { // type mismatch
 }
-`,
-		})
+`, applyTextEdits(string(m["MySprite.spx"]), edits))
 	})
 
 	t.Run("EmptyFile", func(t *testing.T) {
@@ -443,13 +524,7 @@ run "assets", {Title: "My Game"}
 
 		edits, err := s.textDocumentFormatting(params)
 		require.NoError(t, err)
-		require.Len(t, edits, 1)
-		assert.Contains(t, edits, TextEdit{
-			Range: Range{
-				Start: Position{Line: 0, Character: 0},
-				End:   Position{Line: 24, Character: 0},
-			},
-			NewText: `import "fmt"
+		assert.Equal(t, `import "fmt"
 
 // floating comment1
 
@@ -475,8 +550,7 @@ func test() {
 run "assets", {Title: "My Game"}
 
 // floating comment5
-`,
-		})
+`, applyTextEdits(string(m["main.spx"]), edits))
 	})
 
 	t.Run("WithTrailingComments", func(t *testing.T) {
@@ -497,13 +571,7 @@ func test() {} // trailing comment for func test
 
 		edits, err := s.textDocumentFormatting(params)
 		require.NoError(t, err)
-		require.Len(t, edits, 1)
-		assert.Contains(t, edits, TextEdit{
-			Range: Range{
-				Start: Position{Line: 0, Character: 0},
-				End:   Position{Line: 7, Character: 0},
-			},
-			NewText: `import "fmt" // trailing comment for import "fmt"
+		assert.Equal(t, `import "fmt" // trailing comment for import "fmt"
 
 const foo = "bar" // trailing comment for const foo
 
@@ -512,8 +580,7 @@ var (
 )
 
 func test() {} // trailing comment for func test
-`,
-		})
+`, applyTextEdits(string(m["main.spx"]), edits))
 	})
 
 	t.Run("WithMethods", func(t *testing.T) {
@@ -538,13 +605,7 @@ func Bar() {}
 
 		edits, err := s.textDocumentFormatting(params)
 		require.NoError(t, err)
-		require.Len(t, edits, 1)
-		assert.Contains(t, edits, TextEdit{
-			Range: Range{
-				Start: Position{Line: 0, Character: 0},
-				End:   Position{Line: 11, Character: 0},
-			},
-			NewText: `// An spx game.
+		assert.Equal(t, `// An spx game.
 
 type Foo struct{}
 
@@ -555,8 +616,7 @@ var (
 )
 
 func Bar() {}
-`,
-		})
+`, applyTextEdits(string(m["main.spx"]), edits))
 	})
 
 	t.Run("VarBlocksWithAndWithoutInit", func(t *testing.T) {
@@ -582,13 +642,7 @@ run "assets", {Title: "Snake Game"}
 
 		edits, err := s.textDocumentFormatting(params)
 		require.NoError(t, err)
-		require.Len(t, edits, 1)
-		assert.Contains(t, edits, TextEdit{
-			Range: Range{
-				Start: Position{Line: 0, Character: 0},
-				End:   Position{Line: 12, Character: 0},
-			},
-			NewText: `// An spx game.
+		assert.Equal(t, `// An spx game.
 
 var (
 	dir            int
@@ -600,8 +654,7 @@ var (
 )
 
 run "assets", {Title: "Snake Game"}
-`,
-		})
+`, applyTextEdits(string(m["main.spx"]), edits))
 	})
 
 	t.Run("MultipleVarBlocksWithMultipleTypes", func(t *testing.T) {
@@ -636,19 +689,12 @@ run "assets", {Title: "Game"}
 
 		edits, err := s.textDocumentFormatting(params)
 		require.NoError(t, err)
-		require.Len(t, edits, 1)
-		assert.Contains(t, edits, TextEdit{
-			Range: Range{
-				Start: Position{Line: 0, Character: 0},
-				End:   Position{Line: 21, Character: 0},
-			},
-			NewText: `// An spx game.
+		assert.Equal(t, `// An spx game.
 
 var (
 	score     int
 	highScore int
-
-	lives int
+	lives     int
 )
 
 var (
@@ -659,8 +705,7 @@ var (
 )
 
 run "assets", {Title: "Game"}
-`,
-		})
+`, applyTextEdits(string(m["main.spx"]), edits))
 	})
 
 	t.Run("MixedVarDeclarationsWithComments", func(t *testing.T) {
@@ -693,13 +738,7 @@ run "assets", {Title: "Game With Comments"}
 
 		edits, err := s.textDocumentFormatting(params)
 		require.NoError(t, err)
-		require.Len(t, edits, 1)
-		assert.Contains(t, edits, TextEdit{
-			Range: Range{
-				Start: Position{Line: 0, Character: 0},
-				End:   Position{Line: 19, Character: 0},
-			},
-			NewText: `// An spx game.
+		assert.Equal(t, `// An spx game.
 
 // Variables without initialization
 var (
@@ -718,8 +757,7 @@ var (
 )
 
 run "assets", {Title: "Game With Comments"}
-`,
-		})
+`, applyTextEdits(string(m["main.spx"]), edits))
 	})
 
 	t.Run("SingleVarDeclarationsWithMixedInit", func(t *testing.T) {
@@ -741,17 +779,10 @@ run "assets", {Title: "Single Vars"}
 
 		edits, err := s.textDocumentFormatting(params)
 		require.NoError(t, err)
-		require.Len(t, edits, 1)
-		assert.Contains(t, edits, TextEdit{
-			Range: Range{
-				Start: Position{Line: 0, Character: 0},
-				End:   Position{Line: 8, Character: 0},
-			},
-			NewText: `// An spx game.
+		assert.Equal(t, `// An spx game.
 
 var (
 	x int
-
 	z string
 )
 
@@ -762,8 +793,7 @@ var (
 )
 
 run "assets", {Title: "Single Vars"}
-`,
-		})
+`, applyTextEdits(string(m["main.spx"]), edits))
 	})
 
 	t.Run("WithShadowEntryComments", func(t *testing.T) {
@@ -789,4 +819,332 @@ onStart => {
 		require.NoError(t, err)
 		require.Len(t, edits, 0)
 	})
+
+	t.Run("WithClassfileIdioms", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`package main
+
+var (
+	score int
+
+	lives int
+)
+
+onStart () => {
+
+	play "bang"
+	score = 0
+
+}
+
+onKey KeyA, (key) => {
+	println("pressed", key)
+}
+
+onMsg "hit", (msg, data) => {
+	lives--
+}
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		params := &DocumentFormattingParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		}
+
+		edits, err := s.textDocumentFormatting(params)
+		require.NoError(t, err)
+		assert.Equal(t, `var (
+	score int
+	lives int
+)
+
+onStart => {
+	play "bang"
+	score = 0
+}
+
+onKey KeyA, key => {
+	println("pressed", key)
+}
+
+onMsg "hit", (msg, data) => {
+	lives--
+}
+`, applyTextEdits(string(m["main.spx"]), edits))
+	})
+
+	t.Run("WithCommentsInEventHandler", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`onStart => {
+
+	// Reset the score.
+	println "start"
+	// Done.
+
+}
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		params := &DocumentFormattingParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		}
+
+		edits, err := s.textDocumentFormatting(params)
+		require.NoError(t, err)
+		assert.Equal(t, `onStart => {
+	// Reset the score.
+	println "start"
+	// Done.
+}
+`, applyTextEdits(string(m["main.spx"]), edits))
+	})
+}
+
+func TestServerSpxFormatProject(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`run "assets",    { Title:    "My Game" }
+`),
+			"MySprite.spx": []byte(`onStart => {
+	println   "a"
+}
+`),
+			"Formatted.spx": []byte(`onStart => {
+	println "b"
+}
+`),
+			"Broken.spx": []byte(`onStart => {
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		result, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{Command: "spx.formatProject"})
+		require.NoError(t, err)
+		require.IsType(t, &WorkspaceEdit{}, result)
+		workspaceEdit := result.(*WorkspaceEdit)
+		require.Len(t, workspaceEdit.Changes, 2)
+		assert.Equal(t, `run "assets", {Title: "My Game"}
+`, applyTextEdits(string(m["main.spx"]), workspaceEdit.Changes["file:///main.spx"]))
+		assert.Equal(t, `onStart => {
+	println "a"
+}
+`, applyTextEdits(string(m["MySprite.spx"]), workspaceEdit.Changes["file:///MySprite.spx"]))
+	})
+
+	t.Run("AlreadyFormatted", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`run "assets", {Title: "My Game"}
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		workspaceEdit, err := s.spxFormatProject()
+		require.NoError(t, err)
+		assert.Empty(t, workspaceEdit.Changes)
+	})
+}
+
+func TestServerTextDocumentOnTypeFormatting(t *testing.T) {
+	onTypeFormatting := func(t *testing.T, mainSpx string, position Position) []TextEdit {
+		m := map[string][]byte{
+			"main.spx": []byte(mainSpx),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		edits, err := s.textDocumentOnTypeFormatting(&DocumentOnTypeFormattingParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Position:     position,
+			Ch:           "}",
+		})
+		require.NoError(t, err)
+		return edits
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		edits := onTypeFormatting(t, `onStart => {
+if true {
+println "a"
+  }
+}
+`, Position{Line: 4, Character: 1})
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 1, Character: 0},
+					End:   Position{Line: 1, Character: 0},
+				},
+				NewText: "\t",
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 2, Character: 0},
+					End:   Position{Line: 2, Character: 0},
+				},
+				NewText: "\t\t",
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 3, Character: 0},
+					End:   Position{Line: 3, Character: 2},
+				},
+				NewText: "\t",
+			},
+		}, edits)
+	})
+
+	t.Run("NestedBlock", func(t *testing.T) {
+		edits := onTypeFormatting(t, `onStart => {
+	if true {
+	println "a"
+	}
+}
+`, Position{Line: 3, Character: 2})
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 2, Character: 0},
+					End:   Position{Line: 2, Character: 1},
+				},
+				NewText: "\t\t",
+			},
+		}, edits)
+	})
+
+	t.Run("LambdaInParens", func(t *testing.T) {
+		edits := onTypeFormatting(t, `onStart(=> {
+println "a"
+})
+`, Position{Line: 2, Character: 1})
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 1, Character: 0},
+					End:   Position{Line: 1, Character: 0},
+				},
+				NewText: "\t",
+			},
+		}, edits)
+	})
+
+	t.Run("SwitchCase", func(t *testing.T) {
+		edits := onTypeFormatting(t, `func f(n int) {
+	switch n {
+		case 1:
+	println "one"
+		default:
+	}
+}
+`, Position{Line: 6, Character: 1})
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 2, Character: 0},
+					End:   Position{Line: 2, Character: 2},
+				},
+				NewText: "\t",
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 3, Character: 0},
+					End:   Position{Line: 3, Character: 1},
+				},
+				NewText: "\t\t",
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 4, Character: 0},
+					End:   Position{Line: 4, Character: 2},
+				},
+				NewText: "\t",
+			},
+		}, edits)
+	})
+
+	t.Run("ContinuationAndRawString", func(t *testing.T) {
+		edits := onTypeFormatting(t, "onStart => {\nn := 1 +\n        2\nprintln `a\n  b`, n\n}\n", Position{Line: 5, Character: 1})
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 1, Character: 0},
+					End:   Position{Line: 1, Character: 0},
+				},
+				NewText: "\t",
+			},
+			{
+				Range: Range{
+					Start: Position{Line: 3, Character: 0},
+					End:   Position{Line: 3, Character: 0},
+				},
+				NewText: "\t",
+			},
+		}, edits)
+	})
+
+	t.Run("UseSpaces", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte("onStart => {\nprintln \"a\"\n}\n"),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, fileMapGetter(m), &MockScheduler{})
+		require.NoError(t, s.didChangeConfiguration(&DidChangeConfigurationParams{
+			Settings: map[string]any{"formatting": map[string]any{"useSpaces": true, "tabWidth": 2}},
+		}))
+		edits, err := s.textDocumentOnTypeFormatting(&DocumentOnTypeFormattingParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Position:     Position{Line: 2, Character: 1},
+			Ch:           "}",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 1, Character: 0},
+					End:   Position{Line: 1, Character: 0},
+				},
+				NewText: "  ",
+			},
+		}, edits)
+	})
+
+	t.Run("AlreadyIndented", func(t *testing.T) {
+		edits := onTypeFormatting(t, `onStart => {
+	println "a"
+}
+`, Position{Line: 2, Character: 1})
+		assert.Nil(t, edits)
+	})
+
+	t.Run("NotAfterClosingBrace", func(t *testing.T) {
+		edits := onTypeFormatting(t, `onStart => {
+println "a"
+}
+`, Position{Line: 1, Character: 3})
+		assert.Nil(t, edits)
+	})
+
+	t.Run("OtherTriggerCharacter", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte("onStart => {\nprintln \"a\"\n}\n"),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		edits, err := s.textDocumentOnTypeFormatting(&DocumentOnTypeFormattingParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Position:     Position{Line: 2, Character: 1},
+			Ch:           ";",
+		})
+		require.NoError(t, err)
+		assert.Nil(t, edits)
+	})
+
+	t.Run("NonSpxFile", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.xgo": []byte("func f() {\nprintln \"a\"\n}\n"),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		edits, err := s.textDocumentOnTypeFormatting(&DocumentOnTypeFormattingParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.xgo"},
+			Position:     Position{Line: 2, Character: 1},
+			Ch:           "}",
+		})
+		require.NoError(t, err)
+		assert.Nil(t, edits)
+	})
 }
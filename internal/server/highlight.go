@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"slices"
 
 	xgoast "github.com/goplus/xgo/ast"
@@ -9,8 +10,8 @@ import (
 )
 
 // See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#textDocument_documentHighlight
-func (s *Server) textDocumentDocumentHighlight(params *DocumentHighlightParams) (*[]DocumentHighlight, error) {
-	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(params.TextDocument.URI)
+func (s *Server) textDocumentDocumentHighlight(ctx context.Context, params *DocumentHighlightParams) (*[]DocumentHighlight, error) {
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(ctx, params.TextDocument.URI)
 	if err != nil {
 		return nil, err
 	}
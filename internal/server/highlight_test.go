@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -27,7 +28,7 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		mySpriteHighlights, err := s.textDocumentDocumentHighlight(&DocumentHighlightParams{
+		mySpriteHighlights, err := s.textDocumentDocumentHighlight(context.Background(), &DocumentHighlightParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 1},
@@ -51,7 +52,7 @@ onStart => {
 			Kind: Read,
 		})
 
-		leftHighlights, err := s.textDocumentDocumentHighlight(&DocumentHighlightParams{
+		leftHighlights, err := s.textDocumentDocumentHighlight(context.Background(), &DocumentHighlightParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 4, Character: 14},
@@ -1,15 +1,22 @@
 package server
 
 import (
+	"context"
+	"fmt"
+	"go/constant"
 	"go/doc"
+	"go/token"
+	"go/types"
+	"slices"
 	"strings"
 
+	xgoast "github.com/goplus/xgo/ast"
 	"github.com/goplus/xgolsw/xgo/xgoutil"
 )
 
 // See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification#textDocument_hover
-func (s *Server) textDocumentHover(params *HoverParams) (*Hover, error) {
-	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(params.TextDocument.URI)
+func (s *Server) textDocumentHover(ctx context.Context, params *HoverParams) (*Hover, error) {
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(ctx, params.TextDocument.URI)
 	if err != nil {
 		return nil, err
 	}
@@ -20,18 +27,28 @@ func (s *Server) textDocumentHover(params *HoverParams) (*Hover, error) {
 		return nil, nil
 	}
 	position := ToPosition(result.proj, astFile, params.Position)
+	format := s.hoverFormat()
 
 	if spxResourceRef := result.spxResourceRefAtASTFilePosition(astFile, position); spxResourceRef != nil {
+		var value string
+		switch format {
+		case hoverFormatHTML:
+			value = s.spxResourcePreviewHTML(result, spxResourceRef.ID)
+		case hoverFormatMarkdown:
+			value = s.spxResourcePreviewText(result, spxResourceRef.ID, true)
+		case hoverFormatPlainText:
+			value = s.spxResourcePreviewText(result, spxResourceRef.ID, false)
+		}
 		return &Hover{
 			Contents: MarkupContent{
-				Kind:  Markdown,
-				Value: spxResourceRef.ID.URI().HTML(),
+				Kind:  format.markupKind(),
+				Value: value,
 			},
 			Range: RangeForNode(result.proj, spxResourceRef.Node),
 		}, nil
 	}
 
-	ident := xgoutil.IdentAtPosition(result.proj, astFile, position)
+	ident, spxDefs := spxDefinitionsAtPosition(result, astFile, params.Position)
 	if ident == nil {
 		// Check if the position is within an import declaration.
 		// If so, return the package documentation.
@@ -39,29 +56,237 @@ func (s *Server) textDocumentHover(params *HoverParams) (*Hover, error) {
 		if rpkg != nil {
 			return &Hover{
 				Contents: MarkupContent{
-					Kind:  Markdown,
+					Kind:  format.markupKind(),
 					Value: doc.Synopsis(rpkg.Pkg.Doc),
 				},
 				Range: RangeForNode(result.proj, rpkg.Node),
 			}, nil
 		}
+
+		// Check if the position is within a constant expression. If so,
+		// return its evaluated value.
+		if expr, tv := constantExprAtPosition(result, astFile, params.Position); expr != nil {
+			valueFormat := "`%s` (%s)"
+			if format == hoverFormatPlainText {
+				valueFormat = "%s (%s)"
+			}
+			return &Hover{
+				Contents: MarkupContent{
+					Kind:  format.markupKind(),
+					Value: fmt.Sprintf(valueFormat, formatConstantValue(tv.Value), GetSimplifiedTypeString(tv.Type)),
+				},
+				Range: RangeForNode(result.proj, expr),
+			}, nil
+		}
 		return nil, nil
 	}
 
-	spxDefs := result.spxDefinitionsForIdent(ident)
 	if spxDefs == nil {
 		return nil, nil
 	}
 
 	var hoverContent strings.Builder
-	for _, spxDef := range spxDefs {
-		hoverContent.WriteString(spxDef.HTML())
+	for i, spxDef := range spxDefs {
+		switch format {
+		case hoverFormatHTML:
+			hoverContent.WriteString(spxDef.HTML())
+		case hoverFormatMarkdown:
+			if i > 0 {
+				hoverContent.WriteString("\n---\n\n")
+			}
+			hoverContent.WriteString(spxDef.Markdown())
+		case hoverFormatPlainText:
+			if i > 0 {
+				hoverContent.WriteString("\n")
+			}
+			hoverContent.WriteString(spxDef.PlainText())
+		}
 	}
 	return &Hover{
 		Contents: MarkupContent{
-			Kind:  Markdown,
+			Kind:  format.markupKind(),
 			Value: hoverContent.String(),
 		},
 		Range: RangeForNode(result.proj, ident),
 	}, nil
 }
+
+// spxDefinitionsAtPosition returns the identifier at the given position and
+// its spx definitions. It returns a nil identifier if there is none.
+func spxDefinitionsAtPosition(result *compileResult, astFile *xgoast.File, position Position) (*xgoast.Ident, []SpxDefinition) {
+	if ident := xgoutil.IdentAtPosition(result.proj, astFile, ToPosition(result.proj, astFile, position)); ident != nil {
+		return ident, result.spxDefinitionsForIdent(ident)
+	}
+	if paramIdent, param := lambdaParamAtPosition(result, astFile, position); param != nil {
+		// Lambda parameters have implicit types, which are not recorded
+		// if the enclosing call fails to type-check.
+		return paramIdent, []SpxDefinition{GetSpxDefinitionForVar(param, "", true, nil)}
+	}
+	return nil, nil
+}
+
+// lambdaParamAtPosition returns the identifier and the variable of the lambda
+// parameter at the given position that is unknown to the type checker, with
+// its type inferred from the signature of the function the lambda is passed to.
+// It returns nil if there is none.
+func lambdaParamAtPosition(result *compileResult, astFile *xgoast.File, position Position) (*xgoast.Ident, *types.Var) {
+	typeInfo, _ := result.proj.TypeInfo()
+	if typeInfo == nil {
+		return nil, nil
+	}
+	pos := PosAt(result.proj, astFile, position)
+	if !pos.IsValid() {
+		return nil, nil
+	}
+	path, _ := xgoutil.PathEnclosingInterval(astFile, pos, pos)
+	if len(path) == 0 {
+		return nil, nil
+	}
+	ident, ok := path[0].(*xgoast.Ident)
+	if !ok || typeInfo.ObjectOf(ident) != nil {
+		return nil, nil
+	}
+
+	for i, node := range path {
+		var lhs []*xgoast.Ident
+		switch node := node.(type) {
+		case *xgoast.LambdaExpr:
+			lhs = node.Lhs
+		case *xgoast.LambdaExpr2:
+			lhs = node.Lhs
+		default:
+			continue
+		}
+		paramIndex := slices.IndexFunc(lhs, func(param *xgoast.Ident) bool {
+			return param.Name == ident.Name
+		})
+		if paramIndex < 0 || i+1 >= len(path) {
+			continue
+		}
+		callExpr, ok := path[i+1].(*xgoast.CallExpr)
+		if !ok {
+			return nil, nil
+		}
+		argIndex := slices.Index(callExpr.Args, node.(xgoast.Expr))
+		fun := xgoutil.FuncFromCallExpr(typeInfo, callExpr)
+		if argIndex < 0 || fun == nil {
+			return nil, nil
+		}
+
+		// Pick the first overload accepting a callback with as many
+		// parameters as the lambda at the argument.
+		for _, funcOverload := range xgoFuncOverloads(fun) {
+			params := funcOverload.Type().(*types.Signature).Params()
+			index := activeParamIndex(funcOverload, argIndex)
+			if isXGotFunc(funcOverload) {
+				index++
+			}
+			if index >= params.Len() {
+				continue
+			}
+			paramType := params.At(index).Type()
+			if funcOverload.Type().(*types.Signature).Variadic() && index == params.Len()-1 {
+				paramType = paramType.(*types.Slice).Elem()
+			}
+			callbackSig, ok := paramType.Underlying().(*types.Signature)
+			if !ok || callbackSig.Params().Len() != len(lhs) {
+				continue
+			}
+			param := types.NewParam(lhs[paramIndex].Pos(), typeInfo.Pkg(), ident.Name, callbackSig.Params().At(paramIndex).Type())
+			return ident, param
+		}
+		return nil, nil
+	}
+	return nil, nil
+}
+
+// hoverFormat is the format of hover contents.
+type hoverFormat int
+
+const (
+	// hoverFormatHTML is Markdown with embedded HTML elements understood by
+	// XBuilder, such as <pre is="definition-item"> and <resource-preview>.
+	hoverFormatHTML hoverFormat = iota
+
+	// hoverFormatMarkdown is plain Markdown without embedded HTML.
+	hoverFormatMarkdown
+
+	// hoverFormatPlainText is plain text.
+	hoverFormatPlainText
+)
+
+// markupKind returns the markup kind of the hover contents in the format.
+func (f hoverFormat) markupKind() MarkupKind {
+	if f == hoverFormatPlainText {
+		return PlainText
+	}
+	return Markdown
+}
+
+// hoverFormat returns the hover content format preferred by the client. Clients
+// that do not declare any content format, like XBuilder, get
+// [hoverFormatHTML].
+func (s *Server) hoverFormat() hoverFormat {
+	caps := s.clientCapabilities.TextDocument.Hover
+	if caps == nil || len(caps.ContentFormat) == 0 {
+		return hoverFormatHTML
+	}
+	for _, kind := range caps.ContentFormat {
+		switch kind {
+		case Markdown:
+			return hoverFormatMarkdown
+		case PlainText:
+			return hoverFormatPlainText
+		}
+	}
+	return hoverFormatPlainText
+}
+
+// constantExprAtPosition returns the outermost constant expression enclosing
+// the given position, along with its type and value. Literals and identifiers
+// on their own are not considered constant expressions.
+func constantExprAtPosition(result *compileResult, astFile *xgoast.File, position Position) (xgoast.Expr, types.TypeAndValue) {
+	typeInfo, _ := result.proj.TypeInfo()
+	if typeInfo == nil {
+		return nil, types.TypeAndValue{}
+	}
+	pos := PosAt(result.proj, astFile, position)
+	if !pos.IsValid() {
+		return nil, types.TypeAndValue{}
+	}
+
+	var (
+		constExpr xgoast.Expr
+		constTV   types.TypeAndValue
+	)
+	path, _ := xgoutil.PathEnclosingInterval(astFile, pos, pos)
+	for _, node := range path {
+		expr, ok := node.(xgoast.Expr)
+		if !ok {
+			break
+		}
+		tv, ok := typeInfo.Types[expr]
+		if !ok || tv.Value == nil {
+			break
+		}
+		constExpr, constTV = expr, tv
+	}
+	switch constExpr.(type) {
+	case nil, *xgoast.BasicLit, *xgoast.Ident:
+		return nil, types.TypeAndValue{}
+	}
+	return constExpr, constTV
+}
+
+// formatConstantValue formats the given constant value for display. Decimal
+// representations that are not exact, such as those of fractions, are followed
+// by the exact value.
+func formatConstantValue(v constant.Value) string {
+	s := v.String()
+	if v.Kind() == constant.Float {
+		if approx := constant.MakeFromLiteral(s, token.FLOAT, 0); approx.Kind() == constant.Unknown || !constant.Compare(approx, token.EQL, v) {
+			s += " (" + v.ExactString() + ")"
+		}
+	}
+	return s
+}
@@ -1,8 +1,11 @@
 package server
 
 import (
+	"context"
 	"testing"
+	"testing/fstest"
 
+	"github.com/goplus/xgolsw/protocol"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -69,7 +72,7 @@ onTouchStart "MySprite", => {}
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		mySoundHover, err := s.textDocumentHover(&HoverParams{
+		mySoundHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 7, Character: 1},
@@ -88,7 +91,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, mySoundHover)
 
-		mySpriteHover, err := s.textDocumentHover(&HoverParams{
+		mySpriteHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 8, Character: 1},
@@ -99,7 +102,7 @@ onTouchStart "MySprite", => {}
 		assert.Equal(t, &Hover{
 			Contents: MarkupContent{
 				Kind:  Markdown,
-				Value: "<resource-preview resource=\"spx://resources/sprites/MySprite\" />\n",
+				Value: "<resource-preview resource=\"spx://resources/sprites/MySprite\" frames=\"1\" />\n",
 			},
 			Range: Range{
 				Start: Position{Line: 8, Character: 1},
@@ -107,7 +110,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, mySpriteHover)
 
-		varHover, err := s.textDocumentHover(&HoverParams{
+		varHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 11, Character: 1},
@@ -126,7 +129,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, varHover)
 
-		constHover, err := s.textDocumentHover(&HoverParams{
+		constHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 17, Character: 6},
@@ -137,7 +140,7 @@ onTouchStart "MySprite", => {}
 		assert.Equal(t, &Hover{
 			Contents: MarkupContent{
 				Kind:  Markdown,
-				Value: "<pre is=\"definition-item\" def-id=\"xgo:main?MaxCount\" overview=\"const MaxCount = 100\">\nMaxCount is a constant.\n</pre>\n",
+				Value: "<pre is=\"definition-item\" def-id=\"xgo:main?MaxCount\" overview=\"const MaxCount untyped int = 100\">\nMaxCount is a constant.\n</pre>\n",
 			},
 			Range: Range{
 				Start: Position{Line: 17, Character: 6},
@@ -145,7 +148,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, constHover)
 
-		funcHover, err := s.textDocumentHover(&HoverParams{
+		funcHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 20, Character: 5},
@@ -164,7 +167,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, funcHover)
 
-		typeHover, err := s.textDocumentHover(&HoverParams{
+		typeHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 25, Character: 5},
@@ -183,7 +186,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, typeHover)
 
-		typeFieldHover, err := s.textDocumentHover(&HoverParams{
+		typeFieldHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 27, Character: 1},
@@ -202,7 +205,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, typeFieldHover)
 
-		pkgHover, err := s.textDocumentHover(&HoverParams{
+		pkgHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 33, Character: 0},
@@ -215,7 +218,7 @@ onTouchStart "MySprite", => {}
 			End:   Position{Line: 33, Character: 3},
 		}, pkgHover.Range)
 
-		pkgFuncHover, err := s.textDocumentHover(&HoverParams{
+		pkgFuncHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 33, Character: 4},
@@ -228,7 +231,7 @@ onTouchStart "MySprite", => {}
 			End:   Position{Line: 33, Character: 11},
 		}, pkgFuncHover.Range)
 
-		builtinFuncHover, err := s.textDocumentHover(&HoverParams{
+		builtinFuncHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 33, Character: 12},
@@ -247,7 +250,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, builtinFuncHover)
 
-		mySoundRefHover, err := s.textDocumentHover(&HoverParams{
+		mySoundRefHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 35, Character: 5},
@@ -266,7 +269,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, mySoundRefHover)
 
-		mySpriteRefHover, err := s.textDocumentHover(&HoverParams{
+		mySpriteRefHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 36, Character: 0},
@@ -277,7 +280,7 @@ onTouchStart "MySprite", => {}
 		assert.Equal(t, &Hover{
 			Contents: MarkupContent{
 				Kind:  Markdown,
-				Value: "<resource-preview resource=\"spx://resources/sprites/MySprite\" />\n",
+				Value: "<resource-preview resource=\"spx://resources/sprites/MySprite\" frames=\"1\" />\n",
 			},
 			Range: Range{
 				Start: Position{Line: 36, Character: 0},
@@ -285,7 +288,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, mySpriteRefHover)
 
-		mySpriteCostumeRefHover, err := s.textDocumentHover(&HoverParams{
+		mySpriteCostumeRefHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 37, Character: 20},
@@ -296,7 +299,7 @@ onTouchStart "MySprite", => {}
 		assert.Equal(t, &Hover{
 			Contents: MarkupContent{
 				Kind:  Markdown,
-				Value: "<resource-preview resource=\"spx://resources/sprites/MySprite/costumes/costume1\" />\n",
+				Value: "<resource-preview resource=\"spx://resources/sprites/MySprite/costumes/costume1\" frames=\"1\" />\n",
 			},
 			Range: Range{
 				Start: Position{Line: 37, Character: 20},
@@ -304,7 +307,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, mySpriteCostumeRefHover)
 
-		mySpriteSetCostumeFuncHover, err := s.textDocumentHover(&HoverParams{
+		mySpriteSetCostumeFuncHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 37, Character: 9},
@@ -317,7 +320,7 @@ onTouchStart "MySprite", => {}
 			End:   Position{Line: 37, Character: 19},
 		}, mySpriteSetCostumeFuncHover.Range)
 
-		GameOnClickHover, err := s.textDocumentHover(&HoverParams{
+		GameOnClickHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 38, Character: 5},
@@ -336,7 +339,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, GameOnClickHover)
 
-		mainSpxOnClickHover, err := s.textDocumentHover(&HoverParams{
+		mainSpxOnClickHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 39, Character: 0},
@@ -355,7 +358,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, mainSpxOnClickHover)
 
-		mainSpxOnHover, err := s.textDocumentHover(&HoverParams{
+		mainSpxOnHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 40, Character: 0},
@@ -369,7 +372,7 @@ onTouchStart "MySprite", => {}
 			End:   Position{Line: 40, Character: 2},
 		}, mainSpxOnHover.Range)
 
-		mySpriteOnClickFuncHover, err := s.textDocumentHover(&HoverParams{
+		mySpriteOnClickFuncHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
 				Position:     Position{Line: 1, Character: 9},
@@ -380,7 +383,7 @@ onTouchStart "MySprite", => {}
 		assert.Equal(t, &Hover{
 			Contents: MarkupContent{
 				Kind:  Markdown,
-				Value: "<pre is=\"definition-item\" def-id=\"xgo:github.com/goplus/spx/v2?Sprite.onClick\" overview=\"func onClick(onClick func())\">\n</pre>\n",
+				Value: "<pre is=\"definition-item\" def-id=\"xgo:github.com/goplus/spx/v2?Sprite.onClick\" overview=\"func onClick(onClick func())\">\nResolved through `Game → Sprite → onClick`.\n</pre>\n",
 			},
 			Range: Range{
 				Start: Position{Line: 1, Character: 9},
@@ -388,7 +391,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, mySpriteOnClickFuncHover)
 
-		mySpriteSpxOnClickFuncHover, err := s.textDocumentHover(&HoverParams{
+		mySpriteSpxOnClickFuncHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
 				Position:     Position{Line: 2, Character: 0},
@@ -407,7 +410,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, mySpriteSpxOnClickFuncHover)
 
-		mySpriteCloneFuncHover, err := s.textDocumentHover(&HoverParams{
+		mySpriteCloneFuncHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
 				Position:     Position{Line: 5, Character: 1},
@@ -426,7 +429,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, mySpriteCloneFuncHover)
 
-		imagePointFieldHover, err := s.textDocumentHover(&HoverParams{
+		imagePointFieldHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
 				Position:     Position{Line: 6, Character: 12},
@@ -437,7 +440,7 @@ onTouchStart "MySprite", => {}
 		assert.Equal(t, &Hover{
 			Contents: MarkupContent{
 				Kind:  Markdown,
-				Value: "<pre is=\"definition-item\" def-id=\"xgo:image?Point.X\" overview=\"field X int\">\n</pre>\n",
+				Value: "<pre is=\"definition-item\" def-id=\"xgo:image?Point.X\" overview=\"field X int\">\nResolved through `Game → Point → X`.\n</pre>\n",
 			},
 			Range: Range{
 				Start: Position{Line: 6, Character: 12},
@@ -445,7 +448,7 @@ onTouchStart "MySprite", => {}
 			},
 		}, imagePointFieldHover)
 
-		onTouchStartFirstArgHover, err := s.textDocumentHover(&HoverParams{
+		onTouchStartFirstArgHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
 				Position:     Position{Line: 8, Character: 14},
@@ -456,7 +459,7 @@ onTouchStart "MySprite", => {}
 		assert.Equal(t, &Hover{
 			Contents: MarkupContent{
 				Kind:  Markdown,
-				Value: "<resource-preview resource=\"spx://resources/sprites/MySprite\" />\n",
+				Value: "<resource-preview resource=\"spx://resources/sprites/MySprite\" frames=\"1\" />\n",
 			},
 			Range: Range{
 				Start: Position{Line: 8, Character: 13},
@@ -465,13 +468,300 @@ onTouchStart "MySprite", => {}
 		}, onTouchStartFirstArgHover)
 	})
 
+	t.Run("SpriteCostumeIndex", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx": []byte(`
+onStart => {
+	setCostume 1
+}
+`),
+			"assets/index.json":                  []byte(`{}`),
+			"assets/sprites/MySprite/index.json": []byte(`{"costumes":[{"name":"c1"},{"name":"c2"}]}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		hover, err := s.textDocumentHover(context.Background(), &HoverParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
+				Position:     Position{Line: 2, Character: 12},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, hover)
+		assert.Equal(t, "<resource-preview resource=\"spx://resources/sprites/MySprite/costumes/c2\" frames=\"1\" />\n", hover.Contents.Value)
+		assert.Equal(t, Range{
+			Start: Position{Line: 2, Character: 12},
+			End:   Position{Line: 2, Character: 13},
+		}, hover.Range)
+	})
+
+	t.Run("ConstantExpr", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+const Third float64 = 1.0 / 3
+const Steps = 360/8 * 3
+
+onStart => {
+	echo Steps + 1
+	echo 42
+}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		exprHover, err := s.textDocumentHover(context.Background(), &HoverParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 2, Character: 17},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, &Hover{
+			Contents: MarkupContent{
+				Kind:  Markdown,
+				Value: "`135` (untyped int)",
+			},
+			Range: Range{
+				Start: Position{Line: 2, Character: 14},
+				End:   Position{Line: 2, Character: 23},
+			},
+		}, exprHover)
+
+		useHover, err := s.textDocumentHover(context.Background(), &HoverParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 5, Character: 12},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, useHover)
+		assert.Equal(t, "`136` (untyped int)", useHover.Contents.Value)
+
+		constHover, err := s.textDocumentHover(context.Background(), &HoverParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 1, Character: 6},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, constHover)
+		assert.Contains(t, constHover.Contents.Value, `overview="const Third float64 = 0.333333 (1/3)"`)
+
+		literalHover, err := s.textDocumentHover(context.Background(), &HoverParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 6, Character: 7},
+			},
+		})
+		require.NoError(t, err)
+		assert.Nil(t, literalHover)
+	})
+
+	t.Run("DeprecatedAndExamples", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+import "strings"
+
+// Greet says hello. For example:
+//
+//	Greet "Nick"
+//
+// Deprecated: Use say instead.
+func Greet(name string) {}
+
+onStart => {
+	Greet "Nick"
+	echo strings.Title("hello")
+}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		hover := func(position Position) *Hover {
+			hover, err := s.textDocumentHover(context.Background(), &HoverParams{
+				TextDocumentPositionParams: TextDocumentPositionParams{
+					TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+					Position:     position,
+				},
+			})
+			require.NoError(t, err)
+			require.NotNil(t, hover)
+			return hover
+		}
+
+		assert.Equal(t, "<pre is=\"definition-item\" def-id=\"xgo:main?Game.Greet\" overview=\"func Greet(name string)\">\n**Deprecated:** Use say instead.\n\nGreet says hello. For example:\n\n```go\nGreet \"Nick\"\n```\n</pre>\n", hover(Position{Line: 11, Character: 2}).Contents.Value)
+		assert.Contains(t, hover(Position{Line: 12, Character: 16}).Contents.Value, "\n**Deprecated:** ")
+	})
+
+	t.Run("SelectorChain", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+type Point struct {
+	X int
+}
+
+type Shape struct {
+	Point
+	Name string
+}
+
+var shape Shape
+
+onStart => {
+	echo shape.X, shape.Name
+}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		hover := func(position Position) *Hover {
+			hover, err := s.textDocumentHover(context.Background(), &HoverParams{
+				TextDocumentPositionParams: TextDocumentPositionParams{
+					TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+					Position:     position,
+				},
+			})
+			require.NoError(t, err)
+			require.NotNil(t, hover)
+			return hover
+		}
+
+		assert.Equal(t, "<pre is=\"definition-item\" def-id=\"xgo:main?Point.X\" overview=\"field X int\">\nResolved through `Game → Shape → Point → X`.\n</pre>\n", hover(Position{Line: 13, Character: 13}).Contents.Value)
+		assert.Equal(t, "<pre is=\"definition-item\" def-id=\"xgo:main?Shape.Name\" overview=\"field Name string\">\nResolved through `Game → Shape → Name`.\n</pre>\n", hover(Position{Line: 13, Character: 22}).Contents.Value)
+	})
+
+	t.Run("LambdaParams", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+func each(items []int, fn func(i int, s string)) {}
+
+onMsg (msg, data) => {
+	echo msg
+}
+each undefinedItems, (i, s) => {
+	echo s
+}
+`),
+			"MySprite.spx": []byte(`
+onTouchStart undefinedSprite, s => {
+	echo s
+}
+`),
+			"assets/index.json":                  []byte(`{}`),
+			"assets/sprites/MySprite/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		hover := func(uri DocumentURI, position Position) *Hover {
+			hover, err := s.textDocumentHover(context.Background(), &HoverParams{
+				TextDocumentPositionParams: TextDocumentPositionParams{
+					TextDocument: TextDocumentIdentifier{URI: uri},
+					Position:     position,
+				},
+			})
+			require.NoError(t, err)
+			return hover
+		}
+
+		msgHover := hover("file:///main.spx", Position{Line: 4, Character: 7})
+		require.NotNil(t, msgHover)
+		assert.Contains(t, msgHover.Contents.Value, `overview="var msg string"`)
+
+		paramHover := hover("file:///main.spx", Position{Line: 6, Character: 22})
+		assert.Equal(t, &Hover{
+			Contents: MarkupContent{
+				Kind:  Markdown,
+				Value: "<pre is=\"definition-item\" def-id=\"xgo:main?i\" overview=\"var i int\">\n</pre>\n",
+			},
+			Range: Range{
+				Start: Position{Line: 6, Character: 22},
+				End:   Position{Line: 6, Character: 23},
+			},
+		}, paramHover)
+
+		paramUseHover := hover("file:///main.spx", Position{Line: 7, Character: 6})
+		require.NotNil(t, paramUseHover)
+		assert.Contains(t, paramUseHover.Contents.Value, `overview="var s string"`)
+
+		spriteParamHover := hover("file:///MySprite.spx", Position{Line: 2, Character: 6})
+		require.NotNil(t, spriteParamHover)
+		assert.Contains(t, spriteParamHover.Contents.Value, `overview="var s Sprite"`)
+
+		assert.Nil(t, hover("file:///main.spx", Position{Line: 6, Character: 7}))
+	})
+
+	t.Run("ContentFormat", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	MySound Sound
+)
+
+// MaxCount is a constant.
+const MaxCount = 100
+
+play "MySound"
+echo 360/8 * 3
+`),
+			"assets/index.json":                []byte(`{}`),
+			"assets/sounds/MySound/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		hover := func(position Position) *Hover {
+			hover, err := s.textDocumentHover(context.Background(), &HoverParams{
+				TextDocumentPositionParams: TextDocumentPositionParams{
+					TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+					Position:     position,
+				},
+			})
+			require.NoError(t, err)
+			require.NotNil(t, hover)
+			return hover
+		}
+
+		s.clientCapabilities.TextDocument.Hover = &protocol.HoverClientCapabilities{
+			ContentFormat: []MarkupKind{Markdown, PlainText},
+		}
+		assert.Equal(t, MarkupContent{
+			Kind:  Markdown,
+			Value: "```xgo\nconst MaxCount untyped int = 100\n```\n\nMaxCount is a constant.\n",
+		}, hover(Position{Line: 6, Character: 7}).Contents)
+		assert.Equal(t, MarkupContent{
+			Kind:  Markdown,
+			Value: "`spx://resources/sounds/MySound`\n",
+		}, hover(Position{Line: 8, Character: 8}).Contents)
+		assert.Equal(t, MarkupContent{
+			Kind:  Markdown,
+			Value: "`135` (untyped int)",
+		}, hover(Position{Line: 9, Character: 8}).Contents)
+
+		s.clientCapabilities.TextDocument.Hover = &protocol.HoverClientCapabilities{
+			ContentFormat: []MarkupKind{PlainText},
+		}
+		assert.Equal(t, MarkupContent{
+			Kind:  PlainText,
+			Value: "const MaxCount untyped int = 100\n\nMaxCount is a constant.\n",
+		}, hover(Position{Line: 6, Character: 7}).Contents)
+		assert.Equal(t, MarkupContent{
+			Kind:  PlainText,
+			Value: "spx://resources/sounds/MySound\n",
+		}, hover(Position{Line: 8, Character: 8}).Contents)
+		assert.Equal(t, MarkupContent{
+			Kind:  PlainText,
+			Value: "135 (untyped int)",
+		}, hover(Position{Line: 9, Character: 8}).Contents)
+	})
+
 	t.Run("InvalidPosition", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.spx": []byte(`var x int`),
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		hover, err := s.textDocumentHover(&HoverParams{
+		hover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 99, Character: 99},
@@ -499,7 +789,7 @@ fmt.Println("Hello, World!")
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		importHover, err := s.textDocumentHover(&HoverParams{
+		importHover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 1},
@@ -528,7 +818,7 @@ nums = append(nums, 1)
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		hover, err := s.textDocumentHover(&HoverParams{
+		hover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 7},
@@ -552,7 +842,7 @@ echo num
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		hover1, err := s.textDocumentHover(&HoverParams{
+		hover1, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 1, Character: 8},
@@ -566,7 +856,7 @@ echo num
 			End:   Position{Line: 1, Character: 14},
 		}, hover1.Range)
 
-		hover2, err := s.textDocumentHover(&HoverParams{
+		hover2, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 0},
@@ -593,7 +883,7 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		hover1, err := s.textDocumentHover(&HoverParams{
+		hover1, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 3, Character: 15},
@@ -607,7 +897,7 @@ onStart => {
 			End:   Position{Line: 3, Character: 15},
 		}, hover1.Range)
 
-		hover2, err := s.textDocumentHover(&HoverParams{
+		hover2, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 3, Character: 18},
@@ -616,7 +906,7 @@ onStart => {
 		require.NoError(t, err)
 		require.Nil(t, hover2)
 
-		hover3, err := s.textDocumentHover(&HoverParams{
+		hover3, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 4, Character: 18},
@@ -641,7 +931,7 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		hover, err := s.textDocumentHover(&HoverParams{
+		hover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 1},
@@ -668,7 +958,7 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		hover, err := s.textDocumentHover(&HoverParams{
+		hover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 1},
@@ -685,6 +975,147 @@ onStart => {
 		}, hover.Range)
 	})
 
+	t.Run("ImportedPkgsWithoutPkgdata", func(t *testing.T) {
+		m := map[string][]byte{
+			"go.mod": []byte("module example.com/game\n\nrequire example.com/greet v1.0.0\n"),
+			"main.spx": []byte(`
+import (
+	"example.com/game/utils"
+	"example.com/greet"
+)
+
+echo greet.Hello("spx"), utils.Double(2)
+run "assets", {Title: "My Game"}
+`),
+			"utils/math.xgo": []byte(`package utils
+
+// Double returns twice n.
+func Double(n int) int {
+	return n * 2
+}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		proj := newMapFSWithoutModTime(m)
+		proj.ModCache = fstest.MapFS{
+			"example.com/greet@v1.0.0/greet.go": {Data: []byte(`// Package greet greets people.
+package greet
+
+// Hello returns a greeting for name.
+func Hello(name string) string {
+	return "Hello, " + name
+}
+`)},
+		}
+		s := New(proj, nil, fileMapGetter(m), &MockScheduler{})
+		hover := func(position Position) *Hover {
+			hover, err := s.textDocumentHover(context.Background(), &HoverParams{
+				TextDocumentPositionParams: TextDocumentPositionParams{
+					TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+					Position:     position,
+				},
+			})
+			require.NoError(t, err)
+			require.NotNil(t, hover)
+			return hover
+		}
+
+		assert.Contains(t, hover(Position{Line: 6, Character: 12}).Contents.Value, "Hello returns a greeting for name.")
+		assert.Contains(t, hover(Position{Line: 6, Character: 33}).Contents.Value, "Double returns twice n.")
+		assert.Contains(t, hover(Position{Line: 3, Character: 2}).Contents.Value, "Package greet greets people.")
+	})
+
+	t.Run("ImportedPkgExamples", func(t *testing.T) {
+		m := map[string][]byte{
+			"go.mod": []byte("module example.com/game\n\nrequire example.com/greet v1.0.0\n"),
+			"main.spx": []byte(`
+import "example.com/greet"
+
+echo greet.Hello("spx")
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		proj := newMapFSWithoutModTime(m)
+		proj.ModCache = fstest.MapFS{
+			"example.com/greet@v1.0.0/greet.go": {Data: []byte(`package greet
+
+// Hello returns a greeting for name.
+func Hello(name string) string {
+	return "Hello, " + name
+}
+`)},
+			"example.com/greet@v1.0.0/greet_test.go": {Data: []byte(`package greet_test
+
+import (
+	"fmt"
+
+	"example.com/greet"
+)
+
+func ExampleHello() {
+	fmt.Println(greet.Hello("Nick"))
+	// Output: Hello, Nick
+}
+`)},
+		}
+		s := New(proj, nil, fileMapGetter(m), &MockScheduler{})
+
+		hover, err := s.textDocumentHover(context.Background(), &HoverParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 3, Character: 12},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, hover)
+		assert.Equal(t, "<pre is=\"definition-item\" def-id=\"xgo:example.com/greet?hello\" overview=\"func hello(name string) string\">\nHello returns a greeting for name.\n\n```go\nfmt.Println(greet.Hello(\"Nick\"))\n\n// Output:\n// Hello, Nick\n```\n</pre>\n", hover.Contents.Value)
+	})
+
+	t.Run("ImportedPkgOverloads", func(t *testing.T) {
+		m := map[string][]byte{
+			"go.mod": []byte("module example.com/game\n\nrequire example.com/shape v1.0.0\n"),
+			"main.spx": []byte(`
+import "example.com/shape"
+
+shape.draw 1
+shape.draw 1, 2
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		proj := newMapFSWithoutModTime(m)
+		proj.ModCache = fstest.MapFS{
+			"example.com/shape@v1.0.0/shape.go": {Data: []byte(`package shape
+
+const GopPackage = true
+
+// Draw draws a circle or a rectangle.
+const Gopo_Draw = "drawCircle,"
+
+func drawCircle(r int) {}
+
+// Draw__1 draws a rectangle.
+func Draw__1(w, h int) {}
+`)},
+		}
+		s := New(proj, nil, fileMapGetter(m), &MockScheduler{})
+		hover := func(position Position) *Hover {
+			hover, err := s.textDocumentHover(context.Background(), &HoverParams{
+				TextDocumentPositionParams: TextDocumentPositionParams{
+					TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+					Position:     position,
+				},
+			})
+			require.NoError(t, err)
+			require.NotNil(t, hover)
+			return hover
+		}
+
+		assert.Contains(t, hover(Position{Line: 3, Character: 7}).Contents.Value, "\nDraw draws a circle or a rectangle.\n")
+		assert.Contains(t, hover(Position{Line: 4, Character: 7}).Contents.Value, "\nDraw__1 draws a rectangle.\n")
+	})
+
 	t.Run("StartWithInvalidChar", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.spx": []byte(`
@@ -695,7 +1126,7 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		hover, err := s.textDocumentHover(&HoverParams{
+		hover, err := s.textDocumentHover(context.Background(), &HoverParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 1},
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -28,7 +29,7 @@ var x MyInterface
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		implementations, err := s.textDocumentImplementation(&ImplementationParams{
+		implementations, err := s.textDocumentImplementation(context.Background(), &ImplementationParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 1},
@@ -65,7 +66,7 @@ func (t MyType) myMethod() {}
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		implementation, err := s.textDocumentImplementation(&ImplementationParams{
+		implementation, err := s.textDocumentImplementation(context.Background(), &ImplementationParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 3, Character: 16},
@@ -92,7 +93,7 @@ type MyType struct{}
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		implementation, err := s.textDocumentImplementation(&ImplementationParams{
+		implementation, err := s.textDocumentImplementation(context.Background(), &ImplementationParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 99, Character: 99},
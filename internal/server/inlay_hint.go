@@ -2,17 +2,26 @@ package server
 
 import (
 	"cmp"
+	"context"
+	"go/constant"
 	"go/types"
 	"slices"
+	"strings"
 
 	xgoast "github.com/goplus/xgo/ast"
 	xgotoken "github.com/goplus/xgo/token"
+	"github.com/goplus/xgolsw/xgo"
 	"github.com/goplus/xgolsw/xgo/xgoutil"
 )
 
 // See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#textDocument_inlayHint
-func (s *Server) textDocumentInlayHint(params *InlayHintParams) ([]InlayHint, error) {
-	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(params.TextDocument.URI)
+func (s *Server) textDocumentInlayHint(ctx context.Context, params *InlayHintParams) ([]InlayHint, error) {
+	settings := s.getSettings().InlayHints
+	if !settings.anyEnabled() {
+		return nil, nil
+	}
+
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(ctx, params.TextDocument.URI)
 	if err != nil {
 		return nil, err
 	}
@@ -25,13 +34,13 @@ func (s *Server) textDocumentInlayHint(params *InlayHintParams) ([]InlayHint, er
 
 	rangeStart := PosAt(result.proj, astFile, params.Range.Start)
 	rangeEnd := PosAt(result.proj, astFile, params.Range.End)
-	return collectInlayHints(result, astFile, rangeStart, rangeEnd), nil
+	return collectInlayHints(result, astFile, rangeStart, rangeEnd, settings), nil
 }
 
-// collectInlayHints collects inlay hints from the given AST file. If
-// rangeStart and rangeEnd positions are provided (non-zero), only hints within
-// the range are included.
-func collectInlayHints(result *compileResult, astFile *xgoast.File, rangeStart, rangeEnd xgotoken.Pos) []InlayHint {
+// collectInlayHints collects inlay hints of the kinds enabled by settings from
+// the given AST file. If rangeStart and rangeEnd positions are provided
+// (non-zero), only hints within the range are included.
+func collectInlayHints(result *compileResult, astFile *xgoast.File, rangeStart, rangeEnd xgotoken.Pos, settings InlayHintSettings) []InlayHint {
 	typeInfo, _ := result.proj.TypeInfo()
 	if typeInfo == nil {
 		return nil
@@ -53,11 +62,14 @@ func collectInlayHints(result *compileResult, astFile *xgoast.File, rangeStart,
 		switch node := node.(type) {
 		case *xgoast.BranchStmt:
 			if callExpr := xgoutil.CreateCallExprFromBranchStmt(typeInfo, node); callExpr != nil {
-				hints := collectInlayHintsFromCallExpr(result, callExpr)
+				hints := collectInlayHintsFromCallExpr(result, callExpr, settings)
 				inlayHints = append(inlayHints, hints...)
 			}
 		case *xgoast.CallExpr:
-			hints := collectInlayHintsFromCallExpr(result, node)
+			hints := collectInlayHintsFromCallExpr(result, node, settings)
+			inlayHints = append(inlayHints, hints...)
+		case *xgoast.AssignStmt:
+			hints := collectInlayHintsFromAssignStmt(result, node, settings)
 			inlayHints = append(inlayHints, hints...)
 		}
 		return true
@@ -67,7 +79,7 @@ func collectInlayHints(result *compileResult, astFile *xgoast.File, rangeStart,
 }
 
 // collectInlayHintsFromCallExpr collects inlay hints from a call expression.
-func collectInlayHintsFromCallExpr(result *compileResult, callExpr *xgoast.CallExpr) []InlayHint {
+func collectInlayHintsFromCallExpr(result *compileResult, callExpr *xgoast.CallExpr, settings InlayHintSettings) []InlayHint {
 	astFile := xgoutil.NodeASTFile(result.proj, callExpr)
 	if astFile == nil {
 		return nil
@@ -80,6 +92,29 @@ func collectInlayHintsFromCallExpr(result *compileResult, callExpr *xgoast.CallE
 
 	var inlayHints []InlayHint
 	xgoutil.WalkCallExprArgs(typeInfo, callExpr, func(fun *types.Func, params *types.Tuple, paramIndex int, arg xgoast.Expr, argIndex int) bool {
+		if settings.ImplicitConversions {
+			paramType := params.At(paramIndex).Type()
+			if fun.Signature().Variadic() && paramIndex == params.Len()-1 && !callExpr.Ellipsis.IsValid() {
+				if slice, ok := paramType.(*types.Slice); ok {
+					paramType = slice.Elem()
+				}
+			}
+			if label, ok := implicitConversionLabel(typeInfo, arg, paramType); ok {
+				// Create an inlay hint with the converted value after the argument.
+				position := fset.Position(arg.End())
+				hint := InlayHint{
+					Position:    FromPosition(result.proj, astFile, position),
+					Label:       label,
+					Kind:        Type,
+					PaddingLeft: true,
+				}
+				inlayHints = append(inlayHints, hint)
+			}
+		}
+
+		if !settings.ParameterNames {
+			return true
+		}
 		if paramIndex < argIndex {
 			// Stop processing variadic arguments beyond the declared parameters.
 			return false
@@ -91,9 +126,17 @@ func collectInlayHintsFromCallExpr(result *compileResult, callExpr *xgoast.CallE
 			return true
 		}
 
+		paramName := params.At(paramIndex).Name()
+		if len(paramName) < settings.MinParameterNameLength {
+			return true
+		}
+		if settings.SkipMatchingParameterNames && isArgNamedAfterParam(arg, paramName) {
+			return true
+		}
+
 		// Create an inlay hint with the parameter name before the argument.
 		position := fset.Position(arg.Pos())
-		label := params.At(paramIndex).Name()
+		label := paramName
 		if fun.Signature().Variadic() && argIndex == params.Len()-1 {
 			label += "..."
 		}
@@ -108,6 +151,118 @@ func collectInlayHintsFromCallExpr(result *compileResult, callExpr *xgoast.CallE
 	return inlayHints
 }
 
+// isArgNamedAfterParam reports whether the given argument is an identifier or
+// a selector named after the given parameter, ignoring case.
+func isArgNamedAfterParam(arg xgoast.Expr, paramName string) bool {
+	switch arg := arg.(type) {
+	case *xgoast.Ident:
+		return strings.EqualFold(arg.Name, paramName)
+	case *xgoast.SelectorExpr:
+		return strings.EqualFold(arg.Sel.Name, paramName)
+	}
+	return false
+}
+
+// implicitConversionLabel returns the inlay hint label for the given untyped
+// constant argument if it is implicitly converted between integer and float
+// when passed as the given parameter type. Integer literals passed as floats
+// are not reported, since their values are obviously unchanged.
+func implicitConversionLabel(typeInfo *xgo.TypeInfo, arg xgoast.Expr, paramType types.Type) (string, bool) {
+	tv, ok := typeInfo.Types[arg]
+	if !ok || tv.Value == nil {
+		return "", false
+	}
+	argType, ok := tv.Type.(*types.Basic)
+	if !ok || argType.Info()&types.IsUntyped == 0 {
+		return "", false
+	}
+	paramBasicType, ok := paramType.Underlying().(*types.Basic)
+	if !ok {
+		return "", false
+	}
+
+	var value constant.Value
+	switch {
+	case argType.Info()&types.IsInteger != 0 && paramBasicType.Info()&types.IsFloat != 0:
+		if _, ok := arg.(*xgoast.BasicLit); ok {
+			return "", false
+		}
+		value = constant.ToFloat(tv.Value)
+	case argType.Info()&types.IsFloat != 0 && paramBasicType.Info()&types.IsInteger != 0:
+		value = constant.ToInt(tv.Value)
+	default:
+		return "", false
+	}
+	if value.Kind() == constant.Unknown {
+		return "", false
+	}
+	return "→ " + GetSimplifiedTypeString(paramType) + "(" + formatConstantValue(value) + ")", true
+}
+
+// collectInlayHintsFromAssignStmt collects inlay hints with the types of the
+// variables assigned by the given assignment statement.
+func collectInlayHintsFromAssignStmt(result *compileResult, assignStmt *xgoast.AssignStmt, settings InlayHintSettings) []InlayHint {
+	astFile := xgoutil.NodeASTFile(result.proj, assignStmt)
+	if astFile == nil {
+		return nil
+	}
+	typeInfo, _ := result.proj.TypeInfo()
+	if typeInfo == nil {
+		return nil
+	}
+	fset := result.proj.Fset
+
+	var varTypes []types.Type
+	if len(assignStmt.Lhs) > 1 && len(assignStmt.Rhs) == 1 {
+		// Hint the result types of a call returning multiple values.
+		callExpr, ok := assignStmt.Rhs[0].(*xgoast.CallExpr)
+		if !ok || !settings.ReturnValues {
+			return nil
+		}
+		results, ok := typeInfo.TypeOf(callExpr).(*types.Tuple)
+		if !ok || results.Len() != len(assignStmt.Lhs) {
+			return nil
+		}
+		for i := range results.Len() {
+			varTypes = append(varTypes, results.At(i).Type())
+		}
+	} else {
+		// Hint the types of the variables declared with inferred types.
+		if assignStmt.Tok != xgotoken.DEFINE || !settings.VariableTypes {
+			return nil
+		}
+		for _, lhs := range assignStmt.Lhs {
+			ident, ok := lhs.(*xgoast.Ident)
+			if !ok {
+				return nil
+			}
+			var varType types.Type
+			if obj := typeInfo.Defs[ident]; obj != nil {
+				varType = obj.Type()
+			}
+			varTypes = append(varTypes, varType)
+		}
+	}
+
+	inlayHints := make([]InlayHint, 0, len(assignStmt.Lhs))
+	for i, lhs := range assignStmt.Lhs {
+		if varTypes[i] == nil || varTypes[i] == types.Typ[types.Invalid] {
+			// Skip variables that are redeclared or failed to type-check.
+			continue
+		}
+
+		// Create an inlay hint with the type after the variable.
+		position := fset.Position(lhs.End())
+		hint := InlayHint{
+			Position: FromPosition(result.proj, astFile, position),
+			Label:    ": " + GetSimplifiedTypeString(varTypes[i]),
+			Kind:     Type,
+		}
+		inlayHints = append(inlayHints, hint)
+	}
+	return inlayHints
+}
+
 // sortInlayHints sorts the given inlay hints in a stable manner.
 func sortInlayHints(hints []InlayHint) {
 	slices.SortFunc(hints, func(a, b InlayHint) int {
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"slices"
 	"testing"
 
@@ -41,7 +42,7 @@ onStart => {
 			},
 		}
 
-		inlayHints, err := s.textDocumentInlayHint(params)
+		inlayHints, err := s.textDocumentInlayHint(context.Background(), params)
 		require.NoError(t, err)
 		require.NotNil(t, inlayHints)
 		assert.NotEmpty(t, inlayHints)
@@ -85,7 +86,7 @@ onStart => {
 			},
 		}
 
-		inlayHints, err := s.textDocumentInlayHint(params)
+		inlayHints, err := s.textDocumentInlayHint(context.Background(), params)
 		require.NoError(t, err)
 		assert.Empty(t, inlayHints)
 	})
@@ -101,7 +102,7 @@ onStart => {
 			},
 		}
 
-		inlayHints, err := s.textDocumentInlayHint(params)
+		inlayHints, err := s.textDocumentInlayHint(context.Background(), params)
 		require.Error(t, err)
 		assert.Nil(t, inlayHints)
 	})
@@ -137,7 +138,7 @@ onStart => {
 			},
 		}
 
-		inlayHints, err := s.textDocumentInlayHint(params)
+		inlayHints, err := s.textDocumentInlayHint(context.Background(), params)
 		require.NoError(t, err)
 		require.NotNil(t, inlayHints)
 		assert.Equal(t, 2, len(inlayHints))
@@ -196,11 +197,11 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///main.spx")
+		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
 		require.NoError(t, err)
 		require.NotNil(t, astFile)
 
-		inlayHints := collectInlayHints(result, astFile, 0, 0)
+		inlayHints := collectInlayHints(result, astFile, 0, 0, InlayHintSettings{ParameterNames: true})
 		require.NotNil(t, inlayHints)
 		assert.NotEmpty(t, inlayHints)
 
@@ -241,11 +242,11 @@ onStart => {
 		}
 		assert.Equal(t, 3, hsbHintCount)
 
-		spriteResult, _, spriteAstFile, err := s.compileAndGetASTFileForDocumentURI("file:///MySprite.spx")
+		spriteResult, _, spriteAstFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///MySprite.spx")
 		require.NoError(t, err)
 		require.NotNil(t, spriteAstFile)
 
-		spriteInlayHints := collectInlayHints(spriteResult, spriteAstFile, 0, 0)
+		spriteInlayHints := collectInlayHints(spriteResult, spriteAstFile, 0, 0, InlayHintSettings{ParameterNames: true})
 		require.NotNil(t, spriteInlayHints)
 		assert.NotEmpty(t, spriteInlayHints)
 
@@ -276,11 +277,11 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///main.spx")
+		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
 		require.NoError(t, err)
 		require.NotNil(t, astFile)
 
-		inlayHints := collectInlayHints(result, astFile, 0, 0)
+		inlayHints := collectInlayHints(result, astFile, 0, 0, InlayHintSettings{ParameterNames: true})
 		assert.Empty(t, inlayHints)
 	})
 
@@ -297,11 +298,11 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///main.spx")
+		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
 		require.NoError(t, err)
 		require.NotNil(t, astFile)
 
-		inlayHints := collectInlayHints(result, astFile, 0, 0)
+		inlayHints := collectInlayHints(result, astFile, 0, 0, InlayHintSettings{ParameterNames: true})
 		require.NotNil(t, inlayHints)
 		assert.NotEmpty(t, inlayHints)
 
@@ -320,11 +321,11 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///main.spx")
+		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
 		require.NoError(t, err)
 		require.NotNil(t, astFile)
 
-		inlayHints := collectInlayHints(result, astFile, 0, 0)
+		inlayHints := collectInlayHints(result, astFile, 0, 0, InlayHintSettings{ParameterNames: true})
 		assert.Empty(t, inlayHints)
 	})
 
@@ -351,17 +352,17 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///main.spx")
+		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
 		require.NoError(t, err)
 		require.NotNil(t, astFile)
 
 		rangeStart := PosAt(result.proj, astFile, Position{Line: 7, Character: 0})
 		rangeEnd := PosAt(result.proj, astFile, Position{Line: 10, Character: 0})
-		filteredHints := collectInlayHints(result, astFile, rangeStart, rangeEnd)
+		filteredHints := collectInlayHints(result, astFile, rangeStart, rangeEnd, InlayHintSettings{ParameterNames: true})
 		require.NotNil(t, filteredHints)
 		assert.NotEmpty(t, filteredHints)
 
-		allHints := collectInlayHints(result, astFile, 0, 0)
+		allHints := collectInlayHints(result, astFile, 0, 0, InlayHintSettings{ParameterNames: true})
 		require.NotNil(t, allHints)
 		assert.NotEmpty(t, allHints)
 
@@ -396,11 +397,11 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///main.spx")
+		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
 		require.NoError(t, err)
 		require.NotNil(t, astFile)
 
-		inlayHints := collectInlayHints(result, astFile, 0, 0)
+		inlayHints := collectInlayHints(result, astFile, 0, 0, InlayHintSettings{ParameterNames: true})
 		require.Nil(t, inlayHints)
 	})
 
@@ -415,16 +416,168 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI("file:///main.spx")
+		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
 		require.NoError(t, err)
 		require.NotNil(t, astFile)
 
-		inlayHints := collectInlayHints(result, astFile, 0, 0)
+		inlayHints := collectInlayHints(result, astFile, 0, 0, InlayHintSettings{ParameterNames: true})
 		require.NotNil(t, inlayHints)
 		require.Len(t, inlayHints, 2)
 		assert.Equal(t, "a...", inlayHints[0].Label)
 		assert.Equal(t, "a...", inlayHints[1].Label)
 	})
+
+	t.Run("ParameterNameSettings", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+func move(x, speed int) {}
+
+var speed int
+
+onStart => {
+	move 1, speed
+	move speed, 2
+}
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
+		require.NoError(t, err)
+		require.NotNil(t, astFile)
+
+		labels := func(settings InlayHintSettings) []string {
+			var labels []string
+			for _, hint := range collectInlayHints(result, astFile, 0, 0, settings) {
+				labels = append(labels, hint.Label)
+			}
+			return labels
+		}
+		assert.Equal(t, []string{"x", "speed", "x", "speed"}, labels(InlayHintSettings{ParameterNames: true}))
+		assert.Equal(t, []string{"x", "x", "speed"}, labels(InlayHintSettings{
+			ParameterNames:             true,
+			SkipMatchingParameterNames: true,
+		}))
+		assert.Equal(t, []string{"speed", "speed"}, labels(InlayHintSettings{
+			ParameterNames:         true,
+			MinParameterNameLength: 2,
+		}))
+	})
+
+	t.Run("ImplicitConversions", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+func scale(factor float64) {}
+func repeat(times int) {}
+
+const Half = 1 / 2
+
+onStart => {
+	scale 10/3
+	scale 10
+	scale Half
+	repeat 2.0
+	repeat 3
+}
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
+		require.NoError(t, err)
+		require.NotNil(t, astFile)
+
+		inlayHints := collectInlayHints(result, astFile, 0, 0, InlayHintSettings{ImplicitConversions: true})
+		assert.Equal(t, []InlayHint{
+			{
+				Position:    Position{Line: 7, Character: 11},
+				Label:       "→ float64(3)",
+				Kind:        Type,
+				PaddingLeft: true,
+			},
+			{
+				Position:    Position{Line: 9, Character: 11},
+				Label:       "→ float64(0)",
+				Kind:        Type,
+				PaddingLeft: true,
+			},
+			{
+				Position:    Position{Line: 10, Character: 11},
+				Label:       "→ int(2)",
+				Kind:        Type,
+				PaddingLeft: true,
+			},
+		}, inlayHints)
+	})
+
+	t.Run("ReturnValues", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+func divmod(a, b int) (int, int) {
+	return a / b, a % b
+}
+
+onStart => {
+	q, _ := divmod(7, 2)
+	n := len("abc")
+	echo q, n
+}
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
+		require.NoError(t, err)
+		require.NotNil(t, astFile)
+
+		inlayHints := collectInlayHints(result, astFile, 0, 0, InlayHintSettings{ReturnValues: true})
+		assert.Equal(t, []InlayHint{
+			{
+				Position: Position{Line: 6, Character: 2},
+				Label:    ": int",
+				Kind:     Type,
+			},
+			{
+				Position: Position{Line: 6, Character: 5},
+				Label:    ": int",
+				Kind:     Type,
+			},
+		}, inlayHints)
+	})
+
+	t.Run("VariableTypes", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+onStart => {
+	n := 1
+	n, s := 2, "abc"
+	var x = 1.5
+	echo n, s, x
+}
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(context.Background(), "file:///main.spx")
+		require.NoError(t, err)
+		require.NotNil(t, astFile)
+
+		inlayHints := collectInlayHints(result, astFile, 0, 0, InlayHintSettings{VariableTypes: true})
+		assert.Equal(t, []InlayHint{
+			{
+				Position: Position{Line: 2, Character: 2},
+				Label:    ": int",
+				Kind:     Type,
+			},
+			{
+				Position: Position{Line: 3, Character: 5},
+				Label:    ": string",
+				Kind:     Type,
+			},
+		}, inlayHints)
+
+		assert.Empty(t, collectInlayHints(result, astFile, 0, 0, InlayHintSettings{}))
+	})
 }
 
 func TestSortInlayHints(t *testing.T) {
@@ -520,7 +673,7 @@ func TestSortInlayHints(t *testing.T) {
 			},
 		}
 
-		inlayHints, err := s.textDocumentInlayHint(params)
+		inlayHints, err := s.textDocumentInlayHint(context.Background(), params)
 		require.NoError(t, err)
 		require.Nil(t, inlayHints)
 		assert.Empty(t, inlayHints)
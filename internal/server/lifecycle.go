@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/goplus/xgolsw/protocol"
+	"github.com/goplus/xgolsw/xgo"
+)
+
+// See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#initialize
+func (s *Server) initialize(params *InitializeParams) (*InitializeResult, error) {
+	s.clientCapabilities = params.Capabilities
+	if params.Trace != nil {
+		s.trace.Store(params.Trace)
+	}
+
+	settings, err := parseSettings(params.InitializationOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse initialization options: %w", err)
+	}
+	s.setSettings(settings)
+	if rootURI := string(params.RootURI); rootURI != "" {
+		if !strings.HasSuffix(rootURI, "/") {
+			rootURI += "/"
+		}
+		s.workspaceRootURI = DocumentURI(rootURI)
+	}
+
+	return &InitializeResult{
+		Capabilities: s.serverCapabilities(),
+		ServerInfo: &protocol.ServerInfo{
+			Name: "xgolsw",
+		},
+	}, nil
+}
+
+// initializedCallTimeout is the maximum time to wait for the client to respond
+// to the requests made by the server once initialized, so that a client that
+// never responds does not hold back the initial compile.
+var initializedCallTimeout = 10 * time.Second
+
+// See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#initialized
+func (s *Server) initialized(params *InitializedParams) error {
+	ctx := context.Background()
+	callCtx, cancel := context.WithTimeout(ctx, initializedCallTimeout)
+	defer cancel()
+	if err := s.registerFileWatchers(callCtx); err != nil {
+		// Files are still synchronized through text document notifications,
+		// so carry on with the initial compile.
+		s.getLogger().Warn("failed to register file watchers", "error", err)
+	}
+
+	// Warm up the caches with an initial compile, which may take a while for
+	// large projects, so report its progress to the client.
+	progress := s.createWorkDoneProgress(callCtx)
+	progress.begin("Compiling project", "")
+	defer progress.end("")
+
+	if _, err := s.compileWithContext(withWorkDoneProgress(ctx, progress)); err != nil && !errors.Is(err, errNoMainSpxFile) {
+		return err
+	}
+	return nil
+}
+
+// serverCapabilities returns the capabilities provided by the server.
+func (s *Server) serverCapabilities() ServerCapabilities {
+	semanticTokenTypes := make([]string, 0, len(semanticTokenTypesLegend))
+	for _, tokenType := range semanticTokenTypesLegend {
+		semanticTokenTypes = append(semanticTokenTypes, string(tokenType))
+	}
+	semanticTokenModifiers := make([]string, 0, len(semanticTokenModifiersLegend))
+	for _, tokenModifier := range semanticTokenModifiersLegend {
+		semanticTokenModifiers = append(semanticTokenModifiers, string(tokenModifier))
+	}
+
+	capabilities := ServerCapabilities{
+		TextDocumentSync: protocol.TextDocumentSyncOptions{
+			OpenClose:         true,
+			Change:            protocol.Incremental,
+			WillSaveWaitUntil: true,
+			Save:              &protocol.SaveOptions{IncludeText: true},
+		},
+		CompletionProvider:        &protocol.CompletionOptions{TriggerCharacters: []string{"."}},
+		HoverProvider:             &protocol.Or_ServerCapabilities_hoverProvider{Value: true},
+		SignatureHelpProvider:     &protocol.SignatureHelpOptions{TriggerCharacters: []string{"(", ",", " "}, RetriggerCharacters: []string{")"}},
+		DeclarationProvider:       &protocol.Or_ServerCapabilities_declarationProvider{Value: true},
+		DefinitionProvider:        &protocol.Or_ServerCapabilities_definitionProvider{Value: true},
+		TypeDefinitionProvider:    &protocol.Or_ServerCapabilities_typeDefinitionProvider{Value: true},
+		ImplementationProvider:    &protocol.Or_ServerCapabilities_implementationProvider{Value: true},
+		ReferencesProvider:        &protocol.Or_ServerCapabilities_referencesProvider{Value: true},
+		DocumentHighlightProvider: &protocol.Or_ServerCapabilities_documentHighlightProvider{Value: true},
+		DocumentLinkProvider:      &protocol.DocumentLinkOptions{},
+		DiagnosticProvider: &protocol.Or_ServerCapabilities_diagnosticProvider{Value: protocol.DiagnosticOptions{
+			InterFileDependencies: true,
+			WorkspaceDiagnostics:  true,
+			WorkDoneProgressOptions: protocol.WorkDoneProgressOptions{
+				WorkDoneProgress: true,
+			},
+		}},
+		DocumentFormattingProvider:       &protocol.Or_ServerCapabilities_documentFormattingProvider{Value: true},
+		DocumentOnTypeFormattingProvider: &protocol.DocumentOnTypeFormattingOptions{FirstTriggerCharacter: "}"},
+		CodeActionProvider:               &protocol.CodeActionOptions{CodeActionKinds: []protocol.CodeActionKind{protocol.QuickFix, protocol.RefactorExtract, protocol.RefactorRewrite, protocol.SourceOrganizeImports, codeActionKindSourceAddFileHeader}},
+		RenameProvider:                   protocol.RenameOptions{PrepareProvider: true},
+		SemanticTokensProvider: protocol.SemanticTokensOptions{
+			Legend: protocol.SemanticTokensLegend{
+				TokenTypes:     semanticTokenTypes,
+				TokenModifiers: semanticTokenModifiers,
+			},
+			Full: &protocol.Or_SemanticTokensOptions_full{Value: true},
+		},
+		InlayHintProvider: true,
+		ExecuteCommandProvider: &protocol.ExecuteCommandOptions{
+			Commands: []string{
+				"spx.renameResources",
+				"spx.renameResource",
+				"spx.getInputSlots",
+				"spx.getDefinitions",
+				"spx.getSpriteAPIs",
+				"spx.diagnoseWorkspace",
+				"spx.runAnalyzers",
+				"spx.formatProject",
+				"spx.organizeImports",
+				"spx.clearCaches",
+				"spx.getMetrics",
+				"spx.listResources",
+				"spx.getResourceReferences",
+				"spx.listAnalyzers",
+				"spx.generateGo",
+				"spx.createSprite",
+			},
+		},
+		Workspace: &protocol.WorkspaceOptions{
+			FileOperations: &protocol.FileOperationOptions{
+				DidCreate: &fileOperationRegistrationOptions,
+				DidDelete: &fileOperationRegistrationOptions,
+			},
+		},
+	}
+	if !s.hasFeatures(xgo.FeatTypeInfoCache) {
+		// Only the capabilities based on parsing are provided without
+		// type information. See [methodFeatures].
+		capabilities.CompletionProvider = nil
+		capabilities.HoverProvider = nil
+		capabilities.SignatureHelpProvider = nil
+		capabilities.DeclarationProvider = nil
+		capabilities.DefinitionProvider = nil
+		capabilities.TypeDefinitionProvider = nil
+		capabilities.ImplementationProvider = nil
+		capabilities.ReferencesProvider = nil
+		capabilities.DocumentHighlightProvider = nil
+		capabilities.DocumentLinkProvider = nil
+		capabilities.CodeActionProvider = nil
+		capabilities.RenameProvider = nil
+		capabilities.InlayHintProvider = nil
+		capabilities.ExecuteCommandProvider.Commands = slices.DeleteFunc(capabilities.ExecuteCommandProvider.Commands, func(command string) bool {
+			return commandFeatures[command]&xgo.FeatTypeInfoCache != 0
+		})
+	}
+	return capabilities
+}
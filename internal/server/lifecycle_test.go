@@ -0,0 +1,139 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goplus/xgolsw/jsonrpc2"
+	"github.com/goplus/xgolsw/protocol"
+	"github.com/goplus/xgolsw/xgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerInitialize(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), nil, nil, &MockScheduler{})
+
+		result, err := s.initialize(&InitializeParams{})
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		assert.Equal(t, DocumentURI("file:///"), s.workspaceRootURI)
+
+		require.NotNil(t, result.Capabilities.Workspace)
+		require.NotNil(t, result.Capabilities.Workspace.FileOperations)
+		assert.NotNil(t, result.Capabilities.Workspace.FileOperations.DidCreate)
+		assert.NotNil(t, result.Capabilities.Workspace.FileOperations.DidDelete)
+		assert.Equal(t, &protocol.CodeActionOptions{CodeActionKinds: []protocol.CodeActionKind{protocol.QuickFix, protocol.RefactorExtract, protocol.RefactorRewrite, protocol.SourceOrganizeImports, codeActionKindSourceAddFileHeader}}, result.Capabilities.CodeActionProvider)
+		assert.Equal(t, &protocol.DocumentOnTypeFormattingOptions{FirstTriggerCharacter: "}"}, result.Capabilities.DocumentOnTypeFormattingProvider)
+		require.NotNil(t, result.Capabilities.ExecuteCommandProvider)
+		assert.Contains(t, result.Capabilities.ExecuteCommandProvider.Commands, "spx.renameResources")
+		assert.Contains(t, result.Capabilities.ExecuteCommandProvider.Commands, "spx.clearCaches")
+		assert.Contains(t, result.Capabilities.ExecuteCommandProvider.Commands, "spx.getMetrics")
+		assert.Contains(t, result.Capabilities.ExecuteCommandProvider.Commands, "spx.getResourceReferences")
+		assert.Contains(t, result.Capabilities.ExecuteCommandProvider.Commands, "spx.generateGo")
+		assert.Contains(t, result.Capabilities.ExecuteCommandProvider.Commands, "spx.createSprite")
+		assert.Contains(t, result.Capabilities.ExecuteCommandProvider.Commands, "spx.organizeImports")
+		assert.Contains(t, result.Capabilities.ExecuteCommandProvider.Commands, "spx.getSpriteAPIs")
+		assert.Contains(t, result.Capabilities.ExecuteCommandProvider.Commands, "spx.runAnalyzers")
+		assert.Contains(t, result.Capabilities.ExecuteCommandProvider.Commands, "spx.listResources")
+		assert.Contains(t, result.Capabilities.ExecuteCommandProvider.Commands, "spx.listAnalyzers")
+	})
+
+	t.Run("WithoutTypes", func(t *testing.T) {
+		s := New(newMapFSWithFeatures(map[string][]byte{}, xgo.FeatASTCache), nil, nil, &MockScheduler{})
+
+		result, err := s.initialize(&InitializeParams{})
+		require.NoError(t, err)
+		require.NotNil(t, result)
+
+		assert.NotNil(t, result.Capabilities.SemanticTokensProvider)
+		assert.NotNil(t, result.Capabilities.DocumentFormattingProvider)
+		assert.Nil(t, result.Capabilities.HoverProvider)
+		assert.Nil(t, result.Capabilities.CompletionProvider)
+		assert.Nil(t, result.Capabilities.DefinitionProvider)
+		assert.Nil(t, result.Capabilities.RenameProvider)
+		require.NotNil(t, result.Capabilities.ExecuteCommandProvider)
+		assert.NotContains(t, result.Capabilities.ExecuteCommandProvider.Commands, "spx.renameResources")
+		assert.NotContains(t, result.Capabilities.ExecuteCommandProvider.Commands, "spx.generateGo")
+		assert.NotContains(t, result.Capabilities.ExecuteCommandProvider.Commands, "spx.listResources")
+		assert.Contains(t, result.Capabilities.ExecuteCommandProvider.Commands, "spx.clearCaches")
+		assert.Contains(t, result.Capabilities.ExecuteCommandProvider.Commands, "spx.listAnalyzers")
+	})
+
+	t.Run("WithRootURI", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), nil, nil, &MockScheduler{})
+
+		params := &InitializeParams{}
+		params.RootURI = protocol.DocumentURI("file:///workspace")
+		_, err := s.initialize(params)
+		require.NoError(t, err)
+		assert.Equal(t, DocumentURI("file:///workspace/"), s.workspaceRootURI)
+	})
+
+	t.Run("WithInitializationOptions", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), nil, nil, &MockScheduler{})
+
+		params := &InitializeParams{}
+		params.InitializationOptions = map[string]any{
+			"analyzers": map[string]any{"appends": false},
+		}
+		_, err := s.initialize(params)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]bool{"appends": false}, s.getSettings().Analyzers)
+	})
+
+	t.Run("InvalidInitializationOptions", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), nil, nil, &MockScheduler{})
+
+		params := &InitializeParams{}
+		params.InitializationOptions = map[string]any{"diagnosticsDelay": true}
+		_, err := s.initialize(params)
+		require.Error(t, err)
+	})
+}
+
+func TestServerInitialized(t *testing.T) {
+	t.Run("UnresponsiveClient", func(t *testing.T) {
+		defer func(timeout time.Duration) { initializedCallTimeout = timeout }(initializedCallTimeout)
+		initializedCallTimeout = 10 * time.Millisecond
+
+		replier := &mockReplier{} // Never responds.
+		s := New(newMapFSWithoutModTime(map[string][]byte{
+			"main.spx": []byte(`echo "Hello, world!"`),
+		}), replier, nil, &MockScheduler{})
+		s.clientCapabilities.Window.WorkDoneProgress = true
+
+		done := make(chan error, 1)
+		go func() { done <- s.initialized(&InitializedParams{}) }()
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("initialized is blocked by the unresponsive client")
+		}
+
+		msgs := replier.getMessages()
+		require.NotEmpty(t, msgs)
+		c, ok := msgs[0].(*jsonrpc2.Call)
+		require.True(t, ok)
+		assert.Equal(t, "window/workDoneProgress/create", c.Method())
+	})
+	t.Run("FileWatchersRejected", func(t *testing.T) {
+		replier := &callRespondingReplier{err: jsonrpc2.NewError(-32603, "rejected")}
+		s := New(newMapFSWithoutModTime(map[string][]byte{
+			"main.spx": []byte(`echo "Hello, world!"`),
+		}), replier, nil, &MockScheduler{})
+		replier.s = s
+		s.clientCapabilities.Workspace.DidChangeWatchedFiles.DynamicRegistration = true
+
+		err := s.initialized(&InitializedParams{})
+		require.NoError(t, err)
+
+		msgs := replier.getMessages()
+		require.NotEmpty(t, msgs)
+		c, ok := msgs[0].(*jsonrpc2.Call)
+		require.True(t, ok)
+		assert.Equal(t, "client/registerCapability", c.Method())
+	})
+}
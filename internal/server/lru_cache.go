@@ -0,0 +1,108 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache is a size-aware least recently used cache. Entries are evicted,
+// least recently used first, once the total size of all entries exceeds the
+// budget.
+//
+// It is safe for concurrent use.
+type lruCache[K comparable, V any] struct {
+	mu      sync.Mutex
+	entries map[K]*list.Element // Values are *lruCacheEntry[K, V].
+	order   *list.List          // Most recently used first.
+	size    int64
+	budget  int64
+	sizeOf  func(V) int64
+}
+
+// lruCacheEntry is an entry in an [lruCache].
+type lruCacheEntry[K comparable, V any] struct {
+	key   K
+	value V
+	size  int64
+}
+
+// newLRUCache creates a new [lruCache] with the given budget. The sizeOf
+// function reports the approximate size in bytes of a value.
+func newLRUCache[K comparable, V any](budget int64, sizeOf func(V) int64) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		entries: make(map[K]*list.Element),
+		order:   list.New(),
+		budget:  budget,
+		sizeOf:  sizeOf,
+	}
+}
+
+// get returns the value for key and marks it as most recently used.
+func (c *lruCache[K, V]) get(key K) (value V, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return value, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruCacheEntry[K, V]).value, true
+}
+
+// put adds or replaces the value for key, and then evicts least recently used
+// entries until the cache fits its budget. A value larger than the whole
+// budget is not kept.
+func (c *lruCache[K, V]) put(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.removeLocked(elem)
+	}
+	entry := &lruCacheEntry[K, V]{
+		key:   key,
+		value: value,
+		size:  c.sizeOf(value),
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	c.size += entry.size
+	c.evictLocked()
+}
+
+// setBudget sets the budget of the cache, evicting entries if necessary.
+func (c *lruCache[K, V]) setBudget(budget int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.budget = budget
+	c.evictLocked()
+}
+
+// clear removes all entries from the cache.
+func (c *lruCache[K, V]) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.order.Init()
+	c.size = 0
+}
+
+// stats returns the number of entries and their total size.
+func (c *lruCache[K, V]) stats() (count int, size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries), c.size
+}
+
+// evictLocked evicts least recently used entries until the cache fits its
+// budget. It must be called with c.mu held.
+func (c *lruCache[K, V]) evictLocked() {
+	for c.size > c.budget {
+		c.removeLocked(c.order.Back())
+	}
+}
+
+// removeLocked removes elem from the cache. It must be called with c.mu held.
+func (c *lruCache[K, V]) removeLocked(elem *list.Element) {
+	entry := c.order.Remove(elem).(*lruCacheEntry[K, V])
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLRUCache(budget int64) *lruCache[string, string] {
+	return newLRUCache[string, string](budget, func(v string) int64 { return int64(len(v)) })
+}
+
+func TestLRUCache(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		c := newTestLRUCache(10)
+		c.put("a", "foo")
+		c.put("b", "bar")
+
+		v, ok := c.get("a")
+		assert.True(t, ok)
+		assert.Equal(t, "foo", v)
+		_, ok = c.get("c")
+		assert.False(t, ok)
+
+		count, size := c.stats()
+		assert.Equal(t, 2, count)
+		assert.Equal(t, int64(6), size)
+	})
+
+	t.Run("Eviction", func(t *testing.T) {
+		c := newTestLRUCache(6)
+		c.put("a", "foo")
+		c.put("b", "bar")
+		c.get("a") // Makes "b" the least recently used entry.
+		c.put("c", "baz")
+
+		_, ok := c.get("b")
+		assert.False(t, ok)
+		_, ok = c.get("a")
+		assert.True(t, ok)
+		_, ok = c.get("c")
+		assert.True(t, ok)
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		c := newTestLRUCache(10)
+		c.put("a", "foo")
+		c.put("a", "foobar")
+
+		v, ok := c.get("a")
+		assert.True(t, ok)
+		assert.Equal(t, "foobar", v)
+		count, size := c.stats()
+		assert.Equal(t, 1, count)
+		assert.Equal(t, int64(6), size)
+	})
+
+	t.Run("LargerThanBudget", func(t *testing.T) {
+		c := newTestLRUCache(2)
+		c.put("a", "foo")
+
+		_, ok := c.get("a")
+		assert.False(t, ok)
+		count, size := c.stats()
+		assert.Zero(t, count)
+		assert.Zero(t, size)
+	})
+
+	t.Run("SetBudget", func(t *testing.T) {
+		c := newTestLRUCache(10)
+		c.put("a", "foo")
+		c.put("b", "bar")
+
+		c.setBudget(3)
+		_, ok := c.get("a")
+		assert.False(t, ok)
+		_, ok = c.get("b")
+		assert.True(t, ok)
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		c := newTestLRUCache(10)
+		c.put("a", "foo")
+
+		c.clear()
+		_, ok := c.get("a")
+		assert.False(t, ok)
+		count, size := c.stats()
+		assert.Zero(t, count)
+		assert.Zero(t, size)
+	})
+}
+
+func TestSetCacheMemoryBudget(t *testing.T) {
+	defer SetCacheMemoryBudget(DefaultCacheMemoryBudget)
+
+	SetCacheMemoryBudget(1 << 20)
+	nonMainPkgSpxDefCache.mu.Lock()
+	budget := nonMainPkgSpxDefCache.budget
+	nonMainPkgSpxDefCache.mu.Unlock()
+	assert.Equal(t, int64(1<<20), budget)
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/goplus/xgolsw/xgo"
+)
+
+// Metrics reports internal metrics of a [Server], for diagnosing performance
+// issues.
+type Metrics struct {
+	// Compile holds metrics of project compilations.
+	Compile CompileMetrics `json:"compile"`
+
+	// Cache holds metrics of the caches of the server.
+	Cache CacheMetrics `json:"cache"`
+
+	// Requests maps methods of handled requests and notifications to their
+	// latency histograms.
+	Requests map[string]LatencyHistogram `json:"requests"`
+}
+
+// CompileMetrics holds metrics of project compilations.
+type CompileMetrics struct {
+	// Count is the number of compilations, not counting reused results.
+	Count uint64 `json:"count"`
+
+	// Reused is the number of times the last compile result was reused.
+	Reused uint64 `json:"reused"`
+
+	// LastDuration is the duration of the last compilation.
+	LastDuration Duration `json:"lastDuration"`
+
+	// TotalDuration is the total duration of all compilations.
+	TotalDuration Duration `json:"totalDuration"`
+
+	// SpxFiles is the number of spx files in the last compilation.
+	SpxFiles int `json:"spxFiles"`
+
+	// Resources is the number of spx resources (backdrops, sounds, sprites,
+	// and widgets) in the last compilation.
+	Resources int `json:"resources"`
+}
+
+// CacheMetrics holds metrics of the caches of the server.
+type CacheMetrics struct {
+	// Hits is the number of project cache lookups served from the cache,
+	// including lookups during compilations.
+	Hits uint64 `json:"hits"`
+
+	// Misses is the number of project cache lookups that required building
+	// the cache.
+	Misses uint64 `json:"misses"`
+
+	// HitRate is the ratio of Hits to all lookups.
+	HitRate float64 `json:"hitRate"`
+
+	// SpxDefinitions is the number of cached spx definitions of imported
+	// packages.
+	SpxDefinitions int `json:"spxDefinitions"`
+
+	// SpxDefinitionsSize is the approximate size in bytes of the cached spx
+	// definitions of imported packages.
+	SpxDefinitionsSize int64 `json:"spxDefinitionsSize"`
+}
+
+// LatencyHistogram is a histogram of the latencies of handling messages with
+// the same method.
+type LatencyHistogram struct {
+	// Count is the number of handled messages.
+	Count uint64 `json:"count"`
+
+	// Errors is the number of messages that failed to be handled.
+	Errors uint64 `json:"errors"`
+
+	// Total is the total latency of all handled messages.
+	Total Duration `json:"total"`
+
+	// Buckets holds the number of messages by latency. Each bucket counts
+	// the messages handled within its upper bound but not within the upper
+	// bound of the previous bucket. The last bucket has no upper bound.
+	Buckets []LatencyBucket `json:"buckets"`
+}
+
+// LatencyBucket is a bucket of a [LatencyHistogram].
+type LatencyBucket struct {
+	// UpperBound is the inclusive upper bound of the bucket. It is omitted
+	// for the last bucket, which has no upper bound.
+	UpperBound Duration `json:"le,omitzero"`
+
+	// Count is the number of messages in the bucket.
+	Count uint64 `json:"count"`
+}
+
+// latencyBucketBounds are the upper bounds of the buckets of a
+// [LatencyHistogram], not counting the last bucket.
+var latencyBucketBounds = []time.Duration{
+	1 * time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2 * time.Second,
+	5 * time.Second,
+}
+
+// metricsRecorder records the metrics of a [Server].
+//
+// The zero value is ready to use.
+type metricsRecorder struct {
+	mu                sync.Mutex
+	compile           CompileMetrics
+	compileCacheStats xgo.CacheStats // Cache lookups of compiled snapshots.
+	requests          map[string]*LatencyHistogram
+}
+
+// recordCompile records a compilation of the given number of spx files and
+// resources that took duration and looked up the cache as in cacheStats.
+func (r *metricsRecorder) recordCompile(duration time.Duration, spxFiles, resources int, cacheStats xgo.CacheStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.compile.Count++
+	r.compileCacheStats.Hits += cacheStats.Hits
+	r.compileCacheStats.Misses += cacheStats.Misses
+	r.compile.LastDuration = Duration(duration)
+	r.compile.TotalDuration += Duration(duration)
+	r.compile.SpxFiles = spxFiles
+	r.compile.Resources = resources
+}
+
+// recordCompileReused records that the last compile result was reused.
+func (r *metricsRecorder) recordCompileReused() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.compile.Reused++
+}
+
+// recordRequest records that handling a message with the given method took
+// duration and failed if failed is true.
+func (r *metricsRecorder) recordRequest(method string, duration time.Duration, failed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.requests == nil {
+		r.requests = make(map[string]*LatencyHistogram)
+	}
+	h, ok := r.requests[method]
+	if !ok {
+		h = &LatencyHistogram{Buckets: make([]LatencyBucket, len(latencyBucketBounds)+1)}
+		for i, bound := range latencyBucketBounds {
+			h.Buckets[i].UpperBound = Duration(bound)
+		}
+		r.requests[method] = h
+	}
+	h.Count++
+	if failed {
+		h.Errors++
+	}
+	h.Total += Duration(duration)
+	i := len(latencyBucketBounds)
+	for j, bound := range latencyBucketBounds {
+		if duration <= bound {
+			i = j
+			break
+		}
+	}
+	h.Buckets[i].Count++
+}
+
+// Metrics returns the current metrics of the server.
+func (s *Server) Metrics() Metrics {
+	s.metrics.mu.Lock()
+	metrics := Metrics{
+		Compile:  s.metrics.compile,
+		Requests: make(map[string]LatencyHistogram, len(s.metrics.requests)),
+	}
+	cacheStats := s.metrics.compileCacheStats
+	for method, h := range s.metrics.requests {
+		hCopy := *h
+		hCopy.Buckets = append([]LatencyBucket(nil), h.Buckets...)
+		metrics.Requests[method] = hCopy
+	}
+	s.metrics.mu.Unlock()
+
+	if proj := s.getProj(); proj != nil {
+		projCacheStats := proj.CacheStats()
+		cacheStats.Hits += projCacheStats.Hits
+		cacheStats.Misses += projCacheStats.Misses
+	}
+	metrics.Cache = CacheMetrics{
+		Hits:    cacheStats.Hits,
+		Misses:  cacheStats.Misses,
+		HitRate: cacheStats.HitRate(),
+	}
+	metrics.Cache.SpxDefinitions, metrics.Cache.SpxDefinitionsSize = nonMainPkgSpxDefCache.stats()
+	return metrics
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/goplus/xgolsw/jsonrpc2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsRecorderRecordRequest(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		var r metricsRecorder
+		r.recordRequest("textDocument/hover", 500*time.Microsecond, false)
+		r.recordRequest("textDocument/hover", time.Millisecond, false)
+		r.recordRequest("textDocument/hover", 3*time.Millisecond, true)
+		r.recordRequest("textDocument/hover", time.Minute, false)
+
+		h := r.requests["textDocument/hover"]
+		require.NotNil(t, h)
+		assert.Equal(t, uint64(4), h.Count)
+		assert.Equal(t, uint64(1), h.Errors)
+		assert.Equal(t, Duration(time.Minute+4500*time.Microsecond), h.Total)
+		require.Len(t, h.Buckets, len(latencyBucketBounds)+1)
+		assert.Equal(t, LatencyBucket{UpperBound: Duration(time.Millisecond), Count: 2}, h.Buckets[0])
+		assert.Equal(t, LatencyBucket{UpperBound: Duration(2 * time.Millisecond)}, h.Buckets[1])
+		assert.Equal(t, LatencyBucket{UpperBound: Duration(5 * time.Millisecond), Count: 1}, h.Buckets[2])
+		assert.Equal(t, LatencyBucket{Count: 1}, h.Buckets[len(latencyBucketBounds)])
+	})
+}
+
+func TestServerMetrics(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		metrics := s.Metrics()
+		assert.Zero(t, metrics.Compile.Count)
+		assert.Empty(t, metrics.Requests)
+
+		result, err := s.compile()
+		require.NoError(t, err)
+		_, err = s.compile()
+		require.NoError(t, err)
+
+		metrics = s.Metrics()
+		assert.Equal(t, uint64(1), metrics.Compile.Count)
+		assert.Equal(t, uint64(1), metrics.Compile.Reused)
+		assert.Positive(t, metrics.Compile.LastDuration)
+		assert.Equal(t, metrics.Compile.LastDuration, metrics.Compile.TotalDuration)
+		assert.Equal(t, 3, metrics.Compile.SpxFiles)
+		assert.Equal(t, result.spxResourceSet.count(), metrics.Compile.Resources)
+		assert.Positive(t, metrics.Compile.Resources)
+		assert.Positive(t, metrics.Cache.Misses)
+		assert.InDelta(t, float64(metrics.Cache.Hits)/float64(metrics.Cache.Hits+metrics.Cache.Misses), metrics.Cache.HitRate, 1e-9)
+	})
+
+	t.Run("Requests", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, nil, &MockScheduler{})
+
+		call, err := jsonrpc2.NewCall(jsonrpc2.NewIntID(1), "workspace/executeCommand", ExecuteCommandParams{Command: "spx.unknown"})
+		require.NoError(t, err)
+		_, err = s.wrapWithMetrics(call, func() (any, error) {
+			return s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{Command: "spx.unknown"})
+		})()
+		require.Error(t, err)
+
+		h, ok := s.Metrics().Requests["workspace/executeCommand"]
+		require.True(t, ok)
+		assert.Equal(t, uint64(1), h.Count)
+		assert.Equal(t, uint64(1), h.Errors)
+	})
+}
@@ -0,0 +1,323 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"go/types"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	xgoast "github.com/goplus/xgo/ast"
+	xgotoken "github.com/goplus/xgo/token"
+	"github.com/goplus/xgolsw/internal/pkgdata"
+	"github.com/goplus/xgolsw/internal/vfs"
+	"github.com/goplus/xgolsw/pkgdoc"
+	"github.com/goplus/xgolsw/xgo"
+)
+
+// spxImport is an import of an spx source file.
+type spxImport struct {
+	name string // Empty if the import is not renamed.
+	path string
+}
+
+// isStd reports whether the imported package is in the standard library.
+func (imp spxImport) isStd() bool {
+	first, _, _ := strings.Cut(imp.path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// String returns the import spec of imp.
+func (imp spxImport) String() string {
+	if imp.name != "" {
+		return imp.name + " " + strconv.Quote(imp.path)
+	}
+	return strconv.Quote(imp.path)
+}
+
+// See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#textDocument_willSaveWaitUntil
+func (s *Server) textDocumentWillSaveWaitUntil(ctx context.Context, params *WillSaveTextDocumentParams) ([]TextEdit, error) {
+	if !s.getSettings().Formatting.OrganizeImportsOnSave {
+		return nil, nil
+	}
+	spxFile, err := s.fromDocumentURI(params.TextDocument.URI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file path from document uri %q: %w", params.TextDocument.URI, err)
+	}
+	if path.Ext(spxFile) != ".spx" {
+		return nil, nil // Not an spx source file.
+	}
+
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(ctx, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	if astFile == nil {
+		return nil, nil
+	}
+	return s.spxOrganizeImportsEdits(result, astFile), nil
+}
+
+// spxOrganizeImports organizes the imports of all spx source files in the
+// project, the same way as the organize imports code action does for a single
+// file. Files whose imports cannot be organized are left unchanged.
+func (s *Server) spxOrganizeImports(ctx context.Context) (*WorkspaceEdit, error) {
+	result, err := s.compileWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	spxFiles, err := vfs.ListSpxFiles(result.proj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get spx files: %w", err)
+	}
+
+	workspaceEdit := &WorkspaceEdit{
+		Changes: make(map[DocumentURI][]TextEdit),
+	}
+	for _, spxFile := range spxFiles {
+		astFile, _ := result.proj.ASTFile(spxFile)
+		if astFile == nil {
+			continue
+		}
+		if edits := s.spxOrganizeImportsEdits(result, astFile); len(edits) > 0 {
+			workspaceEdit.Changes[s.toDocumentURI(spxFile)] = edits
+		}
+	}
+	return workspaceEdit, nil
+}
+
+// spxOrganizeImportsEdits returns the text edits that organize the imports of
+// astFile. Unused imports are removed, imports are added for unresolved
+// package references that match exactly one package known to pkgdata, and all
+// imports are sorted and grouped with standard library packages first.
+//
+// It returns nil if the imports are already organized, or if they cannot be
+// rewritten without losing comments.
+func (s *Server) spxOrganizeImportsEdits(result *compileResult, astFile *xgoast.File) []TextEdit {
+	typeInfo, _ := result.proj.TypeInfo()
+	if typeInfo == nil {
+		return nil
+	}
+
+	var importDecls []*xgoast.GenDecl
+	for _, decl := range astFile.Decls {
+		if d, ok := decl.(*xgoast.GenDecl); ok && d.Tok == xgotoken.IMPORT {
+			importDecls = append(importDecls, d)
+		}
+	}
+
+	// Keep the imports that are used, or imported for side effects.
+	usedPkgNames := make(map[types.Object]struct{})
+	for _, obj := range typeInfo.Uses {
+		if pkgName, ok := obj.(*types.PkgName); ok {
+			usedPkgNames[pkgName] = struct{}{}
+		}
+	}
+	var (
+		imports       []spxImport
+		importedNames = make(map[string]struct{})
+	)
+	for _, spec := range astFile.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil
+		}
+		imp := spxImport{path: importPath}
+		if spec.Name != nil {
+			imp.name = spec.Name.Name
+		}
+		if imp.name != "_" && imp.name != "." {
+			obj := typeInfo.Implicits[spec]
+			if spec.Name != nil {
+				obj = typeInfo.Defs[spec.Name]
+			}
+			if obj == nil {
+				continue
+			}
+			if _, ok := usedPkgNames[obj]; !ok {
+				continue
+			}
+			importedNames[obj.Name()] = struct{}{}
+		}
+		if !slices.Contains(imports, imp) {
+			imports = append(imports, imp)
+		}
+	}
+
+	// Add imports for unresolved package references.
+	for _, importPath := range missingSpxImports(typeInfo, astFile, importedNames) {
+		imports = append(imports, spxImport{path: importPath})
+	}
+
+	slices.SortFunc(imports, func(a, b spxImport) int {
+		if a.isStd() != b.isStd() {
+			if a.isStd() {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(cmp.Compare(a.path, b.path), cmp.Compare(a.name, b.name))
+	})
+	newText := formatSpxImports(imports)
+
+	code := astFile.Code
+	fset := result.proj.Fset
+	if len(importDecls) == 0 {
+		if newText == "" {
+			return nil
+		}
+
+		// Insert the imports before the first declaration, along with its doc.
+		var anchor xgotoken.Pos
+		for _, decl := range astFile.Decls {
+			if !decl.Pos().IsValid() {
+				continue
+			}
+			anchor = decl.Pos()
+			if doc := getDeclDoc(decl); doc != nil {
+				anchor = doc.Pos()
+			}
+			break
+		}
+		offset := len(code)
+		if anchor.IsValid() {
+			offset = fset.Position(anchor).Offset
+			offset = bytes.LastIndexByte(code[:offset], '\n') + 1
+		}
+		insert := OffsetPosition(code, offset)
+		return []TextEdit{{
+			Range:   Range{Start: insert, End: insert},
+			NewText: newText + "\n\n",
+		}}
+	}
+
+	start := fset.Position(importDecls[0].Pos()).Offset
+	end := fset.Position(importDecls[len(importDecls)-1].End()).Offset
+	for _, decl := range astFile.Decls {
+		if d, ok := decl.(*xgoast.GenDecl); ok && d.Tok == xgotoken.IMPORT {
+			continue
+		}
+		if pos := fset.Position(decl.Pos()).Offset; decl.Pos().IsValid() && start < pos && pos < end {
+			return nil // Imports are interleaved with other declarations.
+		}
+	}
+	for _, cg := range astFile.Comments {
+		if pos := fset.Position(cg.Pos()).Offset; start <= pos && pos < end {
+			return nil
+		}
+	}
+	if string(code[start:end]) == newText {
+		return nil
+	}
+	if newText == "" {
+		// Remove the import declarations along with the blank lines after.
+		start, end = lineExtent(code, start, end)
+		for end < len(code) && code[end] == '\n' {
+			end++
+		}
+	}
+	return []TextEdit{{
+		Range: Range{
+			Start: OffsetPosition(code, start),
+			End:   OffsetPosition(code, end),
+		},
+		NewText: newText,
+	}}
+}
+
+// missingSpxImports returns the paths of the packages that the unresolved
+// selector expressions in astFile likely refer to, such as "strings" for
+// `strings.ToUpper(s)`. A reference is only resolved if exactly one package
+// known to pkgdata has the name and the selected member. Packages whose names
+// are in importedNames are skipped.
+func missingSpxImports(typeInfo *xgo.TypeInfo, astFile *xgoast.File, importedNames map[string]struct{}) []string {
+	var (
+		pkgDocs      []*pkgdoc.PkgDoc
+		pkgDocsReady bool
+		importPaths  []string
+		seenNames    = make(map[string]struct{})
+	)
+	xgoast.Inspect(astFile, func(node xgoast.Node) bool {
+		sel, ok := node.(*xgoast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		ident, ok := sel.X.(*xgoast.Ident)
+		if !ok || typeInfo.ObjectOf(ident) != nil {
+			return true
+		}
+		if _, ok := importedNames[ident.Name]; ok {
+			return true
+		}
+		if _, ok := seenNames[ident.Name]; ok {
+			return true
+		}
+		if !pkgDocsReady {
+			pkgDocsReady = true
+			pkgPaths, err := pkgdata.ListPkgs()
+			if err != nil {
+				return false
+			}
+			for _, pkgPath := range pkgPaths {
+				if pkgDoc, err := pkgdata.GetPkgDoc(pkgPath); err == nil {
+					pkgDocs = append(pkgDocs, pkgDoc)
+				}
+			}
+		}
+
+		var candidates []string
+		for _, pkgDoc := range pkgDocs {
+			if pkgDoc.Name == ident.Name && pkgDocHasMember(pkgDoc, sel.Sel.Name) {
+				candidates = append(candidates, pkgDoc.Path)
+			}
+		}
+		if len(candidates) == 1 {
+			seenNames[ident.Name] = struct{}{}
+			importPaths = append(importPaths, candidates[0])
+		}
+		return true
+	})
+	return importPaths
+}
+
+// pkgDocHasMember reports whether the package documented by pkgDoc has a
+// package-level member with the given name.
+func pkgDocHasMember(pkgDoc *pkgdoc.PkgDoc, name string) bool {
+	if _, ok := pkgDoc.Funcs[name]; ok {
+		return true
+	}
+	if _, ok := pkgDoc.Types[name]; ok {
+		return true
+	}
+	if _, ok := pkgDoc.Vars[name]; ok {
+		return true
+	}
+	_, ok := pkgDoc.Consts[name]
+	return ok
+}
+
+// formatSpxImports returns the import declaration of the given sorted imports,
+// with a blank line between standard library packages and others. It returns
+// an empty string if there are no imports.
+func formatSpxImports(imports []spxImport) string {
+	switch len(imports) {
+	case 0:
+		return ""
+	case 1:
+		return "import " + imports[0].String()
+	}
+	var sb strings.Builder
+	sb.WriteString("import (\n")
+	for i, imp := range imports {
+		if i > 0 && imports[i-1].isStd() && !imp.isStd() {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\t" + imp.String() + "\n")
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
@@ -0,0 +1,281 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/goplus/xgolsw/protocol"
+	"github.com/goplus/xgolsw/xgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerSpxOrganizeImports(t *testing.T) {
+	newServer := func(mainSpx string) *Server {
+		m := map[string][]byte{
+			"main.spx":          []byte(mainSpx),
+			"assets/index.json": []byte(`{}`),
+		}
+		return New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+	}
+	organizeImports := func(t *testing.T, s *Server) []CodeAction {
+		codeActions, err := s.textDocumentCodeAction(context.Background(), &CodeActionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Context:      protocol.CodeActionContext{Only: []CodeActionKind{SourceOrganizeImports}},
+		})
+		require.NoError(t, err)
+		return codeActions
+	}
+
+	t.Run("SortAndGroup", func(t *testing.T) {
+		s := newServer(`import (
+	"github.com/goplus/spx/v2"
+	"strings"
+	"fmt"
+)
+
+onStart => {
+	fmt.Println strings.ToUpper("a"), spx.Mouse
+}
+run "assets", {Title: "My Game"}
+`)
+
+		codeActions := organizeImports(t, s)
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, CodeAction{
+			Title: "Organize imports",
+			Kind:  SourceOrganizeImports,
+			Edit: &WorkspaceEdit{
+				Changes: map[DocumentURI][]TextEdit{
+					"file:///main.spx": {
+						{
+							Range: Range{
+								Start: Position{Line: 0, Character: 0},
+								End:   Position{Line: 4, Character: 1},
+							},
+							NewText: "import (\n\t\"fmt\"\n\t\"strings\"\n\n\t\"github.com/goplus/spx/v2\"\n)",
+						},
+					},
+				},
+			},
+		}, codeActions[0])
+	})
+
+	t.Run("RemoveUnused", func(t *testing.T) {
+		s := newServer(`import (
+	"fmt"
+	"strings"
+)
+
+onStart => {
+	fmt.Println "a"
+}
+run "assets", {Title: "My Game"}
+`)
+
+		codeActions := organizeImports(t, s)
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 0, Character: 0},
+					End:   Position{Line: 3, Character: 1},
+				},
+				NewText: `import "fmt"`,
+			},
+		}, codeActions[0].Edit.Changes["file:///main.spx"])
+	})
+
+	t.Run("RemoveAll", func(t *testing.T) {
+		s := newServer(`import "strings"
+
+onStart => {
+	println "a"
+}
+run "assets", {Title: "My Game"}
+`)
+
+		codeActions := organizeImports(t, s)
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 0, Character: 0},
+					End:   Position{Line: 2, Character: 0},
+				},
+				NewText: "",
+			},
+		}, codeActions[0].Edit.Changes["file:///main.spx"])
+	})
+
+	t.Run("AddMissing", func(t *testing.T) {
+		s := newServer(`onStart => {
+	println strings.ToUpper("a")
+}
+run "assets", {Title: "My Game"}
+`)
+
+		codeActions := organizeImports(t, s)
+		require.Len(t, codeActions, 1)
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 0, Character: 0},
+					End:   Position{Line: 0, Character: 0},
+				},
+				NewText: "import \"strings\"\n\n",
+			},
+		}, codeActions[0].Edit.Changes["file:///main.spx"])
+	})
+
+	t.Run("AlreadyOrganized", func(t *testing.T) {
+		s := newServer(`import "strings"
+
+onStart => {
+	println strings.ToUpper("a")
+}
+run "assets", {Title: "My Game"}
+`)
+
+		codeActions := organizeImports(t, s)
+		assert.Empty(t, codeActions)
+	})
+
+	t.Run("Comments", func(t *testing.T) {
+		s := newServer(`import (
+	"strings" // For ToUpper.
+	"fmt"
+)
+
+onStart => {
+	fmt.Println strings.ToUpper("a")
+}
+run "assets", {Title: "My Game"}
+`)
+
+		codeActions := organizeImports(t, s)
+		assert.Empty(t, codeActions)
+	})
+}
+
+func TestServerTextDocumentWillSaveWaitUntil(t *testing.T) {
+	m := map[string][]byte{
+		"main.spx": []byte(`import (
+	"fmt"
+	"strings"
+)
+
+onStart => {
+	fmt.Println "a"
+}
+run "assets", {Title: "My Game"}
+`),
+		"assets/index.json": []byte(`{}`),
+	}
+	s := New(newMapFSWithoutModTime(m), &mockReplier{}, fileMapGetter(m), &MockScheduler{})
+	params := &WillSaveTextDocumentParams{
+		TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		Reason:       protocol.Manual,
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		edits, err := s.textDocumentWillSaveWaitUntil(context.Background(), params)
+		require.NoError(t, err)
+		assert.Nil(t, edits)
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		require.NoError(t, s.didChangeConfiguration(&DidChangeConfigurationParams{
+			Settings: map[string]any{"formatting": map[string]any{"organizeImportsOnSave": true}},
+		}))
+
+		edits, err := s.textDocumentWillSaveWaitUntil(context.Background(), params)
+		require.NoError(t, err)
+		assert.Equal(t, []TextEdit{
+			{
+				Range: Range{
+					Start: Position{Line: 0, Character: 0},
+					End:   Position{Line: 3, Character: 1},
+				},
+				NewText: `import "fmt"`,
+			},
+		}, edits)
+	})
+
+	t.Run("NonSpxFile", func(t *testing.T) {
+		edits, err := s.textDocumentWillSaveWaitUntil(context.Background(), &WillSaveTextDocumentParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///assets/index.json"},
+			Reason:       protocol.Manual,
+		})
+		require.NoError(t, err)
+		assert.Nil(t, edits)
+	})
+}
+
+func TestServerSpxOrganizeImportsCommand(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`import (
+	"strings"
+	"fmt"
+)
+
+onStart => {
+	fmt.Println strings.ToUpper("a")
+}
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx": []byte(`import "math"
+
+onStart => {
+	println strconv.Itoa(1)
+}
+`),
+			"Organized.spx": []byte(`import "fmt"
+
+onStart => {
+	fmt.Println "a"
+}
+`),
+			"assets/index.json":                   []byte(`{}`),
+			"assets/sprites/MySprite/index.json":  []byte(`{}`),
+			"assets/sprites/Organized/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		result, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{Command: "spx.organizeImports"})
+		require.NoError(t, err)
+		assert.Equal(t, &WorkspaceEdit{
+			Changes: map[DocumentURI][]TextEdit{
+				"file:///main.spx": {
+					{
+						Range: Range{
+							Start: Position{Line: 0, Character: 0},
+							End:   Position{Line: 3, Character: 1},
+						},
+						NewText: "import (\n\t\"fmt\"\n\t\"strings\"\n)",
+					},
+				},
+				"file:///MySprite.spx": {
+					{
+						Range: Range{
+							Start: Position{Line: 0, Character: 0},
+							End:   Position{Line: 0, Character: 13},
+						},
+						NewText: "import \"strconv\"",
+					},
+				},
+			},
+		}, result)
+	})
+
+	t.Run("WithoutTypes", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`run "assets", {Title: "My Game"}`),
+		}
+		s := New(newMapFSWithFeatures(m, xgo.FeatASTCache), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{Command: "spx.organizeImports"})
+		assert.EqualError(t, err, "command spx.organizeImports is not supported without type checking")
+	})
+}
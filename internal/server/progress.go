@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/goplus/xgolsw/jsonrpc2"
+	"github.com/goplus/xgolsw/protocol"
+)
+
+// workDoneProgress reports work done progress to the client for a progress
+// token. All its methods are no-ops on a nil receiver, so callers don't need
+// to check whether progress reporting is available.
+//
+// See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#workDoneProgress
+type workDoneProgress struct {
+	s     *Server
+	token ProgressToken
+}
+
+// newWorkDoneProgress creates a [workDoneProgress] for the given token, which
+// is typically provided by the client via [protocol.WorkDoneProgressParams].
+// It returns nil if token is nil.
+func (s *Server) newWorkDoneProgress(token ProgressToken) *workDoneProgress {
+	if token == nil {
+		return nil
+	}
+	return &workDoneProgress{s: s, token: token}
+}
+
+// createWorkDoneProgress asks the client to create a server-initiated
+// progress token and returns a [workDoneProgress] for it. It returns nil if
+// the client does not support server-initiated progress or rejects the
+// request.
+func (s *Server) createWorkDoneProgress(ctx context.Context) *workDoneProgress {
+	if !s.clientCapabilities.Window.WorkDoneProgress {
+		return nil
+	}
+	token := fmt.Sprintf("xgolsw-%d", s.lastProgressTokenID.Add(1))
+	if _, err := s.call(ctx, "window/workDoneProgress/create", &protocol.WorkDoneProgressCreateParams{Token: token}); err != nil {
+		return nil
+	}
+	return s.newWorkDoneProgress(token)
+}
+
+// begin reports the start of the work.
+func (p *workDoneProgress) begin(title, message string) {
+	if p == nil {
+		return
+	}
+	p.notify(&protocol.WorkDoneProgressBegin{
+		Kind:    "begin",
+		Title:   title,
+		Message: message,
+	})
+}
+
+// report reports the progress of the work. The percentage should be in the
+// range of [0, 100].
+func (p *workDoneProgress) report(message string, percentage uint32) {
+	if p == nil {
+		return
+	}
+	p.notify(&protocol.WorkDoneProgressReport{
+		Kind:       "report",
+		Message:    message,
+		Percentage: percentage,
+	})
+}
+
+// end reports the end of the work.
+func (p *workDoneProgress) end(message string) {
+	if p == nil {
+		return
+	}
+	p.notify(&protocol.WorkDoneProgressEnd{
+		Kind:    "end",
+		Message: message,
+	})
+}
+
+// notify sends a $/progress notification with the given value to the client.
+func (p *workDoneProgress) notify(value any) {
+	n, err := jsonrpc2.NewNotification("$/progress", &protocol.ProgressParams{
+		Token: p.token,
+		Value: value,
+	})
+	if err != nil {
+		return
+	}
+	p.s.replier.ReplyMessage(n)
+}
+
+// workDoneProgressKey is the context key for [workDoneProgress].
+type workDoneProgressKey struct{}
+
+// withWorkDoneProgress returns a copy of ctx that carries the given progress.
+// It allows deeply nested operations, such as compile phases, to report
+// progress without threading it through every call.
+func withWorkDoneProgress(ctx context.Context, progress *workDoneProgress) context.Context {
+	return context.WithValue(ctx, workDoneProgressKey{}, progress)
+}
+
+// workDoneProgressFromContext returns the [workDoneProgress] carried by ctx,
+// or nil if there is none.
+func workDoneProgressFromContext(ctx context.Context) *workDoneProgress {
+	progress, _ := ctx.Value(workDoneProgressKey{}).(*workDoneProgress)
+	return progress
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/goplus/xgolsw/jsonrpc2"
+	"github.com/goplus/xgolsw/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// callRespondingReplier is a [mockReplier] that responds to server-initiated
+// calls with the given error.
+type callRespondingReplier struct {
+	mockReplier
+	s   *Server
+	err error
+}
+
+func (r *callRespondingReplier) ReplyMessage(msg jsonrpc2.Message) error {
+	r.mockReplier.ReplyMessage(msg)
+	if c, ok := msg.(*jsonrpc2.Call); ok {
+		resp, err := jsonrpc2.NewResponse(c.ID(), nil, r.err)
+		if err != nil {
+			return err
+		}
+		go r.s.HandleMessage(resp)
+	}
+	return nil
+}
+
+func progressValues(t *testing.T, msgs []jsonrpc2.Message) []map[string]any {
+	var values []map[string]any
+	for _, msg := range msgs {
+		n, ok := msg.(*jsonrpc2.Notification)
+		if !ok || n.Method() != "$/progress" {
+			continue
+		}
+		var params struct {
+			Token any            `json:"token"`
+			Value map[string]any `json:"value"`
+		}
+		require.NoError(t, json.Unmarshal(n.Params(), &params))
+		values = append(values, params.Value)
+	}
+	return values
+}
+
+func TestWorkDoneProgress(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), replier, nil, &MockScheduler{})
+
+		progress := s.newWorkDoneProgress("token")
+		require.NotNil(t, progress)
+		progress.begin("Title", "Begin")
+		progress.report("Report", 50)
+		progress.end("End")
+
+		values := progressValues(t, replier.getMessages())
+		require.Len(t, values, 3)
+		assert.Equal(t, "begin", values[0]["kind"])
+		assert.Equal(t, "Title", values[0]["title"])
+		assert.Equal(t, "Begin", values[0]["message"])
+		assert.Equal(t, "report", values[1]["kind"])
+		assert.Equal(t, "Report", values[1]["message"])
+		assert.Equal(t, float64(50), values[1]["percentage"])
+		assert.Equal(t, "end", values[2]["kind"])
+		assert.Equal(t, "End", values[2]["message"])
+	})
+
+	t.Run("NilToken", func(t *testing.T) {
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), replier, nil, &MockScheduler{})
+
+		progress := s.newWorkDoneProgress(nil)
+		assert.Nil(t, progress)
+		progress.begin("Title", "")
+		progress.report("", 50)
+		progress.end("")
+		assert.Empty(t, replier.getMessages())
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), &mockReplier{}, nil, &MockScheduler{})
+
+		assert.Nil(t, workDoneProgressFromContext(context.Background()))
+
+		progress := s.newWorkDoneProgress("token")
+		ctx := withWorkDoneProgress(context.Background(), progress)
+		assert.Same(t, progress, workDoneProgressFromContext(ctx))
+	})
+}
+
+func TestServerCreateWorkDoneProgress(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		replier := &callRespondingReplier{}
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), replier, nil, &MockScheduler{})
+		replier.s = s
+		s.clientCapabilities.Window.WorkDoneProgress = true
+
+		progress := s.createWorkDoneProgress(context.Background())
+		require.NotNil(t, progress)
+
+		msgs := replier.getMessages()
+		require.Len(t, msgs, 1)
+		c, ok := msgs[0].(*jsonrpc2.Call)
+		require.True(t, ok)
+		assert.Equal(t, "window/workDoneProgress/create", c.Method())
+		var params protocol.WorkDoneProgressCreateParams
+		require.NoError(t, json.Unmarshal(c.Params(), &params))
+		assert.Equal(t, progress.token, params.Token)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), replier, nil, &MockScheduler{})
+
+		progress := s.createWorkDoneProgress(context.Background())
+		assert.Nil(t, progress)
+		assert.Empty(t, replier.getMessages())
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		replier := &callRespondingReplier{err: jsonrpc2.NewError(-32603, "rejected")}
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), replier, nil, &MockScheduler{})
+		replier.s = s
+		s.clientCapabilities.Window.WorkDoneProgress = true
+
+		progress := s.createWorkDoneProgress(context.Background())
+		assert.Nil(t, progress)
+	})
+
+	t.Run("CancelledContext", func(t *testing.T) {
+		replier := &mockReplier{} // Never responds.
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), replier, nil, &MockScheduler{})
+		s.clientCapabilities.Window.WorkDoneProgress = true
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		progress := s.createWorkDoneProgress(ctx)
+		assert.Nil(t, progress)
+	})
+}
+
+func TestServerHandleResponse(t *testing.T) {
+	t.Run("UnknownCall", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), &mockReplier{}, nil, &MockScheduler{})
+
+		resp, err := jsonrpc2.NewResponse(jsonrpc2.NewIntID(42), nil, nil)
+		require.NoError(t, err)
+		err = s.HandleMessage(resp)
+		assert.ErrorContains(t, err, "unexpected response for unknown call")
+	})
+}
+
+func TestServerWorkspaceDiagnosticProgress(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(newTestFileMap()), replier, fileMapGetter(newTestFileMap()), &MockScheduler{})
+
+		params := &WorkspaceDiagnosticParams{}
+		params.WorkDoneToken = "token"
+		_, err := s.workspaceDiagnostic(context.Background(), params)
+		require.NoError(t, err)
+
+		values := progressValues(t, replier.getMessages())
+		require.GreaterOrEqual(t, len(values), 3)
+		assert.Equal(t, "begin", values[0]["kind"])
+		assert.Equal(t, "Diagnosing workspace", values[0]["title"])
+		for _, value := range values[1 : len(values)-1] {
+			assert.Equal(t, "report", value["kind"])
+		}
+		assert.Equal(t, "end", values[len(values)-1]["kind"])
+	})
+
+	t.Run("WithoutToken", func(t *testing.T) {
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(newTestFileMap()), replier, fileMapGetter(newTestFileMap()), &MockScheduler{})
+
+		_, err := s.workspaceDiagnostic(context.Background(), &WorkspaceDiagnosticParams{})
+		require.NoError(t, err)
+		assert.Empty(t, progressValues(t, replier.getMessages()))
+	})
+}
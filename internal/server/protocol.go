@@ -17,8 +17,18 @@ type (
 	Range       = protocol.Range
 	Location    = protocol.Location
 
-	TextEdit      = protocol.TextEdit
-	WorkspaceEdit = protocol.WorkspaceEdit
+	TextEdit          = protocol.TextEdit
+	AnnotatedTextEdit = protocol.AnnotatedTextEdit
+	WorkspaceEdit     = protocol.WorkspaceEdit
+
+	DocumentChange                          = protocol.DocumentChange
+	TextDocumentEdit                        = protocol.TextDocumentEdit
+	Or_TextDocumentEdit_edits_Elem          = protocol.Or_TextDocumentEdit_edits_Elem
+	OptionalVersionedTextDocumentIdentifier = protocol.OptionalVersionedTextDocumentIdentifier
+	RenameFile                              = protocol.RenameFile
+	CreateFile                              = protocol.CreateFile
+	DeleteFile                              = protocol.DeleteFile
+	ResourceOperationKind                   = protocol.ResourceOperationKind
 
 	TextDocumentPositionParams = protocol.TextDocumentPositionParams
 	TextDocumentIdentifier     = protocol.TextDocumentIdentifier
@@ -26,16 +36,26 @@ type (
 	InsertTextFormat = protocol.InsertTextFormat
 
 	MarkupContent = protocol.MarkupContent
+	MarkupKind    = protocol.MarkupKind
 
 	DocumentHighlightParams = protocol.DocumentHighlightParams
 	DocumentHighlight       = protocol.DocumentHighlight
 
-	DocumentFormattingParams = protocol.DocumentFormattingParams
+	DocumentFormattingParams       = protocol.DocumentFormattingParams
+	DocumentOnTypeFormattingParams = protocol.DocumentOnTypeFormattingParams
+
+	CodeActionParams = protocol.CodeActionParams
+	CodeAction       = protocol.CodeAction
+	CodeActionKind   = protocol.CodeActionKind
 
 	PrepareRenameParams = protocol.PrepareRenameParams
 	RenameParams        = protocol.RenameParams
 
 	Diagnostic                            = protocol.Diagnostic
+	DiagnosticRelatedInformation          = protocol.DiagnosticRelatedInformation
+	CodeDescription                       = protocol.CodeDescription
+	DiagnosticSeverity                    = protocol.DiagnosticSeverity
+	DiagnosticTag                         = protocol.DiagnosticTag
 	DocumentDiagnosticParams              = protocol.DocumentDiagnosticParams
 	WorkspaceDiagnosticParams             = protocol.WorkspaceDiagnosticParams
 	DocumentDiagnosticReport              = protocol.DocumentDiagnosticReport
@@ -49,7 +69,9 @@ type (
 	CompletionParams                = protocol.CompletionParams
 	CompletionItemKind              = protocol.CompletionItemKind
 	CompletionItem                  = protocol.CompletionItem
+	CompletionList                  = protocol.CompletionList
 	Or_CompletionItem_documentation = protocol.Or_CompletionItem_documentation
+	Or_CompletionItem_textEdit      = protocol.Or_CompletionItem_textEdit
 
 	DocumentLinkParams = protocol.DocumentLinkParams
 	DocumentLink       = protocol.DocumentLink
@@ -72,19 +94,38 @@ type (
 	SemanticTokens         = protocol.SemanticTokens
 
 	SignatureHelpParams  = protocol.SignatureHelpParams
+	SignatureHelpContext = protocol.SignatureHelpContext
 	SignatureHelp        = protocol.SignatureHelp
 	SignatureInformation = protocol.SignatureInformation
 	ParameterInformation = protocol.ParameterInformation
 
 	InitializeParams     = protocol.InitializeParams
+	InitializeResult     = protocol.InitializeResult
 	InitializedParams    = protocol.InitializedParams
+	ClientCapabilities   = protocol.ClientCapabilities
+	ServerCapabilities   = protocol.ServerCapabilities
 	ExecuteCommandParams = protocol.ExecuteCommandParams
+	Command              = protocol.Command
 	CancelParams         = protocol.CancelParams
 
+	ProgressToken  = protocol.ProgressToken
+	ProgressParams = protocol.ProgressParams
+
+	TraceValue     = protocol.TraceValue
+	SetTraceParams = protocol.SetTraceParams
+	LogTraceParams = protocol.LogTraceParams
+
 	DidOpenTextDocumentParams   = protocol.DidOpenTextDocumentParams
 	DidChangeTextDocumentParams = protocol.DidChangeTextDocumentParams
 	DidCloseTextDocumentParams  = protocol.DidCloseTextDocumentParams
 	DidSaveTextDocumentParams   = protocol.DidSaveTextDocumentParams
+	WillSaveTextDocumentParams  = protocol.WillSaveTextDocumentParams
+
+	CreateFilesParams = protocol.CreateFilesParams
+	DeleteFilesParams = protocol.DeleteFilesParams
+
+	DidChangeConfigurationParams = protocol.DidChangeConfigurationParams
+	DidChangeWatchedFilesParams  = protocol.DidChangeWatchedFilesParams
 
 	InlayHintParams = protocol.InlayHintParams
 	InlayHint       = protocol.InlayHint
@@ -92,8 +133,13 @@ type (
 )
 
 const (
-	SeverityError   = protocol.SeverityError
-	SeverityWarning = protocol.SeverityWarning
+	SeverityError       = protocol.SeverityError
+	SeverityWarning     = protocol.SeverityWarning
+	SeverityInformation = protocol.SeverityInformation
+	SeverityHint        = protocol.SeverityHint
+
+	Unnecessary = protocol.Unnecessary
+	Deprecated  = protocol.Deprecated
 
 	TextCompletion      = protocol.TextCompletion
 	ClassCompletion     = protocol.ClassCompletion
@@ -106,15 +152,26 @@ const (
 	MethodCompletion    = protocol.MethodCompletion
 	FunctionCompletion  = protocol.FunctionCompletion
 	ModuleCompletion    = protocol.ModuleCompletion
+	SnippetCompletion   = protocol.SnippetCompletion
 
 	DiagnosticFull = protocol.DiagnosticFull
 
-	Markdown = protocol.Markdown
-	Text     = protocol.Text
+	QuickFix              = protocol.QuickFix
+	RefactorExtract       = protocol.RefactorExtract
+	RefactorRewrite       = protocol.RefactorRewrite
+	SourceOrganizeImports = protocol.SourceOrganizeImports
+	Source                = protocol.Source
+
+	Markdown  = protocol.Markdown
+	PlainText = protocol.PlainText
+	Text      = protocol.Text
 
 	Write = protocol.Write
 	Read  = protocol.Read
 
+	CreateResourceOperation = protocol.Create
+	RenameResourceOperation = protocol.Rename
+
 	PlainTextTextFormat = protocol.PlainTextTextFormat
 	SnippetTextFormat   = protocol.SnippetTextFormat
 
@@ -170,6 +227,31 @@ type SpxRenameResourceParams struct {
 	Resource SpxResourceIdentifier `json:"resource"`
 	// The new name of the spx resource.
 	NewName string `json:"newName"`
+	// Whether to also update the resource files, such as index.json entries
+	// and resource directories. If set, the result uses documentChanges.
+	IncludeResourceFiles bool `json:"includeResourceFiles,omitempty"`
+}
+
+// SpxCreateSpriteParams represents parameters to scaffold a new spx sprite in
+// the workspace.
+type SpxCreateSpriteParams struct {
+	// The name of the sprite, which is also the name of its classfile and
+	// resource directory.
+	Name string `json:"name"`
+}
+
+// SpxRenameResourceResult represents the result of the spx.renameResource
+// command.
+type SpxRenameResourceResult struct {
+	// The URI of the spx resource before the rename.
+	OldURI SpxResourceURI `json:"oldUri"`
+	// The URI of the spx resource after the rename.
+	NewURI SpxResourceURI `json:"newUri"`
+	// The edit that renames the spx resource.
+	Edit *WorkspaceEdit `json:"edit"`
+	// The references to the spx resource in the code before the rename,
+	// sorted by document and position.
+	References []SpxResourceReference `json:"references"`
 }
 
 // SpxResourceIdentifier identifies an spx resource.
@@ -246,6 +328,113 @@ type SpxGetInputSlotsParams struct {
 	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
 }
 
+// SpxDefinitionInfo represents info about an spx definition, as returned by
+// the spx.getDefinitions command.
+type SpxDefinitionInfo struct {
+	// The identifier of the definition, including the overload ID of an
+	// overloaded function.
+	ID SpxDefinitionIdentifier `json:"id"`
+	// The overview of the definition, such as its signature.
+	Overview string `json:"overview"`
+	// The documentation of the definition in Markdown.
+	Detail string `json:"detail,omitempty"`
+	// The kind of the definition.
+	Kind CompletionItemKind `json:"kind"`
+	// The simplified type of the definition, if any.
+	Type string `json:"type,omitempty"`
+	// The range of the symbol in the document.
+	Range Range `json:"range"`
+}
+
+// SpxGetSpriteAPIsParams represents parameters to get the spx APIs available
+// in a specific document.
+type SpxGetSpriteAPIsParams struct {
+	// The text document identifier.
+	TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+}
+
+// SpxAPICategoryName is the name of a category of spx APIs.
+type SpxAPICategoryName string
+
+const (
+	SpxAPICategoryMotion  SpxAPICategoryName = "motion"
+	SpxAPICategoryLooks   SpxAPICategoryName = "looks"
+	SpxAPICategorySound   SpxAPICategoryName = "sound"
+	SpxAPICategoryEvents  SpxAPICategoryName = "events"
+	SpxAPICategoryControl SpxAPICategoryName = "control"
+	SpxAPICategorySensing SpxAPICategoryName = "sensing"
+	SpxAPICategoryOther   SpxAPICategoryName = "other"
+)
+
+// SpxAPICategory represents a category of spx APIs, as returned by the
+// spx.getSpriteAPIs command.
+type SpxAPICategory struct {
+	// The name of the category.
+	Name SpxAPICategoryName `json:"name"`
+	// The APIs in the category, in the order they are declared.
+	APIs []SpxAPIInfo `json:"apis"`
+}
+
+// SpxAPIInfo represents info about an spx API.
+type SpxAPIInfo struct {
+	// The identifier of the API, including the overload ID of an overloaded
+	// function.
+	ID SpxDefinitionIdentifier `json:"id"`
+	// The overview of the API, such as its signature.
+	Overview string `json:"overview"`
+	// The documentation of the API in Markdown.
+	Detail string `json:"detail,omitempty"`
+	// The kind of the API.
+	Kind CompletionItemKind `json:"kind"`
+	// The simplified type of the API, if any.
+	Type string `json:"type,omitempty"`
+}
+
+// SpxRunAnalyzersParams represents parameters to run a selected set of
+// analyzers on the workspace.
+type SpxRunAnalyzersParams struct {
+	// The names of the analyzers to run, such as "appends".
+	Analyzers []string `json:"analyzers"`
+}
+
+// SpxWorkspaceDiagnosis represents the diagnostics of the workspace, as
+// returned by the spx.diagnoseWorkspace and spx.runAnalyzers commands.
+type SpxWorkspaceDiagnosis struct {
+	// The diagnosed files sorted by URI, including those without
+	// diagnostics.
+	Files []SpxFileDiagnosis `json:"files"`
+	// The number of diagnostics with error severity in all files.
+	ErrorCount int `json:"errorCount"`
+	// The number of diagnostics with warning severity in all files.
+	WarningCount int `json:"warningCount"`
+}
+
+// SpxFileDiagnosis represents the diagnostics of a file in the workspace.
+type SpxFileDiagnosis struct {
+	// The URI of the file.
+	URI DocumentURI `json:"uri"`
+	// Whether the file is an spx resource metadata file, such as
+	// index.json, rather than an spx source file.
+	Resource bool `json:"resource,omitempty"`
+	// The diagnostics of the file.
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// SpxGeneratedGo represents the Go code generated for the project, as
+// returned by the spx.generateGo command.
+type SpxGeneratedGo struct {
+	// The generated Go source files sorted by name.
+	Files []SpxGeneratedGoFile `json:"files"`
+}
+
+// SpxGeneratedGoFile represents a generated Go source file.
+type SpxGeneratedGoFile struct {
+	// The name of the file, such as "xgo_autogen.go".
+	Name string `json:"name"`
+	// The content of the file.
+	Content string `json:"content"`
+}
+
 // SpxInputSlot represents a modifiable item in the code.
 type SpxInputSlot struct {
 	Kind            SpxInputSlotKind   `json:"kind"`
@@ -333,6 +522,99 @@ const (
 	SpxInputKindPredefined SpxInputKind = "predefined"
 )
 
+// SpxResourceList represents all spx resources of the project, as returned by
+// the spx.listResources command.
+type SpxResourceList struct {
+	Backdrops []SpxBackdropResourceInfo `json:"backdrops"`
+	Sounds    []SpxSoundResourceInfo    `json:"sounds"`
+	Sprites   []SpxSpriteResourceInfo   `json:"sprites"`
+	Widgets   []SpxWidgetResourceInfo   `json:"widgets"`
+}
+
+// SpxBackdropResourceInfo represents info about an spx backdrop resource.
+type SpxBackdropResourceInfo struct {
+	URI              SpxResourceURI `json:"uri"`
+	Name             string         `json:"name"`
+	File             DocumentURI    `json:"file,omitempty"`
+	BitmapResolution int            `json:"bitmapResolution,omitempty"`
+}
+
+// SpxSoundResourceInfo represents info about an spx sound resource.
+type SpxSoundResourceInfo struct {
+	URI      SpxResourceURI `json:"uri"`
+	Name     string         `json:"name"`
+	File     DocumentURI    `json:"file,omitempty"`
+	Format   string         `json:"format,omitempty"`
+	Duration float64        `json:"duration,omitempty"` // In seconds.
+	Rate     int            `json:"rate,omitempty"`
+}
+
+// SpxSpriteResourceInfo represents info about an spx sprite resource.
+type SpxSpriteResourceInfo struct {
+	URI              SpxResourceURI                   `json:"uri"`
+	Name             string                           `json:"name"`
+	Costumes         []SpxSpriteCostumeResourceInfo   `json:"costumes"`
+	CostumeIndex     int                              `json:"costumeIndex"`
+	Animations       []SpxSpriteAnimationResourceInfo `json:"animations"`
+	DefaultAnimation string                           `json:"defaultAnimation,omitempty"`
+}
+
+// SpxSpriteCostumeResourceInfo represents info about an spx sprite costume
+// resource.
+type SpxSpriteCostumeResourceInfo struct {
+	URI              SpxResourceURI `json:"uri"`
+	Name             string         `json:"name"`
+	File             DocumentURI    `json:"file,omitempty"`
+	BitmapResolution int            `json:"bitmapResolution,omitempty"`
+}
+
+// SpxSpriteAnimationResourceInfo represents info about an spx sprite
+// animation resource.
+type SpxSpriteAnimationResourceInfo struct {
+	URI  SpxResourceURI `json:"uri"`
+	Name string         `json:"name"`
+	// Names of the costumes used as the frames of the animation, in order.
+	Frames []string `json:"frames"`
+}
+
+// SpxWidgetResourceInfo represents info about an spx widget resource.
+type SpxWidgetResourceInfo struct {
+	URI   SpxResourceURI `json:"uri"`
+	Name  string         `json:"name"`
+	Type  string         `json:"type,omitempty"`
+	Label string         `json:"label,omitempty"`
+}
+
+// SpxAnalyzerInfo represents info about an analyzer, as returned by the
+// spx.listAnalyzers command. Severities are given by their names as accepted
+// by [Settings.AnalyzerSeverity].
+type SpxAnalyzerInfo struct {
+	Name             string `json:"name"`
+	Doc              string `json:"doc"`
+	EnabledByDefault bool   `json:"enabledByDefault"`
+	DefaultSeverity  string `json:"defaultSeverity"`
+	Enabled          bool   `json:"enabled"`
+	Severity         string `json:"severity"`
+}
+
+// SpxGetResourceReferencesParams represents parameters to get the references
+// to an spx resource, as accepted by the spx.getResourceReferences command.
+type SpxGetResourceReferencesParams struct {
+	// The spx resource.
+	Resource SpxResourceIdentifier `json:"resource"`
+}
+
+// SpxResourceReference represents a reference to an spx resource in the code,
+// as returned by the spx.getResourceReferences command.
+type SpxResourceReference struct {
+	// The URI of the referenced spx resource.
+	Resource SpxResourceURI `json:"resource"`
+	// The kind of the reference.
+	Kind SpxResourceRefKind `json:"kind"`
+	// The location of the reference.
+	Location Location `json:"location"`
+}
+
 // SpxResourceRefDocumentLinkData represents data for an spx resource reference
 // document link.
 type SpxResourceRefDocumentLinkData struct {
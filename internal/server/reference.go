@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"go/types"
 
 	xgoast "github.com/goplus/xgo/ast"
@@ -9,8 +10,8 @@ import (
 )
 
 // See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#textDocument_references
-func (s *Server) textDocumentReferences(params *ReferenceParams) ([]Location, error) {
-	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(params.TextDocument.URI)
+func (s *Server) textDocumentReferences(ctx context.Context, params *ReferenceParams) ([]Location, error) {
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(ctx, params.TextDocument.URI)
 	if err != nil {
 		return nil, err
 	}
@@ -19,6 +20,17 @@ func (s *Server) textDocumentReferences(params *ReferenceParams) ([]Location, er
 	}
 	position := ToPosition(result.proj, astFile, params.Position)
 
+	var locations []Location
+
+	if spxResourceRef := result.spxResourceRefAtASTFilePosition(astFile, position); spxResourceRef != nil {
+		switch spxResourceRef.Kind {
+		case SpxResourceRefKindStringLiteral, SpxResourceRefKindIndexLiteral:
+			return s.findSpxResourceReferenceLocations(result, spxResourceRef.ID, params.Context.IncludeDeclaration), nil
+		case SpxResourceRefKindAutoBinding, SpxResourceRefKindAutoBindingReference:
+			locations = append(locations, s.findSpxResourceReferenceLocations(result, spxResourceRef.ID, params.Context.IncludeDeclaration)...)
+		}
+	}
+
 	ident := xgoutil.IdentAtPosition(result.proj, astFile, position)
 	typeInfo, _ := result.proj.TypeInfo()
 	if typeInfo == nil {
@@ -26,11 +38,12 @@ func (s *Server) textDocumentReferences(params *ReferenceParams) ([]Location, er
 	}
 	obj := typeInfo.ObjectOf(ident)
 	if obj == nil {
+		if len(locations) > 0 {
+			return DedupeLocations(locations), nil
+		}
 		return nil, nil
 	}
 
-	var locations []Location
-
 	locations = append(locations, s.findReferenceLocations(result, obj)...)
 
 	if fn, ok := obj.(*types.Func); ok && fn.Type().(*types.Signature).Recv() != nil {
@@ -70,6 +83,23 @@ func (s *Server) findReferenceLocations(result *compileResult, obj types.Object)
 	return locations
 }
 
+// findSpxResourceReferenceLocations returns the locations of all references to
+// the spx resource identified by id across all spx files. Auto-binding
+// declarations are only included if includeDeclaration is true.
+func (s *Server) findSpxResourceReferenceLocations(result *compileResult, id SpxResourceID, includeDeclaration bool) []Location {
+	var locations []Location
+	for _, ref := range result.spxResourceRefs {
+		if ref.ID != id {
+			continue
+		}
+		if ref.Kind == SpxResourceRefKindAutoBinding && !includeDeclaration {
+			continue
+		}
+		locations = append(locations, s.locationForNode(result.proj, ref.Node))
+	}
+	return DedupeLocations(locations)
+}
+
 // handleMethodReferences finds all references to a method, including interface
 // implementations and interface method references.
 func (s *Server) handleMethodReferences(result *compileResult, fn *types.Func) []Location {
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -27,7 +28,7 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		mainSpxMySpriteRef, err := s.textDocumentReferences(&ReferenceParams{
+		mainSpxMySpriteRef, err := s.textDocumentReferences(context.Background(), &ReferenceParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 2},
@@ -61,7 +62,7 @@ onStart => {
 			},
 		})
 
-		mainSpxTurnRef, err := s.textDocumentReferences(&ReferenceParams{
+		mainSpxTurnRef, err := s.textDocumentReferences(context.Background(), &ReferenceParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 4, Character: 9},
@@ -89,13 +90,94 @@ onStart => {
 		})
 	})
 
+	t.Run("SpxResource", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	MySound Sound
+)
+play MySound
+play "MySound"
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx": []byte(`
+onStart => {
+	play "MySound"
+	play "OtherSound"
+}
+`),
+			"assets/index.json":                   []byte(`{}`),
+			"assets/sprites/MySprite/index.json":  []byte(`{}`),
+			"assets/sounds/MySound/index.json":    []byte(`{}`),
+			"assets/sounds/OtherSound/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		mainSpxMySoundDecl := Location{
+			URI: "file:///main.spx",
+			Range: Range{
+				Start: Position{Line: 2, Character: 1},
+				End:   Position{Line: 2, Character: 8},
+			},
+		}
+		mainSpxMySoundRef := Location{
+			URI: "file:///main.spx",
+			Range: Range{
+				Start: Position{Line: 4, Character: 5},
+				End:   Position{Line: 4, Character: 12},
+			},
+		}
+		mainSpxMySoundLit := Location{
+			URI: "file:///main.spx",
+			Range: Range{
+				Start: Position{Line: 5, Character: 5},
+				End:   Position{Line: 5, Character: 14},
+			},
+		}
+		mySpriteSpxMySoundLit := Location{
+			URI: "file:///MySprite.spx",
+			Range: Range{
+				Start: Position{Line: 2, Character: 6},
+				End:   Position{Line: 2, Character: 15},
+			},
+		}
+
+		litRefs, err := s.textDocumentReferences(context.Background(), &ReferenceParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
+				Position:     Position{Line: 2, Character: 8},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, litRefs, 3)
+		assert.Contains(t, litRefs, mainSpxMySoundRef)
+		assert.Contains(t, litRefs, mainSpxMySoundLit)
+		assert.Contains(t, litRefs, mySpriteSpxMySoundLit)
+
+		varRefs, err := s.textDocumentReferences(context.Background(), &ReferenceParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 4, Character: 6},
+			},
+			Context: ReferenceContext{
+				IncludeDeclaration: true,
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, varRefs, 4)
+		assert.Contains(t, varRefs, mainSpxMySoundDecl)
+		assert.Contains(t, varRefs, mainSpxMySoundRef)
+		assert.Contains(t, varRefs, mainSpxMySoundLit)
+		assert.Contains(t, varRefs, mySpriteSpxMySoundLit)
+	})
+
 	t.Run("InvalidPosition", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.spx": []byte(`var x int`),
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		refs, err := s.textDocumentReferences(&ReferenceParams{
+		refs, err := s.textDocumentReferences(context.Background(), &ReferenceParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 99, Character: 99},
@@ -1,16 +1,20 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"go/types"
 	"slices"
+	"strings"
 
 	xgoast "github.com/goplus/xgo/ast"
+	xgotoken "github.com/goplus/xgo/token"
+	"github.com/goplus/xgolsw/xgo"
 	"github.com/goplus/xgolsw/xgo/xgoutil"
 )
 
 // See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#textDocument_prepareRename
-func (s *Server) textDocumentPrepareRename(params *PrepareRenameParams) (*Range, error) {
+func (s *Server) textDocumentPrepareRename(ctx context.Context, params *PrepareRenameParams) (*Range, error) {
 	proj := s.getProjWithFile()
 	if proj == nil {
 		return nil, nil
@@ -27,28 +31,103 @@ func (s *Server) textDocumentPrepareRename(params *PrepareRenameParams) (*Range,
 	position := ToPosition(proj, astFile, params.Position)
 
 	ident := xgoutil.IdentAtPosition(proj, astFile, position)
-	if ident == nil {
-		return nil, nil
+	if ident != nil {
+		typeInfo, _ := proj.TypeInfo()
+		if typeInfo != nil {
+			obj := typeInfo.ObjectOf(ident)
+			if err := checkRenameableObject(obj); err != nil {
+				return nil, err
+			}
+			if xgoutil.IsRenameable(obj) {
+				defIdent := typeInfo.DefIdentFor(obj)
+				if defIdent != nil && xgoutil.NodeTokenFile(proj, defIdent) != nil {
+					return ToPtr(renameRangeForIdent(proj, ident)), nil
+				}
+			}
+		}
 	}
-	typeInfo, _ := proj.TypeInfo()
-	if typeInfo == nil {
-		return nil, nil
+	return s.spxPrepareRenameResource(ctx, params)
+}
+
+// checkRenameableObject returns an error explaining why the given object
+// cannot be renamed if it is a builtin or defined in a non-main package. It
+// returns nil otherwise, including when obj is nil.
+func checkRenameableObject(obj types.Object) error {
+	if obj == nil {
+		return nil
 	}
-	obj := typeInfo.ObjectOf(ident)
-	if !xgoutil.IsRenameable(obj) {
-		return nil, nil
+	if obj.Pkg() == nil || obj.Parent() == types.Universe || xgoutil.IsInBuiltinPkg(obj) {
+		return fmt.Errorf("cannot rename builtin %q", obj.Name())
 	}
-	defIdent := typeInfo.DefIdentFor(obj)
-	if defIdent == nil || xgoutil.NodeTokenFile(proj, defIdent) == nil {
+	if xgoutil.IsInMainPkg(obj) {
+		return nil
+	}
+	if obj.Pkg().Path() == SpxPkgPath {
+		return fmt.Errorf("cannot rename %q: it is defined in the spx package", obj.Name())
+	}
+	return fmt.Errorf("cannot rename %q: it is defined in package %q", obj.Name(), obj.Pkg().Path())
+}
+
+// renameRangeForIdent returns the range of the given identifier that a rename
+// replaces. For XGo overloaded function names like "foo__0", the overload
+// suffix is excluded so that it is kept by the rename.
+func renameRangeForIdent(proj *xgo.Project, ident *xgoast.Ident) Range {
+	rng := RangeForNode(proj, ident)
+	rng.End.Character -= uint32(len(xgoOverloadSuffix(ident.Name)))
+	return rng
+}
+
+// xgoOverloadSuffix returns the overload suffix (e.g., "__0") of the given XGo
+// overloaded function name, or an empty string if it is not one.
+func xgoOverloadSuffix(name string) string {
+	if !xgoutil.IsXGoOverloadedFuncName(name) {
+		return ""
+	}
+	return name[strings.LastIndex(name, "__"):]
+}
+
+// spxPrepareRenameResource returns the range of the spx resource reference at
+// the given position, or nil if there is none. For string literals, the range
+// excludes the quotes. It returns an error if the referenced spx resource
+// cannot be renamed from there. Sprite type names, which are renamed along
+// with their spx source files, are also accepted.
+func (s *Server) spxPrepareRenameResource(ctx context.Context, params *PrepareRenameParams) (*Range, error) {
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(ctx, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	if astFile == nil {
 		return nil, nil
 	}
+	position := ToPosition(result.proj, astFile, params.Position)
+
+	if ident := xgoutil.IdentAtPosition(result.proj, astFile, position); ident != nil {
+		if typeInfo, _ := result.proj.TypeInfo(); typeInfo != nil && result.isSpxSpriteTypeName(typeInfo.ObjectOf(ident)) {
+			return ToPtr(RangeForNode(result.proj, ident)), nil
+		}
+	}
 
-	return ToPtr(RangeForNode(proj, ident)), nil
+	spxResourceRef := result.spxResourceRefAtASTFilePosition(astFile, position)
+	if spxResourceRef == nil {
+		return nil, nil
+	}
+	if spxResourceRef.Kind == SpxResourceRefKindIndexLiteral {
+		return nil, fmt.Errorf("cannot rename spx resource %q from an index reference", spxResourceRef.ID.URI())
+	}
+	if s.spxResourceDefinitionLocation(result, spxResourceRef.ID) == nil {
+		return nil, fmt.Errorf("cannot rename spx resource %q: it does not exist", spxResourceRef.ID.URI())
+	}
+	rng := RangeForNode(result.proj, spxResourceRef.Node)
+	if lit, ok := spxResourceRef.Node.(*xgoast.BasicLit); ok && lit.Kind == xgotoken.STRING {
+		rng.Start.Character++
+		rng.End.Character--
+	}
+	return &rng, nil
 }
 
 // See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#textDocument_rename
-func (s *Server) textDocumentRename(params *RenameParams) (*WorkspaceEdit, error) {
-	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(params.TextDocument.URI)
+func (s *Server) textDocumentRename(ctx context.Context, params *RenameParams) (*WorkspaceEdit, error) {
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(ctx, params.TextDocument.URI)
 	if err != nil {
 		return nil, err
 	}
@@ -57,21 +136,32 @@ func (s *Server) textDocumentRename(params *RenameParams) (*WorkspaceEdit, error
 	}
 	position := ToPosition(result.proj, astFile, params.Position)
 
+	ident := xgoutil.IdentAtPosition(result.proj, astFile, position)
+	typeInfo, _ := result.proj.TypeInfo()
+	var obj types.Object
+	if ident != nil && typeInfo != nil {
+		obj = typeInfo.ObjectOf(ident)
+	}
+	if id := result.spxResourceIDForAutoBinding(obj); id != nil {
+		return s.spxRenameResourceAutoBinding(result, obj, id, params.NewName)
+	}
+	if result.isSpxSpriteTypeName(obj) {
+		return s.spxRenameSpriteType(result, obj.(*types.TypeName), params.NewName)
+	}
+
 	if spxResourceRef := result.spxResourceRefAtASTFilePosition(astFile, position); spxResourceRef != nil {
 		return s.spxRenameResourcesWithCompileResult(result, []SpxRenameResourceParams{{
 			Resource: SpxResourceIdentifier{
 				URI: spxResourceRef.ID.URI(),
 			},
-			NewName: params.NewName,
+			NewName:              params.NewName,
+			IncludeResourceFiles: s.clientSupportsResourceOperation(RenameResourceOperation),
 		}})
 	}
 
-	ident := xgoutil.IdentAtPosition(result.proj, astFile, position)
-	typeInfo, _ := result.proj.TypeInfo()
 	if typeInfo == nil {
 		return nil, nil
 	}
-	obj := typeInfo.ObjectOf(ident)
 	if !xgoutil.IsRenameable(obj) {
 		return nil, nil
 	}
@@ -80,27 +170,93 @@ func (s *Server) textDocumentRename(params *RenameParams) (*WorkspaceEdit, error
 		return nil, fmt.Errorf("failed to find definition of object %q", obj.Name())
 	}
 
-	defLoc := s.locationForNode(result.proj, defIdent)
-
 	workspaceEdit := WorkspaceEdit{
-		Changes: map[DocumentURI][]TextEdit{
-			defLoc.URI: {
-				{
-					Range:   RangeForNode(result.proj, defIdent),
-					NewText: params.NewName,
-				},
-			},
-		},
+		Changes: make(map[DocumentURI][]TextEdit),
 	}
-	for _, refLoc := range s.findReferenceLocations(result, obj) {
-		workspaceEdit.Changes[refLoc.URI] = append(workspaceEdit.Changes[refLoc.URI], TextEdit{
-			Range:   refLoc.Range,
+	for _, ident := range append([]*xgoast.Ident{defIdent}, typeInfo.RefIdentsFor(obj)...) {
+		documentURI := s.nodeDocumentURI(result.proj, ident)
+		workspaceEdit.Changes[documentURI] = append(workspaceEdit.Changes[documentURI], TextEdit{
+			Range:   renameRangeForIdent(result.proj, ident),
 			NewText: params.NewName,
 		})
 	}
 	return &workspaceEdit, nil
 }
 
+// spxRenameResourceAutoBinding renames an spx resource auto-binding object
+// together with its backing resource, since the object name must always match
+// the resource name. It returns an error if the new name cannot be used as an
+// identifier.
+func (s *Server) spxRenameResourceAutoBinding(result *compileResult, obj types.Object, id SpxResourceID, newName string) (*WorkspaceEdit, error) {
+	if !xgotoken.IsIdentifier(newName) {
+		return nil, fmt.Errorf("cannot rename auto-binding %q to %q: it must be a valid identifier to stay bound to spx resource %q", obj.Name(), newName, id.URI())
+	}
+	return s.spxRenameResourcesWithCompileResult(result, []SpxRenameResourceParams{{
+		Resource: SpxResourceIdentifier{
+			URI: id.URI(),
+		},
+		NewName:              newName,
+		IncludeResourceFiles: s.clientSupportsResourceOperation(RenameResourceOperation),
+	}})
+}
+
+// spxRenameSpriteType renames a sprite type together with the spx source file
+// defining it. If the sprite type backs a sprite resource, the resource and its
+// files are renamed as well, since their names must always match.
+//
+// The rename is only possible if the client supports renaming files, as the
+// sprite type cannot be renamed without its spx source file.
+func (s *Server) spxRenameSpriteType(result *compileResult, typeName *types.TypeName, newName string) (*WorkspaceEdit, error) {
+	oldName := typeName.Name()
+	if !xgotoken.IsIdentifier(newName) {
+		return nil, fmt.Errorf("cannot rename sprite type %q to %q: it must be a valid identifier", oldName, newName)
+	}
+	if !s.clientSupportsResourceOperation(RenameResourceOperation) {
+		return nil, fmt.Errorf("cannot rename sprite type %q: renaming its spx source file is not supported by the client", oldName)
+	}
+	if obj := typeName.Parent().Lookup(newName); obj != nil {
+		return nil, fmt.Errorf("cannot rename sprite type %q to %q: %q is already declared", oldName, newName, newName)
+	}
+
+	if result.spxResourceSet.Sprite(oldName) != nil {
+		return s.spxRenameResourcesWithCompileResult(result, []SpxRenameResourceParams{{
+			Resource: SpxResourceIdentifier{
+				URI: SpxSpriteResourceID{SpriteName: oldName}.URI(),
+			},
+			NewName:              newName,
+			IncludeResourceFiles: true,
+		}})
+	}
+
+	oldFile, newFile := oldName+".spx", newName+".spx"
+	if _, ok := result.proj.File(oldFile); !ok {
+		return nil, fmt.Errorf("cannot rename sprite type %q: spx source file %q not found", oldName, oldFile)
+	}
+	if _, ok := result.proj.File(newFile); ok {
+		return nil, fmt.Errorf("cannot rename sprite type %q to %q: file %q already exists", oldName, newName, newFile)
+	}
+	typeInfo, _ := result.proj.TypeInfo()
+	if typeInfo == nil {
+		return nil, nil
+	}
+	changes := make(map[DocumentURI][]TextEdit)
+	for _, ident := range typeInfo.RefIdentsFor(typeName) {
+		if xgoutil.NodeTokenFile(result.proj, ident) == nil {
+			continue
+		}
+		documentURI := s.nodeDocumentURI(result.proj, ident)
+		changes[documentURI] = append(changes[documentURI], TextEdit{
+			Range:   RangeForNode(result.proj, ident),
+			NewText: newName,
+		})
+	}
+	return toDocumentChangesWorkspaceEdit(changes, []RenameFile{{
+		Kind:   "rename",
+		OldURI: s.toDocumentURI(oldFile),
+		NewURI: s.toDocumentURI(newFile),
+	}}), nil
+}
+
 // spxRenameResourceAtRefs updates spx resource names at reference locations by
 // matching the spx resource ID.
 func (s *Server) spxRenameResourceAtRefs(result *compileResult, id SpxResourceID, newName string) map[DocumentURI][]TextEdit {
@@ -112,7 +268,7 @@ func (s *Server) spxRenameResourceAtRefs(result *compileResult, id SpxResourceID
 		return changes
 	}
 	for _, ref := range result.spxResourceRefs {
-		if ref.ID != id {
+		if ref.ID != id || ref.Kind == SpxResourceRefKindIndexLiteral {
 			continue
 		}
 
@@ -1,8 +1,10 @@
 package server
 
 import (
+	"context"
 	"testing"
 
+	"github.com/goplus/xgolsw/protocol"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -27,7 +29,7 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		range1, err := s.textDocumentPrepareRename(&PrepareRenameParams{
+		range1, err := s.textDocumentPrepareRename(context.Background(), &PrepareRenameParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 1},
@@ -40,7 +42,7 @@ onStart => {
 			End:   Position{Line: 2, Character: 9},
 		}, *range1)
 
-		range2, err := s.textDocumentPrepareRename(&PrepareRenameParams{
+		range2, err := s.textDocumentPrepareRename(context.Background(), &PrepareRenameParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 4, Character: 0},
@@ -53,16 +55,79 @@ onStart => {
 			End:   Position{Line: 4, Character: 8},
 		}, *range2)
 
-		range3, err := s.textDocumentPrepareRename(&PrepareRenameParams{
+		range3, err := s.textDocumentPrepareRename(context.Background(), &PrepareRenameParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 10},
 			},
 		})
-		require.NoError(t, err)
+		require.EqualError(t, err, `cannot rename "Sprite": it is defined in the spx package`)
 		require.Nil(t, range3)
 	})
 
+	t.Run("Builtin", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+onStart => {
+	println len("foo")
+}
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		range1, err := s.textDocumentPrepareRename(context.Background(), &PrepareRenameParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 2, Character: 10},
+			},
+		})
+		require.EqualError(t, err, `cannot rename builtin "len"`)
+		require.Nil(t, range1)
+	})
+
+	t.Run("OverloadedFunc", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+func greet__0() {}
+func greet__1(name string) {}
+onStart => {
+	greet__0
+}
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		range1, err := s.textDocumentPrepareRename(context.Background(), &PrepareRenameParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 1, Character: 6},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, range1)
+		assert.Equal(t, Range{
+			Start: Position{Line: 1, Character: 5},
+			End:   Position{Line: 1, Character: 10},
+		}, *range1)
+
+		range2, err := s.textDocumentPrepareRename(context.Background(), &PrepareRenameParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 4, Character: 2},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, range2)
+		assert.Equal(t, Range{
+			Start: Position{Line: 4, Character: 1},
+			End:   Position{Line: 4, Character: 6},
+		}, *range2)
+	})
+
 	t.Run("ThisPtr", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.spx": []byte(`
@@ -81,7 +146,7 @@ onClick => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		range1, err := s.textDocumentPrepareRename(&PrepareRenameParams{
+		range1, err := s.textDocumentPrepareRename(context.Background(), &PrepareRenameParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 2, Character: 5},
@@ -90,7 +155,7 @@ onClick => {
 		require.NoError(t, err)
 		require.Nil(t, range1)
 
-		range2, err := s.textDocumentPrepareRename(&PrepareRenameParams{
+		range2, err := s.textDocumentPrepareRename(context.Background(), &PrepareRenameParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
 				Position:     Position{Line: 2, Character: 5},
@@ -100,6 +165,117 @@ onClick => {
 		require.Nil(t, range2)
 	})
 
+	t.Run("SpxResourceString", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+onStart => {
+	play "Sound1"
+}
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json":               []byte(`{}`),
+			"assets/sounds/Sound1/index.json": []byte(`{"path":"sound1.wav"}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		range1, err := s.textDocumentPrepareRename(context.Background(), &PrepareRenameParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 2, Character: 9},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, range1)
+		assert.Equal(t, Range{
+			Start: Position{Line: 2, Character: 7},
+			End:   Position{Line: 2, Character: 13},
+		}, *range1)
+
+		range2, err := s.textDocumentPrepareRename(context.Background(), &PrepareRenameParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 4, Character: 6},
+			},
+		})
+		require.NoError(t, err)
+		require.Nil(t, range2)
+	})
+
+	t.Run("SpxResourceIndexLiteral", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx": []byte(`
+onStart => {
+	setCostume 0
+}
+`),
+			"assets/index.json":                  []byte(`{}`),
+			"assets/sprites/MySprite/index.json": []byte(`{"costumes":[{"name":"costume1"}]}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		range1, err := s.textDocumentPrepareRename(context.Background(), &PrepareRenameParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
+				Position:     Position{Line: 2, Character: 12},
+			},
+		})
+		require.EqualError(t, err, `cannot rename spx resource "spx://resources/sprites/MySprite/costumes/costume1" from an index reference`)
+		require.Nil(t, range1)
+	})
+
+	t.Run("SpxResourceNotFound", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+onStart => {
+	play "Sound2"
+}
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json":               []byte(`{}`),
+			"assets/sounds/Sound1/index.json": []byte(`{"path":"sound1.wav"}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		range1, err := s.textDocumentPrepareRename(context.Background(), &PrepareRenameParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 2, Character: 9},
+			},
+		})
+		require.EqualError(t, err, `cannot rename spx resource "spx://resources/sounds/Sound2": it does not exist`)
+		require.Nil(t, range1)
+	})
+
+	t.Run("SpriteType", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	MySprite MySprite
+)
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx":                       []byte(``),
+			"assets/index.json":                  []byte(`{}`),
+			"assets/sprites/MySprite/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		range1, err := s.textDocumentPrepareRename(context.Background(), &PrepareRenameParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 2, Character: 12},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, &Range{
+			Start: Position{Line: 2, Character: 10},
+			End:   Position{Line: 2, Character: 18},
+		}, range1)
+	})
+
 	t.Run("InvalidTextDocument", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.spx": []byte(`
@@ -118,7 +294,7 @@ onClick => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		range1, err := s.textDocumentPrepareRename(&PrepareRenameParams{
+		range1, err := s.textDocumentPrepareRename(context.Background(), &PrepareRenameParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "bucket:///main.spx"},
 				Position:     Position{Line: 2, Character: 5},
@@ -151,7 +327,7 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		workspaceEdit, err := s.textDocumentRename(&RenameParams{
+		workspaceEdit, err := s.textDocumentRename(context.Background(), &RenameParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 			Position:     Position{Line: 4, Character: 6},
 			NewName:      "Bar",
@@ -181,6 +357,47 @@ onStart => {
 		})
 	})
 
+	t.Run("OverloadedFunc", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+func greet__0() {}
+func greet__1(name string) {}
+onStart => {
+	greet__0
+}
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		workspaceEdit, err := s.textDocumentRename(context.Background(), &RenameParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Position:     Position{Line: 1, Character: 6},
+			NewName:      "hello",
+		})
+		require.NoError(t, err)
+		require.NotNil(t, workspaceEdit)
+		assert.Equal(t, map[DocumentURI][]TextEdit{
+			"file:///main.spx": {
+				{
+					Range: Range{
+						Start: Position{Line: 1, Character: 5},
+						End:   Position{Line: 1, Character: 10},
+					},
+					NewText: "hello",
+				},
+				{
+					Range: Range{
+						Start: Position{Line: 4, Character: 1},
+						End:   Position{Line: 4, Character: 6},
+					},
+					NewText: "hello",
+				},
+			},
+		}, workspaceEdit.Changes)
+	})
+
 	t.Run("RenameReference", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.spx": []byte(`
@@ -202,7 +419,7 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		workspaceEdit, err := s.textDocumentRename(&RenameParams{
+		workspaceEdit, err := s.textDocumentRename(context.Background(), &RenameParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
 			Position:     Position{Line: 1, Character: 9},
 			NewName:      "Bar",
@@ -251,7 +468,7 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		workspaceEdit, err := s.textDocumentRename(&RenameParams{
+		workspaceEdit, err := s.textDocumentRename(context.Background(), &RenameParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 			Position:     Position{Line: 2, Character: 4},
 			NewName:      "NewSprite",
@@ -288,6 +505,152 @@ onStart => {
 		})
 	})
 
+	t.Run("SpxResourceWithResourceFiles", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+onStart => {
+	play "Sound1"
+}
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json":               []byte(`{}`),
+			"assets/sounds/Sound1/index.json": []byte(`{"name":"Sound1","path":"sound1.wav"}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		s.clientCapabilities.Workspace.WorkspaceEdit = &protocol.WorkspaceEditClientCapabilities{
+			DocumentChanges:    true,
+			ResourceOperations: []protocol.ResourceOperationKind{protocol.Create, protocol.Rename},
+		}
+
+		workspaceEdit, err := s.textDocumentRename(context.Background(), &RenameParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Position:     Position{Line: 2, Character: 9},
+			NewName:      "Sound2",
+		})
+		require.NoError(t, err)
+		require.NotNil(t, workspaceEdit)
+		assert.Nil(t, workspaceEdit.Changes)
+		require.Len(t, workspaceEdit.DocumentChanges, 3)
+
+		indexJSONEdit := workspaceEdit.DocumentChanges[0].TextDocumentEdit
+		require.NotNil(t, indexJSONEdit)
+		assert.Equal(t, DocumentURI("file:///assets/sounds/Sound1/index.json"), indexJSONEdit.TextDocument.URI)
+		assert.Equal(t, []Or_TextDocumentEdit_edits_Elem{{Value: TextEdit{
+			Range: Range{
+				Start: Position{Line: 0, Character: 8},
+				End:   Position{Line: 0, Character: 16},
+			},
+			NewText: `"Sound2"`,
+		}}}, indexJSONEdit.Edits)
+
+		mainSpxEdit := workspaceEdit.DocumentChanges[1].TextDocumentEdit
+		require.NotNil(t, mainSpxEdit)
+		assert.Equal(t, DocumentURI("file:///main.spx"), mainSpxEdit.TextDocument.URI)
+		assert.Equal(t, []Or_TextDocumentEdit_edits_Elem{{Value: TextEdit{
+			Range: Range{
+				Start: Position{Line: 2, Character: 7},
+				End:   Position{Line: 2, Character: 13},
+			},
+			NewText: "Sound2",
+		}}}, mainSpxEdit.Edits)
+
+		assert.Equal(t, &RenameFile{
+			Kind:   "rename",
+			OldURI: "file:///assets/sounds/Sound1",
+			NewURI: "file:///assets/sounds/Sound2",
+		}, workspaceEdit.DocumentChanges[2].RenameFile)
+	})
+
+	t.Run("SpxResourceAutoBinding", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	Sound1 Sound
+)
+play Sound1
+run "assets", {Title: "My Game"}
+`),
+			"assets/index.json":               []byte(`{}`),
+			"assets/sounds/Sound1/index.json": []byte(`{"name":"Sound1","path":"sound1.wav"}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		s.clientCapabilities.Workspace.WorkspaceEdit = &protocol.WorkspaceEditClientCapabilities{
+			DocumentChanges:    true,
+			ResourceOperations: []protocol.ResourceOperationKind{protocol.Create, protocol.Rename},
+		}
+
+		workspaceEdit, err := s.textDocumentRename(context.Background(), &RenameParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Position:     Position{Line: 4, Character: 6},
+			NewName:      "Sound2",
+		})
+		require.NoError(t, err)
+		require.NotNil(t, workspaceEdit)
+		assert.Nil(t, workspaceEdit.Changes)
+		require.Len(t, workspaceEdit.DocumentChanges, 3)
+
+		indexJSONEdit := workspaceEdit.DocumentChanges[0].TextDocumentEdit
+		require.NotNil(t, indexJSONEdit)
+		assert.Equal(t, DocumentURI("file:///assets/sounds/Sound1/index.json"), indexJSONEdit.TextDocument.URI)
+		assert.Equal(t, []Or_TextDocumentEdit_edits_Elem{{Value: TextEdit{
+			Range: Range{
+				Start: Position{Line: 0, Character: 8},
+				End:   Position{Line: 0, Character: 16},
+			},
+			NewText: `"Sound2"`,
+		}}}, indexJSONEdit.Edits)
+
+		mainSpxEdit := workspaceEdit.DocumentChanges[1].TextDocumentEdit
+		require.NotNil(t, mainSpxEdit)
+		assert.Equal(t, DocumentURI("file:///main.spx"), mainSpxEdit.TextDocument.URI)
+		assert.ElementsMatch(t, []Or_TextDocumentEdit_edits_Elem{
+			{Value: TextEdit{
+				Range: Range{
+					Start: Position{Line: 2, Character: 1},
+					End:   Position{Line: 2, Character: 7},
+				},
+				NewText: "Sound2",
+			}},
+			{Value: TextEdit{
+				Range: Range{
+					Start: Position{Line: 4, Character: 5},
+					End:   Position{Line: 4, Character: 11},
+				},
+				NewText: "Sound2",
+			}},
+		}, mainSpxEdit.Edits)
+
+		assert.Equal(t, &RenameFile{
+			Kind:   "rename",
+			OldURI: "file:///assets/sounds/Sound1",
+			NewURI: "file:///assets/sounds/Sound2",
+		}, workspaceEdit.DocumentChanges[2].RenameFile)
+	})
+
+	t.Run("SpxResourceAutoBindingInvalidIdentifier", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	MySprite Sprite
+)
+MySprite.turn Left
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx":                       []byte(``),
+			"assets/index.json":                  []byte(`{}`),
+			"assets/sprites/MySprite/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		workspaceEdit, err := s.textDocumentRename(context.Background(), &RenameParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Position:     Position{Line: 2, Character: 4},
+			NewName:      "My Sprite",
+		})
+		require.EqualError(t, err, `cannot rename auto-binding "MySprite" to "My Sprite": it must be a valid identifier to stay bound to spx resource "spx://resources/sprites/MySprite"`)
+		assert.Nil(t, workspaceEdit)
+	})
+
 	t.Run("SpxResourceInOtherSpriteFiles", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.spx": []byte(`
@@ -326,7 +689,7 @@ onCloned => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		workspaceEdit, err := s.textDocumentRename(&RenameParams{
+		workspaceEdit, err := s.textDocumentRename(context.Background(), &RenameParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///Bullet.spx"},
 			Position:     Position{Line: 2, Character: 10},
 			NewName:      "Jet",
@@ -388,7 +751,7 @@ onClick => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		mainSpxWorkspaceEdit, err := s.textDocumentRename(&RenameParams{
+		mainSpxWorkspaceEdit, err := s.textDocumentRename(context.Background(), &RenameParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 			Position:     Position{Line: 2, Character: 5},
 			NewName:      "that",
@@ -396,7 +759,7 @@ onClick => {
 		require.NoError(t, err)
 		require.Nil(t, mainSpxWorkspaceEdit)
 
-		mySpriteSpxWorkspaceEdit, err := s.textDocumentRename(&RenameParams{
+		mySpriteSpxWorkspaceEdit, err := s.textDocumentRename(context.Background(), &RenameParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
 			Position:     Position{Line: 2, Character: 5},
 			NewName:      "that",
@@ -404,6 +767,177 @@ onClick => {
 		require.NoError(t, err)
 		require.Nil(t, mySpriteSpxWorkspaceEdit)
 	})
+	t.Run("SpriteType", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	MySprite MySprite
+)
+var s *MySprite
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx":                       []byte(``),
+			"assets/index.json":                  []byte(`{"zorder":["MySprite"]}`),
+			"assets/sprites/MySprite/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		s.clientCapabilities.Workspace.WorkspaceEdit = &protocol.WorkspaceEditClientCapabilities{
+			DocumentChanges:    true,
+			ResourceOperations: []protocol.ResourceOperationKind{protocol.Rename},
+		}
+
+		workspaceEdit, err := s.textDocumentRename(context.Background(), &RenameParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Position:     Position{Line: 4, Character: 8},
+			NewName:      "Hero",
+		})
+		require.NoError(t, err)
+		require.NotNil(t, workspaceEdit)
+		assert.Nil(t, workspaceEdit.Changes)
+		require.Len(t, workspaceEdit.DocumentChanges, 4)
+
+		indexJSONEdit := workspaceEdit.DocumentChanges[0].TextDocumentEdit
+		require.NotNil(t, indexJSONEdit)
+		assert.Equal(t, DocumentURI("file:///assets/index.json"), indexJSONEdit.TextDocument.URI)
+		assert.Equal(t, []Or_TextDocumentEdit_edits_Elem{{Value: TextEdit{
+			Range: Range{
+				Start: Position{Line: 0, Character: 11},
+				End:   Position{Line: 0, Character: 21},
+			},
+			NewText: `"Hero"`,
+		}}}, indexJSONEdit.Edits)
+
+		mainSpxEdit := workspaceEdit.DocumentChanges[1].TextDocumentEdit
+		require.NotNil(t, mainSpxEdit)
+		assert.Equal(t, DocumentURI("file:///main.spx"), mainSpxEdit.TextDocument.URI)
+		assert.ElementsMatch(t, []Or_TextDocumentEdit_edits_Elem{
+			{Value: TextEdit{
+				Range: Range{
+					Start: Position{Line: 2, Character: 1},
+					End:   Position{Line: 2, Character: 9},
+				},
+				NewText: "Hero",
+			}},
+			{Value: TextEdit{
+				Range: Range{
+					Start: Position{Line: 2, Character: 10},
+					End:   Position{Line: 2, Character: 18},
+				},
+				NewText: "Hero",
+			}},
+			{Value: TextEdit{
+				Range: Range{
+					Start: Position{Line: 4, Character: 7},
+					End:   Position{Line: 4, Character: 15},
+				},
+				NewText: "Hero",
+			}},
+		}, mainSpxEdit.Edits)
+
+		assert.Equal(t, &RenameFile{
+			Kind:   "rename",
+			OldURI: "file:///assets/sprites/MySprite",
+			NewURI: "file:///assets/sprites/Hero",
+		}, workspaceEdit.DocumentChanges[2].RenameFile)
+		assert.Equal(t, &RenameFile{
+			Kind:   "rename",
+			OldURI: "file:///MySprite.spx",
+			NewURI: "file:///Hero.spx",
+		}, workspaceEdit.DocumentChanges[3].RenameFile)
+	})
+
+	t.Run("SpriteTypeWithoutResource", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var s *MySprite
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx":      []byte(``),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		s.clientCapabilities.Workspace.WorkspaceEdit = &protocol.WorkspaceEditClientCapabilities{
+			DocumentChanges:    true,
+			ResourceOperations: []protocol.ResourceOperationKind{protocol.Rename},
+		}
+
+		workspaceEdit, err := s.textDocumentRename(context.Background(), &RenameParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Position:     Position{Line: 1, Character: 9},
+			NewName:      "Hero",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, &WorkspaceEdit{
+			DocumentChanges: []DocumentChange{
+				{
+					TextDocumentEdit: &TextDocumentEdit{
+						TextDocument: OptionalVersionedTextDocumentIdentifier{
+							TextDocumentIdentifier: TextDocumentIdentifier{URI: "file:///main.spx"},
+						},
+						Edits: []Or_TextDocumentEdit_edits_Elem{{Value: TextEdit{
+							Range: Range{
+								Start: Position{Line: 1, Character: 7},
+								End:   Position{Line: 1, Character: 15},
+							},
+							NewText: "Hero",
+						}}},
+					},
+				},
+				{
+					RenameFile: &RenameFile{
+						Kind:   "rename",
+						OldURI: "file:///MySprite.spx",
+						NewURI: "file:///Hero.spx",
+					},
+				},
+			},
+		}, workspaceEdit)
+	})
+
+	t.Run("SpriteTypeConflict", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var s *MySprite
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx":      []byte(``),
+			"Hero.spx":          []byte(``),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		s.clientCapabilities.Workspace.WorkspaceEdit = &protocol.WorkspaceEditClientCapabilities{
+			DocumentChanges:    true,
+			ResourceOperations: []protocol.ResourceOperationKind{protocol.Rename},
+		}
+
+		workspaceEdit, err := s.textDocumentRename(context.Background(), &RenameParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Position:     Position{Line: 1, Character: 9},
+			NewName:      "Hero",
+		})
+		require.EqualError(t, err, `cannot rename sprite type "MySprite" to "Hero": "Hero" is already declared`)
+		assert.Nil(t, workspaceEdit)
+	})
+
+	t.Run("SpriteTypeResourceOperationsUnsupported", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var s *MySprite
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx":      []byte(``),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		workspaceEdit, err := s.textDocumentRename(context.Background(), &RenameParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Position:     Position{Line: 1, Character: 9},
+			NewName:      "Hero",
+		})
+		require.EqualError(t, err, `cannot rename sprite type "MySprite": renaming its spx source file is not supported by the client`)
+		assert.Nil(t, workspaceEdit)
+	})
 }
 
 func TestServerSpxRenameBackdropResource(t *testing.T) {
@@ -907,6 +1441,45 @@ onStart => {
 		})
 	})
 
+	t.Run("IndexReference", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx": []byte(`
+onStart => {
+	setCostume 0
+	setCostume "costume1"
+}
+`),
+			"assets/index.json":                  []byte(`{}`),
+			"assets/sprites/MySprite/index.json": []byte(`{"costumes":[{"name":"costume1"}]}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		result, err := s.compile()
+		require.NoError(t, err)
+		require.False(t, result.hasErrorSeverityDiagnostic)
+		var indexRefIDs []SpxResourceID
+		for _, ref := range result.spxResourceRefs {
+			if ref.Kind == SpxResourceRefKindIndexLiteral {
+				indexRefIDs = append(indexRefIDs, ref.ID)
+			}
+		}
+		assert.Equal(t, []SpxResourceID{SpxSpriteCostumeResourceID{SpriteName: "MySprite", CostumeName: "costume1"}}, indexRefIDs)
+
+		changes, err := s.spxRenameSpriteCostumeResource(result, SpxSpriteCostumeResourceID{SpriteName: "MySprite", CostumeName: "costume1"}, "costume2")
+		require.NoError(t, err)
+		assert.Equal(t, map[DocumentURI][]TextEdit{
+			"file:///MySprite.spx": {{
+				Range: Range{
+					Start: Position{Line: 3, Character: 13},
+					End:   Position{Line: 3, Character: 21},
+				},
+				NewText: "costume2",
+			}},
+		}, changes)
+	})
+
 	t.Run("AlreadyExists", func(t *testing.T) {
 		m := map[string][]byte{
 			"main.spx": []byte(`
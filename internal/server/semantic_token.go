@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"go/types"
 	"slices"
 	"sort"
@@ -8,6 +9,7 @@ import (
 
 	xgoast "github.com/goplus/xgo/ast"
 	xgotoken "github.com/goplus/xgo/token"
+	"github.com/goplus/xgolsw/xgo"
 	"github.com/goplus/xgolsw/xgo/xgoutil"
 )
 
@@ -71,20 +73,46 @@ type semanticTokenInfo struct {
 }
 
 // See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#textDocument_semanticTokens
-func (s *Server) textDocumentSemanticTokensFull(params *SemanticTokensParams) (*SemanticTokens, error) {
-	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(params.TextDocument.URI)
+func (s *Server) textDocumentSemanticTokensFull(ctx context.Context, params *SemanticTokensParams) (*SemanticTokens, error) {
+	result, spxFile, astFile, err := s.compileAndGetASTFileForDocumentURI(ctx, params.TextDocument.URI)
 	if err != nil {
 		return nil, err
 	}
 	if astFile == nil {
 		return nil, nil
 	}
+	// Without type information, such as when the project is not type
+	// checked, identifiers other than keywords are not highlighted.
 	typeInfo, _ := result.proj.TypeInfo()
+
+	// Tokens derived from type information are stale once any file changes,
+	// while those derived from the document alone are kept until it changes.
+	generation := result.proj.Generation()
 	if typeInfo == nil {
-		return nil, nil
+		generation, _ = result.proj.FileGeneration(spxFile)
 	}
+	if v, ok := s.semanticTokens.Load(spxFile); ok {
+		if c := v.(*semanticTokensCache); c.generation == generation {
+			return c.tokens, nil
+		}
+	}
+	tokens := computeSemanticTokens(result.proj, astFile, typeInfo)
+	s.semanticTokens.Store(spxFile, &semanticTokensCache{generation: generation, tokens: tokens})
+	return tokens, nil
+}
+
+// semanticTokensCache is the last semantic tokens computed for a document.
+type semanticTokensCache struct {
+	generation uint64 // Generation of the project or document they were computed at.
+	tokens     *SemanticTokens
+}
+
+// computeSemanticTokens computes the semantic tokens of astFile in proj. The
+// typeInfo can be nil, in which case identifiers other than keywords are not
+// highlighted.
+func computeSemanticTokens(proj *xgo.Project, astFile *xgoast.File, typeInfo *xgo.TypeInfo) *SemanticTokens {
+	fset := proj.Fset
 
-	var fset = result.proj.Fset
 	var tokenInfos []semanticTokenInfo
 	addToken := func(startPos, endPos xgotoken.Pos, tokenType SemanticTokenTypes, tokenModifiers []SemanticTokenModifiers) {
 		if !startPos.IsValid() || !endPos.IsValid() {
@@ -122,7 +150,10 @@ func (s *Server) textDocumentSemanticTokensFull(params *SemanticTokensParams) (*
 				addToken(node.Semicolon, node.Semicolon+1, OperatorType, nil)
 			}
 		case *xgoast.Ident:
-			obj := typeInfo.ObjectOf(node)
+			var obj types.Object
+			if typeInfo != nil {
+				obj = typeInfo.ObjectOf(node)
+			}
 			if obj == nil {
 				if xgotoken.Lookup(node.Name).IsKeyword() {
 					addToken(node.Pos(), node.End(), KeywordType, nil)
@@ -153,7 +184,7 @@ func (s *Server) textDocumentSemanticTokensFull(params *SemanticTokensParams) (*
 				}
 			case *types.Var:
 				if obj.IsField() {
-					if xgoutil.IsInMainPkg(obj) && xgoutil.IsDefinedInClassFieldsDecl(result.proj, obj) {
+					if xgoutil.IsInMainPkg(obj) && xgoutil.IsDefinedInClassFieldsDecl(proj, obj) {
 						tokenType = VariableType
 					} else {
 						tokenType = PropertyType
@@ -500,5 +531,5 @@ func (s *Server) textDocumentSemanticTokensFull(params *SemanticTokensParams) (*
 	}
 	return &SemanticTokens{
 		Data: tokensData,
-	}, nil
+	}
 }
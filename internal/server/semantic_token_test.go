@@ -1,8 +1,10 @@
 package server
 
 import (
+	"context"
 	"testing"
 
+	"github.com/goplus/xgolsw/xgo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -27,7 +29,7 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		mainSpxTokens, err := s.textDocumentSemanticTokensFull(&SemanticTokensParams{
+		mainSpxTokens, err := s.textDocumentSemanticTokensFull(context.Background(), &SemanticTokensParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 		})
 		require.NoError(t, err)
@@ -54,7 +56,7 @@ onStart => {
 			0, 1, 1, 13, 0, // }
 		}, mainSpxTokens.Data)
 
-		mySpriteTokens, err := s.textDocumentSemanticTokensFull(&SemanticTokensParams{
+		mySpriteTokens, err := s.textDocumentSemanticTokensFull(context.Background(), &SemanticTokensParams{
 			TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
 		})
 		require.NoError(t, err)
@@ -72,4 +74,71 @@ onStart => {
 			0, 1, 1, 13, 0, // }
 		}, mySpriteTokens.Data)
 	})
+
+	t.Run("WithoutTypes", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var x int
+echo x
+`),
+		}
+		s := New(newMapFSWithFeatures(m, xgo.FeatASTCache), nil, fileMapGetter(m), &MockScheduler{})
+
+		tokens, err := s.textDocumentSemanticTokensFull(context.Background(), &SemanticTokensParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, tokens)
+		assert.Equal(t, []uint32{
+			1, 0, 3, 9, 0, // var
+			0, 6, 3, 1, 0, // int
+			1, 0, 1, 13, 0, // {
+			0, 6, 1, 13, 0, // }
+		}, tokens.Data)
+	})
+	t.Run("CachedUntilDocumentChanges", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx":     []byte("var x int\necho x\n"),
+			"MySprite.spx": []byte("onStart => {}\n"),
+		}
+		s := New(newMapFSWithFeatures(m, xgo.FeatASTCache), nil, fileMapGetter(m), &MockScheduler{})
+		params := &SemanticTokensParams{TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"}}
+
+		tokens, err := s.textDocumentSemanticTokensFull(context.Background(), params)
+		require.NoError(t, err)
+		require.NotNil(t, tokens)
+
+		m["MySprite.spx"] = []byte("onClick => {}\n")
+		cachedTokens, err := s.textDocumentSemanticTokensFull(context.Background(), params)
+		require.NoError(t, err)
+		assert.Same(t, tokens, cachedTokens)
+
+		m["main.spx"] = []byte("var y int\necho y\n")
+		newTokens, err := s.textDocumentSemanticTokensFull(context.Background(), params)
+		require.NoError(t, err)
+		assert.NotSame(t, tokens, newTokens)
+	})
+
+	t.Run("CachedUntilProjectChanges", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx":     []byte("var x int\necho x\n"),
+			"MySprite.spx": []byte("onStart => {}\n"),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		params := &SemanticTokensParams{TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"}}
+
+		tokens, err := s.textDocumentSemanticTokensFull(context.Background(), params)
+		require.NoError(t, err)
+		require.NotNil(t, tokens)
+
+		cachedTokens, err := s.textDocumentSemanticTokensFull(context.Background(), params)
+		require.NoError(t, err)
+		assert.Same(t, tokens, cachedTokens)
+
+		m["MySprite.spx"] = []byte("onClick => {}\n")
+		newTokens, err := s.textDocumentSemanticTokensFull(context.Background(), params)
+		require.NoError(t, err)
+		assert.NotSame(t, tokens, newTokens)
+		assert.Equal(t, tokens.Data, newTokens.Data)
+	})
 }
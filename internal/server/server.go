@@ -2,12 +2,15 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"maps"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/goplus/mod/modload"
@@ -29,6 +32,9 @@ type MessageReplier interface {
 	// The message can be one of:
 	//   - [jsonrpc2.Response]: sent in response to a call.
 	//   - [jsonrpc2.Notification]: sent for server-initiated notifications.
+	//   - [jsonrpc2.Call]: sent for server-initiated calls. The client is
+	//     expected to send back a [jsonrpc2.Response] via
+	//     [Server.HandleMessage].
 	ReplyMessage(m jsonrpc2.Message) error
 }
 
@@ -51,6 +57,27 @@ type Server struct {
 	fileMapGetter    FileMapGetter // TODO(wyvern): Remove this field.
 	cancelCauseFuncs sync.Map      // Map of request IDs to cancel functions (with cause).
 	scheduler        Scheduler
+	tasks            taskQueue
+
+	clientCapabilities  ClientCapabilities
+	settings            atomic.Pointer[Settings]
+	logger              atomic.Pointer[slog.Logger]
+	trace               atomic.Pointer[TraceValue]
+	serverCalls         sync.Map // Map of server-initiated call IDs to response channels.
+	batchCalls          sync.Map // Map of IDs of calls in batches to their *pendingBatch.
+	lastServerCallID    atomic.Int64
+	lastProgressTokenID atomic.Int64
+
+	compileMu          sync.Mutex
+	lastCompile        *compileCache                       // Guarded by compileMu.
+	lastSpxResourceSet atomic.Pointer[spxResourceSetCache] // Only stored with compileMu held.
+	semanticTokens     sync.Map                            // Map of spx files to *semanticTokensCache.
+
+	diagnosticsDebouncer diagnosticsDebouncer
+	metrics              metricsRecorder
+
+	closed    chan struct{}
+	closeOnce sync.Once
 }
 
 func (s *Server) getProj() *xgo.Project {
@@ -59,11 +86,19 @@ func (s *Server) getProj() *xgo.Project {
 
 func (s *Server) getProjWithFile() *xgo.Project {
 	proj := s.workspaceRootFS
-	proj.UpdateFiles(s.fileMapGetter())
+	if s.fileMapGetter != nil {
+		proj.UpdateFiles(s.fileMapGetter())
+	}
 	return proj
 }
 
 // New creates a new Server instance.
+//
+// The fileMapGetter is optional. If it is nil, the server relies solely on
+// document synchronization and file operation notifications to keep the
+// workspace files up to date.
+//
+// If mapFS has no module cache, the one of [internal.Importer] is used.
 func New(mapFS *vfs.MapFS, replier MessageReplier, fileMapGetter FileMapGetter, scheduler Scheduler) *Server {
 	mod := xgomod.New(modload.Default)
 	if err := mod.ImportClasses(); err != nil {
@@ -72,15 +107,67 @@ func New(mapFS *vfs.MapFS, replier MessageReplier, fileMapGetter FileMapGetter,
 	mapFS.PkgPath = "main"
 	mapFS.Mod = mod
 	mapFS.Importer = internal.Importer
-	return &Server{
-		// TODO(spxls): Initialize request should set workspaceRootURI value
+	if mapFS.ModCache == nil {
+		mapFS.ModCache = internal.Importer.ModCache()
+	}
+	s := &Server{
+		// The default is overridden by the root URI of the initialize request.
 		workspaceRootURI: "file:///",
 		workspaceRootFS:  mapFS,
 		replier:          replier,
 		analyzers:        initAnalyzers(true),
 		fileMapGetter:    fileMapGetter,
 		scheduler:        scheduler,
+		closed:           make(chan struct{}),
 	}
+	s.settings.Store(defaultSettings())
+	return s
+}
+
+// hasFeatures reports whether the project served by s was created with all the
+// given features, such as [xgo.FeatTypeInfoCache]. Without type information,
+// only the language features based on parsing, such as semantic tokens,
+// formatting and syntax error diagnostics, are available, which suits
+// lightweight deployments that do not need the rest.
+func (s *Server) hasFeatures(feats uint) bool {
+	return s.workspaceRootFS.Features()&feats == feats
+}
+
+// methodFeatures are the project features required by the methods of calls.
+// Calls of the methods are not supported if the project lacks any of them.
+var methodFeatures = map[string]uint{
+	"textDocument/hover":             xgo.FeatTypeInfoCache,
+	"textDocument/completion":        xgo.FeatTypeInfoCache,
+	"textDocument/signatureHelp":     xgo.FeatTypeInfoCache,
+	"textDocument/declaration":       xgo.FeatTypeInfoCache,
+	"textDocument/definition":        xgo.FeatTypeInfoCache,
+	"textDocument/typeDefinition":    xgo.FeatTypeInfoCache,
+	"textDocument/implementation":    xgo.FeatTypeInfoCache,
+	"textDocument/references":        xgo.FeatTypeInfoCache,
+	"textDocument/documentHighlight": xgo.FeatTypeInfoCache,
+	"textDocument/documentLink":      xgo.FeatTypeInfoCache,
+	"textDocument/codeAction":        xgo.FeatTypeInfoCache,
+	"textDocument/prepareRename":     xgo.FeatTypeInfoCache,
+	"textDocument/rename":            xgo.FeatTypeInfoCache,
+	"textDocument/inlayHint":         xgo.FeatTypeInfoCache,
+}
+
+// errServerClosed is the error for requests and calls aborted by [Server.Close].
+var errServerClosed = errors.New("server closed")
+
+// Close cancels all in-flight requests and server-initiated calls. It is
+// typically called when the connection to the client is torn down. The server
+// must not be used after Close is called.
+func (s *Server) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		s.diagnosticsDebouncer.stop()
+		s.cancelCauseFuncs.Range(func(_, cancelCauseFunc any) bool {
+			cancelCauseFunc.(context.CancelCauseFunc)(errServerClosed)
+			return true
+		})
+	})
+	return nil
 }
 
 // InitAnalyzers initializes the analyzers for the server.
@@ -89,6 +176,9 @@ func initAnalyzers(staticcheck bool) []*analysis.Analyzer {
 	if staticcheck {
 		analyzers = slices.AppendSeq(analyzers, maps.Values(analysis.StaticcheckAnalyzers))
 	}
+	slices.SortFunc(analyzers, func(a, b *analysis.Analyzer) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
 	return analyzers
 }
 
@@ -99,21 +189,61 @@ func (s *Server) HandleMessage(m jsonrpc2.Message) error {
 		return s.handleCall(m)
 	case *jsonrpc2.Notification:
 		return s.handleNotification(m)
+	case *jsonrpc2.Response:
+		return s.handleResponse(m)
+	case jsonrpc2.Batch:
+		return s.handleBatch(m)
 	}
 	return fmt.Errorf("unsupported message type: %T", m)
 }
 
+// pendingBatch collects the responses to the calls in a [jsonrpc2.Batch].
+type pendingBatch struct {
+	mu        sync.Mutex
+	remaining int
+	responses jsonrpc2.Batch
+}
+
+// handleBatch handles a batch message by handling each of its messages in
+// order. The responses to its calls are replied together as a single batch
+// once all of them are ready. See [Server.replyResponse].
+func (s *Server) handleBatch(b jsonrpc2.Batch) error {
+	pb := &pendingBatch{}
+	for _, m := range b {
+		if c, ok := m.(*jsonrpc2.Call); ok {
+			// Calls with duplicate IDs are invalid. Only the first response
+			// to them is batched, so the batch still completes.
+			if _, loaded := s.batchCalls.LoadOrStore(c.ID(), pb); !loaded {
+				pb.remaining++
+			}
+		}
+	}
+
+	var errs []error
+	for _, m := range b {
+		if err := s.HandleMessage(m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // handleCall handles a call message.
 func (s *Server) handleCall(c *jsonrpc2.Call) error {
+	if feats, ok := methodFeatures[c.Method()]; ok && !s.hasFeatures(feats) {
+		return s.replyMethodNotFound(c.ID(), c.Method())
+	}
 	switch c.Method() {
 	case "initialize":
 		var params InitializeParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		return errors.New("TODO")
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.initialize(&params)
+		})
 	case "shutdown":
-		s.runForCall(c, func() (any, error) {
+		s.runForCall(c, func(ctx context.Context) (any, error) {
 			return nil, nil // Protocol conformance only.
 		})
 	case "textDocument/hover":
@@ -121,47 +251,47 @@ func (s *Server) handleCall(c *jsonrpc2.Call) error {
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
-			return s.textDocumentHover(&params)
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentHover(ctx, &params)
 		})
 	case "textDocument/completion":
 		var params CompletionParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
-			return s.textDocumentCompletion(&params)
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentCompletion(ctx, &params)
 		})
 	case "textDocument/signatureHelp":
 		var params SignatureHelpParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
-			return s.textDocumentSignatureHelp(&params)
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentSignatureHelp(ctx, &params)
 		})
 	case "textDocument/declaration":
 		var params DeclarationParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
-			return s.textDocumentDeclaration(&params)
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentDeclaration(ctx, &params)
 		})
 	case "textDocument/definition":
 		var params DefinitionParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
-			return s.textDocumentDefinition(&params)
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentDefinition(ctx, &params)
 		})
 	case "textDocument/typeDefinition":
 		var params TypeDefinitionParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
+		s.runForCall(c, func(ctx context.Context) (any, error) {
 			return s.textDocumentTypeDefinition(&params)
 		})
 	case "textDocument/implementation":
@@ -169,96 +299,120 @@ func (s *Server) handleCall(c *jsonrpc2.Call) error {
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
-			return s.textDocumentImplementation(&params)
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentImplementation(ctx, &params)
 		})
 	case "textDocument/references":
 		var params ReferenceParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
-			return s.textDocumentReferences(&params)
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentReferences(ctx, &params)
 		})
 	case "textDocument/documentHighlight":
 		var params DocumentHighlightParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
-			return s.textDocumentDocumentHighlight(&params)
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentDocumentHighlight(ctx, &params)
 		})
 	case "textDocument/documentLink":
 		var params DocumentLinkParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
-			return s.textDocumentDocumentLink(&params)
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentDocumentLink(ctx, &params)
 		})
 	case "textDocument/diagnostic":
 		var params DocumentDiagnosticParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
-			return s.textDocumentDiagnostic(&params)
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentDiagnostic(ctx, &params)
 		})
 	case "workspace/diagnostic":
 		var params WorkspaceDiagnosticParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
-			return s.workspaceDiagnostic(&params)
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.workspaceDiagnostic(ctx, &params)
 		})
 	case "textDocument/formatting":
 		var params DocumentFormattingParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
+		s.runForCall(c, func(ctx context.Context) (any, error) {
 			return s.textDocumentFormatting(&params)
 		})
+	case "textDocument/onTypeFormatting":
+		var params DocumentOnTypeFormattingParams
+		if err := UnmarshalJSON(c.Params(), &params); err != nil {
+			return s.replyParseError(c.ID(), err)
+		}
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentOnTypeFormatting(&params)
+		})
+	case "textDocument/willSaveWaitUntil":
+		var params WillSaveTextDocumentParams
+		if err := UnmarshalJSON(c.Params(), &params); err != nil {
+			return s.replyParseError(c.ID(), err)
+		}
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentWillSaveWaitUntil(ctx, &params)
+		})
+	case "textDocument/codeAction":
+		var params CodeActionParams
+		if err := UnmarshalJSON(c.Params(), &params); err != nil {
+			return s.replyParseError(c.ID(), err)
+		}
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentCodeAction(ctx, &params)
+		})
 	case "textDocument/prepareRename":
 		var params PrepareRenameParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
-			return s.textDocumentPrepareRename(&params)
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentPrepareRename(ctx, &params)
 		})
 	case "textDocument/rename":
 		var params RenameParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
-			return s.textDocumentRename(&params)
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentRename(ctx, &params)
 		})
 	case "textDocument/semanticTokens/full":
 		var params SemanticTokensParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
-			return s.textDocumentSemanticTokensFull(&params)
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentSemanticTokensFull(ctx, &params)
 		})
 	case "textDocument/inlayHint":
 		var params InlayHintParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
-			return s.textDocumentInlayHint(&params)
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.textDocumentInlayHint(ctx, &params)
 		})
 	case "workspace/executeCommand":
 		var params ExecuteCommandParams
 		if err := UnmarshalJSON(c.Params(), &params); err != nil {
 			return s.replyParseError(c.ID(), err)
 		}
-		s.runForCall(c, func() (any, error) {
-			return s.workspaceExecuteCommand(&params)
+		s.runForCall(c, func(ctx context.Context) (any, error) {
+			return s.workspaceExecuteCommand(ctx, &params)
 		})
 	default:
 		return s.replyMethodNotFound(c.ID(), c.Method())
@@ -275,7 +429,7 @@ func (s *Server) handleNotification(n *jsonrpc2.Notification) error {
 			return fmt.Errorf("failed to parse initialized params: %w", err)
 		}
 		s.runForNotification(n, func() error {
-			return errors.New("TODO")
+			return s.initialized(&params)
 		})
 	case "exit":
 		// Protocol conformance only.
@@ -287,6 +441,14 @@ func (s *Server) handleNotification(n *jsonrpc2.Notification) error {
 		s.runForNotification(n, func() error {
 			return s.cancelRequest(&params)
 		})
+	case "$/setTrace":
+		var params SetTraceParams
+		if err := UnmarshalJSON(n.Params(), &params); err != nil {
+			return fmt.Errorf("failed to parse setTrace params: %w", err)
+		}
+		s.runForNotification(n, func() error {
+			return s.setTrace(&params)
+		})
 	case "textDocument/didOpen":
 		var params DidOpenTextDocumentParams
 		if err := UnmarshalJSON(n.Params(), &params); err != nil {
@@ -319,6 +481,38 @@ func (s *Server) handleNotification(n *jsonrpc2.Notification) error {
 		s.runForNotification(n, func() error {
 			return s.didClose(&params)
 		})
+	case "workspace/didCreateFiles":
+		var params CreateFilesParams
+		if err := UnmarshalJSON(n.Params(), &params); err != nil {
+			return fmt.Errorf("failed to parse didCreateFiles params: %w", err)
+		}
+		s.runForNotification(n, func() error {
+			return s.didCreateFiles(&params)
+		})
+	case "workspace/didDeleteFiles":
+		var params DeleteFilesParams
+		if err := UnmarshalJSON(n.Params(), &params); err != nil {
+			return fmt.Errorf("failed to parse didDeleteFiles params: %w", err)
+		}
+		s.runForNotification(n, func() error {
+			return s.didDeleteFiles(&params)
+		})
+	case "workspace/didChangeConfiguration":
+		var params DidChangeConfigurationParams
+		if err := UnmarshalJSON(n.Params(), &params); err != nil {
+			return fmt.Errorf("failed to parse didChangeConfiguration params: %w", err)
+		}
+		s.runForNotification(n, func() error {
+			return s.didChangeConfiguration(&params)
+		})
+	case "workspace/didChangeWatchedFiles":
+		var params DidChangeWatchedFilesParams
+		if err := UnmarshalJSON(n.Params(), &params); err != nil {
+			return fmt.Errorf("failed to parse didChangeWatchedFiles params: %w", err)
+		}
+		s.runForNotification(n, func() error {
+			return s.didChangeWatchedFiles(&params)
+		})
 	}
 	return nil
 }
@@ -350,8 +544,10 @@ func (s *Server) wrapWithMetrics(msg jsonrpc2.Message, fn func() (any, error)) f
 	initTime := time.Now()
 	telemetryMsg := make(map[string]any)
 
+	var method string
 	switch m := msg.(type) {
 	case *jsonrpc2.Call:
+		method = m.Method()
 		id := m.ID()
 		telemetryMsg = map[string]any{
 			"call": map[string]any{
@@ -361,6 +557,7 @@ func (s *Server) wrapWithMetrics(msg jsonrpc2.Message, fn func() (any, error)) f
 			},
 		}
 	case *jsonrpc2.Notification:
+		method = m.Method()
 		telemetryMsg = map[string]any{
 			"notification": map[string]any{
 				"method": m.Method(),
@@ -380,44 +577,118 @@ func (s *Server) wrapWithMetrics(msg jsonrpc2.Message, fn func() (any, error)) f
 		telemetryMsg["success"] = err == nil
 
 		s.sendTelemetryEvent(telemetryMsg)
+		s.logHandled(msg, initTime, startTime, endTime, err)
+		if method != "" {
+			s.metrics.recordRequest(method, endTime.Sub(startTime), err != nil)
+		}
 		return result, err
 	}
 }
 
 // runForCall runs a function for a call message and replies with the result or error.
-func (s *Server) runForCall(call *jsonrpc2.Call, fn func() (any, error)) {
-	ctx, cancelCauseFunc := context.WithCancelCause(context.TODO())
+//
+// The function is queued in s.tasks, so it may not start right away. See
+// [taskClass] for how calls are prioritized.
+//
+// The function is given a context that is cancelled once the client sends a
+// $/cancelRequest notification for the call. When that happens, the call is
+// replied with a [RequestCancelled] error right away and any late result of
+// the function is discarded. If it has not started yet, it is skipped.
+func (s *Server) runForCall(call *jsonrpc2.Call, fn func(ctx context.Context) (any, error)) {
+	ctx, cancelCauseFunc := context.WithCancelCause(context.Background())
 	s.cancelCauseFuncs.Store(call.ID(), cancelCauseFunc)
-	wrap := s.wrapWithMetrics(call, fn)
+	select {
+	case <-s.closed:
+		cancelCauseFunc(errServerClosed)
+	default:
+	}
+	wrap := s.wrapWithMetrics(call, func() (any, error) {
+		return fn(ctx)
+	})
 	go func() (err error) {
 		defer func() {
 			s.cancelCauseFuncs.Delete(call.ID())
+			cancelCauseFunc(nil)
 			if err != nil {
 				s.replyError(call.ID(), err)
 			}
 		}()
 
-		s.scheduler.Sched() // Do scheduling to receive (cancel) notifications on the fly.
-		if ctx.Err() != nil {
+		type callResult struct {
+			result any
+			err    error
+		}
+		done := make(chan callResult, 1)
+		s.tasks.enqueue(call.Method(), func() {
+			s.scheduler.Sched() // Do scheduling to receive (cancel) notifications on the fly.
+			if ctx.Err() != nil {
+				return
+			}
+			result, err := wrap()
+			done <- callResult{result, err}
+		})
+
+		var cr callResult
+		select {
+		case cr = <-done:
+		case <-ctx.Done():
 			err = context.Cause(ctx)
 			return err
 		}
 
-		result, err := wrap()
-		resp, err := jsonrpc2.NewResponse(call.ID(), result, err)
+		resp, err := jsonrpc2.NewResponse(call.ID(), cr.result, cr.err)
 		if err != nil {
 			return err
 		}
-		return s.replier.ReplyMessage(resp)
+		return s.replyResponse(resp)
 	}()
 }
 
 // runForNotification runs a function for a notification message without expecting a response.
+//
+// The function is queued in s.tasks like in [Server.runForCall].
 func (s *Server) runForNotification(notify *jsonrpc2.Notification, fn func() error) {
 	wrap := s.wrapWithMetrics(notify, func() (any, error) {
 		return nil, fn()
 	})
-	go wrap()
+	s.tasks.enqueue(notify.Method(), func() { wrap() })
+}
+
+// call sends a server-initiated call to the client and waits for its
+// response. It returns early with the cause of ctx if ctx is done before the
+// response arrives.
+func (s *Server) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	id := jsonrpc2.NewIntID(s.lastServerCallID.Add(1))
+	c, err := jsonrpc2.NewCall(id, method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	respChan := make(chan *jsonrpc2.Response, 1)
+	s.serverCalls.Store(id, respChan)
+	defer s.serverCalls.Delete(id)
+	if err := s.replier.ReplyMessage(c); err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-respChan:
+		return resp.Result(), resp.Err()
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	case <-s.closed:
+		return nil, errServerClosed
+	}
+}
+
+// handleResponse handles a response message to a server-initiated call.
+func (s *Server) handleResponse(r *jsonrpc2.Response) error {
+	respChan, ok := s.serverCalls.LoadAndDelete(r.ID())
+	if !ok {
+		return fmt.Errorf("unexpected response for unknown call: %v", r.ID())
+	}
+	respChan.(chan *jsonrpc2.Response) <- r
+	return nil
 }
 
 var requestCancelled = jsonrpc2.NewError(int64(RequestCancelled), "Request cancelled")
@@ -446,7 +717,27 @@ func (s *Server) replyError(id jsonrpc2.ID, err error) error {
 	if err != nil {
 		return err
 	}
-	return s.replier.ReplyMessage(resp)
+	return s.replyResponse(resp)
+}
+
+// replyResponse replies to the client with resp. If resp is for a call in a
+// batch, it is held back until the responses to all calls in the batch are
+// ready, and then replied together with them.
+func (s *Server) replyResponse(resp *jsonrpc2.Response) error {
+	v, ok := s.batchCalls.LoadAndDelete(resp.ID())
+	if !ok {
+		return s.replier.ReplyMessage(resp)
+	}
+	pb := v.(*pendingBatch)
+	pb.mu.Lock()
+	pb.responses = append(pb.responses, resp)
+	pb.remaining--
+	done := pb.remaining == 0
+	pb.mu.Unlock()
+	if !done {
+		return nil
+	}
+	return s.replier.ReplyMessage(pb.responses)
 }
 
 // replyMethodNotFound replies to the client with a method not found error response.
@@ -499,3 +790,13 @@ func (s *Server) locationForNode(proj *xgo.Project, node xgoast.Node) Location {
 		Range: RangeForNode(proj, node),
 	}
 }
+
+// clientSupportsResourceOperation reports whether the client supports
+// workspace edits with documentChanges that include the given kind of resource
+// operation.
+func (s *Server) clientSupportsResourceOperation(kind ResourceOperationKind) bool {
+	workspaceEditCaps := s.clientCapabilities.Workspace.WorkspaceEdit
+	return workspaceEditCaps != nil &&
+		workspaceEditCaps.DocumentChanges &&
+		slices.Contains(workspaceEditCaps.ResourceOperations, kind)
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"sync"
@@ -42,11 +43,15 @@ func (m *mockReplier) reset() {
 }
 
 func newMapFSWithoutModTime(files map[string][]byte) *vfs.MapFS {
+	return newMapFSWithFeatures(files, xgo.FeatAll)
+}
+
+func newMapFSWithFeatures(files map[string][]byte, feats uint) *vfs.MapFS {
 	fileMap := make(map[string]*vfs.MapFile)
 	for k, v := range files {
 		fileMap[k] = &vfs.MapFile{Content: v}
 	}
-	return xgo.NewProject(nil, fileMap, xgo.FeatAll)
+	return xgo.NewProject(nil, fileMap, feats)
 }
 
 func fileMapGetter(files map[string][]byte) func() map[string]*vfs.MapFile {
@@ -82,11 +87,11 @@ echo x
 
 		var request1Runned bool
 		var request2Runned bool
-		s.runForCall(call1, func() (any, error) {
+		s.runForCall(call1, func(ctx context.Context) (any, error) {
 			request1Runned = true
 			return "should not reach here", nil
 		})
-		s.runForCall(call2, func() (any, error) {
+		s.runForCall(call2, func(ctx context.Context) (any, error) {
 			request2Runned = true
 			return "should not reach here either", nil
 		})
@@ -131,6 +136,64 @@ echo x
 		assert.Contains(t, wireErr2.Message, "Request cancelled")
 	})
 
+	t.Run("CancelRunningRequest", func(t *testing.T) {
+		files := map[string][]byte{
+			"main.spx": []byte(`var x = 100`),
+		}
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(files), replier, fileMapGetter(files), &MockScheduler{})
+
+		call, _ := jsonrpc2.NewCall(jsonrpc2.NewIntID(1), "workspace/diagnostic", nil)
+
+		started := make(chan struct{})
+		handlerDone := make(chan error, 1)
+		s.runForCall(call, func(ctx context.Context) (any, error) {
+			close(started)
+			<-ctx.Done()
+			handlerDone <- context.Cause(ctx)
+			return "should be discarded", nil
+		})
+
+		<-started
+		require.NoError(t, s.cancelRequest(&CancelParams{ID: float64(1)}))
+
+		select {
+		case err := <-handlerDone:
+			assert.ErrorIs(t, err, requestCancelled)
+		case <-time.After(time.Second):
+			t.Fatal("handler context was not cancelled")
+		}
+
+		require.Eventually(t, func() bool {
+			return len(replier.getMessages()) > 0
+		}, time.Second, 5*time.Millisecond)
+		var response *jsonrpc2.Response
+		for _, msg := range replier.getMessages() {
+			if resp, ok := msg.(*jsonrpc2.Response); ok {
+				response = resp
+			}
+		}
+		require.NotNil(t, response)
+		assert.Equal(t, call.ID(), response.ID())
+		var wireErr *jsonrpc2.WireError
+		require.True(t, errors.As(response.Err(), &wireErr))
+		assert.Equal(t, int64(RequestCancelled), wireErr.Code)
+	})
+
+	t.Run("CompileWithCancelledContext", func(t *testing.T) {
+		files := map[string][]byte{
+			"main.spx": []byte(`var x = 100`),
+		}
+		s := New(newMapFSWithoutModTime(files), &mockReplier{}, fileMapGetter(files), &MockScheduler{})
+
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(requestCancelled)
+
+		result, err := s.compileWithContext(ctx)
+		assert.ErrorIs(t, err, requestCancelled)
+		assert.Nil(t, result)
+	})
+
 	t.Run("CancelRequestWithInvalidID", func(t *testing.T) {
 		files := map[string][]byte{
 			"main.spx": []byte(`var x = 100`),
@@ -157,6 +220,68 @@ echo x
 	})
 }
 
+func TestServerClose(t *testing.T) {
+	t.Run("CancelRunningRequest", func(t *testing.T) {
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), replier, nil, &MockScheduler{})
+
+		call, _ := jsonrpc2.NewCall(jsonrpc2.NewIntID(1), "workspace/diagnostic", nil)
+		started := make(chan struct{})
+		handlerDone := make(chan error, 1)
+		s.runForCall(call, func(ctx context.Context) (any, error) {
+			close(started)
+			<-ctx.Done()
+			handlerDone <- context.Cause(ctx)
+			return nil, nil
+		})
+
+		<-started
+		require.NoError(t, s.Close())
+		select {
+		case err := <-handlerDone:
+			assert.ErrorIs(t, err, errServerClosed)
+		case <-time.After(time.Second):
+			t.Fatal("handler context was not cancelled")
+		}
+	})
+
+	t.Run("AbortServerCall", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), &mockReplier{}, nil, &MockScheduler{})
+
+		callDone := make(chan error, 1)
+		go func() {
+			_, err := s.call(context.Background(), "window/workDoneProgress/create", nil)
+			callDone <- err
+		}()
+
+		require.NoError(t, s.Close())
+		select {
+		case err := <-callDone:
+			assert.ErrorIs(t, err, errServerClosed)
+		case <-time.After(time.Second):
+			t.Fatal("server call was not aborted")
+		}
+	})
+
+	t.Run("RequestAfterClose", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), &mockReplier{}, nil, &MockScheduler{})
+		require.NoError(t, s.Close())
+		require.NoError(t, s.Close()) // Closing twice is a no-op.
+
+		call, _ := jsonrpc2.NewCall(jsonrpc2.NewIntID(1), "workspace/diagnostic", nil)
+		called := make(chan struct{}, 1)
+		s.runForCall(call, func(ctx context.Context) (any, error) {
+			called <- struct{}{}
+			return nil, nil
+		})
+		select {
+		case <-called:
+			t.Fatal("handler should not run after close")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
+
 func TestHandleMessage_Call(t *testing.T) {
 	testCases := []struct {
 		name   string
@@ -586,3 +711,112 @@ func TestHandleMessage_Notification(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleMessage_Batch(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		files := map[string][]byte{
+			"main.spx": []byte("var x = 100\necho x"),
+		}
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(files), replier, nil, &MockScheduler{})
+
+		didChange, err := jsonrpc2.NewNotification("textDocument/didChange", DidChangeTextDocumentParams{
+			TextDocument: protocol.VersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Version:                2,
+			},
+			ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: "var y = 100\necho y"}},
+		})
+		require.NoError(t, err)
+		hover, err := jsonrpc2.NewCall(jsonrpc2.NewIntID(1), "textDocument/hover", HoverParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 1, Character: 5},
+			},
+		})
+		require.NoError(t, err)
+		unknown, err := jsonrpc2.NewCall(jsonrpc2.NewIntID(2), "unknown/method", nil)
+		require.NoError(t, err)
+
+		require.NoError(t, s.HandleMessage(jsonrpc2.Batch{didChange, hover, unknown}))
+
+		var batches []jsonrpc2.Batch
+		require.Eventually(t, func() bool {
+			batches = nil
+			for _, msg := range replier.getMessages() {
+				switch msg := msg.(type) {
+				case jsonrpc2.Batch:
+					batches = append(batches, msg)
+				case *jsonrpc2.Response:
+					t.Fatalf("unexpected response outside of batch: %v", msg.ID())
+				}
+			}
+			return len(batches) > 0
+		}, 5*time.Second, 10*time.Millisecond)
+		require.Len(t, batches, 1)
+		require.Len(t, batches[0], 2)
+
+		responses := make(map[jsonrpc2.ID]*jsonrpc2.Response)
+		for _, msg := range batches[0] {
+			resp, ok := msg.(*jsonrpc2.Response)
+			require.True(t, ok)
+			responses[resp.ID()] = resp
+		}
+		require.Contains(t, responses, hover.ID())
+		require.NoError(t, responses[hover.ID()].Err())
+		// The hover sees the change made earlier in the same batch.
+		assert.Contains(t, string(responses[hover.ID()].Result()), "xgo:main?Game.y")
+		require.Contains(t, responses, unknown.ID())
+		assert.ErrorIs(t, responses[unknown.ID()].Err(), jsonrpc2.ErrMethodNotFound)
+	})
+
+	t.Run("NotificationsOnly", func(t *testing.T) {
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), replier, nil, &MockScheduler{})
+
+		setTrace, err := jsonrpc2.NewNotification("$/setTrace", SetTraceParams{Value: "off"})
+		require.NoError(t, err)
+		require.NoError(t, s.HandleMessage(jsonrpc2.Batch{setTrace}))
+
+		time.Sleep(100 * time.Millisecond)
+		for _, msg := range replier.getMessages() {
+			_, ok := msg.(jsonrpc2.Batch)
+			assert.False(t, ok, "unexpected batch reply")
+		}
+	})
+}
+
+func TestHandleMessage_WithoutTypes(t *testing.T) {
+	files := map[string][]byte{
+		"main.spx": []byte("var x = 100\necho x"),
+	}
+	replier := &mockReplier{}
+	s := New(newMapFSWithFeatures(files, xgo.FeatASTCache), replier, fileMapGetter(files), &MockScheduler{})
+
+	hover, err := jsonrpc2.NewCall(jsonrpc2.NewIntID(1), "textDocument/hover", HoverParams{
+		TextDocumentPositionParams: TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Position:     Position{Line: 1, Character: 5},
+		},
+	})
+	require.NoError(t, err)
+	semanticTokens, err := jsonrpc2.NewCall(jsonrpc2.NewIntID(2), "textDocument/semanticTokens/full", SemanticTokensParams{
+		TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, s.HandleMessage(hover))
+	require.NoError(t, s.HandleMessage(semanticTokens))
+
+	responses := make(map[jsonrpc2.ID]*jsonrpc2.Response)
+	require.Eventually(t, func() bool {
+		for _, msg := range replier.getMessages() {
+			if resp, ok := msg.(*jsonrpc2.Response); ok {
+				responses[resp.ID()] = resp
+			}
+		}
+		return len(responses) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, responses[hover.ID()].Err(), jsonrpc2.ErrMethodNotFound)
+	assert.NoError(t, responses[semanticTokens.ID()].Err())
+}
@@ -0,0 +1,378 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/goplus/xgolsw/internal/analysis"
+	"golang.org/x/mod/semver"
+)
+
+// settingsSection is the section name under which clients may nest the server
+// settings, for example in workspace/didChangeConfiguration notifications.
+const settingsSection = "xgolsw"
+
+// Settings holds the user configurable settings of the server.
+//
+// Settings are populated from the initializationOptions of the initialize
+// request and updated by workspace/didChangeConfiguration notifications.
+// Fields omitted by the client keep their default values.
+type Settings struct {
+	// Analyzers maps analyzer names to whether they are enabled. Analyzers
+	// not listed here fall back to [analysis.Analyzer.EnabledByDefault].
+	Analyzers map[string]bool `json:"analyzers,omitempty"`
+
+	// AnalyzerSeverity maps analyzer names to the severity of the diagnostics
+	// they report, which is one of "error", "warning", "information" and
+	// "hint". Analyzers not listed here fall back to
+	// [analysis.Analyzer.Severity].
+	AnalyzerSeverity map[string]string `json:"analyzerSeverity,omitempty"`
+
+	// Formatting holds the formatting preferences.
+	Formatting FormattingSettings `json:"formatting"`
+
+	// InlayHints holds the inlay hint preferences.
+	InlayHints InlayHintSettings `json:"inlayHints"`
+
+	// Completion holds the completion preferences.
+	Completion CompletionSettings `json:"completion"`
+
+	// DiagnosticsDelay is the debounce window for publishing diagnostics
+	// after file changes, for example "500ms". Changes made within the window
+	// are coalesced, and diagnostics are only published once no further
+	// changes are made. Zero means diagnostics are published right away.
+	DiagnosticsDelay Duration `json:"diagnosticsDelay,omitzero"`
+
+	// ResourceRootDir overrides the spx resource root directory, relative to
+	// the workspace root. Empty means the directory is taken from the first
+	// argument of the run call in main.spx, falling back to "assets".
+	ResourceRootDir string `json:"resourceRootDir,omitempty"`
+
+	// SpxVersion overrides the spx version that the project targets, for
+	// example "v2.0.0", which selects the bundled spx API documentation used
+	// by hovers, completion and deprecation checks. Empty means the version
+	// is taken from the requirement of the spx module in go.mod, falling back
+	// to the version bundled as default.
+	SpxVersion string `json:"spxVersion,omitempty"`
+}
+
+// FormattingSettings holds the formatting preferences.
+type FormattingSettings struct {
+	// EliminateUnusedLambdaParams controls whether unused lambda parameters
+	// are eliminated when formatting.
+	EliminateUnusedLambdaParams bool `json:"eliminateUnusedLambdaParams"`
+
+	// ReorderDecls controls whether top-level declarations are reordered
+	// when formatting.
+	ReorderDecls bool `json:"reorderDecls"`
+
+	// OrganizeImportsOnSave controls whether imports are organized right
+	// before a document is saved.
+	OrganizeImportsOnSave bool `json:"organizeImportsOnSave"`
+
+	// UseSpaces controls whether lines are indented with spaces instead of
+	// tabs.
+	UseSpaces bool `json:"useSpaces"`
+
+	// TabWidth is the number of spaces per indentation level when UseSpaces
+	// is enabled.
+	TabWidth int `json:"tabWidth"`
+
+	// MaxBlankLines is the maximum number of consecutive blank lines kept.
+	// The XGo formatter keeps at most one, so zero, which removes all blank
+	// lines outside of raw strings and comments, is the only value that
+	// makes a further difference.
+	MaxBlankLines int `json:"maxBlankLines"`
+
+	// AlignStructLiteralFields controls whether the values of key-value
+	// pairs in composite literals spanning multiple lines are aligned.
+	AlignStructLiteralFields bool `json:"alignStructLiteralFields"`
+
+	// Strict controls whether the stricter formatting profile is applied,
+	// which, like gofumpt, also removes redundant parentheses, rewrites raw
+	// string literals that need no escaping as interpreted string literals,
+	// and reorders top-level declarations regardless of ReorderDecls.
+	Strict bool `json:"strict"`
+
+	// FileHeader is the template of the file header inserted by the source
+	// action, such as "Copyright ${year} The Authors". Each line becomes a
+	// line comment, with `${year}` replaced by the current year and `${file}`
+	// by the file name. Empty means no file header is offered.
+	FileHeader string `json:"fileHeader,omitempty"`
+}
+
+// indentUnit returns the text of one indentation level.
+func (st FormattingSettings) indentUnit() string {
+	if st.UseSpaces {
+		return strings.Repeat(" ", st.TabWidth)
+	}
+	return "\t"
+}
+
+// InlayHintSettings holds the inlay hint preferences.
+type InlayHintSettings struct {
+	// ParameterNames controls whether parameter name hints are shown for
+	// call arguments.
+	ParameterNames bool `json:"parameterNames"`
+
+	// MinParameterNameLength is the minimum length of parameter names for
+	// which parameter name hints are shown, so that short names like `x`
+	// can be left out.
+	MinParameterNameLength int `json:"minParameterNameLength"`
+
+	// SkipMatchingParameterNames controls whether parameter name hints are
+	// left out for arguments already named after the parameter, such as
+	// `speed` passed as the `speed` parameter.
+	SkipMatchingParameterNames bool `json:"skipMatchingParameterNames"`
+
+	// ImplicitConversions controls whether hints are shown for untyped
+	// constant arguments implicitly converted between integer and float
+	// parameters, such as `10/3` passed as `float64(3)`.
+	ImplicitConversions bool `json:"implicitConversions"`
+
+	// ReturnValues controls whether result type hints are shown for the
+	// variables assigned from a call returning multiple values.
+	ReturnValues bool `json:"returnValues"`
+
+	// VariableTypes controls whether type hints are shown for the variables
+	// declared by `:=` with their types inferred from single values.
+	VariableTypes bool `json:"variableTypes"`
+}
+
+// anyEnabled reports whether any kind of inlay hints is enabled.
+func (st InlayHintSettings) anyEnabled() bool {
+	return st.ParameterNames || st.ImplicitConversions || st.ReturnValues || st.VariableTypes
+}
+
+// CompletionSettings holds the completion preferences.
+type CompletionSettings struct {
+	// CommitCharacters maps completion item kinds, such as "function" and
+	// "variable", to the characters that accept a completion item of the
+	// kind when typed. Kinds not listed here have no commit characters.
+	CommitCharacters map[string][]string `json:"commitCharacters,omitempty"`
+
+	// CaseSensitiveSort controls whether completion items of the same kind
+	// are sorted by their labels case-sensitively.
+	CaseSensitiveSort bool `json:"caseSensitiveSort"`
+
+	// Preselect controls whether the best matching completion item is
+	// preselected.
+	Preselect bool `json:"preselect"`
+}
+
+// completionItemKinds maps the completion item kind names accepted by
+// [CompletionSettings.CommitCharacters] to completion item kinds.
+var completionItemKinds = map[string]CompletionItemKind{
+	"text":      TextCompletion,
+	"method":    MethodCompletion,
+	"function":  FunctionCompletion,
+	"field":     FieldCompletion,
+	"variable":  VariableCompletion,
+	"class":     ClassCompletion,
+	"interface": InterfaceCompletion,
+	"module":    ModuleCompletion,
+	"keyword":   KeywordCompletion,
+	"snippet":   SnippetCompletion,
+	"constant":  ConstantCompletion,
+	"struct":    StructCompletion,
+}
+
+// commitCharacters returns the commit characters for completion items of the
+// given kind.
+func (st *CompletionSettings) commitCharacters(kind CompletionItemKind) []string {
+	for name, k := range completionItemKinds {
+		if k == kind {
+			return st.CommitCharacters[name]
+		}
+	}
+	return nil
+}
+
+// defaultSettings returns the default settings.
+func defaultSettings() *Settings {
+	return &Settings{
+		Formatting: FormattingSettings{
+			EliminateUnusedLambdaParams: true,
+			ReorderDecls:                true,
+			TabWidth:                    4,
+			MaxBlankLines:               1,
+			AlignStructLiteralFields:    true,
+		},
+		InlayHints: InlayHintSettings{
+			ParameterNames:             true,
+			SkipMatchingParameterNames: true,
+			ImplicitConversions:        true,
+			ReturnValues:               true,
+		},
+	}
+}
+
+// analyzerEnabled reports whether the given analyzer is enabled.
+func (st *Settings) analyzerEnabled(a *analysis.Analyzer) bool {
+	if enabled, ok := st.Analyzers[a.Name()]; ok {
+		return enabled
+	}
+	return a.EnabledByDefault()
+}
+
+// analyzerSeverities maps the severity names accepted by
+// [Settings.AnalyzerSeverity] to diagnostic severities.
+var analyzerSeverities = map[string]DiagnosticSeverity{
+	"error":       SeverityError,
+	"warning":     SeverityWarning,
+	"information": SeverityInformation,
+	"hint":        SeverityHint,
+}
+
+// analyzerSeverityName returns the name of the given severity as accepted by
+// [Settings.AnalyzerSeverity].
+func analyzerSeverityName(severity DiagnosticSeverity) string {
+	for name, s := range analyzerSeverities {
+		if s == severity {
+			return name
+		}
+	}
+	return ""
+}
+
+// analyzerSeverity returns the severity of diagnostics reported by the given
+// analyzer.
+func (st *Settings) analyzerSeverity(a *analysis.Analyzer) DiagnosticSeverity {
+	if severity, ok := analyzerSeverities[st.AnalyzerSeverity[a.Name()]]; ok {
+		return severity
+	}
+	return DiagnosticSeverity(a.Severity())
+}
+
+// analyzerSeverityConfigured reports whether the severity of the given
+// analyzer is explicitly configured in the settings, in which case it applies
+// to all diagnostics reported by the analyzer.
+func (st *Settings) analyzerSeverityConfigured(a *analysis.Analyzer) bool {
+	_, ok := analyzerSeverities[st.AnalyzerSeverity[a.Name()]]
+	return ok
+}
+
+// analyzerConfig returns the configuration of diagnostics reported by the
+// given analyzer.
+func (st *Settings) analyzerConfig(a *analysis.Analyzer) analyzerConfig {
+	return analyzerConfig{
+		severity:         st.analyzerSeverity(a),
+		overrideSeverity: st.analyzerSeverityConfigured(a),
+		tags:             analyzerTags(a),
+	}
+}
+
+// parseSettings parses the settings sent by the client on top of the default
+// settings. The settings may either be given directly or nested under
+// [settingsSection]. A nil value results in the default settings.
+func parseSettings(v any) (*Settings, error) {
+	settings := defaultSettings()
+	if v == nil {
+		return settings, nil
+	}
+	if m, ok := v.(map[string]any); ok {
+		if section, ok := m[settingsSection]; ok {
+			v = section
+		}
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, settings); err != nil {
+		return nil, fmt.Errorf("invalid settings: %w", err)
+	}
+	if settings.Formatting.TabWidth < 1 {
+		return nil, fmt.Errorf("invalid settings: formatting.tabWidth must be positive: %d", settings.Formatting.TabWidth)
+	}
+	if settings.Formatting.MaxBlankLines < 0 {
+		return nil, fmt.Errorf("invalid settings: formatting.maxBlankLines must not be negative: %d", settings.Formatting.MaxBlankLines)
+	}
+	if settings.InlayHints.MinParameterNameLength < 0 {
+		return nil, fmt.Errorf("invalid settings: inlayHints.minParameterNameLength must not be negative: %d", settings.InlayHints.MinParameterNameLength)
+	}
+	for kind, chars := range settings.Completion.CommitCharacters {
+		if _, ok := completionItemKinds[kind]; !ok {
+			return nil, fmt.Errorf("invalid settings: unknown completion item kind in commitCharacters: %q", kind)
+		}
+		for _, char := range chars {
+			if utf8.RuneCountInString(char) != 1 {
+				return nil, fmt.Errorf("invalid settings: commitCharacters of %q must be single characters: %q", kind, char)
+			}
+		}
+	}
+	for name, severity := range settings.AnalyzerSeverity {
+		if _, ok := analyzerSeverities[severity]; !ok {
+			return nil, fmt.Errorf("invalid settings: analyzerSeverity of %q must be one of error, warning, information and hint: %q", name, severity)
+		}
+	}
+	if dir := settings.ResourceRootDir; dir != "" {
+		if path.IsAbs(dir) || !fs.ValidPath(path.Clean(dir)) {
+			return nil, fmt.Errorf("invalid settings: resourceRootDir must be a relative path within the workspace: %q", dir)
+		}
+		settings.ResourceRootDir = path.Clean(dir)
+	}
+	if v := settings.SpxVersion; v != "" && !semver.IsValid(v) {
+		return nil, fmt.Errorf("invalid settings: spxVersion must be a valid semantic version: %q", v)
+	}
+	return settings, nil
+}
+
+// setSettings sets the current settings.
+func (s *Server) setSettings(settings *Settings) {
+	s.settings.Store(settings)
+}
+
+// getSettings returns the current settings. The returned settings must not be
+// modified.
+func (s *Server) getSettings() *Settings {
+	if settings := s.settings.Load(); settings != nil {
+		return settings
+	}
+	return defaultSettings()
+}
+
+// Duration is a [time.Duration] that is encoded as a duration string in JSON,
+// for example "1.5s" or "300ms".
+type Duration time.Duration
+
+// MarshalJSON implements [json.Marshaler].
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if parsed < 0 {
+		return fmt.Errorf("duration must not be negative: %s", s)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#workspace_didChangeConfiguration
+func (s *Server) didChangeConfiguration(params *DidChangeConfigurationParams) error {
+	settings, err := parseSettings(params.Settings)
+	if err != nil {
+		return err
+	}
+	s.setSettings(settings)
+
+	// Analyzer toggles and severities may change diagnostics of any spx file.
+	s.publishDiagnosticsForSpxFiles()
+	return nil
+}
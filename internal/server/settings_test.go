@@ -0,0 +1,278 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/goplus/xgolsw/internal/analysis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSettings(t *testing.T) {
+	t.Run("Nil", func(t *testing.T) {
+		settings, err := parseSettings(nil)
+		require.NoError(t, err)
+		assert.Equal(t, defaultSettings(), settings)
+	})
+
+	t.Run("Normal", func(t *testing.T) {
+		settings, err := parseSettings(map[string]any{
+			"analyzers": map[string]any{"appends": false},
+			"formatting": map[string]any{
+				"reorderDecls": false,
+			},
+			"diagnosticsDelay": "300ms",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]bool{"appends": false}, settings.Analyzers)
+		assert.True(t, settings.Formatting.EliminateUnusedLambdaParams)
+		assert.False(t, settings.Formatting.ReorderDecls)
+		assert.False(t, settings.Formatting.OrganizeImportsOnSave)
+		assert.False(t, settings.Formatting.UseSpaces)
+		assert.Equal(t, 4, settings.Formatting.TabWidth)
+		assert.Equal(t, 1, settings.Formatting.MaxBlankLines)
+		assert.True(t, settings.Formatting.AlignStructLiteralFields)
+		assert.False(t, settings.Formatting.Strict)
+		assert.Empty(t, settings.Formatting.FileHeader)
+		assert.True(t, settings.InlayHints.ParameterNames)
+		assert.True(t, settings.InlayHints.SkipMatchingParameterNames)
+		assert.True(t, settings.InlayHints.ImplicitConversions)
+		assert.True(t, settings.InlayHints.ReturnValues)
+		assert.False(t, settings.InlayHints.VariableTypes)
+		assert.Equal(t, Duration(300*time.Millisecond), settings.DiagnosticsDelay)
+	})
+
+	t.Run("NestedSection", func(t *testing.T) {
+		settings, err := parseSettings(map[string]any{
+			"xgolsw": map[string]any{
+				"inlayHints": map[string]any{"parameterNames": false, "returnValues": false},
+			},
+		})
+		require.NoError(t, err)
+		assert.False(t, settings.InlayHints.ParameterNames)
+		assert.True(t, settings.InlayHints.ImplicitConversions)
+		assert.False(t, settings.InlayHints.ReturnValues)
+	})
+
+	t.Run("InvalidDuration", func(t *testing.T) {
+		_, err := parseSettings(map[string]any{"diagnosticsDelay": "soon"})
+		require.Error(t, err)
+
+		_, err = parseSettings(map[string]any{"diagnosticsDelay": 300})
+		require.Error(t, err)
+
+		_, err = parseSettings(map[string]any{"diagnosticsDelay": "-1s"})
+		require.Error(t, err)
+	})
+
+	t.Run("InvalidMinParameterNameLength", func(t *testing.T) {
+		_, err := parseSettings(map[string]any{
+			"inlayHints": map[string]any{"minParameterNameLength": -1},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("InvalidFormatting", func(t *testing.T) {
+		_, err := parseSettings(map[string]any{
+			"formatting": map[string]any{"tabWidth": 0},
+		})
+		require.Error(t, err)
+
+		_, err = parseSettings(map[string]any{
+			"formatting": map[string]any{"maxBlankLines": -1},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("InvalidType", func(t *testing.T) {
+		_, err := parseSettings(map[string]any{"analyzers": "appends"})
+		require.Error(t, err)
+	})
+
+	t.Run("ResourceRootDir", func(t *testing.T) {
+		settings, err := parseSettings(map[string]any{"resourceRootDir": "./res/"})
+		require.NoError(t, err)
+		assert.Equal(t, "res", settings.ResourceRootDir)
+
+		for _, dir := range []string{"/res", "../res", "res/../../x"} {
+			_, err := parseSettings(map[string]any{"resourceRootDir": dir})
+			require.Error(t, err, dir)
+		}
+	})
+
+	t.Run("SpxVersion", func(t *testing.T) {
+		settings, err := parseSettings(map[string]any{"spxVersion": "v2.0.0"})
+		require.NoError(t, err)
+		assert.Equal(t, "v2.0.0", settings.SpxVersion)
+
+		_, err = parseSettings(map[string]any{"spxVersion": "2.0"})
+		require.Error(t, err)
+	})
+
+	t.Run("CompletionCommitCharacters", func(t *testing.T) {
+		settings, err := parseSettings(map[string]any{
+			"completion": map[string]any{
+				"commitCharacters": map[string]any{"function": []string{"("}},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"("}, settings.Completion.commitCharacters(FunctionCompletion))
+		assert.Nil(t, settings.Completion.commitCharacters(VariableCompletion))
+
+		_, err = parseSettings(map[string]any{
+			"completion": map[string]any{
+				"commitCharacters": map[string]any{"lambda": []string{"("}},
+			},
+		})
+		require.Error(t, err)
+
+		_, err = parseSettings(map[string]any{
+			"completion": map[string]any{
+				"commitCharacters": map[string]any{"function": []string{"()"}},
+			},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestFormattingSettingsIndentUnit(t *testing.T) {
+	assert.Equal(t, "\t", defaultSettings().Formatting.indentUnit())
+	assert.Equal(t, "  ", FormattingSettings{UseSpaces: true, TabWidth: 2}.indentUnit())
+}
+
+func TestSettingsAnalyzerEnabled(t *testing.T) {
+	appends := analysis.DefaultAnalyzers["appends"]
+	require.NotNil(t, appends)
+
+	t.Run("Default", func(t *testing.T) {
+		assert.Equal(t, appends.EnabledByDefault(), defaultSettings().analyzerEnabled(appends))
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		settings := defaultSettings()
+		settings.Analyzers = map[string]bool{"appends": false}
+		assert.False(t, settings.analyzerEnabled(appends))
+	})
+}
+
+func TestSettingsAnalyzerSeverity(t *testing.T) {
+	appends := analysis.DefaultAnalyzers["appends"]
+	require.NotNil(t, appends)
+
+	t.Run("Default", func(t *testing.T) {
+		assert.Equal(t, DiagnosticSeverity(appends.Severity()), defaultSettings().analyzerSeverity(appends))
+		assert.False(t, defaultSettings().analyzerSeverityConfigured(appends))
+	})
+
+	t.Run("Overridden", func(t *testing.T) {
+		settings, err := parseSettings(map[string]any{"analyzerSeverity": map[string]any{"appends": "error"}})
+		require.NoError(t, err)
+		assert.Equal(t, SeverityError, settings.analyzerSeverity(appends))
+		assert.True(t, settings.analyzerSeverityConfigured(appends))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := parseSettings(map[string]any{"analyzerSeverity": map[string]any{"appends": "fatal"}})
+		require.Error(t, err)
+	})
+}
+
+func TestServerDidChangeConfiguration(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), &mockReplier{}, nil, &MockScheduler{})
+		assert.Equal(t, defaultSettings(), s.getSettings())
+
+		err := s.didChangeConfiguration(&DidChangeConfigurationParams{
+			Settings: map[string]any{
+				"formatting": map[string]any{"eliminateUnusedLambdaParams": false},
+			},
+		})
+		require.NoError(t, err)
+		assert.False(t, s.getSettings().Formatting.EliminateUnusedLambdaParams)
+		assert.True(t, s.getSettings().Formatting.ReorderDecls)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), &mockReplier{}, nil, &MockScheduler{})
+
+		err := s.didChangeConfiguration(&DidChangeConfigurationParams{
+			Settings: map[string]any{"inlayHints": map[string]any{"parameterNames": false}},
+		})
+		require.NoError(t, err)
+		assert.False(t, s.getSettings().InlayHints.ParameterNames)
+
+		err = s.didChangeConfiguration(&DidChangeConfigurationParams{})
+		require.NoError(t, err)
+		assert.Equal(t, defaultSettings(), s.getSettings())
+	})
+
+	t.Run("InvalidSettings", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), &mockReplier{}, nil, &MockScheduler{})
+
+		err := s.didChangeConfiguration(&DidChangeConfigurationParams{
+			Settings: map[string]any{"diagnosticsDelay": "soon"},
+		})
+		require.Error(t, err)
+		assert.Equal(t, defaultSettings(), s.getSettings())
+	})
+
+	t.Run("DisableInlayHints", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+onStart => {
+	println "Hello, World!"
+}
+`),
+			"assets/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, fileMapGetter(m), &MockScheduler{})
+		params := &InlayHintParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+			Range: Range{
+				Start: Position{Line: 0, Character: 0},
+				End:   Position{Line: 100, Character: 0},
+			},
+		}
+
+		inlayHints, err := s.textDocumentInlayHint(context.Background(), params)
+		require.NoError(t, err)
+		assert.NotEmpty(t, inlayHints)
+
+		err = s.didChangeConfiguration(&DidChangeConfigurationParams{
+			Settings: map[string]any{"inlayHints": map[string]any{"parameterNames": false}},
+		})
+		require.NoError(t, err)
+
+		inlayHints, err = s.textDocumentInlayHint(context.Background(), params)
+		require.NoError(t, err)
+		assert.Empty(t, inlayHints)
+	})
+
+	t.Run("DisableDeclReordering", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`var score Score
+
+type Score int
+`),
+		}
+		s := New(newMapFSWithoutModTime(m), &mockReplier{}, fileMapGetter(m), &MockScheduler{})
+		params := &DocumentFormattingParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+		}
+
+		edits, err := s.textDocumentFormatting(params)
+		require.NoError(t, err)
+		assert.NotEmpty(t, edits)
+
+		err = s.didChangeConfiguration(&DidChangeConfigurationParams{
+			Settings: map[string]any{"formatting": map[string]any{"reorderDecls": false}},
+		})
+		require.NoError(t, err)
+
+		edits, err = s.textDocumentFormatting(params)
+		require.NoError(t, err)
+		assert.Empty(t, edits)
+	})
+}
@@ -1,53 +1,417 @@
 package server
 
 import (
+	"context"
+	"go/constant"
 	"go/types"
+	"slices"
 	"strings"
 
+	xgoast "github.com/goplus/xgo/ast"
+	xgoscanner "github.com/goplus/xgo/scanner"
+	xgotoken "github.com/goplus/xgo/token"
+	"github.com/goplus/xgolsw/xgo"
 	"github.com/goplus/xgolsw/xgo/xgoutil"
 )
 
 // See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#textDocument_signatureHelp
-func (s *Server) textDocumentSignatureHelp(params *SignatureHelpParams) (*SignatureHelp, error) {
-	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(params.TextDocument.URI)
+func (s *Server) textDocumentSignatureHelp(ctx context.Context, params *SignatureHelpParams) (*SignatureHelp, error) {
+	result, _, astFile, err := s.compileAndGetASTFileForDocumentURI(ctx, params.TextDocument.URI)
 	if err != nil {
 		return nil, err
 	}
 	if astFile == nil {
 		return nil, nil
 	}
-	position := ToPosition(result.proj, astFile, params.Position)
-	ident := xgoutil.IdentAtPosition(result.proj, astFile, position)
-
 	typeInfo, _ := result.proj.TypeInfo()
 	if typeInfo == nil {
 		return nil, nil
 	}
 
-	obj := typeInfo.ObjectOf(ident)
-	if obj == nil {
+	pos := PosAt(result.proj, astFile, params.Position)
+	if !pos.IsValid() {
 		return nil, nil
 	}
+	site, inCall := scanSignatureHelpCallSite(xgoutil.NodeTokenFile(result.proj, astFile), astFile.Code, pos)
 
-	fun, ok := obj.(*types.Func)
-	if !ok {
+	var ident *xgoast.Ident
+	if inCall {
+		ident = xgoutil.IdentAtPosition(result.proj, astFile, result.proj.Fset.Position(site.funPos))
+	} else {
+		// Not within the arguments of a call, but possibly on the name of
+		// the called function.
+		position := ToPosition(result.proj, astFile, params.Position)
+		ident = xgoutil.IdentAtPosition(result.proj, astFile, position)
+	}
+	var fun *types.Func
+	if ident != nil {
+		fun, _ = typeInfo.ObjectOf(ident).(*types.Func)
+	}
+	if fun == nil {
+		if inCall {
+			// The call may not be resolvable while being edited, for
+			// example with a trailing comma, so keep showing the active
+			// signature help of the client with the parameter advanced.
+			return retriggeredSignatureHelp(params.Context, site.argIndex), nil
+		}
 		return nil, nil
 	}
-	sig, ok := fun.Type().(*types.Signature)
+	funcOverloads := xgoFuncOverloads(fun)
+
+	var args []xgoast.Expr
+	if callExpr := callExprForFuncIdent(astFile, ident); callExpr != nil {
+		args = callExpr.Args
+		if inCall {
+			args = args[:min(site.argIndex, len(args))]
+		}
+	}
+
+	// Prefer the overload resolved by the type checker, and fall back to the
+	// first overload accepting the provided arguments.
+	activeSignature := slices.Index(funcOverloads, fun)
+	if activeSignature < 0 {
+		activeSignature = max(slices.IndexFunc(funcOverloads, func(funcOverload *types.Func) bool {
+			return isFuncAcceptingArgs(typeInfo, funcOverload, args)
+		}), 0)
+	}
+
+	help := &SignatureHelp{ActiveSignature: uint32(activeSignature)}
+	for i, funcOverload := range funcOverloads {
+		info := signatureInformationForFunc(funcOverload)
+		if inCall {
+			info.ActiveParameter = uint32(activeParamIndex(funcOverload, site.argIndex))
+			if i == activeSignature {
+				help.ActiveParameter = info.ActiveParameter
+			}
+		}
+		help.Signatures = append(help.Signatures, info)
+	}
+	return help, nil
+}
+
+// retriggeredSignatureHelp returns the active signature help of the given
+// retrigger context with the active parameter set to the one receiving the
+// argument at argIndex. It returns nil if the context is not a retrigger.
+func retriggeredSignatureHelp(ctx *SignatureHelpContext, argIndex int) *SignatureHelp {
+	if ctx == nil || !ctx.IsRetrigger || ctx.ActiveSignatureHelp == nil {
+		return nil
+	}
+
+	help := *ctx.ActiveSignatureHelp
+	help.Signatures = slices.Clone(help.Signatures)
+	for i := range help.Signatures {
+		info := &help.Signatures[i]
+		paramIndex := argIndex
+		if paramCount := len(info.Parameters); paramCount > 0 && paramIndex >= paramCount-1 {
+			if strings.Contains(info.Parameters[paramCount-1].Label, " ...") {
+				paramIndex = paramCount - 1
+			}
+		}
+		info.ActiveParameter = uint32(paramIndex)
+		if i == int(help.ActiveSignature) {
+			help.ActiveParameter = info.ActiveParameter
+		}
+	}
+	return &help
+}
+
+// callExprForFuncIdent returns the call expression calling the function
+// denoted by the given identifier, or nil if there is none.
+func callExprForFuncIdent(astFile *xgoast.File, ident *xgoast.Ident) *xgoast.CallExpr {
+	var callExpr *xgoast.CallExpr
+	xgoast.Inspect(astFile, func(node xgoast.Node) bool {
+		if callExpr != nil {
+			return false
+		}
+		if node == nil || node.Pos() > ident.Pos() || node.End() < ident.End() {
+			return node == nil
+		}
+		if call, ok := node.(*xgoast.CallExpr); ok {
+			switch fun := call.Fun.(type) {
+			case *xgoast.Ident:
+				if fun == ident {
+					callExpr = call
+				}
+			case *xgoast.SelectorExpr:
+				if fun.Sel == ident {
+					callExpr = call
+				}
+			}
+		}
+		return callExpr == nil
+	})
+	return callExpr
+}
+
+// signatureHelpCallSite is the call site whose arguments enclose a position.
+type signatureHelpCallSite struct {
+	// funPos is the position of the identifier of the called function.
+	funPos xgotoken.Pos
+
+	// argIndex is the index of the argument at the position.
+	argIndex int
+}
+
+// signatureHelpScanFrame is a bracket or block being scanned by
+// [scanSignatureHelpCallSite].
+type signatureHelpScanFrame struct {
+	// open is the opening token, which is [xgotoken.ILLEGAL] for the file.
+	open xgotoken.Token
+
+	// isBlock reports whether the frame holds statements, in which
+	// command-style calls like `play "sound", true` may appear.
+	isBlock bool
+
+	// funPos is the position of the called function identifier, and commas
+	// is the number of commas seen at the frame level since then.
+	funPos xgotoken.Pos
+	funEnd xgotoken.Pos
+	commas int
+
+	// state is the state of the statement being scanned in a block.
+	state signatureHelpStmtState
+}
+
+// signatureHelpStmtState is the state of a statement being scanned by
+// [scanSignatureHelpCallSite].
+type signatureHelpStmtState int
+
+const (
+	stmtStateStart       signatureHelpStmtState = iota // at the start of a statement
+	stmtStateFunc                                      // after a leading identifier or selector
+	stmtStateSelector                                  // after a "." in a leading selector
+	stmtStateCommandArgs                               // in the arguments of a command-style call
+	stmtStateOther                                     // in any other statement
+)
+
+// scanSignatureHelpCallSite scans the source code up to the given position and
+// returns the innermost call site whose arguments enclose the position. Unlike
+// walking the AST, it works with code that is being edited and does not parse,
+// and with command-style calls without parentheses.
+func scanSignatureHelpCallSite(tokenFile *xgotoken.File, code []byte, pos xgotoken.Pos) (site signatureHelpCallSite, ok bool) {
+	var s xgoscanner.Scanner
+	s.Init(tokenFile, code, nil, 0)
+
+	stack := []*signatureHelpScanFrame{{open: xgotoken.ILLEGAL, isBlock: true}}
+	var (
+		prevTok xgotoken.Token
+		prevPos xgotoken.Pos
+		prevEnd xgotoken.Pos
+	)
+	for {
+		tokPos, tok, lit := s.Scan()
+		if tok == xgotoken.EOF || tokPos >= pos {
+			break
+		}
+		tokEnd := tokPos + xgotoken.Pos(len(lit))
+		if lit == "" {
+			tokEnd = tokPos + xgotoken.Pos(len(tok.String()))
+		}
+
+		top := stack[len(stack)-1]
+		if top.isBlock {
+			switch top.state {
+			case stmtStateStart:
+				top.state = stmtStateOther
+				if tok == xgotoken.IDENT {
+					top.funPos, top.funEnd, top.commas = tokPos, tokEnd, 0
+					top.state = stmtStateFunc
+				}
+			case stmtStateFunc:
+				switch {
+				case tok == xgotoken.PERIOD && tokPos == prevEnd:
+					top.state = stmtStateSelector
+				case tok.IsLiteral() || tok == xgotoken.DRARROW,
+					// Operators following a space but not followed by
+					// one start the first argument, e.g., `turn -90`.
+					(tok == xgotoken.SUB || tok == xgotoken.ADD || tok == xgotoken.NOT ||
+						tok == xgotoken.LBRACK || tok == xgotoken.MUL) && tokPos > prevEnd:
+					top.state = stmtStateCommandArgs
+				default:
+					top.state = stmtStateOther
+				}
+			case stmtStateSelector:
+				top.state = stmtStateOther
+				if tok == xgotoken.IDENT {
+					top.funPos, top.funEnd = tokPos, tokEnd
+					top.state = stmtStateFunc
+				}
+			case stmtStateCommandArgs:
+				if tok == xgotoken.COMMA {
+					top.commas++
+				}
+			}
+			if tok == xgotoken.SEMICOLON {
+				top.state = stmtStateStart
+			}
+		} else if tok == xgotoken.COMMA {
+			top.commas++
+		}
+
+		switch tok {
+		case xgotoken.LPAREN:
+			frame := &signatureHelpScanFrame{open: tok}
+			if prevTok == xgotoken.IDENT && prevEnd == tokPos {
+				frame.funPos, frame.funEnd = prevPos, prevEnd
+			}
+			stack = append(stack, frame)
+		case xgotoken.LBRACK:
+			stack = append(stack, &signatureHelpScanFrame{open: tok})
+		case xgotoken.LBRACE:
+			// Braces following these tokens enclose composite literal
+			// elements rather than statements.
+			isCompositeLit := prevTok == xgotoken.COMMA || prevTok == xgotoken.LPAREN ||
+				prevTok == xgotoken.COLON || prevTok == xgotoken.LBRACE || prevTok == xgotoken.RBRACK
+			stack = append(stack, &signatureHelpScanFrame{open: tok, isBlock: !isCompositeLit})
+		case xgotoken.RPAREN, xgotoken.RBRACK, xgotoken.RBRACE:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+		prevTok, prevPos, prevEnd = tok, tokPos, tokEnd
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		frame := stack[i]
+		if frame.isBlock {
+			switch frame.state {
+			case stmtStateCommandArgs:
+				return signatureHelpCallSite{funPos: frame.funPos, argIndex: frame.commas}, true
+			case stmtStateFunc:
+				// A space after the leading identifier may start the
+				// first argument of a command-style call.
+				if pos > frame.funEnd && frame.funEnd == prevEnd {
+					return signatureHelpCallSite{funPos: frame.funPos}, true
+				}
+			}
+			// Calls enclosing a block are not what the user is typing
+			// arguments for.
+			return signatureHelpCallSite{}, false
+		}
+		if frame.open == xgotoken.LPAREN && frame.funPos.IsValid() {
+			return signatureHelpCallSite{funPos: frame.funPos, argIndex: frame.commas}, true
+		}
+	}
+	return signatureHelpCallSite{}, false
+}
+
+// isXGotFunc reports whether the given function is an XGo template method,
+// which is a function whose first parameter is the receiver.
+func isXGotFunc(fun *types.Func) bool {
+	_, _, ok := xgoutil.SplitXGotMethodName(fun.Name(), true)
+	return ok && fun.Type().(*types.Signature).Recv() == nil
+}
+
+// activeParamIndex returns the index of the parameter of the given function
+// that receives the argument at argIndex, with all trailing arguments going to
+// a variadic parameter.
+func activeParamIndex(fun *types.Func, argIndex int) int {
+	sig := fun.Type().(*types.Signature)
+	paramCount := sig.Params().Len()
+	if isXGotFunc(fun) {
+		paramCount--
+	}
+	if sig.Variadic() && argIndex >= paramCount-1 {
+		return max(paramCount-1, 0)
+	}
+	return argIndex
+}
+
+// isFuncAcceptingArgs reports whether the given function accepts the provided
+// arguments as a prefix of its parameters. Arguments of unknown types are
+// accepted by any parameter.
+func isFuncAcceptingArgs(typeInfo *xgo.TypeInfo, fun *types.Func, args []xgoast.Expr) bool {
+	sig := fun.Type().(*types.Signature)
+	params := sig.Params()
+	offset := 0
+	if isXGotFunc(fun) {
+		offset = 1
+	}
+	if !sig.Variadic() && len(args) > params.Len()-offset {
+		return false
+	}
+	for i, arg := range args {
+		paramIndex := i + offset
+		var paramType types.Type
+		if sig.Variadic() && paramIndex >= params.Len()-1 {
+			paramType = params.At(params.Len() - 1).Type().(*types.Slice).Elem()
+		} else {
+			paramType = params.At(paramIndex).Type()
+		}
+
+		tv, ok := typeInfo.Types[arg]
+		if !ok || tv.Type == nil || tv.Type == types.Typ[types.Invalid] {
+			continue
+		}
+		if !isArgAssignableTo(tv, paramType) {
+			return false
+		}
+	}
+	return true
+}
+
+// isArgAssignableTo reports whether an argument of the given type and value is
+// assignable to a parameter of the given type.
+func isArgAssignableTo(tv types.TypeAndValue, paramType types.Type) bool {
+	basic, ok := tv.Type.(*types.Basic)
+	if !ok || basic.Info()&types.IsUntyped == 0 {
+		return xgoutil.IsTypesCompatible(tv.Type, paramType)
+	}
+
+	// Untyped constants are assignable to types they are representable by,
+	// which [types.AssignableTo] does not take into account.
+	if iface, ok := paramType.Underlying().(*types.Interface); ok {
+		return iface.Empty() || types.Implements(types.Default(basic), iface)
+	}
+	paramBasic, ok := paramType.Underlying().(*types.Basic)
 	if !ok {
-		return nil, nil
+		return basic.Kind() == types.UntypedNil
+	}
+	switch {
+	case basic.Info()&types.IsBoolean != 0:
+		return paramBasic.Info()&types.IsBoolean != 0
+	case basic.Info()&types.IsString != 0:
+		return paramBasic.Info()&types.IsString != 0
+	case basic.Info()&types.IsNumeric != 0:
+		if paramBasic.Info()&types.IsInteger != 0 && tv.Value != nil {
+			return constant.ToInt(tv.Value).Kind() == constant.Int
+		}
+		return paramBasic.Info()&types.IsNumeric != 0
+	}
+	return false
+}
+
+// signatureInformationForFunc returns the signature information for the given
+// function.
+func signatureInformationForFunc(fun *types.Func) SignatureInformation {
+	sig := fun.Type().(*types.Signature)
+
+	name := fun.Name()
+	isXGotMethod := isXGotFunc(fun)
+	if isXGotMethod || xgoutil.IsXGoOverloadedFuncName(name) {
+		_, _, name, _ = makeSpxDefinitionOverviewForFunc(fun)
 	}
 
 	var paramsInfo []ParameterInformation
-	for param := range sig.Params().Variables() {
+	for i := range sig.Params().Len() {
+		if isXGotMethod && i == 0 {
+			continue
+		}
+		param := sig.Params().At(i)
+		paramTypeName := GetSimplifiedTypeString(param.Type())
+		if sig.Variadic() && i == sig.Params().Len()-1 {
+			if slice, ok := param.Type().(*types.Slice); ok {
+				paramTypeName = "..." + GetSimplifiedTypeString(slice.Elem())
+			}
+		}
 		paramsInfo = append(paramsInfo, ParameterInformation{
-			Label: param.Name() + " " + GetSimplifiedTypeString(param.Type()),
+			Label: param.Name() + " " + paramTypeName,
 			// TODO: Add documentation.
 		})
 	}
 
-	label := fun.Name() + "("
-	if sig.Params().Len() > 0 {
+	label := name + "("
+	if len(paramsInfo) > 0 {
 		var paramLabels []string
 		for _, p := range paramsInfo {
 			paramLabels = append(paramLabels, p.Label)
@@ -64,11 +428,37 @@ func (s *Server) textDocumentSignatureHelp(params *SignatureHelpParams) (*Signat
 		label += " (" + strings.Join(returnTypes, ", ") + ")"
 	}
 
-	return &SignatureHelp{
-		Signatures: []SignatureInformation{{
-			Label: label,
-			// TODO: Add documentation.
-			Parameters: paramsInfo,
-		}},
-	}, nil
+	return SignatureInformation{
+		Label: label,
+		// TODO: Add documentation.
+		Parameters: paramsInfo,
+	}
+}
+
+// xgoFuncOverloads returns all overloads of the XGo overloadable function the
+// given function belongs to, or just the given function if it is not part of
+// an overloadable function.
+func xgoFuncOverloads(fun *types.Func) []*types.Func {
+	if funcOverloads := xgoutil.ExpandXGoOverloadableFunc(fun); funcOverloads != nil {
+		return funcOverloads
+	}
+
+	name := fun.Name()
+	if !xgoutil.IsXGoOverloadedFuncName(name) || fun.Pkg() == nil {
+		return []*types.Func{fun}
+	}
+	baseName := name[:strings.LastIndex(name, "__")]
+
+	var base types.Object
+	if recv := fun.Type().(*types.Signature).Recv(); recv != nil {
+		base, _, _ = types.LookupFieldOrMethod(recv.Type(), true, fun.Pkg(), baseName)
+	} else {
+		base = fun.Pkg().Scope().Lookup(baseName)
+	}
+	if baseFun, ok := base.(*types.Func); ok {
+		if funcOverloads := xgoutil.ExpandXGoOverloadableFunc(baseFun); slices.Contains(funcOverloads, fun) {
+			return funcOverloads
+		}
+	}
+	return []*types.Func{fun}
 }
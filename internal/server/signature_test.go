@@ -1,8 +1,13 @@
 package server
 
 import (
+	"context"
+	"go/constant"
+	"go/types"
+	"strings"
 	"testing"
 
+	"github.com/goplus/xgolsw/protocol"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -29,7 +34,7 @@ onStart => {
 		}
 		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
 
-		help, err := s.textDocumentSignatureHelp(&SignatureHelpParams{
+		help, err := s.textDocumentSignatureHelp(context.Background(), &SignatureHelpParams{
 			TextDocumentPositionParams: TextDocumentPositionParams{
 				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
 				Position:     Position{Line: 5, Character: 11},
@@ -39,12 +44,191 @@ onStart => {
 		require.NotNil(t, help)
 		require.Len(t, help.Signatures, 1)
 		assert.Equal(t, SignatureInformation{
-			Label: "Println(a []any) (int, error)",
+			Label: "Println(a ...any) (int, error)",
 			Parameters: []ParameterInformation{
 				{
-					Label: "a []any",
+					Label: "a ...any",
 				},
 			},
 		}, help.Signatures[0])
 	})
+
+	t.Run("FuncOverloads", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	recording Sound
+)
+
+play recording
+`),
+			"assets/index.json":                  []byte(`{}`),
+			"assets/sounds/recording/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		help, err := s.textDocumentSignatureHelp(context.Background(), &SignatureHelpParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+				Position:     Position{Line: 5, Character: 2},
+			},
+		})
+		require.NoError(t, err)
+		require.NotNil(t, help)
+		assert.Greater(t, len(help.Signatures), 1)
+		require.Less(t, int(help.ActiveSignature), len(help.Signatures))
+		assert.Equal(t, SignatureInformation{
+			Label: "play(media Sound)",
+			Parameters: []ParameterInformation{
+				{
+					Label: "media Sound",
+				},
+			},
+		}, help.Signatures[help.ActiveSignature])
+		for _, sig := range help.Signatures {
+			assert.True(t, strings.HasPrefix(sig.Label, "play("))
+		}
+	})
+
+	t.Run("ActiveSignatureAndParameter", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+func add(a int, b float64, rest ...string) {}
+
+onStart => {
+	play "recording", true
+	add(1, 2.5, "x", "y")
+}
+`),
+			"assets/index.json":                  []byte(`{}`),
+			"assets/sounds/recording/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		signatureHelp := func(position Position) *SignatureHelp {
+			help, err := s.textDocumentSignatureHelp(context.Background(), &SignatureHelpParams{
+				TextDocumentPositionParams: TextDocumentPositionParams{
+					TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+					Position:     position,
+				},
+			})
+			require.NoError(t, err)
+			require.NotNil(t, help)
+			return help
+		}
+
+		help := signatureHelp(Position{Line: 4, Character: 22})
+		require.Less(t, int(help.ActiveSignature), len(help.Signatures))
+		assert.Equal(t, "play(media SoundName, wait bool)", help.Signatures[help.ActiveSignature].Label)
+		assert.Equal(t, uint32(1), help.ActiveParameter)
+
+		help = signatureHelp(Position{Line: 4, Character: 8})
+		assert.Equal(t, "play(media SoundName, wait bool)", help.Signatures[help.ActiveSignature].Label)
+		assert.Equal(t, uint32(0), help.ActiveParameter)
+
+		help = signatureHelp(Position{Line: 5, Character: 8})
+		require.Len(t, help.Signatures, 1)
+		assert.Equal(t, uint32(1), help.ActiveParameter)
+		assert.Equal(t, uint32(1), help.Signatures[0].ActiveParameter)
+
+		help = signatureHelp(Position{Line: 5, Character: 19})
+		assert.Equal(t, uint32(2), help.ActiveParameter)
+	})
+
+	t.Run("EditingArguments", func(t *testing.T) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+func add(a int, b float64, rest ...string) {}
+
+onStart => {
+	play "recording", true
+	undefinedFunc 1
+	add(1, 
+}
+`),
+			"assets/index.json":                  []byte(`{}`),
+			"assets/sounds/recording/index.json": []byte(`{}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		signatureHelp := func(position Position, ctx *SignatureHelpContext) *SignatureHelp {
+			help, err := s.textDocumentSignatureHelp(context.Background(), &SignatureHelpParams{
+				TextDocumentPositionParams: TextDocumentPositionParams{
+					TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"},
+					Position:     position,
+				},
+				Context: ctx,
+			})
+			require.NoError(t, err)
+			return help
+		}
+
+		t.Run("UnclosedParen", func(t *testing.T) {
+			help := signatureHelp(Position{Line: 6, Character: 8}, nil)
+			require.NotNil(t, help)
+			require.Len(t, help.Signatures, 1)
+			assert.Equal(t, "add(a int, b float64, rest ...string)", help.Signatures[0].Label)
+			assert.Equal(t, uint32(1), help.ActiveParameter)
+		})
+
+		t.Run("CommandStyleCall", func(t *testing.T) {
+			help := signatureHelp(Position{Line: 4, Character: 19}, nil)
+			require.NotNil(t, help)
+			require.Less(t, int(help.ActiveSignature), len(help.Signatures))
+			assert.True(t, strings.HasPrefix(help.Signatures[help.ActiveSignature].Label, "play("))
+			assert.Equal(t, uint32(1), help.ActiveParameter)
+		})
+
+		t.Run("Retrigger", func(t *testing.T) {
+			activeHelp := &SignatureHelp{
+				Signatures: []SignatureInformation{{
+					Label: "undefinedFunc(a ...any)",
+					Parameters: []ParameterInformation{
+						{Label: "a ...any"},
+					},
+				}},
+			}
+
+			help := signatureHelp(Position{Line: 5, Character: 16}, &SignatureHelpContext{
+				TriggerKind:         protocol.SigTriggerCharacter,
+				TriggerCharacter:    " ",
+				IsRetrigger:         true,
+				ActiveSignatureHelp: activeHelp,
+			})
+			require.NotNil(t, help)
+			assert.Equal(t, activeHelp.Signatures[0].Label, help.Signatures[0].Label)
+			assert.Equal(t, uint32(0), help.ActiveParameter)
+
+			help = signatureHelp(Position{Line: 5, Character: 16}, nil)
+			assert.Nil(t, help)
+		})
+
+		t.Run("OutsideCall", func(t *testing.T) {
+			help := signatureHelp(Position{Line: 3, Character: 12}, &SignatureHelpContext{
+				TriggerKind:         protocol.SigContentChange,
+				IsRetrigger:         true,
+				ActiveSignatureHelp: &SignatureHelp{},
+			})
+			assert.Nil(t, help)
+		})
+	})
+}
+
+func TestIsArgAssignableTo(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		tv        types.TypeAndValue
+		paramType types.Type
+		want      bool
+	}{
+		{"UntypedIntToFloat", types.TypeAndValue{Type: types.Typ[types.UntypedInt], Value: constant.MakeInt64(1)}, types.Typ[types.Float64], true},
+		{"UntypedFloatToInt", types.TypeAndValue{Type: types.Typ[types.UntypedFloat], Value: constant.MakeFloat64(1.5)}, types.Typ[types.Int], false},
+		{"UntypedWholeFloatToInt", types.TypeAndValue{Type: types.Typ[types.UntypedFloat], Value: constant.MakeFloat64(2)}, types.Typ[types.Int], true},
+		{"UntypedStringToInt", types.TypeAndValue{Type: types.Typ[types.UntypedString], Value: constant.MakeString("a")}, types.Typ[types.Int], false},
+		{"UntypedBoolToAny", types.TypeAndValue{Type: types.Typ[types.UntypedBool], Value: constant.MakeBool(true)}, types.NewInterfaceType(nil, nil), true},
+		{"UntypedNilToPointer", types.TypeAndValue{Type: types.Typ[types.UntypedNil]}, types.NewPointer(types.Typ[types.Int]), true},
+		{"TypedIntToFloat", types.TypeAndValue{Type: types.Typ[types.Int]}, types.Typ[types.Float64], false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isArgAssignableTo(tt.tv, tt.paramType))
+		})
+	}
 }
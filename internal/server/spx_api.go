@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/types"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/goplus/xgolsw/pkgdoc"
+	"github.com/goplus/xgolsw/xgo/xgoutil"
+)
+
+// spxAPICategories are the categories of spx APIs, in the order they are
+// returned by the spx.getSpriteAPIs command.
+var spxAPICategories = []SpxAPICategoryName{
+	SpxAPICategoryMotion,
+	SpxAPICategoryLooks,
+	SpxAPICategorySound,
+	SpxAPICategoryEvents,
+	SpxAPICategoryControl,
+	SpxAPICategorySensing,
+	SpxAPICategoryOther,
+}
+
+// spxAPICategoryOf returns the category of the spx API with the given name,
+// such as "turn" for Sprite.turn, as recorded in spxPkgDoc by the package
+// data generator for the documented spx version. Event handlers, such as
+// "onStart", not recorded there are categorized by their names instead, and
+// other APIs are in [SpxAPICategoryOther].
+func spxAPICategoryOf(spxPkgDoc *pkgdoc.PkgDoc, name string) SpxAPICategoryName {
+	if spxPkgDoc != nil {
+		if category := SpxAPICategoryName(spxPkgDoc.Categories[name]); slices.Contains(spxAPICategories, category) {
+			return category
+		}
+	}
+	if IsSpxEventHandlerFuncName(name) {
+		return SpxAPICategoryEvents
+	}
+	return SpxAPICategoryOther
+}
+
+// spxGetSpriteAPIs returns the spx APIs available in the context of an spx
+// source file, which are the spx members of its class, categorized the way
+// they are in a block palette. Members declared in the project are excluded.
+func (s *Server) spxGetSpriteAPIs(ctx context.Context, params []SpxGetSpriteAPIsParams) ([]SpxAPICategory, error) {
+	if l := len(params); l == 0 {
+		return nil, nil
+	} else if l > 1 {
+		return nil, errors.New("spx.getSpriteAPIs only supports one document at a time")
+	}
+	param := params[0]
+
+	result, spxFile, astFile, err := s.compileAndGetASTFileForDocumentURI(ctx, param.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	if astFile == nil {
+		return nil, nil
+	}
+	typeInfo, _ := result.proj.TypeInfo()
+	if typeInfo == nil {
+		return nil, nil
+	}
+
+	className := strings.TrimSuffix(path.Base(spxFile), ".spx")
+	if spxFile == result.mainSpxFile {
+		className = "Game"
+	}
+	obj := typeInfo.Pkg().Scope().Lookup(className)
+	if obj == nil {
+		return nil, fmt.Errorf("class %q of file %q not found", className, spxFile)
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok || !xgoutil.IsNamedStructType(named) {
+		return nil, fmt.Errorf("class %q of file %q is not a struct type", className, spxFile)
+	}
+
+	spxPkgDoc, _ := result.pkgDoc(SpxPkgPath)
+	apis := make(map[SpxAPICategoryName][]SpxAPIInfo, len(spxAPICategories))
+	seenIDs := make(map[string]struct{})
+	for _, spxDef := range result.spxDefinitionsForNamedStruct(named) {
+		if spxDef.ID.Package == nil || *spxDef.ID.Package != SpxPkgPath || spxDef.ID.Name == nil {
+			continue
+		}
+		if _, ok := seenIDs[spxDef.ID.String()]; ok {
+			continue
+		}
+		seenIDs[spxDef.ID.String()] = struct{}{}
+
+		name := *spxDef.ID.Name
+		if idx := strings.LastIndex(name, "."); idx >= 0 {
+			name = name[idx+1:]
+		}
+		info := SpxAPIInfo{
+			ID:       spxDef.ID,
+			Overview: spxDef.Overview,
+			Detail:   spxDef.Detail,
+			Kind:     spxDef.CompletionItemKind,
+		}
+		if spxDef.TypeHint != nil {
+			info.Type = GetSimplifiedTypeString(spxDef.TypeHint)
+		}
+		category := spxAPICategoryOf(spxPkgDoc, name)
+		apis[category] = append(apis[category], info)
+	}
+
+	categories := make([]SpxAPICategory, 0, len(spxAPICategories))
+	for _, category := range spxAPICategories {
+		categories = append(categories, SpxAPICategory{
+			Name: category,
+			APIs: append([]SpxAPIInfo{}, apis[category]...),
+		})
+	}
+	return categories, nil
+}
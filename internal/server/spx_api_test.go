@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/goplus/xgolsw/internal/pkgdata"
+	"github.com/goplus/xgolsw/pkgdoc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerSpxGetSpriteAPIs(t *testing.T) {
+	m := map[string][]byte{
+		"main.spx": []byte(`
+var (
+	MySprite Sprite
+)
+run "assets", {Title: "My Game"}
+`),
+		"MySprite.spx": []byte(`
+func jump() {}
+
+onStart => {}
+`),
+		"assets/index.json":                  []byte(`{}`),
+		"assets/sprites/MySprite/index.json": []byte(`{}`),
+	}
+	apiNames := func(categories []SpxAPICategory, name SpxAPICategoryName) []string {
+		idx := slices.IndexFunc(categories, func(category SpxAPICategory) bool { return category.Name == name })
+		require.GreaterOrEqual(t, idx, 0)
+		var names []string
+		for _, api := range categories[idx].APIs {
+			names = append(names, *api.ID.Name)
+		}
+		return names
+	}
+
+	t.Run("Sprite", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		arg, err := json.Marshal(SpxGetSpriteAPIsParams{
+			TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"},
+		})
+		require.NoError(t, err)
+		result, err := s.workspaceExecuteCommand(context.Background(), &ExecuteCommandParams{
+			Command:   "spx.getSpriteAPIs",
+			Arguments: []json.RawMessage{arg},
+		})
+		require.NoError(t, err)
+		categories, ok := result.([]SpxAPICategory)
+		require.True(t, ok)
+
+		var names []SpxAPICategoryName
+		for _, category := range categories {
+			names = append(names, category.Name)
+		}
+		assert.Equal(t, spxAPICategories, names)
+		assert.Contains(t, apiNames(categories, SpxAPICategoryMotion), "Sprite.turn")
+		assert.Contains(t, apiNames(categories, SpxAPICategoryLooks), "Sprite.say")
+		assert.Contains(t, apiNames(categories, SpxAPICategoryLooks), "Game.startBackdrop")
+		assert.Contains(t, apiNames(categories, SpxAPICategorySound), "Sprite.play")
+		assert.Contains(t, apiNames(categories, SpxAPICategoryEvents), "Sprite.onStart")
+		assert.Contains(t, apiNames(categories, SpxAPICategoryEvents), "Game.broadcast")
+		assert.Contains(t, apiNames(categories, SpxAPICategoryControl), "Game.wait")
+		assert.Contains(t, apiNames(categories, SpxAPICategorySensing), "Sprite.touching")
+
+		seenIDs := make(map[string]struct{})
+		for _, category := range categories {
+			for _, api := range category.APIs {
+				assert.Equal(t, SpxPkgPath, *api.ID.Package)
+				assert.NotEmpty(t, api.Overview)
+				assert.NotContains(t, seenIDs, api.ID.String())
+				seenIDs[api.ID.String()] = struct{}{}
+			}
+		}
+		assert.NotContains(t, seenIDs, "xgo:main?MySprite.jump")
+	})
+
+	t.Run("MainSpx", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		categories, err := s.spxGetSpriteAPIs(context.Background(), []SpxGetSpriteAPIsParams{
+			{TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"}},
+		})
+		require.NoError(t, err)
+		assert.Contains(t, apiNames(categories, SpxAPICategoryLooks), "Game.startBackdrop")
+		assert.NotContains(t, apiNames(categories, SpxAPICategoryMotion), "Sprite.turn")
+	})
+
+	t.Run("NoParams", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		categories, err := s.spxGetSpriteAPIs(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Nil(t, categories)
+	})
+
+	t.Run("MultipleDocuments", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := s.spxGetSpriteAPIs(context.Background(), []SpxGetSpriteAPIsParams{
+			{TextDocument: TextDocumentIdentifier{URI: "file:///main.spx"}},
+			{TextDocument: TextDocumentIdentifier{URI: "file:///MySprite.spx"}},
+		})
+		assert.EqualError(t, err, "spx.getSpriteAPIs only supports one document at a time")
+	})
+
+	t.Run("NonSpxFile", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+
+		_, err := s.spxGetSpriteAPIs(context.Background(), []SpxGetSpriteAPIsParams{
+			{TextDocument: TextDocumentIdentifier{URI: "file:///assets/index.json"}},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestSpxAPICategoryOf(t *testing.T) {
+	spxPkgDoc := &pkgdoc.PkgDoc{
+		Categories: map[string]string{
+			"glide":     "motion",
+			"broadcast": "events",
+			"penDown":   "pen",
+		},
+	}
+
+	t.Run("Recorded", func(t *testing.T) {
+		assert.Equal(t, SpxAPICategoryMotion, spxAPICategoryOf(spxPkgDoc, "glide"))
+		assert.Equal(t, SpxAPICategoryEvents, spxAPICategoryOf(spxPkgDoc, "broadcast"))
+	})
+
+	t.Run("EventHandler", func(t *testing.T) {
+		assert.Equal(t, SpxAPICategoryEvents, spxAPICategoryOf(spxPkgDoc, "onKey"))
+		assert.Equal(t, SpxAPICategoryEvents, spxAPICategoryOf(nil, "onStart"))
+	})
+
+	t.Run("Other", func(t *testing.T) {
+		assert.Equal(t, SpxAPICategoryOther, spxAPICategoryOf(spxPkgDoc, "setVolume"))
+		assert.Equal(t, SpxAPICategoryOther, spxAPICategoryOf(spxPkgDoc, "penDown"))
+		assert.Equal(t, SpxAPICategoryOther, spxAPICategoryOf(nil, "glide"))
+	})
+
+	t.Run("EmbeddedPkgDoc", func(t *testing.T) {
+		spxPkgDoc, err := pkgdata.GetPkgDoc(SpxPkgPath)
+		require.NoError(t, err)
+		assert.Equal(t, SpxAPICategoryMotion, spxAPICategoryOf(spxPkgDoc, "turn"))
+		assert.Equal(t, SpxAPICategoryLooks, spxAPICategoryOf(spxPkgDoc, "setCostume"))
+		assert.Equal(t, SpxAPICategorySound, spxAPICategoryOf(spxPkgDoc, "setVolume"))
+		assert.Equal(t, SpxAPICategoryControl, spxAPICategoryOf(spxPkgDoc, "clone"))
+		assert.Equal(t, SpxAPICategorySensing, spxAPICategoryOf(spxPkgDoc, "keyPressed"))
+		assert.Equal(t, SpxAPICategoryOther, spxAPICategoryOf(spxPkgDoc, "penDown"))
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"unsafe"
 
 	"github.com/goplus/xgolsw/internal"
 	"github.com/goplus/xgolsw/internal/pkgdata"
@@ -35,6 +36,42 @@ func (def SpxDefinition) HTML() string {
 	return fmt.Sprintf("<pre is=\"definition-item\" def-id=%q overview=%q>\n%s</pre>\n", template.HTMLEscapeString(def.ID.String()), template.HTMLEscapeString(def.Overview), def.Detail)
 }
 
+// Markdown returns the Markdown representation of the definition, which
+// unlike [SpxDefinition.HTML] contains no embedded HTML.
+func (def SpxDefinition) Markdown() string {
+	var sb strings.Builder
+	if def.Overview != "" {
+		sb.WriteString("```xgo\n")
+		sb.WriteString(def.Overview)
+		sb.WriteString("\n```\n")
+	}
+	if def.Detail != "" {
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(strings.TrimSuffix(def.Detail, "\n"))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// PlainText returns the plain text representation of the definition.
+func (def SpxDefinition) PlainText() string {
+	var sb strings.Builder
+	if def.Overview != "" {
+		sb.WriteString(def.Overview)
+		sb.WriteString("\n")
+	}
+	if def.Detail != "" {
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(strings.TrimSuffix(def.Detail, "\n"))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
 // CompletionItem constructs a [CompletionItem] from the definition.
 func (def SpxDefinition) CompletionItem() CompletionItem {
 	return CompletionItem{
@@ -49,6 +86,54 @@ func (def SpxDefinition) CompletionItem() CompletionItem {
 	}
 }
 
+// formatSpxDefinitionDetail formats the given documentation as the detail of
+// an spx definition, with the deprecation notice first and the examples as
+// code blocks last. The given runnable examples follow the ones in the
+// documentation, each with its expected output, if any.
+func formatSpxDefinitionDetail(doc string, examples []pkgdoc.Example) string {
+	notes := pkgdoc.ParseNotes(doc)
+	if notes.Deprecated == "" && len(notes.Examples) == 0 && len(examples) == 0 {
+		return doc
+	}
+
+	var sb strings.Builder
+	if notes.Deprecated != "" {
+		sb.WriteString("**Deprecated:** ")
+		sb.WriteString(notes.Deprecated)
+		sb.WriteString("\n")
+	}
+	if notes.Text != "" {
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(notes.Text)
+	}
+	writeCodeBlock := func(code, output string) {
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("```go\n")
+		sb.WriteString(code)
+		sb.WriteString("\n")
+		if output != "" {
+			sb.WriteString("\n// Output:\n")
+			for line := range strings.Lines(strings.TrimSuffix(output, "\n")) {
+				sb.WriteString("// ")
+				sb.WriteString(strings.TrimSuffix(line, "\n"))
+				sb.WriteString("\n")
+			}
+		}
+		sb.WriteString("```\n")
+	}
+	for _, example := range notes.Examples {
+		writeCodeBlock(example, "")
+	}
+	for _, example := range examples {
+		writeCodeBlock(example.Code, example.Output)
+	}
+	return sb.String()
+}
+
 var (
 	// GeneralSpxDefinitions are general spx definitions.
 	GeneralSpxDefinitions = []SpxDefinition{
@@ -286,7 +371,7 @@ func GetSpxDefinitionForBuiltinObj(obj types.Object) SpxDefinition {
 			Name:    &idName,
 		},
 		Overview: overview,
-		Detail:   detail,
+		Detail:   formatSpxDefinitionDetail(detail, nil),
 
 		CompletionItemLabel:            obj.Name(),
 		CompletionItemKind:             completionItemKind,
@@ -527,52 +612,160 @@ var (
 		spxPkg := GetSpxPkg()
 		return spxPkg.Scope().Lookup("Gopt_Game_Run").(*types.Func)
 	})
+
+	// GetSpxGoptGameGopxGetWidgetFunc returns the [spx.Gopt_Game_Gopx_GetWidget] type.
+	GetSpxGoptGameGopxGetWidgetFunc = sync.OnceValue(func() *types.Func {
+		spxPkg := GetSpxPkg()
+		return spxPkg.Scope().Lookup("Gopt_Game_Gopx_GetWidget").(*types.Func)
+	})
+
+	// GetSpxSpriteImplSetCostumeByIndexFuncs returns the overloads of
+	// [spx.SpriteImpl.SetCostume] that select a costume by index.
+	GetSpxSpriteImplSetCostumeByIndexFuncs = sync.OnceValue(func() []*types.Func {
+		spriteImplType := GetSpxSpriteImplType()
+		var funcs []*types.Func
+		for _, name := range []string{"SetCostume__1", "SetCostume__2"} {
+			obj, _, _ := types.LookupFieldOrMethod(spriteImplType, true, spriteImplType.Obj().Pkg(), name)
+			funcs = append(funcs, obj.(*types.Func))
+		}
+		return funcs
+	})
+
+	// GetSpxMonitorType returns the [spx.Monitor] type.
+	GetSpxMonitorType = sync.OnceValue(func() *types.Named {
+		spxPkg := GetSpxPkg()
+		return spxPkg.Scope().Lookup("Monitor").Type().(*types.Named)
+	})
 )
 
-// nonMainPkgSpxDefsCache is a cache of non-main package spx definitions.
-var nonMainPkgSpxDefsCache sync.Map // map[*types.Package][]SpxDefinition
+// nonMainPkgSpxDefCache is a cache of spx definitions of non-main packages.
+// They are shared by all servers, as non-main packages are imported by the
+// global importer.
+//
+// It maps [nonMainPkgSpxDefCacheKey]s of *types.Package to []SpxDefinition,
+// and those of *types.Const, *types.TypeName, *types.PkgName, and the
+// nonMainPkgSpxDefCacheFor*Key keys to SpxDefinition.
+var nonMainPkgSpxDefCache = newLRUCache[nonMainPkgSpxDefCacheKey, any](DefaultCacheMemoryBudget, sizeOfNonMainPkgSpxDefCacheValue)
+
+// DefaultCacheMemoryBudget is the default of the budget set by
+// [SetCacheMemoryBudget], in bytes.
+const DefaultCacheMemoryBudget = 64 << 20
+
+// SetCacheMemoryBudget sets the approximate amount of memory in bytes that
+// caches kept across compilations, such as spx definitions of imported
+// packages, may use. Least recently used entries are evicted once it is
+// exceeded. The caches are shared by all servers in the process, so the
+// budget is set for the process rather than by the settings of a server.
+func SetCacheMemoryBudget(budget int64) {
+	nonMainPkgSpxDefCache.setBudget(budget)
+}
+
+// sizeOfNonMainPkgSpxDefCacheValue returns the approximate size in bytes of a
+// value in [nonMainPkgSpxDefCache].
+func sizeOfNonMainPkgSpxDefCacheValue(v any) int64 {
+	switch v := v.(type) {
+	case SpxDefinition:
+		return sizeOfSpxDefinition(v)
+	case []SpxDefinition:
+		var size int64
+		for _, def := range v {
+			size += sizeOfSpxDefinition(def)
+		}
+		return size
+	}
+	return 0
+}
+
+// sizeOfSpxDefinition returns the approximate size in bytes of def, not
+// counting its type hint, which is owned by the type checker.
+func sizeOfSpxDefinition(def SpxDefinition) int64 {
+	size := int64(unsafe.Sizeof(def))
+	for _, s := range []*string{def.ID.Package, def.ID.Name, def.ID.OverloadID} {
+		if s != nil {
+			size += int64(unsafe.Sizeof(*s) + uintptr(len(*s)))
+		}
+	}
+	size += int64(len(def.Overview) + len(def.Detail) + len(def.CompletionItemLabel) + len(def.CompletionItemInsertText))
+	return size
+}
+
+// nonMainPkgSpxDefCacheKey is the key for [nonMainPkgSpxDefCache]. The same
+// object may be documented by the package docs of different spx versions, so
+// the package doc used to build the definitions is part of the key.
+type nonMainPkgSpxDefCacheKey struct {
+	key    any
+	pkgDoc *pkgdoc.PkgDoc
+}
+
+// completionIndexFor returns the completion index of pkg. Indexes of the
+// packages imported from pkgdata are cached for the lifetime of the process,
+// while those of other packages, such as local packages and packages of
+// third-party modules, are built on demand, as they are type checked again on
+// every compile.
+func completionIndexFor(pkg *types.Package, pkgDoc *pkgdoc.PkgDoc) *pkgdata.CompletionIndex {
+	if internal.Importer.Imported(pkg) {
+		return pkgdata.GetCompletionIndex(pkg)
+	}
+	return pkgdata.NewCompletionIndex(pkg, pkgDoc)
+}
 
 // GetSpxDefinitionsForPkg returns the spx definitions for the given package.
 func GetSpxDefinitionsForPkg(pkg *types.Package, pkgDoc *pkgdoc.PkgDoc) (defs []SpxDefinition) {
 	if !xgoutil.IsMainPkg(pkg) {
-		if defsIface, ok := nonMainPkgSpxDefsCache.Load(pkg); ok {
+		cacheKey := nonMainPkgSpxDefCacheKey{pkg, pkgDoc}
+		if defsIface, ok := nonMainPkgSpxDefCache.get(cacheKey); ok {
 			return defsIface.([]SpxDefinition)
 		}
 		defer func() {
-			nonMainPkgSpxDefsCache.Store(pkg, defs)
+			nonMainPkgSpxDefCache.put(cacheKey, defs)
 		}()
 	}
 
+	if !xgoutil.IsMainPkg(pkg) {
+		entries := completionIndexFor(pkg, pkgDoc).Entries
+		defs = make([]SpxDefinition, 0, len(entries))
+		for _, entry := range entries {
+			defs = append(defs, getSpxDefinitionForObj(entry.Object, pkgDoc)...)
+		}
+		return defs
+	}
+
 	names := pkg.Scope().Names()
 	defs = make([]SpxDefinition, 0, len(names))
 	for _, name := range names {
 		if obj := pkg.Scope().Lookup(name); obj != nil && obj.Exported() {
-			switch obj := obj.(type) {
-			case *types.Var:
-				defs = append(defs, GetSpxDefinitionForVar(obj, "", false, pkgDoc))
-			case *types.Const:
-				defs = append(defs, GetSpxDefinitionForConst(obj, pkgDoc))
-			case *types.TypeName:
-				defs = append(defs, GetSpxDefinitionForType(obj, pkgDoc))
-			case *types.Func:
-				if funcOverloads := xgoutil.ExpandXGoOverloadableFunc(obj); funcOverloads != nil {
+			if fun, ok := obj.(*types.Func); ok {
+				if funcOverloads := xgoutil.ExpandXGoOverloadableFunc(fun); funcOverloads != nil {
 					for _, funcOverload := range funcOverloads {
-						defs = append(defs, GetSpxDefinitionForFunc(funcOverload, "", pkgDoc))
+						defs = append(defs, getSpxDefinitionForObj(funcOverload, pkgDoc)...)
 					}
-				} else {
-					defs = append(defs, GetSpxDefinitionForFunc(obj, "", pkgDoc))
+					continue
 				}
-			case *types.PkgName:
-				defs = append(defs, GetSpxDefinitionForPkg(obj, pkgDoc))
 			}
+			defs = append(defs, getSpxDefinitionForObj(obj, pkgDoc)...)
 		}
 	}
 	return slices.Clip(defs)
 }
 
-// nonMainPkgSpxDefCacheForVars is a cache of non-main package spx definitions
-// for variables.
-var nonMainPkgSpxDefCacheForVars sync.Map // map[nonMainPkgSpxDefCacheForVarsKey]SpxDefinition
+// getSpxDefinitionForObj returns the spx definition for the provided package
+// level object, or nil if obj is not a variable, constant, type, function, or
+// package name.
+func getSpxDefinitionForObj(obj types.Object, pkgDoc *pkgdoc.PkgDoc) []SpxDefinition {
+	switch obj := obj.(type) {
+	case *types.Var:
+		return []SpxDefinition{GetSpxDefinitionForVar(obj, "", false, pkgDoc)}
+	case *types.Const:
+		return []SpxDefinition{GetSpxDefinitionForConst(obj, pkgDoc)}
+	case *types.TypeName:
+		return []SpxDefinition{GetSpxDefinitionForType(obj, pkgDoc)}
+	case *types.Func:
+		return []SpxDefinition{GetSpxDefinitionForFunc(obj, "", pkgDoc)}
+	case *types.PkgName:
+		return []SpxDefinition{GetSpxDefinitionForPkg(obj, pkgDoc)}
+	}
+	return nil
+}
 
 // nonMainPkgSpxDefCacheForVarsKey is the key for the non-main package spx
 // definition cache for variables.
@@ -584,15 +777,15 @@ type nonMainPkgSpxDefCacheForVarsKey struct {
 // GetSpxDefinitionForVar returns the spx definition for the provided variable.
 func GetSpxDefinitionForVar(v *types.Var, selectorTypeName string, forceVar bool, pkgDoc *pkgdoc.PkgDoc) (def SpxDefinition) {
 	if !xgoutil.IsInMainPkg(v) {
-		cacheKey := nonMainPkgSpxDefCacheForVarsKey{
+		cacheKey := nonMainPkgSpxDefCacheKey{nonMainPkgSpxDefCacheForVarsKey{
 			v:                v,
 			selectorTypeName: selectorTypeName,
-		}
-		if defIface, ok := nonMainPkgSpxDefCacheForVars.Load(cacheKey); ok {
+		}, pkgDoc}
+		if defIface, ok := nonMainPkgSpxDefCache.get(cacheKey); ok {
 			return defIface.(SpxDefinition)
 		}
 		defer func() {
-			nonMainPkgSpxDefCacheForVars.Store(cacheKey, def)
+			nonMainPkgSpxDefCache.put(cacheKey, def)
 		}()
 	}
 
@@ -639,7 +832,7 @@ func GetSpxDefinitionForVar(v *types.Var, selectorTypeName string, forceVar bool
 			Name:    &idName,
 		},
 		Overview: overview.String(),
-		Detail:   detail,
+		Detail:   formatSpxDefinitionDetail(detail, nil),
 
 		CompletionItemLabel:            v.Name(),
 		CompletionItemKind:             completionItemKind,
@@ -649,26 +842,25 @@ func GetSpxDefinitionForVar(v *types.Var, selectorTypeName string, forceVar bool
 	return
 }
 
-// nonMainPkgSpxDefCacheForConsts is a cache of non-main package spx definitions
-// for constants.
-var nonMainPkgSpxDefCacheForConsts sync.Map // map[*types.Const]SpxDefinition
-
 // GetSpxDefinitionForConst returns the spx definition for the provided constant.
 func GetSpxDefinitionForConst(c *types.Const, pkgDoc *pkgdoc.PkgDoc) (def SpxDefinition) {
 	if !xgoutil.IsInMainPkg(c) {
-		if defIface, ok := nonMainPkgSpxDefCacheForConsts.Load(c); ok {
+		cacheKey := nonMainPkgSpxDefCacheKey{c, pkgDoc}
+		if defIface, ok := nonMainPkgSpxDefCache.get(cacheKey); ok {
 			return defIface.(SpxDefinition)
 		}
 		defer func() {
-			nonMainPkgSpxDefCacheForConsts.Store(c, def)
+			nonMainPkgSpxDefCache.put(cacheKey, def)
 		}()
 	}
 
 	var overview strings.Builder
 	overview.WriteString("const ")
 	overview.WriteString(c.Name())
+	overview.WriteString(" ")
+	overview.WriteString(GetSimplifiedTypeString(c.Type()))
 	overview.WriteString(" = ")
-	overview.WriteString(c.Val().String())
+	overview.WriteString(formatConstantValue(c.Val()))
 
 	var detail string
 	if pkgDoc != nil {
@@ -683,7 +875,7 @@ func GetSpxDefinitionForConst(c *types.Const, pkgDoc *pkgdoc.PkgDoc) (def SpxDef
 			Name:    ToPtr(c.Name()),
 		},
 		Overview: overview.String(),
-		Detail:   detail,
+		Detail:   formatSpxDefinitionDetail(detail, nil),
 
 		CompletionItemLabel:            c.Name(),
 		CompletionItemKind:             ConstantCompletion,
@@ -693,18 +885,15 @@ func GetSpxDefinitionForConst(c *types.Const, pkgDoc *pkgdoc.PkgDoc) (def SpxDef
 	return
 }
 
-// nonMainPkgSpxDefCacheForTypes is a cache of non-main package spx definitions
-// for types.
-var nonMainPkgSpxDefCacheForTypes sync.Map // map[*types.TypeName]SpxDefinition
-
 // GetSpxDefinitionForType returns the spx definition for the provided type.
 func GetSpxDefinitionForType(typeName *types.TypeName, pkgDoc *pkgdoc.PkgDoc) (def SpxDefinition) {
 	if !xgoutil.IsInMainPkg(typeName) {
-		if defIface, ok := nonMainPkgSpxDefCacheForTypes.Load(typeName); ok {
+		cacheKey := nonMainPkgSpxDefCacheKey{typeName, pkgDoc}
+		if defIface, ok := nonMainPkgSpxDefCache.get(cacheKey); ok {
 			return defIface.(SpxDefinition)
 		}
 		defer func() {
-			nonMainPkgSpxDefCacheForTypes.Store(typeName, def)
+			nonMainPkgSpxDefCache.put(cacheKey, def)
 		}()
 	}
 
@@ -712,12 +901,16 @@ func GetSpxDefinitionForType(typeName *types.TypeName, pkgDoc *pkgdoc.PkgDoc) (d
 	overview.WriteString("type ")
 	overview.WriteString(typeName.Name())
 
-	var detail string
+	var (
+		detail   string
+		examples []pkgdoc.Example
+	)
 	if pkgDoc != nil {
 		typeDoc, ok := pkgDoc.Types[typeName.Name()]
 		if ok {
 			detail = typeDoc.Doc
 		}
+		examples = pkgDoc.Examples[typeName.Name()]
 	}
 
 	completionKind := ClassCompletion
@@ -738,7 +931,7 @@ func GetSpxDefinitionForType(typeName *types.TypeName, pkgDoc *pkgdoc.PkgDoc) (d
 			Name:    ToPtr(typeName.Name()),
 		},
 		Overview: overview.String(),
-		Detail:   detail,
+		Detail:   formatSpxDefinitionDetail(detail, examples),
 
 		CompletionItemLabel:            typeName.Name(),
 		CompletionItemKind:             completionKind,
@@ -748,10 +941,6 @@ func GetSpxDefinitionForType(typeName *types.TypeName, pkgDoc *pkgdoc.PkgDoc) (d
 	return
 }
 
-// nonMainPkgSpxDefCacheForFuncs is a cache of non-main package spx definitions
-// for functions.
-var nonMainPkgSpxDefCacheForFuncs sync.Map // map[nonMainPkgSpxDefCacheForFuncsKey]SpxDefinition
-
 // nonMainPkgSpxDefCacheForFuncsKey is the key for the non-main package spx
 // definition cache for functions.
 type nonMainPkgSpxDefCacheForFuncsKey struct {
@@ -762,15 +951,15 @@ type nonMainPkgSpxDefCacheForFuncsKey struct {
 // GetSpxDefinitionForFunc returns the spx definition for the provided function.
 func GetSpxDefinitionForFunc(fun *types.Func, recvTypeName string, pkgDoc *pkgdoc.PkgDoc) (def SpxDefinition) {
 	if !xgoutil.IsInMainPkg(fun) {
-		cacheKey := nonMainPkgSpxDefCacheForFuncsKey{
+		cacheKey := nonMainPkgSpxDefCacheKey{nonMainPkgSpxDefCacheForFuncsKey{
 			fun:          fun,
 			recvTypeName: recvTypeName,
-		}
-		if defIface, ok := nonMainPkgSpxDefCacheForFuncs.Load(cacheKey); ok {
+		}, pkgDoc}
+		if defIface, ok := nonMainPkgSpxDefCache.get(cacheKey); ok {
 			return defIface.(SpxDefinition)
 		}
 		defer func() {
-			nonMainPkgSpxDefCacheForFuncs.Store(cacheKey, def)
+			nonMainPkgSpxDefCache.put(cacheKey, def)
 		}()
 	}
 
@@ -783,13 +972,28 @@ func GetSpxDefinitionForFunc(fun *types.Func, recvTypeName string, pkgDoc *pkgdo
 		recvTypeName = parsedRecvTypeName
 	}
 
-	var detail string
+	var (
+		detail   string
+		examples []pkgdoc.Example
+	)
 	if pkgDoc != nil {
 		funcName := fun.Name()
 		if recvTypeName == "" || xgoutil.IsXGotMethodName(funcName) {
 			detail = pkgDoc.Funcs[funcName]
-		} else if typeDoc, ok := pkgDoc.Types[recvTypeName]; ok {
-			detail = typeDoc.Methods[funcName]
+			examples = pkgDoc.Examples[funcName]
+		} else {
+			if typeDoc, ok := pkgDoc.Types[recvTypeName]; ok {
+				detail = typeDoc.Methods[funcName]
+			}
+			examples = pkgDoc.Examples[recvTypeName+"."+funcName]
+		}
+
+		// An overload without its own documentation is documented by
+		// the Gopo_ constant that declares its overload group.
+		if detail == "" {
+			if overloadDoc, ok := pkgDoc.OverloadDocOf(parsedRecvTypeName, funcName); ok {
+				detail = overloadDoc.Doc
+			}
 		}
 	}
 
@@ -810,7 +1014,7 @@ func GetSpxDefinitionForFunc(fun *types.Func, recvTypeName string, pkgDoc *pkgdo
 			OverloadID: overloadID,
 		},
 		Overview: overview,
-		Detail:   detail,
+		Detail:   formatSpxDefinitionDetail(detail, examples),
 
 		CompletionItemLabel:            parsedName,
 		CompletionItemKind:             FunctionCompletion,
@@ -907,24 +1111,25 @@ func makeSpxDefinitionOverviewForFunc(fun *types.Func) (overview, parsedRecvType
 	return
 }
 
-// nonMainPkgSpxDefCacheForPkgs is a cache of non-main package spx definitions
-// for packages.
-var nonMainPkgSpxDefCacheForPkgs sync.Map // map[*types.PkgName]SpxDefinition
-
 // GetSpxDefinitionForPkg returns the spx definition for the provided package.
 func GetSpxDefinitionForPkg(pkgName *types.PkgName, pkgDoc *pkgdoc.PkgDoc) (def SpxDefinition) {
 	if !xgoutil.IsInMainPkg(pkgName) {
-		if defIface, ok := nonMainPkgSpxDefCacheForPkgs.Load(pkgName); ok {
+		cacheKey := nonMainPkgSpxDefCacheKey{pkgName, pkgDoc}
+		if defIface, ok := nonMainPkgSpxDefCache.get(cacheKey); ok {
 			return defIface.(SpxDefinition)
 		}
 		defer func() {
-			nonMainPkgSpxDefCacheForPkgs.Store(pkgName, def)
+			nonMainPkgSpxDefCache.put(cacheKey, def)
 		}()
 	}
 
-	var detail string
+	var (
+		detail   string
+		examples []pkgdoc.Example
+	)
 	if pkgDoc != nil {
 		detail = pkgDoc.Doc
+		examples = pkgDoc.Examples[""]
 	}
 
 	def = SpxDefinition{
@@ -934,7 +1139,7 @@ func GetSpxDefinitionForPkg(pkgName *types.PkgName, pkgDoc *pkgdoc.PkgDoc) (def
 			Package: ToPtr(xgoutil.PkgPath(pkgName.Pkg())),
 		},
 		Overview: "package " + pkgName.Name(),
-		Detail:   detail,
+		Detail:   formatSpxDefinitionDetail(detail, examples),
 
 		CompletionItemLabel:            pkgName.Name(),
 		CompletionItemKind:             ModuleCompletion,
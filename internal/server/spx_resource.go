@@ -1,6 +1,7 @@
 package server
 
 import (
+	"cmp"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"path"
 	"slices"
 	"strings"
+	"time"
 
 	xgoast "github.com/goplus/xgo/ast"
 	"github.com/goplus/xgolsw/internal/vfs"
@@ -36,6 +38,7 @@ const (
 	SpxResourceRefKindAutoBinding          SpxResourceRefKind = "autoBinding"
 	SpxResourceRefKindAutoBindingReference SpxResourceRefKind = "autoBindingReference"
 	SpxResourceRefKindConstantReference    SpxResourceRefKind = "constantReference"
+	SpxResourceRefKindIndexLiteral         SpxResourceRefKind = "indexLiteral"
 )
 
 // ParseSpxResourceURI parses an spx resource URI and returns the corresponding
@@ -79,6 +82,16 @@ type SpxResourceSet struct {
 	sounds    map[string]*SpxSoundResource
 	sprites   map[string]*SpxSpriteResource
 	widgets   map[string]*SpxWidgetResource
+
+	// assets is the set of paths, relative to the resource root directory,
+	// of the asset files referenced by backdrops, sounds, and costumes.
+	assets map[string]struct{}
+}
+
+// count returns the number of backdrops, sounds, sprites, and widgets in the
+// set.
+func (set *SpxResourceSet) count() int {
+	return len(set.backdrops) + len(set.sounds) + len(set.sprites) + len(set.widgets)
 }
 
 // NewSpxResourceSet creates a new spx resource set.
@@ -97,11 +110,19 @@ func NewSpxResourceSet(rootFS vfs.SubFS) (*SpxResourceSet, error) {
 		return nil, fmt.Errorf("failed to parse index.json: %w", err)
 	}
 
+	assetPaths := make(map[string]struct{})
+	addAssetPath := func(dir, p string) {
+		if p != "" {
+			assetPaths[path.Join(dir, p)] = struct{}{}
+		}
+	}
+
 	// Process backdrops.
 	backdrops := make(map[string]*SpxBackdropResource, len(assets.Backdrops))
 	for _, backdrop := range assets.Backdrops {
 		backdrop.ID = SpxBackdropResourceID{BackdropName: backdrop.Name}
 		backdrops[backdrop.Name] = &backdrop
+		addAssetPath(".", backdrop.Path)
 	}
 
 	// Process widgets from zorder.
@@ -138,6 +159,7 @@ func NewSpxResourceSet(rootFS vfs.SubFS) (*SpxResourceSet, error) {
 		sound.Name = soundName
 		sound.ID = SpxSoundResourceID{SoundName: soundName}
 		sounds[soundName] = &sound
+		addAssetPath(path.Join("sounds", soundName), sound.Path)
 	}
 
 	// Read sprites directory.
@@ -171,6 +193,7 @@ func NewSpxResourceSet(rootFS vfs.SubFS) (*SpxResourceSet, error) {
 				SpriteName:  spriteName,
 				CostumeName: costume.Name,
 			}
+			addAssetPath(path.Join("sprites", spriteName), costume.Path)
 		}
 
 		// Process animations.
@@ -203,9 +226,45 @@ func NewSpxResourceSet(rootFS vfs.SubFS) (*SpxResourceSet, error) {
 		sounds:    sounds,
 		sprites:   sprites,
 		widgets:   widgets,
+		assets:    assetPaths,
 	}, nil
 }
 
+// HasAsset reports whether the asset file at the given path, relative to the
+// resource root directory, is referenced by a backdrop, sound, or costume.
+func (set *SpxResourceSet) HasAsset(p string) bool {
+	_, ok := set.assets[path.Clean(p)]
+	return ok
+}
+
+// IDs returns the IDs of all resources in the set, including sprite costumes
+// and animations, sorted by their URIs.
+func (set *SpxResourceSet) IDs() []SpxResourceID {
+	var ids []SpxResourceID
+	for _, backdrop := range set.backdrops {
+		ids = append(ids, backdrop.ID)
+	}
+	for _, sound := range set.sounds {
+		ids = append(ids, sound.ID)
+	}
+	for _, sprite := range set.sprites {
+		ids = append(ids, sprite.ID)
+		for _, costume := range sprite.Costumes {
+			ids = append(ids, costume.ID)
+		}
+		for _, animation := range sprite.Animations {
+			ids = append(ids, animation.ID)
+		}
+	}
+	for _, widget := range set.widgets {
+		ids = append(ids, widget.ID)
+	}
+	slices.SortFunc(ids, func(a, b SpxResourceID) int {
+		return cmp.Compare(a.URI(), b.URI())
+	})
+	return ids
+}
+
 // Backdrop returns the backdrop with the given name. It returns nil if not found.
 func (set *SpxResourceSet) Backdrop(name string) *SpxBackdropResource {
 	if set.backdrops == nil {
@@ -240,9 +299,10 @@ func (set *SpxResourceSet) Widget(name string) *SpxWidgetResource {
 
 // SpxBackdropResource represents a backdrop resource in spx.
 type SpxBackdropResource struct {
-	ID   SpxBackdropResourceID `json:"-"`
-	Name string                `json:"name"`
-	Path string                `json:"path"`
+	ID               SpxBackdropResourceID `json:"-"`
+	Name             string                `json:"name"`
+	Path             string                `json:"path"`
+	BitmapResolution int                   `json:"bitmapResolution,omitempty"`
 }
 
 // SpxBackdropResourceID is the ID of an spx backdrop resource.
@@ -270,9 +330,54 @@ func (id SpxBackdropResourceID) ContextURI() SpxResourceContextURI {
 
 // SpxSoundResource represents a sound resource in spx.
 type SpxSoundResource struct {
-	ID   SpxSoundResourceID `json:"-"`
-	Name string             `json:"name"`
-	Path string             `json:"path"`
+	ID          SpxSoundResourceID `json:"-"`
+	Name        string             `json:"name"`
+	Path        string             `json:"path"`
+	Rate        int                `json:"rate,omitempty"`
+	SampleCount int                `json:"sampleCount,omitempty"`
+}
+
+// UnmarshalJSON implements [json.Unmarshaler]. Invalid rate and sample count
+// values are ignored, since they are reported by the metadata validation.
+func (sound *SpxSoundResource) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Name        string          `json:"name"`
+		Path        string          `json:"path"`
+		Rate        json.RawMessage `json:"rate"`
+		SampleCount json.RawMessage `json:"sampleCount"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	sound.Name = raw.Name
+	sound.Path = raw.Path
+	sound.Rate, sound.SampleCount = 0, 0
+	json.Unmarshal(raw.Rate, &sound.Rate)
+	json.Unmarshal(raw.SampleCount, &sound.SampleCount)
+	return nil
+}
+
+// Format returns the audio format of the sound, which is the lowercase
+// extension of its file without the leading dot, e.g., "wav".
+func (sound *SpxSoundResource) Format() string {
+	return strings.ToLower(strings.TrimPrefix(path.Ext(sound.Path), "."))
+}
+
+// Duration returns the duration of the sound computed from its sample count
+// and rate. It returns zero if either is unknown.
+func (sound *SpxSoundResource) Duration() time.Duration {
+	if sound.Rate <= 0 || sound.SampleCount <= 0 {
+		return 0
+	}
+	return time.Duration(float64(sound.SampleCount) / float64(sound.Rate) * float64(time.Second))
+}
+
+// spxSupportedSoundFormats is the set of audio formats supported by the spx
+// runtime.
+var spxSupportedSoundFormats = map[string]bool{
+	"wav": true,
+	"mp3": true,
+	"ogg": true,
 }
 
 // SpxSoundResourceID is the ID of an spx sound resource.
@@ -363,9 +468,10 @@ func (sprite *SpxSpriteResource) Animation(name string) *SpxSpriteAnimationResou
 
 // SpxSpriteCostumeResource represents an spx sprite costume resource.
 type SpxSpriteCostumeResource struct {
-	ID   SpxSpriteCostumeResourceID `json:"-"`
-	Name string                     `json:"name"`
-	Path string                     `json:"path"`
+	ID               SpxSpriteCostumeResourceID `json:"-"`
+	Name             string                     `json:"name"`
+	Path             string                     `json:"path"`
+	BitmapResolution int                        `json:"bitmapResolution,omitempty"`
 }
 
 // SpxSpriteCostumeResourceID is the ID of an spx sprite costume resource.
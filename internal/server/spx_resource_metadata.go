@@ -0,0 +1,334 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/goplus/xgolsw/internal/vfs"
+)
+
+// jsonStringToken is a string token in a JSON document.
+type jsonStringToken struct {
+	// path holds the keys of the objects enclosing the token, from the
+	// outermost one. Array elements are represented by "[]".
+	path []string
+
+	// isKey reports whether the token is an object key rather than a value.
+	isKey bool
+
+	value      string
+	start, end int // Byte offsets of the token, including quotes.
+}
+
+// pathMatches reports whether tok.path matches pattern, where "*" matches any
+// single element.
+func (tok jsonStringToken) pathMatches(pattern ...string) bool {
+	if len(tok.path) != len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if p != "*" && p != tok.path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonStringTokens returns all string tokens in the JSON document content.
+func jsonStringTokens(content []byte) ([]jsonStringToken, error) {
+	type frame struct {
+		isObject  bool
+		expectKey bool
+		key       string
+	}
+	var (
+		stack  []*frame
+		tokens []jsonStringToken
+	)
+	currentPath := func() []string {
+		p := make([]string, 0, len(stack))
+		for _, f := range stack {
+			if f.isObject {
+				p = append(p, f.key)
+			} else {
+				p = append(p, "[]")
+			}
+		}
+		return p
+	}
+	valueDone := func() {
+		if len(stack) > 0 && stack[len(stack)-1].isObject {
+			stack[len(stack)-1].expectKey = true
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(content))
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			if len(stack) > 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return tokens, nil
+		} else if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case json.Delim:
+			switch tok {
+			case '{', '[':
+				stack = append(stack, &frame{isObject: tok == '{', expectKey: tok == '{'})
+			case '}', ']':
+				stack = stack[:len(stack)-1]
+				valueDone()
+			}
+		case string:
+			end := int(dec.InputOffset())
+			strTok := jsonStringToken{
+				value: tok,
+				start: jsonStringTokenStart(content, end),
+				end:   end,
+			}
+			if top := len(stack) - 1; top >= 0 && stack[top].isObject && stack[top].expectKey {
+				stack = stack[:top]
+				strTok.path = currentPath()
+				strTok.isKey = true
+				stack = append(stack[:top], &frame{isObject: true, key: tok})
+			} else {
+				strTok.path = currentPath()
+				valueDone()
+			}
+			tokens = append(tokens, strTok)
+		default:
+			valueDone()
+		}
+	}
+}
+
+// jsonStringTokenStart returns the offset of the opening quote of the string
+// token ending at end in content.
+func jsonStringTokenStart(content []byte, end int) int {
+	for i := end - 2; i >= 0; i-- {
+		if content[i] != '"' {
+			continue
+		}
+		backslashes := 0
+		for j := i - 1; j >= 0 && content[j] == '\\'; j-- {
+			backslashes++
+		}
+		if backslashes%2 == 0 {
+			return i
+		}
+	}
+	return 0
+}
+
+// spxResourceMetadataEdits returns text edits that replace string tokens
+// matching match in the JSON file at filePath with newValue. It returns nil if
+// the file does not exist.
+func (s *Server) spxResourceMetadataEdits(result *compileResult, filePath, newValue string, match func(tok jsonStringToken) bool) (map[DocumentURI][]TextEdit, error) {
+	content, err := vfs.ReadFile(result.proj, filePath)
+	if err != nil {
+		return nil, nil
+	}
+	tokens, err := jsonStringTokens(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+	newText, err := json.Marshal(newValue)
+	if err != nil {
+		return nil, err
+	}
+
+	var textEdits []TextEdit
+	for _, tok := range tokens {
+		if !match(tok) {
+			continue
+		}
+		textEdits = append(textEdits, TextEdit{
+			Range: Range{
+				Start: OffsetPosition(content, tok.start),
+				End:   OffsetPosition(content, tok.end),
+			},
+			NewText: string(newText),
+		})
+	}
+	if len(textEdits) == 0 {
+		return nil, nil
+	}
+	return map[DocumentURI][]TextEdit{s.toDocumentURI(filePath): textEdits}, nil
+}
+
+// spxResourceFileChanges returns the changes to the resource files, such as
+// index.json entries and resource directories, required to rename the spx
+// resource identified by id to newName.
+func (s *Server) spxResourceFileChanges(result *compileResult, id SpxResourceID, newName string) (textEdits map[DocumentURI][]TextEdit, renames []RenameFile, err error) {
+	rootDir := result.spxResourceRootDir
+	rootIndexFile := path.Join(rootDir, "index.json")
+	oldName := id.Name()
+	renameFile := func(oldPath, newPath string) error {
+		for filePath := range result.proj.Files() {
+			if filePath == newPath || strings.HasPrefix(filePath, newPath+"/") {
+				return fmt.Errorf("file %q already exists", newPath)
+			}
+		}
+		renames = append(renames, RenameFile{
+			Kind:   "rename",
+			OldURI: s.toDocumentURI(oldPath),
+			NewURI: s.toDocumentURI(newPath),
+		})
+		return nil
+	}
+
+	switch id := id.(type) {
+	case SpxBackdropResourceID:
+		textEdits, err = s.spxResourceMetadataEdits(result, rootIndexFile, newName, func(tok jsonStringToken) bool {
+			return !tok.isKey && tok.value == oldName && tok.pathMatches("backdrops", "[]", "name")
+		})
+	case SpxSoundResourceID:
+		soundDir := path.Join(rootDir, "sounds", id.SoundName)
+		textEdits, err = s.spxResourceMetadataEdits(result, path.Join(soundDir, "index.json"), newName, func(tok jsonStringToken) bool {
+			return !tok.isKey && tok.value == oldName && tok.pathMatches("name")
+		})
+		if err == nil {
+			err = renameFile(soundDir, path.Join(rootDir, "sounds", newName))
+		}
+	case SpxSpriteResourceID:
+		spriteDir := path.Join(rootDir, "sprites", id.SpriteName)
+		textEdits, err = s.spxResourceMetadataEdits(result, rootIndexFile, newName, func(tok jsonStringToken) bool {
+			return !tok.isKey && tok.value == oldName && tok.pathMatches("zorder", "[]")
+		})
+		if err != nil {
+			break
+		}
+		var spriteTextEdits map[DocumentURI][]TextEdit
+		spriteTextEdits, err = s.spxResourceMetadataEdits(result, path.Join(spriteDir, "index.json"), newName, func(tok jsonStringToken) bool {
+			return !tok.isKey && tok.value == oldName && tok.pathMatches("name")
+		})
+		if err != nil {
+			break
+		}
+		textEdits = mergeTextEdits(textEdits, spriteTextEdits)
+		if err = renameFile(spriteDir, path.Join(rootDir, "sprites", newName)); err != nil {
+			break
+		}
+		if _, ok := result.proj.File(id.SpriteName + ".spx"); ok {
+			err = renameFile(id.SpriteName+".spx", newName+".spx")
+		}
+	case SpxSpriteCostumeResourceID:
+		spriteIndexFile := path.Join(rootDir, "sprites", id.SpriteName, "index.json")
+		textEdits, err = s.spxResourceMetadataEdits(result, spriteIndexFile, newName, func(tok jsonStringToken) bool {
+			return !tok.isKey && tok.value == oldName &&
+				(tok.pathMatches("costumes", "[]", "name") ||
+					tok.pathMatches("fAnimations", "*", "frameFrom") ||
+					tok.pathMatches("fAnimations", "*", "frameTo"))
+		})
+	case SpxSpriteAnimationResourceID:
+		spriteIndexFile := path.Join(rootDir, "sprites", id.SpriteName, "index.json")
+		textEdits, err = s.spxResourceMetadataEdits(result, spriteIndexFile, newName, func(tok jsonStringToken) bool {
+			if tok.value != oldName {
+				return false
+			}
+			if tok.isKey {
+				return tok.pathMatches("fAnimations")
+			}
+			return tok.pathMatches("defaultAnimation") || tok.pathMatches("animBindings", "*")
+		})
+	case SpxWidgetResourceID:
+		textEdits, err = s.spxResourceMetadataEdits(result, rootIndexFile, newName, func(tok jsonStringToken) bool {
+			return !tok.isKey && tok.value == oldName && tok.pathMatches("zorder", "[]", "name")
+		})
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return textEdits, renames, nil
+}
+
+// mergeTextEdits merges the text edits of b into a, and returns a.
+func mergeTextEdits(a, b map[DocumentURI][]TextEdit) map[DocumentURI][]TextEdit {
+	if a == nil {
+		a = make(map[DocumentURI][]TextEdit, len(b))
+	}
+	for documentURI, textEdits := range b {
+		a[documentURI] = append(a[documentURI], textEdits...)
+	}
+	return a
+}
+
+// spxResourceDefinitionLocation returns the location where the spx resource
+// identified by id is defined in its metadata files, which is the name of the
+// resource in the enclosing index.json file. For sprites and sounds, whose
+// metadata files may not include their names, it falls back to the start of
+// their own index.json files. It returns nil if the resource does not exist.
+func (s *Server) spxResourceDefinitionLocation(result *compileResult, id SpxResourceID) *Location {
+	rootDir := result.spxResourceRootDir
+	var (
+		metadataFile string
+		match        func(tok jsonStringToken) bool
+		fallback     bool
+	)
+	name := id.Name()
+	switch id := id.(type) {
+	case SpxBackdropResourceID:
+		metadataFile = path.Join(rootDir, "index.json")
+		match = func(tok jsonStringToken) bool {
+			return !tok.isKey && tok.value == name && tok.pathMatches("backdrops", "[]", "name")
+		}
+	case SpxSoundResourceID:
+		metadataFile = path.Join(rootDir, "sounds", name, "index.json")
+		match = func(tok jsonStringToken) bool {
+			return !tok.isKey && tok.value == name && tok.pathMatches("name")
+		}
+		fallback = true
+	case SpxSpriteResourceID:
+		metadataFile = path.Join(rootDir, "sprites", name, "index.json")
+		match = func(tok jsonStringToken) bool {
+			return !tok.isKey && tok.value == name && tok.pathMatches("name")
+		}
+		fallback = true
+	case SpxSpriteCostumeResourceID:
+		metadataFile = path.Join(rootDir, "sprites", id.SpriteName, "index.json")
+		match = func(tok jsonStringToken) bool {
+			return !tok.isKey && tok.value == name && tok.pathMatches("costumes", "[]", "name")
+		}
+	case SpxSpriteAnimationResourceID:
+		metadataFile = path.Join(rootDir, "sprites", id.SpriteName, "index.json")
+		match = func(tok jsonStringToken) bool {
+			return tok.isKey && tok.value == name && tok.pathMatches("fAnimations")
+		}
+	case SpxWidgetResourceID:
+		metadataFile = path.Join(rootDir, "index.json")
+		match = func(tok jsonStringToken) bool {
+			return !tok.isKey && tok.value == name && tok.pathMatches("zorder", "[]", "name")
+		}
+	default:
+		return nil
+	}
+
+	content, err := vfs.ReadFile(result.proj, metadataFile)
+	if err != nil {
+		return nil
+	}
+	location := &Location{URI: s.toDocumentURI(metadataFile)}
+	if tokens, err := jsonStringTokens(content); err == nil {
+		for _, tok := range tokens {
+			if match(tok) {
+				location.Range = Range{
+					Start: OffsetPosition(content, tok.start),
+					End:   OffsetPosition(content, tok.end),
+				}
+				return location
+			}
+		}
+	}
+	if fallback {
+		return location
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/goplus/xgolsw/xgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONStringTokens(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		content := []byte(`{"name":"a","list":["b",{"c":"d"}],"n":1,"obj":{"e":true,"f":"g"}}`)
+		tokens, err := jsonStringTokens(content)
+		require.NoError(t, err)
+
+		type token struct {
+			path  []string
+			isKey bool
+			value string
+		}
+		var got []token
+		for _, tok := range tokens {
+			assert.Equal(t, `"`+tok.value+`"`, string(content[tok.start:tok.end]))
+			got = append(got, token{path: tok.path, isKey: tok.isKey, value: tok.value})
+		}
+		assert.Equal(t, []token{
+			{path: []string{}, isKey: true, value: "name"},
+			{path: []string{"name"}, value: "a"},
+			{path: []string{}, isKey: true, value: "list"},
+			{path: []string{"list", "[]"}, value: "b"},
+			{path: []string{"list", "[]"}, isKey: true, value: "c"},
+			{path: []string{"list", "[]", "c"}, value: "d"},
+			{path: []string{}, isKey: true, value: "n"},
+			{path: []string{}, isKey: true, value: "obj"},
+			{path: []string{"obj"}, isKey: true, value: "e"},
+			{path: []string{"obj"}, isKey: true, value: "f"},
+			{path: []string{"obj", "f"}, value: "g"},
+		}, got)
+	})
+
+	t.Run("EscapedQuotes", func(t *testing.T) {
+		content := []byte(`{"name":"a\"b\\"}`)
+		tokens, err := jsonStringTokens(content)
+		require.NoError(t, err)
+		require.Len(t, tokens, 2)
+		assert.Equal(t, `a"b\`, tokens[1].value)
+		assert.Equal(t, `"a\"b\\"`, string(content[tokens[1].start:tokens[1].end]))
+	})
+
+	t.Run("InvalidJSON", func(t *testing.T) {
+		_, err := jsonStringTokens([]byte(`{"name":`))
+		require.Error(t, err)
+	})
+}
+
+func TestServerSpxResourceFileChanges(t *testing.T) {
+	newServer := func(t *testing.T) (*Server, *compileResult) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	Sprite1 Sprite
+)
+run "assets", {Title: "My Game"}
+`),
+			"Sprite1.spx":       []byte(``),
+			"assets/index.json": []byte(`{"backdrops":[{"name":"backdrop1","path":"backdrop1.png"}],"zorder":["Sprite1",{"name":"widget1","type":"monitor"}]}`),
+			"assets/sprites/Sprite1/index.json": []byte(`{
+  "name": "Sprite1",
+  "costumes": [{"name": "costume1", "path": "costume1.png"}, {"name": "costume2", "path": "costume2.png"}],
+  "fAnimations": {"anim1": {"frameFrom": "costume1", "frameTo": "costume2"}},
+  "defaultAnimation": "anim1",
+  "animBindings": {"step": "anim1"}
+}`),
+			"assets/sounds/Sound1/index.json": []byte(`{"name":"Sound1","path":"sound1.wav"}`),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		result, err := s.compile()
+		require.NoError(t, err)
+		return s, result
+	}
+
+	t.Run("Backdrop", func(t *testing.T) {
+		s, result := newServer(t)
+
+		textEdits, renames, err := s.spxResourceFileChanges(result, SpxBackdropResourceID{BackdropName: "backdrop1"}, "backdrop2")
+		require.NoError(t, err)
+		assert.Empty(t, renames)
+		assert.Equal(t, map[DocumentURI][]TextEdit{
+			"file:///assets/index.json": {{
+				Range: Range{
+					Start: Position{Line: 0, Character: 22},
+					End:   Position{Line: 0, Character: 33},
+				},
+				NewText: `"backdrop2"`,
+			}},
+		}, textEdits)
+	})
+
+	t.Run("Sound", func(t *testing.T) {
+		s, result := newServer(t)
+
+		textEdits, renames, err := s.spxResourceFileChanges(result, SpxSoundResourceID{SoundName: "Sound1"}, "Sound2")
+		require.NoError(t, err)
+		assert.Equal(t, []RenameFile{{
+			Kind:   "rename",
+			OldURI: "file:///assets/sounds/Sound1",
+			NewURI: "file:///assets/sounds/Sound2",
+		}}, renames)
+		assert.Equal(t, map[DocumentURI][]TextEdit{
+			"file:///assets/sounds/Sound1/index.json": {{
+				Range: Range{
+					Start: Position{Line: 0, Character: 8},
+					End:   Position{Line: 0, Character: 16},
+				},
+				NewText: `"Sound2"`,
+			}},
+		}, textEdits)
+	})
+
+	t.Run("Sprite", func(t *testing.T) {
+		s, result := newServer(t)
+
+		textEdits, renames, err := s.spxResourceFileChanges(result, SpxSpriteResourceID{SpriteName: "Sprite1"}, "Sprite2")
+		require.NoError(t, err)
+		assert.Equal(t, []RenameFile{
+			{
+				Kind:   "rename",
+				OldURI: "file:///assets/sprites/Sprite1",
+				NewURI: "file:///assets/sprites/Sprite2",
+			},
+			{
+				Kind:   "rename",
+				OldURI: "file:///Sprite1.spx",
+				NewURI: "file:///Sprite2.spx",
+			},
+		}, renames)
+		assert.Equal(t, map[DocumentURI][]TextEdit{
+			"file:///assets/index.json": {{
+				Range: Range{
+					Start: Position{Line: 0, Character: 69},
+					End:   Position{Line: 0, Character: 78},
+				},
+				NewText: `"Sprite2"`,
+			}},
+			"file:///assets/sprites/Sprite1/index.json": {{
+				Range: Range{
+					Start: Position{Line: 1, Character: 10},
+					End:   Position{Line: 1, Character: 19},
+				},
+				NewText: `"Sprite2"`,
+			}},
+		}, textEdits)
+	})
+
+	t.Run("SpriteFileAlreadyExists", func(t *testing.T) {
+		s, result := newServer(t)
+		result.proj.PutFile("Sprite2.spx", &xgo.File{})
+
+		_, _, err := s.spxResourceFileChanges(result, SpxSpriteResourceID{SpriteName: "Sprite1"}, "Sprite2")
+		require.Error(t, err)
+	})
+
+	t.Run("Costume", func(t *testing.T) {
+		s, result := newServer(t)
+
+		textEdits, renames, err := s.spxResourceFileChanges(result, SpxSpriteCostumeResourceID{SpriteName: "Sprite1", CostumeName: "costume1"}, "costume3")
+		require.NoError(t, err)
+		assert.Empty(t, renames)
+		assert.Equal(t, map[DocumentURI][]TextEdit{
+			"file:///assets/sprites/Sprite1/index.json": {
+				{
+					Range: Range{
+						Start: Position{Line: 2, Character: 24},
+						End:   Position{Line: 2, Character: 34},
+					},
+					NewText: `"costume3"`,
+				},
+				{
+					Range: Range{
+						Start: Position{Line: 3, Character: 41},
+						End:   Position{Line: 3, Character: 51},
+					},
+					NewText: `"costume3"`,
+				},
+			},
+		}, textEdits)
+	})
+
+	t.Run("Animation", func(t *testing.T) {
+		s, result := newServer(t)
+
+		textEdits, renames, err := s.spxResourceFileChanges(result, SpxSpriteAnimationResourceID{SpriteName: "Sprite1", AnimationName: "anim1"}, "anim2")
+		require.NoError(t, err)
+		assert.Empty(t, renames)
+		spriteTextEdits := textEdits["file:///assets/sprites/Sprite1/index.json"]
+		require.Len(t, spriteTextEdits, 3)
+		assert.Equal(t, Position{Line: 3, Character: 18}, spriteTextEdits[0].Range.Start)
+		assert.Equal(t, Position{Line: 4, Character: 22}, spriteTextEdits[1].Range.Start)
+		assert.Equal(t, Position{Line: 5, Character: 27}, spriteTextEdits[2].Range.Start)
+		for _, textEdit := range spriteTextEdits {
+			assert.Equal(t, `"anim2"`, textEdit.NewText)
+		}
+	})
+
+	t.Run("Widget", func(t *testing.T) {
+		s, result := newServer(t)
+
+		textEdits, renames, err := s.spxResourceFileChanges(result, SpxWidgetResourceID{WidgetName: "widget1"}, "widget2")
+		require.NoError(t, err)
+		assert.Empty(t, renames)
+		assert.Equal(t, map[DocumentURI][]TextEdit{
+			"file:///assets/index.json": {{
+				Range: Range{
+					Start: Position{Line: 0, Character: 87},
+					End:   Position{Line: 0, Character: 96},
+				},
+				NewText: `"widget2"`,
+			}},
+		}, textEdits)
+	})
+}
+
+func TestServerSpxResourceDefinitionLocation(t *testing.T) {
+	m := map[string][]byte{
+		"main.spx": []byte(`
+var (
+	Sprite1 Sprite
+)
+run "assets", {Title: "My Game"}
+`),
+		"Sprite1.spx":       []byte(``),
+		"assets/index.json": []byte(`{"backdrops":[{"name":"backdrop1","path":"backdrop1.png"}],"zorder":["Sprite1",{"name":"widget1","type":"monitor"}]}`),
+		"assets/sprites/Sprite1/index.json": []byte(`{
+  "costumes": [{"name": "costume1", "path": "costume1.png"}],
+  "fAnimations": {"anim1": {"frameFrom": "costume1", "frameTo": "costume1"}}
+}`),
+		"assets/sounds/Sound1/index.json": []byte(`{"name":"Sound1","path":"sound1.wav"}`),
+	}
+	s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+	result, err := s.compile()
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		name string
+		id   SpxResourceID
+		want *Location
+	}{
+		{
+			name: "Backdrop",
+			id:   SpxBackdropResourceID{BackdropName: "backdrop1"},
+			want: &Location{
+				URI: "file:///assets/index.json",
+				Range: Range{
+					Start: Position{Line: 0, Character: 22},
+					End:   Position{Line: 0, Character: 33},
+				},
+			},
+		},
+		{
+			name: "Sound",
+			id:   SpxSoundResourceID{SoundName: "Sound1"},
+			want: &Location{
+				URI: "file:///assets/sounds/Sound1/index.json",
+				Range: Range{
+					Start: Position{Line: 0, Character: 8},
+					End:   Position{Line: 0, Character: 16},
+				},
+			},
+		},
+		{
+			name: "SpriteWithoutName",
+			id:   SpxSpriteResourceID{SpriteName: "Sprite1"},
+			want: &Location{URI: "file:///assets/sprites/Sprite1/index.json"},
+		},
+		{
+			name: "Costume",
+			id:   SpxSpriteCostumeResourceID{SpriteName: "Sprite1", CostumeName: "costume1"},
+			want: &Location{
+				URI: "file:///assets/sprites/Sprite1/index.json",
+				Range: Range{
+					Start: Position{Line: 1, Character: 24},
+					End:   Position{Line: 1, Character: 34},
+				},
+			},
+		},
+		{
+			name: "Animation",
+			id:   SpxSpriteAnimationResourceID{SpriteName: "Sprite1", AnimationName: "anim1"},
+			want: &Location{
+				URI: "file:///assets/sprites/Sprite1/index.json",
+				Range: Range{
+					Start: Position{Line: 2, Character: 18},
+					End:   Position{Line: 2, Character: 25},
+				},
+			},
+		},
+		{
+			name: "Widget",
+			id:   SpxWidgetResourceID{WidgetName: "widget1"},
+			want: &Location{
+				URI: "file:///assets/index.json",
+				Range: Range{
+					Start: Position{Line: 0, Character: 87},
+					End:   Position{Line: 0, Character: 96},
+				},
+			},
+		},
+		{
+			name: "NonExistentSound",
+			id:   SpxSoundResourceID{SoundName: "Sound2"},
+		},
+		{
+			name: "NonExistentCostume",
+			id:   SpxSpriteCostumeResourceID{SpriteName: "Sprite1", CostumeName: "costume2"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.spxResourceDefinitionLocation(result, tt.id))
+		})
+	}
+}
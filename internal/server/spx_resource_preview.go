@@ -0,0 +1,270 @@
+package server
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goplus/xgolsw/internal/vfs"
+)
+
+// spxResourcePreview is the preview information of a visual or audio spx
+// resource.
+type spxResourcePreview struct {
+	// imagePath is the path of the image to preview.
+	imagePath string
+
+	// width and height are the dimensions of the image in stage pixels, with
+	// the bitmap resolution applied. They are zero if unknown.
+	width, height int
+
+	// frames is the number of frames, e.g., costumes of a sprite or an
+	// animation.
+	frames int
+
+	// format, duration and rate are the audio format, duration and sample
+	// rate of a sound. They are zero values if unknown.
+	format   string
+	duration time.Duration
+	rate     int
+}
+
+// spxResourcePreviewHTML returns the HTML to preview the spx resource
+// identified by id. In addition to the resource URI, it includes the preview
+// image URI, the image dimensions and the frame count for visual resources, and
+// the format, duration in seconds and sample rate for sounds.
+func (s *Server) spxResourcePreviewHTML(result *compileResult, id SpxResourceID) string {
+	preview := s.spxResourcePreview(result, id)
+	if preview == nil {
+		return id.URI().HTML()
+	}
+
+	var attrs strings.Builder
+	fmt.Fprintf(&attrs, "resource=%q", template.HTMLEscapeString(string(id.URI())))
+	if preview.imagePath != "" {
+		fmt.Fprintf(&attrs, " preview=%q", template.HTMLEscapeString(string(s.toDocumentURI(preview.imagePath))))
+	}
+	if preview.width > 0 && preview.height > 0 {
+		fmt.Fprintf(&attrs, " width=\"%d\" height=\"%d\"", preview.width, preview.height)
+	}
+	if preview.frames > 0 {
+		fmt.Fprintf(&attrs, " frames=\"%d\"", preview.frames)
+	}
+	if preview.format != "" {
+		fmt.Fprintf(&attrs, " format=%q", template.HTMLEscapeString(preview.format))
+	}
+	if preview.duration > 0 {
+		fmt.Fprintf(&attrs, " duration=\"%s\"", strconv.FormatFloat(preview.duration.Seconds(), 'f', -1, 64))
+	}
+	if preview.rate > 0 {
+		fmt.Fprintf(&attrs, " rate=\"%d\"", preview.rate)
+	}
+	return fmt.Sprintf("<resource-preview %s />\n", attrs.String())
+}
+
+// spxResourcePreviewText returns the Markdown or plain text to preview the spx
+// resource identified by id, describing the same information as
+// [Server.spxResourcePreviewHTML]. In Markdown, the preview image is embedded.
+func (s *Server) spxResourcePreviewText(result *compileResult, id SpxResourceID, markdown bool) string {
+	var sb strings.Builder
+	if markdown {
+		fmt.Fprintf(&sb, "`%s`\n", id.URI())
+	} else {
+		fmt.Fprintf(&sb, "%s\n", id.URI())
+	}
+
+	preview := s.spxResourcePreview(result, id)
+	if preview == nil {
+		return sb.String()
+	}
+	if markdown && preview.imagePath != "" {
+		fmt.Fprintf(&sb, "\n![%s](%s)\n", path.Base(preview.imagePath), s.toDocumentURI(preview.imagePath))
+	}
+
+	var details []string
+	if preview.width > 0 && preview.height > 0 {
+		details = append(details, fmt.Sprintf("%dx%d", preview.width, preview.height))
+	}
+	if preview.frames > 0 {
+		details = append(details, fmt.Sprintf("%d frames", preview.frames))
+	}
+	if preview.format != "" {
+		details = append(details, preview.format)
+	}
+	if preview.duration > 0 {
+		details = append(details, strconv.FormatFloat(preview.duration.Seconds(), 'f', -1, 64)+"s")
+	}
+	if preview.rate > 0 {
+		details = append(details, fmt.Sprintf("%d Hz", preview.rate))
+	}
+	if len(details) > 0 {
+		fmt.Fprintf(&sb, "\n%s\n", strings.Join(details, ", "))
+	}
+	return sb.String()
+}
+
+// spxResourcePreview returns the preview information of the spx resource
+// identified by id. It returns nil if the resource is neither visual nor audio,
+// or does not exist.
+func (s *Server) spxResourcePreview(result *compileResult, id SpxResourceID) *spxResourcePreview {
+	rootDir := result.spxResourceRootDir
+	costumePreview := func(sprite *SpxSpriteResource, costume SpxSpriteCostumeResource, frames int) *spxResourcePreview {
+		preview := &spxResourcePreview{frames: frames}
+		if costume.Path != "" {
+			preview.imagePath = path.Join(rootDir, "sprites", sprite.Name, costume.Path)
+			preview.width, preview.height = spxImageSize(result.proj, preview.imagePath, costume.BitmapResolution)
+		}
+		return preview
+	}
+
+	switch id := id.(type) {
+	case SpxBackdropResourceID:
+		backdrop := result.spxResourceSet.Backdrop(id.BackdropName)
+		if backdrop == nil {
+			return nil
+		}
+		preview := &spxResourcePreview{frames: 1}
+		if backdrop.Path != "" {
+			preview.imagePath = path.Join(rootDir, backdrop.Path)
+			preview.width, preview.height = spxImageSize(result.proj, preview.imagePath, backdrop.BitmapResolution)
+		}
+		return preview
+	case SpxSoundResourceID:
+		sound := result.spxResourceSet.Sound(id.SoundName)
+		if sound == nil {
+			return nil
+		}
+		return &spxResourcePreview{
+			format:   sound.Format(),
+			duration: sound.Duration(),
+			rate:     sound.Rate,
+		}
+	case SpxSpriteResourceID:
+		sprite := result.spxResourceSet.Sprite(id.SpriteName)
+		if sprite == nil {
+			return nil
+		}
+		if len(sprite.Costumes) == 0 {
+			return &spxResourcePreview{}
+		}
+		costumeIndex := sprite.CostumeIndex
+		if costumeIndex < 0 || costumeIndex >= len(sprite.Costumes) {
+			costumeIndex = 0
+		}
+		return costumePreview(sprite, sprite.Costumes[costumeIndex], len(sprite.NormalCostumes))
+	case SpxSpriteCostumeResourceID:
+		sprite := result.spxResourceSet.Sprite(id.SpriteName)
+		if sprite == nil {
+			return nil
+		}
+		costume := sprite.Costume(id.CostumeName)
+		if costume == nil {
+			return nil
+		}
+		return costumePreview(sprite, *costume, 1)
+	case SpxSpriteAnimationResourceID:
+		sprite := result.spxResourceSet.Sprite(id.SpriteName)
+		if sprite == nil {
+			return nil
+		}
+		animation := sprite.Animation(id.AnimationName)
+		if animation == nil {
+			return nil
+		}
+		if animation.FromIndex == nil || animation.ToIndex == nil ||
+			*animation.FromIndex < 0 || *animation.ToIndex >= len(sprite.Costumes) || *animation.FromIndex > *animation.ToIndex {
+			return &spxResourcePreview{}
+		}
+		frames := *animation.ToIndex - *animation.FromIndex + 1
+		return costumePreview(sprite, sprite.Costumes[*animation.FromIndex], frames)
+	}
+	return nil
+}
+
+// spxImageSize returns the size of the image at imagePath in stage pixels,
+// which is the image size divided by the bitmap resolution. It returns zeros if
+// the size cannot be determined.
+//
+// Only the header of bitmap images is read, so large images that are loaded
+// lazily are not loaded into memory as a whole.
+func spxImageSize(proj *vfs.MapFS, imagePath string, bitmapResolution int) (width, height int) {
+	if strings.EqualFold(path.Ext(imagePath), ".svg") {
+		content, err := vfs.ReadFile(proj, imagePath)
+		if err != nil {
+			return 0, 0
+		}
+		width, height = svgImageSize(content)
+	} else {
+		r, err := vfs.OpenFile(proj, imagePath)
+		if err != nil {
+			return 0, 0
+		}
+		defer r.Close()
+		if cfg, _, err := image.DecodeConfig(r); err == nil {
+			width, height = cfg.Width, cfg.Height
+		}
+	}
+	if bitmapResolution > 1 {
+		width /= bitmapResolution
+		height /= bitmapResolution
+	}
+	return
+}
+
+// svgImageSize returns the size of the SVG image. It uses the width and height
+// attributes of the root element, falling back to its viewBox. It returns zeros
+// if the size cannot be determined.
+func svgImageSize(content []byte) (width, height int) {
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, 0
+		}
+		root, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		var viewBox string
+		for _, attr := range root.Attr {
+			switch attr.Name.Local {
+			case "width":
+				width = parseSVGLength(attr.Value)
+			case "height":
+				height = parseSVGLength(attr.Value)
+			case "viewBox":
+				viewBox = attr.Value
+			}
+		}
+		if (width == 0 || height == 0) && viewBox != "" {
+			fields := strings.FieldsFunc(viewBox, func(r rune) bool {
+				return r == ' ' || r == ','
+			})
+			if len(fields) == 4 {
+				width = parseSVGLength(fields[2])
+				height = parseSVGLength(fields[3])
+			}
+		}
+		return width, height
+	}
+}
+
+// parseSVGLength parses an SVG length in pixels, such as "100" or "100px". It
+// returns zero for other units or invalid lengths.
+func parseSVGLength(s string) int {
+	f, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "px"), 64)
+	if err != nil || f < 0 {
+		return 0
+	}
+	return int(f + 0.5)
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerSpxResourcePreviewHTML(t *testing.T) {
+	newPNG := func(t *testing.T, width, height int) []byte {
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+		return buf.Bytes()
+	}
+	newServer := func(t *testing.T) (*Server, *compileResult) {
+		m := map[string][]byte{
+			"main.spx": []byte(`
+var (
+	MySprite MySprite
+)
+run "assets", {Title: "My Game"}
+`),
+			"MySprite.spx":      []byte(``),
+			"assets/index.json": []byte(`{"backdrops":[{"name":"backdrop1","path":"backdrop1.svg"}]}`),
+			"assets/backdrop1.svg": []byte(`<?xml version="1.0"?>
+<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 480 360"></svg>`),
+			"assets/sprites/MySprite/index.json": []byte(`{
+  "costumeIndex": 1,
+  "costumes": [
+    {"name": "costume1", "path": "costume1.png", "bitmapResolution": 2},
+    {"name": "costume2", "path": "costume2.png", "bitmapResolution": 2},
+    {"name": "walk1", "path": "walk1.png"},
+    {"name": "walk2", "path": "walk2.png"},
+    {"name": "walk3", "path": "walk3.png"}
+  ],
+  "fAnimations": {"walk": {"frameFrom": "walk1", "frameTo": "walk3"}}
+}`),
+			"assets/sprites/MySprite/costume1.png": newPNG(t, 196, 244),
+			"assets/sprites/MySprite/costume2.png": newPNG(t, 100, 50),
+			"assets/sprites/MySprite/walk1.png":    newPNG(t, 30, 40),
+			"assets/sounds/MyMusic/index.json":     []byte(`{"path":"MyMusic.WAV","rate":44100,"sampleCount":66150}`),
+			"assets/sounds/MyMusic/MyMusic.WAV":    []byte(``),
+		}
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		result, err := s.compile()
+		require.NoError(t, err)
+		return s, result
+	}
+
+	t.Run("Backdrop", func(t *testing.T) {
+		s, result := newServer(t)
+
+		html := s.spxResourcePreviewHTML(result, SpxBackdropResourceID{BackdropName: "backdrop1"})
+		assert.Equal(t, `<resource-preview resource="spx://resources/backdrops/backdrop1" preview="file:///assets/backdrop1.svg" width="480" height="360" frames="1" />`+"\n", html)
+	})
+
+	t.Run("Sprite", func(t *testing.T) {
+		s, result := newServer(t)
+
+		html := s.spxResourcePreviewHTML(result, SpxSpriteResourceID{SpriteName: "MySprite"})
+		assert.Equal(t, `<resource-preview resource="spx://resources/sprites/MySprite" preview="file:///assets/sprites/MySprite/costume2.png" width="50" height="25" frames="2" />`+"\n", html)
+	})
+
+	t.Run("Costume", func(t *testing.T) {
+		s, result := newServer(t)
+
+		html := s.spxResourcePreviewHTML(result, SpxSpriteCostumeResourceID{SpriteName: "MySprite", CostumeName: "costume1"})
+		assert.Equal(t, `<resource-preview resource="spx://resources/sprites/MySprite/costumes/costume1" preview="file:///assets/sprites/MySprite/costume1.png" width="98" height="122" frames="1" />`+"\n", html)
+	})
+
+	t.Run("Animation", func(t *testing.T) {
+		s, result := newServer(t)
+
+		html := s.spxResourcePreviewHTML(result, SpxSpriteAnimationResourceID{SpriteName: "MySprite", AnimationName: "walk"})
+		assert.Equal(t, `<resource-preview resource="spx://resources/sprites/MySprite/animations/walk" preview="file:///assets/sprites/MySprite/walk1.png" width="30" height="40" frames="3" />`+"\n", html)
+	})
+
+	t.Run("Sound", func(t *testing.T) {
+		s, result := newServer(t)
+
+		html := s.spxResourcePreviewHTML(result, SpxSoundResourceID{SoundName: "MyMusic"})
+		assert.Equal(t, `<resource-preview resource="spx://resources/sounds/MyMusic" format="wav" duration="1.5" rate="44100" />`+"\n", html)
+	})
+
+	t.Run("NonVisualResource", func(t *testing.T) {
+		s, result := newServer(t)
+
+		html := s.spxResourcePreviewHTML(result, SpxSoundResourceID{SoundName: "MySound"})
+		assert.Equal(t, `<resource-preview resource="spx://resources/sounds/MySound" />`+"\n", html)
+	})
+}
+
+func TestSVGImageSize(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		content    string
+		wantWidth  int
+		wantHeight int
+	}{
+		{
+			name:       "WidthAndHeight",
+			content:    `<svg width="100px" height="50"></svg>`,
+			wantWidth:  100,
+			wantHeight: 50,
+		},
+		{
+			name:       "ViewBox",
+			content:    `<svg viewBox="0,0,64.4,32"></svg>`,
+			wantWidth:  64,
+			wantHeight: 32,
+		},
+		{
+			name:       "UnsupportedUnit",
+			content:    `<svg width="10em" height="10em"></svg>`,
+			wantWidth:  0,
+			wantHeight: 0,
+		},
+		{
+			name:       "Invalid",
+			content:    `not svg`,
+			wantWidth:  0,
+			wantHeight: 0,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height := svgImageSize([]byte(tt.content))
+			assert.Equal(t, tt.wantWidth, width)
+			assert.Equal(t, tt.wantHeight, height)
+		})
+	}
+}
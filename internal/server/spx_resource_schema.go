@@ -0,0 +1,526 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/goplus/xgolsw/internal/vfs"
+)
+
+// jsonNodeKind is the kind of a [jsonNode].
+type jsonNodeKind int
+
+const (
+	jsonNull jsonNodeKind = iota
+	jsonBool
+	jsonNumber
+	jsonString
+	jsonArray
+	jsonObject
+)
+
+// String implements [fmt.Stringer]. It returns the kind with an article, as
+// used in diagnostic messages.
+func (k jsonNodeKind) String() string {
+	switch k {
+	case jsonBool:
+		return "a boolean"
+	case jsonNumber:
+		return "a number"
+	case jsonString:
+		return "a string"
+	case jsonArray:
+		return "an array"
+	case jsonObject:
+		return "an object"
+	}
+	return "null"
+}
+
+// jsonNode is a value in a JSON document.
+type jsonNode struct {
+	kind jsonNodeKind
+
+	// value is the value of a scalar node: a bool, [json.Number] or string.
+	value any
+
+	members []jsonMember // Members of an object node, in document order.
+	elems   []*jsonNode  // Elements of an array node.
+
+	start, end int // Byte offsets of the value.
+}
+
+// jsonMember is a member of a JSON object.
+type jsonMember struct {
+	key   *jsonNode
+	value *jsonNode
+}
+
+// member returns the value of the last member of object node n with the given
+// key, which is the one that takes effect when decoding. It returns nil if n is
+// nil, not an object, or has no such member.
+func (n *jsonNode) member(key string) *jsonNode {
+	if n == nil || n.kind != jsonObject {
+		return nil
+	}
+	for _, m := range slices.Backward(n.members) {
+		if m.key.value == key {
+			return m.value
+		}
+	}
+	return nil
+}
+
+// str returns the value of string node n. It returns an empty string if n is
+// nil or not a string.
+func (n *jsonNode) str() string {
+	if n == nil || n.kind != jsonString {
+		return ""
+	}
+	return n.value.(string)
+}
+
+// jsonParseError is an error parsing a JSON document.
+type jsonParseError struct {
+	offset int // Byte offset at which the error occurred.
+	err    error
+}
+
+// Error implements [error].
+func (e *jsonParseError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *jsonParseError) Unwrap() error {
+	return e.err
+}
+
+// parseJSONNode parses the JSON document content into a tree of [jsonNode]. It
+// returns a [*jsonParseError] if content is not valid JSON.
+func parseJSONNode(content []byte) (*jsonNode, error) {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+
+	var prevEnd int
+	next := func() (tok json.Token, start, end int, err error) {
+		tok, err = dec.Token()
+		if err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return nil, 0, 0, &jsonParseError{offset: max(int(syntaxErr.Offset)-1, 0), err: err}
+			}
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, 0, 0, &jsonParseError{offset: len(content), err: err}
+		}
+		start = prevEnd
+		for start < len(content) && strings.IndexByte(" \t\r\n,:", content[start]) >= 0 {
+			start++
+		}
+		end = int(dec.InputOffset())
+		prevEnd = end
+		return tok, start, end, nil
+	}
+
+	var parse func(tok json.Token, start, end int) (*jsonNode, error)
+	parse = func(tok json.Token, start, end int) (*jsonNode, error) {
+		node := &jsonNode{value: tok, start: start, end: end}
+		switch tok := tok.(type) {
+		case nil:
+			node.kind = jsonNull
+		case bool:
+			node.kind = jsonBool
+		case json.Number:
+			node.kind = jsonNumber
+		case string:
+			node.kind = jsonString
+		case json.Delim:
+			node.value = nil
+			if tok == '{' {
+				node.kind = jsonObject
+			} else {
+				node.kind = jsonArray
+			}
+			for {
+				tok, start, end, err := next()
+				if err != nil {
+					return nil, err
+				}
+				if tok == json.Delim('}') || tok == json.Delim(']') {
+					node.end = end
+					return node, nil
+				}
+				child, err := parse(tok, start, end)
+				if err != nil {
+					return nil, err
+				}
+				if node.kind == jsonArray {
+					node.elems = append(node.elems, child)
+					continue
+				}
+
+				tok, start, end, err = next()
+				if err != nil {
+					return nil, err
+				}
+				value, err := parse(tok, start, end)
+				if err != nil {
+					return nil, err
+				}
+				node.members = append(node.members, jsonMember{key: child, value: value})
+			}
+		}
+		return node, nil
+	}
+
+	tok, start, end, err := next()
+	if err != nil {
+		return nil, err
+	}
+	root, err := parse(tok, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if _, start, _, err := next(); err == nil {
+		return nil, &jsonParseError{offset: start, err: errors.New("invalid data after top-level value")}
+	} else if !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	return root, nil
+}
+
+// inspectForSpxResourceMetadata validates the index.json files under the spx
+// resource root directory and reports malformed or inconsistent entries as
+// diagnostics on the files themselves.
+func (s *Server) inspectForSpxResourceMetadata(snapshot *vfs.MapFS, result *compileResult) {
+	rootDir := result.spxResourceRootDir
+	var (
+		metadataFiles []string
+		spriteNames   = make(map[string]struct{})
+		soundNames    = make(map[string]struct{})
+	)
+	for p := range snapshot.Files() {
+		rel, ok := strings.CutPrefix(p, rootDir+"/")
+		if !ok || path.Base(rel) != "index.json" {
+			continue
+		}
+		switch parts := strings.Split(rel, "/"); {
+		case len(parts) == 1:
+		case len(parts) == 3 && parts[0] == "sprites":
+			spriteNames[parts[1]] = struct{}{}
+		case len(parts) == 3 && parts[0] == "sounds":
+			soundNames[parts[1]] = struct{}{}
+		default:
+			continue
+		}
+		metadataFiles = append(metadataFiles, p)
+	}
+	slices.Sort(metadataFiles)
+
+	for _, metadataFile := range metadataFiles {
+		documentURI := s.toDocumentURI(metadataFile)
+		result.diagnostics[documentURI] = []Diagnostic{}
+
+		content, err := vfs.ReadFile(snapshot, metadataFile)
+		if err != nil {
+			continue
+		}
+		v := &spxResourceMetadataValidator{
+			snapshot: snapshot,
+			rootDir:  rootDir,
+			content:  content,
+		}
+		root, err := parseJSONNode(content)
+		if err != nil {
+			var parseErr *jsonParseError
+			errors.As(err, &parseErr)
+			v.report(SeverityError, &jsonNode{start: parseErr.offset, end: min(parseErr.offset+1, len(content))}, "invalid JSON: %v", err)
+		} else {
+			switch parts := strings.Split(strings.TrimPrefix(metadataFile, rootDir+"/"), "/"); parts[0] {
+			case "sprites":
+				v.validateSprite(root, parts[1])
+				v.validateDirNameCollision(root, "sprite", parts[1], spriteNames)
+			case "sounds":
+				v.validateSound(root, parts[1])
+				v.validateDirNameCollision(root, "sound", parts[1], soundNames)
+			default:
+				v.validateStage(root, spriteNames)
+			}
+		}
+		result.addDiagnostics(documentURI, v.diagnostics...)
+	}
+}
+
+// spxResourceMetadataValidator validates an spx resource metadata file.
+type spxResourceMetadataValidator struct {
+	snapshot    *vfs.MapFS
+	rootDir     string
+	content     []byte
+	diagnostics []Diagnostic
+}
+
+// report reports a diagnostic for node.
+func (v *spxResourceMetadataValidator) report(severity DiagnosticSeverity, node *jsonNode, format string, args ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		Severity: severity,
+		Code:     diagnosticCodeSpxResourceMetadataInvalid,
+		Range: Range{
+			Start: OffsetPosition(v.content, node.start),
+			End:   OffsetPosition(v.content, node.end),
+		},
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// expect reports whether node is of the given kind. It reports an error if
+// node is present but of another kind. It returns false if node is nil.
+func (v *spxResourceMetadataValidator) expect(node *jsonNode, kind jsonNodeKind, what string) bool {
+	if node == nil {
+		return false
+	}
+	if node.kind != kind {
+		v.report(SeverityError, node, "%s must be %s", what, kind)
+		return false
+	}
+	return true
+}
+
+// spxResourceNameKey returns the key of an spx resource name for detecting
+// names that differ only in case or surrounding whitespace, which collide on
+// case-insensitive file systems.
+func spxResourceNameKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// validateName validates the required name of the object node of a resource
+// of the given kind, and records it in seen, which maps name keys to the first
+// names with those keys. It returns the name, or an empty string if it is
+// invalid.
+func (v *spxResourceMetadataValidator) validateName(node *jsonNode, kind string, seen map[string]string) string {
+	nameNode := node.member("name")
+	if nameNode == nil || nameNode.kind != jsonString || nameNode.str() == "" {
+		v.report(SeverityError, cmp.Or(nameNode, node), "%s name must be a non-empty string", kind)
+		return ""
+	}
+	name := nameNode.str()
+	v.validateNameCollision(nameNode, kind, name, seen)
+	return name
+}
+
+// validateNameCollision reports name of a resource of the given kind at node
+// if it duplicates or collides with a name in seen, and records it otherwise.
+func (v *spxResourceMetadataValidator) validateNameCollision(node *jsonNode, kind, name string, seen map[string]string) {
+	key := spxResourceNameKey(name)
+	switch other, ok := seen[key]; {
+	case !ok:
+		seen[key] = name
+	case other == name:
+		v.report(SeverityError, node, "duplicate %s name %q", kind, name)
+	default:
+		v.report(SeverityWarning, node, "%s name %q differs only in case or surrounding whitespace from %q", kind, name, other)
+	}
+}
+
+// validateDirName validates the optional name node of a resource of the given
+// kind against the name of the directory the resource is stored in.
+func (v *spxResourceMetadataValidator) validateDirName(node *jsonNode, kind, dirName string) {
+	if !v.expect(node, jsonString, kind+" name") {
+		return
+	}
+	if name := node.str(); name != dirName {
+		v.report(SeverityWarning, node, "%s name %q does not match its directory name %q", kind, name, dirName)
+	}
+}
+
+// validateDirNameCollision reports the root node of the metadata file of a
+// resource of the given kind stored in the directory named dirName if another
+// directory in dirNames differs from it only in case or surrounding whitespace.
+// The report is placed on the name node if present, or the start of the root
+// node otherwise.
+func (v *spxResourceMetadataValidator) validateDirNameCollision(root *jsonNode, kind, dirName string, dirNames map[string]struct{}) {
+	key := spxResourceNameKey(dirName)
+	for _, other := range slices.Sorted(maps.Keys(dirNames)) {
+		if other == dirName || spxResourceNameKey(other) != key {
+			continue
+		}
+		node := root.member("name")
+		if node == nil {
+			node = &jsonNode{start: root.start, end: min(root.start+1, root.end)}
+		}
+		v.report(SeverityWarning, node, "%s directory name %q differs only in case or surrounding whitespace from %q", kind, dirName, other)
+		return
+	}
+}
+
+// validateAsset validates the asset path node and bitmap resolution node of
+// the object node of a resource of the given kind. The asset path is relative
+// to dir, which is relative to the resource root directory.
+func (v *spxResourceMetadataValidator) validateAsset(node *jsonNode, kind, dir string) {
+	if pathNode := node.member("path"); v.expect(pathNode, jsonString, kind+" path") && pathNode.str() != "" {
+		assetPath := path.Join(v.rootDir, dir, pathNode.str())
+		if _, ok := v.snapshot.File(assetPath); !ok {
+			v.report(SeverityWarning, pathNode, "%s file %q not found", kind, assetPath)
+		}
+	}
+	if resolutionNode := node.member("bitmapResolution"); v.expect(resolutionNode, jsonNumber, "bitmapResolution") {
+		if n, err := resolutionNode.value.(json.Number).Int64(); err != nil || n <= 0 {
+			v.report(SeverityError, resolutionNode, "bitmapResolution must be a positive integer")
+		}
+	}
+}
+
+// validateIndex validates the optional index node named name, which must be a
+// valid index into a list of length n unless the list is empty.
+func (v *spxResourceMetadataValidator) validateIndex(node *jsonNode, name string, n int) {
+	if !v.expect(node, jsonNumber, name) {
+		return
+	}
+	i, err := node.value.(json.Number).Int64()
+	if err != nil {
+		v.report(SeverityError, node, "%s must be an integer", name)
+		return
+	}
+	if n > 0 && (i < 0 || i >= int64(n)) {
+		v.report(SeverityError, node, "%s %d is out of range [0, %d)", name, i, n)
+	}
+}
+
+// validateStage validates the root node of the stage metadata file, the
+// index.json file in the resource root directory. spriteNames is the set of
+// sprites with a metadata file.
+func (v *spxResourceMetadataValidator) validateStage(root *jsonNode, spriteNames map[string]struct{}) {
+	if !v.expect(root, jsonObject, "stage metadata") {
+		return
+	}
+
+	var backdropCount int
+	if backdrops := root.member("backdrops"); v.expect(backdrops, jsonArray, "backdrops") {
+		backdropCount = len(backdrops.elems)
+		seen := make(map[string]string, len(backdrops.elems))
+		for _, backdrop := range backdrops.elems {
+			if !v.expect(backdrop, jsonObject, "backdrop") {
+				continue
+			}
+			v.validateName(backdrop, "backdrop", seen)
+			v.validateAsset(backdrop, "backdrop", ".")
+		}
+	}
+	v.validateIndex(root.member("backdropIndex"), "backdropIndex", backdropCount)
+
+	if zorder := root.member("zorder"); v.expect(zorder, jsonArray, "zorder") {
+		seen := make(map[string]string, len(zorder.elems))
+		for _, item := range zorder.elems {
+			switch item.kind {
+			case jsonString:
+				if _, ok := spriteNames[item.str()]; !ok {
+					v.report(SeverityWarning, item, "sprite %q in zorder not found", item.str())
+				}
+			case jsonObject:
+				v.validateName(item, "widget", seen)
+				v.expect(item.member("type"), jsonString, "widget type")
+			default:
+				v.report(SeverityError, item, "zorder item must be a sprite name or a widget object")
+			}
+		}
+	}
+}
+
+// validateSprite validates the root node of the metadata file of the sprite
+// stored in the directory named spriteName.
+func (v *spxResourceMetadataValidator) validateSprite(root *jsonNode, spriteName string) {
+	if !v.expect(root, jsonObject, "sprite metadata") {
+		return
+	}
+	v.validateDirName(root.member("name"), "sprite", spriteName)
+
+	costumeIndexes := make(map[string]int)
+	var costumeCount int
+	if costumes := root.member("costumes"); v.expect(costumes, jsonArray, "costumes") {
+		costumeCount = len(costumes.elems)
+		seen := make(map[string]string, len(costumes.elems))
+		for i, costume := range costumes.elems {
+			if !v.expect(costume, jsonObject, "costume") {
+				continue
+			}
+			if name := v.validateName(costume, "costume", seen); name != "" {
+				if _, ok := costumeIndexes[name]; !ok {
+					costumeIndexes[name] = i
+				}
+			}
+			v.validateAsset(costume, "costume", path.Join("sprites", spriteName))
+		}
+	}
+	v.validateIndex(root.member("costumeIndex"), "costumeIndex", costumeCount)
+
+	animations := make(map[string]struct{})
+	if fAnimations := root.member("fAnimations"); v.expect(fAnimations, jsonObject, "fAnimations") {
+		seen := make(map[string]string, len(fAnimations.members))
+		for _, m := range fAnimations.members {
+			animationName := m.key.str()
+			animations[animationName] = struct{}{}
+			v.validateNameCollision(m.key, "animation", animationName, seen)
+			if !v.expect(m.value, jsonObject, "animation") {
+				continue
+			}
+
+			frameIndex := func(key string) (int, bool) {
+				frame := m.value.member(key)
+				if !v.expect(frame, jsonString, key) {
+					return 0, false
+				}
+				i, ok := costumeIndexes[frame.str()]
+				if !ok {
+					v.report(SeverityWarning, frame, "costume %q not found", frame.str())
+				}
+				return i, ok
+			}
+			from, fromOK := frameIndex("frameFrom")
+			to, toOK := frameIndex("frameTo")
+			if fromOK && toOK && from > to {
+				v.report(SeverityWarning, m.key, "animation %q starts after it ends", animationName)
+			}
+		}
+	}
+
+	validateAnimationName := func(node *jsonNode, what string) {
+		if v.expect(node, jsonString, what) && node.str() != "" {
+			if _, ok := animations[node.str()]; !ok {
+				v.report(SeverityWarning, node, "animation %q not found", node.str())
+			}
+		}
+	}
+	validateAnimationName(root.member("defaultAnimation"), "defaultAnimation")
+	if animBindings := root.member("animBindings"); v.expect(animBindings, jsonObject, "animBindings") {
+		for _, m := range animBindings.members {
+			validateAnimationName(m.value, fmt.Sprintf("animation binding %q", m.key.str()))
+		}
+	}
+}
+
+// validateSound validates the root node of the metadata file of the sound
+// stored in the directory named soundName.
+func (v *spxResourceMetadataValidator) validateSound(root *jsonNode, soundName string) {
+	if !v.expect(root, jsonObject, "sound metadata") {
+		return
+	}
+	v.validateDirName(root.member("name"), "sound", soundName)
+	v.validateAsset(root, "sound", path.Join("sounds", soundName))
+	if pathNode := root.member("path"); pathNode != nil && pathNode.kind == jsonString && pathNode.str() != "" {
+		sound := SpxSoundResource{Path: pathNode.str()}
+		if format := sound.Format(); !spxSupportedSoundFormats[format] {
+			v.report(SeverityWarning, pathNode, "sound format %q is not supported", format)
+		}
+	}
+	v.expect(root.member("rate"), jsonNumber, "rate")
+	v.expect(root.member("sampleCount"), jsonNumber, "sampleCount")
+}
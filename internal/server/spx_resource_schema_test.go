@@ -0,0 +1,277 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSONNode(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		content := []byte(`{"name": "a", "list": [1, true, null], "obj": {"b": "c"}}`)
+		root, err := parseJSONNode(content)
+		require.NoError(t, err)
+		assert.Equal(t, jsonObject, root.kind)
+		assert.Equal(t, string(content), string(content[root.start:root.end]))
+		require.Len(t, root.members, 3)
+
+		name := root.member("name")
+		require.NotNil(t, name)
+		assert.Equal(t, "a", name.str())
+		assert.Equal(t, `"a"`, string(content[name.start:name.end]))
+
+		list := root.member("list")
+		require.NotNil(t, list)
+		assert.Equal(t, jsonArray, list.kind)
+		assert.Equal(t, `[1, true, null]`, string(content[list.start:list.end]))
+		require.Len(t, list.elems, 3)
+		assert.Equal(t, jsonNumber, list.elems[0].kind)
+		assert.Equal(t, json.Number("1"), list.elems[0].value)
+		assert.Equal(t, jsonBool, list.elems[1].kind)
+		assert.Equal(t, `true`, string(content[list.elems[1].start:list.elems[1].end]))
+		assert.Equal(t, jsonNull, list.elems[2].kind)
+
+		obj := root.member("obj")
+		require.NotNil(t, obj)
+		assert.Equal(t, "c", obj.member("b").str())
+		assert.Equal(t, `"b"`, string(content[obj.members[0].key.start:obj.members[0].key.end]))
+
+		assert.Nil(t, root.member("missing"))
+		assert.Nil(t, name.member("name"))
+	})
+
+	t.Run("DuplicateKeys", func(t *testing.T) {
+		root, err := parseJSONNode([]byte(`{"a": 1, "a": 2}`))
+		require.NoError(t, err)
+		assert.Equal(t, json.Number("2"), root.member("a").value)
+	})
+
+	t.Run("SyntaxError", func(t *testing.T) {
+		_, err := parseJSONNode([]byte(`{"a": x}`))
+		var parseErr *jsonParseError
+		require.ErrorAs(t, err, &parseErr)
+		assert.Equal(t, 6, parseErr.offset)
+	})
+
+	t.Run("UnexpectedEOF", func(t *testing.T) {
+		_, err := parseJSONNode([]byte(`{"a": [1`))
+		var parseErr *jsonParseError
+		require.ErrorAs(t, err, &parseErr)
+		assert.Equal(t, 8, parseErr.offset)
+	})
+
+	t.Run("TrailingData", func(t *testing.T) {
+		_, err := parseJSONNode([]byte(`{} []`))
+		var parseErr *jsonParseError
+		require.ErrorAs(t, err, &parseErr)
+		assert.Equal(t, 3, parseErr.offset)
+	})
+}
+
+func TestServerInspectForSpxResourceMetadata(t *testing.T) {
+	compile := func(t *testing.T, m map[string][]byte) *compileResult {
+		m["main.spx"] = []byte(`run "assets", {Title: "My Game"}`)
+		s := New(newMapFSWithoutModTime(m), nil, fileMapGetter(m), &MockScheduler{})
+		result, err := s.compile()
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		result := compile(t, map[string][]byte{
+			"MySprite.spx":         []byte(``),
+			"assets/index.json":    []byte(`{"backdrops":[{"name":"backdrop1","path":"backdrop1.png"}],"backdropIndex":0,"zorder":["MySprite",{"name":"widget1","type":"monitor"}]}`),
+			"assets/backdrop1.png": []byte(``),
+			"assets/sprites/MySprite/index.json": []byte(`{
+  "costumeIndex": 1,
+  "costumes": [{"name": "c1", "path": "c1.png", "bitmapResolution": 2}, {"name": "c2", "path": "c2.png"}],
+  "fAnimations": {"anim": {"frameFrom": "c1", "frameTo": "c2"}},
+  "defaultAnimation": "anim",
+  "animBindings": {"step": "anim"}
+}`),
+			"assets/sprites/MySprite/c1.png":   []byte(``),
+			"assets/sprites/MySprite/c2.png":   []byte(``),
+			"assets/sounds/MySound/index.json": []byte(`{"rate":44100,"sampleCount":100,"path":"s.wav"}`),
+			"assets/sounds/MySound/s.wav":      []byte(``),
+		})
+		for _, uri := range []DocumentURI{
+			"file:///assets/index.json",
+			"file:///assets/sprites/MySprite/index.json",
+			"file:///assets/sounds/MySound/index.json",
+		} {
+			diagnostics, ok := result.diagnostics[uri]
+			require.True(t, ok, uri)
+			assert.Empty(t, diagnostics, uri)
+		}
+	})
+
+	t.Run("InvalidJSON", func(t *testing.T) {
+		result := compile(t, map[string][]byte{
+			"assets/index.json": []byte("{\n  \"backdrops\": [\n}"),
+		})
+		assert.Equal(t, []Diagnostic{{
+			Severity:        SeverityError,
+			Code:            "spx-resource-metadata-invalid",
+			CodeDescription: diagnosticCodeDescription("spx-resource-metadata-invalid"),
+			Range: Range{
+				Start: Position{Line: 2, Character: 0},
+				End:   Position{Line: 2, Character: 1},
+			},
+			Message: "invalid JSON: invalid character '}' looking for beginning of value",
+		}}, result.diagnostics["file:///assets/index.json"])
+	})
+
+	t.Run("Stage", func(t *testing.T) {
+		result := compile(t, map[string][]byte{
+			"assets/index.json": []byte(`{"backdrops":[{"name":"b"},{"name":"b","path":"b.png"},{"path":1}],"backdropIndex":3,"zorder":["Nope",{"type":"monitor"},1]}`),
+		})
+		diagnostics := result.diagnostics["file:///assets/index.json"]
+		var messages []string
+		for _, diagnostic := range diagnostics {
+			messages = append(messages, diagnostic.Message)
+		}
+		assert.Equal(t, []string{
+			`duplicate backdrop name "b"`,
+			`backdrop file "assets/b.png" not found`,
+			`backdrop name must be a non-empty string`,
+			`backdrop path must be a string`,
+			`backdropIndex 3 is out of range [0, 3)`,
+			`sprite "Nope" in zorder not found`,
+			`widget name must be a non-empty string`,
+			`zorder item must be a sprite name or a widget object`,
+		}, messages)
+		assert.Equal(t, Range{
+			Start: Position{Line: 0, Character: 35},
+			End:   Position{Line: 0, Character: 38},
+		}, diagnostics[0].Range)
+	})
+
+	t.Run("Sprite", func(t *testing.T) {
+		result := compile(t, map[string][]byte{
+			"MySprite.spx":      []byte(``),
+			"assets/index.json": []byte(`{}`),
+			"assets/sprites/MySprite/index.json": []byte(`{
+  "name": "Other",
+  "costumeIndex": 1.5,
+  "costumes": [{"name": "c1", "bitmapResolution": 0}, {"name": "c2"}],
+  "fAnimations": {"anim": {"frameFrom": "c2", "frameTo": "c1"}, "bad": {"frameFrom": "c3"}},
+  "defaultAnimation": "nope",
+  "animBindings": {"step": 1}
+}`),
+		})
+		diagnostics := result.diagnostics["file:///assets/sprites/MySprite/index.json"]
+		var messages []string
+		for _, diagnostic := range diagnostics {
+			messages = append(messages, diagnostic.Message)
+		}
+		assert.Equal(t, []string{
+			`sprite name "Other" does not match its directory name "MySprite"`,
+			`bitmapResolution must be a positive integer`,
+			`costumeIndex must be an integer`,
+			`animation "anim" starts after it ends`,
+			`costume "c3" not found`,
+			`animation "nope" not found`,
+			`animation binding "step" must be a string`,
+		}, messages)
+		assert.Equal(t, SeverityWarning, diagnostics[0].Severity)
+		assert.Equal(t, Range{
+			Start: Position{Line: 1, Character: 10},
+			End:   Position{Line: 1, Character: 17},
+		}, diagnostics[0].Range)
+	})
+
+	t.Run("Sound", func(t *testing.T) {
+		result := compile(t, map[string][]byte{
+			"assets/index.json":                []byte(`{}`),
+			"assets/sounds/MySound/index.json": []byte(`{"name":"MySound","rate":"fast","path":"s.wav"}`),
+			"assets/sounds/MyMusic/index.json": []byte(`{"path":"m.flac"}`),
+			"assets/sounds/MyMusic/m.flac":     []byte(``),
+		})
+		diagnostics := result.diagnostics["file:///assets/sounds/MySound/index.json"]
+		var messages []string
+		for _, diagnostic := range diagnostics {
+			messages = append(messages, diagnostic.Message)
+		}
+		assert.Equal(t, []string{
+			`sound file "assets/sounds/MySound/s.wav" not found`,
+			`rate must be a number`,
+		}, messages)
+		assert.Equal(t, []Diagnostic{{
+			Severity:        SeverityWarning,
+			Code:            "spx-resource-metadata-invalid",
+			CodeDescription: diagnosticCodeDescription("spx-resource-metadata-invalid"),
+			Range: Range{
+				Start: Position{Line: 0, Character: 8},
+				End:   Position{Line: 0, Character: 16},
+			},
+			Message: `sound format "flac" is not supported`,
+		}}, result.diagnostics["file:///assets/sounds/MyMusic/index.json"])
+	})
+
+	t.Run("NameCollisions", func(t *testing.T) {
+		result := compile(t, map[string][]byte{
+			"MySprite.spx":      []byte(``),
+			"assets/index.json": []byte(`{"backdrops":[{"name":"Sky"},{"name":"sky "},{"name":"Sky"}],"zorder":[{"name":"Score","type":"monitor"},{"name":"score","type":"monitor"}]}`),
+			"assets/sprites/MySprite/index.json": []byte(`{
+  "costumes": [{"name": "c1"}, {"name": "C1"}],
+  "fAnimations": {"walk": {}, "Walk": {}}
+}`),
+			"assets/sounds/Boom/index.json": []byte(`{"name":"Boom"}`),
+			"assets/sounds/boom/index.json": []byte(`{}`),
+		})
+		messages := func(uri DocumentURI) []string {
+			var messages []string
+			for _, diagnostic := range result.diagnostics[uri] {
+				messages = append(messages, diagnostic.Message)
+			}
+			return messages
+		}
+		assert.Equal(t, []string{
+			`backdrop name "sky " differs only in case or surrounding whitespace from "Sky"`,
+			`duplicate backdrop name "Sky"`,
+			`widget name "score" differs only in case or surrounding whitespace from "Score"`,
+		}, messages("file:///assets/index.json"))
+		assert.Equal(t, []string{
+			`costume name "C1" differs only in case or surrounding whitespace from "c1"`,
+			`animation name "Walk" differs only in case or surrounding whitespace from "walk"`,
+		}, messages("file:///assets/sprites/MySprite/index.json"))
+		assert.Equal(t, []Diagnostic{{
+			Severity:        SeverityWarning,
+			Code:            "spx-resource-metadata-invalid",
+			CodeDescription: diagnosticCodeDescription("spx-resource-metadata-invalid"),
+			Range: Range{
+				Start: Position{Line: 0, Character: 8},
+				End:   Position{Line: 0, Character: 14},
+			},
+			Message: `sound directory name "Boom" differs only in case or surrounding whitespace from "boom"`,
+		}}, result.diagnostics["file:///assets/sounds/Boom/index.json"])
+		assert.Equal(t, []Diagnostic{{
+			Severity:        SeverityWarning,
+			Code:            "spx-resource-metadata-invalid",
+			CodeDescription: diagnosticCodeDescription("spx-resource-metadata-invalid"),
+			Range: Range{
+				Start: Position{Line: 0, Character: 0},
+				End:   Position{Line: 0, Character: 1},
+			},
+			Message: `sound directory name "boom" differs only in case or surrounding whitespace from "Boom"`,
+		}}, result.diagnostics["file:///assets/sounds/boom/index.json"])
+	})
+
+	t.Run("NotAnObject", func(t *testing.T) {
+		result := compile(t, map[string][]byte{
+			"assets/index.json": []byte(`[]`),
+		})
+		assert.Equal(t, []Diagnostic{{
+			Severity:        SeverityError,
+			Code:            "spx-resource-metadata-invalid",
+			CodeDescription: diagnosticCodeDescription("spx-resource-metadata-invalid"),
+			Range: Range{
+				Start: Position{Line: 0, Character: 0},
+				End:   Position{Line: 0, Character: 2},
+			},
+			Message: "stage metadata must be an object",
+		}}, result.diagnostics["file:///assets/index.json"])
+	})
+}
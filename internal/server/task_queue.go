@@ -0,0 +1,168 @@
+package server
+
+import (
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// taskPriority is the priority of a task in a [taskQueue]. Tasks with a higher
+// priority are started first.
+type taskPriority int
+
+const (
+	// taskPriorityBackground is for work that users do not wait for, such as
+	// workspace diagnostics and document links.
+	taskPriorityBackground taskPriority = iota
+
+	// taskPriorityNormal is for everything that is neither interactive nor
+	// background work.
+	taskPriorityNormal
+
+	// taskPriorityInteractive is for requests that users wait for while
+	// typing, such as completion, hover, and signature help.
+	taskPriorityInteractive
+)
+
+// taskClass describes how a message with the given method is scheduled.
+//
+// Messages that mutate the project are exclusive: they run alone and in the
+// order they were received, after all earlier tasks are done and before any
+// later task is started. Messages that must take effect immediately, such as
+// $/cancelRequest, are not queued at all.
+func taskClass(method string) (priority taskPriority, exclusive, immediate bool) {
+	switch method {
+	case "$/cancelRequest", "$/setTrace":
+		return taskPriorityNormal, false, true
+	case "initialize",
+		"textDocument/didOpen",
+		"textDocument/didChange",
+		"textDocument/didSave",
+		"textDocument/didClose",
+		"workspace/didCreateFiles",
+		"workspace/didDeleteFiles",
+		"workspace/didChangeWatchedFiles",
+		"workspace/didChangeConfiguration":
+		return taskPriorityNormal, true, false
+	case "textDocument/completion",
+		"textDocument/hover",
+		"textDocument/signatureHelp":
+		return taskPriorityInteractive, false, false
+	case "workspace/diagnostic",
+		"textDocument/diagnostic",
+		"textDocument/documentLink",
+		"textDocument/inlayHint",
+		"textDocument/semanticTokens/full":
+		return taskPriorityBackground, false, false
+	}
+	return taskPriorityNormal, false, false
+}
+
+// taskQueue schedules the handling of messages. It limits the number of tasks
+// running at the same time, starts queued tasks by priority, and serializes
+// exclusive tasks against all other tasks.
+//
+// The zero value is ready to use.
+type taskQueue struct {
+	mu               sync.Mutex
+	pending          []*queuedTask // Ordered by arrival.
+	running          int
+	runningExclusive bool
+	maxRunning       int // Zero means [defaultMaxRunningTasks].
+}
+
+// queuedTask is a task waiting in a [taskQueue].
+type queuedTask struct {
+	priority  taskPriority
+	exclusive bool
+	run       func()
+}
+
+// defaultMaxRunningTasks returns the default maximum number of tasks running
+// at the same time. It is at least 2, so one slot can always be reserved for
+// non-background tasks.
+func defaultMaxRunningTasks() int {
+	return max(runtime.GOMAXPROCS(0), 2)
+}
+
+// enqueue queues run for the message with the given method and starts it in
+// a new goroutine once it is its turn.
+func (q *taskQueue) enqueue(method string, run func()) {
+	priority, exclusive, immediate := taskClass(method)
+	if immediate {
+		go run()
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, &queuedTask{
+		priority:  priority,
+		exclusive: exclusive,
+		run:       run,
+	})
+	q.dispatchLocked()
+}
+
+// dispatchLocked starts as many pending tasks as allowed. It must be called
+// with q.mu held.
+func (q *taskQueue) dispatchLocked() {
+	for {
+		i := q.nextLocked()
+		if i < 0 {
+			return
+		}
+		t := q.pending[i]
+		q.pending = slices.Delete(q.pending, i, i+1)
+		q.running++
+		if t.exclusive {
+			q.runningExclusive = true
+		}
+		go q.runTask(t)
+	}
+}
+
+// nextLocked returns the index of the next pending task to start, or -1 if
+// none can be started yet. It must be called with q.mu held.
+func (q *taskQueue) nextLocked() int {
+	maxRunning := q.maxRunning
+	if maxRunning <= 0 {
+		maxRunning = defaultMaxRunningTasks()
+	}
+	if q.runningExclusive || q.running >= maxRunning {
+		return -1
+	}
+
+	next := -1
+	for i, t := range q.pending {
+		if t.exclusive {
+			// An exclusive task is a barrier: it waits for all earlier tasks,
+			// and all later tasks wait for it.
+			if next < 0 && i == 0 && q.running == 0 {
+				return i
+			}
+			break
+		}
+		if t.priority == taskPriorityBackground && q.running >= maxRunning-1 {
+			continue // Keep the last slot for non-background tasks.
+		}
+		if next < 0 || t.priority > q.pending[next].priority {
+			next = i
+		}
+	}
+	return next
+}
+
+// runTask runs t and then starts the tasks that were waiting for it.
+func (q *taskQueue) runTask(t *queuedTask) {
+	defer func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		q.running--
+		if t.exclusive {
+			q.runningExclusive = false
+		}
+		q.dispatchLocked()
+	}()
+	t.run()
+}
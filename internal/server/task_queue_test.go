@@ -0,0 +1,155 @@
+package server
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// taskRecorder records the order in which tasks are started.
+type taskRecorder struct {
+	mu      sync.Mutex
+	started []string
+	wg      sync.WaitGroup
+}
+
+// task returns a task named name that records its start and then blocks until
+// release is closed.
+func (r *taskRecorder) task(name string, release <-chan struct{}) func() {
+	r.wg.Add(1)
+	return func() {
+		defer r.wg.Done()
+		r.mu.Lock()
+		r.started = append(r.started, name)
+		r.mu.Unlock()
+		<-release
+	}
+}
+
+func (r *taskRecorder) getStarted() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.started...)
+}
+
+// waitStarted waits until n tasks have been started.
+func (r *taskRecorder) waitStarted(t *testing.T, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		return len(r.getStarted()) >= n
+	}, time.Second, time.Millisecond)
+}
+
+func TestTaskClass(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		for _, tt := range []struct {
+			method    string
+			priority  taskPriority
+			exclusive bool
+			immediate bool
+		}{
+			{"textDocument/completion", taskPriorityInteractive, false, false},
+			{"textDocument/hover", taskPriorityInteractive, false, false},
+			{"textDocument/signatureHelp", taskPriorityInteractive, false, false},
+			{"textDocument/definition", taskPriorityNormal, false, false},
+			{"workspace/diagnostic", taskPriorityBackground, false, false},
+			{"textDocument/documentLink", taskPriorityBackground, false, false},
+			{"textDocument/didChange", taskPriorityNormal, true, false},
+			{"workspace/didDeleteFiles", taskPriorityNormal, true, false},
+			{"$/cancelRequest", taskPriorityNormal, false, true},
+		} {
+			priority, exclusive, immediate := taskClass(tt.method)
+			assert.Equal(t, tt.priority, priority, tt.method)
+			assert.Equal(t, tt.exclusive, exclusive, tt.method)
+			assert.Equal(t, tt.immediate, immediate, tt.method)
+		}
+	})
+}
+
+func TestTaskQueue(t *testing.T) {
+	t.Run("Priority", func(t *testing.T) {
+		q := &taskQueue{maxRunning: 2}
+		var r taskRecorder
+		block1, block2, release := make(chan struct{}), make(chan struct{}), make(chan struct{})
+
+		q.enqueue("textDocument/definition", r.task("blocker1", block1))
+		q.enqueue("textDocument/definition", r.task("blocker2", block2))
+		r.waitStarted(t, 2)
+
+		q.enqueue("workspace/diagnostic", r.task("background", release))
+		q.enqueue("textDocument/definition", r.task("normal", release))
+		q.enqueue("textDocument/hover", r.task("interactive", release))
+		assert.Len(t, r.getStarted(), 2)
+
+		close(block1)
+		r.waitStarted(t, 3)
+		assert.Equal(t, []string{"interactive"}, r.getStarted()[2:])
+
+		close(block2)
+		r.waitStarted(t, 4)
+		assert.Equal(t, []string{"interactive", "normal"}, r.getStarted()[2:])
+
+		close(release)
+		r.wg.Wait()
+		assert.Equal(t, []string{"interactive", "normal", "background"}, r.getStarted()[2:])
+	})
+
+	t.Run("ReservedSlot", func(t *testing.T) {
+		q := &taskQueue{maxRunning: 2}
+		var r taskRecorder
+		release := make(chan struct{})
+
+		q.enqueue("workspace/diagnostic", r.task("background1", release))
+		r.waitStarted(t, 1)
+		q.enqueue("workspace/diagnostic", r.task("background2", release))
+		q.enqueue("textDocument/completion", r.task("interactive", release))
+		r.waitStarted(t, 2)
+		assert.Equal(t, []string{"background1", "interactive"}, r.getStarted())
+
+		close(release)
+		r.wg.Wait()
+		assert.Equal(t, []string{"background1", "interactive", "background2"}, r.getStarted())
+	})
+
+	t.Run("Exclusive", func(t *testing.T) {
+		q := &taskQueue{maxRunning: 4}
+		var r taskRecorder
+		release1, release2 := make(chan struct{}), make(chan struct{})
+
+		q.enqueue("textDocument/hover", r.task("before", release1))
+		r.waitStarted(t, 1)
+		q.enqueue("textDocument/didChange", r.task("change", release2))
+		q.enqueue("textDocument/completion", r.task("after", release2))
+
+		// The exclusive task waits for earlier tasks, and later tasks wait
+		// for it.
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, []string{"before"}, r.getStarted())
+
+		close(release1)
+		r.waitStarted(t, 2)
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, []string{"before", "change"}, r.getStarted())
+
+		close(release2)
+		r.wg.Wait()
+		assert.Equal(t, []string{"before", "change", "after"}, r.getStarted())
+	})
+
+	t.Run("Immediate", func(t *testing.T) {
+		q := &taskQueue{maxRunning: 2}
+		var r taskRecorder
+		block, release := make(chan struct{}), make(chan struct{})
+		defer close(block)
+
+		q.enqueue("textDocument/didChange", r.task("change", block))
+		r.waitStarted(t, 1)
+		q.enqueue("$/cancelRequest", r.task("cancel", release))
+		r.waitStarted(t, 2)
+		assert.Equal(t, []string{"change", "cancel"}, r.getStarted())
+		close(release)
+	})
+}
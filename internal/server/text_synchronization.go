@@ -2,8 +2,12 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"go/types"
+	"maps"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/goplus/gogen"
@@ -89,35 +93,153 @@ func (s *Server) didClose(params *DidCloseTextDocumentParams) error {
 // It updates the project with file changes and asynchronously publishes diagnostics.
 // The function:
 //  1. Updates the project's files with the provided changes
-//  2. Starts a goroutine to generate and publish diagnostics for each changed file
+//  2. Starts a goroutine to generate and publish diagnostics for each changed file,
+//     and asks the client to pull diagnostics again if spx resources may be affected
 //  3. Returns immediately after updating files for better responsiveness
 func (s *Server) didModifyFile(changes []FileChange) error {
+	paths := make([]string, 0, len(changes))
+	for _, change := range changes {
+		paths = append(paths, change.Path)
+	}
+
 	// 1. Update files synchronously
+	affectsSpxResources := s.affectsSpxResources(s.getProj(), paths)
 	s.ModifyFiles(changes)
 
 	// 2. Asynchronously generate and publish diagnostics
 	// This allows for quick response while diagnostics computation happens in background
-	go func() {
-		for _, change := range changes {
-			// Convert path to URI for diagnostics
-			uri := s.toDocumentURI(change.Path)
-
-			// Get diagnostics from AST and type checking
-			diagnostics, err := s.getDiagnostics(change.Path)
-			if err != nil {
-				// Log error but continue processing other files
-				continue
-			}
+	s.publishDiagnosticsForFiles(paths)
+	if affectsSpxResources {
+		s.refreshDiagnostics()
+	}
 
-			// Publish diagnostics
-			if err := s.publishDiagnostics(uri, diagnostics); err != nil {
-				// Log error but continue
-				continue
-			}
+	return nil
+}
+
+// diagnosticsDebouncer coalesces requests to publish diagnostics for files,
+// so rapid edits result in a single run once the user pauses. The zero value
+// is ready to use.
+type diagnosticsDebouncer struct {
+	mu      sync.Mutex
+	pending map[string]struct{} // Files waiting for the next run.
+	timer   *time.Timer
+	run     *diagnosticsRun // The current run, if any.
+}
+
+// diagnosticsRun is a run of [Server.runPendingDiagnostics].
+type diagnosticsRun struct {
+	cancel context.CancelFunc
+	paths  []string
+}
+
+// stop stops the pending timer and cancels the current run.
+func (d *diagnosticsDebouncer) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	if d.run != nil {
+		d.run.cancel()
+		d.run = nil
+	}
+	clear(d.pending)
+}
+
+// publishDiagnosticsForFiles asynchronously generates and publishes
+// diagnostics for the given files once no further file changes are made
+// within [Settings.DiagnosticsDelay]. A run that is still in progress is
+// cancelled, and its files are published by the next run instead.
+func (s *Server) publishDiagnosticsForFiles(paths []string) {
+	d := &s.diagnosticsDebouncer
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.pending == nil {
+		d.pending = make(map[string]struct{})
+	}
+	for _, path := range paths {
+		d.pending[path] = struct{}{}
+	}
+	if d.run != nil {
+		d.run.cancel()
+		for _, path := range d.run.paths {
+			d.pending[path] = struct{}{}
+		}
+		d.run = nil
+	}
+
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = time.AfterFunc(time.Duration(s.getSettings().DiagnosticsDelay), s.runPendingDiagnostics)
+}
+
+// runPendingDiagnostics generates and publishes diagnostics for the files
+// pending in s.diagnosticsDebouncer. It stops early if it is superseded.
+func (s *Server) runPendingDiagnostics() {
+	d := &s.diagnosticsDebouncer
+	d.mu.Lock()
+	if len(d.pending) == 0 {
+		d.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &diagnosticsRun{
+		cancel: cancel,
+		paths:  slices.Sorted(maps.Keys(d.pending)),
+	}
+	clear(d.pending)
+	if d.run != nil {
+		d.run.cancel()
+		run.paths = append(run.paths, d.run.paths...)
+		slices.Sort(run.paths)
+		run.paths = slices.Compact(run.paths)
+	}
+	d.run = run
+	d.mu.Unlock()
+
+	defer func() {
+		d.mu.Lock()
+		if d.run == run {
+			d.run = nil
 		}
+		d.mu.Unlock()
+		cancel()
 	}()
 
-	return nil
+	for _, path := range run.paths {
+		if err := s.checkContext(ctx); err != nil {
+			return
+		}
+
+		// Get diagnostics from AST and type checking
+		diagnostics, err := s.getDiagnostics(path)
+		if err != nil {
+			// Log error but continue processing other files
+			continue
+		}
+		if ctx.Err() != nil {
+			return // Superseded, so the diagnostics may be stale.
+		}
+
+		// Publish diagnostics
+		if err := s.publishDiagnostics(s.toDocumentURI(path), diagnostics); err != nil {
+			// Log error but continue
+			continue
+		}
+	}
+}
+
+// publishDiagnosticsForSpxFiles asynchronously generates and publishes
+// diagnostics for all spx files in the project. It is used when a change may
+// affect the whole project, such as adding or removing a sprite.
+func (s *Server) publishDiagnosticsForSpxFiles() {
+	spxFiles, err := vfs.ListSpxFiles(s.getProj())
+	if err != nil {
+		return
+	}
+	s.publishDiagnosticsForFiles(spxFiles)
 }
 
 // changedText processes document content changes from the client.
@@ -156,7 +278,10 @@ func (s *Server) applyIncrementalChanges(path string, changes []protocol.TextDoc
 		return nil, fmt.Errorf("%w: file not found", jsonrpc2.ErrInternal)
 	}
 
-	content := file.Content
+	content, err := file.ReadContent()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read file: %w", jsonrpc2.ErrInternal, err)
+	}
 
 	// Apply each change sequentially
 	for _, change := range changes {
@@ -252,7 +377,7 @@ func (s *Server) getDiagnostics(path string) ([]Diagnostic, error) {
 
 	// 2. Get type checking diagnostics
 	// Perform type checking on the file
-	_, err = proj.TypeInfo()
+	_, err = proj.TypeInfoFor(path)
 	if err != nil {
 		// Add type checking errors to diagnostics
 		switch err := err.(type) {
@@ -289,7 +414,8 @@ func (s *Server) ModifyFiles(changes []FileChange) {
 
 		// Check if file exists
 		if oldFile, ok := p.File(change.Path); ok {
-			// Only update if version is newer
+			// Only update if version is newer. If the content is the same,
+			// only the version is recorded, so caches are kept.
 			if change.Version > oldFile.Version {
 				p.PutFile(change.Path, file)
 			}
@@ -1,7 +1,10 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"go/token"
 	"testing"
 	"time"
@@ -11,6 +14,8 @@ import (
 	"github.com/goplus/xgolsw/jsonrpc2"
 	"github.com/goplus/xgolsw/protocol"
 	"github.com/goplus/xgolsw/xgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // MockReplier implements a message replier for testing
@@ -94,6 +99,25 @@ func TestModifyFiles(t *testing.T) {
 				"main.go": "current content",
 			},
 		},
+		{
+			name: "same content with newer version",
+			initial: map[string]*xgo.File{
+				"main.go": &vfs.MapFile{
+					Content: []byte("current content"),
+					Version: 100,
+				},
+			},
+			changes: []FileChange{
+				{
+					Path:    "main.go",
+					Content: []byte("current content"),
+					Version: 200,
+				},
+			},
+			want: map[string]string{
+				"main.go": "current content",
+			},
+		},
 		{
 			name: "multiple file changes",
 			initial: map[string]*xgo.File{
@@ -983,3 +1007,187 @@ func TestGetDiagnostics(t *testing.T) {
 		})
 	}
 }
+
+// publishedDiagnosticsURIs returns the URIs of all textDocument/publishDiagnostics
+// notifications in msgs, in order.
+func publishedDiagnosticsURIs(t *testing.T, msgs []jsonrpc2.Message) []DocumentURI {
+	var uris []DocumentURI
+	for _, msg := range msgs {
+		n, ok := msg.(*jsonrpc2.Notification)
+		if !ok || n.Method() != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var params PublishDiagnosticsParams
+		require.NoError(t, json.Unmarshal(n.Params(), &params))
+		uris = append(uris, params.URI)
+	}
+	return uris
+}
+
+func TestPublishDiagnosticsForFiles(t *testing.T) {
+	t.Run("Debounce", func(t *testing.T) {
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(map[string][]byte{
+			"main.spx": []byte("var x = 1"),
+		}), replier, nil, &MockScheduler{})
+		s.settings.Store(&Settings{DiagnosticsDelay: Duration(100 * time.Millisecond)})
+
+		for i := range 5 {
+			s.ModifyFiles([]FileChange{{
+				Path:    "main.spx",
+				Content: []byte(fmt.Sprintf("var x = %d", i)),
+				Version: i + 1,
+			}})
+			s.publishDiagnosticsForFiles([]string{"main.spx"})
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.Empty(t, publishedDiagnosticsURIs(t, replier.getMessages()))
+
+		require.Eventually(t, func() bool {
+			return len(publishedDiagnosticsURIs(t, replier.getMessages())) > 0
+		}, time.Second, 10*time.Millisecond)
+		time.Sleep(200 * time.Millisecond)
+		assert.Equal(t, []DocumentURI{"file:///main.spx"}, publishedDiagnosticsURIs(t, replier.getMessages()))
+	})
+
+	t.Run("SupersedeRun", func(t *testing.T) {
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(map[string][]byte{
+			"main.spx":     []byte("var x = 1"),
+			"MySprite.spx": []byte("var y = 1"),
+		}), replier, nil, &MockScheduler{})
+
+		// Simulate a run in progress for MySprite.spx.
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		s.diagnosticsDebouncer.run = &diagnosticsRun{cancel: cancel, paths: []string{"MySprite.spx"}}
+
+		s.publishDiagnosticsForFiles([]string{"main.spx"})
+		assert.Error(t, ctx.Err(), "the run in progress should be cancelled")
+
+		// The files of the cancelled run are published by the next run.
+		require.Eventually(t, func() bool {
+			return len(publishedDiagnosticsURIs(t, replier.getMessages())) == 2
+		}, time.Second, 10*time.Millisecond)
+		assert.ElementsMatch(t,
+			[]DocumentURI{"file:///main.spx", "file:///MySprite.spx"},
+			publishedDiagnosticsURIs(t, replier.getMessages()))
+	})
+
+	t.Run("StoppedOnClose", func(t *testing.T) {
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(map[string][]byte{
+			"main.spx": []byte("var x = 1"),
+		}), replier, nil, &MockScheduler{})
+		s.settings.Store(&Settings{DiagnosticsDelay: Duration(50 * time.Millisecond)})
+
+		s.publishDiagnosticsForFiles([]string{"main.spx"})
+		require.NoError(t, s.Close())
+
+		time.Sleep(150 * time.Millisecond)
+		assert.Empty(t, publishedDiagnosticsURIs(t, replier.getMessages()))
+	})
+}
+
+// refreshDiagnosticsCalls returns the number of workspace/diagnostic/refresh
+// calls in msgs.
+func refreshDiagnosticsCalls(msgs []jsonrpc2.Message) int {
+	var n int
+	for _, msg := range msgs {
+		if c, ok := msg.(*jsonrpc2.Call); ok && c.Method() == "workspace/diagnostic/refresh" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDidModifyFileAffectingSpxResources(t *testing.T) {
+	newServer := func(t *testing.T, m map[string][]byte) (*Server, *callRespondingReplier) {
+		replier := &callRespondingReplier{}
+		s := New(newMapFSWithoutModTime(m), replier, nil, &MockScheduler{})
+		replier.s = s
+		s.clientCapabilities.Workspace.Diagnostics = &protocol.DiagnosticWorkspaceClientCapabilities{RefreshSupport: true}
+		t.Cleanup(func() { s.Close() })
+		return s, replier
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		m := newTestFileMap()
+		m["MyAircraft.spx"] = append(m["MyAircraft.spx"], "\nonStart => {\n\tsetCostume \"hero2\"\n}\n"...)
+		s, replier := newServer(t, m)
+		costumeNotFound := Diagnostic{
+			Severity:        SeverityError,
+			Code:            "spx-resource-not-found",
+			CodeDescription: diagnosticCodeDescription("spx-resource-not-found"),
+			Message:         `costume resource "hero2" not found in sprite "MyAircraft"`,
+			Range: Range{
+				Start: Position{Line: 11, Character: 12},
+				End:   Position{Line: 11, Character: 19},
+			},
+			RelatedInformation: []DiagnosticRelatedInformation{{
+				Location: Location{URI: "file:///assets/sprites/MyAircraft/index.json"},
+				Message:  `costume resources of sprite "MyAircraft" are looked up in assets/sprites/MyAircraft/index.json`,
+			}},
+		}
+		pullDiagnostics := func() []Diagnostic {
+			report, err := s.textDocumentDiagnostic(context.Background(), &DocumentDiagnosticParams{
+				TextDocument: TextDocumentIdentifier{URI: "file:///MyAircraft.spx"},
+			})
+			require.NoError(t, err)
+			return report.Value.(RelatedFullDocumentDiagnosticReport).Items
+		}
+		assert.Contains(t, pullDiagnostics(), costumeNotFound)
+
+		require.NoError(t, s.didModifyFile([]FileChange{{
+			Path:    "assets/sprites/MyAircraft/index.json",
+			Content: []byte(`{"costumes":[{"name":"hero","path":"hero.png"},{"name":"hero2","path":"hero2.png"}]}`),
+			Version: 1,
+		}}))
+		require.Eventually(t, func() bool {
+			return refreshDiagnosticsCalls(replier.getMessages()) == 1
+		}, time.Second, 10*time.Millisecond)
+		assert.NotContains(t, pullDiagnostics(), costumeNotFound)
+	})
+
+	t.Run("AssetAdded", func(t *testing.T) {
+		s, replier := newServer(t, newTestFileMap())
+		_, err := s.compile()
+		require.NoError(t, err)
+
+		require.NoError(t, s.didModifyFile([]FileChange{{
+			Path:    "assets/sprites/MyAircraft/hero2.png",
+			Content: []byte("png"),
+			Version: 1,
+		}}))
+		require.Eventually(t, func() bool {
+			return refreshDiagnosticsCalls(replier.getMessages()) == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("CodeChanged", func(t *testing.T) {
+		s, replier := newServer(t, newTestFileMap())
+		_, err := s.compile()
+		require.NoError(t, err)
+
+		require.NoError(t, s.didModifyFile([]FileChange{{
+			Path:    "main.spx",
+			Content: []byte("echo 1\n"),
+			Version: 100,
+		}}))
+		time.Sleep(50 * time.Millisecond)
+		assert.Zero(t, refreshDiagnosticsCalls(replier.getMessages()))
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(newTestFileMap()), replier, nil, &MockScheduler{})
+
+		require.NoError(t, s.didModifyFile([]FileChange{{
+			Path:    "assets/sprites/MyAircraft/hero2.png",
+			Content: []byte("png"),
+			Version: 1,
+		}}))
+		time.Sleep(50 * time.Millisecond)
+		assert.Zero(t, refreshDiagnosticsCalls(replier.getMessages()))
+	})
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/goplus/xgolsw/jsonrpc2"
+	"github.com/goplus/xgolsw/protocol"
+)
+
+// SetLogger sets the logger used by the server for leveled, structured logs,
+// such as request durations, compile times, and cache hit rates. By default,
+// all logs are discarded.
+func (s *Server) SetLogger(logger *slog.Logger) {
+	s.logger.Store(logger)
+}
+
+// getLogger returns the logger of the server.
+func (s *Server) getLogger() *slog.Logger {
+	if logger := s.logger.Load(); logger != nil {
+		return logger
+	}
+	return discardLogger
+}
+
+// discardLogger is a logger that discards all logs.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#setTrace
+func (s *Server) setTrace(params *SetTraceParams) error {
+	s.trace.Store(&params.Value)
+	return nil
+}
+
+// getTrace returns the current trace setting of the client.
+func (s *Server) getTrace() TraceValue {
+	if trace := s.trace.Load(); trace != nil {
+		return *trace
+	}
+	return protocol.Off
+}
+
+// logTrace sends a $/logTrace notification to the client if tracing is
+// enabled. The verbose is only called when the trace setting is verbose.
+//
+// See https://microsoft.github.io/language-server-protocol/specifications/lsp/3.18/specification/#logTrace
+func (s *Server) logTrace(message string, verbose func() string) {
+	trace := s.getTrace()
+	if trace == protocol.Off || trace == "" {
+		return
+	}
+	params := &LogTraceParams{Message: message}
+	if trace == protocol.Verbose && verbose != nil {
+		params.Verbose = verbose()
+	}
+	n, err := jsonrpc2.NewNotification("$/logTrace", params)
+	if err != nil {
+		return
+	}
+	s.replier.ReplyMessage(n)
+}
+
+// logHandled logs how long it took to handle msg, both queued and running,
+// and traces it to the client.
+func (s *Server) logHandled(msg jsonrpc2.Message, initTime, startTime, endTime time.Time, err error) {
+	var (
+		kind   string
+		name   string
+		params json.RawMessage
+		attrs  []any
+	)
+	switch m := msg.(type) {
+	case *jsonrpc2.Call:
+		kind = "request"
+		name = fmt.Sprintf("%s - (%v)", m.Method(), m.ID())
+		params = m.Params()
+		attrs = []any{"method", m.Method(), "id", fmt.Sprint(m.ID())}
+	case *jsonrpc2.Notification:
+		kind = "notification"
+		name = m.Method()
+		params = m.Params()
+		attrs = []any{"method", m.Method()}
+	default:
+		return
+	}
+	duration := endTime.Sub(startTime)
+	attrs = append(attrs, "queued", startTime.Sub(initTime), "duration", duration)
+
+	logger := s.getLogger()
+	message := fmt.Sprintf("Handled %s '%s' in %dms.", kind, name, duration.Milliseconds())
+	if err != nil {
+		logger.Warn("failed to handle "+kind, append(attrs, "error", err)...)
+		message = fmt.Sprintf("Failed to handle %s '%s' in %dms: %v", kind, name, duration.Milliseconds(), err)
+	} else {
+		logger.Debug("handled "+kind, attrs...)
+	}
+	s.logTrace(message, func() string {
+		return fmt.Sprintf("Params: %s", params)
+	})
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/goplus/xgolsw/jsonrpc2"
+	"github.com/goplus/xgolsw/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func logTraceParams(t *testing.T, msgs []jsonrpc2.Message) []LogTraceParams {
+	var params []LogTraceParams
+	for _, msg := range msgs {
+		n, ok := msg.(*jsonrpc2.Notification)
+		if !ok || n.Method() != "$/logTrace" {
+			continue
+		}
+		var p LogTraceParams
+		require.NoError(t, json.Unmarshal(n.Params(), &p))
+		params = append(params, p)
+	}
+	return params
+}
+
+func TestServerSetTrace(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), &mockReplier{}, nil, &MockScheduler{})
+		assert.Equal(t, protocol.Off, s.getTrace())
+
+		require.NoError(t, s.setTrace(&SetTraceParams{Value: protocol.Verbose}))
+		assert.Equal(t, protocol.Verbose, s.getTrace())
+	})
+
+	t.Run("Initialize", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), &mockReplier{}, nil, &MockScheduler{})
+
+		params := &InitializeParams{}
+		trace := protocol.Messages
+		params.Trace = &trace
+		_, err := s.initialize(params)
+		require.NoError(t, err)
+		assert.Equal(t, protocol.Messages, s.getTrace())
+	})
+}
+
+func TestServerLogTrace(t *testing.T) {
+	t.Run("Off", func(t *testing.T) {
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), replier, nil, &MockScheduler{})
+
+		s.logTrace("message", func() string { return "verbose" })
+		assert.Empty(t, replier.getMessages())
+	})
+
+	t.Run("Messages", func(t *testing.T) {
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), replier, nil, &MockScheduler{})
+		require.NoError(t, s.setTrace(&SetTraceParams{Value: protocol.Messages}))
+
+		s.logTrace("message", func() string {
+			t.Fatal("verbose should not be called")
+			return ""
+		})
+		params := logTraceParams(t, replier.getMessages())
+		require.Len(t, params, 1)
+		assert.Equal(t, LogTraceParams{Message: "message"}, params[0])
+	})
+
+	t.Run("Verbose", func(t *testing.T) {
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), replier, nil, &MockScheduler{})
+		require.NoError(t, s.setTrace(&SetTraceParams{Value: protocol.Verbose}))
+
+		s.logTrace("message", func() string { return "verbose" })
+		params := logTraceParams(t, replier.getMessages())
+		require.Len(t, params, 1)
+		assert.Equal(t, LogTraceParams{Message: "message", Verbose: "verbose"}, params[0])
+	})
+}
+
+func TestServerLogHandled(t *testing.T) {
+	t.Run("Call", func(t *testing.T) {
+		replier := &mockReplier{}
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), replier, nil, &MockScheduler{})
+		var logs bytes.Buffer
+		s.SetLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+		require.NoError(t, s.setTrace(&SetTraceParams{Value: protocol.Verbose}))
+
+		call, err := jsonrpc2.NewCall(jsonrpc2.NewIntID(1), "textDocument/hover", map[string]any{"foo": "bar"})
+		require.NoError(t, err)
+		now := time.Now()
+		s.logHandled(call, now, now, now.Add(12*time.Millisecond), nil)
+
+		assert.Contains(t, logs.String(), "level=DEBUG")
+		assert.Contains(t, logs.String(), `msg="handled request" method=textDocument/hover id=1`)
+		assert.Contains(t, logs.String(), "duration=12ms")
+
+		params := logTraceParams(t, replier.getMessages())
+		require.Len(t, params, 1)
+		assert.Equal(t, "Handled request 'textDocument/hover - (1)' in 12ms.", params[0].Message)
+		assert.Equal(t, `Params: {"foo":"bar"}`, params[0].Verbose)
+	})
+
+	t.Run("FailedNotification", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(map[string][]byte{}), &mockReplier{}, nil, &MockScheduler{})
+		var logs bytes.Buffer
+		s.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+		n, err := jsonrpc2.NewNotification("textDocument/didOpen", nil)
+		require.NoError(t, err)
+		now := time.Now()
+		s.logHandled(n, now, now, now, assert.AnError)
+
+		assert.Contains(t, logs.String(), "level=WARN")
+		assert.Contains(t, logs.String(), `msg="failed to handle notification" method=textDocument/didOpen`)
+		assert.Contains(t, logs.String(), "error=")
+	})
+
+	t.Run("Compile", func(t *testing.T) {
+		s := New(newMapFSWithoutModTime(newTestFileMap()), &mockReplier{}, fileMapGetter(newTestFileMap()), &MockScheduler{})
+		var logs bytes.Buffer
+		s.SetLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+		_, err := s.compile()
+		require.NoError(t, err)
+		assert.Contains(t, logs.String(), `msg="compiled project"`)
+		assert.Contains(t, logs.String(), "cacheHitRate=")
+	})
+}
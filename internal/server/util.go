@@ -121,6 +121,18 @@ func PositionOffset(content []byte, position Position) int {
 	return lineOffset + utf8Offset
 }
 
+// OffsetPosition converts a byte offset in the document to an LSP position
+// (line, character), with the character counted in UTF-16 code units. It is
+// the inverse of [PositionOffset].
+func OffsetPosition(content []byte, offset int) Position {
+	offset = max(0, min(offset, len(content)))
+	lineStart := bytes.LastIndexByte(content[:offset], '\n') + 1
+	return Position{
+		Line:      uint32(bytes.Count(content[:lineStart], []byte{'\n'})),
+		Character: uint32(UTF16Len(string(content[lineStart:offset]))),
+	}
+}
+
 // FromPosition converts a [xgotoken.Position] to a [Position].
 func FromPosition(proj *xgo.Project, astFile *xgoast.File, position xgotoken.Position) Position {
 	tokenFile := xgoutil.NodeTokenFile(proj, astFile)
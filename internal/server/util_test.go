@@ -308,3 +308,55 @@ func TestRangesOverlap(t *testing.T) {
 		})
 	}
 }
+
+func TestOffsetPosition(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+		offset  int
+		want    Position
+	}{
+		{
+			name:    "EmptyContent",
+			content: "",
+			offset:  0,
+			want:    Position{Line: 0, Character: 0},
+		},
+		{
+			name:    "FirstLine",
+			content: "abc\ndef",
+			offset:  2,
+			want:    Position{Line: 0, Character: 2},
+		},
+		{
+			name:    "StartOfSecondLine",
+			content: "abc\ndef",
+			offset:  4,
+			want:    Position{Line: 1, Character: 0},
+		},
+		{
+			name:    "CJKCharacters",
+			content: "abc\n世界def",
+			offset:  10,
+			want:    Position{Line: 1, Character: 2},
+		},
+		{
+			name:    "Emoji",
+			content: "😀a",
+			offset:  4,
+			want:    Position{Line: 0, Character: 2},
+		},
+		{
+			name:    "OffsetBeyondContent",
+			content: "abc\ndef",
+			offset:  100,
+			want:    Position{Line: 1, Character: 3},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := OffsetPosition([]byte(tt.content), tt.offset)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, min(tt.offset, len(tt.content)), PositionOffset([]byte(tt.content), got))
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/goplus/xgolsw/xgo"
+)
+
+// DiskFS backs a MapFS with a directory on disk, so that the workspace files
+// can be loaded directly instead of being pushed by the client.
+//
+// Files ignored by the .gitignore or .spxignore file at the root directory are
+// not loaded, such as generated directories and editor backup files.
+//
+// Large asset files, such as sounds and images, are loaded lazily: only their
+// size is kept in memory, and their content is read from disk when needed.
+//
+// Files opened by the client form an in-memory overlay on top of the disk
+// content. Their content in the MapFS is managed by the client, so that
+// unsaved buffers are not overwritten by reloads from disk until they are
+// closed.
+type DiskFS struct {
+	rootFS  *MapFS
+	rootDir string
+
+	mu      sync.Mutex
+	overlay map[string]struct{}
+}
+
+// NewDiskFS creates a new DiskFS that backs rootFS with the directory at
+// rootDir.
+func NewDiskFS(rootFS *MapFS, rootDir string) *DiskFS {
+	return &DiskFS{
+		rootFS:  rootFS,
+		rootDir: filepath.Clean(rootDir),
+		overlay: make(map[string]struct{}),
+	}
+}
+
+// RootDir returns the directory on disk backing the MapFS.
+func (d *DiskFS) RootDir() string {
+	return d.rootDir
+}
+
+// Rel returns the slash-separated path of the local file path p relative to
+// the root directory. It reports false if p is not in the root directory.
+func (d *DiskFS) Rel(p string) (name string, ok bool) {
+	rel, err := filepath.Rel(d.rootDir, p)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// isHidden reports whether any element of the slash-separated path name is
+// hidden.
+func isHidden(name string) bool {
+	for elem := range strings.SplitSeq(name, "/") {
+		if strings.HasPrefix(elem, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Load loads all files in the root directory into the MapFS. Hidden files and
+// directories are skipped, except for ignore files at the root, as are files
+// ignored by them and files in the overlay. Files in the MapFS that are
+// ignored are deleted from it.
+func (d *DiskFS) Load() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.load()
+}
+
+// load is like [DiskFS.Load], but must be called with d.mu held.
+func (d *DiskFS) load() error {
+	// Load ignore files first, as they decide which other files are loaded.
+	entries, err := os.ReadDir(d.rootDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if name := entry.Name(); xgo.IsIgnoreFile(name) && !d.inOverlay(name) {
+			if err := d.readFile(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	err = filepath.WalkDir(d.rootDir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == d.rootDir {
+			return nil
+		}
+		name, ok := d.Rel(p)
+		if !ok {
+			return nil
+		}
+		if entry.IsDir() {
+			if strings.HasPrefix(entry.Name(), ".") || d.rootFS.IsIgnored(name+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") || d.inOverlay(name) {
+			return nil
+		}
+		return d.reload(name)
+	})
+	if err != nil {
+		return err
+	}
+
+	var ignored []string
+	for name := range d.rootFS.Files() {
+		if !xgo.IsIgnoreFile(name) && !d.inOverlay(name) && d.rootFS.IsIgnored(name) {
+			ignored = append(ignored, name)
+		}
+	}
+	for _, name := range ignored {
+		if err := d.rootFS.DeleteFile(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// inOverlay reports whether the file at name is in the overlay. It must be
+// called with d.mu held.
+func (d *DiskFS) inOverlay(name string) bool {
+	_, ok := d.overlay[name]
+	return ok
+}
+
+// Reload reloads the file at the slash-separated path name, relative to the
+// root directory, from disk into the MapFS. The file is deleted from the MapFS
+// if it no longer exists on disk or is ignored. Reloading an ignore file
+// reloads all files, as it may change which of them are ignored. Hidden files
+// and files in the overlay are left as they are.
+func (d *DiskFS) Reload(name string) error {
+	if !fs.ValidPath(name) || (isHidden(name) && !xgo.IsIgnoreFile(name)) {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.inOverlay(name) {
+		return nil
+	}
+	return d.reload(name)
+}
+
+// reload is like [DiskFS.Reload], but must be called with d.mu held.
+func (d *DiskFS) reload(name string) error {
+	if xgo.IsIgnoreFile(name) {
+		if err := d.readFile(name); err != nil {
+			return err
+		}
+		return d.load()
+	}
+	if d.rootFS.IsIgnored(name) {
+		if err := d.rootFS.DeleteFile(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return d.readFile(name)
+}
+
+// readFile reads the file at name from disk into the MapFS, or deletes it
+// from the MapFS if it does not exist on disk. It must be called with d.mu
+// held.
+func (d *DiskFS) readFile(name string) error {
+	p := filepath.Join(d.rootDir, filepath.FromSlash(name))
+	info, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		if err := d.rootFS.DeleteFile(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+	if isLazyFile(name, info.Size()) {
+		d.rootFS.PutFile(name, &MapFile{
+			ModTime: info.ModTime(),
+			Source:  func() (io.ReadCloser, error) { return os.Open(p) },
+			Size:    info.Size(),
+		})
+		return nil
+	}
+	content, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	d.rootFS.PutFile(name, &MapFile{Content: content, ModTime: info.ModTime()})
+	return nil
+}
+
+// lazyFileMinSize is the minimum size of an asset file, such as a sound or an
+// image, for its content to be loaded lazily from disk instead of being held
+// in memory.
+const lazyFileMinSize = 256 << 10
+
+// isLazyFile reports whether the content of the file at the slash-separated
+// path name with the given size should be loaded lazily. Source files and
+// metadata files are always loaded, as they are read on every compilation.
+func isLazyFile(name string, size int64) bool {
+	if size < lazyFileMinSize {
+		return false
+	}
+	switch path.Ext(name) {
+	case ".spx", ".xgo", ".gop", ".go", ".json", ".mod":
+		return false
+	}
+	return true
+}
+
+// Open adds the file at the slash-separated path name to the overlay, so that
+// its content in the MapFS is managed by the client until it is closed.
+func (d *DiskFS) Open(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.overlay[path.Clean(name)] = struct{}{}
+}
+
+// Close removes the file at the slash-separated path name from the overlay
+// and reloads it from disk, which discards unsaved changes made by the client.
+func (d *DiskFS) Close(name string) error {
+	name = path.Clean(name)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.overlay[name]; !ok {
+		return nil
+	}
+	delete(d.overlay, name)
+	if !fs.ValidPath(name) || (isHidden(name) && !xgo.IsIgnoreFile(name)) {
+		return nil
+	}
+	return d.reload(name)
+}
@@ -0,0 +1,162 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goplus/xgolsw/xgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskFS(t *testing.T) {
+	newDiskFS := func(t *testing.T, files map[string]string) (*DiskFS, *MapFS, string) {
+		rootDir := t.TempDir()
+		for name, content := range files {
+			p := filepath.Join(rootDir, filepath.FromSlash(name))
+			require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+			require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+		}
+		rootFS := xgo.NewProject(nil, nil, xgo.FeatAll)
+		return NewDiskFS(rootFS, rootDir), rootFS, rootDir
+	}
+	readFile := func(t *testing.T, rootFS *MapFS, name string) string {
+		content, err := ReadFile(rootFS, name)
+		require.NoError(t, err)
+		return string(content)
+	}
+
+	t.Run("Load", func(t *testing.T) {
+		d, rootFS, _ := newDiskFS(t, map[string]string{
+			"main.spx":            `run "assets", {Title: "My Game"}`,
+			"assets/index.json":   `{}`,
+			".git/config":         `[core]`,
+			"assets/.DS_Store":    ``,
+			"MySprite.spx":        `onStart => {}`,
+			"assets/sounds/a.wav": `RIFF`,
+		})
+		require.NoError(t, d.Load())
+
+		var names []string
+		for name := range rootFS.Files() {
+			names = append(names, name)
+		}
+		assert.ElementsMatch(t, []string{"main.spx", "assets/index.json", "MySprite.spx", "assets/sounds/a.wav"}, names)
+		assert.Equal(t, `onStart => {}`, readFile(t, rootFS, "MySprite.spx"))
+	})
+
+	t.Run("Reload", func(t *testing.T) {
+		d, rootFS, rootDir := newDiskFS(t, map[string]string{"main.spx": `echo 1`})
+		require.NoError(t, d.Load())
+
+		require.NoError(t, os.WriteFile(filepath.Join(rootDir, "main.spx"), []byte(`echo 2`), 0o644))
+		require.NoError(t, d.Reload("main.spx"))
+		assert.Equal(t, `echo 2`, readFile(t, rootFS, "main.spx"))
+
+		require.NoError(t, os.Remove(filepath.Join(rootDir, "main.spx")))
+		require.NoError(t, d.Reload("main.spx"))
+		_, ok := rootFS.File("main.spx")
+		assert.False(t, ok)
+
+		require.NoError(t, d.Reload("../outside.spx"))
+		require.NoError(t, d.Reload(".hidden.spx"))
+	})
+
+	t.Run("Overlay", func(t *testing.T) {
+		d, rootFS, rootDir := newDiskFS(t, map[string]string{"main.spx": `echo 1`})
+		require.NoError(t, d.Load())
+
+		d.Open("main.spx")
+		rootFS.PutFile("main.spx", &MapFile{Content: []byte(`echo "unsaved"`)})
+		require.NoError(t, os.WriteFile(filepath.Join(rootDir, "main.spx"), []byte(`echo 2`), 0o644))
+		require.NoError(t, d.Reload("main.spx"))
+		assert.Equal(t, `echo "unsaved"`, readFile(t, rootFS, "main.spx"))
+
+		require.NoError(t, d.Close("main.spx"))
+		assert.Equal(t, `echo 2`, readFile(t, rootFS, "main.spx"))
+	})
+
+	t.Run("LazyLoad", func(t *testing.T) {
+		sound := strings.Repeat("a", lazyFileMinSize)
+		metadata := `{"names":[` + strings.Repeat(`"x",`, lazyFileMinSize/4) + `"x"]}`
+		d, rootFS, rootDir := newDiskFS(t, map[string]string{
+			"assets/sounds/a.wav":      sound,
+			"assets/sounds/b.wav":      `RIFF`,
+			"assets/sounds/index.json": metadata,
+		})
+		require.NoError(t, d.Load())
+
+		file, ok := rootFS.File("assets/sounds/a.wav")
+		require.True(t, ok)
+		assert.True(t, file.IsLazy())
+		assert.Nil(t, file.Content)
+		assert.Equal(t, int64(len(sound)), file.Len())
+		assert.Equal(t, sound, readFile(t, rootFS, "assets/sounds/a.wav"))
+
+		file, ok = rootFS.File("assets/sounds/b.wav")
+		require.True(t, ok)
+		assert.False(t, file.IsLazy())
+
+		file, ok = rootFS.File("assets/sounds/index.json")
+		require.True(t, ok)
+		assert.False(t, file.IsLazy())
+		assert.Equal(t, metadata, string(file.Content))
+
+		// Reloading an unchanged lazily loaded file keeps the generation.
+		gen := rootFS.Generation()
+		require.NoError(t, d.Reload("assets/sounds/a.wav"))
+		assert.Equal(t, gen, rootFS.Generation())
+
+		// The content is read from disk when needed.
+		require.NoError(t, os.WriteFile(filepath.Join(rootDir, "assets", "sounds", "a.wav"), []byte(sound+"b"), 0o644))
+		require.NoError(t, d.Reload("assets/sounds/a.wav"))
+		assert.Greater(t, rootFS.Generation(), gen)
+		assert.Equal(t, sound+"b", readFile(t, rootFS, "assets/sounds/a.wav"))
+	})
+
+	t.Run("Ignore", func(t *testing.T) {
+		d, rootFS, rootDir := newDiskFS(t, map[string]string{
+			".spxignore":           "build/\n*~\n",
+			"main.spx":             `echo 1`,
+			"main.spx~":            `echo 0`,
+			"build/out.spx":        `echo 2`,
+			"assets/sounds/a.json": `{}`,
+		})
+		require.NoError(t, d.Load())
+
+		var names []string
+		for name := range rootFS.Files() {
+			names = append(names, name)
+		}
+		assert.ElementsMatch(t, []string{".spxignore", "main.spx", "assets/sounds/a.json"}, names)
+
+		require.NoError(t, d.Reload("build/out.spx"))
+		_, ok := rootFS.File("build/out.spx")
+		assert.False(t, ok)
+
+		// Changing the ignore file reloads all files.
+		require.NoError(t, os.WriteFile(filepath.Join(rootDir, ".spxignore"), []byte("assets/\n"), 0o644))
+		require.NoError(t, d.Reload(".spxignore"))
+		names = nil
+		for name := range rootFS.Files() {
+			names = append(names, name)
+		}
+		assert.ElementsMatch(t, []string{".spxignore", "main.spx", "main.spx~", "build/out.spx"}, names)
+	})
+
+	t.Run("Rel", func(t *testing.T) {
+		d, _, rootDir := newDiskFS(t, nil)
+
+		name, ok := d.Rel(filepath.Join(rootDir, "assets", "index.json"))
+		assert.True(t, ok)
+		assert.Equal(t, "assets/index.json", name)
+
+		_, ok = d.Rel(rootDir)
+		assert.False(t, ok)
+
+		_, ok = d.Rel(filepath.Join(filepath.Dir(rootDir), "other.spx"))
+		assert.False(t, ok)
+	})
+}
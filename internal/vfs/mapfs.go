@@ -1,6 +1,7 @@
 package vfs
 
 import (
+	"io"
 	"io/fs"
 	"path"
 	"sort"
@@ -13,13 +14,16 @@ import (
 type MapFile = xgo.File
 type MapFS = xgo.Project
 
-// RangeSpriteNames iterates sprite names.
+// RangeSpriteNames iterates sprite names. Ignored files are skipped.
 func RangeSpriteNames(rootFS *MapFS, f func(name string) bool) {
 	for filename := range rootFS.Files() {
 		if filename == "main.spx" {
 			// Skip the main.spx file, as it is not a sprite file.
 			continue
 		}
+		if rootFS.IsIgnored(filename) {
+			continue
+		}
 
 		name := path.Base(filename)
 		if strings.HasSuffix(name, ".spx") {
@@ -30,32 +34,40 @@ func RangeSpriteNames(rootFS *MapFS, f func(name string) bool) {
 	}
 }
 
-// ListSpxFiles returns a list of .spx files in the rootFS.
+// ListSpxFiles returns a list of .spx files in the rootFS. Ignored files are
+// skipped.
 func ListSpxFiles(rootFS *MapFS) (files []string, err error) {
 	for path := range rootFS.Files() {
-		if strings.HasSuffix(path, ".spx") {
+		if strings.HasSuffix(path, ".spx") && !rootFS.IsIgnored(path) {
 			files = append(files, path)
 		}
 	}
 	return
 }
 
-// WithOverlay returns a new MapFS with overlay files.
+// WithOverlay returns a new MapFS with overlay files. See [xgo.Project.Fork].
 func WithOverlay(rootFS *MapFS, overlay map[string]*MapFile) *MapFS {
-	ret := rootFS.Snapshot()
-	for k, v := range overlay {
-		ret.PutFile(k, v)
-	}
-	return ret
+	return rootFS.Fork(overlay)
 }
 
-// ReadFile reads a file from the rootFS.
+// ReadFile reads a file from the rootFS. The content of a lazily loaded file
+// is read from its source.
 func ReadFile(rootFS *MapFS, name string) ([]byte, error) {
 	ret, ok := rootFS.File(name)
 	if !ok {
 		return nil, fs.ErrNotExist
 	}
-	return ret.Content, nil
+	return ret.ReadContent()
+}
+
+// OpenFile opens a file from the rootFS for reading, which does not load the
+// whole content of a lazily loaded file into memory.
+func OpenFile(rootFS *MapFS, name string) (io.ReadCloser, error) {
+	ret, ok := rootFS.File(name)
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return ret.Open()
 }
 
 type SubFS struct {
@@ -67,16 +79,18 @@ func (fs SubFS) ReadFile(name string) ([]byte, error) {
 	return ReadFile(fs.root, fs.base+"/"+name)
 }
 
+// Readdir reads the directory at name. Ignored files are skipped, as are
+// directories that only contain ignored files.
 func (fs SubFS) Readdir(name string) (ret []fs.FileInfo, err error) {
 	prefix := fs.base + "/" + name + "/"
-	entries := map[string]int{}
+	entries := map[string]int64{}
 	for path, file := range fs.root.Files() {
-		if strings.HasPrefix(path, prefix) {
+		if strings.HasPrefix(path, prefix) && !fs.root.IsIgnored(path) {
 			name := path[len(prefix):]
 			if i := strings.Index(name, "/"); i >= 0 {
 				entries[name[:i]] = -1
 			} else {
-				entries[name] = len(file.Content)
+				entries[name] = file.Len()
 			}
 		}
 	}
@@ -84,7 +98,7 @@ func (fs SubFS) Readdir(name string) (ret []fs.FileInfo, err error) {
 		if size < 0 {
 			ret = append(ret, xfs.NewDirInfo(name))
 		} else {
-			ret = append(ret, xfs.NewFileInfo(name, int64(size)))
+			ret = append(ret, xfs.NewFileInfo(name, size))
 		}
 	}
 	sort.Slice(ret, func(i, j int) bool {
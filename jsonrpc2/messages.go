@@ -5,6 +5,7 @@
 package jsonrpc2
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,7 +14,7 @@ import (
 // Message is the interface to all jsonrpc2 message types.
 // They share no common functionality, but are a closed set of concrete types
 // that are allowed to implement this interface. The message types are *Call,
-// *Notification and *Response.
+// *Notification, *Response and Batch.
 type Message interface {
 	// isJSONRPC2Message is used to make the set of message implementations a
 	// closed set.
@@ -63,6 +64,15 @@ type Response struct {
 	id ID
 }
 
+// Batch is a list of messages sent together as a single JSON array. The
+// elements of a batch are never batches themselves.
+//
+// A batch of requests is replied with a batch of the responses to its calls,
+// in any order. No reply is sent if the batch contains no calls.
+type Batch []Message
+
+func (Batch) isJSONRPC2Message() {}
+
 // NewNotification constructs a new Notification message for the supplied
 // method and parameters.
 func NewNotification(method string, params interface{}) (*Notification, error) {
@@ -193,7 +203,37 @@ func (r *Response) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// DecodeMessage decodes a single message, or a [Batch] if data is a JSON
+// array.
 func DecodeMessage(data []byte) (Message, error) {
+	if trimmed := bytes.TrimLeft(data, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+		return decodeBatch(trimmed)
+	}
+	return decodeMessage(data)
+}
+
+// decodeBatch decodes a [Batch] from a JSON array.
+func decodeBatch(data []byte) (Batch, error) {
+	var elems []json.RawMessage
+	if err := json.Unmarshal(data, &elems); err != nil {
+		return nil, fmt.Errorf("unmarshaling jsonrpc batch: %w", err)
+	}
+	if len(elems) == 0 {
+		return nil, fmt.Errorf("%w: empty batch", ErrInvalidRequest)
+	}
+	batch := make(Batch, 0, len(elems))
+	for i, elem := range elems {
+		msg, err := decodeMessage(elem)
+		if err != nil {
+			return nil, fmt.Errorf("decoding batch element %d: %w", i, err)
+		}
+		batch = append(batch, msg)
+	}
+	return batch, nil
+}
+
+// decodeMessage decodes a single message.
+func decodeMessage(data []byte) (Message, error) {
 	msg := wireCombined{}
 	if err := json.Unmarshal(data, &msg); err != nil {
 		return nil, fmt.Errorf("unmarshaling jsonrpc message: %w", err)
@@ -0,0 +1,74 @@
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeMessage(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		msg, err := DecodeMessage([]byte(`{"jsonrpc":"2.0","id":1,"method":"shutdown"}`))
+		require.NoError(t, err)
+		call, ok := msg.(*Call)
+		require.True(t, ok)
+		assert.Equal(t, NewIntID(1), call.ID())
+		assert.Equal(t, "shutdown", call.Method())
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		msg, err := DecodeMessage([]byte(` [
+			{"jsonrpc":"2.0","method":"textDocument/didChange","params":{}},
+			{"jsonrpc":"2.0","id":1,"method":"textDocument/completion","params":{}},
+			{"jsonrpc":"2.0","id":"2","result":null}
+		]`))
+		require.NoError(t, err)
+		batch, ok := msg.(Batch)
+		require.True(t, ok)
+		require.Len(t, batch, 3)
+		assert.IsType(t, &Notification{}, batch[0])
+		assert.IsType(t, &Call{}, batch[1])
+		assert.IsType(t, &Response{}, batch[2])
+	})
+
+	t.Run("EmptyBatch", func(t *testing.T) {
+		_, err := DecodeMessage([]byte(`[]`))
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+	})
+
+	t.Run("NestedBatch", func(t *testing.T) {
+		_, err := DecodeMessage([]byte(`[[{"jsonrpc":"2.0","method":"exit"}]]`))
+		assert.Error(t, err)
+	})
+
+	t.Run("InvalidBatchElement", func(t *testing.T) {
+		_, err := DecodeMessage([]byte(`[{"jsonrpc":"2.0","method":"exit"},{"jsonrpc":"2.0"}]`))
+		assert.ErrorIs(t, err, ErrInvalidRequest)
+	})
+}
+
+func TestBatchMarshalJSON(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		resp1, err := NewResponse(NewIntID(1), "foo", nil)
+		require.NoError(t, err)
+		resp2, err := NewResponse(NewIntID(2), nil, ErrMethodNotFound)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(Batch{resp1, resp2})
+		require.NoError(t, err)
+
+		msg, err := DecodeMessage(data)
+		require.NoError(t, err)
+		batch, ok := msg.(Batch)
+		require.True(t, ok)
+		require.Len(t, batch, 2)
+		assert.Equal(t, NewIntID(1), batch[0].(*Response).ID())
+		assert.JSONEq(t, `"foo"`, string(batch[0].(*Response).Result()))
+		assert.Equal(t, NewIntID(2), batch[1].(*Response).ID())
+		var wireErr *WireError
+		require.ErrorAs(t, batch[1].(*Response).Err(), &wireErr)
+		assert.Equal(t, int64(-32601), wireErr.Code)
+	})
+}
@@ -0,0 +1,69 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bufferConn is an [io.ReadWriteCloser] that reads from in and writes to out.
+type bufferConn struct {
+	in  io.Reader
+	out bytes.Buffer
+}
+
+func (c *bufferConn) Read(p []byte) (int, error)  { return c.in.Read(p) }
+func (c *bufferConn) Write(p []byte) (int, error) { return c.out.Write(p) }
+func (c *bufferConn) Close() error                { return nil }
+
+func TestHeaderStream(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		call, err := NewCall(NewIntID(1), "initialize", map[string]any{"rootUri": "file:///"})
+		require.NoError(t, err)
+
+		writeConn := &bufferConn{in: &bytes.Buffer{}}
+		_, err = NewHeaderStream(writeConn).Write(context.Background(), call)
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(writeConn.out.Bytes(), []byte("Content-Length: ")))
+
+		readConn := &bufferConn{in: bytes.NewReader(writeConn.out.Bytes())}
+		stream := NewHeaderStream(readConn)
+		msg, _, err := stream.Read(context.Background())
+		require.NoError(t, err)
+		got, ok := msg.(*Call)
+		require.True(t, ok)
+		assert.Equal(t, call.ID(), got.ID())
+		assert.Equal(t, "initialize", got.Method())
+		assert.JSONEq(t, `{"rootUri":"file:///"}`, string(got.Params()))
+
+		_, _, err = stream.Read(context.Background())
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("UnknownHeader", func(t *testing.T) {
+		data := "Content-Type: application/vscode-jsonrpc; charset=utf-8\r\nContent-Length: 35\r\n\r\n" +
+			`{"jsonrpc":"2.0","method":"exit"}  `
+		stream := NewHeaderStream(&bufferConn{in: bytes.NewReader([]byte(data))})
+		msg, _, err := stream.Read(context.Background())
+		require.NoError(t, err)
+		n, ok := msg.(*Notification)
+		require.True(t, ok)
+		assert.Equal(t, "exit", n.Method())
+	})
+
+	t.Run("MissingContentLength", func(t *testing.T) {
+		stream := NewHeaderStream(&bufferConn{in: bytes.NewReader([]byte("\r\n{}"))})
+		_, _, err := stream.Read(context.Background())
+		assert.ErrorContains(t, err, "missing Content-Length header")
+	})
+
+	t.Run("UnexpectedEOF", func(t *testing.T) {
+		stream := NewHeaderStream(&bufferConn{in: bytes.NewReader([]byte("Content-Length: 10"))})
+		_, _, err := stream.Read(context.Background())
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+}
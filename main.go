@@ -3,12 +3,16 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"syscall/js"
 	"time"
 
+	"github.com/goplus/xgolsw/internal"
 	"github.com/goplus/xgolsw/internal/pkgdata"
 	"github.com/goplus/xgolsw/internal/server"
 	"github.com/goplus/xgolsw/internal/vfs"
@@ -25,8 +29,8 @@ type Spxls struct {
 
 // NewSpxls creates a new instance of [Spxls].
 func NewSpxls(this js.Value, args []js.Value) any {
-	if len(args) != 2 {
-		return errors.New("NewSpxls: expected 2 arguments")
+	if len(args) != 2 && len(args) != 3 {
+		return errors.New("NewSpxls: expected 2 or 3 arguments")
 	}
 	if args[0].Type() != js.TypeFunction {
 		return errors.New("NewSpxls: filesProvider argument must be a function")
@@ -34,6 +38,25 @@ func NewSpxls(this js.Value, args []js.Value) any {
 	if args[1].Type() != js.TypeFunction {
 		return errors.New("NewSpxls: messageReplier argument must be a function")
 	}
+	var feats uint = xgo.FeatAll
+	if len(args) == 3 && !args[2].IsUndefined() && !args[2].IsNull() {
+		if args[2].Type() != js.TypeObject {
+			return errors.New("NewSpxls: options argument must be an object")
+		}
+		if features := args[2].Get("features"); !features.IsUndefined() {
+			if features.Type() != js.TypeString {
+				return errors.New("NewSpxls: options.features must be a string")
+			}
+			var err error
+			feats, err = xgo.ParseFeatures(features.String())
+			if err == nil && feats&xgo.FeatASTCache == 0 {
+				err = errors.New("no features enabled")
+			}
+			if err != nil {
+				return fmt.Errorf("NewSpxls: invalid options.features: %w", err)
+			}
+		}
+	}
 	filesProvider := args[0]
 	s := &Spxls{
 		messageReplier: args[1],
@@ -44,7 +67,7 @@ func NewSpxls(this js.Value, args []js.Value) any {
 		return ConvertJSFilesToMap(files)
 	}
 	scheduler := &JSScheduler{}
-	s.server = server.New(xgo.NewProject(nil, filesMapGetter(), xgo.FeatAll), s, filesMapGetter, scheduler)
+	s.server = server.New(xgo.NewProject(nil, filesMapGetter(), feats), s, filesMapGetter, scheduler)
 	return js.ValueOf(map[string]any{
 		"handleMessage": JSFuncOfWithError(s.HandleMessage),
 	})
@@ -121,6 +144,89 @@ func SetCustomPkgdataZip(this js.Value, args []js.Value) any {
 	return nil
 }
 
+// SetCacheMemoryBudget sets the approximate amount of memory in MiB that
+// caches kept across compilations, such as spx definitions of imported
+// packages, may use.
+func SetCacheMemoryBudget(this js.Value, args []js.Value) any {
+	if len(args) != 1 {
+		return errors.New("SetCacheMemoryBudget: expected 1 argument")
+	}
+	if args[0].Type() != js.TypeNumber || args[0].Int() <= 0 {
+		return errors.New("SetCacheMemoryBudget: argument must be a positive number")
+	}
+	server.SetCacheMemoryBudget(int64(args[0].Int()) << 20)
+	return nil
+}
+
+// SetModuleFetcher sets the function that fetches the zip archives of the
+// third-party modules required by projects, in the format served by a Go
+// module proxy. The function is called with the module path and version, and
+// returns a Uint8Array, null if there is no such module version, or a promise
+// of either.
+func SetModuleFetcher(this js.Value, args []js.Value) any {
+	if len(args) != 1 {
+		return errors.New("SetModuleFetcher: expected 1 argument")
+	}
+	if args[0].Type() != js.TypeFunction {
+		return errors.New("SetModuleFetcher: argument must be a function")
+	}
+	fetcher := args[0]
+	internal.Importer.SetModCache(internal.NewModCache(nil, func(modulePath, version string) ([]byte, error) {
+		result, err := JSAwait(func() js.Value {
+			return fetcher.Invoke(modulePath, version)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch module %s@%s: %w", modulePath, version, err)
+		}
+		if result.IsNull() || result.IsUndefined() {
+			return nil, fmt.Errorf("module %s@%s: %w", modulePath, version, fs.ErrNotExist)
+		}
+		if !result.InstanceOf(js.Global().Get("Uint8Array")) {
+			return nil, fmt.Errorf("failed to fetch module %s@%s: result must be a Uint8Array", modulePath, version)
+		}
+		return JSUint8ArrayToBytes(result), nil
+	}))
+	return nil
+}
+
+// JSAwait calls fn and waits for the promise it returns, if any, to settle.
+// A non-promise result is returned as is. Errors thrown by fn and rejections
+// of the promise are returned as errors.
+func JSAwait(fn func() js.Value) (result js.Value, err error) {
+	// Catch potential panics during JavaScript execution.
+	defer func() {
+		if r := recover(); r != nil {
+			if jsErr, ok := r.(js.Error); ok {
+				err = fmt.Errorf("client error: %w", jsErr)
+			} else {
+				err = fmt.Errorf("client panic: %v", r)
+			}
+		}
+	}()
+
+	value := fn()
+	if !value.InstanceOf(js.Global().Get("Promise")) {
+		return value, nil
+	}
+
+	done := make(chan struct{})
+	onFulfilled := js.FuncOf(func(this js.Value, args []js.Value) any {
+		result = args[0]
+		close(done)
+		return nil
+	})
+	defer onFulfilled.Release()
+	onRejected := js.FuncOf(func(this js.Value, args []js.Value) any {
+		err = fmt.Errorf("client error: %w", js.Error{Value: args[0]})
+		close(done)
+		return nil
+	})
+	defer onRejected.Release()
+	value.Call("then", onFulfilled, onRejected)
+	<-done
+	return result, err
+}
+
 // SetClassfileAutoImportedPackages sets the auto-imported packages for the
 // classfile specified by id.
 func SetClassfileAutoImportedPackages(this js.Value, args []js.Value) any {
@@ -171,6 +277,11 @@ func JSUint8ArrayToBytes(uint8Array js.Value) []byte {
 }
 
 // ConvertJSFilesToMap converts a JavaScript object of files to a map.
+//
+// Each file either has its content as a Uint8Array, or is loaded lazily by
+// giving its size and a load function that returns its content, or a promise
+// of it, every time it is needed. Lazy loading suits large asset files, such
+// as sounds and images, whose content is rarely needed.
 func ConvertJSFilesToMap(files js.Value) map[string]*vfs.MapFile {
 	if files.Type() != js.TypeObject {
 		return nil
@@ -180,19 +291,46 @@ func ConvertJSFilesToMap(files js.Value) map[string]*vfs.MapFile {
 	for i := range keys.Length() {
 		key := keys.Index(i).String()
 		value := files.Get(key)
-		if value.InstanceOf(js.Global().Get("Object")) {
+		if !value.InstanceOf(js.Global().Get("Object")) {
+			continue
+		}
+		if load := value.Get("load"); load.Type() == js.TypeFunction {
 			result[key] = &vfs.MapFile{
-				Content: JSUint8ArrayToBytes(value.Get("content")),
-				ModTime: time.UnixMilli(int64(value.Get("modTime").Int())),
+				Source: newJSFileSource(key, load),
+				Size:   int64(value.Get("size").Int()),
 			}
+			continue
+		}
+		result[key] = &vfs.MapFile{
+			Content: JSUint8ArrayToBytes(value.Get("content")),
+			ModTime: time.UnixMilli(int64(value.Get("modTime").Int())),
 		}
 	}
 	return result
 }
 
+// newJSFileSource returns a source of a lazily loaded file at name that reads
+// its content by calling the JavaScript function load.
+func newJSFileSource(name string, load js.Value) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		result, err := JSAwait(func() js.Value {
+			return load.Invoke()
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load file %s: %w", name, err)
+		}
+		if !result.InstanceOf(js.Global().Get("Uint8Array")) {
+			return nil, fmt.Errorf("failed to load file %s: result must be a Uint8Array", name)
+		}
+		return io.NopCloser(bytes.NewReader(JSUint8ArrayToBytes(result))), nil
+	}
+}
+
 func main() {
 	js.Global().Set("NewSpxls", JSFuncOfWithError(NewSpxls))
 	js.Global().Set("SetCustomPkgdataZip", JSFuncOfWithError(SetCustomPkgdataZip))
 	js.Global().Set("SetClassfileAutoImportedPackages", JSFuncOfWithError(SetClassfileAutoImportedPackages))
+	js.Global().Set("SetModuleFetcher", JSFuncOfWithError(SetModuleFetcher))
+	js.Global().Set("SetCacheMemoryBudget", JSFuncOfWithError(SetCacheMemoryBudget))
 	select {}
 }
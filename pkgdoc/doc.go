@@ -17,9 +17,13 @@
 package pkgdoc
 
 import (
+	"bytes"
 	"go/ast"
 	"go/doc"
+	"go/format"
+	"go/printer"
 	"go/token"
+	"strconv"
 	"strings"
 )
 
@@ -32,6 +36,21 @@ type PkgDoc struct {
 	Consts map[string]string
 	Types  map[string]*TypeDoc
 	Funcs  map[string]string
+
+	// Overloads are the overload groups of functions declared by Gopo_
+	// constants, keyed by the names of the groups.
+	Overloads map[string]*OverloadDoc `json:",omitempty"`
+
+	// Examples are the runnable examples of the package, keyed by the names
+	// of the members they exemplify, which are "" for the package itself,
+	// the names of functions and types, and "Type.Method" for methods.
+	Examples map[string][]Example `json:",omitempty"`
+
+	// Categories are the categories of the APIs of the package, such as
+	// "motion", keyed by their XGo names, such as "turn" for Turn. They are
+	// only set for packages whose APIs are presented by category, such as
+	// spx.
+	Categories map[string]string `json:",omitempty"`
 }
 
 // typeDoc returns the documentation for the given type name. It creates a new
@@ -46,20 +65,92 @@ func (p *PkgDoc) typeDoc(typeName string) *TypeDoc {
 	return p.Types[typeName]
 }
 
+// OverloadDocOf returns the documentation of the overload group declared by a
+// Gopo_ constant that the function or method name belongs to. The receiver
+// type name is empty for a function.
+func (p *PkgDoc) OverloadDocOf(recvTypeName, name string) (*OverloadDoc, bool) {
+	overloads := p.Overloads
+	if recvTypeName != "" {
+		typeDoc, ok := p.Types[recvTypeName]
+		if !ok {
+			return nil, false
+		}
+		overloads = typeDoc.Overloads
+	}
+	for _, overloadDoc := range overloads {
+		for _, member := range overloadDoc.Members {
+			if member == name || (recvTypeName != "" && member == "."+name) {
+				return overloadDoc, true
+			}
+		}
+	}
+	return nil, false
+}
+
 // TypeDoc is the documentation for a type.
 type TypeDoc struct {
 	Doc     string
 	Fields  map[string]string
 	Methods map[string]string
+
+	// Overloads are the overload groups of methods declared by Gopo_
+	// constants, keyed by the names of the groups.
+	Overloads map[string]*OverloadDoc `json:",omitempty"`
+}
+
+// OverloadDoc is the documentation for an overload group declared by a Gopo_
+// constant, such as
+//
+//	const Gopo_Sprite_Step = ".Step__0,.stepTo,"
+type OverloadDoc struct {
+	// Doc is the documentation of the Gopo_ constant, which documents the
+	// group as a whole.
+	Doc string
+
+	// Members are the names of the overloads in the group, in order. The
+	// name of a method starts with ".", and an empty slot of the constant
+	// stands for the name of the group suffixed with "__" and its index,
+	// such as ".Step__2".
+	Members []string
+}
+
+// Example is a runnable example found in the test files of a package.
+type Example struct {
+	// Suffix is the suffix of the name of the example function, which
+	// distinguishes the examples of the same member, such as "second" of
+	// ExampleFoo_second.
+	Suffix string `json:",omitempty"`
+
+	// Code is the formatted body of the example function.
+	Code string
+
+	// Output is the expected output of the example, if any.
+	Output string `json:",omitempty"`
 }
 
 // NewGo creates a new [PkgDoc] from the given Go [ast.Package].
 func NewGo(pkgPath string, pkg *ast.Package) *PkgDoc {
 	docPkg := doc.New(pkg, pkgPath, doc.AllDecls|doc.AllMethods|doc.PreserveAST)
+	return newGo(nil, pkgPath, pkg.Name, docPkg)
+}
+
+// NewGoFromFiles creates a new [PkgDoc] from the given Go source files of a
+// package. Unlike [NewGo], the files may include test files, whose Example
+// functions make up [PkgDoc.Examples].
+func NewGoFromFiles(fset *token.FileSet, pkgPath string, files []*ast.File) (*PkgDoc, error) {
+	docPkg, err := doc.NewFromFiles(fset, files, pkgPath, doc.AllDecls|doc.AllMethods|doc.PreserveAST)
+	if err != nil {
+		return nil, err
+	}
+	return newGo(fset, pkgPath, docPkg.Name, docPkg), nil
+}
+
+// newGo creates a new [PkgDoc] from the given [doc.Package].
+func newGo(fset *token.FileSet, pkgPath, pkgName string, docPkg *doc.Package) *PkgDoc {
 	pkgDoc := &PkgDoc{
 		Doc:    docPkg.Doc,
 		Path:   pkgPath,
-		Name:   pkg.Name,
+		Name:   pkgName,
 		Vars:   make(map[string]string),
 		Consts: make(map[string]string),
 		Types:  make(map[string]*TypeDoc),
@@ -86,6 +177,13 @@ func NewGo(pkgPath string, pkg *ast.Package) *PkgDoc {
 		}
 	}
 
+	// Members of overload groups are documented even if they are not
+	// exported, since they are called through the groups.
+	var overloadMembers map[string]bool
+	if isXGoPackage {
+		overloadMembers = addOverloadDocs(pkgDoc, docPkg)
+	}
+
 	for _, t := range docPkg.Types {
 		if !token.IsExported(t.Name) {
 			continue
@@ -118,31 +216,30 @@ func NewGo(pkgPath string, pkg *ast.Package) *PkgDoc {
 				continue
 			}
 			for _, field := range structType.Fields.List {
-				if len(field.Names) == 0 {
-					if ident, ok := field.Type.(*ast.Ident); ok && token.IsExported(ident.Name) {
-						typeDoc.Fields[ident.Name] = field.Doc.Text()
-					}
-				} else {
-					for _, name := range field.Names {
-						if token.IsExported(name.Name) {
-							typeDoc.Fields[name.Name] = field.Doc.Text()
-						}
+				for _, name := range fieldNames(field) {
+					if token.IsExported(name) {
+						typeDoc.Fields[name] = fieldDoc(field)
 					}
 				}
 			}
 		}
 		for _, m := range t.Methods {
-			if token.IsExported(m.Name) {
+			if token.IsExported(m.Name) || overloadMembers[t.Name+"."+m.Name] {
 				typeDoc.Methods[m.Name] = m.Doc
 			}
 		}
+		addExamples(pkgDoc, fset, t.Name, t.Examples)
+		for _, m := range t.Methods {
+			addExamples(pkgDoc, fset, t.Name+"."+m.Name, m.Examples)
+		}
 	}
 
 	for _, f := range docPkg.Funcs {
-		if !token.IsExported(f.Name) {
+		if !token.IsExported(f.Name) && !overloadMembers[f.Name] {
 			continue
 		}
 		pkgDoc.Funcs[f.Name] = f.Doc
+		addExamples(pkgDoc, fset, f.Name, f.Examples)
 		if !isXGoPackage {
 			continue
 		}
@@ -155,10 +252,203 @@ func NewGo(pkgPath string, pkg *ast.Package) *PkgDoc {
 			pkgDoc.typeDoc(recvTypeName).Methods[methodName] = f.Doc
 		}
 	}
+	addExamples(pkgDoc, fset, "", docPkg.Examples)
 
 	return pkgDoc
 }
 
+// overloadIndexTable is the table of the indexes that suffix the names of
+// overloads, which is the same as the one used by gogen.
+const overloadIndexTable = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// addOverloadDocs adds the overload groups declared by the Gopo_ constants of
+// docPkg to pkgDoc. It returns the names of the members of the groups, where
+// the name of a method is qualified by its receiver type name, such as
+// "Sprite.stepTo".
+func addOverloadDocs(pkgDoc *PkgDoc, docPkg *doc.Package) map[string]bool {
+	isType := func(name string) bool {
+		for _, t := range docPkg.Types {
+			if t.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	members := make(map[string]bool)
+	for _, c := range docPkg.Consts {
+		for _, spec := range c.Decl.Specs {
+			valueSpec, ok := spec.(*ast.ValueSpec)
+			if !ok {
+				continue
+			}
+			for i, name := range valueSpec.Names {
+				if !strings.HasPrefix(name.Name, XGooPrefix) || i >= len(valueSpec.Values) {
+					continue
+				}
+				lit, ok := valueSpec.Values[i].(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+				value, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					continue
+				}
+
+				recvTypeName, groupName := splitXGooName(name.Name, isType)
+				doc := valueSpec.Doc.Text()
+				if doc == "" {
+					doc = c.Doc
+				}
+				overloadDoc := &OverloadDoc{Doc: doc}
+				for j, member := range strings.Split(value, ",") {
+					if member == "" {
+						if j >= len(overloadIndexTable) {
+							break
+						}
+						if recvTypeName != "" {
+							member = "."
+						}
+						member += groupName + "__" + overloadIndexTable[j:j+1]
+					}
+					overloadDoc.Members = append(overloadDoc.Members, member)
+					if methodName, ok := strings.CutPrefix(member, "."); ok {
+						members[recvTypeName+"."+methodName] = true
+					} else {
+						members[member] = true
+					}
+				}
+
+				if recvTypeName == "" {
+					if pkgDoc.Overloads == nil {
+						pkgDoc.Overloads = make(map[string]*OverloadDoc)
+					}
+					pkgDoc.Overloads[groupName] = overloadDoc
+				} else {
+					typeDoc := pkgDoc.typeDoc(recvTypeName)
+					if typeDoc.Overloads == nil {
+						typeDoc.Overloads = make(map[string]*OverloadDoc)
+					}
+					typeDoc.Overloads[groupName] = overloadDoc
+				}
+			}
+		}
+	}
+	return members
+}
+
+// splitXGooName splits the name of a Gopo_ constant into the receiver type
+// name and the name of the overload group, where the receiver type name is
+// empty for a group of functions. Like gogen, it accepts both Gopo_Type_Method
+// and Gopo__Type__Method, and treats Gopo_A_B as a group of functions unless A
+// is a type.
+func splitXGooName(name string, isType func(string) bool) (recvTypeName, groupName string) {
+	name = strings.TrimPrefix(name, XGooPrefix)
+	if rest, ok := strings.CutPrefix(name, "_"); ok {
+		if recvTypeName, groupName, ok := strings.Cut(rest, "__"); ok && recvTypeName != "" {
+			return recvTypeName, groupName
+		}
+		return "", rest
+	}
+	if recvTypeName, groupName, ok := strings.Cut(name, "_"); ok && isType(recvTypeName) {
+		return recvTypeName, groupName
+	}
+	return "", name
+}
+
+// fieldNames returns the names of the given struct field, which is the name
+// of its type for an embedded field.
+func fieldNames(field *ast.Field) []string {
+	if len(field.Names) == 0 {
+		typ := field.Type
+		for {
+			switch t := typ.(type) {
+			case *ast.Ident:
+				return []string{t.Name}
+			case *ast.SelectorExpr:
+				return []string{t.Sel.Name}
+			case *ast.StarExpr:
+				typ = t.X
+			case *ast.IndexExpr:
+				typ = t.X
+			case *ast.IndexListExpr:
+				typ = t.X
+			default:
+				return nil
+			}
+		}
+	}
+	names := make([]string, 0, len(field.Names))
+	for _, name := range field.Names {
+		names = append(names, name.Name)
+	}
+	return names
+}
+
+// fieldDoc returns the documentation of the given struct field, which is its
+// doc comment, or its line comment if it has no doc comment.
+func fieldDoc(field *ast.Field) string {
+	if doc := field.Doc.Text(); doc != "" {
+		return doc
+	}
+	return field.Comment.Text()
+}
+
+// addExamples adds the given examples of the named member to pkgDoc.
+func addExamples(pkgDoc *PkgDoc, fset *token.FileSet, name string, examples []*doc.Example) {
+	for _, ex := range examples {
+		code, ok := exampleCode(fset, ex)
+		if !ok {
+			continue
+		}
+		if pkgDoc.Examples == nil {
+			pkgDoc.Examples = make(map[string][]Example)
+		}
+		pkgDoc.Examples[name] = append(pkgDoc.Examples[name], Example{
+			Suffix: ex.Suffix,
+			Code:   code,
+			Output: ex.Output,
+		})
+	}
+}
+
+// exampleCode formats the code of the given example. The braces around the
+// body of the example function are removed, and so is its output comment.
+func exampleCode(fset *token.FileSet, ex *doc.Example) (string, bool) {
+	body, ok := ex.Code.(*ast.BlockStmt)
+	if !ok || fset == nil {
+		return "", false
+	}
+
+	comments := make([]*ast.CommentGroup, 0, len(ex.Comments))
+	for _, c := range ex.Comments {
+		if c.Pos() < body.Lbrace || c.End() > body.Rbrace || isOutputComment(c) {
+			continue
+		}
+		comments = append(comments, c)
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, &printer.CommentedNode{Node: body, Comments: comments}); err != nil {
+		return "", false
+	}
+	code := strings.TrimSpace(buf.String())
+	code = strings.TrimPrefix(code, "{")
+	code = strings.TrimSuffix(code, "}")
+	code = strings.Trim(code, "\n")
+	if code == "" {
+		return "", false
+	}
+	return dedent(strings.Split(code, "\n")), true
+}
+
+// isOutputComment reports whether the given comment is the output comment of
+// an example, which starts with "Output:" or "Unordered output:".
+func isOutputComment(c *ast.CommentGroup) bool {
+	text := strings.ToLower(strings.TrimSpace(c.Text()))
+	return strings.HasPrefix(text, "output:") || strings.HasPrefix(text, "unordered output:")
+}
+
 const (
 	XGotPrefix = "Gopt_"      // XGo template method
 	XGooPrefix = "Gopo_"      // XGo overload function/method
@@ -0,0 +1,208 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkgdoc
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseGoFiles(t *testing.T, fset *token.FileSet, srcs map[string]string) []*ast.File {
+	files := make([]*ast.File, 0, len(srcs))
+	for name, src := range srcs {
+		f, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+		require.NoError(t, err)
+		files = append(files, f)
+	}
+	return files
+}
+
+func TestNewGo(t *testing.T) {
+	fset := token.NewFileSet()
+	files := parseGoFiles(t, fset, map[string]string{
+		"sprite.go": `// Package sprite moves sprites.
+package sprite
+
+// Sprite is a sprite.
+type Sprite struct {
+	// Name is the name of the sprite.
+	Name string
+	Heading float64 // Heading is the direction of the sprite.
+	*Costume
+	hidden bool
+}
+
+// Costume is a costume of a sprite.
+type Costume struct{}
+
+// Step moves the sprite.
+func (p *Sprite) Step(n int) {}
+`,
+	})
+	pkg := &ast.Package{Name: "sprite", Files: map[string]*ast.File{"sprite.go": files[0]}}
+
+	pkgDoc := NewGo("example.com/sprite", pkg)
+	assert.Equal(t, "Package sprite moves sprites.\n", pkgDoc.Doc)
+	require.Contains(t, pkgDoc.Types, "Sprite")
+	assert.Equal(t, map[string]string{
+		"Name":    "Name is the name of the sprite.\n",
+		"Heading": "Heading is the direction of the sprite.\n",
+		"Costume": "",
+	}, pkgDoc.Types["Sprite"].Fields)
+	assert.Equal(t, "Step moves the sprite.\n", pkgDoc.Types["Sprite"].Methods["Step"])
+	assert.Nil(t, pkgDoc.Overloads)
+	assert.Nil(t, pkgDoc.Examples)
+}
+
+func TestNewGoFromFiles(t *testing.T) {
+	t.Run("Overloads", func(t *testing.T) {
+		fset := token.NewFileSet()
+		files := parseGoFiles(t, fset, map[string]string{
+			"sprite.go": `package sprite
+
+const GopPackage = true
+
+// Sprite is a sprite.
+type Sprite struct{}
+
+// Step moves the sprite by n steps or to a target.
+const Gopo_Sprite_Step = ".Step__0,.stepTo,"
+
+// Step__0 moves the sprite by n steps.
+func (p *Sprite) Step__0(n int) {}
+
+// stepTo moves the sprite to target.
+func (p *Sprite) stepTo(target string) {}
+
+func (p *Sprite) Step__2(x, y float64) {}
+
+// Play plays a sound.
+const Gopo_Play = "playSound,Play__1"
+
+// playSound plays the named sound.
+func playSound(name string) {}
+
+func Play__1(name string, wait bool) {}
+
+func unrelated() {}
+`,
+		})
+
+		pkgDoc, err := NewGoFromFiles(fset, "example.com/sprite", files)
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]*OverloadDoc{
+			"Play": {
+				Doc:     "Play plays a sound.\n",
+				Members: []string{"playSound", "Play__1"},
+			},
+		}, pkgDoc.Overloads)
+		assert.Equal(t, "playSound plays the named sound.\n", pkgDoc.Funcs["playSound"])
+		assert.NotContains(t, pkgDoc.Funcs, "unrelated")
+
+		typeDoc := pkgDoc.Types["Sprite"]
+		require.NotNil(t, typeDoc)
+		assert.Equal(t, map[string]*OverloadDoc{
+			"Step": {
+				Doc:     "Step moves the sprite by n steps or to a target.\n",
+				Members: []string{".Step__0", ".stepTo", ".Step__2"},
+			},
+		}, typeDoc.Overloads)
+		assert.Equal(t, "stepTo moves the sprite to target.\n", typeDoc.Methods["stepTo"])
+
+		overloadDoc, ok := pkgDoc.OverloadDocOf("Sprite", "Step__2")
+		require.True(t, ok)
+		assert.Equal(t, "Step moves the sprite by n steps or to a target.\n", overloadDoc.Doc)
+		overloadDoc, ok = pkgDoc.OverloadDocOf("", "playSound")
+		require.True(t, ok)
+		assert.Equal(t, "Play plays a sound.\n", overloadDoc.Doc)
+		_, ok = pkgDoc.OverloadDocOf("", "Step__0")
+		assert.False(t, ok)
+		_, ok = pkgDoc.OverloadDocOf("Costume", "Step__0")
+		assert.False(t, ok)
+	})
+
+	t.Run("Examples", func(t *testing.T) {
+		fset := token.NewFileSet()
+		files := parseGoFiles(t, fset, map[string]string{
+			"greet.go": `package greet
+
+// Greeter greets people.
+type Greeter struct{}
+
+// Greet greets name.
+func (g Greeter) Greet(name string) string { return "Hello, " + name }
+
+// Hello returns a greeting for name.
+func Hello(name string) string { return "Hello, " + name }
+`,
+			"greet_test.go": `package greet_test
+
+import (
+	"fmt"
+
+	"example.com/greet"
+)
+
+func Example() {
+	fmt.Println(greet.Hello("world"))
+}
+
+func ExampleHello() {
+	// Greet a friend.
+	fmt.Println(greet.Hello("Nick"))
+	// Output: Hello, Nick
+}
+
+func ExampleHello_twice() {
+	for range 2 {
+		fmt.Println(greet.Hello("Nick"))
+	}
+	// Output:
+	// Hello, Nick
+	// Hello, Nick
+}
+
+func ExampleGreeter_Greet() {
+	var g greet.Greeter
+	fmt.Println(g.Greet("Nick"))
+}
+`,
+		})
+
+		pkgDoc, err := NewGoFromFiles(fset, "example.com/greet", files)
+		require.NoError(t, err)
+		assert.NotContains(t, pkgDoc.Funcs, "ExampleHello")
+		assert.Equal(t, map[string][]Example{
+			"": {
+				{Code: `fmt.Println(greet.Hello("world"))`},
+			},
+			"Hello": {
+				{Code: "// Greet a friend.\nfmt.Println(greet.Hello(\"Nick\"))", Output: "Hello, Nick\n"},
+				{Suffix: "twice", Code: "for range 2 {\n\tfmt.Println(greet.Hello(\"Nick\"))\n}", Output: "Hello, Nick\nHello, Nick\n"},
+			},
+			"Greeter.Greet": {
+				{Code: "var g greet.Greeter\nfmt.Println(g.Greet(\"Nick\"))"},
+			},
+		}, pkgDoc.Examples)
+	})
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkgdoc
+
+import (
+	"iter"
+	"strings"
+)
+
+// deprecatedPrefix is the prefix of a paragraph holding a deprecation notice.
+const deprecatedPrefix = "Deprecated: "
+
+// Notes is the API guidance found in a documentation text, such as the ones
+// held by [PkgDoc].
+type Notes struct {
+	// Text is the documentation text without the deprecation notice and the
+	// examples.
+	Text string
+
+	// Deprecated is the deprecation notice without the "Deprecated: "
+	// prefix. It is empty if the documented member is not deprecated.
+	Deprecated string
+
+	// Examples are the example code blocks, which are the indented blocks
+	// of the documentation text, with the common indentation removed.
+	Examples []string
+}
+
+// ParseNotes parses the notes from the given documentation text.
+func ParseNotes(doc string) Notes {
+	var (
+		notes      Notes
+		textParas  []string
+		codeLines  []string
+		blankLines int
+	)
+	flushCode := func() {
+		if len(codeLines) > 0 {
+			notes.Examples = append(notes.Examples, dedent(codeLines))
+			codeLines = nil
+		}
+	}
+	for para := range paragraphs(doc) {
+		if isCodeParagraph(para) {
+			// Blank lines within a code block are kept.
+			if len(codeLines) > 0 {
+				for range blankLines {
+					codeLines = append(codeLines, "")
+				}
+			}
+			codeLines = append(codeLines, para.lines...)
+			blankLines = para.blankLinesAfter
+			continue
+		}
+		flushCode()
+
+		text := strings.Join(para.lines, "\n")
+		if notes.Deprecated == "" && strings.HasPrefix(text, deprecatedPrefix) {
+			notes.Deprecated = strings.Join(strings.Fields(text[len(deprecatedPrefix):]), " ")
+			continue
+		}
+		textParas = append(textParas, text)
+	}
+	flushCode()
+
+	if len(textParas) > 0 {
+		notes.Text = strings.Join(textParas, "\n\n") + "\n"
+	}
+	return notes
+}
+
+// paragraph is a paragraph of a documentation text.
+type paragraph struct {
+	lines           []string
+	blankLinesAfter int
+}
+
+// paragraphs returns the paragraphs of the given documentation text, which are
+// separated by blank lines.
+func paragraphs(doc string) iter.Seq[paragraph] {
+	return func(yield func(paragraph) bool) {
+		var para paragraph
+		for line := range strings.SplitSeq(doc, "\n") {
+			if strings.TrimSpace(line) == "" {
+				if len(para.lines) > 0 {
+					para.blankLinesAfter++
+				}
+				continue
+			}
+			if para.blankLinesAfter > 0 {
+				if !yield(para) {
+					return
+				}
+				para = paragraph{}
+			}
+			para.lines = append(para.lines, line)
+		}
+		if len(para.lines) > 0 {
+			yield(para)
+		}
+	}
+}
+
+// isCodeParagraph reports whether all lines of the given paragraph are
+// indented, which makes it a code block.
+func isCodeParagraph(para paragraph) bool {
+	for _, line := range para.lines {
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			return false
+		}
+	}
+	return true
+}
+
+// dedent joins the given lines with their common indentation removed.
+func dedent(lines []string) string {
+	indent, found := "", false
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		lineIndent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			indent, found = lineIndent, true
+			continue
+		}
+		n := 0
+		for n < len(indent) && n < len(lineIndent) && indent[n] == lineIndent[n] {
+			n++
+		}
+		indent = indent[:n]
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, indent)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pkgdoc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNotes(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		doc  string
+		want Notes
+	}{
+		{
+			name: "Empty",
+			doc:  "",
+			want: Notes{},
+		},
+		{
+			name: "TextOnly",
+			doc:  "Turn turns the sprite.\n\nIt is relative to the current heading.\n",
+			want: Notes{Text: "Turn turns the sprite.\n\nIt is relative to the current heading.\n"},
+		},
+		{
+			name: "Deprecated",
+			doc:  "ReadFile reads the file.\n\nDeprecated: As of Go 1.16, this function\nsimply calls [os.ReadFile].\n",
+			want: Notes{
+				Text:       "ReadFile reads the file.\n",
+				Deprecated: "As of Go 1.16, this function simply calls [os.ReadFile].",
+			},
+		},
+		{
+			name: "Examples",
+			doc:  "Say says something. For example:\n\n\tsay \"Hello\"\n\n\tsay \"Hi\", 2\n\nOr with a number:\n\n\t  say 1\n\t\tsay 2\n",
+			want: Notes{
+				Text:     "Say says something. For example:\n\nOr with a number:\n",
+				Examples: []string{"say \"Hello\"\n\nsay \"Hi\", 2", "  say 1\n\tsay 2"},
+			},
+		},
+		{
+			name: "DeprecatedNotAtParagraphStart",
+			doc:  "Wait waits.\nDeprecated: no.\n",
+			want: Notes{Text: "Wait waits.\nDeprecated: no.\n"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseNotes(tt.doc))
+		})
+	}
+}
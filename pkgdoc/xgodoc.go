@@ -24,7 +24,10 @@ import (
 	xgotoken "github.com/goplus/xgo/token"
 )
 
-// NewXGo creates a new [PkgDoc] for an XGo package.
+// NewXGo creates a new [PkgDoc] for an XGo package. Functions and the first
+// var block of a class file, such as an spx file, document the methods and
+// fields of its class, while those of other source files document the package
+// members.
 func NewXGo(pkgPath string, pkg *xgoast.Package) *PkgDoc {
 	pkgDoc := &PkgDoc{
 		Path:   pkgPath,
@@ -43,13 +46,16 @@ func NewXGo(pkgPath string, pkg *xgoast.Package) *PkgDoc {
 	}
 
 	for spxFile, astFile := range pkg.Files {
-		var spxBaseSelectorTypeName string
-		if spxFileBaseName := path.Base(spxFile); spxFileBaseName == "main.spx" {
-			spxBaseSelectorTypeName = "Game"
-		} else {
-			spxBaseSelectorTypeName = strings.TrimSuffix(spxFileBaseName, ".spx")
+		var spxBaseSelectorTypeDoc *TypeDoc
+		if isClassFile(spxFile) {
+			var spxBaseSelectorTypeName string
+			if spxFileBaseName := path.Base(spxFile); spxFileBaseName == "main.spx" {
+				spxBaseSelectorTypeName = "Game"
+			} else {
+				spxBaseSelectorTypeName = strings.TrimSuffix(spxFileBaseName, path.Ext(spxFileBaseName))
+			}
+			spxBaseSelectorTypeDoc = pkgDoc.typeDoc(spxBaseSelectorTypeName)
 		}
-		spxBaseSelectorTypeDoc := pkgDoc.typeDoc(spxBaseSelectorTypeName)
 
 		var firstVarBlock *xgoast.GenDecl
 		for _, decl := range astFile.Decls {
@@ -84,7 +90,7 @@ func NewXGo(pkgPath string, pkg *xgoast.Package) *PkgDoc {
 						for _, name := range spec.Names {
 							switch decl.Tok {
 							case xgotoken.VAR:
-								if decl == firstVarBlock {
+								if decl == firstVarBlock && spxBaseSelectorTypeDoc != nil {
 									spxBaseSelectorTypeDoc.Fields[name.Name] = doc
 								} else {
 									pkgDoc.Vars[name.Name] = doc
@@ -98,17 +104,22 @@ func NewXGo(pkgPath string, pkg *xgoast.Package) *PkgDoc {
 							typeDoc := pkgDoc.typeDoc(spec.Name.Name)
 							typeDoc.Doc = doc
 							for _, field := range structType.Fields.List {
-								fieldDoc := ""
-								if field.Doc != nil {
-									fieldDoc = field.Doc.Text()
+								fieldDoc := field.Doc.Text()
+								if fieldDoc == "" {
+									fieldDoc = field.Comment.Text()
 								}
 
 								if len(field.Names) == 0 {
-									ident, ok := field.Type.(*xgoast.Ident)
-									if !ok {
-										continue
+									typ := field.Type
+									if star, ok := typ.(*xgoast.StarExpr); ok {
+										typ = star.X
+									}
+									switch typ := typ.(type) {
+									case *xgoast.Ident:
+										typeDoc.Fields[typ.Name] = fieldDoc
+									case *xgoast.SelectorExpr:
+										typeDoc.Fields[typ.Sel.Name] = fieldDoc
 									}
-									typeDoc.Fields[ident.Name] = fieldDoc
 								} else {
 									for _, name := range field.Names {
 										typeDoc.Fields[name.Name] = fieldDoc
@@ -130,6 +141,10 @@ func NewXGo(pkgPath string, pkg *xgoast.Package) *PkgDoc {
 
 				var recvTypeDoc *TypeDoc
 				if decl.Recv == nil {
+					if spxBaseSelectorTypeDoc == nil {
+						pkgDoc.Funcs[decl.Name.Name] = doc
+						continue
+					}
 					recvTypeDoc = spxBaseSelectorTypeDoc
 				} else if len(decl.Recv.List) == 1 {
 					recvType := decl.Recv.List[0].Type
@@ -147,3 +162,13 @@ func NewXGo(pkgPath string, pkg *xgoast.Package) *PkgDoc {
 
 	return pkgDoc
 }
+
+// isClassFile reports whether the named file is a class file, which is any
+// XGo source file other than a plain XGo or Go source file.
+func isClassFile(name string) bool {
+	switch path.Ext(name) {
+	case ".xgo", ".gop", ".go":
+		return false
+	}
+	return true
+}
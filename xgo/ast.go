@@ -45,10 +45,14 @@ func buildASTFileCache(proj *Project, path string, file *File) (cache any, err e
 		}
 	}()
 	mode := parser.ParseComments | parser.AllErrors
-	if !strings.HasSuffix(path, ".xgo") && !strings.HasSuffix(path, ".gop") { // TODO(xsw): use xgomod
+	if !strings.HasSuffix(path, ".xgo") && !strings.HasSuffix(path, ".gop") && !strings.HasSuffix(path, ".go") { // TODO(xsw): use xgomod
 		mode |= parser.ParseGoPlusClass
 	}
-	astFile, parserErr := parser.ParseEntry(proj.Fset, path, file.Content, parser.Config{
+	content, err := file.ReadContent()
+	if err != nil {
+		return nil, err
+	}
+	astFile, parserErr := parser.ParseEntry(proj.Fset, path, content, parser.Config{
 		Mode: mode,
 	})
 	cache = &astFileCache{astFile, parserErr}
@@ -86,27 +90,44 @@ func buildASTPackageCache(proj *Project) (any, error) {
 	}
 	var parserErrs scanner.ErrorList
 	for path := range proj.Files() {
-		switch filepath.Ext(path) { // TODO(xsw): use xgomod
-		case ".spx", ".xgo", ".gop", ".gox":
-			astFile, err := proj.ASTFile(path)
-			if err != nil {
-				if el, ok := err.(scanner.ErrorList); ok {
-					parserErrs = append(parserErrs, el...)
-				} else {
-					parserErrs.Add(token.Position{}, err.Error())
-				}
+		if !isSourceFile(path) || isLocalPackageFile(path) || proj.IsIgnored(path) {
+			continue
+		}
+		astFile, err := proj.ASTFile(path)
+		if err != nil {
+			if el, ok := err.(scanner.ErrorList); ok {
+				parserErrs = append(parserErrs, el...)
+			} else {
+				parserErrs.Add(token.Position{}, err.Error())
 			}
-			if astFile != nil {
-				if pkg.Name == "" {
-					pkg.Name = astFile.Name.Name
-				}
-				pkg.Files[path] = astFile
+		}
+		if astFile != nil {
+			if pkg.Name == "" {
+				pkg.Name = astFile.Name.Name
 			}
+			pkg.Files[path] = astFile
 		}
 	}
 	return &astPackageCache{pkg, parserErrs.Err()}, nil
 }
 
+// isSourceFile reports whether the file at path is an XGo source file of the
+// package.
+func isSourceFile(path string) bool {
+	switch filepath.Ext(path) { // TODO(xsw): use xgomod
+	case ".spx", ".xgo", ".gop", ".gox":
+		return true
+	}
+	return false
+}
+
+// isPackageFile reports whether the file at path may affect the package, which
+// is the case for source files, including those of local packages, ignore
+// files and module files.
+func isPackageFile(path string) bool {
+	return isSourceFile(path) || isLocalPackageFile(path) || IsIgnoreFile(path) || isModFile(path)
+}
+
 // ASTPackage retrieves the [ast.Package] from the project. The returned
 // [ast.Package] is nil only if building failed.
 //
@@ -50,9 +50,25 @@ type fileCacheKey struct {
 //
 //	proj.RegisterCacheBuilder(myCacheKind{}, myBuilder)
 func (p *Project) RegisterCacheBuilder(kind CacheKind, builder func(root *Project) (any, error)) {
+	p.RegisterCacheBuilderWithDeps(kind, builder, nil)
+}
+
+// RegisterCacheBuilderWithDeps is like [Project.RegisterCacheBuilder], but
+// the cache is only invalidated when a file that dependsOn reports true for is
+// added, changed, or deleted. If dependsOn is nil, the cache depends on all
+// files.
+//
+// For example, a cache built only from source files does not have to be
+// rebuilt when an asset file changes.
+func (p *Project) RegisterCacheBuilderWithDeps(kind CacheKind, builder func(root *Project) (any, error), dependsOn func(path string) bool) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.cacheBuilders[kind] = builder
+	if dependsOn != nil {
+		p.cacheDeps[kind] = dependsOn
+	} else {
+		delete(p.cacheDeps, kind)
+	}
 }
 
 // RegisterFileCacheBuilder registers a file level cache builder.
@@ -73,12 +89,11 @@ func (p *Project) RegisterFileCacheBuilder(kind CacheKind, builder func(proj *Pr
 //
 // The kind must be the same comparable value that was used with [Project.RegisterCacheBuilder].
 func (p *Project) Cache(kind CacheKind) (any, error) {
-	p.mu.RLock()
-	v, ok := p.caches[kind]
-	p.mu.RUnlock()
-	if ok {
+	if v, ok := loadCache(p, projectCaches, kind); ok {
+		p.cacheHits.Add(1)
 		return decodeDataOrErr(v)
 	}
+	p.cacheMisses.Add(1)
 
 	data, err, _ := p.cacheSFG.Do(fmt.Sprintf("%T-%v", kind, kind), func() (any, error) {
 		p.mu.RLock()
@@ -88,12 +103,11 @@ func (p *Project) Cache(kind CacheKind) (any, error) {
 			return nil, ErrUnknownCacheKind
 		}
 
+		generation := p.generation.Load()
 		data, err := builder(p)
-
-		p.mu.Lock()
-		p.caches[kind] = encodeDataOrErr(data, err)
-		p.mu.Unlock()
-
+		if existing, ok := storeCache(p, projectCaches, kind, encodeDataOrErr(data, err), generation); ok {
+			return decodeDataOrErr(existing)
+		}
 		return data, err
 	})
 	return data, err
@@ -104,13 +118,11 @@ func (p *Project) Cache(kind CacheKind) (any, error) {
 // The kind must be the same comparable value that was used with [Project.RegisterFileCacheBuilder].
 func (p *Project) FileCache(kind CacheKind, path string) (any, error) {
 	key := fileCacheKey{kind, path}
-
-	p.mu.RLock()
-	v, ok := p.fileCaches[key]
-	p.mu.RUnlock()
-	if ok {
+	if v, ok := loadCache(p, fileCaches, key); ok {
+		p.cacheHits.Add(1)
 		return decodeDataOrErr(v)
 	}
+	p.cacheMisses.Add(1)
 
 	data, err, _ := p.fileCacheSFG.Do(fmt.Sprintf("%T-%v-%s", kind, kind, path), func() (any, error) {
 		p.mu.RLock()
@@ -124,26 +136,125 @@ func (p *Project) FileCache(kind CacheKind, path string) (any, error) {
 			return nil, fs.ErrNotExist
 		}
 
+		generation := p.generation.Load()
 		data, err := builder(p, path, file)
-
-		p.mu.Lock()
-		p.fileCaches[key] = encodeDataOrErr(data, err)
-		p.mu.Unlock()
-
+		if existing, ok := storeCache(p, fileCaches, key, encodeDataOrErr(data, err), generation); ok {
+			return decodeDataOrErr(existing)
+		}
 		return data, err
 	})
 	return data, err
 }
 
+// ClearCaches drops all project level and file level caches, so they are
+// rebuilt the next time they are requested. It does not change the generation
+// of the project, as its content stays the same.
+func (p *Project) ClearCaches() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	clear(p.caches)
+	clear(p.fileCaches)
+}
+
+// CacheStats represents the statistics of cache lookups in a project.
+type CacheStats struct {
+	Hits   uint64 // Number of lookups served from the cache.
+	Misses uint64 // Number of lookups that required building the cache.
+}
+
+// HitRate returns the ratio of hits to all lookups, or 0 if there are none.
+func (s CacheStats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// CacheStats returns the statistics of both project level and file level
+// cache lookups in the project. A snapshot starts with its own statistics.
+func (p *Project) CacheStats() CacheStats {
+	return CacheStats{
+		Hits:   p.cacheHits.Load(),
+		Misses: p.cacheMisses.Load(),
+	}
+}
+
 // deleteFileCache deletes file-specific caches for the given path. It also
-// clears project-level caches implicitly if necessary.
+// deletes project-level caches that depend on the file, and advances the
+// generation of the project and of the file.
 func (p *Project) deleteFileCache(path string) {
-	clear(p.caches)
+	generation := nextGeneration()
+	p.generation.Store(generation)
+	p.writableFileGenerations()[path] = generation
+	for kind := range p.caches {
+		if dependsOn, ok := p.cacheDeps[kind]; !ok || dependsOn(path) {
+			delete(p.caches, kind)
+		}
+	}
 	for kind := range p.fileCacheBuilders {
 		delete(p.fileCaches, fileCacheKey{kind, path})
 	}
 }
 
+// projectCaches returns the project level caches of p.
+func projectCaches(p *Project) map[CacheKind]dataOrErr { return p.caches }
+
+// fileCaches returns the file level caches of p.
+func fileCaches(p *Project) map[fileCacheKey]dataOrErr { return p.fileCaches }
+
+// loadCache looks up the cache for key in p. If p does not have it, it is
+// taken from the project p is a snapshot of, provided that both still have
+// the same generation, and is then kept in p as well.
+func loadCache[K comparable](p *Project, caches func(*Project) map[K]dataOrErr, key K) (dataOrErr, bool) {
+	generation := p.generation.Load()
+	for proj := p; proj != nil; proj = proj.origin {
+		proj.mu.RLock()
+		v, ok := caches(proj)[key]
+		sameGeneration := proj.generation.Load() == generation
+		proj.mu.RUnlock()
+		if !sameGeneration {
+			break
+		}
+		if ok {
+			if proj != p {
+				if existing, ok := storeCache(p, caches, key, v, generation); ok {
+					v = existing
+				}
+			}
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// storeCache stores v as the cache for key, which was built at generation,
+// in p and in the projects p is a snapshot of. It stops at the first project
+// that no longer has that generation, so a cache built from outdated files is
+// never stored, and it never replaces an existing cache, so all projects that
+// share a generation also share the same cache data.
+//
+// If p already had a cache for key, it is returned with ok set to true.
+func storeCache[K comparable](p *Project, caches func(*Project) map[K]dataOrErr, key K, v dataOrErr, generation uint64) (existing dataOrErr, ok bool) {
+	for proj := p; proj != nil; proj = proj.origin {
+		proj.mu.Lock()
+		sameGeneration := proj.generation.Load() == generation
+		if sameGeneration {
+			m := caches(proj)
+			if cached, found := m[key]; !found {
+				m[key] = v
+			} else if proj == p {
+				existing, ok = cached, true
+			}
+		}
+		proj.mu.Unlock()
+		if !sameGeneration {
+			break
+		}
+	}
+	return
+}
+
 // dataOrErr represents a data or an error.
 type dataOrErr = any
 
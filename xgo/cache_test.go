@@ -150,6 +150,103 @@ func TestProjectRegisterCacheBuilder(t *testing.T) {
 	})
 }
 
+func TestProjectRegisterCacheBuilderWithDeps(t *testing.T) {
+	t.Run("InvalidatedOnlyByDependencies", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo":    file("package main"),
+			"assets.json": file("{}"),
+		}, 0)
+
+		type testCacheKind struct{}
+
+		var buildCount int
+		proj.RegisterCacheBuilderWithDeps(testCacheKind{}, func(p *Project) (any, error) {
+			buildCount++
+			return buildCount, nil
+		}, isSourceFile)
+
+		data, err := proj.Cache(testCacheKind{})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, data)
+
+		// Changing a file that is not a dependency keeps the cache.
+		proj.PutFile("assets.json", file(`{"x":1}`))
+		proj.PutFile("new.json", file("{}"))
+		assert.NoError(t, proj.DeleteFile("new.json"))
+		data, err = proj.Cache(testCacheKind{})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, data)
+
+		// Changing a dependency invalidates the cache.
+		proj.PutFile("main.xgo", file("package main\n\nvar x int"))
+		data, err = proj.Cache(testCacheKind{})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, data)
+
+		// Adding a dependency invalidates the cache.
+		proj.PutFile("other.xgo", file("package main"))
+		data, err = proj.Cache(testCacheKind{})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, data)
+	})
+
+	t.Run("NilDependsOn", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"assets.json": file("{}"),
+		}, 0)
+
+		type testCacheKind struct{}
+
+		var buildCount int
+		builder := func(p *Project) (any, error) {
+			buildCount++
+			return buildCount, nil
+		}
+		proj.RegisterCacheBuilderWithDeps(testCacheKind{}, builder, isSourceFile)
+		proj.RegisterCacheBuilderWithDeps(testCacheKind{}, builder, nil)
+		assert.NotContains(t, proj.cacheDeps, testCacheKind{})
+
+		data, err := proj.Cache(testCacheKind{})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, data)
+
+		// Without dependsOn, any file change invalidates the cache.
+		proj.PutFile("assets.json", file(`{"x":1}`))
+		data, err = proj.Cache(testCacheKind{})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, data)
+	})
+
+	t.Run("BuiltinCaches", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo":    file("package main\n\nvar x int"),
+			"other.xgo":   file("package main\n\nvar z int"),
+			"assets.json": file("{}"),
+		}, FeatAll)
+
+		typeInfo1, err := proj.TypeInfo()
+		assert.NoError(t, err)
+		otherASTFile1, err := proj.ASTFile("other.xgo")
+		assert.NoError(t, err)
+
+		// Asset changes do not trigger re-typechecking.
+		proj.PutFile("assets.json", file(`{"x":1}`))
+		typeInfo2, err := proj.TypeInfo()
+		assert.NoError(t, err)
+		assert.Same(t, typeInfo1, typeInfo2)
+
+		// Source changes re-typecheck the whole package, but keep the ASTs of
+		// unchanged files.
+		proj.PutFile("main.xgo", file("package main\n\nvar y int"))
+		typeInfo3, err := proj.TypeInfo()
+		assert.NoError(t, err)
+		assert.NotSame(t, typeInfo1, typeInfo3)
+		otherASTFile2, err := proj.ASTFile("other.xgo")
+		assert.NoError(t, err)
+		assert.Same(t, otherASTFile1, otherASTFile2)
+	})
+}
+
 func TestProjectRegisterFileCacheBuilder(t *testing.T) {
 	t.Run("RegisterNewFileCacheBuilder", func(t *testing.T) {
 		proj := NewProject(nil, nil, 0)
@@ -538,6 +635,70 @@ func TestProjectCache(t *testing.T) {
 		assert.NoError(t, err2)
 		assert.Equal(t, "types-data", data2)
 	})
+
+	t.Run("CacheBuiltDuringChangeIsNotReused", func(t *testing.T) {
+		proj := NewProject(nil, nil, 0)
+
+		type testCacheKind struct{}
+
+		var buildCount int
+		proj.RegisterCacheBuilder(testCacheKind{}, func(p *Project) (any, error) {
+			buildCount++
+			if buildCount == 1 {
+				// Change the project while the cache is being built.
+				p.PutFile("main.xgo", file("package main"))
+			}
+			return buildCount, nil
+		})
+
+		data1, err1 := proj.Cache(testCacheKind{})
+		assert.NoError(t, err1)
+		assert.Equal(t, 1, data1)
+
+		// The first result was built from outdated files, so it is rebuilt.
+		data2, err2 := proj.Cache(testCacheKind{})
+		assert.NoError(t, err2)
+		assert.Equal(t, 2, data2)
+
+		data3, err3 := proj.Cache(testCacheKind{})
+		assert.NoError(t, err3)
+		assert.Equal(t, 2, data3)
+	})
+
+	t.Run("CacheBuiltBySnapshot", func(t *testing.T) {
+		proj := NewProject(nil, nil, 0)
+
+		type testCacheKind struct{}
+
+		var buildCount int
+		proj.RegisterCacheBuilder(testCacheKind{}, func(p *Project) (any, error) {
+			buildCount++
+			return buildCount, nil
+		})
+
+		// A cache built by a snapshot is reused by the project and by
+		// later snapshots.
+		data1, err1 := proj.Snapshot().Cache(testCacheKind{})
+		assert.NoError(t, err1)
+		assert.Equal(t, 1, data1)
+		data2, err2 := proj.Cache(testCacheKind{})
+		assert.NoError(t, err2)
+		assert.Equal(t, 1, data2)
+		data3, err3 := proj.Snapshot().Cache(testCacheKind{})
+		assert.NoError(t, err3)
+		assert.Equal(t, 1, data3)
+
+		// A cache built by a changed snapshot is not.
+		snapshot := proj.Snapshot()
+		snapshot.PutFile("main.xgo", file("package main"))
+		data4, err4 := snapshot.Cache(testCacheKind{})
+		assert.NoError(t, err4)
+		assert.Equal(t, 2, data4)
+		data5, err5 := proj.Cache(testCacheKind{})
+		assert.NoError(t, err5)
+		assert.Equal(t, 1, data5)
+		assert.Equal(t, 2, buildCount)
+	})
 }
 
 func TestProjectFileCache(t *testing.T) {
@@ -837,6 +998,43 @@ func TestProjectFileCache(t *testing.T) {
 	})
 }
 
+func TestProjectClearCaches(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo": file("package main"),
+		}, 0)
+
+		type testCacheKind struct{}
+		var buildCount int
+		proj.RegisterCacheBuilder(testCacheKind{}, func(p *Project) (any, error) {
+			buildCount++
+			return buildCount, nil
+		})
+		type testFileCacheKind struct{}
+		var fileBuildCount int
+		proj.RegisterFileCacheBuilder(testFileCacheKind{}, func(p *Project, path string, file *File) (any, error) {
+			fileBuildCount++
+			return fileBuildCount, nil
+		})
+
+		_, err := proj.Cache(testCacheKind{})
+		assert.NoError(t, err)
+		_, err = proj.FileCache(testFileCacheKind{}, "main.xgo")
+		assert.NoError(t, err)
+		gen := proj.Generation()
+
+		proj.ClearCaches()
+		assert.Equal(t, gen, proj.Generation())
+
+		data, err := proj.Cache(testCacheKind{})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, data)
+		data, err = proj.FileCache(testFileCacheKind{}, "main.xgo")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, data)
+	})
+}
+
 func TestProjectDeleteFileCache(t *testing.T) {
 	t.Run("DeleteCacheForExistingFile", func(t *testing.T) {
 		proj := NewProject(nil, nil, 0)
@@ -1095,3 +1293,52 @@ func TestDataOrErr(t *testing.T) {
 		}
 	})
 }
+
+func TestProjectCacheStats(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo": {Content: []byte(`echo "Hello"`)},
+		}, 0)
+		assert.Equal(t, CacheStats{}, proj.CacheStats())
+		assert.Zero(t, proj.CacheStats().HitRate())
+
+		type testCacheKind struct{}
+		proj.RegisterCacheBuilder(testCacheKind{}, func(p *Project) (any, error) {
+			return "cached-data", nil
+		})
+		type testFileCacheKind struct{}
+		proj.RegisterFileCacheBuilder(testFileCacheKind{}, func(p *Project, path string, file *File) (any, error) {
+			return "cached-file-data", nil
+		})
+
+		_, err := proj.Cache(testCacheKind{})
+		assert.NoError(t, err)
+		_, err = proj.Cache(testCacheKind{})
+		assert.NoError(t, err)
+		_, err = proj.FileCache(testFileCacheKind{}, "main.xgo")
+		assert.NoError(t, err)
+		_, err = proj.FileCache(testFileCacheKind{}, "main.xgo")
+		assert.NoError(t, err)
+
+		stats := proj.CacheStats()
+		assert.Equal(t, CacheStats{Hits: 2, Misses: 2}, stats)
+		assert.Equal(t, 0.5, stats.HitRate())
+	})
+
+	t.Run("Snapshot", func(t *testing.T) {
+		proj := NewProject(nil, nil, 0)
+		type testCacheKind struct{}
+		proj.RegisterCacheBuilder(testCacheKind{}, func(p *Project) (any, error) {
+			return "cached-data", nil
+		})
+		_, err := proj.Cache(testCacheKind{})
+		assert.NoError(t, err)
+
+		snapshot := proj.Snapshot()
+		assert.Equal(t, CacheStats{}, snapshot.CacheStats())
+		_, err = snapshot.Cache(testCacheKind{})
+		assert.NoError(t, err)
+		assert.Equal(t, CacheStats{Hits: 1}, snapshot.CacheStats())
+		assert.Equal(t, CacheStats{Misses: 1}, proj.CacheStats())
+	})
+}
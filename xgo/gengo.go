@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xgo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/types"
+	"syscall"
+
+	"github.com/goplus/gogen"
+	"github.com/goplus/mod/xgomod"
+	"github.com/goplus/xgo/cl"
+)
+
+// Names of the Go source files generated for the main package, which are the
+// same as the ones written by the xgo command.
+const (
+	GenGoFile     = "xgo_autogen.go"
+	GenGoTestFile = "xgo_autogen_test.go"
+)
+
+// genGoFiles maps the names of the Go source files generated for the main
+// package to the gogen file names they are written from.
+var genGoFiles = map[string]string{
+	GenGoFile:     "",
+	GenGoTestFile: "_test",
+}
+
+// GenGo compiles the main package of the project to Go, and returns the
+// generated Go source files keyed by their names, such as [GenGoFile]. Unlike
+// [Project.TypeInfo], it does not tolerate errors, since the generated code of
+// an erroneous package is incomplete.
+//
+// The result is not cached, as it is expected to be requested only on demand.
+func (p *Project) GenGo() (map[string][]byte, error) {
+	astPkg, err := p.ASTPackage()
+	if err != nil {
+		return nil, err
+	}
+	if astPkg == nil || len(astPkg.Files) == 0 {
+		return nil, errors.New("no source files in main package")
+	}
+
+	mod := p.Mod
+	if mod == nil {
+		mod = xgomod.Default
+	}
+	pkg, err := cl.NewPackage(p.PkgPath, astPkg, &cl.Config{
+		Types:          types.NewPackage(p.PkgPath, astPkg.Name),
+		Fset:           p.Fset,
+		LookupClass:    mod.LookupClass,
+		Importer:       p.localImporter(),
+		NoFileLine:     true,
+		NoSkipConstant: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte, len(genGoFiles))
+	for name, fname := range genGoFiles {
+		var buf bytes.Buffer
+		buf.WriteString(gogen.GeneratedHeader)
+		if err := pkg.WriteTo(&buf, fname); err != nil {
+			if errors.Is(err, syscall.ENOENT) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		files[name] = buf.Bytes()
+	}
+	return files, nil
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xgo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectGenGo(t *testing.T) {
+	t.Run("MainPackage", func(t *testing.T) {
+		proj := newLocalPackagesProject(map[string]*File{
+			"main.xgo": file(`
+import "example.com/game/utils"
+
+func double(x int) int {
+	return x * 2
+}
+
+println double(utils.Answer)
+`),
+			"go.mod":         file("module example.com/game\n"),
+			"utils/utils.go": file("package utils\n\nconst Answer = 42\n"),
+		})
+
+		files, err := proj.GenGo()
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		content := string(files[GenGoFile])
+		assert.Contains(t, content, "// Code generated by gogen; DO NOT EDIT.")
+		assert.Contains(t, content, "package main")
+		assert.Contains(t, content, `"example.com/game/utils"`)
+		assert.Contains(t, content, "func double(x int) int {")
+		assert.Contains(t, content, "func main() {")
+	})
+
+	t.Run("CompileError", func(t *testing.T) {
+		proj := newLocalPackagesProject(map[string]*File{
+			"main.xgo": file("println undefinedVar\n"),
+		})
+
+		files, err := proj.GenGo()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "undefined: undefinedVar")
+		assert.Nil(t, files)
+	})
+
+	t.Run("ParseError", func(t *testing.T) {
+		proj := newLocalPackagesProject(map[string]*File{
+			"main.xgo": file("func {\n"),
+		})
+
+		files, err := proj.GenGo()
+		require.Error(t, err)
+		assert.Nil(t, files)
+	})
+
+	t.Run("NoSourceFiles", func(t *testing.T) {
+		proj := newLocalPackagesProject(map[string]*File{
+			"assets/index.json": file("{}"),
+		})
+
+		files, err := proj.GenGo()
+		assert.EqualError(t, err, "no source files in main package")
+		assert.Nil(t, files)
+	})
+}
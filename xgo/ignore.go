@@ -0,0 +1,181 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xgo
+
+import (
+	"path"
+	"strings"
+)
+
+// ignoreFiles are the names of the ignore files at the root of a project, in
+// the order their patterns are applied. Patterns of later files take
+// precedence, so a .spxignore file can re-include files ignored by a
+// .gitignore file.
+var ignoreFiles = []string{".gitignore", ".spxignore"}
+
+// IsIgnoreFile reports whether the file at path is an ignore file, such as
+// .gitignore or .spxignore at the root of a project.
+func IsIgnoreFile(path string) bool {
+	for _, name := range ignoreFiles {
+		if path == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreCacheKind is a cache kind type for [ignoreCache].
+type ignoreCacheKind struct{}
+
+// ignoreCache is a cache for the patterns of the ignore files of a project.
+type ignoreCache struct {
+	patterns []ignorePattern
+}
+
+// buildIgnoreCache implements [CacheBuilder] to build an [ignoreCache] for the
+// provided XGo project.
+func buildIgnoreCache(proj *Project) (any, error) {
+	var patterns []ignorePattern
+	for _, name := range ignoreFiles {
+		file, ok := proj.File(name)
+		if !ok {
+			continue
+		}
+		content, err := file.ReadContent()
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, parseIgnorePatterns(string(content))...)
+	}
+	return &ignoreCache{patterns}, nil
+}
+
+// IsIgnored reports whether the file at path is ignored by the .gitignore or
+// .spxignore file at the root of the project, which use the gitignore pattern
+// format. A path ending with "/" is a directory. Ignored files are left out of
+// the AST package, so they are neither type checked nor reported in
+// diagnostics.
+//
+// Nothing is ignored if the project was created without [FeatASTCache].
+func (p *Project) IsIgnored(path string) bool {
+	cacheIface, err := p.Cache(ignoreCacheKind{})
+	if err != nil {
+		return false
+	}
+	cache := cacheIface.(*ignoreCache)
+	if len(cache.patterns) == 0 {
+		return false
+	}
+
+	// A file is ignored if any of its parent directories is, and it cannot
+	// be re-included then.
+	dir, isDir := strings.CutSuffix(path, "/")
+	elems := strings.Split(dir, "/")
+	for i := range elems {
+		if matchIgnorePatterns(cache.patterns, elems[:i+1], isDir || i < len(elems)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignorePattern is a pattern of an ignore file.
+type ignorePattern struct {
+	elems    []string // Slash-separated elements of the pattern.
+	negate   bool     // Whether the pattern starts with "!".
+	dirOnly  bool     // Whether the pattern ends with "/".
+	anchored bool     // Whether the pattern contains a "/" before its end.
+}
+
+// parseIgnorePatterns parses the patterns in the content of an ignore file.
+// Blank lines and lines starting with "#" are skipped.
+func parseIgnorePatterns(content string) []ignorePattern {
+	var patterns []ignorePattern
+	for line := range strings.Lines(content) {
+		line = strings.TrimRight(line, "\r\n")
+		line = strings.TrimRight(line, " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var p ignorePattern
+		if rest, ok := strings.CutPrefix(line, "!"); ok {
+			p.negate = true
+			line = rest
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+		if rest, ok := strings.CutSuffix(line, "/"); ok {
+			p.dirOnly = true
+			line = rest
+		}
+		if strings.Contains(line, "/") {
+			p.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		p.elems = strings.Split(line, "/")
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
+
+// matchIgnorePatterns reports whether the path with the given elements is
+// ignored by patterns, where the last matching pattern wins.
+func matchIgnorePatterns(patterns []ignorePattern, elems []string, isDir bool) bool {
+	ignored := false
+	for _, p := range patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		var matched bool
+		if p.anchored {
+			matched = matchIgnoreElems(p.elems, elems)
+		} else {
+			matched, _ = path.Match(p.elems[0], elems[len(elems)-1])
+		}
+		if matched {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// matchIgnoreElems reports whether the pattern elements match the path
+// elements. A "**" element matches zero or more path elements.
+func matchIgnoreElems(pattern, elems []string) bool {
+	if len(pattern) == 0 {
+		return len(elems) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(elems); i++ {
+			if matchIgnoreElems(pattern[1:], elems[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(elems) == 0 {
+		return false
+	}
+	if matched, _ := path.Match(pattern[0], elems[0]); !matched {
+		return false
+	}
+	return matchIgnoreElems(pattern[1:], elems[1:])
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xgo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsIgnoreFile(t *testing.T) {
+	assert.True(t, IsIgnoreFile(".gitignore"))
+	assert.True(t, IsIgnoreFile(".spxignore"))
+	assert.False(t, IsIgnoreFile("assets/.gitignore"))
+	assert.False(t, IsIgnoreFile("main.spx"))
+}
+
+func TestProjectIsIgnored(t *testing.T) {
+	t.Run("Patterns", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			".gitignore": file(`# Generated files.
+node_modules/
+*.bak
+/dist
+!keep.bak
+`),
+			".spxignore": file(`assets/**/*.tmp
+\#notes
+build/
+`),
+		}, FeatAll)
+
+		for _, tt := range []struct {
+			path string
+			want bool
+		}{
+			{"main.spx", false},
+			{"node_modules/a/b.spx", true},
+			{"assets/node_modules/a.json", true},
+			{"node_modules", false},
+			{"node_modules/", true},
+			{"Sprite.spx.bak", true},
+			{"backup/Sprite.spx.bak", true},
+			{"keep.bak", false},
+			{"dist/main.spx", true},
+			{"assets/dist/a.png", false},
+			{"assets/a.tmp", true},
+			{"assets/sounds/a/b.tmp", true},
+			{"other/a.tmp", false},
+			{"#notes", true},
+			{"build", false},
+			{"build/main.spx", true},
+		} {
+			assert.Equal(t, tt.want, proj.IsIgnored(tt.path), tt.path)
+		}
+	})
+
+	t.Run("ParentDirectoryIgnored", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			".gitignore": file("tmp/\n!tmp/keep.spx\n"),
+		}, FeatAll)
+		assert.True(t, proj.IsIgnored("tmp/keep.spx"))
+	})
+
+	t.Run("SpxignoreTakesPrecedence", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			".gitignore": file("*.spx\n"),
+			".spxignore": file("!main.spx\n"),
+		}, FeatAll)
+		assert.False(t, proj.IsIgnored("main.spx"))
+		assert.True(t, proj.IsIgnored("Sprite.spx"))
+	})
+
+	t.Run("NoIgnoreFiles", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.spx": file("echo 1"),
+		}, FeatAll)
+		assert.False(t, proj.IsIgnored("main.spx"))
+	})
+
+	t.Run("WithoutASTCache", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			".gitignore": file("*.spx\n"),
+		}, 0)
+		assert.False(t, proj.IsIgnored("main.spx"))
+	})
+
+	t.Run("ASTPackage", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.spx":        file("echo 1"),
+			"backup/main.spx": file("echo 2"),
+		}, FeatAll)
+		astPkg, err := proj.ASTPackage()
+		require.NoError(t, err)
+		assert.Len(t, astPkg.Files, 2)
+
+		proj.PutFile(".spxignore", file("backup/\n"))
+		astPkg, err = proj.ASTPackage()
+		require.NoError(t, err)
+		assert.Len(t, astPkg.Files, 1)
+		assert.Contains(t, astPkg.Files, "main.spx")
+	})
+}
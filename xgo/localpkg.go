@@ -0,0 +1,278 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xgo
+
+import (
+	"fmt"
+	"go/types"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/goplus/xgo/ast"
+	"github.com/goplus/xgolsw/pkgdoc"
+	"golang.org/x/mod/modfile"
+)
+
+// modFiles are the names of the module files at the root of a project, in
+// the order they are looked up. Only the first one that exists is used.
+var modFiles = []string{"go.mod", "xgo.mod", "gox.mod"}
+
+// isModFile reports whether the file at path is a module file at the root of
+// a project.
+func isModFile(path string) bool {
+	return slices.Contains(modFiles, path)
+}
+
+// isLocalPackageFile reports whether the file at path is a source file of a
+// local package, which is an XGo or Go source file in a subdirectory of the
+// project. Class files, such as spx files, belong to the main package
+// wherever they are.
+func isLocalPackageFile(p string) bool {
+	if !strings.Contains(strings.TrimPrefix(p, "/"), "/") {
+		return false
+	}
+	switch path.Ext(p) {
+	case ".xgo", ".gop":
+		return true
+	case ".go":
+		return !strings.HasSuffix(p, "_test.go")
+	}
+	return false
+}
+
+// ModulePath returns the module path of the project, which is declared by its
+// module file, such as go.mod at the root of the project. It returns an empty
+// string if there is no module file.
+func (p *Project) ModulePath() string {
+	_, content, ok := p.modFile()
+	if !ok {
+		return ""
+	}
+	return modfile.ModulePath(content)
+}
+
+// LocalPackage is a package in a subdirectory of a project, which the main
+// package can import. All the XGo and Go source files directly in the
+// directory make up the package.
+type LocalPackage struct {
+	// Dir is the directory of the package relative to the project root.
+	Dir string
+
+	// Path is the import path of the package, which is Dir joined to the
+	// module path of the project, or Dir itself if the project has no
+	// module path.
+	Path string
+
+	// Files are the AST files of the package, keyed by their paths.
+	Files map[string]*ast.File
+
+	// TypeInfo is the type information of the package, which is partial if
+	// Err is not nil.
+	TypeInfo *TypeInfo
+
+	// Err is the error from type checking the package, if any. Errors from
+	// parsing are reported by [Project.ASTFile].
+	Err error
+
+	pkgDocOnce sync.Once
+	pkgDoc     *pkgdoc.PkgDoc
+}
+
+// PkgDoc returns the documentation of the package, which is extracted from
+// its source files when it is first requested.
+func (pkg *LocalPackage) PkgDoc() *pkgdoc.PkgDoc {
+	pkg.pkgDocOnce.Do(func() {
+		name := ""
+		for _, f := range pkg.Files {
+			name = f.Name.Name
+			break
+		}
+		pkg.pkgDoc = pkgdoc.NewXGo(pkg.Path, &ast.Package{Name: name, Files: pkg.Files})
+	})
+	return pkg.pkgDoc
+}
+
+// localPackagesCacheKind is a cache kind type for [LocalPackage]s.
+type localPackagesCacheKind struct{}
+
+// localPackagesCache is a cache for [LocalPackage]s.
+type localPackagesCache struct {
+	pkgs map[string]*LocalPackage // Keyed by import path.
+}
+
+// buildLocalPackagesCache implements [CacheBuilder] to build a
+// [localPackagesCache] for the provided XGo project.
+func buildLocalPackagesCache(proj *Project) (any, error) {
+	modulePath := proj.ModulePath()
+	pkgs := make(map[string]*LocalPackage)
+	for p := range proj.Files() {
+		if !isLocalPackageFile(p) || proj.IsIgnored(p) {
+			continue
+		}
+		astFile, _ := proj.ASTFile(p)
+		if astFile == nil {
+			continue
+		}
+		dir := path.Dir(p)
+		importPath := dir
+		if modulePath != "" {
+			importPath = modulePath + "/" + dir
+		}
+		pkg, ok := pkgs[importPath]
+		if !ok {
+			pkg = &LocalPackage{
+				Dir:   dir,
+				Path:  importPath,
+				Files: make(map[string]*ast.File),
+			}
+			pkgs[importPath] = pkg
+		}
+		pkg.Files[p] = astFile
+	}
+
+	imp := &localPackagesImporter{
+		proj:     proj,
+		pkgs:     pkgs,
+		checking: make(map[string]bool),
+		fallback: proj.fallbackImporter(),
+	}
+	for _, importPath := range slices.Sorted(maps.Keys(pkgs)) {
+		imp.check(pkgs[importPath])
+	}
+	return &localPackagesCache{pkgs}, nil
+}
+
+// localPackagesImporter is a [types.Importer] that type checks local packages
+// on demand, in the order they are imported, while building a
+// [localPackagesCache].
+type localPackagesImporter struct {
+	proj     *Project
+	pkgs     map[string]*LocalPackage
+	checking map[string]bool
+	fallback types.Importer
+}
+
+// Import implements [types.Importer].
+func (imp *localPackagesImporter) Import(importPath string) (*types.Package, error) {
+	pkg, ok := imp.pkgs[importPath]
+	if !ok {
+		return imp.fallback.Import(importPath)
+	}
+	if imp.checking[importPath] {
+		return nil, fmt.Errorf("import cycle not allowed: %q", importPath)
+	}
+	imp.check(pkg)
+	return pkg.TypeInfo.Pkg(), nil
+}
+
+// check type checks pkg unless it has been checked already.
+func (imp *localPackagesImporter) check(pkg *LocalPackage) {
+	if pkg.TypeInfo != nil {
+		return
+	}
+	imp.checking[pkg.Path] = true
+	defer delete(imp.checking, pkg.Path)
+
+	files := make([]*ast.File, 0, len(pkg.Files))
+	for _, p := range slices.Sorted(maps.Keys(pkg.Files)) {
+		files = append(files, pkg.Files[p])
+	}
+	pkg.TypeInfo, pkg.Err = checkTypeInfo(imp.proj, pkg.Path, files[0].Name.Name, files, imp)
+}
+
+// LocalPackages retrieves the local packages of the project, keyed by their
+// import paths.
+func (p *Project) LocalPackages() (map[string]*LocalPackage, error) {
+	cacheIface, err := p.Cache(localPackagesCacheKind{})
+	if err != nil {
+		return nil, err
+	}
+	return cacheIface.(*localPackagesCache).pkgs, nil
+}
+
+// LocalPackageOf returns the local package that the file at path belongs to,
+// or nil if it does not belong to any.
+func (p *Project) LocalPackageOf(path string) *LocalPackage {
+	if !isLocalPackageFile(path) {
+		return nil
+	}
+	pkgs, err := p.LocalPackages()
+	if err != nil {
+		return nil
+	}
+	for _, pkg := range pkgs {
+		if _, ok := pkg.Files[path]; ok {
+			return pkg
+		}
+	}
+	return nil
+}
+
+// TypeInfoFor retrieves the [TypeInfo] of the package that the file at path
+// belongs to, which is either one of the local packages or the main package.
+//
+// NOTE: Both the returned [TypeInfo] and error can be non-nil, which
+// indicates that only part of the package was type checked successfully.
+func (p *Project) TypeInfoFor(path string) (*TypeInfo, error) {
+	if localPkg := p.LocalPackageOf(path); localPkg != nil {
+		return localPkg.TypeInfo, localPkg.Err
+	}
+	return p.TypeInfo()
+}
+
+// LocalPackageFor returns the local package of the project whose types are
+// pkg, or nil if pkg is not a local package of the project.
+func (p *Project) LocalPackageFor(pkg *types.Package) *LocalPackage {
+	if pkg == nil {
+		return nil
+	}
+	pkgs, err := p.LocalPackages()
+	if err != nil {
+		return nil
+	}
+	if localPkg, ok := pkgs[pkg.Path()]; ok && localPkg.TypeInfo.Pkg() == pkg {
+		return localPkg
+	}
+	return nil
+}
+
+// localImporter returns an importer that resolves the local packages of the
+// project before falling back to [Project.fallbackImporter].
+func (p *Project) localImporter() types.Importer {
+	fallback := p.fallbackImporter()
+	pkgs, err := p.LocalPackages()
+	if err != nil || len(pkgs) == 0 {
+		return fallback
+	}
+	return importerFunc(func(importPath string) (*types.Package, error) {
+		if pkg, ok := pkgs[importPath]; ok {
+			return pkg.TypeInfo.Pkg(), nil
+		}
+		return fallback.Import(importPath)
+	})
+}
+
+// importerFunc is a function that implements [types.Importer].
+type importerFunc func(path string) (*types.Package, error)
+
+// Import implements [types.Importer].
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
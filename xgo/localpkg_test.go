@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xgo
+
+import (
+	"testing"
+
+	"github.com/goplus/mod/xgomod"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLocalPackagesProject(files map[string]*File) *Project {
+	proj := NewProject(nil, files, FeatAll)
+	proj.PkgPath = "main"
+	proj.Mod = xgomod.Default
+	return proj
+}
+
+func TestIsLocalPackageFile(t *testing.T) {
+	assert.True(t, isLocalPackageFile("utils/math.xgo"))
+	assert.True(t, isLocalPackageFile("utils/math.gop"))
+	assert.True(t, isLocalPackageFile("utils/math.go"))
+	assert.True(t, isLocalPackageFile("a/b/c.xgo"))
+	assert.False(t, isLocalPackageFile("utils/math_test.go"))
+	assert.False(t, isLocalPackageFile("main.xgo"))
+	assert.False(t, isLocalPackageFile("/main.xgo"))
+	assert.False(t, isLocalPackageFile("sprites/MySprite.spx"))
+	assert.False(t, isLocalPackageFile("assets/index.json"))
+}
+
+func TestProjectModulePath(t *testing.T) {
+	t.Run("GoMod", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"go.mod": file("module example.com/game\n\ngo 1.24\n"),
+		}, FeatAll)
+		assert.Equal(t, "example.com/game", proj.ModulePath())
+	})
+
+	t.Run("GoxMod", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"gox.mod": file("module example.com/game\n"),
+		}, FeatAll)
+		assert.Equal(t, "example.com/game", proj.ModulePath())
+	})
+
+	t.Run("NoModFile", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo": file("echo 1"),
+		}, FeatAll)
+		assert.Empty(t, proj.ModulePath())
+	})
+}
+
+func TestProjectLocalPackages(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		proj := newLocalPackagesProject(map[string]*File{
+			"go.mod": file("module example.com/game\n"),
+			"main.xgo": file(`import "example.com/game/utils"
+
+echo utils.Double(utils.Answer)
+`),
+			"utils/double.xgo": file(`package utils
+
+func Double(x int) int {
+	return x * 2
+}
+`),
+			"utils/answer.go": file(`package utils
+
+const Answer = 42
+`),
+		})
+
+		pkgs, err := proj.LocalPackages()
+		require.NoError(t, err)
+		require.Len(t, pkgs, 1)
+		pkg := pkgs["example.com/game/utils"]
+		require.NotNil(t, pkg)
+		assert.Equal(t, "utils", pkg.Dir)
+		assert.Len(t, pkg.Files, 2)
+		assert.NoError(t, pkg.Err)
+		assert.NotNil(t, pkg.TypeInfo.Pkg().Scope().Lookup("Double"))
+		assert.Same(t, pkg, proj.LocalPackageOf("utils/answer.go"))
+		assert.Nil(t, proj.LocalPackageOf("main.xgo"))
+		localTypeInfo, err := proj.TypeInfoFor("utils/double.xgo")
+		require.NoError(t, err)
+		assert.Same(t, pkg.TypeInfo, localTypeInfo)
+		assert.Same(t, pkg, proj.LocalPackageFor(pkg.TypeInfo.Pkg()))
+
+		astPkg, err := proj.ASTPackage()
+		require.NoError(t, err)
+		assert.Len(t, astPkg.Files, 1)
+
+		typeInfo, err := proj.TypeInfo()
+		require.NoError(t, err)
+		var usesDouble bool
+		for ident, obj := range typeInfo.Uses {
+			if ident.Name == "Double" {
+				usesDouble = true
+				assert.Same(t, pkg.TypeInfo.Pkg().Scope().Lookup("Double"), obj)
+			}
+		}
+		assert.True(t, usesDouble)
+	})
+
+	t.Run("WithoutModFile", func(t *testing.T) {
+		proj := newLocalPackagesProject(map[string]*File{
+			"main.xgo":       file("import \"utils\"\n\necho utils.Answer\n"),
+			"utils/utils.go": file("package utils\n\nconst Answer = 42\n"),
+		})
+		pkgs, err := proj.LocalPackages()
+		require.NoError(t, err)
+		assert.Contains(t, pkgs, "utils")
+		_, err = proj.TypeInfo()
+		assert.NoError(t, err)
+	})
+
+	t.Run("DependentPackages", func(t *testing.T) {
+		proj := newLocalPackagesProject(map[string]*File{
+			"go.mod":        file("module example.com/game\n"),
+			"a/a.xgo":       file("package a\n\nimport \"example.com/game/b\"\n\nconst A = b.B + 1\n"),
+			"b/b.xgo":       file("package b\n\nconst B = 1\n"),
+			"main.xgo":      file("import \"example.com/game/a\"\n\necho a.A\n"),
+			".spxignore":    file("ignored/\n"),
+			"ignored/c.xgo": file("package c\n"),
+		})
+		pkgs, err := proj.LocalPackages()
+		require.NoError(t, err)
+		assert.Len(t, pkgs, 2)
+		assert.NoError(t, pkgs["example.com/game/a"].Err)
+		_, err = proj.TypeInfo()
+		assert.NoError(t, err)
+	})
+
+	t.Run("ImportCycle", func(t *testing.T) {
+		proj := newLocalPackagesProject(map[string]*File{
+			"go.mod":  file("module example.com/game\n"),
+			"a/a.xgo": file("package a\n\nimport \"example.com/game/b\"\n\nconst A = b.B\n"),
+			"b/b.xgo": file("package b\n\nimport \"example.com/game/a\"\n\nconst B = a.A\n"),
+		})
+		pkgs, err := proj.LocalPackages()
+		require.NoError(t, err)
+		assert.Error(t, pkgs["example.com/game/a"].Err)
+	})
+
+	t.Run("TypeError", func(t *testing.T) {
+		proj := newLocalPackagesProject(map[string]*File{
+			"utils/utils.xgo": file("package utils\n\nvar X int = \"s\"\n"),
+		})
+		pkgs, err := proj.LocalPackages()
+		require.NoError(t, err)
+		assert.Error(t, pkgs["utils"].Err)
+	})
+
+	t.Run("UpdatedFile", func(t *testing.T) {
+		proj := newLocalPackagesProject(map[string]*File{
+			"main.xgo":        file("import \"utils\"\n\necho utils.A\n"),
+			"utils/utils.xgo": file("package utils\n\nconst A = 1\n"),
+		})
+		_, err := proj.TypeInfo()
+		require.NoError(t, err)
+
+		proj.PutFile("utils/utils.xgo", file("package utils\n\nconst B = 1\n"))
+		_, err = proj.TypeInfo()
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,282 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xgo
+
+import (
+	"errors"
+	"fmt"
+	goast "go/ast"
+	"go/build"
+	goparser "go/parser"
+	gotoken "go/token"
+	"go/types"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/goplus/gogen/packages"
+	"github.com/goplus/xgolsw/pkgdoc"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+// modFile returns the name and content of the module file of the project,
+// which is the first of [modFiles] that exists.
+func (p *Project) modFile() (string, []byte, bool) {
+	for _, name := range modFiles {
+		file, ok := p.File(name)
+		if !ok {
+			continue
+		}
+		content, err := file.ReadContent()
+		if err != nil {
+			continue
+		}
+		return name, content, true
+	}
+	return "", nil, false
+}
+
+// Requires returns the modules required by the module file of the project.
+// It returns nil if there is no module file or it cannot be parsed.
+func (p *Project) Requires() []module.Version {
+	name, content, ok := p.modFile()
+	if !ok {
+		return nil
+	}
+	f, err := modfile.ParseLax(name, content, nil)
+	if err != nil {
+		return nil
+	}
+	requires := make([]module.Version, 0, len(f.Require))
+	for _, r := range f.Require {
+		requires = append(requires, r.Mod)
+	}
+	return requires
+}
+
+// moduleImporterCacheKind is a cache kind type for [moduleImporter].
+type moduleImporterCacheKind struct{}
+
+// buildModuleImporterCache implements [CacheBuilder] to build a
+// [moduleImporter] for the provided XGo project.
+func buildModuleImporterCache(proj *Project) (any, error) {
+	return &moduleImporter{
+		requires: proj.Requires(),
+		modCache: proj.ModCache,
+		fallback: proj.defaultImporter(),
+		fset:     gotoken.NewFileSet(),
+		loaded:   make(map[string]*types.Package),
+		docs:     make(map[string]*pkgdoc.PkgDoc),
+	}, nil
+}
+
+// moduleImporter is a [types.Importer] that loads packages with the importer
+// of a project, and falls back to loading the packages of the third-party
+// modules required by the project from its module cache. So packages known to
+// the importer, such as those of spx, always take precedence.
+//
+// Packages are type checked from their Go source files when they are first
+// imported, and kept along with their documentation for as long as the module
+// file of the project stays the same.
+type moduleImporter struct {
+	requires []module.Version
+	modCache fs.FS
+	fallback types.Importer
+	fset     *gotoken.FileSet
+
+	mu     sync.Mutex
+	loaded map[string]*types.Package
+	docs   map[string]*pkgdoc.PkgDoc
+}
+
+// Import implements [types.Importer].
+func (imp *moduleImporter) Import(importPath string) (*types.Package, error) {
+	imp.mu.Lock()
+	defer imp.mu.Unlock()
+	return imp.importLocked(importPath)
+}
+
+// importLocked imports the package at importPath with imp.mu held.
+func (imp *moduleImporter) importLocked(importPath string) (*types.Package, error) {
+	if pkg, ok := imp.loaded[importPath]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle not allowed: %q", importPath)
+		}
+		return pkg, nil
+	}
+
+	pkg, err := imp.fallback.Import(importPath)
+	if err == nil {
+		return pkg, nil
+	}
+	mod, ok := imp.requiredModule(importPath)
+	if !ok {
+		if isThirdPartyImportPath(importPath) {
+			return nil, fmt.Errorf("package %s is not provided by any required module; add the module that provides it to go.mod, for example with \"go get %s\"", importPath, importPath)
+		}
+		return nil, err
+	}
+	if imp.modCache == nil {
+		return nil, fmt.Errorf("cannot load package %s of module %s: no module cache is available", importPath, mod)
+	}
+
+	dir, err := moduleCacheDir(mod, strings.TrimPrefix(strings.TrimPrefix(importPath, mod.Path), "/"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot load package %s: %w", importPath, err)
+	}
+	files, testFiles, err := imp.parseDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("module %s is not in the module cache; download it with \"go mod download %s\"", mod, mod.Path)
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot load package %s: %w", importPath, err)
+	} else if len(files) == 0 {
+		return nil, fmt.Errorf("module %s does not contain package %s", mod, importPath)
+	}
+
+	imp.loaded[importPath] = nil // Marks the package as being imported.
+	conf := &types.Config{
+		Importer: importerFunc(imp.importLocked),
+		Error:    func(error) {}, // Errors in dependencies are not reported.
+	}
+	pkg, _ = conf.Check(importPath, imp.fset, files, nil)
+	imp.loaded[importPath] = pkg
+
+	if pkgDoc, err := pkgdoc.NewGoFromFiles(imp.fset, importPath, append(files, testFiles...)); err == nil {
+		imp.docs[importPath] = pkgDoc
+	}
+	return pkg, nil
+}
+
+// pkgDoc returns the documentation of the package at importPath, importing
+// the package if it has not been imported yet. It reports false if the
+// package is not loaded from the module cache.
+func (imp *moduleImporter) pkgDoc(importPath string) (*pkgdoc.PkgDoc, bool) {
+	imp.mu.Lock()
+	defer imp.mu.Unlock()
+	if _, ok := imp.loaded[importPath]; !ok {
+		if _, err := imp.importLocked(importPath); err != nil {
+			return nil, false
+		}
+	}
+	pkgDoc, ok := imp.docs[importPath]
+	return pkgDoc, ok
+}
+
+// requiredModule returns the required module that provides the package at
+// importPath, which is the one with the longest matching module path.
+func (imp *moduleImporter) requiredModule(importPath string) (module.Version, bool) {
+	var (
+		found module.Version
+		ok    bool
+	)
+	for _, mod := range imp.requires {
+		if importPath != mod.Path && !strings.HasPrefix(importPath, mod.Path+"/") {
+			continue
+		}
+		if !ok || len(mod.Path) > len(found.Path) {
+			found, ok = mod, true
+		}
+	}
+	return found, ok
+}
+
+// parseDir parses the Go source files of the package in dir of the module
+// cache, skipping files excluded by build constraints. Test files, which only
+// contribute examples to the documentation of the package, are returned
+// separately.
+func (imp *moduleImporter) parseDir(dir string) (files, testFiles []*goast.File, err error) {
+	entries, err := fs.ReadDir(imp.modCache, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctxt := build.Default
+	ctxt.CgoEnabled = false
+	ctxt.JoinPath = path.Join
+	ctxt.OpenFile = func(name string) (io.ReadCloser, error) {
+		return imp.modCache.Open(name)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || path.Ext(name) != ".go" {
+			continue
+		}
+		if match, err := ctxt.MatchFile(dir, name); err != nil || !match {
+			continue
+		}
+		content, err := fs.ReadFile(imp.modCache, path.Join(dir, name))
+		if err != nil {
+			return nil, nil, err
+		}
+		f, err := goparser.ParseFile(imp.fset, path.Join(dir, name), content, goparser.ParseComments|goparser.SkipObjectResolution)
+		if f == nil {
+			return nil, nil, err
+		}
+		if strings.HasSuffix(name, "_test.go") {
+			testFiles = append(testFiles, f)
+		} else {
+			files = append(files, f)
+		}
+	}
+	return files, testFiles, nil
+}
+
+// moduleCacheDir returns the directory of the package in subdir of mod in a
+// module cache, which is laid out like GOMODCACHE.
+func moduleCacheDir(mod module.Version, subdir string) (string, error) {
+	escapedPath, err := module.EscapePath(mod.Path)
+	if err != nil {
+		return "", err
+	}
+	escapedVersion, err := module.EscapeVersion(mod.Version)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(escapedPath+"@"+escapedVersion, subdir), nil
+}
+
+// isThirdPartyImportPath reports whether importPath is the import path of a
+// third-party package, whose first element contains a dot, rather than a
+// standard library package.
+func isThirdPartyImportPath(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return strings.Contains(first, ".")
+}
+
+// fallbackImporter returns the importer of the project for packages outside
+// of it, which loads the packages unknown to [Project.defaultImporter] from
+// the required third-party modules in ModCache.
+func (p *Project) fallbackImporter() types.Importer {
+	cacheIface, err := p.Cache(moduleImporterCacheKind{})
+	if err != nil {
+		return p.defaultImporter()
+	}
+	return cacheIface.(*moduleImporter)
+}
+
+// defaultImporter returns the importer of the project, or the default
+// importer that the type checker would use if the project has none.
+func (p *Project) defaultImporter() types.Importer {
+	if p.Importer != nil {
+		return p.Importer
+	}
+	return packages.NewImporter(p.Fset)
+}
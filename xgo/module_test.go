@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) 2025 The XGo Authors (xgo.dev). All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xgo
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/goplus/mod/xgomod"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/module"
+)
+
+func newModuleProject(files map[string]*File, modCache fstest.MapFS) *Project {
+	proj := NewProject(nil, files, FeatAll)
+	proj.PkgPath = "main"
+	proj.Mod = xgomod.Default
+	if modCache != nil {
+		proj.ModCache = modCache
+	}
+	return proj
+}
+
+func TestProjectRequires(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"go.mod": file(`module example.com/game
+
+require (
+	example.com/lib v1.0.0
+	example.com/other v0.2.0 // indirect
+)
+`),
+		}, FeatAll)
+		assert.Equal(t, []module.Version{
+			{Path: "example.com/lib", Version: "v1.0.0"},
+			{Path: "example.com/other", Version: "v0.2.0"},
+		}, proj.Requires())
+	})
+
+	t.Run("XGoMod", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"xgo.mod": file("module example.com/game\n\nrequire example.com/lib v1.0.0\n"),
+		}, FeatAll)
+		assert.Equal(t, []module.Version{{Path: "example.com/lib", Version: "v1.0.0"}}, proj.Requires())
+	})
+
+	t.Run("NoModFile", func(t *testing.T) {
+		proj := NewProject(nil, nil, FeatAll)
+		assert.Nil(t, proj.Requires())
+	})
+}
+
+func TestModuleCacheDir(t *testing.T) {
+	dir, err := moduleCacheDir(module.Version{Path: "github.com/Foo/bar", Version: "v1.2.3"}, "baz")
+	require.NoError(t, err)
+	assert.Equal(t, "github.com/!foo/bar@v1.2.3/baz", dir)
+
+	dir, err = moduleCacheDir(module.Version{Path: "example.com/lib", Version: "v1.0.0"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com/lib@v1.0.0", dir)
+}
+
+func TestProjectThirdPartyImports(t *testing.T) {
+	modCache := fstest.MapFS{
+		"example.com/!lib@v1.0.0/lib.go": {Data: []byte(`package lib
+
+const Greeting = "hello"
+`)},
+		"example.com/!lib@v1.0.0/ignored.go": {Data: []byte(`//go:build ignore
+
+package lib
+
+const Greeting = "ignored"
+`)},
+		"example.com/!lib@v1.0.0/lib_test.go": {Data: []byte(`package lib
+
+const Greeting = "test"
+`)},
+		"example.com/!lib@v1.0.0/sub/sub.go": {Data: []byte(`package sub
+
+import "example.com/Lib"
+
+func Greet(name string) string {
+	return lib.Greeting + ", " + name
+}
+`)},
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		proj := newModuleProject(map[string]*File{
+			"go.mod":   file("module example.com/game\n\nrequire example.com/Lib v1.0.0\n"),
+			"main.xgo": file("import \"example.com/Lib/sub\"\n\necho sub.Greet(\"world\")\n"),
+		}, modCache)
+		typeInfo, err := proj.TypeInfo()
+		require.NoError(t, err)
+		var found bool
+		for ident, obj := range typeInfo.Uses {
+			if ident.Name == "Greet" {
+				found = true
+				assert.Equal(t, "example.com/Lib/sub", obj.Pkg().Path())
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("NotRequired", func(t *testing.T) {
+		proj := newModuleProject(map[string]*File{
+			"main.xgo": file("import \"example.com/Lib\"\n\necho lib.Greeting\n"),
+		}, modCache)
+		_, err := proj.TypeInfo()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `go get example.com/Lib`)
+	})
+
+	t.Run("NotInModuleCache", func(t *testing.T) {
+		proj := newModuleProject(map[string]*File{
+			"go.mod":   file("module example.com/game\n\nrequire example.com/Lib v1.1.0\n"),
+			"main.xgo": file("import \"example.com/Lib\"\n\necho lib.Greeting\n"),
+		}, modCache)
+		_, err := proj.TypeInfo()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `go mod download example.com/Lib`)
+	})
+
+	t.Run("NoModuleCache", func(t *testing.T) {
+		proj := newModuleProject(map[string]*File{
+			"go.mod":   file("module example.com/game\n\nrequire example.com/Lib v1.0.0\n"),
+			"main.xgo": file("import \"example.com/Lib\"\n\necho lib.Greeting\n"),
+		}, nil)
+		_, err := proj.TypeInfo()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no module cache is available")
+	})
+
+	t.Run("StandardLibrary", func(t *testing.T) {
+		proj := newModuleProject(map[string]*File{
+			"go.mod":   file("module example.com/game\n\nrequire example.com/Lib v1.0.0\n"),
+			"main.xgo": file("import \"strings\"\n\necho strings.ToUpper(\"a\")\n"),
+		}, modCache)
+		_, err := proj.TypeInfo()
+		assert.NoError(t, err)
+	})
+}
@@ -16,7 +16,12 @@
 
 package xgo
 
-import "github.com/goplus/xgolsw/pkgdoc"
+import (
+	"fmt"
+	"io/fs"
+
+	"github.com/goplus/xgolsw/pkgdoc"
+)
 
 // pkgDocCacheKind is a cache kind type for [pkgdoc.PkgDoc].
 type pkgDocCacheKind struct{}
@@ -45,3 +50,25 @@ func (p *Project) PkgDoc() (*pkgdoc.PkgDoc, error) {
 	cache := cacheIface.(*pkgDocCache)
 	return cache.pkgDoc, nil
 }
+
+// PkgDocOf retrieves the [pkgdoc.PkgDoc] of the package at pkgPath, which is
+// extracted from the source files of the project for the main package and
+// local packages, or from the module cache for packages of required
+// third-party modules. It returns an error wrapping [fs.ErrNotExist] if the
+// source files of the package are not available to the project.
+func (p *Project) PkgDocOf(pkgPath string) (*pkgdoc.PkgDoc, error) {
+	if pkgPath == p.PkgPath {
+		return p.PkgDoc()
+	}
+	if pkgs, err := p.LocalPackages(); err == nil {
+		if pkg, ok := pkgs[pkgPath]; ok {
+			return pkg.PkgDoc(), nil
+		}
+	}
+	if cacheIface, err := p.Cache(moduleImporterCacheKind{}); err == nil {
+		if pkgDoc, ok := cacheIface.(*moduleImporter).pkgDoc(pkgPath); ok {
+			return pkgDoc, nil
+		}
+	}
+	return nil, fmt.Errorf("no source files of package %q: %w", pkgPath, fs.ErrNotExist)
+}
@@ -17,7 +17,9 @@
 package xgo
 
 import (
+	"io/fs"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -147,3 +149,75 @@ func Test() {}
 		assert.Nil(t, pkgDoc)
 	})
 }
+
+func TestProjectPkgDocOf(t *testing.T) {
+	modCache := fstest.MapFS{
+		"example.com/greet@v1.0.0/greet.go": {Data: []byte(`// Package greet greets people.
+package greet
+
+// Hello returns a greeting for name.
+func Hello(name string) string {
+	return "Hello, " + name
+}
+`)},
+	}
+	proj := newModuleProject(map[string]*File{
+		"go.mod": file("module example.com/game\n\nrequire example.com/greet v1.0.0\n"),
+		"main.spx": file(`import "example.com/game/utils"
+
+// Count is a variable.
+var Count int
+
+echo utils.Double(2)
+`),
+		"utils/math.xgo": file(`// Package utils provides utilities.
+package utils
+
+// Scale is the default scale.
+var Scale = 2
+
+// Double returns twice n.
+func Double(n int) int {
+	return n * Scale
+}
+`),
+	}, modCache)
+
+	t.Run("MainPackage", func(t *testing.T) {
+		pkgDoc, err := proj.PkgDocOf("main")
+		require.NoError(t, err)
+		want, err := proj.PkgDoc()
+		require.NoError(t, err)
+		assert.Same(t, want, pkgDoc)
+	})
+
+	t.Run("LocalPackage", func(t *testing.T) {
+		pkgDoc, err := proj.PkgDocOf("example.com/game/utils")
+		require.NoError(t, err)
+		assert.Equal(t, "example.com/game/utils", pkgDoc.Path)
+		assert.Equal(t, "utils", pkgDoc.Name)
+		assert.Equal(t, "Package utils provides utilities.\n", pkgDoc.Doc)
+		assert.Equal(t, "Double returns twice n.\n", pkgDoc.Funcs["Double"])
+		assert.Equal(t, "Scale is the default scale.\n", pkgDoc.Vars["Scale"])
+		assert.Empty(t, pkgDoc.Types)
+
+		pkgDoc2, err := proj.PkgDocOf("example.com/game/utils")
+		require.NoError(t, err)
+		assert.Same(t, pkgDoc, pkgDoc2)
+	})
+
+	t.Run("ThirdPartyModule", func(t *testing.T) {
+		pkgDoc, err := proj.PkgDocOf("example.com/greet")
+		require.NoError(t, err)
+		assert.Equal(t, "example.com/greet", pkgDoc.Path)
+		assert.Equal(t, "Package greet greets people.\n", pkgDoc.Doc)
+		assert.Equal(t, "Hello returns a greeting for name.\n", pkgDoc.Funcs["Hello"])
+	})
+
+	t.Run("NotExist", func(t *testing.T) {
+		for _, pkgPath := range []string{"fmt", "example.com/unknown", "example.com/game/missing"} {
+			_, err := proj.PkgDocOf(pkgPath)
+			assert.ErrorIs(t, err, fs.ErrNotExist, pkgPath)
+		}
+	})
+}
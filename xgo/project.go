@@ -17,11 +17,16 @@
 package xgo
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
 	"go/token"
 	"go/types"
+	"io"
 	"io/fs"
 	"iter"
 	"maps"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -34,38 +39,166 @@ const (
 	// FeatASTCache enables AST cache building.
 	FeatASTCache = 1 << iota
 
-	// FeatTypeInfoCache enables TypeInfo cache building.
+	// FeatTypeInfoCache enables TypeInfo cache building. It requires
+	// [FeatASTCache].
 	FeatTypeInfoCache
 
-	// FeatPkgDocCache enables PkgDoc cache building.
+	// FeatPkgDocCache enables PkgDoc cache building. It requires
+	// [FeatASTCache].
 	FeatPkgDocCache
 
 	// FeatAll enables all features.
 	FeatAll = FeatASTCache | FeatTypeInfoCache | FeatPkgDocCache
 )
 
+// featureNames maps the names accepted by [ParseFeatures] to the features
+// they enable, including the features they require.
+var featureNames = map[string]uint{
+	"ast":    FeatASTCache,
+	"types":  FeatASTCache | FeatTypeInfoCache,
+	"pkgdoc": FeatASTCache | FeatPkgDocCache,
+	"all":    FeatAll,
+}
+
+// ParseFeatures parses a comma-separated list of feature names into features.
+// The names are "ast" for [FeatASTCache], "types" for [FeatTypeInfoCache],
+// "pkgdoc" for [FeatPkgDocCache] and "all" for [FeatAll]. The features
+// required by the named ones are enabled as well, so "types" also enables
+// [FeatASTCache].
+func ParseFeatures(s string) (uint, error) {
+	var feats uint
+	for name := range strings.SplitSeq(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		feat, ok := featureNames[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown feature %q", name)
+		}
+		feats |= feat
+	}
+	return feats, nil
+}
+
 // cacheFeature represents a cache feature configuration that maps feature
 // flags to their corresponding cache builders.
 type cacheFeature struct {
-	flag    uint
-	kind    CacheKind
-	builder any
+	flag      uint
+	kind      CacheKind
+	builder   any
+	dependsOn func(path string) bool // Only for project level caches. Nil means all files.
 }
 
 // builtinCacheFeatures defines the built-in cache features and their configurations.
 var builtinCacheFeatures = []cacheFeature{
-	{FeatASTCache, astFileCacheKind{}, buildASTFileCache},
-	{FeatASTCache, astPackageCacheKind{}, buildASTPackageCache},
-	{FeatTypeInfoCache, typeInfoCacheKind{}, buildTypeInfoCache},
-	{FeatPkgDocCache, pkgDocCacheKind{}, buildPkgDocCache},
+	{FeatASTCache, astFileCacheKind{}, buildASTFileCache, nil},
+	{FeatASTCache, ignoreCacheKind{}, buildIgnoreCache, IsIgnoreFile},
+	{FeatASTCache, astPackageCacheKind{}, buildASTPackageCache, isPackageFile},
+	{FeatTypeInfoCache, moduleImporterCacheKind{}, buildModuleImporterCache, isModFile},
+	{FeatTypeInfoCache, localPackagesCacheKind{}, buildLocalPackagesCache, isPackageFile},
+	{FeatTypeInfoCache, typeInfoCacheKind{}, buildTypeInfoCache, isPackageFile},
+	{FeatPkgDocCache, pkgDocCacheKind{}, buildPkgDocCache, isPackageFile},
 }
 
 // File represents a file in an XGo project.
+//
+// Caches built for a file are kept as long as its Content stays the same, even
+// if its ModTime or Version changes.
+//
+// The Content of a file must not be modified once it has been put into a
+// project.
+//
+// Large files whose content is rarely needed, such as sounds and images, can
+// be loaded lazily by setting Source and Size instead of Content. Their
+// content is then read from Source every time it is needed, rather than being
+// held in memory. Use [File.Open] or [File.ReadContent] to read the content of
+// any file.
 type File struct {
 	Content []byte
 	// Deprecated: ModTime is no longer supported due to lsp text sync specification. Use Version instead.
 	ModTime time.Time
 	Version int
+
+	// Source, if not nil, opens the content of a lazily loaded file, which
+	// must stay the same for as long as the file is in a project.
+	Source func() (io.ReadCloser, error)
+	// Size is the size of the content of a lazily loaded file in bytes.
+	Size int64
+
+	hash atomic.Pointer[FileHash] // Computed on first use.
+}
+
+// IsLazy reports whether the content of the file is loaded lazily from
+// Source.
+func (f *File) IsLazy() bool {
+	return f.Source != nil
+}
+
+// Len returns the size of the content of the file in bytes, without loading
+// it.
+func (f *File) Len() int64 {
+	if f.IsLazy() {
+		return f.Size
+	}
+	return int64(len(f.Content))
+}
+
+// Open opens the content of the file for reading.
+func (f *File) Open() (io.ReadCloser, error) {
+	if f.IsLazy() {
+		return f.Source()
+	}
+	return io.NopCloser(bytes.NewReader(f.Content)), nil
+}
+
+// ReadContent returns the content of the file. For a lazily loaded file, the
+// content is read from Source and is not kept.
+func (f *File) ReadContent() ([]byte, error) {
+	if !f.IsLazy() {
+		return f.Content, nil
+	}
+	r, err := f.Source()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// FileHash is the SHA-256 hash of the content of a file.
+type FileHash [sha256.Size]byte
+
+// Hash returns the hash of the content of the file. It is computed on first
+// use and then kept with the file, so it can be used to cheaply detect whether
+// the content has changed.
+//
+// The content of a lazily loaded file is streamed from Source to compute its
+// hash. If it cannot be read, the hash of the empty content is returned and
+// not kept.
+func (f *File) Hash() FileHash {
+	if h := f.hash.Load(); h != nil {
+		return *h
+	}
+	if !f.IsLazy() {
+		h := FileHash(sha256.Sum256(f.Content))
+		f.hash.Store(&h)
+		return h
+	}
+
+	r, err := f.Source()
+	if err != nil {
+		return FileHash(sha256.Sum256(nil))
+	}
+	defer r.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, r); err != nil {
+		return FileHash(sha256.Sum256(nil))
+	}
+	var h FileHash
+	hasher.Sum(h[:0])
+	f.hash.Store(&h)
+	return h
 }
 
 // Project represents an XGo project.
@@ -75,17 +208,34 @@ type Project struct {
 	Importer types.Importer
 	Fset     *token.FileSet
 
+	// ModCache is the module cache, laid out like GOMODCACHE, from which
+	// the packages of the third-party modules required by the module file
+	// of the project are loaded. Nil means only packages known to Importer
+	// can be imported.
+	ModCache fs.FS
+
+	feats uint // Features the project was created with.
+
 	mu            sync.RWMutex
-	files         map[string]*File
+	files         map[string]*File                 // Replaced rather than modified, so snapshots can share it.
 	filesSnapshot atomic.Pointer[map[string]*File] // Immutable snapshot for lock-free file reads.
+	origin        *Project                         // Project this project is a snapshot of, if any.
 
 	cacheBuilders map[CacheKind]CacheBuilder
+	cacheDeps     map[CacheKind]func(path string) bool
 	caches        map[CacheKind]dataOrErr
 	cacheSFG      singleflight.Group
 
 	fileCacheBuilders map[CacheKind]FileCacheBuilder
 	fileCaches        map[fileCacheKey]dataOrErr
 	fileCacheSFG      singleflight.Group
+
+	cacheHits   atomic.Uint64
+	cacheMisses atomic.Uint64
+
+	generation            atomic.Uint64
+	fileGenerations       map[string]uint64 // Generation at which each file was last added or changed.
+	fileGenerationsShared atomic.Bool       // Whether fileGenerations is shared with a snapshot.
 }
 
 // NewProject creates a new project with optional static files and features.
@@ -95,8 +245,10 @@ func NewProject(fset *token.FileSet, files map[string]*File, feats uint) *Projec
 	}
 	proj := &Project{
 		Fset:              fset,
+		feats:             feats,
 		files:             make(map[string]*File),
 		cacheBuilders:     make(map[CacheKind]CacheBuilder),
+		cacheDeps:         make(map[CacheKind]func(path string) bool),
 		caches:            make(map[CacheKind]dataOrErr),
 		fileCacheBuilders: make(map[CacheKind]FileCacheBuilder),
 		fileCaches:        make(map[fileCacheKey]dataOrErr),
@@ -105,11 +257,16 @@ func NewProject(fset *token.FileSet, files map[string]*File, feats uint) *Projec
 		maps.Copy(proj.files, files)
 	}
 	proj.updateFilesSnapshot()
+	proj.generation.Store(nextGeneration())
+	proj.fileGenerations = make(map[string]uint64, len(proj.files))
+	for path := range proj.files {
+		proj.fileGenerations[path] = proj.generation.Load()
+	}
 	for _, feat := range builtinCacheFeatures {
 		if feat.flag&feats != 0 {
 			switch feat.builder.(type) {
 			case CacheBuilder:
-				proj.RegisterCacheBuilder(feat.kind, feat.builder.(CacheBuilder))
+				proj.RegisterCacheBuilderWithDeps(feat.kind, feat.builder.(CacheBuilder), feat.dependsOn)
 			case FileCacheBuilder:
 				proj.RegisterFileCacheBuilder(feat.kind, feat.builder.(FileCacheBuilder))
 			}
@@ -118,7 +275,20 @@ func NewProject(fset *token.FileSet, files map[string]*File, feats uint) *Projec
 	return proj
 }
 
-// Snapshot creates a snapshot of the project.
+// Features returns the features the project was created with, such as
+// [FeatAll].
+func (p *Project) Features() uint {
+	return p.feats
+}
+
+// Snapshot creates an immutable view of the current state of the project.
+// Changes made to the project afterwards are not visible in the snapshot, and
+// vice versa.
+//
+// Snapshots are cheap: the files are shared copy-on-write, and caches built by
+// a snapshot are shared with the project as long as neither has changed since
+// the snapshot was taken, so the work done for one is not repeated for the
+// other.
 func (p *Project) Snapshot() *Project {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
@@ -128,16 +298,115 @@ func (p *Project) Snapshot() *Project {
 		Mod:               p.Mod,
 		Importer:          p.Importer,
 		Fset:              p.Fset,
-		files:             maps.Clone(p.files),
+		ModCache:          p.ModCache,
+		feats:             p.feats,
+		files:             p.files,
+		origin:            p,
 		cacheBuilders:     maps.Clone(p.cacheBuilders),
+		cacheDeps:         maps.Clone(p.cacheDeps),
 		caches:            maps.Clone(p.caches),
 		fileCacheBuilders: maps.Clone(p.fileCacheBuilders),
 		fileCaches:        maps.Clone(p.fileCaches),
 	}
-	proj.updateFilesSnapshot()
+	files := p.files
+	proj.filesSnapshot.Store(&files)
+	proj.generation.Store(p.generation.Load())
+	proj.fileGenerations = p.fileGenerations
+	proj.fileGenerationsShared.Store(true)
+	p.fileGenerationsShared.Store(true)
 	return proj
 }
 
+// Fork creates a snapshot of the project with the overlay files applied, so
+// proposed changes can be analyzed, such as type checked, without changing the
+// project. A nil file in overlay deletes the file at its path from the fork.
+//
+// Like a snapshot, a fork keeps the caches built for the files that are not
+// changed by overlay.
+func (p *Project) Fork(overlay map[string]*File) *Project {
+	fork := p.Snapshot()
+	if len(overlay) == 0 {
+		return fork
+	}
+
+	fork.mu.Lock()
+	defer fork.mu.Unlock()
+	files := maps.Clone(fork.files)
+	changed := false
+	for path, file := range overlay {
+		oldFile, ok := files[path]
+		if file == nil {
+			if ok {
+				delete(files, path)
+				fork.deleteFileCache(path)
+				delete(fork.writableFileGenerations(), path)
+				changed = true
+			}
+			continue
+		}
+		if ok && sameFile(oldFile, file) {
+			continue
+		}
+		files[path] = file
+		changed = true
+		if !ok || !sameContent(oldFile, file) {
+			fork.deleteFileCache(path)
+		}
+	}
+	if changed {
+		fork.setFiles(files)
+	}
+	return fork
+}
+
+// generationCounter is the source of generations for all projects.
+var generationCounter atomic.Uint64
+
+// nextGeneration returns a generation that has never been used before.
+func nextGeneration() uint64 {
+	return generationCounter.Add(1)
+}
+
+// Generation returns the generation of the project. It increases every time
+// the content of the project changes, so data derived from the project can
+// cheaply detect whether it is stale.
+//
+// Generations are unique across projects: a snapshot has the same generation
+// as the project it was taken from until either of them changes, and two
+// projects with the same generation always have the same content.
+//
+// Putting a file with the same content as before does not change the
+// generation.
+func (p *Project) Generation() uint64 {
+	return p.generation.Load()
+}
+
+// FileGeneration returns the generation of the project at which the file at
+// path was last added or changed, and whether the file exists. Unlike
+// [Project.Generation], it does not change when other files change, so data
+// derived from a single file can outlive changes to the rest of the project.
+func (p *Project) FileGeneration(path string) (generation uint64, ok bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if _, ok := p.files[path]; !ok {
+		return 0, false
+	}
+	return p.fileGenerations[path], true
+}
+
+// writableFileGenerations returns p.fileGenerations, copying it first if it is
+// shared with a snapshot. It must be called with p.mu held.
+func (p *Project) writableFileGenerations() map[string]uint64 {
+	if p.fileGenerationsShared.Load() || p.fileGenerations == nil {
+		p.fileGenerations = maps.Clone(p.fileGenerations)
+		if p.fileGenerations == nil {
+			p.fileGenerations = make(map[string]uint64)
+		}
+		p.fileGenerationsShared.Store(false)
+	}
+	return p.fileGenerations
+}
+
 // Files returns an iterator over all file path-content pairs in the project.
 func (p *Project) Files() iter.Seq2[string, *File] {
 	snapshot := p.filesSnapshot.Load()
@@ -152,13 +421,22 @@ func (p *Project) File(path string) (file *File, ok bool) {
 	return
 }
 
-// PutFile puts a file into the project.
+// PutFile puts a file into the project. Caches are kept if the file already
+// exists with the same content, and nothing is done if it also has the same
+// ModTime and Version.
 func (p *Project) PutFile(path string, file *File) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.files[path] = file
-	p.updateFilesSnapshot()
-	p.deleteFileCache(path)
+	oldFile, ok := p.files[path]
+	if ok && sameFile(oldFile, file) {
+		return
+	}
+	files := maps.Clone(p.files)
+	files[path] = file
+	p.setFiles(files)
+	if !ok || !sameContent(oldFile, file) {
+		p.deleteFileCache(path)
+	}
 }
 
 // DeleteFile deletes a file from the project.
@@ -166,9 +444,11 @@ func (p *Project) DeleteFile(path string) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if _, ok := p.files[path]; ok {
-		delete(p.files, path)
-		p.updateFilesSnapshot()
+		files := maps.Clone(p.files)
+		delete(files, path)
+		p.setFiles(files)
 		p.deleteFileCache(path)
+		delete(p.writableFileGenerations(), path)
 		return nil
 	}
 	return fs.ErrNotExist
@@ -187,45 +467,95 @@ func (p *Project) RenameFile(oldPath, newPath string) error {
 		return fs.ErrExist
 	}
 
-	p.files[newPath] = file
-	delete(p.files, oldPath)
-	p.updateFilesSnapshot()
+	files := maps.Clone(p.files)
+	files[newPath] = file
+	delete(files, oldPath)
+	p.setFiles(files)
 	p.deleteFileCache(oldPath)
+	fileGenerations := p.writableFileGenerations()
+	delete(fileGenerations, oldPath)
+	fileGenerations[newPath] = p.generation.Load()
 	return nil
 }
 
 // UpdateFiles updates all files in the project with the provided map of files.
 // It removes existing files not present in the new map and updates files from
-// the new map.
+// the new map. Caches are kept for updated files whose content stays the same.
 func (p *Project) UpdateFiles(newFiles map[string]*File) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	files := maps.Clone(p.files)
+	changed := false
+
 	// Delete files that are not in the new map.
-	maps.DeleteFunc(p.files, func(path string, _ *File) bool {
+	maps.DeleteFunc(files, func(path string, _ *File) bool {
 		_, ok := newFiles[path]
 		if !ok {
 			p.deleteFileCache(path)
+			delete(p.writableFileGenerations(), path)
+			changed = true
 		}
 		return !ok
 	})
 
 	// Add or update files from the new map.
 	for path, newFile := range newFiles {
-		if oldFile, ok := p.files[path]; ok {
-			// Only update if ModTime changed.
-			if !oldFile.ModTime.Equal(newFile.ModTime) {
-				p.files[path] = newFile
-				p.deleteFileCache(path)
+		if oldFile, ok := files[path]; ok {
+			// Only update if ModTime changed. Files without ModTime are
+			// compared by content and Version instead, so pushing the same
+			// file again is a no-op.
+			modified := !oldFile.ModTime.Equal(newFile.ModTime)
+			if oldFile.ModTime.IsZero() && newFile.ModTime.IsZero() {
+				modified = !sameFile(oldFile, newFile)
+			}
+			if modified {
+				files[path] = newFile
+				changed = true
+				if !sameContent(oldFile, newFile) {
+					p.deleteFileCache(path)
+				}
 			}
 		} else {
 			// New file, always add.
-			p.files[path] = newFile
+			files[path] = newFile
+			changed = true
 			p.deleteFileCache(path)
 		}
 	}
 
-	p.updateFilesSnapshot()
+	if changed {
+		p.setFiles(files)
+	}
+}
+
+// sameContent reports whether both files are non-nil and have the same
+// content, comparing their sizes and then their hashes. The content of a
+// lazily loaded file is only streamed to compute its hash if its size matches.
+func sameContent(a, b *File) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if a == b {
+		return true
+	}
+	if a.Len() != b.Len() {
+		return false
+	}
+	return a.Hash() == b.Hash()
+}
+
+// sameFile reports whether both files are non-nil and have the same content,
+// ModTime and Version, so replacing one with the other is a no-op.
+func sameFile(a, b *File) bool {
+	return sameContent(a, b) && a.ModTime.Equal(b.ModTime) && a.Version == b.Version
+}
+
+// setFiles replaces the files of the project with files, which must not be
+// modified afterwards. It must be called with p.mu held.
+func (p *Project) setFiles(files map[string]*File) {
+	p.files = files
+	p.filesSnapshot.Store(&files)
 }
 
 // updateFilesSnapshot updates the atomic snapshot of files.
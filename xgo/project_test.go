@@ -17,14 +17,19 @@
 package xgo
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"go/token"
+	"io"
 	"io/fs"
+	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func file(content string) *File {
@@ -103,6 +108,12 @@ func TestNewProject(t *testing.T) {
 		assert.GreaterOrEqual(t, total3, 0)
 	})
 
+	t.Run("Features", func(t *testing.T) {
+		proj := NewProject(nil, nil, FeatASTCache|FeatPkgDocCache)
+		assert.Equal(t, uint(FeatASTCache|FeatPkgDocCache), proj.Features())
+		assert.Equal(t, uint(FeatASTCache|FeatPkgDocCache), proj.Snapshot().Features())
+	})
+
 	t.Run("FilesSnapshotIsCreated", func(t *testing.T) {
 		files := map[string]*File{
 			"test.go": file("package test"),
@@ -119,6 +130,33 @@ func TestNewProject(t *testing.T) {
 	})
 }
 
+func TestParseFeatures(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		s       string
+		want    uint
+		wantErr string
+	}{
+		{name: "Empty", s: "", want: 0},
+		{name: "AST", s: "ast", want: FeatASTCache},
+		{name: "Types", s: "types", want: FeatASTCache | FeatTypeInfoCache},
+		{name: "PkgDoc", s: "pkgdoc", want: FeatASTCache | FeatPkgDocCache},
+		{name: "Multiple", s: "types, pkgdoc", want: FeatAll},
+		{name: "All", s: "all", want: FeatAll},
+		{name: "Unknown", s: "ast,lint", wantErr: `unknown feature "lint"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFeatures(tt.s)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestProjectSnapshot(t *testing.T) {
 	t.Run("BasicSnapshot", func(t *testing.T) {
 		files := map[string]*File{
@@ -231,6 +269,119 @@ func TestProjectSnapshot(t *testing.T) {
 		assert.True(t, ok)
 		assert.Equal(t, []byte("package test"), testFile.Content)
 	})
+
+	t.Run("FilesAreCopiedOnWrite", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo": file("package main"),
+		}, 0)
+
+		snapshot := proj.Snapshot()
+		assert.Equal(t, reflect.ValueOf(proj.files).Pointer(), reflect.ValueOf(snapshot.files).Pointer())
+
+		snapshot.PutFile("main.xgo", file("package main\n\nvar x int"))
+		assert.NotEqual(t, reflect.ValueOf(proj.files).Pointer(), reflect.ValueOf(snapshot.files).Pointer())
+		mainFile, ok := proj.File("main.xgo")
+		require.True(t, ok)
+		assert.Equal(t, []byte("package main"), mainFile.Content)
+	})
+
+	t.Run("CachesAreShared", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo": file("package main"),
+		}, FeatASTCache)
+
+		// Caches built by a snapshot are shared with the project.
+		snapshot := proj.Snapshot()
+		astFile, err := snapshot.ASTFile("main.xgo")
+		require.NoError(t, err)
+		projASTFile, err := proj.ASTFile("main.xgo")
+		require.NoError(t, err)
+		assert.Same(t, astFile, projASTFile)
+
+		// Caches built by the project after the snapshot was taken are
+		// shared with the snapshot.
+		snapshot = proj.Snapshot()
+		projASTPkg, err := proj.ASTPackage()
+		require.NoError(t, err)
+		astPkg, err := snapshot.ASTPackage()
+		require.NoError(t, err)
+		assert.Same(t, projASTPkg, astPkg)
+	})
+
+	t.Run("CachesAreNotSharedAfterChange", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo": file("package main"),
+		}, FeatASTCache)
+
+		snapshot := proj.Snapshot()
+		proj.PutFile("main.xgo", file("package main\n\nvar x int"))
+		astFile, err := snapshot.ASTFile("main.xgo")
+		require.NoError(t, err)
+		assert.Empty(t, astFile.Decls)
+
+		projASTFile, err := proj.ASTFile("main.xgo")
+		require.NoError(t, err)
+		assert.NotSame(t, astFile, projASTFile)
+		assert.Len(t, projASTFile.Decls, 1)
+	})
+}
+
+func TestProjectFork(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo":  file("package main\n\nvar x int"),
+			"other.xgo": file("package main\n\nvar y = x"),
+		}, FeatAll)
+		otherASTFile, err := proj.ASTFile("other.xgo")
+		require.NoError(t, err)
+		_, err = proj.TypeInfo()
+		require.NoError(t, err)
+		gen := proj.Generation()
+
+		fork := proj.Fork(map[string]*File{
+			"main.xgo": file("package main\n\nvar x string\n\nvar z int = y"),
+		})
+		assert.Greater(t, fork.Generation(), gen)
+		_, err = fork.TypeInfo()
+		assert.Error(t, err)
+
+		// Caches of unchanged files are shared with the project.
+		forkOtherASTFile, err := fork.ASTFile("other.xgo")
+		require.NoError(t, err)
+		assert.Same(t, otherASTFile, forkOtherASTFile)
+
+		// The project is not changed.
+		assert.Equal(t, gen, proj.Generation())
+		mainFile, ok := proj.File("main.xgo")
+		require.True(t, ok)
+		assert.Equal(t, []byte("package main\n\nvar x int"), mainFile.Content)
+		_, err = proj.TypeInfo()
+		assert.NoError(t, err)
+	})
+
+	t.Run("DeleteFile", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo":  file("package main"),
+			"other.xgo": file("package main"),
+		}, 0)
+
+		fork := proj.Fork(map[string]*File{"other.xgo": nil, "missing.xgo": nil})
+		_, ok := fork.File("other.xgo")
+		assert.False(t, ok)
+		_, ok = proj.File("other.xgo")
+		assert.True(t, ok)
+	})
+
+	t.Run("EmptyOverlay", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo": file("package main"),
+		}, 0)
+
+		fork := proj.Fork(nil)
+		assert.Equal(t, proj.Generation(), fork.Generation())
+		fork = proj.Fork(map[string]*File{"main.xgo": file("package main")})
+		assert.Equal(t, proj.Generation(), fork.Generation())
+	})
 }
 
 func TestProjectFiles(t *testing.T) {
@@ -438,6 +589,104 @@ func TestProjectFile(t *testing.T) {
 	})
 }
 
+func TestFileHash(t *testing.T) {
+	t.Run("SameContent", func(t *testing.T) {
+		assert.Equal(t, file("package main").Hash(), file("package main").Hash())
+	})
+
+	t.Run("DifferentContent", func(t *testing.T) {
+		assert.NotEqual(t, file("package main").Hash(), file("package main\n").Hash())
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		assert.Equal(t, FileHash(sha256.Sum256(nil)), (&File{}).Hash())
+	})
+
+	t.Run("Cached", func(t *testing.T) {
+		f := file("package main")
+		h := f.Hash()
+		assert.NotNil(t, f.hash.Load())
+		assert.Equal(t, h, f.Hash())
+	})
+}
+
+func TestFileLazy(t *testing.T) {
+	lazyFile := func(content string, opens *int) *File {
+		return &File{
+			Source: func() (io.ReadCloser, error) {
+				*opens++
+				return io.NopCloser(strings.NewReader(content)), nil
+			},
+			Size: int64(len(content)),
+		}
+	}
+
+	t.Run("ReadContent", func(t *testing.T) {
+		var opens int
+		f := lazyFile("RIFF", &opens)
+		assert.True(t, f.IsLazy())
+		assert.Equal(t, int64(4), f.Len())
+		assert.Equal(t, 0, opens)
+
+		content, err := f.ReadContent()
+		require.NoError(t, err)
+		assert.Equal(t, []byte("RIFF"), content)
+		assert.Nil(t, f.Content)
+		assert.Equal(t, 1, opens)
+	})
+
+	t.Run("Open", func(t *testing.T) {
+		for _, f := range []*File{file("RIFF"), lazyFile("RIFF", new(int))} {
+			r, err := f.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.NoError(t, r.Close())
+			assert.Equal(t, []byte("RIFF"), content)
+		}
+	})
+
+	t.Run("SourceError", func(t *testing.T) {
+		f := &File{
+			Source: func() (io.ReadCloser, error) { return nil, fs.ErrNotExist },
+			Size:   4,
+		}
+		_, err := f.ReadContent()
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+		assert.Equal(t, FileHash(sha256.Sum256(nil)), f.Hash())
+		assert.Nil(t, f.hash.Load())
+	})
+
+	t.Run("Hash", func(t *testing.T) {
+		var opens int
+		f := lazyFile("RIFF", &opens)
+		assert.Equal(t, file("RIFF").Hash(), f.Hash())
+		assert.Equal(t, file("RIFF").Hash(), f.Hash())
+		assert.Equal(t, 1, opens)
+	})
+
+	t.Run("PutFile", func(t *testing.T) {
+		var opens int
+		proj := NewProject(nil, map[string]*File{
+			"assets/sounds/a.wav": lazyFile("RIFF", &opens),
+		}, 0)
+		gen := proj.Generation()
+
+		proj.PutFile("assets/sounds/a.wav", lazyFile("RIFF", &opens))
+		assert.Equal(t, gen, proj.Generation())
+		assert.Equal(t, 2, opens)
+
+		proj.PutFile("assets/sounds/a.wav", lazyFile("RIFX", &opens))
+		assert.Greater(t, proj.Generation(), gen)
+		assert.Equal(t, 3, opens)
+
+		gen = proj.Generation()
+		proj.PutFile("assets/sounds/a.wav", lazyFile("RIFF2", &opens))
+		assert.Greater(t, proj.Generation(), gen)
+		assert.Equal(t, 3, opens)
+	})
+}
+
 func TestProjectPutFile(t *testing.T) {
 	t.Run("AddNewFile", func(t *testing.T) {
 		proj := NewProject(nil, nil, 0)
@@ -522,6 +771,41 @@ func TestProjectPutFile(t *testing.T) {
 		assert.Nil(t, nilFile)
 	})
 
+	t.Run("SameContentKeepsCaches", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo": {Content: []byte("package main"), Version: 1},
+		}, FeatAll)
+
+		astFile1, err := proj.ASTFile("main.xgo")
+		assert.NoError(t, err)
+		typeInfo1, err := proj.TypeInfo()
+		assert.NoError(t, err)
+
+		// Put the same content with a new version.
+		proj.PutFile("main.xgo", &File{Content: []byte("package main"), Version: 2})
+
+		updatedFile, ok := proj.File("main.xgo")
+		assert.True(t, ok)
+		assert.Equal(t, 2, updatedFile.Version)
+
+		astFile2, err := proj.ASTFile("main.xgo")
+		assert.NoError(t, err)
+		assert.Same(t, astFile1, astFile2)
+		typeInfo2, err := proj.TypeInfo()
+		assert.NoError(t, err)
+		assert.Same(t, typeInfo1, typeInfo2)
+
+		// Put different content.
+		proj.PutFile("main.xgo", &File{Content: []byte("package main\n\nvar x int"), Version: 3})
+
+		astFile3, err := proj.ASTFile("main.xgo")
+		assert.NoError(t, err)
+		assert.NotSame(t, astFile1, astFile3)
+		typeInfo3, err := proj.TypeInfo()
+		assert.NoError(t, err)
+		assert.NotSame(t, typeInfo1, typeInfo3)
+	})
+
 	t.Run("FilesSnapshotUpdated", func(t *testing.T) {
 		proj := NewProject(nil, nil, 0)
 
@@ -536,6 +820,23 @@ func TestProjectPutFile(t *testing.T) {
 		assert.Len(t, *snapshotAfter, 1)
 		assert.NotEqual(t, snapshotBefore, snapshotAfter)
 	})
+	t.Run("SameFile", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo": {Content: []byte("package main"), Version: 1},
+		}, 0)
+		gen := proj.Generation()
+		snapshotBefore := proj.filesSnapshot.Load()
+
+		proj.PutFile("main.xgo", &File{Content: []byte("package main"), Version: 1})
+		assert.Equal(t, gen, proj.Generation())
+		assert.Same(t, snapshotBefore, proj.filesSnapshot.Load())
+
+		proj.PutFile("main.xgo", &File{Content: []byte("package main"), Version: 2})
+		assert.Equal(t, gen, proj.Generation())
+		mainFile, ok := proj.File("main.xgo")
+		require.True(t, ok)
+		assert.Equal(t, 2, mainFile.Version)
+	})
 }
 
 func TestProjectDeleteFile(t *testing.T) {
@@ -860,6 +1161,31 @@ func TestProjectUpdateFiles(t *testing.T) {
 		assert.Equal(t, newTime, mainFile.ModTime)
 	})
 
+	t.Run("UpdateFilesWithModifiedTimeAndSameContent", func(t *testing.T) {
+		oldTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		newTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		files := map[string]*File{
+			"main.xgo": {Content: []byte("package main"), ModTime: oldTime},
+		}
+		proj := NewProject(nil, files, FeatAll)
+
+		astFile1, err := proj.ASTFile("main.xgo")
+		assert.NoError(t, err)
+
+		proj.UpdateFiles(map[string]*File{
+			"main.xgo": {Content: []byte("package main"), ModTime: newTime},
+		})
+
+		// Verify file was updated, but its caches were kept.
+		mainFile, ok := proj.File("main.xgo")
+		assert.True(t, ok)
+		assert.Equal(t, newTime, mainFile.ModTime)
+		astFile2, err := proj.ASTFile("main.xgo")
+		assert.NoError(t, err)
+		assert.Same(t, astFile1, astFile2)
+	})
+
 	t.Run("UpdateFilesWithSameModTime", func(t *testing.T) {
 		sameTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
 
@@ -982,6 +1308,34 @@ func TestProjectUpdateFiles(t *testing.T) {
 		assert.True(t, ok)
 		assert.Equal(t, []byte("package main"), mainFile.Content)
 	})
+	t.Run("UpdateFilesWithoutModTime", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo": file("package main"),
+		}, FeatAll)
+		astFile1, err := proj.ASTFile("main.xgo")
+		require.NoError(t, err)
+		gen := proj.Generation()
+		snapshotBefore := proj.filesSnapshot.Load()
+
+		// Pushing the same content again is a no-op.
+		proj.UpdateFiles(map[string]*File{
+			"main.xgo": file("package main"),
+		})
+		assert.Equal(t, gen, proj.Generation())
+		assert.Same(t, snapshotBefore, proj.filesSnapshot.Load())
+		astFile2, err := proj.ASTFile("main.xgo")
+		require.NoError(t, err)
+		assert.Same(t, astFile1, astFile2)
+
+		// Different content is detected without ModTime.
+		proj.UpdateFiles(map[string]*File{
+			"main.xgo": file("package main\n\nvar x int"),
+		})
+		assert.Greater(t, proj.Generation(), gen)
+		mainFile, ok := proj.File("main.xgo")
+		require.True(t, ok)
+		assert.Equal(t, []byte("package main\n\nvar x int"), mainFile.Content)
+	})
 }
 
 func TestProjectUpdateFilesSnapshot(t *testing.T) {
@@ -1133,3 +1487,158 @@ func TestProjectUpdateFilesSnapshot(t *testing.T) {
 		wg.Wait()
 	})
 }
+
+func TestProjectGeneration(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo": file("package main"),
+		}, 0)
+		gen := proj.Generation()
+
+		proj.PutFile("main.xgo", file("package main"))
+		assert.Equal(t, gen, proj.Generation(), "same content should not change the generation")
+
+		proj.PutFile("main.xgo", file("package main\n\nvar x int"))
+		assert.Greater(t, proj.Generation(), gen)
+		gen = proj.Generation()
+
+		proj.PutFile("assets/index.json", file("{}"))
+		assert.Greater(t, proj.Generation(), gen)
+		gen = proj.Generation()
+
+		assert.NoError(t, proj.RenameFile("assets/index.json", "assets/other.json"))
+		assert.Greater(t, proj.Generation(), gen)
+		gen = proj.Generation()
+
+		assert.NoError(t, proj.DeleteFile("assets/other.json"))
+		assert.Greater(t, proj.Generation(), gen)
+		gen = proj.Generation()
+
+		assert.ErrorIs(t, proj.DeleteFile("assets/other.json"), fs.ErrNotExist)
+		assert.Equal(t, gen, proj.Generation())
+	})
+
+	t.Run("UpdateFiles", func(t *testing.T) {
+		oldTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		newTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo": {Content: []byte("package main"), ModTime: oldTime},
+		}, 0)
+		gen := proj.Generation()
+
+		proj.UpdateFiles(map[string]*File{
+			"main.xgo": {Content: []byte("package main"), ModTime: newTime},
+		})
+		assert.Equal(t, gen, proj.Generation())
+
+		proj.UpdateFiles(map[string]*File{
+			"main.xgo": {Content: []byte("package main\n\nvar x int"), ModTime: oldTime},
+		})
+		assert.Greater(t, proj.Generation(), gen)
+	})
+
+	t.Run("Snapshot", func(t *testing.T) {
+		proj := NewProject(nil, nil, 0)
+		proj.PutFile("main.xgo", file("package main"))
+		gen := proj.Generation()
+
+		snapshot := proj.Snapshot()
+		assert.Equal(t, gen, snapshot.Generation())
+
+		snapshot.PutFile("main.xgo", file("package main\n\nvar x int"))
+		assert.Greater(t, snapshot.Generation(), gen)
+		assert.Equal(t, gen, proj.Generation())
+	})
+
+	t.Run("UniqueAcrossProjects", func(t *testing.T) {
+		proj1 := NewProject(nil, nil, 0)
+		proj2 := NewProject(nil, nil, 0)
+		assert.NotEqual(t, proj1.Generation(), proj2.Generation())
+
+		snapshot := proj1.Snapshot()
+		proj1.PutFile("main.xgo", file("package main"))
+		snapshot.PutFile("main.xgo", file("package foo"))
+		assert.NotEqual(t, proj1.Generation(), snapshot.Generation())
+	})
+}
+
+func TestProjectFileGeneration(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo":  file("package main"),
+			"other.xgo": file("package main"),
+		}, 0)
+		mainGen, ok := proj.FileGeneration("main.xgo")
+		require.True(t, ok)
+		assert.Equal(t, proj.Generation(), mainGen)
+
+		proj.PutFile("other.xgo", file("package main\n\nvar x int"))
+		gen, ok := proj.FileGeneration("main.xgo")
+		require.True(t, ok)
+		assert.Equal(t, mainGen, gen, "changes to other files should not change the file generation")
+		otherGen, ok := proj.FileGeneration("other.xgo")
+		require.True(t, ok)
+		assert.Equal(t, proj.Generation(), otherGen)
+
+		proj.PutFile("main.xgo", file("package main"))
+		gen, ok = proj.FileGeneration("main.xgo")
+		require.True(t, ok)
+		assert.Equal(t, mainGen, gen, "same content should not change the file generation")
+
+		proj.PutFile("main.xgo", file("package main\n\nvar y int"))
+		gen, ok = proj.FileGeneration("main.xgo")
+		require.True(t, ok)
+		assert.Greater(t, gen, mainGen)
+
+		_, ok = proj.FileGeneration("nonexistent.xgo")
+		assert.False(t, ok)
+	})
+
+	t.Run("DeleteAndRename", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo":  file("package main"),
+			"other.xgo": file("package main"),
+		}, 0)
+
+		require.NoError(t, proj.DeleteFile("other.xgo"))
+		_, ok := proj.FileGeneration("other.xgo")
+		assert.False(t, ok)
+
+		mainGen, _ := proj.FileGeneration("main.xgo")
+		require.NoError(t, proj.RenameFile("main.xgo", "renamed.xgo"))
+		_, ok = proj.FileGeneration("main.xgo")
+		assert.False(t, ok)
+		gen, ok := proj.FileGeneration("renamed.xgo")
+		require.True(t, ok)
+		assert.Greater(t, gen, mainGen)
+		assert.Equal(t, proj.Generation(), gen)
+
+		proj.UpdateFiles(map[string]*File{})
+		_, ok = proj.FileGeneration("renamed.xgo")
+		assert.False(t, ok)
+	})
+
+	t.Run("Snapshot", func(t *testing.T) {
+		proj := NewProject(nil, map[string]*File{
+			"main.xgo": file("package main"),
+		}, 0)
+		mainGen, _ := proj.FileGeneration("main.xgo")
+
+		snapshot := proj.Snapshot()
+		gen, ok := snapshot.FileGeneration("main.xgo")
+		require.True(t, ok)
+		assert.Equal(t, mainGen, gen)
+
+		snapshot.PutFile("main.xgo", file("package main\n\nvar x int"))
+		gen, ok = proj.FileGeneration("main.xgo")
+		require.True(t, ok)
+		assert.Equal(t, mainGen, gen)
+
+		proj.PutFile("other.xgo", file("package main"))
+		_, ok = snapshot.FileGeneration("other.xgo")
+		assert.False(t, ok)
+		snapshotGen, _ := snapshot.FileGeneration("main.xgo")
+		assert.Greater(t, snapshotGen, mainGen)
+	})
+}
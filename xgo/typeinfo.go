@@ -44,6 +44,13 @@ func buildTypeInfoCache(proj *Project) (any, error) {
 		return nil, fmt.Errorf("failed to retrieve AST package: %w", astErr)
 	}
 
+	typeInfo, checkerErr := checkTypeInfo(proj, proj.PkgPath, astPkg.Name, slices.Collect(maps.Values(astPkg.Files)), proj.localImporter())
+	return &typeInfoCache{typeInfo, checkerErr}, nil
+}
+
+// checkTypeInfo type checks the provided files as the package with the
+// provided path and name, resolving its imports with imp.
+func checkTypeInfo(proj *Project, pkgPath, pkgName string, files []*ast.File, imp types.Importer) (*TypeInfo, error) {
 	typeInfo := &TypeInfo{
 		Info: typesutil.Info{
 			Types:      make(map[ast.Expr]types.TypeAndValue),
@@ -53,14 +60,14 @@ func buildTypeInfoCache(proj *Project) (any, error) {
 			Implicits:  make(map[ast.Node]types.Object),
 			Scopes:     make(map[ast.Node]*types.Scope),
 		},
-		pkg: types.NewPackage(proj.PkgPath, astPkg.Name),
+		pkg: types.NewPackage(pkgPath, pkgName),
 	}
 
 	var checkerErrs errors.List
 	if err := typesutil.NewChecker(
 		&types.Config{
 			Error:    func(err error) { checkerErrs.Add(err) },
-			Importer: proj.Importer,
+			Importer: imp,
 		},
 		&typesutil.Config{
 			Types: typeInfo.pkg,
@@ -69,7 +76,7 @@ func buildTypeInfoCache(proj *Project) (any, error) {
 		},
 		nil,
 		&typeInfo.Info,
-	).Files(nil, slices.Collect(maps.Values(astPkg.Files))); err != nil && len(checkerErrs) == 0 {
+	).Files(nil, files); err != nil && len(checkerErrs) == 0 {
 		checkerErrs.Add(err)
 	}
 
@@ -83,7 +90,7 @@ func buildTypeInfoCache(proj *Project) (any, error) {
 		}
 	}
 
-	return &typeInfoCache{typeInfo, checkerErrs.ToError()}, nil
+	return typeInfo, checkerErrs.ToError()
 }
 
 // TypeInfo retrieves the [TypeInfo] from the project. The returned
@@ -91,6 +98,11 @@ func buildTypeInfoCache(proj *Project) (any, error) {
 //
 // NOTE: Both the returned [TypeInfo] and error can be non-nil, which
 // indicates that only part of the project was type checked successfully.
+//
+// Type checking is not incremental: adding, changing, or deleting any file
+// that may affect the package re-typechecks the whole package, reusing only
+// the ASTs of unchanged files. Changes to other files, such as assets, keep
+// the [TypeInfo].
 func (p *Project) TypeInfo() (*TypeInfo, error) {
 	cacheIface, err := p.Cache(typeInfoCacheKind{})
 	if err != nil {
@@ -60,11 +60,15 @@ func PosASTFile(proj *xgo.Project, pos token.Pos) *ast.File {
 	if proj == nil || !pos.IsValid() {
 		return nil
 	}
+	filename := PosFilename(proj, pos)
+	if localPkg := proj.LocalPackageOf(filename); localPkg != nil {
+		return localPkg.Files[filename]
+	}
 	astPkg, _ := proj.ASTPackage()
 	if astPkg == nil {
 		return nil
 	}
-	return astPkg.Files[PosFilename(proj, pos)]
+	return astPkg.Files[filename]
 }
 
 // NodeASTFile returns the AST file for the given node.
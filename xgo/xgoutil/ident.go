@@ -48,7 +48,7 @@ func IdentAtPosition(proj *xgo.Project, astFile *ast.File, position token.Positi
 		lineEnd = token.Pos(tokenFile.Base() + tokenFile.Size())
 	}
 
-	typeInfo, _ := proj.TypeInfo()
+	typeInfo, _ := proj.TypeInfoFor(position.Filename)
 	if typeInfo == nil {
 		return nil
 	}